@@ -0,0 +1,178 @@
+// bloom.go
+package streamloader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// BloomFilterInfo describes a Bloom filter, as returned by BloomFilter.Info
+type BloomFilterInfo struct {
+	Keys      int     `json:"keys" js:"keys"`
+	Bits      int64   `json:"bits" js:"bits"`
+	Hashes    int     `json:"hashes" js:"hashes"`
+	FpRate    float64 `json:"fpRate" js:"fpRate"`
+	SizeBytes int64   `json:"sizeBytes" js:"sizeBytes"`
+}
+
+// bloomBits is the bit array of a Bloom filter, read-only once built.
+type bloomBits struct {
+	words  []uint64
+	bits   uint64
+	hashes int
+	keys   int
+	fpRate float64
+}
+
+// BloomFilter is a read-only handle on a Bloom filter shared by every VU. Close detaches the
+// handle; the filter itself stays in memory for the other VUs.
+type BloomFilter struct {
+	mu     sync.RWMutex
+	key    string     // Key of the shared filter, for Release
+	filter *bloomBits // nil once closed
+}
+
+// BuildBloomFilter builds a Bloom filter over the values of field, a field or dotted path, in a
+// JSON array or NDJSON file, to test cheaply whether a value was in the recorded set. The filter
+// never misses a value that was in the file, and wrongly reports a value that wasn't with
+// probability fpRate; it takes about 1.44 * log2(1 / fpRate) bits per key, under 10 bits at 1%,
+// however long the keys are. The file is read twice, to count the keys and then to add them.
+// Like BuildLookup, the filter is built once per process and shared by every VU, and values
+// compare with numbers matching their string form.
+//
+// Example usage:
+//
+//	const recorded = streamloader.buildBloomFilter("recorded-orders.json", "orderId", 0.001);
+//	// In the default function:
+//	if (!recorded.contains(order.id)) { newOrders.add(1); }
+func (s StreamLoader) BuildBloomFilter(filePath string, field string, fpRate float64) (*BloomFilter, error) {
+	if field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("fpRate must be between 0 and 1, got %v", fpRate)
+	}
+	key := sharedBuildKey("bloom", filePath, field, strconv.FormatFloat(fpRate, 'g', -1, 64))
+	filter, err := buildShared(key, func() (*bloomBits, error) {
+		return buildBloomBits(filePath, field, fpRate, s.call)
+	})
+	if err != nil {
+		return nil, err
+	}
+	bloom := &BloomFilter{key: key, filter: filter}
+	s.closeAtIterationEnd(bloom)
+	return bloom, nil
+}
+
+// buildBloomBits counts the keys in a file, sizes the filter for them and adds them.
+func buildBloomBits(filePath string, field string, fpRate float64, call callLimits) (*bloomBits, error) {
+	keys := 0
+	err := forEachKeyedRecord(filePath, field, call, func(_ int, _ string, found bool) {
+		if found {
+			keys++
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Bloom filter: %w", err)
+	}
+
+	// Optimal size and hash count for n keys at the target false positive rate
+	n := math.Max(1, float64(keys))
+	bits := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	bits = (bits + 63) / 64 * 64
+	hashes := int(math.Max(1, math.Round(float64(bits)/n*math.Ln2)))
+	b := &bloomBits{words: make([]uint64, bits/64), bits: bits, hashes: hashes, fpRate: fpRate}
+
+	err = forEachKeyedRecord(filePath, field, call, func(_ int, key string, found bool) {
+		if found {
+			b.add(key)
+			b.keys++
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Bloom filter: %w", err)
+	}
+	return b, nil
+}
+
+// bloomHashes returns the two base hashes of a key, combined by double hashing into the
+// positions of its bits.
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// add sets the bits of a key.
+func (b *bloomBits) add(key string) {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.bits
+		b.words[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// contains reports whether all bits of a key are set.
+func (b *bloomBits) contains(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.bits
+		if b.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shared returns the filter, or an error if the handle is closed.
+func (f *BloomFilter) shared() (*bloomBits, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.filter == nil {
+		return nil, fmt.Errorf("bloom filter is closed")
+	}
+	return f.filter, nil
+}
+
+// Contains reports whether a value may have been in the file. False is certain; true is wrong
+// with probability about fpRate.
+func (f *BloomFilter) Contains(value interface{}) (bool, error) {
+	filter, err := f.shared()
+	if err != nil {
+		return false, err
+	}
+	return filter.contains(keyString(value)), nil
+}
+
+// Info returns the number of keys added, the size of the filter and its target false positive
+// rate.
+func (f *BloomFilter) Info() (*BloomFilterInfo, error) {
+	filter, err := f.shared()
+	if err != nil {
+		return nil, err
+	}
+	return &BloomFilterInfo{
+		Keys:      filter.keys,
+		Bits:      int64(filter.bits),
+		Hashes:    filter.hashes,
+		FpRate:    filter.fpRate,
+		SizeBytes: int64(filter.bits / 8),
+	}, nil
+}
+
+// Close detaches the handle from the shared filter. Afterwards its methods return an error.
+func (f *BloomFilter) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.filter = nil
+}
+
+// Dispose is an alias of Close.
+func (f *BloomFilter) Dispose() {
+	f.Close()
+}
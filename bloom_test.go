@@ -0,0 +1,118 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildBloomFilter(t *testing.T) {
+	resetSharedBuilds(t)
+	path, _ := writeDataset(t, t.TempDir(), "orders", 10000)
+
+	for _, fpRate := range []float64{0.01, 0.001} {
+		t.Run(fmt.Sprint(fpRate), func(t *testing.T) {
+			filter, err := StreamLoader{}.BuildBloomFilter(path, "id", fpRate)
+			if err != nil {
+				t.Fatalf("BuildBloomFilter() error = %v", err)
+			}
+
+			// Every key in the file is found, as a number or its string form
+			for i := 0; i < 10000; i++ {
+				var key interface{} = int64(i)
+				if i%2 == 0 {
+					key = fmt.Sprint(i)
+				}
+				if ok, err := filter.Contains(key); err != nil || !ok {
+					t.Fatalf("Contains(%v) = %v, %v", key, ok, err)
+				}
+			}
+
+			falsePositives := 0
+			for i := 10000; i < 110000; i++ {
+				if ok, _ := filter.Contains(i); ok {
+					falsePositives++
+				}
+			}
+			if rate := float64(falsePositives) / 100000; rate > fpRate*1.5 {
+				t.Errorf("false positive rate = %v, want about %v", rate, fpRate)
+			}
+
+			info, err := filter.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Keys != 10000 || info.FpRate != fpRate || info.SizeBytes != info.Bits/8 {
+				t.Errorf("Info() = %+v", info)
+			}
+		})
+	}
+}
+
+func TestBuildBloomFilterShared(t *testing.T) {
+	resetSharedBuilds(t)
+	path, _ := writeDataset(t, t.TempDir(), "orders", 100)
+
+	first, err := StreamLoader{}.BuildBloomFilter(path, "id", 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := StreamLoader{}.BuildBloomFilter(path, "id", 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.filter != second.filter {
+		t.Error("VUs got separate filters for the same file, field and rate")
+	}
+
+	first.Close()
+	if _, err := first.Contains(1); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Contains() after Close error = %v", err)
+	}
+	if ok, err := second.Contains(1); err != nil || !ok {
+		t.Errorf("Contains(1) = %v, %v after another handle was closed", ok, err)
+	}
+}
+
+func TestBuildBloomFilterEmpty(t *testing.T) {
+	resetSharedBuilds(t)
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(path, []byte(`[{"other":1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := StreamLoader{}.BuildBloomFilter(path, "id", 0.01)
+	if err != nil {
+		t.Fatalf("BuildBloomFilter() error = %v", err)
+	}
+	if ok, _ := filter.Contains(1); ok {
+		t.Error("empty filter contains 1")
+	}
+}
+
+func TestBuildBloomFilterErrors(t *testing.T) {
+	resetSharedBuilds(t)
+	path, _ := writeDataset(t, t.TempDir(), "orders", 1)
+
+	tests := []struct {
+		name    string
+		path    string
+		field   string
+		fpRate  float64
+		wantErr string
+	}{
+		{"no field", path, "", 0.01, "field is required"},
+		{"zero rate", path, "id", 0, "fpRate must be between 0 and 1"},
+		{"rate of one", path, "id", 1, "fpRate must be between 0 and 1"},
+		{"missing file", path + ".missing", "id", 0.01, "failed to build Bloom filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamLoader{}.BuildBloomFilter(tt.path, tt.field, tt.fpRate)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildBloomFilter() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
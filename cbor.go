@@ -0,0 +1,474 @@
+// cbor.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// cborMaxDepth bounds nesting when decoding, so malicious input can't exhaust the stack
+const cborMaxDepth = 512
+
+// cborBreak is returned by the item decoder when it reads the "break" stop code of an
+// indefinite-length item
+var cborBreak = errors.New("unexpected CBOR break")
+
+// ObjectsToCbor encodes objects as a CBOR sequence (RFC 8742, one CBOR data item per object
+// back to back) and returns it base64-encoded, mirroring ObjectsToCompressedJsonLines.
+//
+// Map keys are sorted so the output is byte-stable. Whole numbers are encoded as CBOR integers
+// and other numbers as 64-bit floats.
+//
+// Example usage:
+//
+//	payload, err := streamloader.ObjectsToCbor(objects)
+func (StreamLoader) ObjectsToCbor(objects []interface{}) (string, error) {
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		if err := encodeCbor(&buf, obj); err != nil {
+			return "", fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// CborToObjects decodes a base64-encoded CBOR sequence, as produced by ObjectsToCbor.
+//
+// Text strings become strings, byte strings become byte arrays, integers that fit become
+// int64 and floats (half, single and double precision) become float64. Tags are dropped and
+// their content is returned. Map keys that aren't strings are converted to strings.
+//
+// Example usage:
+//
+//	objects, err := streamloader.CborToObjects(payload)
+func (StreamLoader) CborToObjects(cborBase64 string) ([]interface{}, error) {
+	data, err := base64.StdEncoding.DecodeString(cborBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64: %w", err)
+	}
+	return decodeCborSequence(cborReader{bufio.NewReader(bytes.NewReader(data)), int64(len(data))})
+}
+
+// LoadCborSequence loads a file containing a CBOR sequence (RFC 8742) or a single CBOR data
+// item. Values are decoded as described for CborToObjects.
+//
+// Example usage:
+//
+//	readings, err := streamloader.LoadCborSequence("fixtures/readings.cbor")
+func (s StreamLoader) LoadCborSequence(filePath string) ([]interface{}, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	size := int64(-1)
+	if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+		size = info.Size()
+	}
+	return decodeCborSequence(cborReader{bufio.NewReaderSize(file, readBufferSize()), size})
+}
+
+// WriteObjectsToCborSequenceFile writes objects to a file as a CBOR sequence, for creating
+// binary fixtures that LoadCborSequence reads back. options are the writer options of
+// WriteObjectsToJsonArrayFile; sortKeys, stableFormatting and metadata don't apply to CBOR.
+//
+// Returns: The number of objects written
+func (StreamLoader) WriteObjectsToCborSequenceFile(objects []interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Metadata != nil {
+		return 0, fmt.Errorf("metadata is not supported for CBOR output")
+	}
+
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return estimateObjectsSize(objects) }); err != nil {
+		return 0, err
+	}
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, opts.BufferSize)
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		buf.Reset()
+		if err := encodeCbor(&buf, obj); err != nil {
+			return i, fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+		if _, err := writer.Write(buf.Bytes()); err != nil {
+			return i, fmt.Errorf("failed to write object: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return len(objects), fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return len(objects), err
+	}
+	return len(objects), nil
+}
+
+// cborReader reads CBOR input, whose size bounds the length of the strings it can hold.
+type cborReader struct {
+	*bufio.Reader
+	size int64 // Size of the input, -1 if unknown
+}
+
+// decodeCborSequence decodes data items until the end of the input.
+func decodeCborSequence(reader cborReader) ([]interface{}, error) {
+	values := make([]interface{}, 0)
+	for {
+		if _, err := reader.Peek(1); err == io.EOF {
+			return values, nil
+		}
+		value, err := decodeCborItem(reader, 0)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to decode CBOR item %d: %w", len(values), err)
+		}
+		values = append(values, value)
+	}
+}
+
+// writeCborHead writes the initial byte and argument of a data item in its shortest form.
+func writeCborHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// writeCborInt writes a signed integer as major type 0 or 1.
+func writeCborInt(buf *bytes.Buffer, n int64) {
+	if n >= 0 {
+		writeCborHead(buf, 0, uint64(n))
+	} else {
+		writeCborHead(buf, 1, uint64(-(n + 1)))
+	}
+}
+
+// encodeCbor appends the CBOR encoding of a value decoded from JSON or passed from JavaScript.
+func encodeCbor(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xF6)
+	case bool:
+		if v {
+			buf.WriteByte(0xF5)
+		} else {
+			buf.WriteByte(0xF4)
+		}
+	case string:
+		writeCborHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		writeCborHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case int:
+		writeCborInt(buf, int64(v))
+	case int32:
+		writeCborInt(buf, int64(v))
+	case int64:
+		writeCborInt(buf, v)
+	case uint64:
+		writeCborHead(buf, 0, v)
+	case float32:
+		return encodeCbor(buf, float64(v))
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			writeCborInt(buf, int64(v))
+		} else {
+			buf.WriteByte(0xFB)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+		}
+	case []interface{}:
+		writeCborHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCbor(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeCborHead(buf, 5, uint64(len(v)))
+		for _, k := range keys {
+			writeCborHead(buf, 3, uint64(len(k)))
+			buf.WriteString(k)
+			if err := encodeCbor(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		// Other slices and maps, e.g. []string from Go callers
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			items := make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).Interface()
+			}
+			return encodeCbor(buf, items)
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				m := make(map[string]interface{}, rv.Len())
+				for _, k := range rv.MapKeys() {
+					m[k.String()] = rv.MapIndex(k).Interface()
+				}
+				return encodeCbor(buf, m)
+			}
+		}
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
+
+// readCborArgument reads the argument that follows an initial byte. indefinite is set for
+// additional information 31.
+func readCborArgument(r cborReader, info byte) (n uint64, indefinite bool, err error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info == 24:
+		b, err := r.ReadByte()
+		return uint64(b), false, err
+	case info == 25:
+		var v uint16
+		err := binary.Read(r, binary.BigEndian, &v)
+		return uint64(v), false, err
+	case info == 26:
+		var v uint32
+		err := binary.Read(r, binary.BigEndian, &v)
+		return uint64(v), false, err
+	case info == 27:
+		var v uint64
+		err := binary.Read(r, binary.BigEndian, &v)
+		return v, false, err
+	case info == 31:
+		return 0, true, nil
+	default:
+		return 0, false, fmt.Errorf("invalid additional information %d", info)
+	}
+}
+
+// readCborBytes reads a definite or indefinite-length byte or text string of the given major type.
+func readCborBytes(r cborReader, major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if n > uint64(math.MaxInt32) {
+			return nil, fmt.Errorf("string length %d is too large", n)
+		}
+		if r.size >= 0 && n > uint64(r.size) {
+			return nil, fmt.Errorf("string length %d exceeds the %d bytes of input", n, r.size)
+		}
+		// The length comes from the input, so the buffer only grows with the bytes actually read
+		buf := bytes.NewBuffer(make([]byte, 0, min(n, uint64(readBufferSize()))))
+		if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// Indefinite length: definite-length chunks of the same major type until "break"
+	var data []byte
+	for {
+		initial, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if initial == 0xFF {
+			return data, nil
+		}
+		if initial>>5 != major {
+			return nil, fmt.Errorf("invalid chunk of major type %d in indefinite-length string", initial>>5)
+		}
+		size, chunkIndefinite, err := readCborArgument(r, initial&0x1F)
+		if err != nil {
+			return nil, err
+		}
+		if chunkIndefinite {
+			return nil, fmt.Errorf("nested indefinite-length string")
+		}
+		chunk, err := readCborBytes(r, major, size, false)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// decodeCborItem decodes one data item.
+func decodeCborItem(r cborReader, depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("nesting deeper than %d levels", cborMaxDepth)
+	}
+	initial, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if initial == 0xFF {
+		return nil, cborBreak
+	}
+	major, info := initial>>5, initial&0x1F
+
+	// Floats and simple values use the additional information differently
+	if major == 7 {
+		return decodeCborSimple(r, info)
+	}
+
+	n, indefinite, err := readCborArgument(r, info)
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major == 0 || major == 1 || major == 6) {
+		return nil, fmt.Errorf("invalid indefinite length for major type %d", major)
+	}
+
+	switch major {
+	case 0:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 1:
+		if n <= math.MaxInt64 {
+			return -1 - int64(n), nil
+		}
+		return -1 - float64(n), nil
+	case 2:
+		return readCborBytes(r, major, n, indefinite)
+	case 3:
+		data, err := readCborBytes(r, major, n, indefinite)
+		return string(data), err
+	case 4:
+		items := make([]interface{}, 0)
+		for i := uint64(0); indefinite || i < n; i++ {
+			item, err := decodeCborItem(r, depth+1)
+			if indefinite && err == cborBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		m := make(map[string]interface{})
+		for i := uint64(0); indefinite || i < n; i++ {
+			key, err := decodeCborItem(r, depth+1)
+			if indefinite && err == cborBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeCborItem(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := key.(string); ok {
+				m[s] = value
+			} else {
+				m[fmt.Sprint(key)] = value
+			}
+		}
+		return m, nil
+	default: // 6: tag, return the tagged content
+		return decodeCborItem(r, depth+1)
+	}
+}
+
+// decodeCborSimple decodes major type 7: false, true, null, undefined and floats.
+func decodeCborSimple(r cborReader, info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		var bits uint16
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return halfToFloat64(bits), nil
+	case 26:
+		var bits uint32
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(bits)), nil
+	case 27:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case 24:
+		// Extended simple value without a standard meaning
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		return int64(b), nil
+	default:
+		if info < 20 {
+			return int64(info), nil
+		}
+		return nil, fmt.Errorf("invalid simple value %d", info)
+	}
+}
+
+// halfToFloat64 converts an IEEE 754 half-precision float.
+func halfToFloat64(bits uint16) float64 {
+	exp := int(bits>>10) & 0x1F
+	mant := float64(bits & 0x3FF)
+	var value float64
+	switch exp {
+	case 0:
+		value = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,129 @@
+package streamloader
+
+import (
+	"encoding/base64"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCbor(t *testing.T) {
+	loader := StreamLoader{}
+	objects := []interface{}{
+		map[string]interface{}{"id": "dev-1", "temp": 21.5, "seq": 1000, "on": true, "tags": []interface{}{"a", nil}},
+		int64(-25),
+		"text",
+		[]byte{1, 2, 3},
+		float64(3),
+	}
+	expected := []interface{}{
+		map[string]interface{}{"id": "dev-1", "temp": 21.5, "seq": int64(1000), "on": true, "tags": []interface{}{"a", nil}},
+		int64(-25),
+		"text",
+		[]byte{1, 2, 3},
+		int64(3),
+	}
+
+	encoded, err := loader.ObjectsToCbor(objects)
+	if err != nil {
+		t.Fatalf("ObjectsToCbor() error = %v", err)
+	}
+	decoded, err := loader.CborToObjects(encoded)
+	if err != nil {
+		t.Fatalf("CborToObjects() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("CborToObjects() = %#v, want %#v", decoded, expected)
+	}
+
+	// Map keys are sorted and heads use the shortest form
+	small, _ := loader.ObjectsToCbor([]interface{}{map[string]interface{}{"b": 1, "a": 500}})
+	raw, _ := base64.StdEncoding.DecodeString(small)
+	want := []byte{0xA2, 0x61, 'a', 0x19, 0x01, 0xF4, 0x61, 'b', 0x01}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("ObjectsToCbor() = % x, want % x", raw, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "readings.cbor")
+	count, err := loader.WriteObjectsToCborSequenceFile(objects, path)
+	if err != nil || count != len(objects) {
+		t.Fatalf("WriteObjectsToCborSequenceFile() = %d, %v", count, err)
+	}
+	loaded, err := loader.LoadCborSequence(path)
+	if err != nil {
+		t.Fatalf("LoadCborSequence() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("LoadCborSequence() = %#v, want %#v", loaded, expected)
+	}
+	if _, err := loader.LoadCborSequence(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := loader.ObjectsToCbor([]interface{}{func() {}}); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestCborDecoding(t *testing.T) {
+	loader := StreamLoader{}
+	tests := []struct {
+		name     string
+		data     []byte
+		expected interface{}
+	}{
+		{"half float", []byte{0xF9, 0x3E, 0x00}, 1.5},
+		{"half float subnormal", []byte{0xF9, 0x00, 0x01}, math.Ldexp(1, -24)},
+		{"single float", []byte{0xFA, 0x47, 0xC3, 0x50, 0x00}, 100000.0},
+		{"undefined", []byte{0xF7}, nil},
+		{"tagged value", []byte{0xC1, 0x1A, 0x51, 0x4B, 0x67, 0xB0}, int64(1363896240)},
+		{"indefinite text", []byte{0x7F, 0x62, 'a', 'b', 0x61, 'c', 0xFF}, "abc"},
+		{"indefinite array", []byte{0x9F, 0x01, 0x82, 0x02, 0x03, 0xFF}, []interface{}{int64(1), []interface{}{int64(2), int64(3)}}},
+		{"integer keys", []byte{0xA1, 0x01, 0x02}, map[string]interface{}{"1": int64(2)}},
+		{"large unsigned", []byte{0x1B, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, uint64(math.MaxUint64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := loader.CborToObjects(base64.StdEncoding.EncodeToString(tt.data))
+			if err != nil {
+				t.Fatalf("CborToObjects() error = %v", err)
+			}
+			if len(values) != 1 || !reflect.DeepEqual(values[0], tt.expected) {
+				t.Errorf("CborToObjects() = %#v, want %#v", values, tt.expected)
+			}
+		})
+	}
+
+	invalid := map[string][]byte{
+		"truncated":     {0x82, 0x01},
+		"stray break":   {0xFF},
+		"bad argument":  {0x1C},
+		"bad chunk":     {0x7F, 0x41, 'a', 0xFF},
+		"truncated str": {0x63, 'a'},
+		"huge length":   {0x5A, 0x7F, 0xFF, 0xFF, 0xFF, 'a'},
+	}
+	for name, data := range invalid {
+		if _, err := loader.CborToObjects(base64.StdEncoding.EncodeToString(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := loader.CborToObjects("not base64!"); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+
+	// A string length beyond the end of a file fails before anything is allocated for it
+	huge := filepath.Join(t.TempDir(), "huge.cbor")
+	os.WriteFile(huge, []byte{0x5B, 0, 0, 0, 0, 0x7F, 0xFF, 0xFF, 0xFF, 'a'}, 0644)
+	if _, err := loader.LoadCborSequence(huge); err == nil || !strings.Contains(err.Error(), "exceeds the 10 bytes of input") {
+		t.Errorf("LoadCborSequence() error = %v, want an error for a length beyond the file", err)
+	}
+
+	// An empty file is an empty sequence
+	path := filepath.Join(t.TempDir(), "empty.cbor")
+	os.WriteFile(path, nil, 0644)
+	if values, err := loader.LoadCborSequence(path); err != nil || len(values) != 0 {
+		t.Errorf("LoadCborSequence() = %v, %v", values, err)
+	}
+}
@@ -0,0 +1,75 @@
+// chunk_file.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FileChunks is an iterator over the chunks of a binary file, with the chunk count known up front
+type FileChunks struct {
+	*Iterator
+	Count     int   `json:"count" js:"count"`
+	Size      int64 `json:"size" js:"size"`
+	ChunkSize int   `json:"chunkSize" js:"chunkSize"`
+}
+
+// ChunkFile opens a file for reading in fixed-size chunks, so upload tests can send large
+// binaries chunk by chunk without holding the whole file in the JS heap. Each call to Next reads
+// one chunk from disk; in a VU chunks are ArrayBuffers, and []byte elsewhere. Every chunk is
+// chunkSizeBytes long except possibly the last. The file is closed once the last chunk is read,
+// when the iterator is closed, or at the end of the iteration that opened it.
+//
+// Returns: An iterator of chunks with the number of chunks, the file size and the chunk size
+//
+// Example usage:
+//
+//	const chunks = streamloader.chunkFile("video.bin", 5 * 1024 * 1024);
+//	for (let i = 0; chunks.hasNext(); i++) {
+//		http.put(`${url}?part=${i + 1}&of=${chunks.count}`, chunks.next());
+//	}
+func (s StreamLoader) ChunkFile(filePath string, chunkSizeBytes int) (*FileChunks, error) {
+	if chunkSizeBytes <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSizeBytes)
+	}
+	info, err := statInput(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", filePath)
+	}
+	file, err := openSequential(filePath, pageCacheKeep, s.call.untimed())
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	closeFile := func() { once.Do(func() { file.Close() }) }
+
+	chunks := &FileChunks{
+		Count:     int((info.Size() + int64(chunkSizeBytes) - 1) / int64(chunkSizeBytes)),
+		Size:      info.Size(),
+		ChunkSize: chunkSizeBytes,
+	}
+	var it *Iterator
+	it = s.newIterator(func() (interface{}, bool, error) {
+		buf := make([]byte, chunkSizeBytes)
+		n, err := io.ReadFull(file, buf)
+		if err == io.EOF {
+			closeFile()
+			return nil, false, nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			closeFile()
+			return nil, false, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		if it.rt != nil {
+			return it.rt.NewArrayBuffer(buf[:n]), true, nil
+		}
+		return buf[:n], true, nil
+	}, closeFile)
+	chunks.Iterator = it
+	return chunks, nil
+}
@@ -0,0 +1,158 @@
+package streamloader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestChunkFile(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250 bytes
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payload.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.bin")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		chunkSize int
+		wantCount int
+		wantLast  int
+	}{
+		{"uneven", path, 100, 3, 50},
+		{"even", path, 50, 5, 50},
+		{"one chunk", path, 1000, 1, 250},
+		{"empty file", empty, 10, 0, 0},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := loader.ChunkFile(tt.path, tt.chunkSize)
+			if err != nil {
+				t.Fatalf("ChunkFile() error = %v", err)
+			}
+			if chunks.Count != tt.wantCount {
+				t.Errorf("Count = %d, want %d", chunks.Count, tt.wantCount)
+			}
+			values, err := chunks.ToArray()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(values) != tt.wantCount {
+				t.Fatalf("read %d chunks, want %d", len(values), tt.wantCount)
+			}
+			var joined []byte
+			for _, v := range values {
+				joined = append(joined, v.([]byte)...)
+			}
+			if tt.wantCount > 0 {
+				if last := len(values[len(values)-1].([]byte)); last != tt.wantLast {
+					t.Errorf("last chunk is %d bytes, want %d", last, tt.wantLast)
+				}
+				if !bytes.Equal(joined, data) {
+					t.Error("chunks don't add up to the file")
+				}
+			}
+			if pooledFiles() != 0 {
+				t.Errorf("file still open after the last chunk was read")
+			}
+		})
+	}
+}
+
+func TestChunkFileClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := StreamLoader{}.ChunkFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chunks.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if pooledFiles() != 1 {
+		t.Errorf("pooledFiles() = %d while reading, want 1", pooledFiles())
+	}
+	chunks.Close()
+	if pooledFiles() != 0 {
+		t.Errorf("pooledFiles() = %d after Close, want 0", pooledFiles())
+	}
+	if ok, _ := chunks.HasNext(); ok {
+		t.Error("HasNext() = true after Close")
+	}
+}
+
+func TestChunkFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "payload.bin")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		chunkSize int
+		wantErr   string
+	}{
+		{"zero chunk size", path, 0, "chunk size must be positive"},
+		{"missing file", filepath.Join(dir, "missing.bin"), 10, "failed to stat file"},
+		{"directory", dir, 10, "not a regular file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamLoader{}.ChunkFile(tt.path, tt.chunkSize)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ChunkFile() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChunkFileJavaScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, []byte("abcdefghij"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Set("path", path); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		const chunks = streamloader.chunkFile(path, 4);
+		const parts = [];
+		while (chunks.hasNext()) {
+			const chunk = chunks.next();
+			if (!(chunk instanceof ArrayBuffer)) throw new Error("chunk is not an ArrayBuffer");
+			parts.push(String.fromCharCode(...new Uint8Array(chunk)));
+		}
+		JSON.stringify({ count: chunks.count, size: chunks.size, parts });
+	`)
+	if err != nil {
+		t.Fatalf("script error = %v", err)
+	}
+	if got, want := value.String(), `{"count":3,"size":10,"parts":["abcd","efgh","ij"]}`; got != want {
+		t.Errorf("script result = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,254 @@
+// chunk_store.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// chunkRefPrefix marks a field value that references a body in a chunk store
+const chunkRefPrefix = "sha256:"
+
+// DedupResult summarizes a DeduplicateField run
+type DedupResult struct {
+	Records      int `json:"records" js:"records"`
+	References   int `json:"references" js:"references"`
+	UniqueBodies int `json:"uniqueBodies" js:"uniqueBodies"`
+	BytesSaved   int `json:"bytesSaved" js:"bytesSaved"`
+}
+
+// ChunkStore holds deduplicated bodies keyed by content hash, as written by DeduplicateField.
+// Bodies are kept as raw JSON and only decoded when resolved. Close drops the bodies.
+type ChunkStore struct {
+	mu     sync.RWMutex
+	chunks map[string]json.RawMessage // nil once closed
+}
+
+// DeduplicateField rewrites a JSON array or NDJSON file so that identical values of one field,
+// such as large recorded request bodies, are stored only once. Each unique value is written to
+// a content-addressable store file (a JSON object mapping "sha256:<hex>" to the value), and the
+// field in every record is replaced with that reference string. Use LoadChunkStore to resolve
+// the references in VU code.
+//
+// Values are hashed after compacting whitespace, so formatting differences don't prevent
+// deduplication. Records without the field, or with a null value, are copied unchanged.
+// Only the hashes of the bodies seen so far are kept in memory.
+//
+// Returns: Record count, number of references written, number of unique bodies and the number
+// of bytes saved by not repeating bodies
+//
+// Example usage:
+//
+//	result, err := streamloader.DeduplicateField("recording.json", "body", "records.json", "bodies.json")
+func (s StreamLoader) DeduplicateField(inputFilePath string, field string, outputFilePath string, storeFilePath string) (*DedupResult, error) {
+	records, err := openJsonRecords(inputFilePath, s.call)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	out, err := createJsonArrayFile(outputFilePath, writeBufferSize())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockOutputPath(storeFilePath)
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+	defer lock.release()
+	store, err := os.Create(storeFilePath)
+	if err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to create store file: %w", err)
+	}
+	storeWriter := bufio.NewWriterSize(store, writeBufferSize())
+	storeWriter.WriteString("{")
+
+	fail := func(err error) (*DedupResult, error) {
+		out.Close()
+		store.Close()
+		return nil, err
+	}
+
+	result := &DedupResult{}
+	seen := make(map[string]bool)
+	var compacted bytes.Buffer
+	for {
+		raw, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+
+		names, values, err := objectFields(raw)
+		if err != nil {
+			return fail(fmt.Errorf("failed to read record %d: %w", result.Records, err))
+		}
+		result.Records++
+
+		index := -1
+		for i, name := range names {
+			if name == field && jsonValueType(values[i]) != "null" {
+				index = i
+			}
+		}
+		if index < 0 {
+			if err := out.Write(raw); err != nil {
+				return fail(err)
+			}
+			continue
+		}
+
+		compacted.Reset()
+		if err := json.Compact(&compacted, values[index]); err != nil {
+			return fail(fmt.Errorf("failed to compact field %q in record %d: %w", field, result.Records-1, err))
+		}
+		sum := sha256.Sum256(compacted.Bytes())
+		ref := chunkRefPrefix + hex.EncodeToString(sum[:])
+
+		if seen[ref] {
+			result.BytesSaved += compacted.Len()
+		} else {
+			seen[ref] = true
+			if result.UniqueBodies > 0 {
+				storeWriter.WriteByte(',')
+			}
+			storeWriter.WriteString(`"` + ref + `":`)
+			if _, err := storeWriter.Write(compacted.Bytes()); err != nil {
+				return fail(fmt.Errorf("failed to write store file: %w", err))
+			}
+			result.UniqueBodies++
+		}
+		result.References++
+
+		values[index] = json.RawMessage(`"` + ref + `"`)
+		if err := out.Write(encodeObjectFields(names, values)); err != nil {
+			return fail(err)
+		}
+	}
+
+	storeWriter.WriteString("}")
+	if err := storeWriter.Flush(); err != nil {
+		return fail(fmt.Errorf("failed to flush store file: %w", err))
+	}
+	if err := store.Close(); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to close store file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// LoadChunkStore loads a store file written by DeduplicateField. Load it once in the init context
+// and resolve references per iteration.
+//
+// Example usage:
+//
+//	const bodies = streamloader.loadChunkStore("bodies.json");
+//	const records = streamloader.loadJSON("records.json");
+//	// In the default function:
+//	const record = bodies.resolve(records[i], "body");
+func (s StreamLoader) LoadChunkStore(storeFilePath string) (*ChunkStore, error) {
+	data, err := readInputFile(storeFilePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	}
+	var chunks map[string]json.RawMessage
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil, fmt.Errorf("failed to parse store file: %w", err)
+	}
+	if chunks == nil {
+		chunks = make(map[string]json.RawMessage)
+	}
+	store := &ChunkStore{chunks: chunks}
+	s.closeAtIterationEnd(store)
+	return store, nil
+}
+
+// Size returns the number of unique bodies in the store.
+func (c *ChunkStore) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.chunks)
+}
+
+// Close drops the bodies. Afterwards Get and Resolve return an error.
+func (c *ChunkStore) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.chunks = nil
+}
+
+// Dispose is an alias of Close.
+func (c *ChunkStore) Dispose() {
+	c.Close()
+}
+
+// Get returns the body for a "sha256:<hex>" reference.
+func (c *ChunkStore) Get(ref string) (any, error) {
+	c.mu.RLock()
+	raw, ok := c.chunks[ref]
+	closed := c.chunks == nil
+	c.mu.RUnlock()
+	if closed {
+		return nil, fmt.Errorf("chunk store is closed")
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown chunk reference %q", ref)
+	}
+	var body any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode chunk %s: %w", ref, err)
+	}
+	return body, nil
+}
+
+// Resolve returns a copy of the record with the referenced field replaced by its body. Records
+// whose field is missing or not a reference are returned unchanged.
+func (c *ChunkStore) Resolve(record map[string]interface{}, field string) (map[string]interface{}, error) {
+	ref, ok := record[field].(string)
+	if !ok || !strings.HasPrefix(ref, chunkRefPrefix) {
+		return record, nil
+	}
+	body, err := c.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]interface{}, len(record))
+	for k, v := range record {
+		resolved[k] = v
+	}
+	resolved[field] = body
+	return resolved, nil
+}
+
+// encodeObjectFields writes keys and raw values back into a JSON object, preserving their order.
+func encodeObjectFields(names []string, values []json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(values[i])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
@@ -0,0 +1,98 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeduplicateField(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "recording.ndjson")
+	content := strings.Join([]string{
+		`{"id":1,"body":{"query":"large payload"}}`,
+		`{"id":2,"body":{ "query" : "large payload" }}`,
+		`{"id":3,"body":"other"}`,
+		`{"id":4}`,
+		`{"id":5,"body":null}`,
+	}, "\n")
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	output := filepath.Join(dir, "records.json")
+	storePath := filepath.Join(dir, "bodies.json")
+
+	result, err := loader.DeduplicateField(input, "body", output, storePath)
+	if err != nil {
+		t.Fatalf("DeduplicateField() error = %v", err)
+	}
+	expected := DedupResult{Records: 5, References: 3, UniqueBodies: 2, BytesSaved: len(`{"query":"large payload"}`)}
+	if *result != expected {
+		t.Errorf("result = %+v, want %+v", *result, expected)
+	}
+
+	records, err := loader.LoadJSON(output)
+	if err != nil {
+		t.Fatalf("LoadJSON() error = %v", err)
+	}
+	list := records.([]interface{})
+	first := list[0].(map[string]interface{})
+	second := list[1].(map[string]interface{})
+	ref, ok := first["body"].(string)
+	if !ok || !strings.HasPrefix(ref, "sha256:") || second["body"] != ref {
+		t.Fatalf("expected identical bodies to share a reference, got %v and %v", first["body"], second["body"])
+	}
+	if _, ok := list[3].(map[string]interface{})["body"]; ok {
+		t.Error("record without the field should be unchanged")
+	}
+
+	store, err := loader.LoadChunkStore(storePath)
+	if err != nil {
+		t.Fatalf("LoadChunkStore() error = %v", err)
+	}
+	if store.Size() != 2 {
+		t.Errorf("Size() = %d, want 2", store.Size())
+	}
+
+	resolved, err := store.Resolve(first, "body")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := map[string]interface{}{"id": 1.0, "body": map[string]interface{}{"query": "large payload"}}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("Resolve() = %v, want %v", resolved, want)
+	}
+	if first["body"] != ref {
+		t.Error("Resolve() must not modify the input record")
+	}
+
+	if body, err := store.Get(list[2].(map[string]interface{})["body"].(string)); err != nil || body != "other" {
+		t.Errorf("Get() = %v (err %v), want \"other\"", body, err)
+	}
+	if _, err := store.Get("sha256:unknown"); err == nil {
+		t.Error("Expected error for unknown reference")
+	}
+	plain := map[string]interface{}{"body": "not a reference"}
+	if got, err := store.Resolve(plain, "body"); err != nil || !reflect.DeepEqual(got, plain) {
+		t.Errorf("Resolve() should leave plain values alone, got %v (err %v)", got, err)
+	}
+}
+
+func TestDeduplicateField_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	if _, err := loader.DeduplicateField(filepath.Join(dir, "missing.json"), "body", filepath.Join(dir, "out.json"), filepath.Join(dir, "store.json")); err == nil {
+		t.Error("Expected error for missing input")
+	}
+	if _, err := loader.LoadChunkStore(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing store")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`[1,2]`), 0644)
+	if _, err := loader.LoadChunkStore(bad); err == nil {
+		t.Error("Expected error for a store that is not an object")
+	}
+}
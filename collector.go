@@ -0,0 +1,226 @@
+// collector.go
+package streamloader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// collectorBuffer holds the records added to a named collector, kept JSON-encoded so VUs never
+// share mutable objects
+type collectorBuffer struct {
+	mu      sync.Mutex
+	records []json.RawMessage
+	bytes   int64
+}
+
+// collectors is the registry of collectors by name, shared by every VU in the k6 process
+var collectors = struct {
+	mu     sync.Mutex
+	byName map[string]*collectorBuffer
+}{byName: make(map[string]*collectorBuffer)}
+
+// Collector is a handle on a named in-memory result buffer shared by every VU. Close detaches
+// the handle; the buffered records stay for the other VUs.
+type Collector struct {
+	mu     sync.RWMutex
+	name   string
+	buffer *collectorBuffer // nil once closed
+}
+
+// CollectorInfo describes a collector's buffer, as returned by Collector.Info
+type CollectorInfo struct {
+	Name    string `json:"name" js:"name"`
+	Records int    `json:"records" js:"records"`
+	Bytes   int64  `json:"bytes" js:"bytes"`
+}
+
+// NewCollector returns a handle on the collector with the given name, creating it on first use.
+// Every VU asking for the same name shares one buffer, so VUs can add results during the test
+// and teardown can write them all to one file for functional assertions, without VUs writing
+// the file concurrently. Records are copied when added, so later changes to an object are not
+// collected; the buffer lives in memory until it is flushed or cleared.
+//
+// Example usage:
+//
+//	const results = streamloader.newCollector("orders");
+//	// In the default function:
+//	results.add({ id: order.id, status: res.status });
+//	// In teardown:
+//	results.flushToJsonArrayFile("orders-results.json");
+func (s StreamLoader) NewCollector(name string) (*Collector, error) {
+	if name == "" {
+		return nil, fmt.Errorf("collector name must not be empty")
+	}
+	collectors.mu.Lock()
+	buffer, ok := collectors.byName[name]
+	if !ok {
+		buffer = &collectorBuffer{}
+		collectors.byName[name] = buffer
+	}
+	collectors.mu.Unlock()
+
+	c := &Collector{name: name, buffer: buffer}
+	s.closeAtIterationEnd(c)
+	return c, nil
+}
+
+// shared returns the buffer, or an error if the handle is closed.
+func (c *Collector) shared() (*collectorBuffer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.buffer == nil {
+		return nil, fmt.Errorf("collector %q is closed", c.name)
+	}
+	return c.buffer, nil
+}
+
+// Add appends a record to the buffer and returns the number of records buffered.
+func (c *Collector) Add(record interface{}) (int, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return 0, err
+	}
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode record: %w", err)
+	}
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	buffer.records = append(buffer.records, encoded)
+	buffer.bytes += int64(len(encoded))
+	return len(buffer.records), nil
+}
+
+// Size returns the number of records buffered.
+func (c *Collector) Size() (int, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return 0, err
+	}
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return len(buffer.records), nil
+}
+
+// Info describes the buffer: its name, the number of records and their encoded size.
+func (c *Collector) Info() (CollectorInfo, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return CollectorInfo{}, err
+	}
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	return CollectorInfo{Name: c.name, Records: len(buffer.records), Bytes: buffer.bytes}, nil
+}
+
+// ToArray returns a copy of the buffered records, in the order they were added, without
+// removing them.
+func (c *Collector) ToArray() ([]interface{}, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return nil, err
+	}
+	buffer.mu.Lock()
+	records := buffer.records[:len(buffer.records):len(buffer.records)]
+	buffer.mu.Unlock()
+
+	values := make([]interface{}, len(records))
+	for i, raw := range records {
+		if err := json.Unmarshal(raw, &values[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode record %d: %w", i, err)
+		}
+	}
+	return values, nil
+}
+
+// FlushToJsonArrayFile writes the buffered records to a JSON array file, in the order they were
+// added, and empties the buffer. Records added while the file is written are kept for the next
+// flush. If writing fails, the records are put back. options are the writer options of
+// WriteObjectsToJsonArrayFile.
+//
+// Returns: The number of records written
+func (c *Collector) FlushToJsonArrayFile(outputFilePath string, options ...interface{}) (int, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return 0, err
+	}
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
+	}
+
+	buffer.mu.Lock()
+	records, size := buffer.records, buffer.bytes
+	buffer.records, buffer.bytes = nil, 0
+	buffer.mu.Unlock()
+
+	if err := writeRawJsonArray(records, size, outputFilePath, opts); err != nil {
+		buffer.mu.Lock()
+		buffer.records = append(records, buffer.records...)
+		buffer.bytes += size
+		buffer.mu.Unlock()
+		return 0, err
+	}
+	return len(records), nil
+}
+
+// writeRawJsonArray writes encoded records to a JSON array file.
+func writeRawJsonArray(records []json.RawMessage, size int64, outputFilePath string, opts JsonWriterOptions) error {
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return size + int64(len(records)) + 2 }); err != nil {
+		return err
+	}
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, opts.BufferSize)
+	writer.WriteByte('[')
+	for i, raw := range records {
+		if i > 0 {
+			writer.WriteByte(',')
+		}
+		formatted, err := opts.formatJSON(raw)
+		if err != nil {
+			return fmt.Errorf("failed to format record %d: %w", i, err)
+		}
+		if _, err := writer.Write(formatted); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	writer.WriteByte(']')
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	return file.Close()
+}
+
+// Clear drops the buffered records and returns how many there were.
+func (c *Collector) Clear() (int, error) {
+	buffer, err := c.shared()
+	if err != nil {
+		return 0, err
+	}
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	n := len(buffer.records)
+	buffer.records, buffer.bytes = nil, 0
+	return n, nil
+}
+
+// Close detaches the handle from the shared buffer. Afterwards its methods return an error.
+func (c *Collector) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buffer = nil
+}
+
+// Dispose is an alias of Close.
+func (c *Collector) Dispose() {
+	c.Close()
+}
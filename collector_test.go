@@ -0,0 +1,167 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func resetCollectors(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		collectors.mu.Lock()
+		defer collectors.mu.Unlock()
+		collectors.byName = make(map[string]*collectorBuffer)
+	})
+}
+
+func TestCollectorConcurrentAdd(t *testing.T) {
+	resetCollectors(t)
+	loader := StreamLoader{}
+
+	var wg sync.WaitGroup
+	for vu := 0; vu < 10; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			// Every VU gets its own handle on the shared buffer
+			c, err := loader.NewCollector("results")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for i := 0; i < 100; i++ {
+				if _, err := c.Add(map[string]interface{}{"vu": vu, "i": i}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(vu)
+	}
+	wg.Wait()
+
+	c, _ := loader.NewCollector("results")
+	if info, _ := c.Info(); info.Records != 1000 || info.Bytes == 0 {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	n, err := c.FlushToJsonArrayFile(path)
+	if err != nil || n != 1000 {
+		t.Fatalf("FlushToJsonArrayFile() = %d, %v", n, err)
+	}
+	data, _ := os.ReadFile(path)
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil || len(records) != 1000 {
+		t.Fatalf("flushed %d records, error %v", len(records), err)
+	}
+	seen := make(map[[2]float64]bool)
+	for _, r := range records {
+		seen[[2]float64{r["vu"].(float64), r["i"].(float64)}] = true
+	}
+	if len(seen) != 1000 {
+		t.Errorf("flushed %d distinct records, want 1000", len(seen))
+	}
+	if size, _ := c.Size(); size != 0 {
+		t.Errorf("Size() after flush = %d, want 0", size)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	resetCollectors(t)
+	loader := StreamLoader{}
+	c, _ := loader.NewCollector("a")
+	other, _ := loader.NewCollector("b")
+
+	record := map[string]interface{}{"id": 1}
+	c.Add(record)
+	record["id"] = 2 // Later changes aren't collected
+	c.Add("text")
+	other.Add(3)
+
+	got, err := c.ToArray()
+	if want := []interface{}{map[string]interface{}{"id": float64(1)}, "text"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ToArray() = %v, %v, want %v", got, err, want)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.json")
+	if n, err := c.FlushToJsonArrayFile(path, map[string]interface{}{"sortKeys": true}); err != nil || n != 2 {
+		t.Fatalf("FlushToJsonArrayFile() = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `[{"id":1},"text"]` {
+		t.Errorf("file = %s", data)
+	}
+	// An empty flush writes an empty array
+	if n, err := c.FlushToJsonArrayFile(path); err != nil || n != 0 {
+		t.Errorf("empty flush = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `[]` {
+		t.Errorf("file = %s", data)
+	}
+
+	// A failed flush keeps the records
+	c.Add(4)
+	if _, err := c.FlushToJsonArrayFile(filepath.Join(dir, "missing", "a.json")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if size, _ := c.Size(); size != 1 {
+		t.Errorf("Size() after a failed flush = %d, want 1", size)
+	}
+	if n, _ := c.Clear(); n != 1 {
+		t.Errorf("Clear() = %d, want 1", n)
+	}
+	if size, _ := other.Size(); size != 1 {
+		t.Errorf("other collector Size() = %d, want 1", size)
+	}
+
+	if _, err := c.Add(func() {}); err == nil {
+		t.Error("expected an error for a value that can't be encoded")
+	}
+	if _, err := loader.NewCollector(""); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+	c.Dispose()
+	if _, err := c.Add(1); err == nil {
+		t.Error("expected an error after Close")
+	}
+	// Closing a handle keeps the buffer for the others
+	again, _ := loader.NewCollector("b")
+	if size, _ := again.Size(); size != 1 {
+		t.Errorf("Size() after closing another handle = %d, want 1", size)
+	}
+}
+
+func TestCollectorJavaScript(t *testing.T) {
+	resetCollectors(t)
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := rt.Set("path", path); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		const results = streamloader.newCollector("js");
+		results.add({ status: 200, ok: true });
+		results.add({ status: 500, ok: false });
+		const flushed = results.flushToJsonArrayFile(path);
+		const loaded = streamloader.loadJSON(path).map((r) => r.status + ":" + r.ok).join(",");
+		JSON.stringify({ flushed, size: results.size(), loaded });
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != `{"flushed":2,"size":0,"loaded":"200:true,500:false"}` {
+		t.Errorf("script result = %s", got)
+	}
+}
@@ -0,0 +1,264 @@
+// combine_incremental.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// combineManifest records what an incremental CombineJsonArrayFiles has written, so the next run
+// can append only the shards that are new.
+type combineManifest struct {
+	Output  string         `json:"output"`
+	Size    int64          `json:"size"` // Size of the output when the manifest was written
+	Records int            `json:"records"`
+	Shards  []combineShard `json:"shards"`
+}
+
+// combineShard is an input file already in the combined output.
+type combineShard struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime string `json:"modTime"`
+}
+
+// newCombineShard describes an input file as it is now.
+func newCombineShard(path string) (combineShard, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return combineShard{}, fmt.Errorf("failed to stat input file %s: %w", path, err)
+	}
+	return combineShard{Path: filepath.Clean(path), Size: info.Size(), ModTime: info.ModTime().UTC().Format(time.RFC3339Nano)}, nil
+}
+
+// combineIncremental is CombineJsonArrayFiles with the manifest option. Without a manifest, or
+// without the output, the output is combined from scratch; otherwise the shards not in the
+// manifest are appended to the output in place. Shards in the manifest must be unchanged and
+// the output must be as the manifest left it, or the call fails, since records would otherwise
+// be duplicated or lost; deleting the manifest forces a full rebuild.
+func (s StreamLoader) combineIncremental(inputFilePaths []string, outputFilePath string, opts JsonWriterOptions) (int, error) {
+	if (opts.CompressOutput != "" && opts.CompressOutput != "none") || opts.EncryptOutput != "" {
+		return 0, fmt.Errorf("the manifest option needs uncompressed, unencrypted output to append to")
+	}
+	if err := checkWritable(opts.Manifest); err != nil {
+		return 0, err
+	}
+
+	manifest, err := readCombineManifest(opts.Manifest)
+	if err != nil {
+		return 0, err
+	}
+	output, statErr := os.Stat(outputFilePath)
+	if manifest == nil || os.IsNotExist(statErr) {
+		return s.combineFromScratch(inputFilePaths, outputFilePath, opts)
+	}
+	if statErr != nil {
+		return 0, fmt.Errorf("failed to stat output file: %w", statErr)
+	}
+	if manifest.Output != filepath.Clean(outputFilePath) || output.Size() != manifest.Size {
+		return 0, fmt.Errorf("%s has changed since manifest %s was written; delete the manifest to combine from scratch", outputFilePath, opts.Manifest)
+	}
+
+	// Find the new shards, checking the merged ones are unchanged
+	merged := make(map[string]combineShard, len(manifest.Shards))
+	for _, shard := range manifest.Shards {
+		merged[shard.Path] = shard
+	}
+	var added []combineShard
+	for _, path := range inputFilePaths {
+		shard, err := newCombineShard(path)
+		if err != nil {
+			return 0, err
+		}
+		if previous, ok := merged[shard.Path]; ok {
+			if previous.Size != shard.Size || previous.ModTime != shard.ModTime {
+				return 0, fmt.Errorf("shard %s has changed since it was combined; delete manifest %s to combine from scratch", path, opts.Manifest)
+			}
+			continue
+		}
+		merged[shard.Path] = shard
+		added = append(added, shard)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	count, size, err := appendShards(added, outputFilePath, opts, s.call)
+	if err != nil {
+		return count, err
+	}
+	manifest.Size = size
+	manifest.Records += count
+	manifest.Shards = append(manifest.Shards, added...)
+	return count, writeCombineManifest(opts.Manifest, manifest)
+}
+
+// combineFromScratch combines every shard into a new output and writes the manifest.
+func (s StreamLoader) combineFromScratch(inputFilePaths []string, outputFilePath string, opts JsonWriterOptions) (int, error) {
+	manifest := &combineManifest{Output: filepath.Clean(outputFilePath), Shards: make([]combineShard, 0, len(inputFilePaths))}
+	for _, path := range inputFilePaths {
+		shard, err := newCombineShard(path)
+		if err != nil {
+			return 0, err
+		}
+		manifest.Shards = append(manifest.Shards, shard)
+	}
+
+	full := opts
+	full.Manifest = ""
+	count, err := s.CombineJsonArrayFiles(inputFilePaths, outputFilePath, full)
+	if err != nil {
+		return count, err
+	}
+	info, err := os.Stat(outputFilePath)
+	if err != nil {
+		return count, fmt.Errorf("failed to stat output file: %w", err)
+	}
+	manifest.Size, manifest.Records = info.Size(), count
+	return count, writeCombineManifest(opts.Manifest, manifest)
+}
+
+// appendShards appends the objects of shards to the JSON array in outputFilePath, replacing its
+// closing bracket, and returns the number of objects appended and the new size of the file.
+func appendShards(shards []combineShard, outputFilePath string, opts JsonWriterOptions, call callLimits) (int, int64, error) {
+	lock, err := lockOutputPath(outputFilePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer lock.release()
+	slot, err := acquireFileSlot(outputFilePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer slot.release()
+
+	file, err := os.OpenFile(outputFilePath, os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer file.Close()
+	end, empty, err := jsonArrayEnd(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to append to %s: %w", outputFilePath, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to stat output file: %w", err)
+	}
+	tail := make([]byte, info.Size()-end)
+	if _, err := file.ReadAt(tail, end); err != nil {
+		return 0, 0, fmt.Errorf("failed to read output file: %w", err)
+	}
+	// A failed append restores the file, so it still matches the manifest
+	fail := func(err error) (int, int64, error) {
+		file.Truncate(end)
+		file.WriteAt(tail, end)
+		return 0, 0, err
+	}
+	if err := file.Truncate(end); err != nil {
+		return 0, 0, fmt.Errorf("failed to truncate output file: %w", err)
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("failed to seek output file: %w", err)
+	}
+
+	writer := bufio.NewWriterSize(countingWriter{file}, opts.BufferSize)
+	written := 1
+	if empty {
+		written = 0
+	}
+	count := 0
+	for _, shard := range shards {
+		n, err := copyJsonArrayFile(writer, shard.Path, opts, written+count, call)
+		count += n
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if _, err := writer.WriteString("]"); err != nil {
+		return fail(fmt.Errorf("failed to write closing bracket: %w", err))
+	}
+	if err := writer.Flush(); err != nil {
+		return fail(fmt.Errorf("failed to flush data to file: %w", err))
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fail(fmt.Errorf("failed to seek output file: %w", err))
+	}
+	if err := file.Close(); err != nil {
+		return count, 0, fmt.Errorf("failed to close output file: %w", err)
+	}
+	return count, size, nil
+}
+
+// jsonArrayEnd returns the offset of the closing bracket of the JSON array in file, and whether
+// the array is empty, reading backwards over trailing whitespace.
+func jsonArrayEnd(file *os.File) (int64, bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	end := int64(-1)
+	b := make([]byte, 1)
+	for offset := info.Size() - 1; offset >= 0; offset-- {
+		if _, err := file.ReadAt(b, offset); err != nil {
+			return 0, false, err
+		}
+		if isWhitespace(b[0]) {
+			continue
+		}
+		if end < 0 {
+			if b[0] != ']' {
+				return 0, false, fmt.Errorf("file does not end with a JSON array")
+			}
+			end = offset
+			continue
+		}
+		return end, b[0] == '[', nil
+	}
+	return 0, false, fmt.Errorf("file does not hold a JSON array")
+}
+
+// readCombineManifest reads a manifest, or returns nil if there is none.
+func readCombineManifest(path string) (*combineManifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest combineManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// writeCombineManifest replaces the manifest, through a temporary file so it is never left
+// half-written.
+func writeCombineManifest(path string, manifest *combineManifest) error {
+	lock, err := lockOutputPath(path)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
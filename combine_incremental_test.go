@@ -0,0 +1,92 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCombineIncremental(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	shard := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	out := filepath.Join(dir, "combined.json")
+	options := map[string]interface{}{"manifest": filepath.Join(dir, "combined.manifest.json")}
+	ids := func() string {
+		var records []struct{ ID int }
+		data, _ := os.ReadFile(out)
+		if err := json.Unmarshal(data, &records); err != nil {
+			t.Fatalf("Output %s is not a JSON array: %v", data, err)
+		}
+		var s []string
+		for _, r := range records {
+			s = append(s, string(rune('0'+r.ID)))
+		}
+		return strings.Join(s, "")
+	}
+
+	empty := shard("day0.json", `[]`)
+	day1 := shard("day1.json", `[{"id":1},{"id":2}]`)
+	tests := []struct {
+		name   string
+		shards []string
+		want   int
+		ids    string
+	}{
+		{"first run combines everything", []string{empty, day1}, 2, "12"},
+		{"nothing new", []string{empty, day1}, 0, "12"},
+		{"new shard is appended", []string{empty, day1, shard("day2.json", "[{\"id\":3}]\n")}, 1, "123"},
+		{"merged shards may be left out", []string{shard("day3.json", `[{"id":4},{"id":5}]`)}, 2, "12345"},
+	}
+	for _, tt := range tests {
+		count, err := loader.CombineJsonArrayFiles(tt.shards, out, options)
+		if err != nil || count != tt.want {
+			t.Fatalf("%s: CombineJsonArrayFiles() = %d, %v, want %d", tt.name, count, err, tt.want)
+		}
+		if got := ids(); got != tt.ids {
+			t.Errorf("%s: ids = %s, want %s", tt.name, got, tt.ids)
+		}
+	}
+
+	// A failed append leaves the output as the manifest describes it
+	bad := shard("bad.json", `[{"id":6},{"id":`)
+	if _, err := loader.CombineJsonArrayFiles([]string{bad}, out, options); err == nil {
+		t.Error("Expected error for a truncated shard")
+	}
+	if got := ids(); got != "12345" {
+		t.Errorf("ids after a failed append = %s, want 12345", got)
+	}
+	os.Remove(bad)
+	if count, err := loader.CombineJsonArrayFiles([]string{shard("day6.json", `[{"id":6}]`)}, out, options); err != nil || count != 1 {
+		t.Errorf("CombineJsonArrayFiles() = %d, %v after a failed append, want 1", count, err)
+	}
+
+	// A changed shard or output can't be appended to
+	os.WriteFile(day1, []byte(`[{"id":1},{"id":2},{"id":7}]`), 0644)
+	if _, err := loader.CombineJsonArrayFiles([]string{day1}, out, options); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("CombineJsonArrayFiles() error = %v, want an error for a changed shard", err)
+	}
+	os.WriteFile(out, []byte(`[]`), 0644)
+	if _, err := loader.CombineJsonArrayFiles([]string{shard("day8.json", `[]`)}, out, options); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("CombineJsonArrayFiles() error = %v, want an error for a changed output", err)
+	}
+
+	// Without the manifest the output is rebuilt
+	os.Remove(options["manifest"].(string))
+	if count, err := loader.CombineJsonArrayFiles([]string{empty, day1}, out, options); err != nil || count != 3 {
+		t.Errorf("CombineJsonArrayFiles() = %d, %v, want a rebuild of 3 records", count, err)
+	}
+
+	gzipped := map[string]interface{}{"manifest": options["manifest"], "compressOutput": "gzip"}
+	if _, err := loader.CombineJsonArrayFiles([]string{day1}, out+".gz", gzipped); err == nil {
+		t.Error("Expected error for compressed output")
+	}
+}
@@ -0,0 +1,542 @@
+// compare_datasets.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultMaxDifferences is the number of differences DatasetDiff lists by default
+const defaultMaxDifferences = 10
+
+// CompareDatasetsOptions configures AssertDatasetsEqual
+type CompareDatasetsOptions struct {
+	IgnoreOrder    bool     `json:"ignoreOrder" js:"ignoreOrder"`
+	KeyField       string   `json:"keyField" js:"keyField"`
+	IgnoreFields   []string `json:"ignoreFields" js:"ignoreFields"`
+	MaxDifferences int      `json:"maxDifferences" js:"maxDifferences"`
+}
+
+// DatasetDiff summarizes the differences between two datasets
+type DatasetDiff struct {
+	Equal         bool                `json:"equal" js:"equal"`
+	RecordsA      int                 `json:"recordsA" js:"recordsA"`
+	RecordsB      int                 `json:"recordsB" js:"recordsB"`
+	Matched       int                 `json:"matched" js:"matched"`             // Records equal in both
+	Changed       int                 `json:"changed" js:"changed"`             // Records paired by position or key whose contents differ
+	OnlyInA       int                 `json:"onlyInA" js:"onlyInA"`             // Records of A with no counterpart in B
+	OnlyInB       int                 `json:"onlyInB" js:"onlyInB"`             // Records of B with no counterpart in A
+	DuplicateKeys int                 `json:"duplicateKeys" js:"duplicateKeys"` // Records whose key appeared earlier in the same file, left unpaired
+	Differences   []DatasetDifference `json:"differences" js:"differences"`     // The first differences found
+}
+
+// DatasetDifference is one difference listed in a DatasetDiff
+type DatasetDifference struct {
+	Kind   string      `json:"kind" js:"kind"`               // "changed", "onlyInA", "onlyInB" or "duplicateKey"
+	Index  int         `json:"index" js:"index"`             // Position of the record in B, or in A for "onlyInA"
+	Key    interface{} `json:"key,omitempty" js:"key"`       // Value of keyField
+	Fields []string    `json:"fields,omitempty" js:"fields"` // Dotted paths of the fields that differ, for "changed"
+	A      interface{} `json:"a,omitempty" js:"a"`           // Record of A
+	B      interface{} `json:"b,omitempty" js:"b"`           // Record of B
+}
+
+// isCsvPath reports whether a file is read as delimited text rather than JSON.
+func isCsvPath(filePath string) bool {
+	switch strings.ToLower(inputExt(filePath)) {
+	case ".csv", ".tsv", ".tab", ".psv":
+		return true
+	}
+	return false
+}
+
+// datasetRecords reads the records of a dataset file as comparable values.
+type datasetRecords struct {
+	json   *jsonRecordReader
+	csv    *csv.Reader
+	file   *inputFile
+	header []string
+	text   bool     // Compare scalar values as their CSV text
+	ignore []string // Dotted paths removed from every record
+	index  int      // Records read
+}
+
+// openDatasetRecords opens a JSON array, NDJSON or CSV file; text makes every scalar a string,
+// as CSV holds them, so records converted between the formats compare equal.
+func openDatasetRecords(filePath string, text bool, ignore []string, call callLimits) (*datasetRecords, error) {
+	r := &datasetRecords{text: text, ignore: ignore}
+	if !isCsvPath(filePath) {
+		records, err := openJsonRecords(filePath, call)
+		if err != nil {
+			return nil, err
+		}
+		r.json = records
+		return r, nil
+	}
+
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	r.file = file
+	r.csv = csv.NewReader(newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true))
+	r.csv.FieldsPerRecord = -1
+	if err := setCsvDelimiter(r.csv, "", filePath); err != nil {
+		file.Close()
+		return nil, err
+	}
+	header, err := r.csv.Read()
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("failed to parse CSV header of %s: %w", filePath, err)
+	}
+	r.header = header
+	return r, nil
+}
+
+// next returns the next record, or io.EOF.
+func (r *datasetRecords) next() (interface{}, error) {
+	var record interface{}
+	if r.json != nil {
+		raw, err := r.json.Next()
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&record); err != nil {
+			return nil, fmt.Errorf("failed to decode record %d: %w", r.index, err)
+		}
+		record = comparableValue(record, r.text)
+	} else {
+		if r.header == nil {
+			return nil, io.EOF
+		}
+		row, err := r.csv.Read()
+		if err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("failed to parse CSV record %d: %w", r.index, err)
+			}
+			return nil, err
+		}
+		fields := make(map[string]interface{}, len(r.header))
+		for i, name := range r.header {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			fields[strings.TrimSpace(name)] = cell
+		}
+		record = fields
+	}
+	for _, path := range r.ignore {
+		if obj, key, ok := fieldParent(record, path); ok {
+			delete(obj, key)
+		}
+	}
+	r.index++
+	return record, nil
+}
+
+func (r *datasetRecords) close() {
+	if r.json != nil {
+		r.json.Close()
+	}
+	if r.file != nil {
+		r.file.Close()
+	}
+}
+
+// comparableValue converts decoded JSON so equal values are equal Go values: numbers become
+// float64, so 1 and 1.0 are equal, or with text every scalar becomes its CSV text.
+func comparableValue(value interface{}, text bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = comparableValue(item, text)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = comparableValue(item, text)
+		}
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		if text {
+			cell, _ := csvCellString(f)
+			return cell
+		}
+		return f
+	}
+	if text {
+		if cell, err := csvCellString(value); err == nil {
+			return cell
+		}
+	}
+	return value
+}
+
+// recordHash returns the hash of the canonical JSON encoding of a record; map keys are sorted.
+func recordHash(record interface{}) [sha256.Size]byte {
+	encoded, _ := json.Marshal(record)
+	return sha256.Sum256(encoded)
+}
+
+// differentFields returns the dotted paths of the fields of two records that differ, descending
+// into nested objects.
+func differentFields(a interface{}, b interface{}, prefix string) []string {
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if recordHash(a) == recordHash(b) {
+			return nil
+		}
+		if prefix == "" {
+			return []string{"."}
+		}
+		return []string{prefix}
+	}
+	keys := make(map[string]bool, len(objA)+len(objB))
+	for key := range objA {
+		keys[key] = true
+	}
+	for key := range objB {
+		keys[key] = true
+	}
+	var fields []string
+	for key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		valueA, inA := objA[key]
+		valueB, inB := objB[key]
+		if inA != inB {
+			fields = append(fields, path)
+			continue
+		}
+		fields = append(fields, differentFields(valueA, valueB, path)...)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// datasetComparison accumulates a DatasetDiff.
+type datasetComparison struct {
+	diff DatasetDiff
+	max  int
+}
+
+func (c *datasetComparison) add(d DatasetDifference) {
+	if len(c.diff.Differences) < c.max {
+		if d.Kind == "changed" {
+			d.Fields = differentFields(d.A, d.B, "")
+		}
+		c.diff.Differences = append(c.diff.Differences, d)
+	}
+}
+
+// AssertDatasetsEqual streams two datasets and compares their records, to validate conversions
+// in pipeline tests. Each file is a JSON array, NDJSON, or CSV with a header row (by the .csv,
+// .tsv, .tab or .psv extension), whose rows are compared as objects keyed by column name. When
+// one file is CSV, values are compared as CSV text, so the number 1.5 equals the cell "1.5" and
+// null an empty cell; otherwise numbers are compared by value, so 1 equals 1.0.
+//
+// Options:
+//   - ignoreOrder: Compare the records as multisets rather than position by position
+//   - keyField: Pair records by this field (or dotted path) instead of by position; records
+//     whose key appeared earlier in the same file are counted as duplicateKeys
+//   - ignoreFields: Fields (or dotted paths) removed from every record before comparing
+//   - maxDifferences: The number of differences listed (default: 10)
+//
+// Without keyField records are paired by position, so one inserted record makes every later
+// one differ; use ignoreOrder or keyField for data whose order isn't meaningful. With
+// ignoreOrder or keyField a hash of every record of the first file is kept in memory, and the
+// first file is read a second time to list records missing from the second.
+//
+// Returns: A summary with equal, the record counts, matched, changed, onlyInA, onlyInB and
+// duplicateKeys, and the first differences with their records and, for changed records, the
+// fields that differ
+//
+// Example usage:
+//
+//	const diff = streamloader.assertDatasetsEqual("orders.csv", "orders.json", { keyField: "id", ignoreFields: ["importedAt"] });
+//	if (!diff.equal) {
+//		throw new Error(`conversion differs: ${JSON.stringify(diff.differences)}`);
+//	}
+func (s StreamLoader) AssertDatasetsEqual(fileA string, fileB string, options ...CompareDatasetsOptions) (*DatasetDiff, error) {
+	var opts CompareDatasetsOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxDifferences < 0 {
+		return nil, fmt.Errorf("maxDifferences must not be negative, got %d", opts.MaxDifferences)
+	}
+	c := &datasetComparison{max: opts.MaxDifferences, diff: DatasetDiff{Differences: []DatasetDifference{}}}
+	if c.max == 0 {
+		c.max = defaultMaxDifferences
+	}
+	if opts.KeyField != "" || opts.IgnoreOrder {
+		if err := requireSeekable(fileA, "assertDatasetsEqual"); err != nil {
+			return nil, err
+		}
+	}
+	text := isCsvPath(fileA) || isCsvPath(fileB)
+	open := func(filePath string) (*datasetRecords, error) {
+		return openDatasetRecords(filePath, text, opts.IgnoreFields, s.call)
+	}
+
+	var err error
+	switch {
+	case opts.KeyField != "":
+		err = c.compareByKey(open, fileA, fileB, opts.KeyField)
+	case opts.IgnoreOrder:
+		err = c.compareUnordered(open, fileA, fileB)
+	default:
+		err = c.compareOrdered(open, fileA, fileB)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &c.diff
+	d.Equal = d.Changed == 0 && d.OnlyInA == 0 && d.OnlyInB == 0 && d.DuplicateKeys == 0
+	return d, nil
+}
+
+// compareOrdered pairs the records of the files by position.
+func (c *datasetComparison) compareOrdered(open func(string) (*datasetRecords, error), fileA string, fileB string) error {
+	a, err := open(fileA)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+	b, err := open(fileB)
+	if err != nil {
+		return err
+	}
+	defer b.close()
+
+	for index := 0; ; index++ {
+		recordA, errA := a.next()
+		if errA != nil && errA != io.EOF {
+			return fmt.Errorf("failed to read %s: %w", fileA, errA)
+		}
+		recordB, errB := b.next()
+		if errB != nil && errB != io.EOF {
+			return fmt.Errorf("failed to read %s: %w", fileB, errB)
+		}
+		switch {
+		case errA == io.EOF && errB == io.EOF:
+			return nil
+		case errB == io.EOF:
+			c.diff.RecordsA++
+			c.diff.OnlyInA++
+			c.add(DatasetDifference{Kind: "onlyInA", Index: index, A: recordA})
+		case errA == io.EOF:
+			c.diff.RecordsB++
+			c.diff.OnlyInB++
+			c.add(DatasetDifference{Kind: "onlyInB", Index: index, B: recordB})
+		default:
+			c.diff.RecordsA++
+			c.diff.RecordsB++
+			if recordHash(recordA) == recordHash(recordB) {
+				c.diff.Matched++
+			} else {
+				c.diff.Changed++
+				c.add(DatasetDifference{Kind: "changed", Index: index, A: recordA, B: recordB})
+			}
+		}
+	}
+}
+
+// compareUnordered compares the files as multisets of records.
+func (c *datasetComparison) compareUnordered(open func(string) (*datasetRecords, error), fileA string, fileB string) error {
+	remaining := make(map[[sha256.Size]byte]int)
+	err := forEachDatasetRecord(open, fileA, func(index int, record interface{}) error {
+		remaining[recordHash(record)]++
+		c.diff.RecordsA++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = forEachDatasetRecord(open, fileB, func(index int, record interface{}) error {
+		c.diff.RecordsB++
+		h := recordHash(record)
+		if remaining[h] > 0 {
+			remaining[h]--
+			c.diff.Matched++
+			return nil
+		}
+		c.diff.OnlyInB++
+		c.add(DatasetDifference{Kind: "onlyInB", Index: index, B: record})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.diff.OnlyInA = c.diff.RecordsA - c.diff.Matched
+	if c.diff.OnlyInA == 0 {
+		return nil
+	}
+	// Second pass over A: the records left over after matching B, in order
+	return forEachDatasetRecord(open, fileA, func(index int, record interface{}) error {
+		h := recordHash(record)
+		if remaining[h] > 0 {
+			remaining[h]--
+			c.add(DatasetDifference{Kind: "onlyInA", Index: index, A: record})
+		}
+		return nil
+	})
+}
+
+// keyedRecord is what compareByKey keeps of a record of the first file.
+type keyedRecord struct {
+	index  int
+	hash   [sha256.Size]byte
+	paired bool
+}
+
+// compareByKey pairs the records of the files by the value of keyField.
+func (c *datasetComparison) compareByKey(open func(string) (*datasetRecords, error), fileA string, fileB string, keyField string) error {
+	keyOf := func(filePath string, index int, record interface{}) (string, interface{}, error) {
+		value, ok := lookupField(record, keyField)
+		if !ok || value == nil {
+			return "", nil, fmt.Errorf("record %d of %s has no %q field", index, filePath, keyField)
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded), value, nil
+	}
+
+	byKey := make(map[string]*keyedRecord)
+	duplicateA := make(map[int]bool)
+	err := forEachDatasetRecord(open, fileA, func(index int, record interface{}) error {
+		c.diff.RecordsA++
+		key, value, err := keyOf(fileA, index, record)
+		if err != nil {
+			return err
+		}
+		if _, exists := byKey[key]; exists {
+			c.diff.DuplicateKeys++
+			duplicateA[index] = true
+			c.add(DatasetDifference{Kind: "duplicateKey", Index: index, Key: value, A: record})
+			return nil
+		}
+		byKey[key] = &keyedRecord{index: index, hash: recordHash(record)}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Changed records are listed once the records of A they differ from are read again
+	changed := make(map[int]DatasetDifference)
+	var changedOrder []int
+	seenB := make(map[string]bool)
+	err = forEachDatasetRecord(open, fileB, func(index int, record interface{}) error {
+		c.diff.RecordsB++
+		key, value, err := keyOf(fileB, index, record)
+		if err != nil {
+			return err
+		}
+		if seenB[key] {
+			c.diff.DuplicateKeys++
+			c.add(DatasetDifference{Kind: "duplicateKey", Index: index, Key: value, B: record})
+			return nil
+		}
+		seenB[key] = true
+		a, ok := byKey[key]
+		switch {
+		case !ok:
+			c.diff.OnlyInB++
+			c.add(DatasetDifference{Kind: "onlyInB", Index: index, Key: value, B: record})
+		case a.hash == recordHash(record):
+			a.paired = true
+			c.diff.Matched++
+		default:
+			a.paired = true
+			c.diff.Changed++
+			if len(c.diff.Differences)+len(changed) < c.max {
+				changed[a.index] = DatasetDifference{Kind: "changed", Index: index, Key: value, B: record}
+				changedOrder = append(changedOrder, a.index)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	unpaired := 0
+	for _, a := range byKey {
+		if !a.paired {
+			unpaired++
+		}
+	}
+	c.diff.OnlyInA = unpaired
+	if unpaired == 0 && len(changed) == 0 {
+		return nil
+	}
+
+	// Second pass over A for the records of changed and unpaired keys
+	var onlyInA []DatasetDifference
+	err = forEachDatasetRecord(open, fileA, func(index int, record interface{}) error {
+		if d, ok := changed[index]; ok {
+			d.A = record
+			changed[index] = d
+			return nil
+		}
+		if duplicateA[index] {
+			return nil
+		}
+		key, value, _ := keyOf(fileA, index, record)
+		if a := byKey[key]; a != nil && !a.paired {
+			onlyInA = append(onlyInA, DatasetDifference{Kind: "onlyInA", Index: index, Key: value, A: record})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, index := range changedOrder {
+		c.add(changed[index])
+	}
+	for _, d := range onlyInA {
+		c.add(d)
+	}
+	return nil
+}
+
+// forEachDatasetRecord calls fn with every record of a dataset file and its index, until fn
+// fails.
+func forEachDatasetRecord(open func(string) (*datasetRecords, error), filePath string, fn func(int, interface{}) error) error {
+	records, err := open(filePath)
+	if err != nil {
+		return err
+	}
+	defer records.close()
+	for index := 0; ; index++ {
+		record, err := records.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		if err := fn(index, record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAssertDatasetsEqual(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+	orders := write("orders.json", `[{"id":1,"total":1.5,"note":null,"at":"mon"},{"id":2,"total":3,"note":"gift","at":"tue"}]`)
+	ordersNdjson := write("orders.jsonl", "{\"id\":1.0,\"total\":1.5,\"note\":null,\"at\":\"mon\"}\n{\"note\":\"gift\",\"id\":2,\"total\":3,\"at\":\"wed\"}\n")
+	ordersCsv := write("orders.csv", "id,total,note,at\n1,1.5,,mon\n2,3,gift,tue\n")
+	reversed := write("reversed.json", `[{"id":2,"total":3,"note":"gift","at":"tue"},{"id":1,"total":1.5,"note":null,"at":"mon"}]`)
+	changed := write("changed.json", `[{"id":2,"total":4,"note":"gift","at":"tue"},{"id":3,"total":1,"note":null,"at":"mon"}]`)
+	duplicates := write("duplicates.json", `[{"id":1},{"id":1}]`)
+
+	tests := []struct {
+		name    string
+		a, b    string
+		options CompareDatasetsOptions
+		want    DatasetDiff // Differences are not compared
+	}{
+		{"JSON and NDJSON", orders, ordersNdjson, CompareDatasetsOptions{IgnoreFields: []string{"at"}},
+			DatasetDiff{Equal: true, RecordsA: 2, RecordsB: 2, Matched: 2}},
+		{"JSON and CSV", orders, ordersCsv, CompareDatasetsOptions{},
+			DatasetDiff{Equal: true, RecordsA: 2, RecordsB: 2, Matched: 2}},
+		{"order matters by default", orders, reversed, CompareDatasetsOptions{},
+			DatasetDiff{RecordsA: 2, RecordsB: 2, Changed: 2}},
+		{"ignoreOrder", orders, reversed, CompareDatasetsOptions{IgnoreOrder: true},
+			DatasetDiff{Equal: true, RecordsA: 2, RecordsB: 2, Matched: 2}},
+		{"ignoreOrder with differences", orders, changed, CompareDatasetsOptions{IgnoreOrder: true},
+			DatasetDiff{RecordsA: 2, RecordsB: 2, OnlyInA: 2, OnlyInB: 2}},
+		{"keyField", ordersCsv, changed, CompareDatasetsOptions{KeyField: "id"},
+			DatasetDiff{RecordsA: 2, RecordsB: 2, Changed: 1, OnlyInA: 1, OnlyInB: 1}},
+		{"duplicate keys", duplicates, duplicates, CompareDatasetsOptions{KeyField: "id"},
+			DatasetDiff{RecordsA: 2, RecordsB: 2, Matched: 1, DuplicateKeys: 2}},
+		{"extra records", orders, write("one.json", `[{"id":1,"total":1.5,"note":null,"at":"mon"}]`), CompareDatasetsOptions{},
+			DatasetDiff{RecordsA: 2, RecordsB: 1, Matched: 1, OnlyInA: 1}},
+	}
+	for _, tt := range tests {
+		got, err := loader.AssertDatasetsEqual(tt.a, tt.b, tt.options)
+		if err != nil {
+			t.Fatalf("%s: AssertDatasetsEqual() error = %v", tt.name, err)
+		}
+		counts := *got
+		counts.Differences = nil
+		if !reflect.DeepEqual(counts, tt.want) {
+			t.Errorf("%s: AssertDatasetsEqual() = %+v, want %+v", tt.name, counts, tt.want)
+		}
+	}
+
+	// Differences list the records and the fields that changed
+	diff, _ := loader.AssertDatasetsEqual(ordersCsv, changed, CompareDatasetsOptions{KeyField: "id"})
+	kinds := map[string]DatasetDifference{}
+	for _, d := range diff.Differences {
+		kinds[d.Kind] = d
+	}
+	if d := kinds["changed"]; d.Key != "2" || !reflect.DeepEqual(d.Fields, []string{"total"}) || d.A == nil || d.B == nil {
+		t.Errorf("changed difference = %+v", d)
+	}
+	if d := kinds["onlyInA"]; d.Key != "1" || d.Index != 0 {
+		t.Errorf("onlyInA difference = %+v", d)
+	}
+	if d := kinds["onlyInB"]; d.Key != "3" || d.Index != 1 {
+		t.Errorf("onlyInB difference = %+v", d)
+	}
+	diff, _ = loader.AssertDatasetsEqual(orders, reversed, CompareDatasetsOptions{MaxDifferences: 1})
+	if len(diff.Differences) != 1 || !reflect.DeepEqual(diff.Differences[0].Fields, []string{"at", "id", "note", "total"}) {
+		t.Errorf("differences = %+v", diff.Differences)
+	}
+
+	invalid := []struct {
+		a, b    string
+		options CompareDatasetsOptions
+	}{
+		{orders, filepath.Join(dir, "missing.json"), CompareDatasetsOptions{}},
+		{orders, orders, CompareDatasetsOptions{KeyField: "missing"}},
+		{orders, orders, CompareDatasetsOptions{MaxDifferences: -1}},
+	}
+	for _, tt := range invalid {
+		if _, err := loader.AssertDatasetsEqual(tt.a, tt.b, tt.options); err == nil {
+			t.Errorf("AssertDatasetsEqual(%s, %s, %+v) expected an error", tt.a, tt.b, tt.options)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// compressed_batches.go
+package streamloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// gzipBound is an upper bound on the bytes deflate adds when compressing n more bytes and
+// closing the stream: at worst the data is stored uncompressed with a 5 byte header per block,
+// followed by a flush marker, the final block and the 8 byte gzip trailer.
+func gzipBound(n int) int {
+	return n + 5*(n/16384+2) + 8
+}
+
+// ObjectsToCompressedJsonLinesBatches converts objects into JSON lines and splits them into
+// batches that each compress to at most maxCompressedBytes bytes of gzip data. Each batch is
+// returned base64-encoded, like ObjectsToCompressedJsonLines, and holds whole lines, so every
+// batch can be decoded on its own. Use it for APIs that reject payloads above a size limit.
+//
+// The limit applies to the gzip data; base64 encoding adds a third on top, so pass three
+// quarters of the limit when the base64 string itself is the payload.
+//
+// Parameters:
+//   - objects: An array of JavaScript objects to convert.
+//   - maxCompressedBytes: The maximum size of each batch's gzip data in bytes.
+//   - compressionLevel: Optional compression level (0-9), as for ObjectsToCompressedJsonLines.
+//
+// Returns:
+//   - An array of base64-encoded, gzip-compressed JSONL batches, in order.
+//   - An error if an object can't be encoded, or compresses to more than the limit on its own.
+//
+// Example:
+//
+//	batches = streamloader.ObjectsToCompressedJsonLinesBatches(results, 5 * 1024 * 1024)
+//	batches.forEach((batch) => http.post(url, encoding.b64decode(batch)))
+func (StreamLoader) ObjectsToCompressedJsonLinesBatches(objects []interface{}, maxCompressedBytes int, compressionLevel ...int) ([]string, error) {
+	if maxCompressedBytes <= 0 {
+		return nil, fmt.Errorf("maxCompressedBytes must be positive, got %d", maxCompressedBytes)
+	}
+	level := gzip.DefaultCompression
+	if len(compressionLevel) > 0 && compressionLevel[0] >= gzip.NoCompression && compressionLevel[0] <= gzip.BestCompression {
+		level = compressionLevel[0]
+	}
+
+	batches := make([]string, 0)
+	var buf bytes.Buffer
+	var gzWriter *gzip.Writer
+	lines := 0   // Lines in the current batch
+	pending := 0 // Bytes written since the last flush, which may not have reached buf yet
+	firstIndex := 0
+
+	finish := func() error {
+		if err := gzWriter.Close(); err != nil {
+			return fmt.Errorf("failed to close gzip writer: %w", err)
+		}
+		if buf.Len() > maxCompressedBytes {
+			return fmt.Errorf("object at index %d compresses to %d bytes, more than the limit of %d", firstIndex, buf.Len(), maxCompressedBytes)
+		}
+		batches = append(batches, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		gzWriter, lines, pending = nil, 0, 0
+		return nil
+	}
+
+	var lineBuffer bytes.Buffer
+	encoder := json.NewEncoder(&lineBuffer)
+	encoder.SetEscapeHTML(false)
+
+	for i, obj := range objects {
+		lineBuffer.Reset()
+		if err := encoder.Encode(obj); err != nil {
+			return nil, fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+		line := lineBuffer.Bytes()
+
+		// Flush to learn the exact compressed size only when the cheap upper bound says the line
+		// might not fit
+		if gzWriter != nil && buf.Len()+gzipBound(pending+len(line)) > maxCompressedBytes {
+			if err := gzWriter.Flush(); err != nil {
+				return nil, fmt.Errorf("failed to compress data: %w", err)
+			}
+			pending = 0
+			if buf.Len()+gzipBound(len(line)) > maxCompressedBytes {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if gzWriter == nil {
+			buf.Reset()
+			var err error
+			if gzWriter, err = gzip.NewWriterLevel(&buf, level); err != nil {
+				return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+			}
+			firstIndex = i
+		}
+		if _, err := gzWriter.Write(line); err != nil {
+			return nil, fmt.Errorf("failed to compress data: %w", err)
+		}
+		lines++
+		pending += len(line)
+	}
+
+	if gzWriter != nil && lines > 0 {
+		if err := finish(); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
@@ -0,0 +1,71 @@
+package streamloader
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestObjectsToCompressedJsonLinesBatches(t *testing.T) {
+	loader := StreamLoader{}
+	rng := rand.New(rand.NewSource(1))
+	objects := make([]interface{}, 2000)
+	for i := range objects {
+		// Random payloads compress poorly, so the data spans several batches
+		objects[i] = map[string]interface{}{"id": float64(i), "payload": fmt.Sprintf("%x", rng.Int63())}
+	}
+
+	tests := []struct {
+		name  string
+		max   int
+		level []int
+	}{
+		{"small limit", 4096, nil},
+		{"medium limit", 20000, nil},
+		{"no compression", 8192, []int{0}},
+		{"best compression", 4096, []int{9}},
+		{"single batch", 1 << 20, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := loader.ObjectsToCompressedJsonLinesBatches(objects, tt.max, tt.level...)
+			if err != nil {
+				t.Fatalf("ObjectsToCompressedJsonLinesBatches() error = %v", err)
+			}
+			var decoded []interface{}
+			for i, batch := range batches {
+				raw, _ := base64.StdEncoding.DecodeString(batch)
+				if len(raw) > tt.max {
+					t.Errorf("batch %d is %d bytes, limit %d", i, len(raw), tt.max)
+				}
+				objs, err := loader.CompressedJsonLinesToObjects(batch)
+				if err != nil {
+					t.Fatalf("batch %d: %v", i, err)
+				}
+				decoded = append(decoded, objs...)
+			}
+			if !reflect.DeepEqual(decoded, objects) {
+				t.Errorf("decoded %d objects, want %d", len(decoded), len(objects))
+			}
+			if tt.max == 1<<20 && len(batches) != 1 {
+				t.Errorf("got %d batches, want 1", len(batches))
+			}
+			if tt.max == 4096 && len(batches) < 5 {
+				t.Errorf("got %d batches, want more", len(batches))
+			}
+		})
+	}
+
+	if batches, err := loader.ObjectsToCompressedJsonLinesBatches(nil, 1024); err != nil || len(batches) != 0 {
+		t.Errorf("empty input = %v, %v", batches, err)
+	}
+	if _, err := loader.ObjectsToCompressedJsonLinesBatches(objects, 0); err == nil {
+		t.Error("Expected error for a non-positive limit")
+	}
+	huge := []interface{}{map[string]interface{}{"payload": fmt.Sprintf("%x%x%x", rng.Int63(), rng.Int63(), rng.Int63())}}
+	if _, err := loader.ObjectsToCompressedJsonLinesBatches(huge, 30); err == nil {
+		t.Error("Expected error for an object larger than the limit")
+	}
+}
@@ -542,3 +542,83 @@ func TestCompressedRoundtripFunctionality(t *testing.T) {
 func base64Decoder(encoded string) io.Reader {
 	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(encoded))
 }
+
+func TestObjectsToCompressedJsonLinesBuffer(t *testing.T) {
+	loader := StreamLoader{}
+	objects := []interface{}{
+		map[string]interface{}{"id": 1, "name": "<Alice>"},
+		map[string]interface{}{"id": 2, "tags": []interface{}{"a", "b"}},
+	}
+
+	result, err := loader.ObjectsToCompressedJsonLinesBuffer(objects, 9)
+	if err != nil {
+		t.Fatalf("ObjectsToCompressedJsonLinesBuffer() error = %v", err)
+	}
+	// Outside a VU the bytes are returned as they are
+	data, ok := result.([]byte)
+	if !ok {
+		t.Fatalf("ObjectsToCompressedJsonLinesBuffer() returned %T", result)
+	}
+	encoded, _ := loader.ObjectsToCompressedJsonLines(objects, 9)
+	if base64.StdEncoding.EncodeToString(data) != encoded {
+		t.Error("buffer differs from the base64-encoded result")
+	}
+
+	gzReader, err := gzip.NewReader(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	decompressed, _ := io.ReadAll(gzReader)
+	jsonLines, _ := loader.ObjectsToJsonLines(objects)
+	if string(decompressed) != jsonLines {
+		t.Errorf("decompressed = %q, want %q", decompressed, jsonLines)
+	}
+
+	if _, err := loader.ObjectsToCompressedJsonLinesBuffer([]interface{}{func() {}}); err == nil {
+		t.Error("Expected error for unencodable object")
+	}
+}
+
+func TestWriteCompressedObjectsToJsonArrayFileGzipOutput(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	objects := []interface{}{
+		map[string]interface{}{"id": 1, "html": "<b>"},
+		map[string]interface{}{"id": 2},
+	}
+	expected := `[{"html":"<b>","id":1},{"id":2}]`
+
+	plainPath := filepath.Join(tempDir, "out.json")
+	if count, err := loader.WriteCompressedObjectsToJsonArrayFile(objects, plainPath); err != nil || count != 2 {
+		t.Fatalf("WriteCompressedObjectsToJsonArrayFile() = %d, %v", count, err)
+	}
+	plain, _ := os.ReadFile(plainPath)
+	if string(plain) != expected {
+		t.Errorf("plain file = %s, want %s", plain, expected)
+	}
+
+	gzPath := filepath.Join(tempDir, "out.json.gz")
+	if count, err := loader.WriteCompressedObjectsToJsonArrayFile(objects, gzPath, gzip.BestCompression); err != nil || count != 2 {
+		t.Fatalf("WriteCompressedObjectsToJsonArrayFile() = %d, %v", count, err)
+	}
+	file, _ := os.Open(gzPath)
+	defer file.Close()
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("output is not gzip-compressed: %v", err)
+	}
+	decompressed, _ := io.ReadAll(gzReader)
+	if string(decompressed) != expected {
+		t.Errorf("decompressed file = %s, want %s", decompressed, expected)
+	}
+
+	// Writer options override the extension and apply to the objects
+	optionsPath := filepath.Join(tempDir, "options.json.gz")
+	options := map[string]interface{}{"compressOutput": "none", "sortKeys": true}
+	if count, err := loader.WriteCompressedObjectsToJsonArrayFile(objects, optionsPath, options); err != nil || count != 2 {
+		t.Fatalf("WriteCompressedObjectsToJsonArrayFile(options) = %d, %v", count, err)
+	}
+	if data, _ := os.ReadFile(optionsPath); string(data) != expected {
+		t.Errorf("file written with options = %s, want %s", data, expected)
+	}
+}
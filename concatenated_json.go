@@ -0,0 +1,71 @@
+// concatenated_json.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LoadConcatenatedJSON loads a file of concatenated top-level JSON values, such as
+// {"a":1}{"a":2} [3] "x", regardless of whether they are separated by newlines, spaces or
+// nothing at all. Some exports are a single multi-gigabyte line of objects without newlines,
+// which line-based NDJSON parsing cannot split; this tokenizer reads one value at a time and
+// never needs the whole line in memory.
+//
+// The values may be of any JSON type. Options are the same as for LoadJSON; with allowPartial,
+// the values before one that fails to decode are returned.
+//
+// Returns: An array with the decoded values in file order
+//
+// Example usage:
+//
+//	records, err := streamloader.LoadConcatenatedJSON("export.json")
+func (s StreamLoader) LoadConcatenatedJSON(filePath string, options ...JsonOptions) ([]any, error) {
+	var opts JsonOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	pipeline, err := newRecordPipeline(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, readBufferSize())
+	dec := json.NewDecoder(newLineNormalizer(reader, !opts.PreserveLineEndings, false))
+
+	values := make([]any, 0)
+	for {
+		var value any
+		if opts.DetectDuplicateKeys {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return partialOrError(opts.AllowPartial, "loadConcatenatedJSON", filePath, values, fmt.Errorf("failed to decode value %d: %w", len(values), err))
+			}
+			if err := checkDuplicateKeys(raw, fmt.Sprintf("$[%d]", len(values))); err != nil {
+				return nil, err
+			}
+			json.Unmarshal(raw, &value)
+		} else if err := dec.Decode(&value); err == io.EOF {
+			break
+		} else if err != nil {
+			return partialOrError(opts.AllowPartial, "loadConcatenatedJSON", filePath, values, fmt.Errorf("failed to decode value %d: %w", len(values), err))
+		}
+		if keep, err := pipeline.apply(value); err != nil {
+			return nil, fmt.Errorf("value %d: %w", len(values), err)
+		} else if !keep {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
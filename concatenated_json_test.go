@@ -0,0 +1,73 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConcatenatedJSON(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []any
+	}{
+		{"no separators", `{"a":1}{"a":2}{"a":3}`, []any{
+			map[string]any{"a": 1.0}, map[string]any{"a": 2.0}, map[string]any{"a": 3.0},
+		}},
+		{"mixed separators and types", "\xEF\xBB\xBF{\"a\":1}\n [2,3]\r\n\"x\" 4 null", []any{
+			map[string]any{"a": 1.0}, []any{2.0, 3.0}, "x", 4.0, nil,
+		}},
+		{"empty file", "  \n", []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "data.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write input: %v", err)
+			}
+			values, err := loader.LoadConcatenatedJSON(path)
+			if err != nil {
+				t.Fatalf("LoadConcatenatedJSON() error = %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("got %#v, want %#v", values, tt.expected)
+			}
+		})
+	}
+
+	t.Run("single line larger than the scanner limit", func(t *testing.T) {
+		path := filepath.Join(dir, "huge.json")
+		record := `{"payload":"` + strings.Repeat("x", 1000) + `"}`
+		if err := os.WriteFile(path, []byte(strings.Repeat(record, 200)), 0644); err != nil {
+			t.Fatalf("failed to write input: %v", err)
+		}
+		values, err := loader.LoadConcatenatedJSON(path)
+		if err != nil {
+			t.Fatalf("LoadConcatenatedJSON() error = %v", err)
+		}
+		if len(values) != 200 {
+			t.Errorf("got %d values, want 200", len(values))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		path := filepath.Join(dir, "bad.json")
+		os.WriteFile(path, []byte(`{"a":1}{"a":`), 0644)
+		if _, err := loader.LoadConcatenatedJSON(path); err == nil {
+			t.Error("Expected error for truncated value")
+		}
+		os.WriteFile(path, []byte(`{"a":1}{"a":1,"a":2}`), 0644)
+		if _, err := loader.LoadConcatenatedJSON(path, JsonOptions{DetectDuplicateKeys: true}); err == nil {
+			t.Error("Expected duplicate key error")
+		}
+		if _, err := loader.LoadConcatenatedJSON(filepath.Join(dir, "missing.json")); err == nil {
+			t.Error("Expected error for missing file")
+		}
+	})
+}
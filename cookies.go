@@ -0,0 +1,298 @@
+// cookies.go
+package streamloader
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CookieExtractOptions names the record fields ExtractCookies reads
+type CookieExtractOptions struct {
+	SessionField         string `json:"sessionField" js:"sessionField"`
+	RequestHeadersField  string `json:"requestHeadersField" js:"requestHeadersField"`
+	ResponseHeadersField string `json:"responseHeadersField" js:"responseHeadersField"`
+	URLField             string `json:"urlField" js:"urlField"`
+	OutputFile           string `json:"outputFile" js:"outputFile"`
+}
+
+// RecordedCookie is a cookie of a session's jar, with the attributes of its last Set-Cookie
+type RecordedCookie struct {
+	Name     string `json:"name" js:"name"`
+	Value    string `json:"value" js:"value"`
+	Domain   string `json:"domain,omitempty" js:"domain"`
+	Path     string `json:"path,omitempty" js:"path"`
+	Expires  string `json:"expires,omitempty" js:"expires"` // RFC 3339
+	MaxAge   int    `json:"maxAge,omitempty" js:"maxAge"`
+	Secure   bool   `json:"secure,omitempty" js:"secure"`
+	HttpOnly bool   `json:"httpOnly,omitempty" js:"httpOnly"`
+	SameSite string `json:"sameSite,omitempty" js:"sameSite"`
+}
+
+// CookieJars is the result of ExtractCookies
+type CookieJars struct {
+	Records  int                         `json:"records" js:"records"`
+	Skipped  int                         `json:"skipped" js:"skipped"` // Records without a session
+	Sessions int                         `json:"sessions" js:"sessions"`
+	Cookies  int                         `json:"cookies" js:"cookies"` // Cookies in all jars
+	Jars     map[string][]RecordedCookie `json:"jars" js:"jars"`
+}
+
+// cookieJar holds the cookies of a session in the order they were first seen.
+type cookieJar struct {
+	cookies []RecordedCookie
+	index   map[string]int // Name, domain and path -> position in cookies
+}
+
+// set adds or replaces a cookie; a Set-Cookie that expires it removes it.
+func (j *cookieJar) set(cookie RecordedCookie, remove bool) {
+	key := cookie.Name + "\x00" + cookie.Domain + "\x00" + cookie.Path
+	i, ok := j.index[key]
+	switch {
+	case remove && ok:
+		j.cookies = append(j.cookies[:i], j.cookies[i+1:]...)
+		delete(j.index, key)
+		for k, pos := range j.index {
+			if pos > i {
+				j.index[k] = pos - 1
+			}
+		}
+	case remove:
+	case ok:
+		j.cookies[i] = cookie
+	default:
+		j.index[key] = len(j.cookies)
+		j.cookies = append(j.cookies, cookie)
+	}
+}
+
+// setValue records a cookie sent in a request's Cookie header, which only carries its name and
+// value: the value of a cookie of that name already in the jar is updated, keeping the
+// attributes of its Set-Cookie.
+func (j *cookieJar) setValue(name string, value string, domain string) {
+	for i := range j.cookies {
+		if j.cookies[i].Name == name {
+			j.cookies[i].Value = value
+			return
+		}
+	}
+	j.set(RecordedCookie{Name: name, Value: value, Domain: domain, Path: "/"}, false)
+}
+
+// headerValues returns the values of a header, matched case-insensitively, in headers given as
+// an object of names to a string or an array of strings, or as an array of {name, value}
+// objects as in HAR files.
+func headerValues(headers interface{}, name string) []string {
+	var values []string
+	add := func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+	}
+	switch h := headers.(type) {
+	case map[string]interface{}:
+		for key, v := range h {
+			if strings.EqualFold(key, name) {
+				add(v)
+			}
+		}
+	case []interface{}:
+		for _, item := range h {
+			header, _ := item.(map[string]interface{})
+			if key, _ := header["name"].(string); strings.EqualFold(key, name) {
+				add(header["value"])
+			}
+		}
+	}
+	return values
+}
+
+// ExtractCookies scans recorded requests and responses and builds the cookie jar of every
+// session, so a replay can start each virtual user with the cookies its recorded session had
+// instead of parsing headers in every test. Records are read in order from a JSON array or
+// NDJSON file, such as the entries of a HAR file with a session field added: the cookies of a
+// request's Cookie header are added to its session's jar, then the response's Set-Cookie
+// headers add or replace cookies with their attributes, or remove them when they expire them
+// with Max-Age=0 or a negative Max-Age. Set-Cookie values holding several cookies separated by
+// newlines are split.
+//
+// Options:
+//   - sessionField: Field (or dotted path) naming the session of a record; records without it
+//     are skipped (default: "sessionId")
+//   - requestHeadersField: Field holding the request headers (default: "request.headers")
+//   - responseHeadersField: Field holding the response headers (default: "response.headers")
+//   - urlField: Field holding the request URL, whose host is the domain of cookies without a
+//     Domain attribute (default: "request.url")
+//   - outputFile: Also write the jars as a JSON object of session to cookies
+//
+// Headers are an object of names to a string or an array of strings, or an array of
+// {name, value} objects.
+//
+// Returns: The number of records read and skipped, of sessions and of cookies, and the jar of
+// every session: its cookies with name, value, domain, path, expires, maxAge, secure, httpOnly
+// and sameSite, in the order they were first seen
+//
+// Example usage:
+//
+//	const { jars } = streamloader.extractCookies("recording.json", { sessionField: "session" });
+//	// In the default function:
+//	const jar = http.cookieJar();
+//	for (const c of jars[session] ?? []) {
+//		jar.set(`https://${c.domain}`, c.name, c.value, { path: c.path, secure: c.secure, http_only: c.httpOnly });
+//	}
+func (s StreamLoader) ExtractCookies(datasetFile string, options ...CookieExtractOptions) (*CookieJars, error) {
+	var opts CookieExtractOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.SessionField == "" {
+		opts.SessionField = "sessionId"
+	}
+	if opts.RequestHeadersField == "" {
+		opts.RequestHeadersField = "request.headers"
+	}
+	if opts.ResponseHeadersField == "" {
+		opts.ResponseHeadersField = "response.headers"
+	}
+	if opts.URLField == "" {
+		opts.URLField = "request.url"
+	}
+
+	result := &CookieJars{Jars: make(map[string][]RecordedCookie)}
+	jars := make(map[string]*cookieJar)
+	err := forEachJsonRecord(datasetFile, s.call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", result.Records, err)
+		}
+		result.Records++
+		session, found := lookupField(record, opts.SessionField)
+		if !found || session == nil {
+			result.Skipped++
+			return true, nil
+		}
+		key := keyString(session)
+		jar := jars[key]
+		if jar == nil {
+			jar = &cookieJar{index: make(map[string]int)}
+			jars[key] = jar
+		}
+
+		domain := ""
+		if target, ok := lookupField(record, opts.URLField); ok {
+			if u, err := url.Parse(fmt.Sprint(target)); err == nil {
+				domain = u.Hostname()
+			}
+		}
+
+		if headers, ok := lookupField(record, opts.RequestHeadersField); ok {
+			for _, line := range headerValues(headers, "Cookie") {
+				cookies, err := http.ParseCookie(line)
+				if err != nil {
+					// Keep the pairs that parse, as browsers do
+					cookies = parseCookiePairs(line)
+				}
+				for _, c := range cookies {
+					jar.setValue(c.Name, c.Value, domain)
+				}
+			}
+		}
+		if headers, ok := lookupField(record, opts.ResponseHeadersField); ok {
+			for _, value := range headerValues(headers, "Set-Cookie") {
+				for _, line := range strings.Split(value, "\n") {
+					c, err := http.ParseSetCookie(strings.TrimSpace(line))
+					if err != nil {
+						continue
+					}
+					cookie := RecordedCookie{
+						Name:     c.Name,
+						Value:    c.Value,
+						Domain:   strings.TrimPrefix(c.Domain, "."),
+						Path:     c.Path,
+						MaxAge:   max(c.MaxAge, 0),
+						Secure:   c.Secure,
+						HttpOnly: c.HttpOnly,
+						SameSite: sameSiteName(c.SameSite),
+					}
+					if cookie.Domain == "" {
+						cookie.Domain = domain
+					}
+					if cookie.Path == "" {
+						cookie.Path = "/"
+					}
+					if !c.Expires.IsZero() {
+						cookie.Expires = c.Expires.UTC().Format(time.RFC3339)
+					}
+					jar.set(cookie, c.MaxAge < 0)
+				}
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, jar := range jars {
+		result.Jars[key] = append([]RecordedCookie{}, jar.cookies...)
+		result.Cookies += len(jar.cookies)
+	}
+	result.Sessions = len(jars)
+
+	if opts.OutputFile != "" {
+		encoded, err := json.Marshal(result.Jars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cookie jars: %w", err)
+		}
+		file, err := createOutputFile(opts.OutputFile, JsonWriterOptions{}, gzip.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if _, err := file.Write(encoded); err != nil {
+			return nil, fmt.Errorf("failed to write cookie jars: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// parseCookiePairs splits a Cookie header into its name=value pairs, skipping those without a
+// valid name.
+func parseCookiePairs(line string) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, pair := range strings.Split(line, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || strings.ContainsAny(name, " \t\"(),/:<=>?@[\\]{}") {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: name, Value: strings.Trim(value, `"`)})
+	}
+	return cookies
+}
+
+// sameSiteName returns the SameSite attribute as it is written in a Set-Cookie header.
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractCookies(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	recording := filepath.Join(dir, "recording.ndjson")
+	os.WriteFile(recording, []byte(strings.Join([]string{
+		// HAR-style headers
+		`{"sessionId":"s1","request":{"url":"https://shop.example.com/login","headers":[{"name":"cookie","value":"consent=yes; ab=B"}]},` +
+			`"response":{"headers":[{"name":"Set-Cookie","value":"sid=abc; Path=/; Domain=.example.com; Secure; HttpOnly; SameSite=Lax"},` +
+			`{"name":"set-cookie","value":"cart=1; Max-Age=3600\ntheme=dark; Expires=Wed, 21 Oct 2026 07:28:00 GMT"}]}}`,
+		`{"sessionId":"s1","request":{"url":"https://shop.example.com/cart","headers":[{"name":"Cookie","value":"sid=abc; ab=C"}]},` +
+			`"response":{"headers":[{"name":"Set-Cookie","value":"cart=; Max-Age=0"}]}}`,
+		// Header objects, a numeric session, and a record without a session
+		`{"sessionId":7,"request":{"url":"http://localhost:8080/","headers":{"Cookie":"a=1"}},"response":{"headers":{"Set-Cookie":["b=2","bad cookie"]}}}`,
+		`{"request":{"url":"https://shop.example.com/","headers":{"Cookie":"x=1"}}}`,
+	}, "\n")), 0644)
+
+	output := filepath.Join(dir, "jars.json")
+	result, err := loader.ExtractCookies(recording, CookieExtractOptions{OutputFile: output})
+	if err != nil {
+		t.Fatalf("ExtractCookies() error = %v", err)
+	}
+	if result.Records != 4 || result.Skipped != 1 || result.Sessions != 2 || result.Cookies != 6 {
+		t.Errorf("ExtractCookies() = %d records, %d skipped, %d sessions, %d cookies, want 4, 1, 2, 6",
+			result.Records, result.Skipped, result.Sessions, result.Cookies)
+	}
+	want := map[string][]RecordedCookie{
+		"s1": {
+			{Name: "consent", Value: "yes", Domain: "shop.example.com", Path: "/"},
+			{Name: "ab", Value: "C", Domain: "shop.example.com", Path: "/"},
+			{Name: "sid", Value: "abc", Domain: "example.com", Path: "/", Secure: true, HttpOnly: true, SameSite: "Lax"},
+			{Name: "theme", Value: "dark", Domain: "shop.example.com", Path: "/", Expires: "2026-10-21T07:28:00Z"},
+		},
+		"7": {
+			{Name: "a", Value: "1", Domain: "localhost", Path: "/"},
+			{Name: "b", Value: "2", Domain: "localhost", Path: "/"},
+		},
+	}
+	if !reflect.DeepEqual(result.Jars, want) {
+		t.Errorf("ExtractCookies() jars = %+v, want %+v", result.Jars, want)
+	}
+
+	var written map[string][]RecordedCookie
+	data, _ := os.ReadFile(output)
+	if err := json.Unmarshal(data, &written); err != nil || !reflect.DeepEqual(written, want) {
+		t.Errorf("output file = %s (%v)", data, err)
+	}
+
+	// Other fields
+	custom := filepath.Join(dir, "custom.json")
+	os.WriteFile(custom, []byte(`[{"user":{"id":"u1"},"reqHeaders":{"Cookie":"k=v"},"respHeaders":{"Set-Cookie":"m=n; Path=/api"},"target":"https://api.example.com/x"}]`), 0644)
+	result, err = loader.ExtractCookies(custom, CookieExtractOptions{SessionField: "user.id", RequestHeadersField: "reqHeaders", ResponseHeadersField: "respHeaders", URLField: "target"})
+	if err != nil {
+		t.Fatalf("ExtractCookies(custom) error = %v", err)
+	}
+	if jar := result.Jars["u1"]; len(jar) != 2 || jar[1] != (RecordedCookie{Name: "m", Value: "n", Domain: "api.example.com", Path: "/api"}) {
+		t.Errorf("ExtractCookies(custom) = %+v", result.Jars)
+	}
+
+	if _, err := loader.ExtractCookies(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("ExtractCookies(missing) expected an error")
+	}
+}
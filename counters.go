@@ -0,0 +1,220 @@
+// counters.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// CountLines counts the lines in a file by scanning raw bytes for newline characters,
+// without decoding or storing any line content. "\r\n" and lone "\r" line endings count as
+// one line break each, as the loaders read them. A final line without a trailing newline
+// is counted as well.
+//
+// Example usage:
+//
+//	lines, err := streamloader.CountLines("data.ndjson")
+func (s StreamLoader) CountLines(filePath string) (int, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	reader := newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true)
+
+	count := 0
+	var last byte
+	buf := make([]byte, readBufferSize())
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			count += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if last != 0 && last != '\n' {
+		count++
+	}
+	return count, nil
+}
+
+// CountJsonArrayElements counts the elements of a top-level JSON array without parsing them.
+// It scans the bytes once, tracking string and nesting state, and counts the separators at
+// the top level of the array. The elements themselves are not validated.
+//
+// Example usage:
+//
+//	records, err := streamloader.CountJsonArrayElements("samples.json")
+func (s StreamLoader) CountJsonArrayElements(filePath string) (int, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	depth := 0
+	inString := false
+	escaped := false
+	started := false // Opening '[' has been seen
+	closed := false  // Closing ']' has been seen
+	hasElement := false
+	count := 0
+
+	buf := make([]byte, readBufferSize())
+	for !closed {
+		n, err := file.Read(buf)
+		for _, c := range buf[:n] {
+			if closed {
+				break
+			}
+			if inString {
+				if escaped {
+					escaped = false
+				} else if c == '\\' {
+					escaped = true
+				} else if c == '"' {
+					inString = false
+				}
+				continue
+			}
+			if isWhitespace(c) {
+				continue
+			}
+			if !started {
+				if c != '[' {
+					return 0, fmt.Errorf("expected JSON array, got %q", c)
+				}
+				started = true
+				depth = 1
+				continue
+			}
+
+			switch c {
+			case '"':
+				inString = true
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					closed = true
+					continue
+				}
+			case ',':
+				if depth == 1 {
+					count++
+				}
+				continue
+			}
+			if depth >= 1 {
+				hasElement = true
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if !started {
+		return 0, fmt.Errorf("expected JSON array, got empty file")
+	}
+	if !closed {
+		return 0, fmt.Errorf("unexpected end of JSON array")
+	}
+	if hasElement {
+		count++
+	}
+	return count, nil
+}
+
+// CountCsvRows counts the records in a CSV file without parsing fields. Newlines inside
+// quoted fields do not start a new record, empty lines are skipped, lone "\r" line endings
+// end a record and a quote after leading spaces starts a quoted field, matching LoadCSV with
+// its default options.
+// The header row, if any, is included in the count.
+//
+// Example usage:
+//
+//	rows, err := streamloader.CountCsvRows("data.csv")
+//	dataRows := rows - 1 // Excluding the header
+func (s StreamLoader) CountCsvRows(filePath string) (int, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	reader := newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true)
+
+	count := 0
+	inQuotes := false
+	quotePending := false // A quote was seen inside a quoted field; it may be an escaped quote
+	fieldStart := true
+	rowHasData := false
+
+	buf := make([]byte, readBufferSize())
+	for {
+		n, err := reader.Read(buf)
+		for _, c := range buf[:n] {
+			if quotePending {
+				quotePending = false
+				if c == '"' {
+					continue // Escaped quote, still inside the field
+				}
+				inQuotes = false
+			}
+			if inQuotes {
+				if c == '"' {
+					quotePending = true
+				}
+				continue
+			}
+
+			switch c {
+			case '\n':
+				if rowHasData {
+					count++
+				}
+				rowHasData = false
+				fieldStart = true
+			case ' ', '\t', '\v', '\f':
+				// Leading spaces are trimmed, so a quote after them still opens a quoted field
+				rowHasData = true
+			case ',':
+				rowHasData = true
+				fieldStart = true
+			case '"':
+				rowHasData = true
+				if fieldStart {
+					inQuotes = true
+				}
+				fieldStart = false
+			default:
+				rowHasData = true
+				fieldStart = false
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if rowHasData {
+		count++
+	}
+	return count, nil
+}
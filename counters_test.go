@@ -0,0 +1,169 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCountLines(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"Empty file", "", 0},
+		{"Single line without newline", "hello", 1},
+		{"Single line with newline", "hello\n", 1},
+		{"Multiple lines", "a\nb\nc\n", 3},
+		{"Trailing line without newline", "a\nb\nc", 3},
+		{"Blank lines are counted", "a\n\n\nb\n", 4},
+		{"CRLF line endings", "a\r\nb\r\n", 2},
+		{"Lone CR line endings", "a\rb\rc", 3},
+		{"Mixed line endings", "a\r\nb\rc\nd", 4},
+		{"Blank lines between CRs", "a\r\rb\r", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, "lines.txt")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			got, err := loader.CountLines(path)
+			if err != nil {
+				t.Fatalf("CountLines() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("CountLines() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := loader.CountLines("no_such_file.txt"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestCountJsonArrayElements(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    int
+		expectError bool
+	}{
+		{name: "Empty array", content: "[]", expected: 0},
+		{name: "Whitespace only array", content: " [ \n ] ", expected: 0},
+		{name: "Scalars", content: "[1, 2, 3]", expected: 3},
+		{name: "Nested values", content: `[{"a":[1,2,{"b":3}]},[4,5],"x,y"]`, expected: 3},
+		{name: "Strings with brackets and escapes", content: `["[\"],{", "\\", "}"]`, expected: 3},
+		{name: "Not an array", content: `{"a":1}`, expectError: true},
+		{name: "Truncated array", content: `[{"a":1},`, expectError: true},
+		{name: "Empty file", content: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, "array.json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			got, err := loader.CountJsonArrayElements(path)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("CountJsonArrayElements() error = %v, expectError %v", err, tt.expectError)
+			}
+			if !tt.expectError && got != tt.expected {
+				t.Errorf("CountJsonArrayElements() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountJsonArrayElements_MatchesLoadJSON(t *testing.T) {
+	loader := StreamLoader{}
+
+	for _, path := range []string{"testdata/samples.json", "testdata/complex.json", "testdata/mixedtypes.json"} {
+		loaded, err := loader.LoadJSON(path)
+		if err != nil {
+			t.Fatalf("LoadJSON(%s) failed: %v", path, err)
+		}
+		arr, ok := loaded.([]interface{})
+		if !ok {
+			continue
+		}
+
+		count, err := loader.CountJsonArrayElements(path)
+		if err != nil {
+			t.Fatalf("CountJsonArrayElements(%s) failed: %v", path, err)
+		}
+		if count != len(arr) {
+			t.Errorf("%s: CountJsonArrayElements() = %d, LoadJSON returned %d", path, count, len(arr))
+		}
+	}
+}
+
+func TestCountCsvRows(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{"Empty file", "", 0},
+		{"Header only", "id,name\n", 1},
+		{"Rows without trailing newline", "id,name\n1,a\n2,b", 3},
+		{"Quoted newlines", "id,text\n1,\"line1\nline2\"\n2,\"x\"\n", 3},
+		{"Escaped quotes", "id,text\n1,\"say \"\"hi\"\"\nthere\"\n", 2},
+		{"Blank lines skipped", "id\n\n1\n\r\n2\n", 3},
+		{"Quote inside unquoted field", "id,text\n1,a\"b\n2,c\n", 3},
+		{"Lone CR line endings", "id\r1\r2", 3},
+		{"Mixed line endings", "id\r\n1\r2\n3\r\n", 4},
+		{"Quote after leading spaces", "id,text\n1,  \"a\nb\"\n2,\t\"c,\rd\"\n", 3},
+		{"Quote after text and spaces", "id,text\n1,a \"b\n2,c\n", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, "rows.csv")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			got, err := loader.CountCsvRows(path)
+			if err != nil {
+				t.Fatalf("CountCsvRows() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("CountCsvRows() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountCsvRows_MatchesLoadCSV(t *testing.T) {
+	loader := StreamLoader{}
+
+	mixed := filepath.Join(t.TempDir(), "mixed.csv")
+	content := "id,text\r\n1, \"a\rb\"\r2,\"c\r\nd\"\n\r\n3,  \"e,\"\"f\"\"\"\r4,g \"h\"\n"
+	if err := os.WriteFile(mixed, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"testdata/basic.csv", "testdata/quoted.csv", "testdata/specialchars.csv", "testdata/all_quoted.csv", mixed} {
+		records, err := loader.LoadCSV(path)
+		if err != nil {
+			t.Fatalf("LoadCSV(%s) failed: %v", path, err)
+		}
+		count, err := loader.CountCsvRows(path)
+		if err != nil {
+			t.Fatalf("CountCsvRows(%s) failed: %v", path, err)
+		}
+		if count != len(records) {
+			t.Errorf("%s: CountCsvRows() = %d, LoadCSV returned %d", path, count, len(records))
+		}
+	}
+}
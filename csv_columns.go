@@ -0,0 +1,224 @@
+// csv_columns.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CsvColumnSchema describes a column to extract with LoadCSVColumns
+type CsvColumnSchema struct {
+	Name   string `json:"name" js:"name"`
+	Column *int   `json:"column,omitempty" js:"column"`
+	Type   string `json:"type" js:"type"`
+}
+
+// LoadCSVColumns streams a CSV file into column-major typed arrays instead of the row-major
+// [][]string returned by LoadCSV. Only the columns listed in the schema are kept, and numeric
+// columns are stored as float64 values, which uses considerably less memory than one string
+// per cell for numeric-heavy datasets.
+//
+// The first row is treated as the header. Each schema entry selects a column by header name,
+// or by zero-based index when column is set, and stores it under name in the result.
+//
+// Column types:
+// - "string": []string (default)
+// - "number": []float64, empty cells become NaN
+// - "int": []int64
+// - "bool": []bool, parsed with strconv.ParseBool
+// - "float64": Float64Array, empty cells become NaN
+// - "int32": Int32Array
+//
+// The typed arrays hold their numbers in one block of memory instead of one JavaScript value
+// per cell, which keeps large numeric columns, such as pacing tables, out of the garbage
+// collector's way.
+//
+// Example usage:
+//
+//	columns, err := streamloader.LoadCSVColumns("metrics.csv", []CsvColumnSchema{
+//		{Name: "id", Type: "string"},
+//		{Name: "latency", Type: "number"},
+//	})
+//	// columns["latency"] is a []float64 with one value per data row
+func (s StreamLoader) LoadCSVColumns(filePath string, schema []CsvColumnSchema) (map[string]interface{}, error) {
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("schema must contain at least one column")
+	}
+
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(bufio.NewReaderSize(file, readBufferSize()))
+	csvReader.TrimLeadingSpace = true
+	csvReader.LazyQuotes = true
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV header: %w", err)
+	}
+
+	// Resolve column indexes and allocate typed vectors
+	indexes := make([]int, len(schema))
+	vectors := make([]interface{}, len(schema))
+	for i, col := range schema {
+		if col.Name == "" {
+			return nil, fmt.Errorf("schema column %d has no name", i)
+		}
+		if col.Column != nil {
+			if *col.Column < 0 {
+				return nil, fmt.Errorf("schema column %q has negative index %d", col.Name, *col.Column)
+			}
+			indexes[i] = *col.Column
+		} else {
+			indexes[i] = -1
+			for j, name := range header {
+				if strings.TrimSpace(name) == col.Name {
+					indexes[i] = j
+					break
+				}
+			}
+			if indexes[i] < 0 {
+				return nil, fmt.Errorf("column %q not found in CSV header", col.Name)
+			}
+		}
+
+		if vectors[i], err = newColumnVector(col); err != nil {
+			return nil, err
+		}
+	}
+
+	line := 1
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV at line %d: %w", line, err)
+		}
+
+		for i, col := range schema {
+			cell := ""
+			if indexes[i] < len(record) {
+				cell = record[indexes[i]]
+			}
+			if vectors[i], err = appendColumnCell(vectors[i], cell, col.Name, fmt.Sprintf("line %d", line)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.columnResult(schema, vectors), nil
+}
+
+// newColumnVector returns the empty vector of a column's type.
+func newColumnVector(col CsvColumnSchema) (interface{}, error) {
+	switch col.Type {
+	case "", "string":
+		return []string{}, nil
+	case "number", "float64":
+		return []float64{}, nil
+	case "int":
+		return []int64{}, nil
+	case "int32":
+		return []int32{}, nil
+	case "bool":
+		return []bool{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q for column %q", col.Type, col.Name)
+	}
+}
+
+// appendColumnCell parses a cell for the vector of a column and appends it. where locates the
+// cell in errors.
+func appendColumnCell(vector interface{}, cell string, column string, where string) (interface{}, error) {
+	switch v := vector.(type) {
+	case []string:
+		return append(v, cell), nil
+	case []float64:
+		num := math.NaN()
+		if trimmed := strings.TrimSpace(cell); trimmed != "" {
+			var err error
+			if num, err = strconv.ParseFloat(trimmed, 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q in column %q at %s", cell, column, where)
+			}
+		}
+		return append(v, num), nil
+	case []int64:
+		num, err := strconv.ParseInt(strings.TrimSpace(cell), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q in column %q at %s", cell, column, where)
+		}
+		return append(v, num), nil
+	case []int32:
+		num, err := strconv.ParseInt(strings.TrimSpace(cell), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 32-bit integer %q in column %q at %s", cell, column, where)
+		}
+		return append(v, int32(num)), nil
+	case []bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(cell))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q in column %q at %s", cell, column, where)
+		}
+		return append(v, b), nil
+	}
+	return vector, nil
+}
+
+// columnResult maps each column name to its vector, turning the float64 and int32 columns into
+// typed arrays.
+func (s StreamLoader) columnResult(schema []CsvColumnSchema, vectors []interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema))
+	for i, col := range schema {
+		switch col.Type {
+		case "float64":
+			values := vectors[i].([]float64)
+			data := make([]byte, 8*len(values))
+			for j, f := range values {
+				binary.LittleEndian.PutUint64(data[8*j:], math.Float64bits(f))
+			}
+			result[col.Name] = s.newTypedArray("Float64Array", data, values)
+		case "int32":
+			values := vectors[i].([]int32)
+			data := make([]byte, 4*len(values))
+			for j, n := range values {
+				binary.LittleEndian.PutUint32(data[4*j:], uint32(n))
+			}
+			result[col.Name] = s.newTypedArray("Int32Array", data, values)
+		default:
+			result[col.Name] = vectors[i]
+		}
+	}
+	return result
+}
+
+// newTypedArray wraps data, in little-endian order as on every platform k6 runs on, in a
+// JavaScript typed array made with the constructor ctor. Outside a VU, as in Go tests, values
+// are returned instead.
+func (s StreamLoader) newTypedArray(ctor string, data []byte, values interface{}) interface{} {
+	if s.vu == nil {
+		return values
+	}
+	rt := s.vu.Runtime()
+	array, err := rt.New(rt.Get(ctor), rt.ToValue(rt.NewArrayBuffer(data)))
+	if err != nil {
+		return values
+	}
+	return array
+}
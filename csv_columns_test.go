@@ -0,0 +1,138 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestLoadCSVColumns(t *testing.T) {
+	loader := StreamLoader{}
+	csvPath := filepath.Join(t.TempDir(), "metrics.csv")
+
+	csvContent := `id,latency,count,ok,label
+a,1.5,10,true,first
+b,,20,false,"second, quoted"
+c,3e2,30,1,third`
+
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+
+	labelIndex := 4
+	columns, err := loader.LoadCSVColumns(csvPath, []CsvColumnSchema{
+		{Name: "id"},
+		{Name: "latency", Type: "number"},
+		{Name: "count", Type: "int"},
+		{Name: "ok", Type: "bool"},
+		{Name: "text", Column: &labelIndex, Type: "string"},
+	})
+	if err != nil {
+		t.Fatalf("LoadCSVColumns failed: %v", err)
+	}
+
+	if got := columns["id"]; !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Unexpected id column: %v", got)
+	}
+	if got := columns["count"]; !reflect.DeepEqual(got, []int64{10, 20, 30}) {
+		t.Errorf("Unexpected count column: %v", got)
+	}
+	if got := columns["ok"]; !reflect.DeepEqual(got, []bool{true, false, true}) {
+		t.Errorf("Unexpected ok column: %v", got)
+	}
+	if got := columns["text"]; !reflect.DeepEqual(got, []string{"first", "second, quoted", "third"}) {
+		t.Errorf("Unexpected text column: %v", got)
+	}
+
+	latency, ok := columns["latency"].([]float64)
+	if !ok || len(latency) != 3 {
+		t.Fatalf("Expected 3 float64 latency values, got %v", columns["latency"])
+	}
+	if latency[0] != 1.5 || !math.IsNaN(latency[1]) || latency[2] != 300 {
+		t.Errorf("Unexpected latency column: %v", latency)
+	}
+}
+
+func TestLoadCSVColumns_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	csvPath := filepath.Join(tempDir, "data.csv")
+	os.WriteFile(csvPath, []byte("id,value\n1,abc\n"), 0644)
+
+	emptyPath := filepath.Join(tempDir, "empty.csv")
+	os.WriteFile(emptyPath, []byte(""), 0644)
+	negative := -1
+
+	tests := []struct {
+		name   string
+		path   string
+		schema []CsvColumnSchema
+	}{
+		{"Missing file", "no_such_file.csv", []CsvColumnSchema{{Name: "id"}}},
+		{"Empty file", emptyPath, []CsvColumnSchema{{Name: "id"}}},
+		{"Empty schema", csvPath, nil},
+		{"Unknown column", csvPath, []CsvColumnSchema{{Name: "missing"}}},
+		{"Negative column index", csvPath, []CsvColumnSchema{{Name: "value", Column: &negative}}},
+		{"Unsupported type", csvPath, []CsvColumnSchema{{Name: "id", Type: "date"}}},
+		{"Invalid number", csvPath, []CsvColumnSchema{{Name: "value", Type: "number"}}},
+		{"Invalid integer", csvPath, []CsvColumnSchema{{Name: "value", Type: "int"}}},
+		{"Invalid 32-bit integer", csvPath, []CsvColumnSchema{{Name: "value", Type: "int32"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loader.LoadCSVColumns(tt.path, tt.schema); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoadCSVColumns_TypedArrays(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "pacing.csv")
+	os.WriteFile(csvPath, []byte("offset,rate\n0,1.5\n250,\n-500,3\n"), 0644)
+	schema := []CsvColumnSchema{{Name: "offset", Type: "int32"}, {Name: "rate", Type: "float64"}}
+
+	// Outside a VU the Go slices are returned
+	columns, err := StreamLoader{}.LoadCSVColumns(csvPath, schema)
+	if err != nil {
+		t.Fatalf("LoadCSVColumns failed: %v", err)
+	}
+	if got := columns["offset"]; !reflect.DeepEqual(got, []int32{0, 250, -500}) {
+		t.Errorf("Unexpected offset column: %v", got)
+	}
+	rate := columns["rate"].([]float64)
+	if rate[0] != 1.5 || !math.IsNaN(rate[1]) || rate[2] != 3 {
+		t.Errorf("Unexpected rate column: %v", rate)
+	}
+
+	overflow := filepath.Join(t.TempDir(), "overflow.csv")
+	os.WriteFile(overflow, []byte("offset\n2147483648\n"), 0644)
+	if _, err := (StreamLoader{}).LoadCSVColumns(overflow, schema[:1]); err == nil {
+		t.Error("Expected an error for a value out of the int32 range")
+	}
+
+	// Scripts get typed arrays
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	rt.Set("csvPath", csvPath)
+	value, err := rt.RunString(`
+		const cols = streamloader.loadCSVColumns(csvPath, [{ name: "offset", type: "int32" }, { name: "rate", type: "float64" }]);
+		[cols.offset instanceof Int32Array, cols.rate instanceof Float64Array, Array.from(cols.offset).join(","), String(cols.rate[0]), isNaN(cols.rate[1])].join(" ");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != "true true 0,250,-500 1.5 true" {
+		t.Errorf("script result = %s", got)
+	}
+}
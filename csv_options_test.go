@@ -3,7 +3,12 @@ package streamloader
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
 )
 
 func TestTrimLeadingSpaceOption(t *testing.T) {
@@ -243,4 +248,234 @@ func TestTrimSpaceOption(t *testing.T) {
 			t.Errorf("Expected all spaces preserved '   200   ', got '%s'", records[2][2])
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestCsvCommentAndBlankRows(t *testing.T) {
+	loader := StreamLoader{}
+	csvPath := filepath.Join(t.TempDir(), "params.csv")
+
+	csvContent := `# Hand-edited parameter file
+id,name
+1,Alice
+,
+# Disabled: 2,Bob
+
+3,Charlie
+  ,  ,
+`
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+
+	t.Run("LoadCSV defaults keep blank rows and comments", func(t *testing.T) {
+		records, err := loader.LoadCSV(csvPath)
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		if len(records) != 7 {
+			t.Errorf("Expected 7 records, got %d: %q", len(records), records)
+		}
+	})
+
+	t.Run("LoadCSV with comment and skipBlankRows", func(t *testing.T) {
+		records, err := loader.LoadCSV(csvPath, CsvOptions{
+			LazyQuotes:       true,
+			TrimLeadingSpace: true,
+			ReuseRecord:      true,
+			Comment:          "#",
+			SkipBlankRows:    true,
+		})
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		expected := [][]string{{"id", "name"}, {"1", "Alice"}, {"3", "Charlie"}}
+		if !reflect.DeepEqual(records, expected) {
+			t.Errorf("got %q, want %q", records, expected)
+		}
+	})
+
+	t.Run("ProcessCsvFile with comment and skipBlankRows", func(t *testing.T) {
+		result, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+			SkipHeader:    true,
+			Comment:       "#",
+			SkipBlankRows: true,
+		})
+		if err != nil {
+			t.Fatalf("ProcessCsvFile failed: %v", err)
+		}
+		expected := [][]interface{}{{"1", "Alice"}, {"3", "Charlie"}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("got %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("Invalid comment option", func(t *testing.T) {
+		if _, err := loader.LoadCSV(csvPath, CsvOptions{Comment: "//"}); err == nil {
+			t.Error("Expected error for multi-character comment")
+		}
+		if _, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{Comment: "//"}); err == nil {
+			t.Error("Expected error for multi-character comment")
+		}
+	})
+}
+
+func TestCsvExpectHeaders(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	csvPath := filepath.Join(tempDir, "headers.csv")
+	if err := os.WriteFile(csvPath, []byte("ID,Name,Email\n1,Alice,a@example.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		expect          []string
+		match           string
+		caseInsensitive bool
+		wantErr         string
+	}{
+		{"exact match", []string{"ID", "Name", "Email"}, "", false, ""},
+		{"exact case mismatch", []string{"id", "name", "email"}, "exact", false, `missing columns ["id" "name" "email"]`},
+		{"exact case-insensitive", []string{"id", "name", "email"}, "exact", true, ""},
+		{"exact extra column", []string{"ID", "Name"}, "exact", false, `unexpected columns ["Email"]`},
+		{"exact wrong order", []string{"Name", "ID", "Email"}, "exact", false, "different order"},
+		{"subset match", []string{"email", "id"}, "subset", true, ""},
+		{"subset missing column", []string{"ID", "Phone"}, "subset", false, `missing columns ["Phone"]`},
+		{"invalid mode", []string{"ID"}, "prefix", false, "invalid headerMatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, loadErr := loader.LoadCSV(csvPath, CsvOptions{
+				ExpectHeaders:         tt.expect,
+				HeaderMatch:           tt.match,
+				HeaderCaseInsensitive: tt.caseInsensitive,
+			})
+			_, processErr := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+				SkipHeader:            true,
+				ExpectHeaders:         tt.expect,
+				HeaderMatch:           tt.match,
+				HeaderCaseInsensitive: tt.caseInsensitive,
+			})
+			for _, err := range []error{loadErr, processErr} {
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("Unexpected error: %v", err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCsvDelimiters(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		return path
+	}
+	tsvPath := write("data.tsv", "id\tname\tnote\n1\t\tfirst, with comma\n2\tBob\t\n")
+	psvPath := write("data.psv", "id|name\n1|Alice\n")
+	txtPath := write("data.txt", "id\tname\n1\tAlice\n")
+	expectedTsv := [][]string{{"id", "name", "note"}, {"1", "", "first, with comma"}, {"2", "Bob", ""}}
+
+	tests := []struct {
+		name     string
+		load     func() ([][]string, error)
+		expected [][]string
+	}{
+		{"LoadCSV detects .tsv", func() ([][]string, error) { return loader.LoadCSV(tsvPath) }, expectedTsv},
+		{"LoadCSV detects .psv", func() ([][]string, error) { return loader.LoadCSV(psvPath) }, [][]string{{"id", "name"}, {"1", "Alice"}}},
+		{"LoadTSV", func() ([][]string, error) { return loader.LoadTSV(txtPath) }, [][]string{{"id", "name"}, {"1", "Alice"}}},
+		{"LoadPSV", func() ([][]string, error) { return loader.LoadPSV(psvPath) }, [][]string{{"id", "name"}, {"1", "Alice"}}},
+		{"TSV with options keeps empty fields", func() ([][]string, error) {
+			return loader.LoadTSV(tsvPath, CsvOptions{TrimLeadingSpace: true, ReuseRecord: true})
+		}, expectedTsv},
+		{"explicit delimiter wins", func() ([][]string, error) {
+			return loader.LoadCSV(psvPath, CsvOptions{Delimiter: ","})
+		}, [][]string{{"id|name"}, {"1|Alice"}}},
+		{"other extensions stay comma-separated", func() ([][]string, error) { return loader.LoadCSV(txtPath) }, [][]string{{"id\tname"}, {"1\tAlice"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := tt.load()
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.expected) {
+				t.Errorf("got %q, want %q", records, tt.expected)
+			}
+		})
+	}
+
+	t.Run("ProcessCsvFile", func(t *testing.T) {
+		result, err := loader.ProcessCsvFile(tsvPath, ProcessCsvOptions{SkipHeader: true, TrimLeadingSpace: true})
+		if err != nil {
+			t.Fatalf("ProcessCsvFile failed: %v", err)
+		}
+		expected := [][]interface{}{{"1", "", "first, with comma"}, {"2", "Bob", ""}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("got %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("Invalid delimiter", func(t *testing.T) {
+		for _, delimiter := range []string{"\"", "\n", "||"} {
+			if _, err := loader.LoadCSV(psvPath, CsvOptions{Delimiter: delimiter}); err == nil {
+				t.Errorf("Expected error for delimiter %q", delimiter)
+			}
+		}
+	})
+}
+
+func TestLoadCSVScriptOptions(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "params.psv")
+	csvContent := "# Hand-edited\nid|name\n\n1|Alice\n"
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+
+	// Scripts pass plain objects, which must reach the same options as the Go struct
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	rt.Set("csvPath", csvPath)
+	value, err := rt.RunString(`
+		const rows = streamloader.loadCSV(csvPath, { comment: "#", skipBlankRows: true, delimiter: "|", expectHeaders: ["id", "name"] });
+		JSON.stringify(rows);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != `[["id","name"],["1","Alice"]]` {
+		t.Errorf("script result = %s", got)
+	}
+
+	_, err = rt.RunString(`streamloader.loadCSV(csvPath, { comment: "#", skipBlankRows: true, delimiter: "|", expectHeaders: ["id", "email"] })`)
+	if err == nil || !strings.Contains(err.Error(), "email") {
+		t.Errorf("Expected a header mismatch error, got %v", err)
+	}
+
+	// Unset fields keep the LoadCSV defaults
+	records, err := StreamLoader{}.LoadCSV(csvPath, map[string]interface{}{"delimiter": "|"})
+	if err != nil {
+		t.Fatalf("LoadCSV failed: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "# Hand-edited" || records[2][1] != "Alice" {
+		t.Errorf("Unexpected records: %q", records)
+	}
+	if _, err := (StreamLoader{}).LoadCSV(csvPath, map[string]interface{}{"comment": 1}); err == nil {
+		t.Error("Expected an error for an invalid comment option")
+	}
+}
@@ -0,0 +1,377 @@
+// csv_repair.go
+package streamloader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSV repair heuristics
+const (
+	csvRepairNul        = "nul"              // Remove NUL bytes
+	csvRepairStray      = "strayQuotes"      // Keep quotes inside fields as literal quotes
+	csvRepairUnbalanced = "unbalancedQuotes" // Read a quote opening a field that is never closed as a literal quote
+)
+
+// defaultCsvRepairMaxRecordLines is the number of lines a quoted field may span before its
+// opening quote is considered unbalanced
+const defaultCsvRepairMaxRecordLines = 100
+
+// defaultCsvRepairMaxReported is the number of repaired lines listed in CsvRepairResult
+const defaultCsvRepairMaxReported = 100
+
+// CsvRepairOptions configures RepairCsvFile
+type CsvRepairOptions struct {
+	Delimiter      string   `json:"delimiter" js:"delimiter"`
+	Heuristics     []string `json:"heuristics" js:"heuristics"`
+	MaxRecordLines int      `json:"maxRecordLines" js:"maxRecordLines"`
+	MaxReported    int      `json:"maxReported" js:"maxReported"`
+	ReportFile     string   `json:"reportFile" js:"reportFile"`
+}
+
+// CsvRepairResult summarizes a RepairCsvFile run
+type CsvRepairResult struct {
+	Records          int             `json:"records" js:"records"`                   // Records written
+	RepairedRecords  int             `json:"repairedRecords" js:"repairedRecords"`   // Records written with at least one fix
+	DroppedRecords   int             `json:"droppedRecords" js:"droppedRecords"`     // Records needing a disabled heuristic
+	NulBytes         int             `json:"nulBytes" js:"nulBytes"`                 // NUL bytes removed
+	StrayQuotes      int             `json:"strayQuotes" js:"strayQuotes"`           // Fields with quotes kept as literal quotes
+	UnbalancedQuotes int             `json:"unbalancedQuotes" js:"unbalancedQuotes"` // Opening quotes read as literal quotes
+	Lines            []CsvRepairLine `json:"lines" js:"lines"`                       // The first repaired or dropped records
+}
+
+// CsvRepairLine describes a record RepairCsvFile changed or dropped
+type CsvRepairLine struct {
+	Line    int      `json:"line" js:"line"`       // Line of the input the record starts on
+	Fixes   []string `json:"fixes" js:"fixes"`     // Heuristics applied
+	Dropped bool     `json:"dropped" js:"dropped"` // The record needed a disabled heuristic and was not written
+}
+
+// csvRecordParser splits the lines of a damaged CSV file into records, reading lines ahead as
+// quoted fields span them.
+type csvRecordParser struct {
+	reader   *bufio.Reader
+	comma    string
+	pending  []string // Lines read ahead of the current record
+	line     int      // Line number of pending[0]
+	eof      bool
+	maxLines int
+}
+
+// lineAt returns the k-th line ahead, reading it if needed, and false past the end of the file.
+func (p *csvRecordParser) lineAt(k int) (string, bool, error) {
+	for len(p.pending) <= k {
+		if p.eof {
+			return "", false, nil
+		}
+		text, err := p.reader.ReadString('\n')
+		if err == io.EOF {
+			p.eof = true
+			if text == "" {
+				return "", false, nil
+			}
+		} else if err != nil {
+			return "", false, err
+		}
+		p.pending = append(p.pending, strings.TrimSuffix(text, "\n"))
+	}
+	return p.pending[k], true, nil
+}
+
+// parsedCsvRecord is a record split into fields, with the fixes it needed.
+type parsedCsvRecord struct {
+	fields     []string
+	lines      int // Lines spanned
+	nulBytes   int
+	stray      int  // Fields with stray quotes
+	unbalanced bool // An opening quote was read as a literal quote
+	complete   bool // Every quoted field was closed within maxLines lines
+}
+
+// parse splits the record starting at the first pending line. Quoted fields may span lines,
+// unless literalOpen reads the opening quote of a field still open at the end of the first line
+// as part of an unquoted field.
+func (p *csvRecordParser) parse(literalOpen bool) (parsedCsvRecord, error) {
+	rec := parsedCsvRecord{lines: 1, complete: true}
+	s, _, err := p.lineAt(0)
+	if err != nil {
+		return rec, err
+	}
+	pos := 0
+	for {
+		var field strings.Builder
+		start := pos
+		quoted := strings.HasPrefix(s[pos:], `"`)
+		if quoted {
+			pos++
+			strayField := false
+			for {
+				j := strings.IndexByte(s[pos:], '"')
+				if j < 0 {
+					if literalOpen {
+						rec.unbalanced = true
+						quoted = false
+						pos = start
+						field.Reset()
+						break
+					}
+					field.WriteString(s[pos:])
+					next, ok, err := p.lineAt(rec.lines)
+					if err != nil {
+						return rec, err
+					}
+					if !ok || rec.lines >= p.maxLines {
+						rec.complete = false
+						return rec, nil
+					}
+					field.WriteByte('\n')
+					s, pos = next, 0
+					rec.lines++
+					continue
+				}
+				field.WriteString(s[pos : pos+j])
+				pos += j + 1
+				switch {
+				case strings.HasPrefix(s[pos:], `"`):
+					field.WriteByte('"')
+					pos++
+					continue
+				case pos == len(s) || strings.HasPrefix(s[pos:], p.comma):
+				default:
+					// A quote inside the field that doesn't close it
+					field.WriteByte('"')
+					strayField = true
+					continue
+				}
+				break
+			}
+			if quoted && strayField {
+				rec.stray++
+			}
+		}
+		if !quoted {
+			end := strings.Index(s[pos:], p.comma)
+			if end < 0 {
+				end = len(s) - pos
+			}
+			value := s[pos : pos+end]
+			if strings.Contains(value, `"`) && !(rec.unbalanced && pos == start) {
+				rec.stray++
+			}
+			field.WriteString(value)
+			pos += end
+		}
+		rec.fields = append(rec.fields, field.String())
+		if pos >= len(s) {
+			return rec, nil
+		}
+		pos += len(p.comma)
+	}
+}
+
+// next returns the next record and the line it starts on, choosing between reading an open
+// quoted field across lines and reading its opening quote as a literal; columns is the expected
+// number of fields, or 0.
+func (p *csvRecordParser) next(columns int, fixUnbalanced bool) (parsedCsvRecord, int, bool, error) {
+	if _, ok, err := p.lineAt(0); err != nil || !ok {
+		return parsedCsvRecord{}, 0, false, err
+	}
+	line := p.line
+	rec, err := p.parse(false)
+	if err != nil {
+		return rec, 0, false, err
+	}
+	if rec.lines > 1 || !rec.complete {
+		literal, err := p.parse(true)
+		if err != nil {
+			return rec, 0, false, err
+		}
+		// Reading across lines is trusted unless the literal reading fits the columns better
+		fits := columns > 0 && len(literal.fields) == columns
+		if !rec.complete || fits && (len(rec.fields) != columns || rec.stray > literal.stray) {
+			rec = literal
+			if !fixUnbalanced {
+				rec.fields = nil // Dropped
+			}
+		}
+	}
+
+	// Consume the lines of the record, counting the NUL bytes they contained
+	for _, text := range p.pending[:rec.lines] {
+		rec.nulBytes += strings.Count(text, "\x00")
+	}
+	p.pending = p.pending[rec.lines:]
+	p.line += rec.lines
+	return rec, line, true, nil
+}
+
+// RepairCsvFile rewrites a CSV file damaged by bad quoting or escaping, which the CSV loaders
+// reject and lazyQuotes only partly tolerates, as a clean RFC 4180 file: fields are quoted only
+// when needed, with quotes doubled. The heuristics applied are:
+//   - nul: Remove NUL bytes, left by some exporters and by files cut off on disk
+//   - strayQuotes: Keep a quote in an unquoted field, or one inside a quoted field that is not
+//     followed by the delimiter or the end of the line, as a literal quote
+//   - unbalancedQuotes: Read the quote opening a field as a literal quote when the field is
+//     never closed within maxRecordLines lines, or when reading the quote as a literal gives a
+//     record with as many fields as the first record while reading the field across lines gives
+//     a different number or needs stray quotes
+//
+// Options:
+//   - delimiter: Field delimiter, detected from the extension as for the CSV loaders
+//   - heuristics: The heuristics to apply (default: all); records needing another one are dropped
+//   - maxRecordLines: The number of lines a quoted field may span (default: 100)
+//   - maxReported: The number of repaired or dropped records listed in the result (default: 100)
+//   - reportFile: Also write every repaired or dropped record as a JSON array file
+//
+// A BOM is removed and line endings are normalized to "\n".
+//
+// Returns: The records written, repaired and dropped, the count of each fix, and the first
+// repaired records with the line they start on and the fixes applied
+//
+// Example usage:
+//
+//	const report = streamloader.repairCsvFile("export.csv", "export-clean.csv", { reportFile: "export-repairs.json" });
+//	const rows = streamloader.loadCSV("export-clean.csv");
+func (s StreamLoader) RepairCsvFile(inputFilePath string, outputFilePath string, options ...CsvRepairOptions) (*CsvRepairResult, error) {
+	var opts CsvRepairOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	enabled := map[string]bool{csvRepairNul: true, csvRepairStray: true, csvRepairUnbalanced: true}
+	if opts.Heuristics != nil {
+		enabled = make(map[string]bool, len(opts.Heuristics))
+		for _, h := range opts.Heuristics {
+			switch h {
+			case csvRepairNul, csvRepairStray, csvRepairUnbalanced:
+				enabled[h] = true
+			default:
+				return nil, fmt.Errorf("invalid heuristic %q: expected nul, strayQuotes or unbalancedQuotes", h)
+			}
+		}
+	}
+	if opts.MaxRecordLines < 0 || opts.MaxReported < 0 {
+		return nil, fmt.Errorf("maxRecordLines and maxReported must not be negative")
+	}
+	maxLines := opts.MaxRecordLines
+	if maxLines == 0 {
+		maxLines = defaultCsvRepairMaxRecordLines
+	}
+	maxReported := opts.MaxReported
+	if maxReported == 0 {
+		maxReported = defaultCsvRepairMaxReported
+	}
+	probe := csv.NewReader(nil)
+	if err := setCsvDelimiter(probe, opts.Delimiter, inputFilePath); err != nil {
+		return nil, err
+	}
+
+	file, err := openInput(inputFilePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true), readBufferSize())
+	p := &csvRecordParser{reader: reader, comma: string(probe.Comma), line: 1, maxLines: maxLines}
+
+	out, err := createOutputFile(outputFilePath, JsonWriterOptions{}, gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	buffered := bufio.NewWriterSize(out, writeBufferSize())
+	writer := csv.NewWriter(buffered)
+	writer.Comma = probe.Comma
+	var report *jsonArrayWriter
+	if opts.ReportFile != "" {
+		if report, err = createJsonArrayFile(opts.ReportFile, writeBufferSize()); err != nil {
+			return nil, err
+		}
+		defer report.Close()
+	}
+
+	result := &CsvRepairResult{Lines: []CsvRepairLine{}}
+	columns := 0
+	for {
+		rec, line, ok, err := p.next(columns, enabled[csvRepairUnbalanced])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		var fixes []string
+		dropped := rec.fields == nil
+		if rec.nulBytes > 0 {
+			fixes = append(fixes, csvRepairNul)
+			if enabled[csvRepairNul] {
+				for i, field := range rec.fields {
+					rec.fields[i] = strings.ReplaceAll(field, "\x00", "")
+				}
+			} else {
+				dropped = true
+			}
+		}
+		if rec.stray > 0 {
+			fixes = append(fixes, csvRepairStray)
+			dropped = dropped || !enabled[csvRepairStray]
+		}
+		if rec.unbalanced {
+			fixes = append(fixes, csvRepairUnbalanced)
+		}
+
+		if len(fixes) > 0 {
+			repaired := CsvRepairLine{Line: line, Fixes: fixes, Dropped: dropped}
+			if len(result.Lines) < maxReported {
+				result.Lines = append(result.Lines, repaired)
+			}
+			if report != nil {
+				encoded, _ := json.Marshal(repaired)
+				if err := report.Write(encoded); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if dropped {
+			result.DroppedRecords++
+			continue
+		}
+		if len(fixes) > 0 {
+			result.RepairedRecords++
+			result.NulBytes += rec.nulBytes
+			result.StrayQuotes += rec.stray
+			if rec.unbalanced {
+				result.UnbalancedQuotes++
+			}
+		}
+		if columns == 0 {
+			columns = len(rec.fields)
+		}
+		if err := writer.Write(rec.fields); err != nil {
+			return nil, fmt.Errorf("failed to write CSV line %d: %w", line, err)
+		}
+		result.Records++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	if report != nil {
+		if err := report.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
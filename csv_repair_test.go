@@ -0,0 +1,86 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRepairCsvFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "export.csv")
+	os.WriteFile(input, []byte("id,name,note\r\n"+
+		"1,Bob \"the builder\",ok\r\n"+
+		"2,\"say \"hi\" now\",ok\r\n"+
+		"3,\"unterminated,ok\r\n"+
+		"4,\"multi\nline\",ok\r\n"+
+		"5,nul\x00led,ok\r\n"+
+		"6,\"quoted, fine\",\"\"\"escaped\"\"\"\r\n"), 0644)
+	output := filepath.Join(dir, "clean.csv")
+	reportPath := filepath.Join(dir, "report.json")
+
+	result, err := loader.RepairCsvFile(input, output, CsvRepairOptions{ReportFile: reportPath})
+	if err != nil {
+		t.Fatalf("RepairCsvFile() error = %v", err)
+	}
+	want := "id,name,note\n" +
+		"1,\"Bob \"\"the builder\"\"\",ok\n" +
+		"2,\"say \"\"hi\"\" now\",ok\n" +
+		"3,\"\"\"unterminated\",ok\n" +
+		"4,\"multi\nline\",ok\n" +
+		"5,nulled,ok\n" +
+		"6,\"quoted, fine\",\"\"\"escaped\"\"\"\n"
+	if data, _ := os.ReadFile(output); string(data) != want {
+		t.Errorf("output = %q, want %q", data, want)
+	}
+	wantLines := []CsvRepairLine{
+		{Line: 2, Fixes: []string{"strayQuotes"}},
+		{Line: 3, Fixes: []string{"strayQuotes"}},
+		{Line: 4, Fixes: []string{"unbalancedQuotes"}},
+		{Line: 7, Fixes: []string{"nul"}},
+	}
+	if result.Records != 7 || result.RepairedRecords != 4 || result.DroppedRecords != 0 ||
+		result.StrayQuotes != 2 || result.UnbalancedQuotes != 1 || result.NulBytes != 1 ||
+		!reflect.DeepEqual(result.Lines, wantLines) {
+		t.Errorf("RepairCsvFile() = %+v", result)
+	}
+	var report []CsvRepairLine
+	data, _ := os.ReadFile(reportPath)
+	if err := json.Unmarshal(data, &report); err != nil || !reflect.DeepEqual(report, wantLines) {
+		t.Errorf("report = %s (%v)", data, err)
+	}
+
+	// The repaired file loads
+	if rows, err := loader.LoadCSV(output); err != nil || len(rows) != 7 {
+		t.Errorf("LoadCSV(repaired) = %d rows, %v", len(rows), err)
+	}
+
+	// Records needing a disabled heuristic are dropped
+	result, err = loader.RepairCsvFile(input, output, CsvRepairOptions{Heuristics: []string{"nul"}, MaxReported: 1})
+	if err != nil {
+		t.Fatalf("RepairCsvFile() error = %v", err)
+	}
+	if result.Records != 4 || result.DroppedRecords != 3 || result.RepairedRecords != 1 || len(result.Lines) != 1 || !result.Lines[0].Dropped {
+		t.Errorf("RepairCsvFile(nul only) = %+v", result)
+	}
+
+	// A quoted field may span at most maxRecordLines lines
+	result, _ = loader.RepairCsvFile(input, output, CsvRepairOptions{MaxRecordLines: 1})
+	if result.UnbalancedQuotes != 2 {
+		t.Errorf("RepairCsvFile(maxRecordLines 1) = %+v", result)
+	}
+
+	invalid := []CsvRepairOptions{
+		{Heuristics: []string{"magic"}},
+		{MaxRecordLines: -1},
+		{Delimiter: "\"\""},
+	}
+	for _, options := range invalid {
+		if _, err := loader.RepairCsvFile(input, output, options); err == nil {
+			t.Errorf("RepairCsvFile(%+v) expected an error", options)
+		}
+	}
+}
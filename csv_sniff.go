@@ -0,0 +1,306 @@
+// csv_sniff.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// defaultSniffSampleBytes is the number of bytes SniffCsv reads by default
+const defaultSniffSampleBytes = 64 * 1024
+
+// sniffDelimiters are the delimiters SniffCsv tries, in order of preference for ties
+var sniffDelimiters = []string{",", "\t", ";", "|"}
+
+// SniffCsvOptions configures SniffCsv
+type SniffCsvOptions struct {
+	SampleBytes int `json:"sampleBytes" js:"sampleBytes"`
+}
+
+// CsvSniffResult describes the format SniffCsv detected
+type CsvSniffResult struct {
+	Encoding          string `json:"encoding" js:"encoding"`                   // WHATWG name, as normalizeTextFile accepts
+	BOM               bool   `json:"bom" js:"bom"`                             // The file starts with a byte order mark
+	Delimiter         string `json:"delimiter" js:"delimiter"`                 // Field delimiter
+	Quote             string `json:"quote" js:"quote"`                         // `"`, `'`, or "" if no field is quoted
+	Header            bool   `json:"header" js:"header"`                       // The first row looks like a header
+	LineEnding        string `json:"lineEnding" js:"lineEnding"`               // "lf", "crlf", "cr", "mixed", or "" for a single line
+	Columns           int    `json:"columns" js:"columns"`                     // The most common number of fields per row
+	ConsistentColumns bool   `json:"consistentColumns" js:"consistentColumns"` // Every sampled row has Columns fields
+	InconsistentRows  int    `json:"inconsistentRows" js:"inconsistentRows"`   // Sampled rows with another number of fields
+	SampledRows       int    `json:"sampledRows" js:"sampledRows"`
+}
+
+// sniffEncoding detects the encoding of the start of a file from its byte order mark, the zero
+// bytes of UTF-16 text, or whether it is valid UTF-8, which any ASCII text is.
+func sniffEncoding(sample []byte, truncated bool) (string, bool) {
+	switch {
+	case bytes.HasPrefix(sample, utf8BOM):
+		return "utf-8", true
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return "utf-16le", true
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return "utf-16be", true
+	}
+
+	// ASCII text in UTF-16 has a zero byte in every other position
+	var evenZeros, oddZeros int
+	for i, b := range sample {
+		if b == 0 {
+			if i%2 == 0 {
+				evenZeros++
+			} else {
+				oddZeros++
+			}
+		}
+	}
+	if half := len(sample) / 2; half > 0 {
+		if oddZeros > half/2 && evenZeros < oddZeros/10 {
+			return "utf-16le", false
+		}
+		if evenZeros > half/2 && oddZeros < evenZeros/10 {
+			return "utf-16be", false
+		}
+	}
+
+	if truncated {
+		// Ignore a character cut off at the end of the sample
+		for i := 1; i <= utf8.UTFMax && i <= len(sample); i++ {
+			if utf8.RuneStart(sample[len(sample)-i]) {
+				if !utf8.FullRune(sample[len(sample)-i:]) {
+					sample = sample[:len(sample)-i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(sample) {
+		return "utf-8", false
+	}
+	return "windows-1252", false
+}
+
+// sniffLineEnding classifies the line endings of text.
+func sniffLineEnding(text string) string {
+	crlf := strings.Count(text, "\r\n")
+	cr := strings.Count(text, "\r") - crlf
+	lf := strings.Count(text, "\n") - crlf
+	kinds := 0
+	ending := ""
+	for _, k := range []struct {
+		count int
+		name  string
+	}{{lf, "lf"}, {crlf, "crlf"}, {cr, "cr"}} {
+		if k.count > 0 {
+			kinds++
+			ending = k.name
+		}
+	}
+	if kinds > 1 {
+		return "mixed"
+	}
+	return ending
+}
+
+// sniffRows splits text into rows with a delimiter, returning nil if it isn't valid CSV with it.
+func sniffRows(text string, delimiter string) [][]string {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			return nil
+		}
+		rows = append(rows, row)
+	}
+}
+
+// columnConsistency returns the most common number of fields of rows and how many rows have it.
+func columnConsistency(rows [][]string) (int, int) {
+	counts := make(map[int]int)
+	columns, matching := 0, 0
+	for _, row := range rows {
+		counts[len(row)]++
+		if n := counts[len(row)]; n > matching || n == matching && len(row) > columns {
+			columns, matching = len(row), n
+		}
+	}
+	return columns, matching
+}
+
+// sniffHeader guesses whether the first row is a header: each column whose data cells are all
+// numbers, or all the same length in at least two rows, votes for a header if the first cell
+// breaks the pattern and against it otherwise.
+func sniffHeader(rows [][]string, columns int) bool {
+	if len(rows) < 2 || len(rows[0]) != columns {
+		return false
+	}
+	seen := make(map[string]bool, columns)
+	for _, name := range rows[0] {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			return false
+		}
+		seen[name] = true
+	}
+	isNumber := func(s string) bool {
+		_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return err == nil
+	}
+
+	votes := 0
+	for col := 0; col < columns; col++ {
+		numeric, sameLength := true, true
+		length := -1
+		for _, row := range rows[1:] {
+			if col >= len(row) {
+				continue
+			}
+			numeric = numeric && isNumber(row[col])
+			if length < 0 {
+				length = len(row[col])
+			}
+			sameLength = sameLength && len(row[col]) == length
+		}
+		head := rows[0][col]
+		switch {
+		case numeric:
+			if isNumber(head) {
+				votes--
+			} else {
+				votes++
+			}
+		case sameLength && len(rows) > 2:
+			if len(head) != length {
+				votes++
+			} else {
+				votes--
+			}
+		}
+	}
+	return votes > 0
+}
+
+// SniffCsv inspects the start of a delimited text file and reports its format, so scripts can
+// pick the options to load it with instead of loading it by trial and error. Only the first
+// sampleBytes bytes are read, so it is cheap on files of any size.
+//
+// Detected:
+//   - encoding: From a byte order mark, the zero bytes of UTF-16 text, or whether the sample is
+//     valid UTF-8; anything else is reported as windows-1252. Convert files that aren't UTF-8
+//     with normalizeTextFile before loading them
+//   - delimiter: The one of ",", tab, ";" and "|" that splits the sampled rows into the same
+//     number of fields most consistently
+//   - quote: The quote character fields are enclosed in; the CSV loaders only support `"`
+//   - header: Whether the first row looks like a header, because it breaks the pattern of the
+//     rows below it, such as text above a numeric column
+//   - lineEnding, and the number of fields per row and how many rows differ from it
+//
+// Options:
+//   - sampleBytes: The number of bytes read (default: 65536)
+//
+// Example usage:
+//
+//	const format = streamloader.sniffCsv("export.csv");
+//	const rows = streamloader.processCsvFile("export.csv", { delimiter: format.delimiter, skipHeader: format.header });
+func (s StreamLoader) SniffCsv(filePath string, options ...SniffCsvOptions) (*CsvSniffResult, error) {
+	var opts SniffCsvOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.SampleBytes < 0 {
+		return nil, fmt.Errorf("sampleBytes must not be negative, got %d", opts.SampleBytes)
+	}
+	sampleBytes := opts.SampleBytes
+	if sampleBytes == 0 {
+		sampleBytes = defaultSniffSampleBytes
+	}
+
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+	sample := make([]byte, sampleBytes+1)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read CSV file: %w", err)
+	}
+	truncated := n > sampleBytes
+	sample = sample[:min(n, sampleBytes)]
+
+	result := &CsvSniffResult{}
+	result.Encoding, result.BOM = sniffEncoding(sample, truncated)
+	decoder, err := textDecoder(result.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := transform.Bytes(decoder, sample)
+	if err != nil && !truncated {
+		return nil, fmt.Errorf("failed to decode CSV file as %s: %w", result.Encoding, err)
+	}
+	text := strings.TrimPrefix(string(decoded), "\ufeff")
+	if truncated {
+		// Drop the last line, which is likely cut off
+		if end := strings.LastIndexAny(text, "\r\n"); end >= 0 {
+			text = text[:end+1]
+		}
+	}
+	result.LineEnding = sniffLineEnding(text)
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	// Pick the delimiter that gives the most rows with the same number of fields
+	var best [][]string
+	bestMatching := 0
+	for _, delimiter := range sniffDelimiters {
+		rows := sniffRows(text, delimiter)
+		columns, matching := columnConsistency(rows)
+		if columns < 2 {
+			continue
+		}
+		if best == nil || matching*len(best) > bestMatching*len(rows) ||
+			matching*len(best) == bestMatching*len(rows) && columns > result.Columns {
+			best, bestMatching = rows, matching
+			result.Delimiter, result.Columns = delimiter, columns
+		}
+	}
+	if best == nil {
+		// A single column: no delimiter occurs consistently
+		result.Delimiter = ","
+		best = sniffRows(text, ",")
+		result.Columns, bestMatching = columnConsistency(best)
+	}
+	result.SampledRows = len(best)
+	result.InconsistentRows = len(best) - bestMatching
+	result.ConsistentColumns = result.InconsistentRows == 0
+	result.Header = sniffHeader(best, result.Columns)
+
+	// Count fields starting with each quote character
+	quotes := map[byte]int{}
+	for _, line := range strings.Split(text, "\n") {
+		for _, field := range strings.Split(line, result.Delimiter) {
+			if len(field) > 1 && (field[0] == '"' || field[0] == '\'') {
+				quotes[field[0]]++
+			}
+		}
+	}
+	if quotes['"'] > 0 && quotes['"'] >= quotes['\''] {
+		result.Quote = `"`
+	} else if quotes['\''] > 0 {
+		result.Quote = "'"
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestSniffCsv(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	utf16le := func(s string) []byte {
+		data := []byte{0xFF, 0xFE}
+		for _, u := range utf16.Encode([]rune(s)) {
+			data = append(data, byte(u), byte(u>>8))
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    CsvSniffResult
+	}{
+		{"comma with header", []byte("id,name,price\n1,\"Smith, J\",9.5\n2,Lee,12\n"),
+			CsvSniffResult{Encoding: "utf-8", Delimiter: ",", Quote: `"`, Header: true, LineEnding: "lf", Columns: 3, ConsistentColumns: true, SampledRows: 3}},
+		{"semicolon with decimal commas", []byte("\xef\xbb\xbfcode;amount;rate\r\nA1;1,5;0,2\r\nB2;2,5;0,3\r\n"),
+			CsvSniffResult{Encoding: "utf-8", BOM: true, Delimiter: ";", Header: true, LineEnding: "crlf", Columns: 3, ConsistentColumns: true, SampledRows: 3}},
+		{"tab without header", []byte("1\t2\t3\n4\t5\t6\n7\t8\n"),
+			CsvSniffResult{Encoding: "utf-8", Delimiter: "\t", LineEnding: "lf", Columns: 3, InconsistentRows: 1, SampledRows: 3}},
+		{"pipe with single quotes", []byte("a|b\n'x|y'|1\n'z'|2\n"),
+			CsvSniffResult{Encoding: "utf-8", Delimiter: "|", Quote: "'", LineEnding: "lf", Columns: 2, InconsistentRows: 1, SampledRows: 3}},
+		{"windows-1252", []byte("name,city\nJos\xe9,M\xe1laga\n"),
+			CsvSniffResult{Encoding: "windows-1252", Delimiter: ",", LineEnding: "lf", Columns: 2, ConsistentColumns: true, SampledRows: 2}},
+		{"utf-16le", utf16le("id,score\n1,10\n2,20\n"),
+			CsvSniffResult{Encoding: "utf-16le", BOM: true, Delimiter: ",", Header: true, LineEnding: "lf", Columns: 2, ConsistentColumns: true, SampledRows: 3}},
+		{"single column", []byte("email\na@example.com\n"),
+			CsvSniffResult{Encoding: "utf-8", Delimiter: ",", LineEnding: "lf", Columns: 1, ConsistentColumns: true, SampledRows: 2}},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, string(rune('a'+i))+".csv")
+		os.WriteFile(path, tt.content, 0644)
+		got, err := loader.SniffCsv(path)
+		if err != nil {
+			t.Fatalf("%s: SniffCsv() error = %v", tt.name, err)
+		}
+		if *got != tt.want {
+			t.Errorf("%s: SniffCsv() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	// Only the sample is read, and a row cut off at its end is ignored
+	large := filepath.Join(dir, "large.csv")
+	os.WriteFile(large, []byte("id,value\n"+strings.Repeat("1,2\n", 1000)+"3,4,5\n"), 0644)
+	got, err := loader.SniffCsv(large, SniffCsvOptions{SampleBytes: 100})
+	if err != nil {
+		t.Fatalf("SniffCsv(sampleBytes) error = %v", err)
+	}
+	if got.SampledRows != 23 || !got.ConsistentColumns || !got.Header {
+		t.Errorf("SniffCsv(sampleBytes) = %+v", got)
+	}
+
+	if _, err := loader.SniffCsv(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("SniffCsv(missing) expected an error")
+	}
+	if _, err := loader.SniffCsv(large, SniffCsvOptions{SampleBytes: -1}); err == nil {
+		t.Error("SniffCsv(sampleBytes -1) expected an error")
+	}
+}
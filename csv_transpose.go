@@ -0,0 +1,92 @@
+// csv_transpose.go
+package streamloader
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TransposeCsv swaps the rows and columns of a small CSV matrix, for parameter files laid out
+// with one column per scenario and one row per field: after transposing, each row is a scenario
+// and the first row holds the field names, as the other CSV functions expect.
+//
+// pathOrRows is either the path of a CSV file, read as LoadCSV reads it and with the same
+// options, or rows such as those returned by LoadCSV. Cells of rows may also be numbers,
+// booleans or null, which become strings as they are written in a CSV file, and null an empty
+// cell. Rows shorter than the longest row are padded with empty cells. The whole matrix is held
+// in memory, so this is meant for parameter matrices, not datasets.
+//
+// Example usage:
+//
+//	// scenarios.csv:
+//	// field,smoke,soak
+//	// vus,1,50
+//	// duration,30s,4h
+//	const rows = streamloader.transposeCsv("scenarios.csv");
+//	// [["field","vus","duration"],["smoke","1","30s"],["soak","50","4h"]]
+func (s StreamLoader) TransposeCsv(pathOrRows interface{}, options ...interface{}) ([][]string, error) {
+	var rows [][]string
+	switch v := pathOrRows.(type) {
+	case string:
+		loaded, err := s.loadDelimited("transposeCsv", v, "", options...)
+		if err != nil {
+			return nil, err
+		}
+		rows = loaded
+	case [][]string:
+		rows = v
+	case []interface{}:
+		rows = make([][]string, len(v))
+		for i, row := range v {
+			cells, ok := row.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid row %d: expected an array, got %T", i, row)
+			}
+			rows[i] = make([]string, len(cells))
+			for j, cell := range cells {
+				text, err := csvCellString(cell)
+				if err != nil {
+					return nil, fmt.Errorf("invalid cell %d of row %d: %w", j, i, err)
+				}
+				rows[i][j] = text
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid argument: expected a file path or an array of rows, got %T", pathOrRows)
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	transposed := make([][]string, columns)
+	for j := range transposed {
+		transposed[j] = make([]string, len(rows))
+		for i, row := range rows {
+			if j < len(row) {
+				transposed[j][i] = row[j]
+			}
+		}
+	}
+	return transposed, nil
+}
+
+// csvCellString returns the text of a scalar cell as it would be written in a CSV file.
+func csvCellString(cell interface{}) (string, error) {
+	switch v := cell.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or null, got %T", cell)
+	}
+}
@@ -0,0 +1,77 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestTransposeCsv(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	matrix := filepath.Join(dir, "scenarios.csv")
+	os.WriteFile(matrix, []byte("field,smoke,soak\nvus,1,50\nduration,30s,\"4h, then ramp\"\ntags,\n"), 0644)
+	tsv := filepath.Join(dir, "scenarios.tsv")
+	os.WriteFile(tsv, []byte("field\tsmoke\nvus\t1\n"), 0644)
+
+	tests := []struct {
+		name       string
+		pathOrRows interface{}
+		want       [][]string
+	}{
+		{"file", matrix, [][]string{
+			{"field", "vus", "duration", "tags"},
+			{"smoke", "1", "30s", ""},
+			{"soak", "50", "4h, then ramp", ""},
+		}},
+		{"delimiter from the extension", tsv, [][]string{{"field", "vus"}, {"smoke", "1"}}},
+		{"rows", [][]string{{"a", "b"}, {"c"}}, [][]string{{"a", "c"}, {"b", ""}}},
+		{"script values", []interface{}{
+			[]interface{}{"n", int64(1), 2.5, true, nil},
+		}, [][]string{{"n"}, {"1"}, {"2.5"}, {"true"}, {""}}},
+		{"empty", [][]string{}, [][]string{}},
+	}
+	for _, tt := range tests {
+		got, err := loader.TransposeCsv(tt.pathOrRows)
+		if err != nil {
+			t.Fatalf("%s: TransposeCsv() error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: TransposeCsv() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Transposing twice restores the matrix, padded to a rectangle
+	once, _ := loader.TransposeCsv(matrix)
+	twice, _ := loader.TransposeCsv(once)
+	if want, _ := loader.LoadCSV(matrix); !reflect.DeepEqual(twice[:3], want[:3]) {
+		t.Errorf("TransposeCsv(TransposeCsv()) = %q, want %q", twice, want)
+	}
+
+	invalid := []interface{}{42, []interface{}{"not a row"}, []interface{}{[]interface{}{map[string]interface{}{}}}}
+	for _, v := range invalid {
+		if _, err := loader.TransposeCsv(v); err == nil {
+			t.Errorf("TransposeCsv(%v) expected an error", v)
+		}
+	}
+}
+
+func TestTransposeCsvFromScript(t *testing.T) {
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	value, err := rt.RunString(`JSON.stringify(streamloader.transposeCsv([["field", "smoke"], ["vus", 1], ["debug", false]]))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != `[["field","vus","debug"],["smoke","1","false"]]` {
+		t.Errorf("script result = %s", got)
+	}
+}
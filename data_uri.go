@@ -0,0 +1,120 @@
+// data_uri.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dataURIMediaExtensions maps the media types of data URIs to the file extensions the loaders
+// detect formats by
+var dataURIMediaExtensions = map[string]string{
+	"text/csv":                  ".csv",
+	"text/tab-separated-values": ".tsv",
+	"application/json":          ".json",
+	"application/x-ndjson":      ".ndjson",
+	"application/jsonl":         ".ndjson",
+	"application/gzip":          ".gz",
+}
+
+// isDataURI reports whether a path argument is a data URI rather than a file path.
+func isDataURI(path string) bool {
+	return len(path) >= 5 && strings.EqualFold(path[:5], "data:")
+}
+
+// decodeDataURI returns the media type and payload of a data URI (RFC 2397):
+// data:[<media type>][;base64],<data>. Base64 payloads may be padded or not and use the standard
+// or URL alphabet, as environment variables often carry them; other payloads are percent-decoded.
+func decodeDataURI(uri string) (string, []byte, error) {
+	header, payload, ok := strings.Cut(uri[len("data:"):], ",")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid data URI: missing ','")
+	}
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(header), ";base64") {
+		isBase64 = true
+		header = header[:len(header)-len(";base64")]
+	}
+	mediaType := "text/plain"
+	if header != "" {
+		parsed, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid data URI media type %q: %w", header, err)
+		}
+		mediaType = parsed
+	}
+
+	if !isBase64 {
+		data, err := url.PathUnescape(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid data URI payload: %w", err)
+		}
+		return mediaType, []byte(data), nil
+	}
+	payload = strings.TrimRight(payload, "=")
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(payload, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	data, err := encoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data URI base64 payload: %w", err)
+	}
+	return mediaType, data, nil
+}
+
+// openDataURI returns the payload of a data URI, served from memory, so every reader can treat
+// it as a regular file without it touching the disk.
+func openDataURI(uri string) (*inputFile, error) {
+	_, data, err := decodeDataURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &inputFile{data: bytes.NewReader(data), path: uri}, nil
+}
+
+// dataURIInfo describes the payload of a data URI as a read-only regular file.
+type dataURIInfo struct {
+	size int64
+}
+
+func (i dataURIInfo) Name() string       { return "data" }
+func (i dataURIInfo) Size() int64        { return i.size }
+func (i dataURIInfo) Mode() fs.FileMode  { return 0444 }
+func (i dataURIInfo) ModTime() time.Time { return time.Time{} }
+func (i dataURIInfo) IsDir() bool        { return false }
+func (i dataURIInfo) Sys() any           { return nil }
+
+// statInput returns the file info of an input path, or of the payload of a data URI.
+func statInput(path string) (os.FileInfo, error) {
+	if !isDataURI(path) {
+		return os.Stat(path)
+	}
+	_, data, err := decodeDataURI(path)
+	if err != nil {
+		return nil, err
+	}
+	return dataURIInfo{size: int64(len(data))}, nil
+}
+
+// inputExt returns the extension of an input path or of the path of a URL, or the one matching
+// the media type of a data URI, such as ".csv" for text/csv.
+func inputExt(path string) string {
+	if isRemoteURL(path) {
+		return remoteExt(path)
+	}
+	if !isDataURI(path) {
+		return filepath.Ext(path)
+	}
+	header, _, _ := strings.Cut(path[len("data:"):], ",")
+	mediaType, _, _ := strings.Cut(header, ";")
+	return dataURIMediaExtensions[strings.ToLower(strings.TrimSpace(mediaType))]
+}
@@ -0,0 +1,122 @@
+package streamloader
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeDataURI(t *testing.T) {
+	payload := `[{"id":1,"tags":["a/b"]}]`
+	std := base64.StdEncoding.EncodeToString([]byte(payload + "??"))
+	url := base64.RawURLEncoding.EncodeToString([]byte(payload + "??"))
+
+	tests := []struct {
+		uri       string
+		mediaType string
+		data      string
+	}{
+		{"data:application/json;base64," + std, "application/json", payload + "??"},
+		{"data:application/json;base64," + url, "application/json", payload + "??"},
+		{"DATA:text/csv;charset=utf-8,id%2Cname%0A1%2CAda%20Lovelace%0A", "text/csv", "id,name\n1,Ada Lovelace\n"},
+		{"data:,a+b", "text/plain", "a+b"},
+		{"data:;base64,", "text/plain", ""},
+	}
+	for _, tt := range tests {
+		mediaType, data, err := decodeDataURI(tt.uri)
+		if err != nil {
+			t.Fatalf("decodeDataURI(%q) error = %v", tt.uri, err)
+		}
+		if mediaType != tt.mediaType || string(data) != tt.data {
+			t.Errorf("decodeDataURI(%q) = %q, %q, want %q, %q", tt.uri, mediaType, data, tt.mediaType, tt.data)
+		}
+	}
+
+	for _, uri := range []string{"data:text/csv", "data:;base64,%%%", "data:,%zz", "data:text/;x,abc"} {
+		if _, _, err := decodeDataURI(uri); err == nil {
+			t.Errorf("decodeDataURI(%q) expected an error", uri)
+		}
+	}
+
+	exts := map[string]string{
+		"data:text/tab-separated-values;base64,": ".tsv",
+		"data:application/x-ndjson,":             ".ndjson",
+		"data:text/plain,x.csv":                  "",
+		"users.csv":                              ".csv",
+	}
+	for path, want := range exts {
+		if got := inputExt(path); got != want {
+			t.Errorf("inputExt(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDataURILoaders(t *testing.T) {
+	loader := StreamLoader{}
+	encode := func(mediaType string, s string) string {
+		return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	users := encode("application/json", `[{"id":1},{"id":2}]`)
+	data, err := loader.LoadJSON(users)
+	if err != nil {
+		t.Fatalf("LoadJSON(data URI) error = %v", err)
+	}
+	if encoded, _ := json.Marshal(data); string(encoded) != `[{"id":1},{"id":2}]` {
+		t.Errorf("LoadJSON(data URI) = %s", encoded)
+	}
+
+	// The media type selects the format, as the extension of a file does
+	lines, err := loader.LoadJSON(encode("application/x-ndjson", "{\"id\":1}\n{\"id\":2}\n"))
+	if encoded, _ := json.Marshal(lines); err != nil || string(encoded) != `[{"id":1},{"id":2}]` {
+		t.Errorf("LoadJSON(NDJSON data URI) = %v, %v", lines, err)
+	}
+	rows, err := loader.LoadCSV(encode("text/tab-separated-values", "id\tname\n1\tAda, L\n"))
+	if err != nil || !reflect.DeepEqual(rows, [][]string{{"id", "name"}, {"1", "Ada, L"}}) {
+		t.Errorf("LoadCSV(TSV data URI) = %q, %v", rows, err)
+	}
+	processed, err := loader.ProcessCsvFile("data:text/csv,id%2Cname%0A1%2CAda%0A", ProcessCsvOptions{SkipHeader: true})
+	if err != nil || len(processed) != 1 {
+		t.Errorf("ProcessCsvFile(data URI) = %v, %v", processed, err)
+	}
+
+	info, err := statInput(users)
+	if err != nil || info.Size() != 19 || !info.Mode().IsRegular() {
+		t.Errorf("statInput(data URI) = %v, %v", info, err)
+	}
+	if _, err := loader.LoadJSON("data:application/json;base64,!!!"); err == nil {
+		t.Error("LoadJSON(invalid data URI) expected an error")
+	}
+}
+
+func TestDataURIServedFromMemory(t *testing.T) {
+	t.Cleanup(func() { readOnly.Store(false) })
+	readOnly.Store(true)
+	uri := "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte("line 1\nline 2\n"))
+	file, err := openInput(uri, callLimits{})
+	if err != nil {
+		t.Fatalf("openInput() in read-only mode = %v", err)
+	}
+	if file.File != nil {
+		t.Error("data URI payload is held in a file")
+	}
+	if info, err := file.Stat(); err != nil || info.Size() != 14 || !info.Mode().IsRegular() {
+		t.Errorf("Stat() = %v, %v", info, err)
+	}
+	data, _ := io.ReadAll(file)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := io.ReadAll(file)
+	if string(data) != "line 1\nline 2\n" || string(again) != string(data) {
+		t.Errorf("read %q, then %q after seeking back", data, again)
+	}
+	if err := file.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if n, err := (StreamLoader{}).CountLines(uri); err != nil || n != 2 {
+		t.Errorf("CountLines(data URI) = %d, %v", n, err)
+	}
+}
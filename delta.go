@@ -0,0 +1,351 @@
+// delta.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// deltaMaxInsertRun caps the records buffered in a single insert operation
+const deltaMaxInsertRun = 1000
+
+// DatasetDeltaResult summarizes a delta created by CreateDatasetDelta
+type DatasetDeltaResult struct {
+	OldRecords      int `json:"oldRecords" js:"oldRecords"`
+	NewRecords      int `json:"newRecords" js:"newRecords"`
+	CopiedRecords   int `json:"copiedRecords" js:"copiedRecords"`
+	InsertedRecords int `json:"insertedRecords" js:"insertedRecords"`
+}
+
+// datasetDeltaBase identifies the dataset a delta was created against
+type datasetDeltaBase struct {
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+// datasetDeltaOp is one delta operation: copy a run of records from the old dataset, or insert
+// new records
+type datasetDeltaOp struct {
+	Copy   []int             `json:"copy,omitempty"`
+	Insert []json.RawMessage `json:"insert,omitempty"`
+}
+
+// CreateDatasetDelta compares two versions of a JSON array or NDJSON dataset and writes a delta
+// file that turns the old version into the new one. Shipping the delta to remote load
+// generators transfers only the changed records instead of the full dataset.
+//
+// The delta is a JSON object with the old dataset's record count and checksum, and a list of
+// operations: {"copy": [from, count]} reuses a run of old records, {"insert": [...]} adds new
+// ones. Records are compared after compacting whitespace. The old dataset is indexed by record
+// hash (32 bytes per record); records are streamed and never held in memory.
+//
+// Example usage:
+//
+//	result, err := streamloader.CreateDatasetDelta("users-monday.json", "users-tuesday.json", "users.delta.json")
+func (s StreamLoader) CreateDatasetDelta(oldFilePath string, newFilePath string, deltaFilePath string) (*DatasetDeltaResult, error) {
+	// Index the old dataset by record hash
+	var oldHashes [][sha256.Size]byte
+	firstIndex := make(map[[sha256.Size]byte]int)
+	base, err := scanDatasetBase(oldFilePath, s.call, func(index int, compact []byte) error {
+		h := sha256.Sum256(compact)
+		oldHashes = append(oldHashes, h)
+		if _, ok := firstIndex[h]; !ok {
+			firstIndex[h] = index
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := openJsonRecords(newFilePath, s.call)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	lock, err := lockOutputPath(deltaFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+	file, err := os.Create(deltaFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delta file: %w", err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriterSize(file, writeBufferSize())
+
+	header, _ := json.Marshal(base)
+	writer.WriteString(`{"base":`)
+	writer.Write(header)
+	writer.WriteString(`,"ops":[`)
+
+	result := &DatasetDeltaResult{OldRecords: base.Records}
+	ops := 0
+	writeOp := func(op datasetDeltaOp) error {
+		encoded, err := json.Marshal(op)
+		if err != nil {
+			return fmt.Errorf("failed to encode delta operation: %w", err)
+		}
+		if ops > 0 {
+			writer.WriteByte(',')
+		}
+		ops++
+		if _, err := writer.Write(encoded); err != nil {
+			return fmt.Errorf("failed to write delta file: %w", err)
+		}
+		return nil
+	}
+
+	copyFrom, copyCount := 0, 0
+	var inserts []json.RawMessage
+	flush := func() error {
+		if copyCount > 0 {
+			if err := writeOp(datasetDeltaOp{Copy: []int{copyFrom, copyCount}}); err != nil {
+				return err
+			}
+			result.CopiedRecords += copyCount
+			copyCount = 0
+		}
+		if len(inserts) > 0 {
+			if err := writeOp(datasetDeltaOp{Insert: inserts}); err != nil {
+				return err
+			}
+			result.InsertedRecords += len(inserts)
+			inserts = nil
+		}
+		return nil
+	}
+
+	for {
+		raw, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, fmt.Errorf("failed to compact record %d: %w", result.NewRecords, err)
+		}
+		result.NewRecords++
+		h := sha256.Sum256(compact.Bytes())
+
+		// Extend the current copy run if this record continues it
+		if copyCount > 0 {
+			next := copyFrom + copyCount
+			if next < len(oldHashes) && oldHashes[next] == h {
+				copyCount++
+				continue
+			}
+		}
+
+		if index, ok := firstIndex[h]; ok {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			copyFrom, copyCount = index, 1
+			continue
+		}
+
+		if copyCount > 0 || len(inserts) >= deltaMaxInsertRun {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		inserts = append(inserts, json.RawMessage(compact.Bytes()))
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	writer.WriteString("]}")
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to flush delta file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close delta file: %w", err)
+	}
+	return result, nil
+}
+
+// ApplyDatasetDelta rebuilds the new version of a dataset from the old version and a delta file
+// written by CreateDatasetDelta, writing it as a JSON array file. The old dataset is verified
+// against the checksum in the delta before anything is written.
+//
+// Returns: The number of records written
+//
+// Example usage:
+//
+//	count, err := streamloader.ApplyDatasetDelta("users.json", "users.delta.json", "users-new.json")
+func (s StreamLoader) ApplyDatasetDelta(oldFilePath string, deltaFilePath string, outputFilePath string) (int, error) {
+	deltaFile, err := openInput(deltaFilePath, s.call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open delta file: %w", err)
+	}
+	defer deltaFile.Close()
+	dec := json.NewDecoder(bufio.NewReaderSize(deltaFile, readBufferSize()))
+
+	// Read the header up to the start of the operations
+	var base datasetDeltaBase
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+	if err := expectKey(dec, "base"); err != nil {
+		return 0, err
+	}
+	if err := dec.Decode(&base); err != nil {
+		return 0, fmt.Errorf("invalid delta file: %w", err)
+	}
+	if err := expectKey(dec, "ops"); err != nil {
+		return 0, err
+	}
+	if err := expectDelim(dec, '['); err != nil {
+		return 0, err
+	}
+
+	if err := requireSeekable(oldFilePath, "applyDatasetDelta"); err != nil {
+		return 0, err
+	}
+	actual, err := scanDatasetBase(oldFilePath, s.call, nil)
+	if err != nil {
+		return 0, err
+	}
+	if *actual != base {
+		return 0, fmt.Errorf("delta does not apply to %s: expected %d records with checksum %s, got %d records with checksum %s",
+			oldFilePath, base.Records, base.SHA256, actual.Records, actual.SHA256)
+	}
+
+	out, err := createJsonArrayFile(outputFilePath, writeBufferSize())
+	if err != nil {
+		return 0, err
+	}
+	fail := func(err error) (int, error) {
+		out.Close()
+		return out.count, err
+	}
+
+	// Old records are streamed; a copy that goes backwards reopens the file
+	var old *jsonRecordReader
+	position := 0
+	defer func() {
+		if old != nil {
+			old.Close()
+		}
+	}()
+
+	for dec.More() {
+		var op datasetDeltaOp
+		if err := dec.Decode(&op); err != nil {
+			return fail(fmt.Errorf("invalid delta operation: %w", err))
+		}
+		for _, record := range op.Insert {
+			if err := out.Write(record); err != nil {
+				return fail(err)
+			}
+		}
+		if op.Copy == nil {
+			continue
+		}
+		if len(op.Copy) != 2 || op.Copy[0] < 0 || op.Copy[1] < 0 || op.Copy[0]+op.Copy[1] > base.Records {
+			return fail(fmt.Errorf("invalid copy operation %v", op.Copy))
+		}
+
+		from, count := op.Copy[0], op.Copy[1]
+		if old == nil || from < position {
+			if old != nil {
+				old.Close()
+			}
+			if old, err = openJsonRecords(oldFilePath, s.call); err != nil {
+				return fail(err)
+			}
+			position = 0
+		}
+		for ; position < from+count; position++ {
+			raw, err := old.Next()
+			if err != nil {
+				return fail(fmt.Errorf("failed to read record %d of %s: %w", position, oldFilePath, err))
+			}
+			if position < from {
+				continue
+			}
+			var compact bytes.Buffer
+			json.Compact(&compact, raw)
+			if err := out.Write(compact.Bytes()); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return out.count, err
+	}
+	return out.count, nil
+}
+
+// scanDatasetBase streams a dataset and returns its record count and a checksum over the
+// compacted records. fn, if given, is called with each compacted record.
+func scanDatasetBase(filePath string, call callLimits, fn func(index int, compact []byte) error) (*datasetDeltaBase, error) {
+	records, err := openJsonRecords(filePath, call)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+
+	checksum := sha256.New()
+	var compact bytes.Buffer
+	count := 0
+	for ; ; count++ {
+		raw, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		compact.Reset()
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, fmt.Errorf("failed to compact record %d: %w", count, err)
+		}
+		checksum.Write(compact.Bytes())
+		checksum.Write([]byte{'\n'})
+		if fn != nil {
+			if err := fn(count, compact.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &datasetDeltaBase{Records: count, SHA256: hex.EncodeToString(checksum.Sum(nil))}, nil
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid delta file: %w", err)
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("invalid delta file: expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+// expectKey reads the next token and checks that it is the given object key.
+func expectKey(dec *json.Decoder, key string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid delta file: %w", err)
+	}
+	if k, ok := t.(string); !ok || k != key {
+		return fmt.Errorf("invalid delta file: expected key %q, got %v", key, t)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDatasetDelta_RoundTrip(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	writeRecords := func(name string, ids []int) string {
+		var lines []string
+		for _, id := range ids {
+			lines = append(lines, fmt.Sprintf(`{"id": %d, "name": "user-%d"}`, id, id))
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("["+strings.Join(lines, ",\n")+"]"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		oldIDs   []int
+		newIDs   []int
+		expected DatasetDeltaResult
+	}{
+		{"unchanged", []int{1, 2, 3}, []int{1, 2, 3}, DatasetDeltaResult{3, 3, 3, 0}},
+		{"insert in the middle", []int{1, 2, 3, 4}, []int{1, 2, 10, 3, 4}, DatasetDeltaResult{4, 5, 4, 1}},
+		{"delete and append", []int{1, 2, 3, 4}, []int{1, 3, 4, 5, 6}, DatasetDeltaResult{4, 5, 3, 2}},
+		{"reordered", []int{1, 2, 3, 4}, []int{3, 4, 1, 2}, DatasetDeltaResult{4, 4, 4, 0}},
+		{"from empty", []int{}, []int{7, 8}, DatasetDeltaResult{0, 2, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldPath := writeRecords("old.json", tt.oldIDs)
+			newPath := writeRecords("new.json", tt.newIDs)
+			deltaPath := filepath.Join(dir, "delta.json")
+			outPath := filepath.Join(dir, "out.json")
+
+			result, err := loader.CreateDatasetDelta(oldPath, newPath, deltaPath)
+			if err != nil {
+				t.Fatalf("CreateDatasetDelta() error = %v", err)
+			}
+			if *result != tt.expected {
+				t.Errorf("result = %+v, want %+v", *result, tt.expected)
+			}
+
+			count, err := loader.ApplyDatasetDelta(oldPath, deltaPath, outPath)
+			if err != nil {
+				t.Fatalf("ApplyDatasetDelta() error = %v", err)
+			}
+			if count != len(tt.newIDs) {
+				t.Errorf("ApplyDatasetDelta() wrote %d records, want %d", count, len(tt.newIDs))
+			}
+			got, _ := loader.LoadJSON(outPath)
+			want, _ := loader.LoadJSON(newPath)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("rebuilt dataset = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyDatasetDelta_WrongBase(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.json")
+	newPath := filepath.Join(dir, "new.json")
+	otherPath := filepath.Join(dir, "other.json")
+	deltaPath := filepath.Join(dir, "delta.json")
+	os.WriteFile(oldPath, []byte(`[{"id":1},{"id":2}]`), 0644)
+	os.WriteFile(newPath, []byte(`[{"id":2},{"id":3}]`), 0644)
+	os.WriteFile(otherPath, []byte(`[{"id":1},{"id":5}]`), 0644)
+
+	if _, err := loader.CreateDatasetDelta(oldPath, newPath, deltaPath); err != nil {
+		t.Fatalf("CreateDatasetDelta() error = %v", err)
+	}
+	_, err := loader.ApplyDatasetDelta(otherPath, deltaPath, filepath.Join(dir, "out.json"))
+	if err == nil || !strings.Contains(err.Error(), "does not apply") {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+
+	os.WriteFile(deltaPath, []byte(`{"ops":[]}`), 0644)
+	if _, err := loader.ApplyDatasetDelta(oldPath, deltaPath, filepath.Join(dir, "out.json")); err == nil {
+		t.Error("Expected error for malformed delta")
+	}
+	if _, err := loader.CreateDatasetDelta(filepath.Join(dir, "missing.json"), newPath, deltaPath); err == nil {
+		t.Error("Expected error for missing old dataset")
+	}
+}
@@ -0,0 +1,260 @@
+// dictionary.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DictionaryOptions configures the files BuildDictionary writes
+type DictionaryOptions struct {
+	OutputFile     string `json:"outputFile" js:"outputFile"`
+	DictionaryFile string `json:"dictionaryFile" js:"dictionaryFile"`
+}
+
+// Dictionary holds the distinct values of a field; the code of a value is its index in Values
+type Dictionary struct {
+	Field   string        `json:"field" js:"field"`
+	Values  []interface{} `json:"values" js:"values"`
+	Counts  []int         `json:"counts" js:"counts"`   // Records holding each value
+	Records int           `json:"records" js:"records"` // Records read
+	Missing int           `json:"missing" js:"missing"` // Records without the field, or with null
+}
+
+// dictionaryBuilder assigns codes to values in the order they are first seen.
+type dictionaryBuilder struct {
+	dict  Dictionary
+	codes map[string]int // Value as JSON -> code
+}
+
+// code returns the code of a value, assigning the next one to a new value.
+func (b *dictionaryBuilder) code(value interface{}) (int, error) {
+	if n, ok := value.(json.Number); ok {
+		// 1 and 1.0 are the same value
+		f, err := n.Float64()
+		if err != nil {
+			return 0, err
+		}
+		value = f
+	}
+	key, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	code, ok := b.codes[string(key)]
+	if !ok {
+		code = len(b.dict.Values)
+		b.codes[string(key)] = code
+		b.dict.Values = append(b.dict.Values, value)
+		b.dict.Counts = append(b.dict.Counts, 0)
+	}
+	b.dict.Counts[code]++
+	return code, nil
+}
+
+// BuildDictionary collects the distinct values of a categorical field and assigns each an
+// integer code, in the order values first appear, so replay data with many repeated strings,
+// such as user agents or product categories, can be held as small numbers and a single copy of
+// each value. Files ending in .csv are read as CSV with a header row and field names a column;
+// everything else is read as a JSON array or NDJSON and field may be a dotted path. Values keep
+// their JSON type, so "1" and 1 get different codes.
+//
+// Options:
+//   - outputFile: Also write the dataset with the field's values replaced by their codes, as a
+//     JSON array, or as CSV for CSV input; missing and null values, or empty CSV cells, are left
+//     as they are
+//   - dictionaryFile: Also write the dictionary as JSON, for decoding the encoded dataset later
+//
+// Returns: The field, its distinct values (the code of a value is its index), the number of
+// records holding each value, and the number of records read and without the field
+//
+// Example usage:
+//
+//	const dict = streamloader.buildDictionary("requests.json", "userAgent", {
+//		outputFile: "requests-encoded.json",
+//		dictionaryFile: "user-agents.json",
+//	});
+//	const requests = streamloader.loadJSON("requests-encoded.json");
+//	// In the default function:
+//	const userAgent = dict.values[requests[i].userAgent];
+func (s StreamLoader) BuildDictionary(filePath string, field string, options ...DictionaryOptions) (*Dictionary, error) {
+	if field == "" {
+		return nil, fmt.Errorf("field must not be empty")
+	}
+	var opts DictionaryOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	b := &dictionaryBuilder{dict: Dictionary{Field: field, Values: []interface{}{}, Counts: []int{}}, codes: make(map[string]int)}
+
+	var err error
+	if strings.EqualFold(inputExt(filePath), ".csv") {
+		err = b.encodeCSV(filePath, opts.OutputFile, s.call)
+	} else {
+		err = b.encodeJSON(filePath, opts.OutputFile, s.call)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DictionaryFile != "" {
+		encoded, err := json.Marshal(b.dict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode dictionary: %w", err)
+		}
+		file, err := createOutputFile(opts.DictionaryFile, JsonWriterOptions{}, gzip.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if _, err := file.Write(encoded); err != nil {
+			return nil, fmt.Errorf("failed to write dictionary: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return &b.dict, nil
+}
+
+// encodeJSON builds the dictionary from JSON records, writing them with codes to outputFilePath
+// unless it is empty.
+func (b *dictionaryBuilder) encodeJSON(filePath string, outputFilePath string, call callLimits) error {
+	var out *jsonArrayWriter
+	if outputFilePath != "" {
+		var err error
+		if out, err = createJsonArrayFile(outputFilePath, writeBufferSize()); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	field := b.dict.Field
+	err := forEachJsonRecord(filePath, call, func(raw json.RawMessage) (bool, error) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var record any
+		if err := dec.Decode(&record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", b.dict.Records, err)
+		}
+		b.dict.Records++
+
+		obj, key, ok := fieldParent(record, field)
+		if !ok || obj[key] == nil {
+			b.dict.Missing++
+		} else {
+			code, err := b.code(obj[key])
+			if err != nil {
+				return false, fmt.Errorf("failed to encode field %q of record %d: %w", field, b.dict.Records-1, err)
+			}
+			obj[key] = code
+		}
+		if out == nil {
+			return true, nil
+		}
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode record %d: %w", b.dict.Records-1, err)
+		}
+		return true, out.Write(encoded)
+	})
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		return out.Close()
+	}
+	return nil
+}
+
+// encodeCSV builds the dictionary from a CSV column, writing the rows with codes to
+// outputFilePath unless it is empty.
+func (b *dictionaryBuilder) encodeCSV(filePath string, outputFilePath string, call callLimits) error {
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+	csvReader := csv.NewReader(newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true))
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse CSV header: %w", err)
+	}
+	column := -1
+	for i, name := range header {
+		if strings.TrimSpace(name) == b.dict.Field {
+			column = i
+			break
+		}
+	}
+	if column < 0 {
+		return fmt.Errorf("column %q not found in CSV header", b.dict.Field)
+	}
+
+	var out *outputFile
+	var buffered *bufio.Writer
+	var writer *csv.Writer
+	if outputFilePath != "" {
+		if out, err = createOutputFile(outputFilePath, JsonWriterOptions{}, gzip.DefaultCompression); err != nil {
+			return err
+		}
+		defer out.Close()
+		buffered = bufio.NewWriterSize(out, writeBufferSize())
+		writer = csv.NewWriter(buffered)
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	line := 1
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV at line %d: %w", line, err)
+		}
+		b.dict.Records++
+		if column >= len(record) || record[column] == "" {
+			b.dict.Missing++
+		} else {
+			code, err := b.code(record[column])
+			if err != nil {
+				return err
+			}
+			record[column] = strconv.Itoa(code)
+		}
+		if writer != nil {
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV line %d: %w", line, err)
+			}
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	return out.Close()
+}
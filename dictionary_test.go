@@ -0,0 +1,86 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildDictionary(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	requests := filepath.Join(dir, "requests.ndjson")
+	os.WriteFile(requests, []byte(`{"id":1,"client":{"ua":"curl"},"tier":1}
+{"id":2,"client":{"ua":"firefox"},"tier":"1"}
+{"id":3,"client":{"ua":"curl"},"tier":1.0}
+{"id":4,"client":{}}
+{"id":5,"client":{"ua":null},"tier":12345678901234567890}
+`), 0644)
+
+	tests := []struct {
+		field   string
+		values  []interface{}
+		counts  []int
+		missing int
+	}{
+		{"client.ua", []interface{}{"curl", "firefox"}, []int{2, 1}, 2},
+		{"tier", []interface{}{1.0, "1", 12345678901234567890.0}, []int{2, 1, 1}, 1},
+	}
+	for _, tt := range tests {
+		dict, err := loader.BuildDictionary(requests, tt.field)
+		if err != nil {
+			t.Fatalf("BuildDictionary(%s) error = %v", tt.field, err)
+		}
+		if !reflect.DeepEqual(dict.Values, tt.values) || !reflect.DeepEqual(dict.Counts, tt.counts) || dict.Records != 5 || dict.Missing != tt.missing {
+			t.Errorf("BuildDictionary(%s) = %+v", tt.field, dict)
+		}
+	}
+
+	// The encoded dataset and the dictionary file
+	encoded := filepath.Join(dir, "requests-encoded.json")
+	dictFile := filepath.Join(dir, "ua.json")
+	dict, err := loader.BuildDictionary(requests, "client.ua", DictionaryOptions{OutputFile: encoded, DictionaryFile: dictFile})
+	if err != nil {
+		t.Fatalf("BuildDictionary() error = %v", err)
+	}
+	data, _ := os.ReadFile(encoded)
+	want := `[{"client":{"ua":0},"id":1,"tier":1},{"client":{"ua":1},"id":2,"tier":"1"},{"client":{"ua":0},"id":3,"tier":1.0},{"client":{},"id":4},{"client":{"ua":null},"id":5,"tier":12345678901234567890}]`
+	if string(data) != want {
+		t.Errorf("Encoded dataset = %s, want %s", data, want)
+	}
+	var saved Dictionary
+	data, _ = os.ReadFile(dictFile)
+	if err := json.Unmarshal(data, &saved); err != nil || !reflect.DeepEqual(saved, *dict) {
+		t.Errorf("Dictionary file = %s, %v, want %+v", data, err, *dict)
+	}
+
+	if _, err := loader.BuildDictionary(requests, ""); err == nil {
+		t.Error("Expected error for an empty field")
+	}
+}
+
+func TestBuildDictionaryCSV(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "orders.csv")
+	os.WriteFile(input, []byte("id,category\n1,books\n2,games\n3,\n4,books\n"), 0644)
+
+	encoded := filepath.Join(dir, "orders-encoded.csv")
+	dict, err := loader.BuildDictionary(input, "category", DictionaryOptions{OutputFile: encoded})
+	if err != nil {
+		t.Fatalf("BuildDictionary() error = %v", err)
+	}
+	if !reflect.DeepEqual(dict.Values, []interface{}{"books", "games"}) || dict.Records != 4 || dict.Missing != 1 {
+		t.Errorf("BuildDictionary() = %+v", dict)
+	}
+	data, _ := os.ReadFile(encoded)
+	if want := "id,category\n1,0\n2,1\n3,\n4,0\n"; string(data) != want {
+		t.Errorf("Encoded CSV = %q, want %q", data, want)
+	}
+
+	if _, err := loader.BuildDictionary(input, "missing"); err == nil {
+		t.Error("Expected error for a missing column")
+	}
+}
@@ -0,0 +1,68 @@
+// disk_space.go
+package streamloader
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// diskSpaceHeadroom is the fraction added to an output size estimate before it is compared
+// with the available space, since estimates from samples can be low
+const diskSpaceHeadroom = 0.1
+
+// diskSpaceSampleSize is the number of objects encoded to estimate an output's size
+const diskSpaceSampleSize = 100
+
+// ensureDiskSpace fails with a clear error if the checkDiskSpace option is set and the file
+// system holding outputFilePath doesn't have room for the estimated output. estimate is only
+// called when the check is enabled. On platforms where free space can't be queried the check
+// is skipped.
+func (o JsonWriterOptions) ensureDiskSpace(outputFilePath string, estimate func() int64) error {
+	if !o.CheckDiskSpace {
+		return nil
+	}
+	available, ok := availableDiskSpace(filepath.Dir(outputFilePath))
+	if !ok {
+		return nil
+	}
+	needed := estimate()
+	needed += int64(float64(needed) * diskSpaceHeadroom)
+	if uint64(needed) > available {
+		return fmt.Errorf("not enough disk space for %s: about %d bytes needed, %d bytes available", outputFilePath, needed, available)
+	}
+	return nil
+}
+
+// estimateObjectsSize estimates the size of objects written as a JSON array by encoding up to
+// diskSpaceSampleSize of them, spread evenly across the slice.
+func estimateObjectsSize(objects []interface{}) int64 {
+	if len(objects) == 0 {
+		return 2
+	}
+	step := len(objects)/diskSpaceSampleSize + 1
+	var sampled, bytes int64
+	for i := 0; i < len(objects); i += step {
+		encoded, err := json.Marshal(objects[i])
+		if err != nil {
+			// The writer reports the error itself
+			continue
+		}
+		sampled++
+		bytes += int64(len(encoded)) + 1
+	}
+	if sampled == 0 {
+		return 2
+	}
+	return bytes*int64(len(objects))/sampled + 2
+}
+
+// gzipUncompressedSize returns the uncompressed size recorded in the trailer of gzip data. For
+// data with several members it is the size of the last member, a lower bound.
+func gzipUncompressedSize(data []byte) int64 {
+	if len(data) < 18 {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint32(data[len(data)-4:]))
+}
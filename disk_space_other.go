@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+// disk_space_other.go
+package streamloader
+
+// availableDiskSpace can't query free space on this platform, so the check is skipped.
+func availableDiskSpace(dir string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,75 @@
+package streamloader
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnsureDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+
+	called := false
+	disabled := JsonWriterOptions{}
+	if err := disabled.ensureDiskSpace(path, func() int64 { called = true; return 1 << 62 }); err != nil || called {
+		t.Errorf("disabled check = %v, estimate called = %v", err, called)
+	}
+
+	if _, ok := availableDiskSpace(dir); !ok {
+		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+			t.Fatal("availableDiskSpace() failed")
+		}
+		t.Skip("free space can't be queried on this platform")
+	}
+	enabled := JsonWriterOptions{CheckDiskSpace: true}
+	if err := enabled.ensureDiskSpace(path, func() int64 { return 1024 }); err != nil {
+		t.Errorf("small output: %v", err)
+	}
+	err := enabled.ensureDiskSpace(path, func() int64 { return 1 << 62 })
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Errorf("huge output: expected error, got %v", err)
+	}
+
+	// The writers run the check before creating the file
+	loader := StreamLoader{}
+	if _, err := loader.WriteObjectsToJsonArrayFile([]interface{}{1, 2}, path, map[string]interface{}{"checkDiskSpace": true}); err != nil {
+		t.Errorf("WriteObjectsToJsonArrayFile() error = %v", err)
+	}
+	if _, err := loader.WriteCompressedObjectsToJsonArrayFile([]interface{}{1, 2}, path, map[string]interface{}{"checkDiskSpace": true}); err != nil {
+		t.Errorf("WriteCompressedObjectsToJsonArrayFile() error = %v", err)
+	}
+
+	// A batch whose gzip trailer claims 4GB, repeated until the output can't fit anywhere
+	huge := make([]byte, 18)
+	binary.LittleEndian.PutUint32(huge[14:], 0xFFFFFFFF)
+	batches := make([]interface{}, 1<<16)
+	for i := range batches {
+		batches[i] = base64.StdEncoding.EncodeToString(huge)
+	}
+	weightedPath := filepath.Join(dir, "weighted.json")
+	_, err = loader.WriteWeightedMultipleCompressedJsonLinesToArrayFile([][]interface{}{{batches, 1}}, weightedPath, map[string]interface{}{"checkDiskSpace": true})
+	if err == nil || !strings.Contains(err.Error(), "not enough disk space") {
+		t.Errorf("WriteWeightedMultipleCompressedJsonLinesToArrayFile() error = %v, want a disk space error", err)
+	}
+	if _, err := os.Stat(weightedPath); !os.IsNotExist(err) {
+		t.Errorf("output file created despite the disk space check: %v", err)
+	}
+}
+
+func TestEstimateObjectsSize(t *testing.T) {
+	objects := make([]interface{}, 1000)
+	for i := range objects {
+		objects[i] = map[string]interface{}{"name": "abcdefghij"} // 21 bytes encoded
+	}
+	if size := estimateObjectsSize(objects); size != 1000*22+2 {
+		t.Errorf("estimateObjectsSize() = %d, want %d", size, 1000*22+2)
+	}
+	if size := estimateObjectsSize(nil); size != 2 {
+		t.Errorf("estimateObjectsSize(nil) = %d, want 2", size)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+// disk_space_unix.go
+package streamloader
+
+import "syscall"
+
+// availableDiskSpace returns the bytes available to unprivileged users on the file system
+// holding dir.
+func availableDiskSpace(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
@@ -0,0 +1,149 @@
+// duplicate_keys.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DuplicateKey describes a key that appeared more than once in the same JSON object.
+type DuplicateKey struct {
+	Path  string `json:"path" js:"path"`
+	Key   string `json:"key" js:"key"`
+	Count int    `json:"count" js:"count"`
+}
+
+// FindDuplicateJsonKeys streams the given JSON file token by token and reports every key that
+// appears more than once within the same object. Standard decoding silently keeps the last
+// value of a duplicated key, so this is useful for validating generated fixtures before use.
+// Supports JSON arrays, JSON objects and NDJSON (each line is reported as $[lineIndex]).
+//
+// Paths use a JSONPath-like notation: $ is the root, .name selects an object key and [i]
+// selects an array element. Path points at the object containing the duplicated key.
+//
+// Example usage:
+//
+//	duplicates, err := streamloader.FindDuplicateJsonKeys("fixtures.json")
+//	// duplicates[0] = {path: "$[3]", key: "id", count: 2}
+func (s StreamLoader) FindDuplicateJsonKeys(filePath string) ([]DuplicateKey, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(bufio.NewReaderSize(file, readBufferSize()))
+
+	var values [][]DuplicateKey
+	for {
+		var found []DuplicateKey
+		path := "$[" + strconv.Itoa(len(values)) + "]"
+		err := walkJSONKeys(dec, path, func(d DuplicateKey) {
+			found = append(found, d)
+		})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		values = append(values, found)
+	}
+
+	duplicates := []DuplicateKey{}
+	for _, found := range values {
+		for _, d := range found {
+			// A single top-level value is addressed as $ rather than $[0]
+			if len(values) == 1 {
+				d.Path = "$" + strings.TrimPrefix(d.Path, "$[0]")
+			}
+			duplicates = append(duplicates, d)
+		}
+	}
+	return duplicates, nil
+}
+
+// checkDuplicateKeys returns an error listing every duplicated key found in a single JSON value.
+func checkDuplicateKeys(data []byte, path string) error {
+	var found []string
+	dec := json.NewDecoder(bytes.NewReader(data))
+	err := walkJSONKeys(dec, path, func(d DuplicateKey) {
+		found = append(found, fmt.Sprintf("%q at %s", d.Key, d.Path))
+	})
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if len(found) > 0 {
+		return fmt.Errorf("duplicate keys found: %s", strings.Join(found, ", "))
+	}
+	return nil
+}
+
+// walkJSONKeys consumes exactly one JSON value from the decoder and calls report for each
+// key that appears more than once in an object. It returns io.EOF when no value is left.
+func walkJSONKeys(dec *json.Decoder, path string, report func(DuplicateKey)) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil // Scalar value
+	}
+
+	switch delim {
+	case '{':
+		counts := make(map[string]int)
+		var order []string
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return fmt.Errorf("expected object key at %s, got %v", path, keyTok)
+			}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+			if err := walkJSONKeys(dec, path+"."+key, report); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+		for _, key := range order {
+			if counts[key] > 1 {
+				report(DuplicateKey{Path: path, Key: key, Count: counts[key]})
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := walkJSONKeys(dec, path+"["+strconv.Itoa(i)+"]", report); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+	default:
+		return fmt.Errorf("unexpected delimiter %v at %s", delim, path)
+	}
+
+	// Consume the closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return unexpectedEOF(err)
+	}
+	return nil
+}
+
+// unexpectedEOF converts io.EOF inside a value into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
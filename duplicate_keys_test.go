@@ -0,0 +1,135 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicateJsonKeys(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []DuplicateKey
+	}{
+		{
+			name:     "No duplicates",
+			content:  `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`,
+			expected: []DuplicateKey{},
+		},
+		{
+			name:    "Duplicate in array element",
+			content: `[{"id":1},{"id":2,"id":3,"name":"x"}]`,
+			expected: []DuplicateKey{
+				{Path: "$[1]", Key: "id", Count: 2},
+			},
+		},
+		{
+			name:    "Nested duplicates in object",
+			content: `{"user":{"name":"a","tags":[{"k":1,"k":2,"k":3}],"name":"b"}}`,
+			expected: []DuplicateKey{
+				{Path: "$.user.tags[0]", Key: "k", Count: 3},
+				{Path: "$.user", Key: "name", Count: 2},
+			},
+		},
+		{
+			name:    "NDJSON lines",
+			content: "{\"a\":1}\n{\"a\":1,\"a\":2}\n",
+			expected: []DuplicateKey{
+				{Path: "$[1]", Key: "a", Count: 2},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, "dup_"+string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			got, err := loader.FindDuplicateJsonKeys(path)
+			if err != nil {
+				t.Fatalf("FindDuplicateJsonKeys failed: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d duplicates, got %d: %+v", len(tt.expected), len(got), got)
+			}
+			for j := range got {
+				if got[j] != tt.expected[j] {
+					t.Errorf("Duplicate %d: expected %+v, got %+v", j, tt.expected[j], got[j])
+				}
+			}
+		})
+	}
+}
+
+func TestFindDuplicateJsonKeys_Errors(t *testing.T) {
+	loader := StreamLoader{}
+
+	if _, err := loader.FindDuplicateJsonKeys("no_such_file.json"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	path := filepath.Join(t.TempDir(), "truncated.json")
+	if err := os.WriteFile(path, []byte(`[{"a":1},{"b":`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := loader.FindDuplicateJsonKeys(path); err == nil {
+		t.Error("Expected error for truncated JSON")
+	}
+}
+
+func TestLoadJSON_DetectDuplicateKeys(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	arrayPath := filepath.Join(tempDir, "array.json")
+	os.WriteFile(arrayPath, []byte(`[{"id":1},{"id":2,"meta":{"x":1,"x":2}}]`), 0644)
+
+	objectPath := filepath.Join(tempDir, "object.json")
+	os.WriteFile(objectPath, []byte(`{"a":{"id":1},"a":{"id":2}}`), 0644)
+
+	ndjsonPath := filepath.Join(tempDir, "lines.ndjson")
+	os.WriteFile(ndjsonPath, []byte("{\"id\":1}\n{\"id\":2,\"id\":3}\n"), 0644)
+
+	cases := []struct {
+		path    string
+		message string
+	}{
+		{arrayPath, `"x" at $[1].meta`},
+		{objectPath, `"a" at $`},
+		{ndjsonPath, `"id" at $[1]`},
+	}
+
+	for _, c := range cases {
+		// Default behaviour keeps the last value silently
+		if _, err := loader.LoadJSON(c.path); err != nil {
+			t.Errorf("LoadJSON(%s) without detection failed: %v", filepath.Base(c.path), err)
+		}
+
+		_, err := loader.LoadJSON(c.path, JsonOptions{DetectDuplicateKeys: true})
+		if err == nil {
+			t.Errorf("LoadJSON(%s) expected duplicate key error", filepath.Base(c.path))
+			continue
+		}
+		if !strings.Contains(err.Error(), c.message) {
+			t.Errorf("LoadJSON(%s) error %q does not mention %s", filepath.Base(c.path), err, c.message)
+		}
+	}
+
+	// Clean files still load with detection enabled
+	cleanPath := filepath.Join(tempDir, "clean.json")
+	os.WriteFile(cleanPath, []byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`), 0644)
+	result, err := loader.LoadJSON(cleanPath, JsonOptions{DetectDuplicateKeys: true})
+	if err != nil {
+		t.Fatalf("LoadJSON with detection failed on clean file: %v", err)
+	}
+	if arr, ok := result.([]interface{}); !ok || len(arr) != 2 {
+		t.Errorf("Expected 2 records, got %v", result)
+	}
+}
@@ -0,0 +1,258 @@
+// duplicates.go
+package streamloader
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+)
+
+// Defaults of FindDuplicates
+const (
+	defaultDuplicateReport     = 100
+	defaultDuplicatePartitions = 64
+)
+
+// DuplicateOptions configures FindDuplicates
+type DuplicateOptions struct {
+	MaxReport  int    `json:"maxReport" js:"maxReport"`
+	SpillDir   string `json:"spillDir" js:"spillDir"`
+	Partitions int    `json:"partitions" js:"partitions"`
+}
+
+// DuplicateValue is a key value held by more than one record
+type DuplicateValue struct {
+	Key   string `json:"key" js:"key"`
+	Count int    `json:"count" js:"count"`
+}
+
+// DuplicateReport is the result of FindDuplicates
+type DuplicateReport struct {
+	Records          int              `json:"records" js:"records"`
+	Missing          int              `json:"missing" js:"missing"`
+	DistinctKeys     int              `json:"distinctKeys" js:"distinctKeys"`
+	DuplicateKeys    int              `json:"duplicateKeys" js:"duplicateKeys"`
+	DuplicateRecords int              `json:"duplicateRecords" js:"duplicateRecords"`
+	Duplicates       []DuplicateValue `json:"duplicates" js:"duplicates"`
+}
+
+// add counts the keys of a map of key counts, or of one partition of them, into the report,
+// offering the duplicate keys to top.
+func (r *DuplicateReport) add(counts map[string]int, top *duplicateHeap) {
+	r.DistinctKeys += len(counts)
+	for key, count := range counts {
+		if count > 1 {
+			r.DuplicateKeys++
+			r.DuplicateRecords += count - 1
+			top.offer(DuplicateValue{Key: key, Count: count})
+		}
+	}
+}
+
+// rankedBefore reports whether a duplicate key is listed before another: by count, then key.
+func rankedBefore(a, b DuplicateValue) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Key < b.Key
+}
+
+// duplicateHeap keeps the max duplicate keys ranked first, with the last ranked at the root, so
+// the report's memory doesn't grow with the number of duplicate keys.
+type duplicateHeap struct {
+	values []DuplicateValue
+	max    int
+}
+
+func (h *duplicateHeap) Len() int           { return len(h.values) }
+func (h *duplicateHeap) Less(i, j int) bool { return rankedBefore(h.values[j], h.values[i]) }
+func (h *duplicateHeap) Swap(i, j int)      { h.values[i], h.values[j] = h.values[j], h.values[i] }
+func (h *duplicateHeap) Push(x any)         { h.values = append(h.values, x.(DuplicateValue)) }
+func (h *duplicateHeap) Pop() any {
+	last := h.values[len(h.values)-1]
+	h.values = h.values[:len(h.values)-1]
+	return last
+}
+
+// offer adds a duplicate key if it ranks among the first max seen so far.
+func (h *duplicateHeap) offer(v DuplicateValue) {
+	if len(h.values) < h.max {
+		heap.Push(h, v)
+	} else if rankedBefore(v, h.values[0]) {
+		h.values[0] = v
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the kept duplicate keys in report order.
+func (h *duplicateHeap) sorted() []DuplicateValue {
+	sort.Slice(h.values, func(i, j int) bool {
+		return rankedBefore(h.values[i], h.values[j])
+	})
+	return h.values
+}
+
+// FindDuplicates counts the records of a JSON array, NDJSON or CSV file (with a header row) by a
+// key field and reports the keys held by more than one record, so corpus quality checks don't
+// need the whole file loaded into a JavaScript Set. Keys may be dotted paths into nested objects
+// and are compared by value, with numbers matching their string form, so 42 and "42" are the
+// same key; records whose key is missing or null are counted as missing.
+//
+// Only the distinct keys are kept in memory. For corpora with more distinct keys than fit, set
+// spillDir: the keys are then written to temporary files there, one per partition of their
+// hashes, and each partition is counted on its own, so memory holds the keys of one partition
+// and the maxReport duplicate keys listed. The temporary files are removed before returning.
+// Like other functions writing files, spilling fails in read-only mode.
+//
+// Options:
+//   - maxReport: Most duplicate keys listed, those with the highest counts first (default: 100)
+//   - spillDir: Directory for the temporary partition files (default: none, count in memory)
+//   - partitions: Number of partitions with spillDir (default: 64)
+//
+// Returns: The number of records read and without the key, the number of distinct keys, of keys
+// held by more than one record and of records repeating an earlier record's key, and up to
+// maxReport duplicate keys with their counts, by count and then key
+//
+// Example usage:
+//
+//	const report = streamloader.findDuplicates("users.ndjson", "email", { maxReport: 20, spillDir: "/tmp" });
+//	if (report.duplicateKeys > 0) {
+//		throw new Error(`${report.duplicateKeys} emails are used more than once: ${JSON.stringify(report.duplicates)}`);
+//	}
+func (s StreamLoader) FindDuplicates(filePath string, keyField string, options ...DuplicateOptions) (*DuplicateReport, error) {
+	if keyField == "" {
+		return nil, fmt.Errorf("keyField must not be empty")
+	}
+	var opts DuplicateOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxReport < 0 || opts.Partitions < 0 {
+		return nil, fmt.Errorf("maxReport and partitions must not be negative")
+	}
+	maxReport := opts.MaxReport
+	if maxReport == 0 {
+		maxReport = defaultDuplicateReport
+	}
+
+	report := &DuplicateReport{}
+	top := &duplicateHeap{values: []DuplicateValue{}, max: maxReport}
+	var err error
+	if opts.SpillDir != "" {
+		partitions := opts.Partitions
+		if partitions == 0 {
+			partitions = defaultDuplicatePartitions
+		}
+		err = countKeysSpilled(filePath, keyField, opts.SpillDir, partitions, report, top, s.call)
+	} else {
+		counts := make(map[string]int)
+		err = forEachDatasetKey(filePath, keyField, report, s.call, func(key string) error {
+			counts[key]++
+			return nil
+		})
+		report.add(counts, top)
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.Duplicates = top.sorted()
+	return report, nil
+}
+
+// forEachDatasetKey calls fn with the key of every record of a dataset file that has one,
+// counting the records read and without a key in report.
+func forEachDatasetKey(filePath string, keyField string, report *DuplicateReport, call callLimits, fn func(string) error) error {
+	open := func(filePath string) (*datasetRecords, error) { return openDatasetRecords(filePath, false, nil, call) }
+	return forEachDatasetRecord(open, filePath, func(_ int, record interface{}) error {
+		report.Records++
+		value, found := lookupField(record, keyField)
+		if !found || value == nil {
+			report.Missing++
+			return nil
+		}
+		return fn(keyString(value))
+	})
+}
+
+// countKeysSpilled counts the keys of a dataset file through partition files in dir: every key
+// is appended to the file of its hash's partition as a JSON string line, and the partitions are
+// then counted one at a time. The partition files hold slots in the file pool while open.
+func countKeysSpilled(filePath string, keyField string, dir string, partitions int, report *DuplicateReport, top *duplicateHeap, call callLimits) error {
+	if err := checkWritable(dir); err != nil {
+		return err
+	}
+	files := make([]*os.File, 0, partitions)
+	writers := make([]*bufio.Writer, 0, partitions)
+	slots := make([]*fileSlot, 0, partitions)
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+		for _, slot := range slots {
+			slot.release()
+		}
+	}()
+	for i := 0; i < partitions; i++ {
+		slot, err := acquireFileSlot(dir)
+		if err != nil {
+			return err
+		}
+		slots = append(slots, slot)
+		file, err := os.CreateTemp(dir, "duplicates-*.part")
+		if err != nil {
+			return fmt.Errorf("failed to create partition file: %w", err)
+		}
+		files = append(files, file)
+		writers = append(writers, bufio.NewWriterSize(file, groupOutputBufferSize))
+	}
+
+	err := forEachDatasetKey(filePath, keyField, report, call, func(key string) error {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		encoded, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		w := writers[h.Sum64()%uint64(partitions)]
+		w.Write(encoded)
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	for i, w := range writers {
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write partition file: %w", err)
+		}
+		if _, err := files[i].Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read partition file: %w", err)
+		}
+	}
+
+	for _, file := range files {
+		counts := make(map[string]int)
+		reader := bufio.NewReaderSize(file, readBufferSize())
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read partition file: %w", err)
+			}
+			var key string
+			if err := json.Unmarshal(line, &key); err != nil {
+				return fmt.Errorf("failed to decode partition file: %w", err)
+			}
+			counts[key]++
+		}
+		report.add(counts, top)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package streamloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	ndjson := filepath.Join(dir, "users.ndjson")
+	os.WriteFile(ndjson, []byte(strings.Join([]string{
+		`{"user":{"email":"a@x"}}`,
+		`{"user":{"email":"b@x"}}`,
+		`{"user":{"email":"a@x"}}`,
+		`{"user":{}}`,
+		`{"user":{"email":"c@x"}}`,
+		`{"user":{"email":"b@x"}}`,
+		`{"user":{"email":"a@x"}}`,
+		`{"user":{"email":null}}`,
+	}, "\n")), 0644)
+	csvPath := filepath.Join(dir, "orders.csv")
+	os.WriteFile(csvPath, []byte("id,total\n1,5\n2,7\n1,9\n"), 0644)
+	spillDir := t.TempDir()
+
+	full := DuplicateReport{
+		Records:          8,
+		Missing:          2,
+		DistinctKeys:     3,
+		DuplicateKeys:    2,
+		DuplicateRecords: 3,
+		Duplicates:       []DuplicateValue{{Key: "a@x", Count: 3}, {Key: "b@x", Count: 2}},
+	}
+	tests := []struct {
+		name    string
+		file    string
+		field   string
+		options DuplicateOptions
+		want    DuplicateReport
+		wantErr string
+	}{
+		{name: "in memory", file: ndjson, field: "user.email", want: full},
+		{name: "spilled", file: ndjson, field: "user.email", options: DuplicateOptions{SpillDir: spillDir, Partitions: 4}, want: full},
+		{
+			name:    "maxReport",
+			file:    ndjson,
+			field:   "user.email",
+			options: DuplicateOptions{MaxReport: 1},
+			want: DuplicateReport{
+				Records: 8, Missing: 2, DistinctKeys: 3, DuplicateKeys: 2, DuplicateRecords: 3,
+				Duplicates: []DuplicateValue{{Key: "a@x", Count: 3}},
+			},
+		},
+		{
+			name:  "csv",
+			file:  csvPath,
+			field: "id",
+			want: DuplicateReport{
+				Records: 3, DistinctKeys: 2, DuplicateKeys: 1, DuplicateRecords: 1,
+				Duplicates: []DuplicateValue{{Key: "1", Count: 2}},
+			},
+		},
+		{name: "empty key field", file: ndjson, wantErr: "keyField must not be empty"},
+		{name: "missing spill dir", file: ndjson, field: "user.email", options: DuplicateOptions{SpillDir: filepath.Join(dir, "missing")}, wantErr: "partition file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loader.FindDuplicates(tt.file, tt.field, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FindDuplicates() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindDuplicates() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("FindDuplicates() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if entries, _ := os.ReadDir(spillDir); len(entries) != 0 {
+		t.Errorf("partition files left in spillDir: %v", entries)
+	}
+	if n := pooledFiles(); n != 0 {
+		t.Errorf("%d file pool slots still in use", n)
+	}
+
+	t.Run("read-only", func(t *testing.T) {
+		setReadOnly(t)
+		if _, err := loader.FindDuplicates(ndjson, "user.email", DuplicateOptions{SpillDir: spillDir}); !errors.Is(err, errReadOnly) {
+			t.Errorf("FindDuplicates() with spillDir error = %v, want read-only error", err)
+		}
+		if _, err := loader.FindDuplicates(ndjson, "user.email"); err != nil {
+			t.Errorf("FindDuplicates() in memory error = %v", err)
+		}
+	})
+}
+
+func TestFindDuplicatesSpilledMatchesInMemory(t *testing.T) {
+	loader := StreamLoader{}
+	path := filepath.Join(t.TempDir(), "keys.ndjson")
+	var b strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&b, "{\"k\":%d}\n", i%3700)
+	}
+	os.WriteFile(path, []byte(b.String()), 0644)
+
+	inMemory, err := loader.FindDuplicates(path, "k", DuplicateOptions{MaxReport: 10000})
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	spilled, err := loader.FindDuplicates(path, "k", DuplicateOptions{MaxReport: 10000, SpillDir: t.TempDir(), Partitions: 7})
+	if err != nil {
+		t.Fatalf("FindDuplicates() spilled error = %v", err)
+	}
+	if inMemory.DistinctKeys != 3700 || inMemory.DuplicateKeys != 1300 || inMemory.DuplicateRecords != 1300 {
+		t.Errorf("in memory = %d distinct, %d duplicate keys, %d duplicate records", inMemory.DistinctKeys, inMemory.DuplicateKeys, inMemory.DuplicateRecords)
+	}
+	if !reflect.DeepEqual(inMemory, spilled) {
+		t.Errorf("spilled report differs from the in-memory one")
+	}
+
+	// A smaller maxReport keeps the same first keys
+	top, err := loader.FindDuplicates(path, "k", DuplicateOptions{MaxReport: 5, SpillDir: t.TempDir(), Partitions: 7})
+	if err != nil {
+		t.Fatalf("FindDuplicates() maxReport error = %v", err)
+	}
+	if !reflect.DeepEqual(top.Duplicates, inMemory.Duplicates[:5]) {
+		t.Errorf("top duplicates = %v, want %v", top.Duplicates, inMemory.Duplicates[:5])
+	}
+}
@@ -0,0 +1,222 @@
+// expand_uri.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExpandUriOptions configures ExpandUriTemplates
+type ExpandUriOptions struct {
+	Count         int    `json:"count" js:"count"`
+	WeightField   string `json:"weightField" js:"weightField"`
+	TemplateField string `json:"templateField" js:"templateField"`
+	Seed          int64  `json:"seed" js:"seed"`
+}
+
+// UriExpansionResult is the result of ExpandUriTemplates
+type UriExpansionResult struct {
+	Written int            `json:"written" js:"written"`
+	Counts  map[string]int `json:"counts" js:"counts"` // URIs written per template
+}
+
+// uriTemplatePart is a literal run of a URI template, or a placeholder filled from a dataset.
+type uriTemplatePart struct {
+	literal string
+	dataset string // Dataset of a placeholder, "" for literals
+	field   string // Dotted path into the drawn record, "" for the record itself
+	query   bool   // The placeholder is in the query string, so its value is query-escaped
+}
+
+// uriTemplate is a parsed template with the datasets it draws from.
+type uriTemplate struct {
+	text     string
+	method   interface{}
+	parts    []uriTemplatePart
+	datasets []string // Distinct datasets of the placeholders, in order of appearance
+}
+
+// parseUriTemplate splits a template into literals and {dataset} or {dataset.field} placeholders.
+func parseUriTemplate(text string) (*uriTemplate, error) {
+	t := &uriTemplate{text: text}
+	seen := make(map[string]bool)
+	query := false
+	rest := text
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			t.parts = append(t.parts, uriTemplatePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, uriTemplatePart{literal: rest[:open]})
+			query = query || strings.Contains(rest[:open], "?")
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in URI template %q", text)
+		}
+		name := strings.TrimSpace(rest[open+1 : open+end])
+		if name == "" {
+			return nil, fmt.Errorf("empty placeholder in URI template %q", text)
+		}
+		dataset, field, _ := strings.Cut(name, ".")
+		t.parts = append(t.parts, uriTemplatePart{dataset: dataset, field: field, query: query})
+		if !seen[dataset] {
+			seen[dataset] = true
+			t.datasets = append(t.datasets, dataset)
+		}
+		rest = rest[open+end+1:]
+	}
+	return t, nil
+}
+
+// ExpandUriTemplates writes a corpus of concrete URIs for a recorded traffic mix: each URI comes
+// from a template chosen in proportion to its weight, like CompileWeights, with its placeholders
+// filled from records drawn from parameter datasets. This replaces an offline script turning
+// recording stats into a replayable request file.
+//
+// stats is a recording stats file, whose filterStats entries are the templates, a JSON array
+// or NDJSON file of entries, or an array of entries. Each entry holds a template and a weight,
+// and may hold a method, copied to the URIs built from it. Placeholders name a dataset of
+// paramsFiles and optionally a field of its records, as a dotted path: in
+// "/stores/{store.id}/items?sort={order}", {store.id} is the id of a record of the store
+// dataset and {order} a whole record of the order dataset, such as a string of a JSON array.
+// Placeholders of the same dataset in one URI share its record. Values are escaped for their
+// position, as a path segment or a query value; objects and arrays are inserted as JSON.
+//
+// paramsFiles maps dataset names to JSON array, NDJSON or CSV files (with a header row); they
+// are read into memory once, while the URIs are streamed to outputFilePath as a JSON array of
+// {method, uri, template} objects.
+//
+// Options:
+//   - count: Number of URIs to write (required)
+//   - weightField: Field (or dotted path) of the entries holding the weight (default: "weight")
+//   - templateField: Field of the entries holding the template (default: "uriTemplate")
+//   - seed: Seed of the draws; the same seed and inputs always produce the same corpus (default: 0)
+//
+// Returns: The number of URIs written, and the number written per template
+//
+// Example usage:
+//
+//	streamloader.expandUriTemplates("recording-stats.json", {
+//		store: "stores.csv",
+//		user: "users.json",
+//	}, "requests.json", { count: 100000, seed: 7 });
+//	const requests = streamloader.loadJSON("requests.json");
+func (s StreamLoader) ExpandUriTemplates(stats interface{}, paramsFiles map[string]string, outputFilePath string, options ExpandUriOptions) (*UriExpansionResult, error) {
+	if options.Count <= 0 {
+		return nil, fmt.Errorf("count must be positive, got %d", options.Count)
+	}
+	if options.WeightField == "" {
+		options.WeightField = "weight"
+	}
+	if options.TemplateField == "" {
+		options.TemplateField = "uriTemplate"
+	}
+
+	sampler, err := StreamLoader{}.CompileWeights(stats, options.WeightField, options.Seed)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]*uriTemplate, len(sampler.entries))
+	for i, entry := range sampler.entries {
+		value, found := lookupField(entry, options.TemplateField)
+		text, ok := value.(string)
+		if !found || !ok {
+			return nil, fmt.Errorf("entry %d: %q is not a URI template string", i, options.TemplateField)
+		}
+		if templates[i], err = parseUriTemplate(text); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if method, found := lookupField(entry, "method"); found {
+			templates[i].method = method
+		}
+		for _, name := range templates[i].datasets {
+			if _, ok := paramsFiles[name]; !ok {
+				return nil, fmt.Errorf("entry %d: URI template %q uses dataset %q, which is not in paramsFiles", i, text, name)
+			}
+		}
+	}
+
+	// Load the datasets the templates use
+	datasets := make(map[string][]interface{})
+	open := func(filePath string) (*datasetRecords, error) {
+		return openDatasetRecords(filePath, false, nil, s.call)
+	}
+	for _, t := range templates {
+		for _, name := range t.datasets {
+			if _, loaded := datasets[name]; loaded {
+				continue
+			}
+			var records []interface{}
+			err := forEachDatasetRecord(open, paramsFiles[name], func(_ int, record interface{}) error {
+				records = append(records, record)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(records) == 0 {
+				return nil, fmt.Errorf("dataset %q (%s) has no records", name, paramsFiles[name])
+			}
+			datasets[name] = records
+		}
+	}
+
+	out, err := createJsonArrayFile(outputFilePath, writeBufferSize())
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	result := &UriExpansionResult{Counts: make(map[string]int)}
+	draws := int64(0)
+	drawn := make(map[string]interface{})
+	var uri strings.Builder
+	for n := 0; n < options.Count; n++ {
+		t := templates[sampler.NextIndex()]
+		for _, name := range t.datasets {
+			records := datasets[name]
+			drawn[name] = records[pickIndex(options.Seed, draws, len(records))]
+			draws++
+		}
+
+		uri.Reset()
+		for _, part := range t.parts {
+			if part.dataset == "" {
+				uri.WriteString(part.literal)
+				continue
+			}
+			value, found := lookupField(drawn[part.dataset], part.field)
+			if !found || value == nil {
+				return nil, fmt.Errorf("URI template %q: record of dataset %q has no %q field", t.text, part.dataset, part.field)
+			}
+			if part.query {
+				uri.WriteString(url.QueryEscape(keyString(value)))
+			} else {
+				uri.WriteString(url.PathEscape(keyString(value)))
+			}
+		}
+
+		record := map[string]interface{}{"uri": uri.String(), "template": t.text}
+		if t.method != nil {
+			record["method"] = t.method
+		}
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode URI %d: %w", n, err)
+		}
+		if err := out.Write(encoded); err != nil {
+			return nil, err
+		}
+		result.Counts[t.text]++
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	result.Written = options.Count
+	return result, nil
+}
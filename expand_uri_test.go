@@ -0,0 +1,106 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandUriTemplates(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	stats := filepath.Join(dir, "recording-stats.json")
+	os.WriteFile(stats, []byte(`{"recordingId": "x", "filterStats": [
+		{"method": "GET", "uriTemplate": "/stores/{store.id}/items?region={store.region}&q={term}", "weight": 3},
+		{"method": "POST", "uriTemplate": "/carts", "weight": 1},
+		{"method": "GET", "uriTemplate": "/never", "weight": 0}
+	]}`), 0644)
+	stores := filepath.Join(dir, "stores.csv")
+	os.WriteFile(stores, []byte("id,region\ns1,eu west\ns2,us/east\n"), 0644)
+	terms := filepath.Join(dir, "terms.json")
+	os.WriteFile(terms, []byte(`["coffee & tea", "milk", 42]`), 0644)
+	params := map[string]string{"store": stores, "term": terms}
+
+	output := filepath.Join(dir, "requests.json")
+	result, err := loader.ExpandUriTemplates(stats, params, output, ExpandUriOptions{Count: 2000, Seed: 7})
+	if err != nil {
+		t.Fatalf("ExpandUriTemplates() error = %v", err)
+	}
+	template := "/stores/{store.id}/items?region={store.region}&q={term}"
+	if result.Written != 2000 || result.Counts[template]+result.Counts["/carts"] != 2000 || result.Counts["/never"] != 0 {
+		t.Errorf("ExpandUriTemplates() = %+v", result)
+	}
+	if share := float64(result.Counts[template]) / 2000; share < 0.7 || share > 0.8 {
+		t.Errorf("share of %s = %v, want about 0.75", template, share)
+	}
+
+	data, _ := os.ReadFile(output)
+	var requests []map[string]string
+	if err := json.Unmarshal(data, &requests); err != nil {
+		t.Fatalf("output is not a JSON array: %v", err)
+	}
+	if len(requests) != 2000 {
+		t.Fatalf("output has %d URIs, want 2000", len(requests))
+	}
+	regions := map[string]string{"s1": "eu west", "s2": "us/east"}
+	for _, request := range requests {
+		if request["template"] == "/carts" {
+			if request["method"] != "POST" || request["uri"] != "/carts" {
+				t.Errorf("request = %v", request)
+			}
+			continue
+		}
+		u, err := url.Parse(request["uri"])
+		if err != nil || request["method"] != "GET" {
+			t.Fatalf("request = %v (%v)", request, err)
+		}
+		// The path and the query of a URI use the same store record
+		store := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/stores/"), "/items")
+		if u.Query().Get("region") != regions[store] {
+			t.Errorf("uri %s: region %q for store %q", request["uri"], u.Query().Get("region"), store)
+		}
+		if q := u.Query().Get("q"); q != "coffee & tea" && q != "milk" && q != "42" {
+			t.Errorf("uri %s: q = %q", request["uri"], q)
+		}
+	}
+
+	// The same seed writes the same corpus
+	again := filepath.Join(dir, "again.json")
+	loader.ExpandUriTemplates(stats, params, again, ExpandUriOptions{Count: 2000, Seed: 7})
+	if other, _ := os.ReadFile(again); string(other) != string(data) {
+		t.Error("ExpandUriTemplates() with the same seed wrote a different corpus")
+	}
+
+	// Entries as an array, with other fields
+	entries := []interface{}{map[string]interface{}{"t": map[string]interface{}{"path": "/terms/{term}"}, "w": 1}}
+	result, err = loader.ExpandUriTemplates(entries, params, output, ExpandUriOptions{Count: 3, WeightField: "w", TemplateField: "t.path"})
+	if err != nil || result.Written != 3 {
+		t.Fatalf("ExpandUriTemplates(array) = %+v, %v", result, err)
+	}
+	data, _ = os.ReadFile(output)
+	if strings.Contains(string(data), `"method"`) || !strings.Contains(string(data), `"/terms/`) {
+		t.Errorf("ExpandUriTemplates(array) wrote %s", data)
+	}
+
+	// Errors
+	invalid := []struct {
+		stats   interface{}
+		params  map[string]string
+		options ExpandUriOptions
+	}{
+		{stats, params, ExpandUriOptions{}},
+		{stats, map[string]string{"store": stores}, ExpandUriOptions{Count: 1}},
+		{stats, params, ExpandUriOptions{Count: 1, TemplateField: "missing"}},
+		{[]interface{}{map[string]interface{}{"uriTemplate": "/a/{x", "weight": 1}}, params, ExpandUriOptions{Count: 1}},
+		{[]interface{}{map[string]interface{}{"uriTemplate": "/a/{store.missing}", "weight": 1}}, params, ExpandUriOptions{Count: 1}},
+		{stats, map[string]string{"store": stores, "term": filepath.Join(dir, "missing.json")}, ExpandUriOptions{Count: 1}},
+	}
+	for i, tt := range invalid {
+		if _, err := loader.ExpandUriTemplates(tt.stats, tt.params, filepath.Join(dir, "invalid.json"), tt.options); err == nil {
+			t.Errorf("case %d: ExpandUriTemplates() expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// expiry.go
+package streamloader
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// testStart is when the module was loaded, which is when k6 starts the test run
+var testStart = time.Now()
+
+// expiryReference returns the function giving the time records are checked against: "now"
+// (the default) for the wall clock at the time of the check, or "testStart".
+func expiryReference(reference string) (func() time.Time, error) {
+	switch reference {
+	case "", "now":
+		return time.Now, nil
+	case "testStart":
+		return func() time.Time { return testStart }, nil
+	default:
+		return nil, fmt.Errorf("invalid expiry reference %q: expected \"now\" or \"testStart\"", reference)
+	}
+}
+
+// parseTimestamp reads a timestamp field: an RFC 3339 string such as "2025-06-29T06:56:04.299Z",
+// or a Unix time number in seconds or, if larger than 1e11, milliseconds.
+func parseTimestamp(v interface{}) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339 or Unix time", s)
+		}
+		v = n
+	}
+	n, ok := numberValue(v)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid timestamp %v: expected RFC 3339 or Unix time", v)
+	}
+	if math.Abs(n) > 1e11 {
+		return time.UnixMilli(int64(n)), nil
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// expiryCheck drops records whose timestamp field is in the past.
+type expiryCheck struct {
+	field string
+	now   func() time.Time
+}
+
+// expired reports whether a record's timestamp field is before the reference time. Records
+// without the field, or with a null value, never expire.
+func (e *expiryCheck) expired(record any) (bool, error) {
+	value, found := lookupField(record, e.field)
+	if !found || value == nil {
+		return false, nil
+	}
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return false, fmt.Errorf("field %q: %w", e.field, err)
+	}
+	return t.Before(e.now()), nil
+}
+
+// recordPipeline applies the per-record JsonOptions of the loaders: field decoding and
+// dropping expired records.
+type recordPipeline struct {
+	decoders []fieldDecoder
+	expiry   *expiryCheck // nil unless dropExpired is set
+}
+
+// newRecordPipeline compiles the per-record options.
+func newRecordPipeline(opts JsonOptions) (*recordPipeline, error) {
+	decoders, err := compileFieldDecoders(opts.DecodeFields)
+	if err != nil {
+		return nil, err
+	}
+	p := &recordPipeline{decoders: decoders}
+	if opts.DropExpired != "" {
+		now, err := expiryReference(opts.ExpiryReference)
+		if err != nil {
+			return nil, err
+		}
+		p.expiry = &expiryCheck{field: opts.DropExpired, now: now}
+	}
+	return p, nil
+}
+
+// apply processes a record in place and reports whether to keep it.
+func (p *recordPipeline) apply(record any) (bool, error) {
+	if p.expiry != nil {
+		if expired, err := p.expiry.expired(record); err != nil || expired {
+			return false, err
+		}
+	}
+	return true, decodeFields(record, p.decoders)
+}
@@ -0,0 +1,151 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 6, 29, 6, 56, 4, 299000000, time.UTC)
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"RFC 3339", "2025-06-29T06:56:04.299Z"},
+		{"RFC 3339 with offset", "2025-06-29T08:56:04.299+02:00"},
+		{"Unix milliseconds", float64(want.UnixMilli())},
+		{"Unix seconds", float64(want.UnixMilli()) / 1000},
+		{"Unix milliseconds string", fmt.Sprint(want.UnixMilli())},
+		{"int64", want.UnixMilli()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestamp(tt.value)
+			if err != nil {
+				t.Fatalf("parseTimestamp() error = %v", err)
+			}
+			if got.Sub(want).Abs() > time.Millisecond {
+				t.Errorf("parseTimestamp() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	for _, invalid := range []interface{}{"yesterday", true} {
+		if _, err := parseTimestamp(invalid); err == nil {
+			t.Errorf("parseTimestamp(%v) succeeded", invalid)
+		}
+	}
+}
+
+func TestLoadJSONDropExpired(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	sinceStart := testStart.Add(time.Millisecond).UnixMilli()
+
+	records := []string{
+		fmt.Sprintf(`{"id":1,"session":{"expireAt":%q}}`, past),
+		fmt.Sprintf(`{"id":2,"session":{"expireAt":%q}}`, future),
+		`{"id":3}`,
+		`{"id":4,"session":{"expireAt":null}}`,
+		fmt.Sprintf(`{"id":5,"session":{"expireAt":%d}}`, sinceStart),
+	}
+
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		reference string
+		want      []float64
+	}{
+		{"array against the wall clock", "data.json", "[" + strings.Join(records, ",") + "]", "", []float64{2, 3, 4}},
+		{"NDJSON against the test start", "data.ndjson", strings.Join(records, "\n"), "testStart", []float64{2, 3, 4, 5}},
+	}
+
+	// Let the wall clock pass the timestamp of record 5
+	time.Sleep(5 * time.Millisecond)
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			data, err := loader.LoadJSON(path, JsonOptions{DropExpired: "session.expireAt", ExpiryReference: tt.reference})
+			if err != nil {
+				t.Fatalf("LoadJSON() error = %v", err)
+			}
+			var ids []float64
+			switch records := data.(type) {
+			case []interface{}:
+				for _, r := range records {
+					ids = append(ids, r.(map[string]interface{})["id"].(float64))
+				}
+			case []map[string]any:
+				for _, r := range records {
+					ids = append(ids, r["id"].(float64))
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("kept ids %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJSONDropExpiredErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte(`[{"expireAt":"soon"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader := StreamLoader{}
+	if _, err := loader.LoadJSON(path, JsonOptions{DropExpired: "expireAt"}); err == nil || !strings.Contains(err.Error(), `record 0: field "expireAt": invalid timestamp`) {
+		t.Errorf("LoadJSON() error = %v", err)
+	}
+	if _, err := loader.LoadJSON(path, JsonOptions{DropExpired: "expireAt", ExpiryReference: "yesterday"}); err == nil || !strings.Contains(err.Error(), "invalid expiry reference") {
+		t.Errorf("LoadJSON() error = %v", err)
+	}
+}
+
+func TestIteratorExpiredConditions(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	values := []interface{}{
+		map[string]interface{}{"id": float64(1), "endTime": past},
+		map[string]interface{}{"id": float64(2), "endTime": future},
+		map[string]interface{}{"id": float64(3)},
+		map[string]interface{}{"id": float64(4), "endTime": "garbage"},
+	}
+
+	tests := []struct {
+		op   string
+		want []interface{}
+	}{
+		{"expired", []interface{}{float64(1)}},
+		{"notExpired", []interface{}{float64(2), float64(3)}},
+	}
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.op, func(t *testing.T) {
+			it, err := loader.Iterate(values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filtered, err := it.Filter(map[string]interface{}{"field": "endTime", "op": tt.op})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(t, filtered); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Filter(%s) = %v, want %v", tt.op, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := compileCondition(IteratorCondition{Field: "endTime", Op: "expired", Value: "later"}); err == nil {
+		t.Error("expected an invalid reference to fail")
+	}
+}
@@ -0,0 +1,201 @@
+// explain.go
+package streamloader
+
+import (
+	"runtime"
+	"time"
+)
+
+// explainHeapSampleInterval is the number of rows between heap usage samples
+const explainHeapSampleInterval = 8192
+
+// PipelineExplain is the result of ExplainProcessCsvFile
+type PipelineExplain struct {
+	Result [][]interface{} `json:"result" js:"result"`
+	Stats  PipelineStats   `json:"stats" js:"stats"`
+}
+
+// PipelineStats holds per-stage statistics of a ProcessCsvFile run
+type PipelineStats struct {
+	Files             int              `json:"files" js:"files"`
+	RowsRead          int              `json:"rowsRead" js:"rowsRead"`
+	BlankRowsSkipped  int              `json:"blankRowsSkipped" js:"blankRowsSkipped"`
+	HeaderRowsSkipped int              `json:"headerRowsSkipped" js:"headerRowsSkipped"`
+	Filters           []FilterStats    `json:"filters" js:"filters"`
+	Transforms        []TransformStats `json:"transforms" js:"transforms"`
+	ProjectionMs      float64          `json:"projectionMs" js:"projectionMs"`
+	RowsOut           int              `json:"rowsOut" js:"rowsOut"`
+	Groups            int              `json:"groups" js:"groups"`
+	DurationMs        float64          `json:"durationMs" js:"durationMs"`
+	PeakHeapBytes     uint64           `json:"peakHeapBytes" js:"peakHeapBytes"`
+}
+
+// FilterStats describes how many rows reached a filter and how many it let through
+type FilterStats struct {
+	Type       string  `json:"type" js:"type"`
+	Column     int     `json:"column" js:"column"`
+	RowsIn     int     `json:"rowsIn" js:"rowsIn"`
+	RowsOut    int     `json:"rowsOut" js:"rowsOut"`
+	DurationMs float64 `json:"durationMs" js:"durationMs"`
+}
+
+// TransformStats describes how many rows a transform was applied to and the time it took
+type TransformStats struct {
+	Type       string  `json:"type" js:"type"`
+	Column     int     `json:"column" js:"column"`
+	Rows       int     `json:"rows" js:"rows"`
+	DurationMs float64 `json:"durationMs" js:"durationMs"`
+}
+
+// pipelineTrace collects statistics while a pipeline runs. All methods are no-ops on a nil
+// trace, so the pipeline calls them unconditionally and pays nothing when not explaining.
+type pipelineTrace struct {
+	stats          PipelineStats
+	filterTime     []time.Duration
+	transformTime  []time.Duration
+	projectionTime time.Duration
+}
+
+// newPipelineTrace prepares per-stage counters for the configured filters and transforms.
+func newPipelineTrace(options ProcessCsvOptions) *pipelineTrace {
+	t := &pipelineTrace{
+		stats: PipelineStats{
+			Filters:    make([]FilterStats, len(options.Filters)),
+			Transforms: make([]TransformStats, len(options.Transforms)),
+		},
+		filterTime:    make([]time.Duration, len(options.Filters)),
+		transformTime: make([]time.Duration, len(options.Transforms)),
+	}
+	for i, f := range options.Filters {
+		t.stats.Filters[i] = FilterStats{Type: f.Type, Column: f.Column}
+	}
+	for i, tr := range options.Transforms {
+		t.stats.Transforms[i] = TransformStats{Type: tr.Type, Column: tr.Column}
+	}
+	return t
+}
+
+// ExplainProcessCsvFile runs ProcessCsvFile with the same arguments and returns its result
+// together with per-stage statistics: rows in and out of every filter, time spent in each
+// filter, transform and the projection, the number of groups, and the peak heap usage. Use it
+// to find which filter drops most rows or which stage dominates the runtime.
+//
+// The cache option is ignored so the statistics always describe a real run. Timing every stage
+// adds overhead, so keep using ProcessCsvFile in the load test itself.
+//
+// Example usage:
+//
+//	explain, err := streamloader.ExplainProcessCsvFile("data.csv", options)
+//	// explain.Stats.Filters[0] = {Type: "regexMatch", Column: 3, RowsIn: 10000, RowsOut: 1000, ...}
+func (s StreamLoader) ExplainProcessCsvFile(filePath interface{}, options ProcessCsvOptions) (*PipelineExplain, error) {
+	paths, err := resolveCsvPaths(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := newPipelineTrace(options)
+	start := time.Now()
+	trace.sampleHeap()
+	result, err := processCsvPaths(paths, options, trace, s.call)
+	if err != nil {
+		return nil, err
+	}
+	trace.sampleHeap()
+	trace.stats.DurationMs = durationMs(time.Since(start))
+
+	for i, d := range trace.filterTime {
+		trace.stats.Filters[i].DurationMs = durationMs(d)
+	}
+	for i, d := range trace.transformTime {
+		trace.stats.Transforms[i].DurationMs = durationMs(d)
+	}
+	trace.stats.ProjectionMs = durationMs(trace.projectionTime)
+	return &PipelineExplain{Result: result, Stats: trace.stats}, nil
+}
+
+// durationMs converts a duration into fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// now returns the current time, or the zero time when not tracing.
+func (t *pipelineTrace) now() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (t *pipelineTrace) fileStarted() {
+	if t != nil {
+		t.stats.Files++
+	}
+}
+
+func (t *pipelineTrace) rowRead() {
+	if t == nil {
+		return
+	}
+	t.stats.RowsRead++
+	if t.stats.RowsRead%explainHeapSampleInterval == 0 {
+		t.sampleHeap()
+	}
+}
+
+func (t *pipelineTrace) blankRowSkipped() {
+	if t != nil {
+		t.stats.BlankRowsSkipped++
+	}
+}
+
+func (t *pipelineTrace) headerSkipped() {
+	if t != nil {
+		t.stats.HeaderRowsSkipped++
+	}
+}
+
+func (t *pipelineTrace) filter(index int, start time.Time, dropped bool) {
+	if t == nil {
+		return
+	}
+	t.filterTime[index] += time.Since(start)
+	t.stats.Filters[index].RowsIn++
+	if !dropped {
+		t.stats.Filters[index].RowsOut++
+	}
+}
+
+func (t *pipelineTrace) transform(index int, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.transformTime[index] += time.Since(start)
+	t.stats.Transforms[index].Rows++
+}
+
+func (t *pipelineTrace) projection(start time.Time) {
+	if t != nil {
+		t.projectionTime += time.Since(start)
+	}
+}
+
+func (t *pipelineTrace) rowOut() {
+	if t != nil {
+		t.stats.RowsOut++
+	}
+}
+
+func (t *pipelineTrace) groupsFormed(n int) {
+	if t != nil {
+		t.stats.Groups = n
+	}
+}
+
+// sampleHeap records the current heap usage if it is a new high-water mark.
+func (t *pipelineTrace) sampleHeap() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.HeapAlloc > t.stats.PeakHeapBytes {
+		t.stats.PeakHeapBytes = m.HeapAlloc
+	}
+}
@@ -0,0 +1,69 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExplainProcessCsvFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "data.csv")
+	csvContent := "id,name,value,category\n1,alpha,100,A\n2,bravo,,B\n,,,\n3,charlie,300,A\n4,delta,400,C\n5,echo,500,A\n"
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	options := ProcessCsvOptions{
+		SkipHeader:    true,
+		SkipBlankRows: true,
+		Filters: []FilterConfig{
+			{Type: "emptyString", Column: 2},
+			{Type: "regexMatch", Column: 3, Pattern: "^A$"},
+		},
+		Transforms: []TransformConfig{{Type: "parseInt", Column: 2}},
+		GroupBy:    &GroupByConfig{Column: 3},
+		Fields:     []FieldConfig{{Type: "column", Column: 0}},
+	}
+
+	explain, err := loader.ExplainProcessCsvFile(csvPath, options)
+	if err != nil {
+		t.Fatalf("ExplainProcessCsvFile() error = %v", err)
+	}
+
+	plain, err := loader.ProcessCsvFile(csvPath, options)
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(explain.Result, plain) {
+		t.Errorf("Result = %v, want the ProcessCsvFile result %v", explain.Result, plain)
+	}
+
+	stats := explain.Stats
+	if stats.Files != 1 || stats.RowsRead != 7 || stats.BlankRowsSkipped != 1 || stats.HeaderRowsSkipped != 1 {
+		t.Errorf("unexpected read counts: %+v", stats)
+	}
+	if stats.RowsOut != 3 || stats.Groups != 1 {
+		t.Errorf("RowsOut = %d, Groups = %d, want 3 and 1", stats.RowsOut, stats.Groups)
+	}
+
+	wantFilters := []FilterStats{
+		{Type: "emptyString", Column: 2, RowsIn: 5, RowsOut: 4},
+		{Type: "regexMatch", Column: 3, RowsIn: 4, RowsOut: 3},
+	}
+	for i := range wantFilters {
+		got := stats.Filters[i]
+		got.DurationMs = 0
+		if got != wantFilters[i] {
+			t.Errorf("Filters[%d] = %+v, want %+v", i, got, wantFilters[i])
+		}
+	}
+	if len(stats.Transforms) != 1 || stats.Transforms[0].Rows != 3 {
+		t.Errorf("unexpected transform stats: %+v", stats.Transforms)
+	}
+	if stats.PeakHeapBytes == 0 || stats.DurationMs <= 0 {
+		t.Errorf("expected heap and duration to be measured, got %d bytes, %v ms", stats.PeakHeapBytes, stats.DurationMs)
+	}
+}
@@ -0,0 +1,150 @@
+// field_decode.go
+package streamloader
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// fieldDecoder decodes the encoded string value of one field of a record.
+type fieldDecoder struct {
+	field string
+	steps []func([]byte) ([]byte, error)
+	json  bool // Parse the decoded bytes as JSON instead of returning a string
+}
+
+// compileFieldDecoders turns the decodeFields option, a map from field to a "+"-separated
+// list of encodings such as "base64+gzip", into decoders. Steps are applied left to right:
+// "base64" and "base64url" (padded or not), then "gzip", "zlib", "deflate" or "zstd",
+// optionally followed by "json" to parse the result.
+func compileFieldDecoders(fields map[string]string) ([]fieldDecoder, error) {
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	decoders := make([]fieldDecoder, 0, len(fields))
+	for _, field := range names {
+		d := fieldDecoder{field: field}
+		for _, step := range strings.Split(fields[field], "+") {
+			if d.json {
+				return nil, fmt.Errorf("invalid decoding %q for field %q: json must be the last step", fields[field], field)
+			}
+			switch strings.ToLower(strings.TrimSpace(step)) {
+			case "base64":
+				d.steps = append(d.steps, decodeBase64(base64.StdEncoding))
+			case "base64url":
+				d.steps = append(d.steps, decodeBase64(base64.URLEncoding))
+			case "gzip":
+				d.steps = append(d.steps, decompress(func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }))
+			case "zlib":
+				d.steps = append(d.steps, decompress(zlib.NewReader))
+			case "deflate":
+				d.steps = append(d.steps, decompress(func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil }))
+			case "zstd":
+				d.steps = append(d.steps, decompress(func(r io.Reader) (io.ReadCloser, error) {
+					zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+					if err != nil {
+						return nil, err
+					}
+					return zr.IOReadCloser(), nil
+				}))
+			case "json":
+				d.json = true
+			default:
+				return nil, fmt.Errorf("unknown decoding step %q for field %q, expected base64, base64url, gzip, zlib, deflate, zstd or json", step, field)
+			}
+		}
+		decoders = append(decoders, d)
+	}
+	return decoders, nil
+}
+
+// decodeBase64 returns a step decoding standard or URL-safe base64, with or without padding.
+func decodeBase64(enc *base64.Encoding) func([]byte) ([]byte, error) {
+	enc = enc.WithPadding(base64.NoPadding)
+	return func(data []byte) ([]byte, error) {
+		data = bytes.TrimRight(bytes.TrimSpace(data), "=")
+		out := make([]byte, enc.DecodedLen(len(data)))
+		n, err := enc.Decode(out, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %w", err)
+		}
+		return out[:n], nil
+	}
+}
+
+// decompress returns a step reading data through a decompressor.
+func decompress(open func(io.Reader) (io.ReadCloser, error)) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		r, err := open(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+}
+
+// fieldParent returns the object holding a field, or dotted path, of a record and the field's
+// key in it, so the field can be replaced in place.
+func fieldParent(record any, field string) (map[string]any, string, bool) {
+	parent, key := record, field
+	if i := strings.LastIndex(field, "."); i >= 0 {
+		var ok bool
+		if parent, ok = lookupField(record, field[:i]); !ok {
+			return nil, "", false
+		}
+		key = field[i+1:]
+	}
+	obj, ok := parent.(map[string]any)
+	return obj, key, ok
+}
+
+// decodeFields decodes the fields of a record in place. Fields may be dotted paths into nested
+// objects; records without the field, or with a null value, are left unchanged.
+func decodeFields(record any, decoders []fieldDecoder) error {
+	for _, d := range decoders {
+		obj, key, ok := fieldParent(record, d.field)
+		if !ok {
+			continue
+		}
+		value, ok := obj[key]
+		if !ok || value == nil {
+			continue
+		}
+		encoded, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("failed to decode field %q: expected a string, got %T", d.field, value)
+		}
+
+		data := []byte(encoded)
+		for _, step := range d.steps {
+			var err error
+			if data, err = step(data); err != nil {
+				return fmt.Errorf("failed to decode field %q: %w", d.field, err)
+			}
+		}
+		if !d.json {
+			obj[key] = string(data)
+			continue
+		}
+		var decoded any
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return fmt.Errorf("failed to decode field %q: %w", d.field, err)
+		}
+		obj[key] = decoded
+	}
+	return nil
+}
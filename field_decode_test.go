@@ -0,0 +1,156 @@
+package streamloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadJSONDecodeFields(t *testing.T) {
+	body := `{"ok":true,"items":[1,2]}`
+	b64gzip := base64.StdEncoding.EncodeToString(gzipped(t, body))
+	var zbuf bytes.Buffer
+	zw := zlib.NewWriter(&zbuf)
+	zw.Write([]byte(body))
+	zw.Close()
+	b64urlZlib := base64.RawURLEncoding.EncodeToString(zbuf.Bytes())
+	zstdEncoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64zstd := base64.StdEncoding.EncodeToString(zstdEncoder.EncodeAll([]byte(body), nil))
+	zstdEncoder.Close()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		fields  map[string]string
+		want    interface{}
+	}{
+		{
+			name:    "base64+gzip in array",
+			file:    "data.json",
+			content: `[{"id":1,"body":"` + b64gzip + `"},{"id":2},{"id":3,"body":null}]`,
+			fields:  map[string]string{"body": "base64+gzip"},
+			want: []interface{}{
+				map[string]interface{}{"id": float64(1), "body": body},
+				map[string]interface{}{"id": float64(2)},
+				map[string]interface{}{"id": float64(3), "body": nil},
+			},
+		},
+		{
+			name:    "nested field parsed as JSON in NDJSON",
+			file:    "data.ndjson",
+			content: `{"response":{"body":"` + b64gzip + `"}}` + "\n",
+			fields:  map[string]string{"response.body": "base64+gzip+json"},
+			want: []map[string]interface{}{
+				{"response": map[string]interface{}{"body": map[string]interface{}{"ok": true, "items": []interface{}{float64(1), float64(2)}}}},
+			},
+		},
+		{
+			name:    "unpadded base64url and zlib",
+			file:    "data.json",
+			content: `[{"body":"` + b64urlZlib + `"}]`,
+			fields:  map[string]string{"body": "base64url+zlib"},
+			want:    []interface{}{map[string]interface{}{"body": body}},
+		},
+		{
+			name:    "base64 and zstd",
+			file:    "data.ndjson",
+			content: `{"body":"` + b64zstd + `"}` + "\n",
+			fields:  map[string]string{"body": "base64+zstd+json"},
+			want: []map[string]interface{}{
+				{"body": map[string]interface{}{"ok": true, "items": []interface{}{float64(1), float64(2)}}},
+			},
+		},
+		{
+			name:    "plain base64",
+			file:    "data.json",
+			content: `[{"body":"aGVsbG8="}]`,
+			fields:  map[string]string{"body": "base64"},
+			want:    []interface{}{map[string]interface{}{"body": "hello"}},
+		},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loader.LoadJSON(path, JsonOptions{DecodeFields: tt.fields})
+			if err != nil {
+				t.Fatalf("LoadJSON() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadJSON() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConcatenatedJSONDecodeFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, []byte(`{"body":"aGk="}{"body":"eW8="}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := StreamLoader{}.LoadConcatenatedJSON(path, JsonOptions{DecodeFields: map[string]string{"body": "base64"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{map[string]any{"body": "hi"}, map[string]any{"body": "yo"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadConcatenatedJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadJSONDecodeFieldsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		fields  map[string]string
+		wantErr string
+	}{
+		{"not zstd", `[{"body":"aGk="}]`, map[string]string{"body": "base64+zstd"}, `record 0: failed to decode field "body"`},
+		{"unknown step", `[]`, map[string]string{"body": "rot13"}, "unknown decoding step"},
+		{"json not last", `[]`, map[string]string{"body": "json+gzip"}, "json must be the last step"},
+		{"invalid base64", `[{"body":"!!"}]`, map[string]string{"body": "base64"}, `record 0: failed to decode field "body": invalid base64`},
+		{"not gzip", `[{"a":1},{"body":"aGk="}]`, map[string]string{"body": "base64+gzip"}, "record 1"},
+		{"not a string", `[{"body":5}]`, map[string]string{"body": "base64"}, "expected a string"},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loader.LoadJSON(path, JsonOptions{DecodeFields: tt.fields})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadJSON() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// file_pool.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// fileSlotWait is how long opening a file waits for a free slot in the file pool before failing
+var fileSlotWait = 10 * time.Second
+
+// filePool limits the files the loaders and writers hold open at the same time, shared by every
+// VU in the k6 process. With many VUs streaming files concurrently, opening a file waits for
+// another to be closed rather than exhausting the process's file descriptor limit.
+var filePool struct {
+	mu     sync.Mutex
+	inUse  int
+	freed  chan struct{} // Closed and replaced whenever a slot is released
+	limit  int           // Default limit, derived from the file descriptor limit
+	inited bool
+}
+
+// maxOpenFiles returns the number of files the pool lets be open at once.
+func maxOpenFiles() int {
+	if max := tuning.Load().MaxOpenFiles; max > 0 {
+		return max
+	}
+	filePool.mu.Lock()
+	defer filePool.mu.Unlock()
+	if !filePool.inited {
+		filePool.limit = defaultMaxOpenFiles()
+		filePool.inited = true
+	}
+	return filePool.limit
+}
+
+// fileSlot is a slot in the file pool held by an open file. Releasing it more than once is safe,
+// so it can be released from a Close that may be called repeatedly.
+type fileSlot struct {
+	once sync.Once
+}
+
+// acquireFileSlot waits for a free slot in the file pool for path.
+func acquireFileSlot(path string) (*fileSlot, error) {
+	limit := maxOpenFiles()
+	var timeout <-chan time.Time
+	for {
+		filePool.mu.Lock()
+		if filePool.inUse < limit {
+			filePool.inUse++
+			filePool.mu.Unlock()
+			return &fileSlot{}, nil
+		}
+		if filePool.freed == nil {
+			filePool.freed = make(chan struct{})
+		}
+		freed := filePool.freed
+		filePool.mu.Unlock()
+
+		if timeout == nil {
+			timer := time.NewTimer(fileSlotWait)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-freed:
+			limit = maxOpenFiles()
+		case <-timeout:
+			return nil, fmt.Errorf("failed to open %s: %d files are already open; close iterators that are no longer needed or raise maxOpenFiles with setDefaults", path, limit)
+		}
+	}
+}
+
+// release returns the slot to the file pool.
+func (s *fileSlot) release() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		filePool.mu.Lock()
+		defer filePool.mu.Unlock()
+		filePool.inUse--
+		if filePool.freed != nil {
+			close(filePool.freed)
+			filePool.freed = nil
+		}
+	})
+}
+
+// pooledFiles returns the number of slots in use.
+func pooledFiles() int {
+	filePool.mu.Lock()
+	defer filePool.mu.Unlock()
+	return filePool.inUse
+}
+
+// pooledReader releases its file pool slot when it is closed.
+type pooledReader struct {
+	io.ReadCloser
+	slot *fileSlot
+}
+
+func (r pooledReader) Close() error {
+	defer r.slot.release()
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+// file_pool_other.go
+package streamloader
+
+// defaultMaxOpenFiles is a fixed limit where the file descriptor limit can't be queried.
+func defaultMaxOpenFiles() int {
+	return 256
+}
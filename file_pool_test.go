@@ -0,0 +1,90 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilePoolLimit(t *testing.T) {
+	loader := StreamLoader{}
+	defer loader.ResetDefaults()
+	defer func(wait time.Duration) { fileSlotWait = wait }(fileSlotWait)
+	fileSlotWait = 100 * time.Millisecond
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	if err := os.WriteFile(path, []byte(`[{"id":1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := pooledFiles()
+	if _, err := loader.SetDefaults(TuningDefaults{MaxOpenFiles: base + 2}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := openJsonRecords(path, callLimits{})
+	if err != nil {
+		t.Fatalf("openJsonRecords() error = %v", err)
+	}
+	second, err := openSequential(path, "", callLimits{})
+	if err != nil {
+		t.Fatalf("openSequential() error = %v", err)
+	}
+	if got := loader.GetRuntimeStats().PooledFiles; got != base+2 {
+		t.Errorf("PooledFiles = %d, want %d", got, base+2)
+	}
+
+	// The pool is full, so opening another file times out
+	if _, err := loader.LoadJSON(path); err == nil || !strings.Contains(err.Error(), "maxOpenFiles") {
+		t.Errorf("LoadJSON() with a full pool error = %v, want pool limit error", err)
+	}
+
+	// A file closed while waiting frees a slot
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		first.Close()
+	}()
+	if _, err := loader.LoadJSON(path); err != nil {
+		t.Errorf("LoadJSON() after a slot was freed error = %v", err)
+	}
+
+	// Closing twice releases the slot once
+	second.Close()
+	second.Close()
+	first.Close()
+	if got := pooledFiles(); got != base {
+		t.Errorf("pooledFiles() after closing = %d, want %d", got, base)
+	}
+}
+
+func TestFilePoolReleasedByWriters(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	base := pooledFiles()
+
+	objects := []interface{}{map[string]interface{}{"id": 1}}
+	output := filepath.Join(dir, "out.json")
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, output); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, filepath.Join(dir, "missing", "out.json")); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+	if _, err := loader.InterleaveFiles([]InterleaveSource{{Path: output, Ratio: 1}}, filepath.Join(dir, "mixed.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.LoadJSON(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	if got := pooledFiles(); got != base {
+		t.Errorf("pooledFiles() = %d, want %d", got, base)
+	}
+}
+
+func TestDefaultMaxOpenFiles(t *testing.T) {
+	if n := defaultMaxOpenFiles(); n <= 0 {
+		t.Errorf("defaultMaxOpenFiles() = %d, want positive", n)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+// file_pool_unix.go
+package streamloader
+
+import "syscall"
+
+// defaultMaxOpenFiles allows half of the soft file descriptor limit, leaving the rest to k6
+// itself and to network connections.
+func defaultMaxOpenFiles() int {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil || limit.Cur < 64 {
+		return 32
+	}
+	if limit.Cur > 1<<20 {
+		return 1 << 19
+	}
+	return int(limit.Cur / 2)
+}
@@ -0,0 +1,406 @@
+// fpe.go
+package streamloader
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// AnonymizeOptions configures AnonymizeJsonFile
+type AnonymizeOptions struct {
+	KeyEnv string            `json:"keyEnv" js:"keyEnv"`
+	Fields map[string]string `json:"fields" js:"fields"`
+	Tweak  string            `json:"tweak" js:"tweak"`
+
+	MappingFile string `json:"mappingFile" js:"mappingFile"`
+}
+
+// tokenMapping is an entry of the mapping file of AnonymizeJsonFile
+type tokenMapping struct {
+	Field string `json:"field"`
+	Hash  string `json:"hash"` // Hex SHA-256 of the original value
+	Token string `json:"token"`
+}
+
+// Formats of the anonymized fields
+const (
+	fpeDigits = "digits" // Digits are replaced, other characters kept
+	fpeLuhn   = "luhn"   // As digits, then the last digit is set to a valid Luhn check digit
+	fpeAlnum  = "alnum"  // Digits and ASCII letters are replaced, keeping the case of letters
+)
+
+// fpeMinDomain is the minimum number of possible values of an FF1 input, radix^len, required by
+// NIST SP 800-38G Rev. 1; smaller domains can be enumerated.
+const fpeMinDomain = 1000000
+
+// fpeMinLength returns the minimum number of numerals of radix FF1 encrypts.
+func fpeMinLength(radix int) int {
+	n := 1
+	for domain := radix; domain < fpeMinDomain; domain *= radix {
+		n++
+	}
+	return n
+}
+
+// ff1 is the FF1 format-preserving encryption mode of NIST SP 800-38G, over AES.
+type ff1 struct {
+	block cipher.Block
+	tweak []byte
+}
+
+// prf is the CBC-MAC of data, whose length is a multiple of the block size, with a zero IV.
+func (f ff1) prf(data []byte) []byte {
+	y := make([]byte, 16)
+	for i := 0; i < len(data); i += 16 {
+		for j := range y {
+			y[j] ^= data[i+j]
+		}
+		f.block.Encrypt(y, y)
+	}
+	return y
+}
+
+// encrypt encrypts a string of at least two numerals of base radix into one of the same length.
+func (f ff1) encrypt(x []byte, radix int) []byte {
+	n, t := len(x), len(f.tweak)
+	u, v := n/2, n-n/2
+	a, b := append([]byte(nil), x[:u]...), append([]byte(nil), x[u:]...)
+
+	// Bytes of NUM(B), ceil(ceil(v*log2(radix))/8); the radixes used are not powers of two, so
+	// the bit length of radix^v is ceil(v*log2(radix))
+	r := big.NewInt(int64(radix))
+	byteLen := (new(big.Int).Exp(r, big.NewInt(int64(v)), nil).BitLen() + 7) / 8
+	d := 4*((byteLen+3)/4) + 4
+
+	p := []byte{1, 2, 1, byte(radix >> 16), byte(radix >> 8), byte(radix), 10, byte(u), 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(p[8:], uint32(n))
+	binary.BigEndian.PutUint32(p[12:], uint32(t))
+	padding := ((-t-byteLen-1)%16 + 16) % 16
+
+	modU := new(big.Int).Exp(r, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(r, big.NewInt(int64(v)), nil)
+	for i := 0; i < 10; i++ {
+		// P || Q, with Q = T || zeros || i || NUM(B)
+		pq := make([]byte, len(p)+t+padding+1+byteLen)
+		copy(pq, p)
+		copy(pq[len(p):], f.tweak)
+		pq[len(p)+t+padding] = byte(i)
+		numeralsToInt(b, radix).FillBytes(pq[len(p)+t+padding+1:])
+
+		rBlock := f.prf(pq)
+		s := append([]byte(nil), rBlock...)
+		for j := 1; len(s) < d; j++ {
+			block := make([]byte, 16)
+			binary.BigEndian.PutUint64(block[8:], uint64(j))
+			for k := range block {
+				block[k] ^= rBlock[k]
+			}
+			f.block.Encrypt(block, block)
+			s = append(s, block...)
+		}
+		y := new(big.Int).SetBytes(s[:d])
+
+		m, mod := u, modU
+		if i%2 == 1 {
+			m, mod = v, modV
+		}
+		c := numeralsToInt(a, radix)
+		c.Add(c, y).Mod(c, mod)
+		a, b = b, intToNumerals(c, radix, m)
+	}
+	return append(a, b...)
+}
+
+// numeralsToInt returns the number whose base radix numerals are x, most significant first.
+func numeralsToInt(x []byte, radix int) *big.Int {
+	r, n := big.NewInt(int64(radix)), new(big.Int)
+	for _, digit := range x {
+		n.Mul(n, r).Add(n, big.NewInt(int64(digit)))
+	}
+	return n
+}
+
+// intToNumerals returns the m base radix numerals of n, most significant first.
+func intToNumerals(n *big.Int, radix int, m int) []byte {
+	r, digit := big.NewInt(int64(radix)), new(big.Int)
+	n = new(big.Int).Set(n)
+	x := make([]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		n.DivMod(n, r, digit)
+		x[i] = byte(digit.Int64())
+	}
+	return x
+}
+
+// anonymizer replaces values with format-preserving substitutes.
+type anonymizer struct {
+	cipher ff1
+}
+
+// newAnonymizer returns an anonymizer keyed by the AES key in the environment variable keyEnv.
+func newAnonymizer(keyEnv string, tweak string) (*anonymizer, error) {
+	if keyEnv == "" {
+		return nil, fmt.Errorf("keyEnv must name the environment variable holding the anonymization key")
+	}
+	block, err := encryptionBlock(keyEnv)
+	if err != nil {
+		return nil, err
+	}
+	return &anonymizer{cipher: ff1{block: block, tweak: []byte(tweak)}}, nil
+}
+
+// validAnonymizeFormat returns an error if format is not a known format.
+func validAnonymizeFormat(format string) error {
+	switch format {
+	case fpeDigits, fpeLuhn, fpeAlnum:
+		return nil
+	}
+	return fmt.Errorf("unknown anonymization format %q, expected digits, luhn or alnum", format)
+}
+
+// anonymize returns the substitute of value in format. Digits, or letters, too few to make up
+// the minimum FF1 domain are left unchanged.
+func (a *anonymizer) anonymize(value string, format string) string {
+	out := []byte(value)
+	var digits []int
+	for i, c := range out {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, i)
+		}
+	}
+	switch format {
+	case fpeLuhn:
+		if len(digits)-1 < fpeMinLength(10) {
+			return value
+		}
+		a.replace(out, digits[:len(digits)-1], 10, '0')
+		out[digits[len(digits)-1]] = luhnCheckDigit(out, digits[:len(digits)-1])
+		return string(out)
+	case fpeAlnum:
+		var upper, letters []int
+		for i, c := range out {
+			switch {
+			case c >= 'a' && c <= 'z':
+				letters = append(letters, i)
+			case c >= 'A' && c <= 'Z':
+				upper = append(upper, i)
+				letters = append(letters, i)
+			}
+		}
+		for _, i := range upper {
+			out[i] += 'a' - 'A'
+		}
+		a.replace(out, letters, 26, 'a')
+		for _, i := range upper {
+			out[i] -= 'a' - 'A'
+		}
+	}
+	a.replace(out, digits, 10, '0')
+	return string(out)
+}
+
+// replace encrypts the characters at positions, numerals of radix counted from zero, in place,
+// unless there are too few of them for the minimum FF1 domain.
+func (a *anonymizer) replace(out []byte, positions []int, radix int, zero byte) {
+	if len(positions) < fpeMinLength(radix) {
+		return
+	}
+	x := make([]byte, len(positions))
+	for i, p := range positions {
+		x[i] = out[p] - zero
+	}
+	for i, numeral := range a.cipher.encrypt(x, radix) {
+		out[positions[i]] = numeral + zero
+	}
+}
+
+// luhnCheckDigit returns the Luhn check digit following the digits of out at positions.
+func luhnCheckDigit(out []byte, positions []int) byte {
+	sum := 0
+	for i := len(positions) - 1; i >= 0; i-- {
+		digit := int(out[positions[i]] - '0')
+		if (len(positions)-1-i)%2 == 0 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// AnonymizeJsonFile streams the records of a JSON array or NDJSON file to a JSON array file,
+// replacing the values of sensitive fields, such as card and phone numbers, with substitutes of
+// the same format, using FF1 format-preserving encryption (NIST SP 800-38G) keyed by an AES key.
+// The same value always gets the same substitute under the same key and tweak, so joins across
+// datasets anonymized with one key still match, while the real values are removed.
+//
+// Options:
+//   - keyEnv: The environment variable holding the AES key, hex or base64 encoded, 16, 24 or 32
+//     bytes long; the key never appears in the script
+//   - fields: Map from field, or dotted path, to format: "digits" replaces the digits and keeps
+//     other characters such as "+", "-" and spaces, "luhn" does the same and then sets the last
+//     digit to a valid Luhn check digit, for card numbers, and "alnum" replaces digits and ASCII
+//     letters, keeping their case
+//   - tweak: Public value varying the substitutes, e.g. per environment (default: none)
+//   - mappingFile: Also write a JSON array of {field, hash, token} entries, one per distinct
+//     value replaced, where hash is the hex SHA-256 of the original value, so values observed in
+//     the system under test can be traced back by whoever holds the file (default: none)
+//
+// Records without a field, or with a null value, are left unchanged. NIST SP 800-38G Rev. 1
+// requires at least a million possible inputs, so values with fewer than 6 digits to replace
+// (7 for luhn, whose check digit isn't encrypted) are written unchanged, and so are the letters
+// of alnum values with fewer than 5 letters; short postcodes or extensions are therefore not
+// anonymized. Substitutes are not guaranteed to differ from real values.
+// Card and phone numbers are easy to guess from their hash, so the mapping file must be
+// protected like the original data; the distinct values are kept in memory while it is written.
+//
+// Returns: The number of records written
+//
+// Example usage:
+//
+//	const count = streamloader.anonymizeJsonFile("customers.json", "customers-anon.json", {
+//		keyEnv: "ANON_KEY",
+//		fields: { card: "luhn", phone: "digits", "address.postcode": "alnum" },
+//	});
+func (s StreamLoader) AnonymizeJsonFile(inputFilePath string, outputFilePath string, options AnonymizeOptions) (int, error) {
+	if len(options.Fields) == 0 {
+		return 0, fmt.Errorf("fields must name at least one field to anonymize")
+	}
+	fields := make([]string, 0, len(options.Fields))
+	for field, format := range options.Fields {
+		if err := validAnonymizeFormat(format); err != nil {
+			return 0, fmt.Errorf("invalid format for field %q: %w", field, err)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	anon, err := newAnonymizer(options.KeyEnv, options.Tweak)
+	if err != nil {
+		return 0, err
+	}
+
+	var mapping *tokenMappingWriter
+	if options.MappingFile != "" {
+		if mapping, err = newTokenMappingWriter(options.MappingFile); err != nil {
+			return 0, err
+		}
+		defer mapping.Close()
+	}
+	out, err := createJsonArrayFile(outputFilePath, writeBufferSize())
+	if err != nil {
+		return 0, err
+	}
+	index := 0
+	err = forEachJsonRecord(inputFilePath, s.call, func(raw json.RawMessage) (bool, error) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var record any
+		if err := dec.Decode(&record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", index, err)
+		}
+		for _, field := range fields {
+			obj, key, ok := fieldParent(record, field)
+			if !ok || obj[key] == nil {
+				continue
+			}
+			value, ok := obj[key].(string)
+			if !ok {
+				return false, fmt.Errorf("failed to anonymize field %q of record %d: expected a string, got %T", field, index, obj[key])
+			}
+			token := anon.anonymize(value, options.Fields[field])
+			if mapping != nil && token != value {
+				if err := mapping.add(field, value, token); err != nil {
+					return false, err
+				}
+			}
+			obj[key] = token
+		}
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode record %d: %w", index, err)
+		}
+		index++
+		return true, out.Write(encoded)
+	})
+	if err != nil {
+		out.Close()
+		return out.count, err
+	}
+	if err := out.Close(); err != nil {
+		return out.count, err
+	}
+	if mapping != nil {
+		if err := mapping.Close(); err != nil {
+			return out.count, err
+		}
+	}
+	return out.count, nil
+}
+
+// tokenMappingWriter writes the mapping file of AnonymizeJsonFile, once per field and value.
+type tokenMappingWriter struct {
+	out  *jsonArrayWriter
+	seen map[string]bool // Field and hash of the values written
+	done bool
+}
+
+func newTokenMappingWriter(path string) (*tokenMappingWriter, error) {
+	out, err := createJsonArrayFile(path, writeBufferSize())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mapping file: %w", err)
+	}
+	return &tokenMappingWriter{out: out, seen: make(map[string]bool)}, nil
+}
+
+// add writes the mapping of a value of field to its token, unless it was written before.
+func (w *tokenMappingWriter) add(field string, value string, token string) error {
+	sum := sha256.Sum256([]byte(value))
+	hash := hex.EncodeToString(sum[:])
+	if w.seen[field+"\x00"+hash] {
+		return nil
+	}
+	w.seen[field+"\x00"+hash] = true
+	encoded, err := json.Marshal(tokenMapping{Field: field, Hash: hash, Token: token})
+	if err != nil {
+		return err
+	}
+	return w.out.Write(encoded)
+}
+
+// Close finishes the mapping file. Closing it again does nothing.
+func (w *tokenMappingWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	return w.out.Close()
+}
+
+// AnonymizeValue returns the substitute AnonymizeJsonFile writes for value in format, with an
+// optional tweak, so a script can look up anonymized records by a real value, or anonymize
+// values it generates.
+//
+// Example usage:
+//
+//	const card = streamloader.anonymizeValue("4111 1111 1111 1111", "luhn", "ANON_KEY");
+func (StreamLoader) AnonymizeValue(value string, format string, keyEnv string, tweak ...string) (string, error) {
+	if err := validAnonymizeFormat(format); err != nil {
+		return "", err
+	}
+	anon, err := newAnonymizer(keyEnv, "")
+	if len(tweak) > 0 {
+		anon, err = newAnonymizer(keyEnv, tweak[0])
+	}
+	if err != nil {
+		return "", err
+	}
+	return anon.anonymize(value, format), nil
+}
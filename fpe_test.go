@@ -0,0 +1,251 @@
+package streamloader
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFF1Vectors(t *testing.T) {
+	// Samples 1 to 3 of the NIST FF1 examples, with AES-128
+	key, _ := hex.DecodeString("2B7E151628AED2A6ABF7158809CF4F3C")
+	block, _ := aes.NewCipher(key)
+	const numerals = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	tests := []struct {
+		tweak string
+		radix int
+		plain string
+		want  string
+	}{
+		{"", 10, "0123456789", "2433477484"},
+		{"39383736353433323130", 10, "0123456789", "6124200773"},
+		{"3737373770717273373737", 36, "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+	}
+	for _, tt := range tests {
+		tweak, _ := hex.DecodeString(tt.tweak)
+		x := make([]byte, len(tt.plain))
+		for i, c := range tt.plain {
+			x[i] = byte(strings.IndexRune(numerals, c))
+		}
+		var got strings.Builder
+		for _, numeral := range (ff1{block: block, tweak: tweak}).encrypt(x, tt.radix) {
+			got.WriteByte(numerals[numeral])
+		}
+		if got.String() != tt.want {
+			t.Errorf("encrypt(%s, tweak %q) = %s, want %s", tt.plain, tt.tweak, got.String(), tt.want)
+		}
+	}
+}
+
+func TestAnonymizeValue(t *testing.T) {
+	loader := StreamLoader{}
+	t.Setenv("TEST_ANON_KEY", hex.EncodeToString(bytes.Repeat([]byte{0x42}, 32)))
+
+	tests := []struct {
+		value  string
+		format string
+	}{
+		{"4111 1111 1111 1111", "luhn"},
+		{"5500-0000-0000-0004", "luhn"},
+		{"+1 (415) 555-0100", "digits"},
+		{"SW1A 1AA", "alnum"},
+	}
+	for _, tt := range tests {
+		got, err := loader.AnonymizeValue(tt.value, tt.format, "TEST_ANON_KEY")
+		if err != nil {
+			t.Fatalf("AnonymizeValue(%q) error = %v", tt.value, err)
+		}
+		if got == tt.value || len(got) != len(tt.value) {
+			t.Errorf("AnonymizeValue(%q) = %q, want a different value of the same length", tt.value, got)
+		}
+		for i := range got {
+			if charClass(got[i]) != charClass(tt.value[i]) {
+				t.Errorf("AnonymizeValue(%q) = %q, changed the format at %d", tt.value, got, i)
+			}
+		}
+		if tt.format == "luhn" && !luhnValid(got) {
+			t.Errorf("AnonymizeValue(%q) = %q, want a valid Luhn number", tt.value, got)
+		}
+		if again, _ := loader.AnonymizeValue(tt.value, tt.format, "TEST_ANON_KEY"); again != got {
+			t.Errorf("AnonymizeValue(%q) = %q then %q, want the same substitute", tt.value, got, again)
+		}
+		if tweaked, _ := loader.AnonymizeValue(tt.value, tt.format, "TEST_ANON_KEY", "staging"); tweaked == got {
+			t.Errorf("AnonymizeValue(%q) with a tweak = %q, want a different substitute", tt.value, tweaked)
+		}
+	}
+
+	// Different inputs get different substitutes
+	a, _ := loader.AnonymizeValue("4111111111111111", "digits", "TEST_ANON_KEY")
+	b, _ := loader.AnonymizeValue("4111111111111112", "digits", "TEST_ANON_KEY")
+	if a == b {
+		t.Errorf("AnonymizeValue() gave %q for two different values", a)
+	}
+	// Below the minimum FF1 domain of a million values
+	for _, tt := range []struct{ value, format string }{
+		{"x7", "digits"},
+		{"415-55", "digits"},
+		{"411111", "luhn"},
+		{"AB-12", "alnum"},
+	} {
+		if got, _ := loader.AnonymizeValue(tt.value, tt.format, "TEST_ANON_KEY"); got != tt.value {
+			t.Errorf("AnonymizeValue(%q, %s) = %q, want it unchanged", tt.value, tt.format, got)
+		}
+	}
+	if got, _ := loader.AnonymizeValue("415-555", "digits", "TEST_ANON_KEY"); got == "415-555" {
+		t.Error("AnonymizeValue(415-555) is unchanged, want 6 digits encrypted")
+	}
+	if got, _ := loader.AnonymizeValue("ABCDE-12", "alnum", "TEST_ANON_KEY"); got[:5] == "ABCDE" || got[5:] != "-12" {
+		t.Errorf("AnonymizeValue(ABCDE-12) = %q, want the letters encrypted and the digits kept", got)
+	}
+
+	if _, err := loader.AnonymizeValue("123", "hex", "TEST_ANON_KEY"); err == nil {
+		t.Error("Expected error for an unknown format")
+	}
+	if _, err := loader.AnonymizeValue("123", "digits", "TEST_UNSET_ANON_KEY"); err == nil {
+		t.Error("Expected error for an unset key variable")
+	}
+}
+
+func TestAnonymizeJsonFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	t.Setenv("TEST_ANON_KEY", hex.EncodeToString(bytes.Repeat([]byte{0x42}, 16)))
+
+	customers := filepath.Join(dir, "customers.ndjson")
+	os.WriteFile(customers, []byte(`{"id":1,"card":"4111111111111111","contact":{"phone":"+1 415 555 0100"},"total":12.50}
+{"id":2,"card":null,"contact":{}}
+{"id":3,"card":"4111111111111111"}
+`), 0644)
+	orders := filepath.Join(dir, "orders.json")
+	os.WriteFile(orders, []byte(`[{"order":9,"card":"4111111111111111"}]`), 0644)
+
+	options := AnonymizeOptions{KeyEnv: "TEST_ANON_KEY", Fields: map[string]string{"card": "luhn", "contact.phone": "digits"}}
+	out := filepath.Join(dir, "customers-anon.json")
+	count, err := loader.AnonymizeJsonFile(customers, out, options)
+	if err != nil || count != 3 {
+		t.Fatalf("AnonymizeJsonFile() = %d, %v, want 3 records", count, err)
+	}
+	var records []map[string]any
+	data, _ := os.ReadFile(out)
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("Output is not a JSON array: %v", err)
+	}
+	card, _ := records[0]["card"].(string)
+	if card == "4111111111111111" || !luhnValid(card) {
+		t.Errorf("card = %q, want a valid substitute", card)
+	}
+	if phone := records[0]["contact"].(map[string]any)["phone"]; phone == "+1 415 555 0100" || len(phone.(string)) != 15 {
+		t.Errorf("phone = %v, want a substitute of the same format", phone)
+	}
+	if records[1]["card"] != nil || records[2]["card"] != card {
+		t.Errorf("records = %v, want null kept and equal cards equal", records)
+	}
+	if !bytes.Contains(data, []byte(`"total":12.50`)) {
+		t.Errorf("Output %s does not keep other fields as written", data)
+	}
+
+	// Joins across datasets anonymized with the same key still match
+	ordersOut := filepath.Join(dir, "orders-anon.json")
+	if _, err := loader.AnonymizeJsonFile(orders, ordersOut, options); err != nil {
+		t.Fatalf("AnonymizeJsonFile() error = %v", err)
+	}
+	data, _ = os.ReadFile(ordersOut)
+	if !bytes.Contains(data, []byte(`"card":"`+card+`"`)) {
+		t.Errorf("Orders %s do not join on card %s", data, card)
+	}
+
+	// Errors
+	bad := filepath.Join(dir, "bad.ndjson")
+	os.WriteFile(bad, []byte(`{"card":4111111111111111}`), 0644)
+	if _, err := loader.AnonymizeJsonFile(bad, filepath.Join(dir, "bad-anon.json"), options); err == nil || !strings.Contains(err.Error(), "expected a string") {
+		t.Errorf("AnonymizeJsonFile() error = %v, want an error for a number", err)
+	}
+	if _, err := loader.AnonymizeJsonFile(customers, out, AnonymizeOptions{KeyEnv: "TEST_ANON_KEY"}); err == nil {
+		t.Error("Expected error without fields")
+	}
+	if _, err := loader.AnonymizeJsonFile(customers, out, AnonymizeOptions{Fields: options.Fields}); err == nil {
+		t.Error("Expected error without keyEnv")
+	}
+}
+
+func TestAnonymizeMappingFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	t.Setenv("TEST_ANON_KEY", hex.EncodeToString(bytes.Repeat([]byte{0x42}, 16)))
+
+	input := filepath.Join(dir, "users.ndjson")
+	os.WriteFile(input, []byte(`{"card":"4111111111111111","phone":"4111111111111111"}
+{"card":"4111111111111111","phone":"7"}
+{"card":"5500000000000004"}
+`), 0644)
+	mappingFile := filepath.Join(dir, "mapping.json")
+	options := AnonymizeOptions{
+		KeyEnv:      "TEST_ANON_KEY",
+		Fields:      map[string]string{"card": "luhn", "phone": "digits"},
+		MappingFile: mappingFile,
+	}
+	if _, err := loader.AnonymizeJsonFile(input, filepath.Join(dir, "users-anon.json"), options); err != nil {
+		t.Fatalf("AnonymizeJsonFile() error = %v", err)
+	}
+
+	var mapping []tokenMapping
+	data, _ := os.ReadFile(mappingFile)
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		t.Fatalf("Mapping file is not a JSON array: %v", err)
+	}
+	// Repeated values are written once, per field, and unchanged values not at all
+	if len(mapping) != 3 {
+		t.Fatalf("mapping = %v, want 3 entries", mapping)
+	}
+	sum := sha256.Sum256([]byte("4111111111111111"))
+	card, _ := loader.AnonymizeValue("4111111111111111", "luhn", "TEST_ANON_KEY")
+	want := tokenMapping{Field: "card", Hash: hex.EncodeToString(sum[:]), Token: card}
+	if mapping[0] != want {
+		t.Errorf("mapping[0] = %v, want %v", mapping[0], want)
+	}
+	if mapping[1].Field != "phone" || mapping[1].Hash != want.Hash || mapping[2].Field != "card" {
+		t.Errorf("mapping = %v, want entries in the order values were first seen", mapping)
+	}
+	if bytes.Contains(data, []byte("4111111111111111")) {
+		t.Errorf("Mapping file %s contains an original value", data)
+	}
+}
+
+// charClass returns 'd' for digits, 'l' and 'u' for lower and upper case letters, or the byte.
+func charClass(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return 'd'
+	case c >= 'a' && c <= 'z':
+		return 'l'
+	case c >= 'A' && c <= 'Z':
+		return 'u'
+	}
+	return c
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		digit := int(s[i] - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
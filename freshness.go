@@ -0,0 +1,118 @@
+// freshness.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FreshnessOptions represents options for AssertFresh
+type FreshnessOptions struct {
+	MaxAgeHours float64 `json:"maxAgeHours" js:"maxAgeHours"`
+	Field       string  `json:"field" js:"field"`
+}
+
+// FreshnessResult describes the age of a dataset that passed AssertFresh
+type FreshnessResult struct {
+	AgeHours  float64 `json:"ageHours" js:"ageHours"`
+	Timestamp string  `json:"timestamp" js:"timestamp"` // RFC 3339
+	Source    string  `json:"source" js:"source"`       // "mtime" or the field name
+}
+
+// AssertFresh fails when a dataset is older than allowed, so a run against a stale recording
+// stops in setup instead of producing misleading results. maxAge is the allowed age in hours,
+// or an options object:
+//   - maxAgeHours: The allowed age in hours
+//   - field: Timestamp field (or dotted path) giving the age of the data, such as "endTime" in a
+//     recording stats file. For a JSON object file the field of the object is used; for a JSON
+//     array or NDJSON file, the newest value among the records. Timestamps are RFC 3339 strings
+//     or Unix times in seconds or milliseconds. (default: the file's modification time)
+//
+// Returns: The age of the dataset, its timestamp and where the timestamp came from
+//
+// Example usage:
+//
+//	export function setup() {
+//		streamloader.assertFresh("recording.json", 24);
+//		streamloader.assertFresh("recording-stats.json", { maxAgeHours: 24, field: "endTime" });
+//	}
+func (s StreamLoader) AssertFresh(filePath string, maxAge interface{}) (*FreshnessResult, error) {
+	var opts FreshnessOptions
+	switch v := maxAge.(type) {
+	case FreshnessOptions:
+		opts = v
+	case map[string]interface{}:
+		if err := convertConfig(v, &opts); err != nil {
+			return nil, err
+		}
+	default:
+		hours, ok := numberValue(maxAge)
+		if !ok {
+			return nil, fmt.Errorf("invalid maxAge: expected a number of hours or an options object, got %T", maxAge)
+		}
+		opts.MaxAgeHours = hours
+	}
+	if opts.MaxAgeHours <= 0 {
+		return nil, fmt.Errorf("maxAgeHours must be positive, got %v", opts.MaxAgeHours)
+	}
+
+	var timestamp time.Time
+	source := "mtime"
+	if opts.Field == "" {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file: %w", err)
+		}
+		timestamp = info.ModTime()
+	} else {
+		var err error
+		if timestamp, err = newestTimestamp(filePath, opts.Field, s.call); err != nil {
+			return nil, err
+		}
+		source = opts.Field
+	}
+
+	age := time.Since(timestamp)
+	result := &FreshnessResult{AgeHours: age.Hours(), Timestamp: timestamp.UTC().Format(time.RFC3339Nano), Source: source}
+	if age > time.Duration(opts.MaxAgeHours*float64(time.Hour)) {
+		return nil, fmt.Errorf("%s is stale: %s %s is %.1f hours old, more than the allowed %v hours",
+			filePath, source, result.Timestamp, result.AgeHours, opts.MaxAgeHours)
+	}
+	return result, nil
+}
+
+// newestTimestamp returns the newest value of a timestamp field among the records of a file. A
+// JSON object file is a single record.
+func newestTimestamp(filePath string, field string, call callLimits) (time.Time, error) {
+	var newest time.Time
+	found := false
+	index := 0
+	err := forEachJsonRecord(filePath, call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d in %s: %w", index, filePath, err)
+		}
+		index++
+		value, ok := lookupField(record, field)
+		if !ok || value == nil {
+			return true, nil
+		}
+		t, err := parseTimestamp(value)
+		if err != nil {
+			return false, fmt.Errorf("record %d: field %q: %w", index-1, field, err)
+		}
+		if !found || t.After(newest) {
+			newest, found = t, true
+		}
+		return true, nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !found {
+		return time.Time{}, fmt.Errorf("no record in %s has the timestamp field %q", filePath, field)
+	}
+	return newest, nil
+}
@@ -0,0 +1,70 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssertFresh(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string, modTime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+	ts := func(ago time.Duration) string { return now.Add(-ago).UTC().Format(time.RFC3339) }
+
+	fresh := write("fresh.json", `[]`, now.Add(-time.Hour))
+	stale := write("stale.json", `[]`, now.Add(-48*time.Hour))
+	// The file was just written, but the recording inside it is old
+	stats := write("stats.json", fmt.Sprintf(`{"recordingId":"x","endTime":%q}`, ts(30*time.Hour)), now)
+	records := write("records.ndjson", fmt.Sprintf("{\"at\":%q}\n{\"at\":%d}\n{\"id\":3}\n", ts(50*time.Hour), now.Add(-2*time.Hour).UnixMilli()), now.Add(-100*time.Hour))
+
+	tests := []struct {
+		name       string
+		path       string
+		maxAge     interface{}
+		wantAge    float64
+		wantSource string
+		wantErr    string
+	}{
+		{"fresh by mtime", fresh, int64(24), 1, "mtime", ""},
+		{"stale by mtime", stale, 24.0, 0, "", "stale.json is stale: mtime"},
+		{"stale by field", stats, map[string]interface{}{"maxAgeHours": int64(24), "field": "endTime"}, 0, "", "endTime"},
+		{"fresh by field", stats, FreshnessOptions{MaxAgeHours: 36, Field: "endTime"}, 30, "endTime", ""},
+		{"newest record", records, map[string]interface{}{"maxAgeHours": 3, "field": "at"}, 2, "at", ""},
+		{"no record has the field", records, FreshnessOptions{MaxAgeHours: 1, Field: "missing"}, 0, "", `no record in`},
+		{"invalid maxAge", fresh, "a day", 0, "", "invalid maxAge"},
+		{"zero maxAge", fresh, 0, 0, "", "maxAgeHours must be positive"},
+		{"missing file", filepath.Join(dir, "missing.json"), 24, 0, "", "failed to stat file"},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := loader.AssertFresh(tt.path, tt.maxAge)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("AssertFresh() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AssertFresh() error = %v", err)
+			}
+			if result.Source != tt.wantSource || result.AgeHours < tt.wantAge-0.01 || result.AgeHours > tt.wantAge+0.01 {
+				t.Errorf("AssertFresh() = %+v, want an age of %v hours from %s", result, tt.wantAge, tt.wantSource)
+			}
+		})
+	}
+}
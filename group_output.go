@@ -0,0 +1,232 @@
+// group_output.go
+package streamloader
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// groupOutputBufferSize is the write buffer per open group file. It is smaller than the usual
+// 64KB because one buffer is kept for every group file open while the input is processed.
+const groupOutputBufferSize = 16 * 1024
+
+// maxOpenGroupFiles is how many group files are kept open at once. Beyond it, the least recently
+// written file is closed and reopened for appending when its group gets another row, so a key
+// with many distinct values neither runs out of file descriptors nor fills the file pool.
+var maxOpenGroupFiles = 64
+
+// groupFileWriter streams grouped rows into one JSON array file per group key.
+type groupFileWriter struct {
+	pattern string
+	groups  map[string]*groupFile
+	paths   map[string]string // Output path -> group key, to detect keys mapping to the same file
+	open    *list.List        // Open group files, most recently written first
+}
+
+// groupFile is the output file of one group.
+type groupFile struct {
+	path   string
+	writer *jsonArrayWriter
+	elem   *list.Element // Position in the list of open files, nil while suspended
+}
+
+// newGroupFileWriter validates the output pattern, which must contain the {key} placeholder.
+func newGroupFileWriter(pattern string) (*groupFileWriter, error) {
+	if !strings.Contains(pattern, "{key}") {
+		return nil, fmt.Errorf("invalid groupBy outputPattern %q: must contain {key}", pattern)
+	}
+	if dir := filepath.Dir(pattern); !strings.Contains(dir, "{key}") {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("invalid groupBy outputPattern %q: directory %s does not exist", pattern, dir)
+		}
+	}
+	return &groupFileWriter{
+		pattern: pattern,
+		groups:  make(map[string]*groupFile),
+		paths:   make(map[string]string),
+		open:    list.New(),
+	}, nil
+}
+
+// groupFileName makes a group key safe to embed in a file name. Characters other than letters,
+// digits, '.', '-' and '_' are replaced with '_', and an empty key becomes "_".
+func groupFileName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name == "." || name == ".." {
+		name = strings.Repeat("_", len(name)+1)
+	}
+	return name
+}
+
+// Write appends a projected row, or a record, to the file of its group, creating the file on
+// first use.
+func (g *groupFileWriter) Write(key string, projected interface{}) error {
+	f, ok := g.groups[key]
+	switch {
+	case !ok:
+		path := strings.ReplaceAll(g.pattern, "{key}", groupFileName(key))
+		if other, exists := g.paths[path]; exists {
+			return fmt.Errorf("groups %q and %q both map to output file %s", other, key, path)
+		}
+		if err := g.makeRoom(); err != nil {
+			return err
+		}
+		w, err := createJsonArrayFile(path, groupOutputBufferSize)
+		if err != nil {
+			return err
+		}
+		f = &groupFile{path: path, writer: w}
+		g.groups[key] = f
+		g.paths[path] = key
+		f.elem = g.open.PushFront(f)
+	case f.elem == nil:
+		if err := g.makeRoom(); err != nil {
+			return err
+		}
+		if err := f.writer.resume(f.path); err != nil {
+			return err
+		}
+		f.elem = g.open.PushFront(f)
+	default:
+		g.open.MoveToFront(f.elem)
+	}
+
+	encoded, err := json.Marshal(projected)
+	if err != nil {
+		return fmt.Errorf("failed to marshal row for group %q: %w", key, err)
+	}
+	return f.writer.Write(encoded)
+}
+
+// makeRoom suspends the least recently written group file if as many as allowed are open.
+func (g *groupFileWriter) makeRoom() error {
+	if g.open.Len() < maxOpenGroupFiles {
+		return nil
+	}
+	f := g.open.Remove(g.open.Back()).(*groupFile)
+	f.elem = nil
+	if err := f.writer.suspend(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// Close finishes every group file and returns one [key, path, rowCount] summary row per group,
+// sorted by key. If a file can't be finished, every group file is removed.
+func (g *groupFileWriter) Close() ([][]interface{}, error) {
+	var firstErr error
+	summary := make([][]interface{}, 0, len(g.groups))
+	for key, f := range g.groups {
+		if f.elem == nil && firstErr == nil {
+			if err := f.writer.resume(f.path); err != nil {
+				firstErr = err
+			}
+		}
+		if f.writer.file == nil {
+			// Suspended and not reopened after an error
+			f.writer.lock.release()
+		} else if err := f.writer.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to finish %s: %w", f.path, err)
+		}
+		summary = append(summary, []interface{}{key, f.path, f.writer.count})
+	}
+	if firstErr != nil {
+		g.remove()
+		return nil, firstErr
+	}
+
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i][0].(string) < summary[j][0].(string)
+	})
+	return summary, nil
+}
+
+// Abort closes the group files of a failed run and removes them.
+func (g *groupFileWriter) Abort() {
+	for _, f := range g.groups {
+		if f.writer.file != nil {
+			f.writer.file.Close()
+		}
+		f.writer.slot.release()
+		f.writer.lock.release()
+	}
+	g.remove()
+}
+
+// remove deletes every group file created.
+func (g *groupFileWriter) remove() {
+	for path := range g.paths {
+		os.Remove(path)
+	}
+}
+
+// suspend flushes a group file and closes it without finishing the array, releasing its file
+// pool slot and buffer but keeping the output lock.
+func (w *jsonArrayWriter) suspend() error {
+	err := w.writer.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.slot.release()
+	w.file, w.writer, w.slot = nil, nil, nil
+	return err
+}
+
+// resume reopens a suspended group file for appending.
+func (w *jsonArrayWriter) resume(path string) error {
+	slot, err := acquireFileSlot(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		slot.release()
+		return fmt.Errorf("failed to reopen output file: %w", err)
+	}
+	w.file, w.slot = file, slot
+	w.writer = bufio.NewWriterSize(countingWriter{file}, groupOutputBufferSize)
+	return nil
+}
+
+// validateGroupKeyHash checks the groupBy hashKey option.
+func validateGroupKeyHash(algorithm string) error {
+	switch algorithm {
+	case "", "sha1", "sha256":
+		return nil
+	default:
+		return fmt.Errorf("invalid groupBy hashKey %q: expected \"sha1\" or \"sha256\"", algorithm)
+	}
+}
+
+// hashGroupKey anonymizes a group key by hashing the salt followed by the key. The hex digest is
+// stable across runs for the same salt, so grouped outputs can be shared and compared without
+// exposing the original identifiers. An empty algorithm returns the key unchanged.
+func hashGroupKey(key string, algorithm string, salt string) string {
+	var h hash.Hash
+	switch algorithm {
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return key
+	}
+	h.Write([]byte(salt))
+	h.Write([]byte(key))
+	return hex.EncodeToString(h.Sum(nil))
+}
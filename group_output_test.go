@@ -0,0 +1,196 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessCsvFile_GroupOutputFiles(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "tenants.csv")
+	csvContent := "id,tenant\n1,acme\n2,globex\n3,acme\n4,a/b\n"
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	pattern := filepath.Join(dir, "out-{key}.json")
+	summary, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		GroupBy:    &GroupByConfig{Column: 1, OutputPattern: pattern},
+		Fields:     []FieldConfig{{Type: "column", Column: 0}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+
+	expected := fmt.Sprint([][]interface{}{
+		{"a/b", filepath.Join(dir, "out-a_b.json"), 1},
+		{"acme", filepath.Join(dir, "out-acme.json"), 2},
+		{"globex", filepath.Join(dir, "out-globex.json"), 1},
+	})
+	if got := fmt.Sprint(summary); got != expected {
+		t.Errorf("summary = %s, want %s", got, expected)
+	}
+
+	files := map[string]string{
+		"out-acme.json":   `[["1"],["3"]]`,
+		"out-globex.json": `[["2"]]`,
+		"out-a_b.json":    `[["4"]]`,
+	}
+	for name, want := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %s, want %s", name, data, want)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			content string
+			pattern string
+		}{
+			{"pattern without key", csvContent, filepath.Join(dir, "out.json")},
+			{"missing directory", csvContent, filepath.Join(dir, "missing", "{key}.json")},
+			{"colliding keys", "id,tenant\n1,a b\n2,a_b\n", filepath.Join(dir, "c-{key}.json")},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				path := filepath.Join(dir, "input.csv")
+				os.WriteFile(path, []byte(tt.content), 0644)
+				_, err := loader.ProcessCsvFile(path, ProcessCsvOptions{
+					SkipHeader: true,
+					GroupBy:    &GroupByConfig{Column: 1, OutputPattern: tt.pattern},
+				})
+				if err == nil {
+					t.Error("Expected error")
+				}
+			})
+		}
+	})
+}
+
+func TestProcessCsvFile_HashedGroupKeys(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "customers.csv")
+	if err := os.WriteFile(csvPath, []byte("id,customer\n1,alice@example.com\n2,bob@example.com\n3,alice@example.com\n"), 0644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	aliceSha256 := hashGroupKey("alice@example.com", "sha256", "s3cret")
+	if len(aliceSha256) != 64 || aliceSha256 == hashGroupKey("alice@example.com", "sha256", "") {
+		t.Fatalf("unexpected salted sha256 digest %q", aliceSha256)
+	}
+	if got := hashGroupKey("alice@example.com", "sha1", ""); len(got) != 40 {
+		t.Errorf("sha1 digest has length %d, want 40", len(got))
+	}
+
+	summary, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		GroupBy: &GroupByConfig{
+			Column:        1,
+			OutputPattern: filepath.Join(dir, "customer-{key}.json"),
+			HashKey:       "sha256",
+			Salt:          "s3cret",
+		},
+		Fields: []FieldConfig{{Type: "column", Column: 0}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if len(summary) != 2 {
+		t.Fatalf("expected 2 groups, got %v", summary)
+	}
+	for _, group := range summary {
+		key := group[0].(string)
+		if key != aliceSha256 && key != hashGroupKey("bob@example.com", "sha256", "s3cret") {
+			t.Errorf("group key %q is not a salted digest", key)
+		}
+		if key == aliceSha256 && group[2] != 2 {
+			t.Errorf("expected 2 rows for alice, got %v", group[2])
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "customer-"+aliceSha256+".json"))
+	if err != nil || string(data) != `[["1"],["3"]]` {
+		t.Errorf("unexpected hashed group file: %s (err %v)", data, err)
+	}
+
+	// In-memory grouping groups by the hashed key as well
+	grouped, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		GroupBy:    &GroupByConfig{Column: 1, HashKey: "sha1"},
+		Fields:     []FieldConfig{{Type: "column", Column: 0}},
+	})
+	if err != nil || len(grouped) != 2 {
+		t.Errorf("expected 2 in-memory groups, got %v (err %v)", grouped, err)
+	}
+
+	if _, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{GroupBy: &GroupByConfig{Column: 1, HashKey: "md5"}}); err == nil {
+		t.Error("Expected error for unsupported hash algorithm")
+	}
+}
+
+func TestProcessCsvFile_GroupOutputFilesReopened(t *testing.T) {
+	defer func(limit int) { maxOpenGroupFiles = limit }(maxOpenGroupFiles)
+	maxOpenGroupFiles = 2
+
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "tenants.csv")
+	// Rows alternate between more groups than may be open at once
+	csvContent := "id,tenant\n"
+	for i := 0; i < 12; i++ {
+		csvContent += fmt.Sprintf("%d,t%d\n", i, i%4)
+	}
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	summary, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		GroupBy:    &GroupByConfig{Column: 1, OutputPattern: filepath.Join(dir, "out-{key}.json")},
+		Fields:     []FieldConfig{{Type: "column", Column: 0}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if len(summary) != 4 {
+		t.Fatalf("summary = %v, want 4 groups", summary)
+	}
+	for g := 0; g < 4; g++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("out-t%d.json", g)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf(`[["%d"],["%d"],["%d"]]`, g, g+4, g+8)
+		if string(data) != want {
+			t.Errorf("out-t%d.json = %s, want %s", g, data, want)
+		}
+	}
+	if n := pooledFiles(); n != 0 {
+		t.Errorf("%d file pool slots still in use", n)
+	}
+
+	// A failed run removes the group files it created
+	failDir := t.TempDir()
+	os.WriteFile(csvPath, []byte("id,tenant\n1,a b\n2,c\n3,d\n4,a_b\n"), 0644)
+	if _, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		GroupBy:    &GroupByConfig{Column: 1, OutputPattern: filepath.Join(failDir, "{key}.json")},
+	}); err == nil {
+		t.Fatal("Expected error for colliding keys")
+	}
+	if entries, _ := os.ReadDir(failDir); len(entries) != 0 {
+		t.Errorf("Expected no files left after a failed run, got %d", len(entries))
+	}
+	if n := pooledFiles(); n != 0 {
+		t.Errorf("%d file pool slots still in use", n)
+	}
+}
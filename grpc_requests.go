@@ -0,0 +1,356 @@
+// grpc_requests.go
+package streamloader
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// defaultGrpcReflectTimeout bounds the server reflection calls of BuildGrpcRequests.
+const defaultGrpcReflectTimeout = 10 * time.Second
+
+// maxGrpcMismatches is the number of records that don't match the request type listed in the
+// error of BuildGrpcRequests; the rest are only counted.
+const maxGrpcMismatches = 10
+
+// GrpcRequestOptions configures where BuildGrpcRequests finds the service definitions and what
+// it returns. Exactly one of DescriptorSet and Reflect is set.
+type GrpcRequestOptions struct {
+	DescriptorSet  string `json:"descriptorSet" js:"descriptorSet"`
+	Reflect        string `json:"reflect" js:"reflect"`
+	Plaintext      bool   `json:"plaintext" js:"plaintext"`
+	TimeoutMs      int64  `json:"timeoutMs" js:"timeoutMs"`
+	DiscardUnknown bool   `json:"discardUnknown" js:"discardUnknown"`
+	Binary         bool   `json:"binary" js:"binary"`
+}
+
+// GrpcRequests holds the records of BuildGrpcRequests, checked against the request type of a method
+type GrpcRequests struct {
+	Method       string        `json:"method" js:"method"`             // As k6's client.invoke takes it: package.Service/Method
+	RequestType  string        `json:"requestType" js:"requestType"`   // Full name of the request message
+	ResponseType string        `json:"responseType" js:"responseType"` // Full name of the response message
+	Requests     []interface{} `json:"requests" js:"requests"`
+}
+
+// BuildGrpcRequests reads the records of a JSON array or NDJSON file and checks each against
+// the request message of a gRPC method, so a dataset that doesn't match the service fails at
+// init instead of as a stream of INVALID_ARGUMENT responses. Records use the protobuf JSON
+// mapping: field names as in the .proto file or in lowerCamelCase, 64-bit integers as numbers
+// or strings, enums by name or number, bytes as base64.
+//
+// method is "package.Service/Method", as k6's client.invoke takes it; a leading '/' or a '.'
+// instead of the '/' is accepted too.
+//
+// Options:
+//   - descriptorSet: Path of a binary FileDescriptorSet holding the service, such as written by
+//     `protoc --include_imports -o api.pb` or `buf build -o api.pb`
+//   - reflect: Address of a server to ask for the service with gRPC server reflection
+//   - plaintext: Connect to the reflect address without TLS (default: false)
+//   - timeoutMs: Time allowed for the reflection calls (default: 10000)
+//   - discardUnknown: Drop record fields the request message doesn't have instead of failing
+//   - binary: Return the requests in the protobuf wire format, as ArrayBuffers, instead of objects
+//
+// Returns: The method, its request and response types, and the requests: objects in the
+// canonical protobuf JSON form, which client.invoke accepts as they are, or ArrayBuffers with
+// binary. Records that don't match fail the call, with the first few mismatches in the error.
+//
+// Example usage:
+//
+//	const batch = streamloader.buildGrpcRequests("requests.json", "hello.HelloService/SayHello", {
+//		descriptorSet: "hello.pb",
+//	});
+//	// In the default function:
+//	client.invoke(batch.method, batch.requests[exec.scenario.iterationInTest % batch.requests.length]);
+func (s StreamLoader) BuildGrpcRequests(filePath string, method string, options GrpcRequestOptions) (*GrpcRequests, error) {
+	serviceName, methodName, err := splitGrpcMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	var files *protoregistry.Files
+	switch {
+	case (options.DescriptorSet == "") == (options.Reflect == ""):
+		return nil, fmt.Errorf("exactly one of descriptorSet and reflect must be set")
+	case options.DescriptorSet != "":
+		files, err = loadDescriptorSet(options.DescriptorSet, s.call)
+	default:
+		files, err = reflectGrpcService(options, serviceName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", serviceName, err)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	methodDesc := service.Methods().ByName(protoreflect.Name(methodName))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+
+	input := methodDesc.Input()
+	result := &GrpcRequests{
+		Method:       serviceName + "/" + methodName,
+		RequestType:  string(input.FullName()),
+		ResponseType: string(methodDesc.Output().FullName()),
+		Requests:     []interface{}{},
+	}
+	unmarshal := protojson.UnmarshalOptions{DiscardUnknown: options.DiscardUnknown, Resolver: dynamicpb.NewTypes(files)}
+	marshal := protojson.MarshalOptions{Resolver: unmarshal.Resolver}
+	var mismatches []string
+	records, failed := 0, 0
+	err = forEachJsonRecord(filePath, s.call, func(raw json.RawMessage) (bool, error) {
+		index := records
+		records++
+		message := dynamicpb.NewMessage(input)
+		if err := unmarshal.Unmarshal(raw, message); err != nil {
+			failed++
+			if len(mismatches) < maxGrpcMismatches {
+				mismatches = append(mismatches, fmt.Sprintf("record %d: %v", index, err))
+			}
+			return true, nil
+		}
+		if failed > 0 {
+			// Only the mismatches are reported
+			return true, nil
+		}
+
+		if options.Binary {
+			encoded, err := proto.Marshal(message)
+			if err != nil {
+				return false, fmt.Errorf("failed to encode record %d: %w", index, err)
+			}
+			result.Requests = append(result.Requests, s.newArrayBuffer(encoded))
+			return true, nil
+		}
+		encoded, err := marshal.Marshal(message)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode record %d: %w", index, err)
+		}
+		var request interface{}
+		if err := json.Unmarshal(encoded, &request); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", index, err)
+		}
+		result.Requests = append(result.Requests, request)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		return nil, fmt.Errorf("%d of %d records don't match %s: %s", failed, records, input.FullName(), strings.Join(mismatches, "; "))
+	}
+	return result, nil
+}
+
+// splitGrpcMethod splits "package.Service/Method" into the service and method names.
+func splitGrpcMethod(method string) (string, string, error) {
+	method = strings.TrimPrefix(method, "/")
+	cut := strings.LastIndex(method, "/")
+	if cut < 0 {
+		cut = strings.LastIndex(method, ".")
+	}
+	if cut <= 0 || cut == len(method)-1 {
+		return "", "", fmt.Errorf("invalid method %q: expected package.Service/Method", method)
+	}
+	return method[:cut], method[cut+1:], nil
+}
+
+// loadDescriptorSet reads a binary FileDescriptorSet.
+func loadDescriptorSet(filePath string, call callLimits) (*protoregistry.Files, error) {
+	data, err := readInputFile(filePath, call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode descriptor set %s: %w", filePath, err)
+	}
+	return newGrpcFiles(set.File)
+}
+
+// newGrpcFiles links file descriptors into a registry. Imports missing from files, as in a
+// descriptor set written without --include_imports, are taken from the descriptors built into
+// the binary when it has them, which covers the well-known types.
+func newGrpcFiles(files []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	byName := make(map[string]bool, len(files))
+	for _, file := range files {
+		byName[file.GetName()] = true
+	}
+	for i := 0; i < len(files); i++ {
+		for _, dep := range files[i].GetDependency() {
+			if byName[dep] {
+				continue
+			}
+			builtin, err := protoregistry.GlobalFiles.FindFileByPath(dep)
+			if err != nil {
+				return nil, fmt.Errorf("import %s of %s is missing; include it in the descriptor set", dep, files[i].GetName())
+			}
+			byName[dep] = true
+			files = append(files, protodesc.ToFileDescriptorProto(builtin))
+		}
+	}
+	registry, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: files})
+	if err != nil {
+		return nil, fmt.Errorf("invalid service definitions: %w", err)
+	}
+	return registry, nil
+}
+
+// reflectionRequest asks a reflection stream for the file declaring symbol, or for the file
+// named filename, returning serialized FileDescriptorProtos.
+type reflectionRequest func(symbol string, filename string) ([][]byte, error)
+
+// reflectGrpcService asks a server for the file declaring a service and its imports with
+// gRPC server reflection, falling back to the v1alpha protocol older servers implement.
+func reflectGrpcService(options GrpcRequestOptions, serviceName string) (*protoregistry.Files, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if options.Plaintext {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(options.Reflect, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", options.Reflect, err)
+	}
+	defer conn.Close()
+
+	timeout := defaultGrpcReflectTimeout
+	if options.TimeoutMs > 0 {
+		timeout = time.Duration(options.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, err := reflectionV1(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server reflection on %s: %w", options.Reflect, err)
+	}
+	raw, err := request(serviceName, "")
+	if status.Code(err) == codes.Unimplemented {
+		if request, err = reflectionV1alpha(ctx, conn); err != nil {
+			return nil, fmt.Errorf("failed to start server reflection on %s: %w", options.Reflect, err)
+		}
+		raw, err = request(serviceName, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reflect service %s on %s: %w", serviceName, options.Reflect, err)
+	}
+
+	// Servers send the imports of a file along with it, except those sent earlier on the
+	// stream; ask for any still missing by name
+	var files []*descriptorpb.FileDescriptorProto
+	byName := make(map[string]bool)
+	add := func(raw [][]byte) error {
+		for _, data := range raw {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, file); err != nil {
+				return fmt.Errorf("failed to decode file descriptor from %s: %w", options.Reflect, err)
+			}
+			if !byName[file.GetName()] {
+				byName[file.GetName()] = true
+				files = append(files, file)
+			}
+		}
+		return nil
+	}
+	if err := add(raw); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(files); i++ {
+		for _, dep := range files[i].GetDependency() {
+			if byName[dep] {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				// Left to newGrpcFiles
+				continue
+			}
+			raw, err := request("", dep)
+			if err != nil {
+				return nil, fmt.Errorf("failed to reflect file %s on %s: %w", dep, options.Reflect, err)
+			}
+			if err := add(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return newGrpcFiles(files)
+}
+
+// reflectionV1 opens a grpc.reflection.v1 stream.
+func reflectionV1(ctx context.Context, conn *grpc.ClientConn) (reflectionRequest, error) {
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(symbol string, filename string) ([][]byte, error) {
+		req := &reflectionv1.ServerReflectionRequest{}
+		if symbol != "" {
+			req.MessageRequest = &reflectionv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol}
+		} else {
+			req.MessageRequest = &reflectionv1.ServerReflectionRequest_FileByFilename{FileByFilename: filename}
+		}
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}, nil
+}
+
+// reflectionV1alpha opens a grpc.reflection.v1alpha stream.
+func reflectionV1alpha(ctx context.Context, conn *grpc.ClientConn) (reflectionRequest, error) {
+	stream, err := reflectionv1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(symbol string, filename string) ([][]byte, error) {
+		req := &reflectionv1alpha.ServerReflectionRequest{}
+		if symbol != "" {
+			req.MessageRequest = &reflectionv1alpha.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol}
+		} else {
+			req.MessageRequest = &reflectionv1alpha.ServerReflectionRequest_FileByFilename{FileByFilename: filename}
+		}
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}, nil
+}
@@ -0,0 +1,208 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// helloProto describes a small service whose request imports a well-known type.
+func helloProto() *descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("hello.proto"),
+		Package:    proto.String("hello"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Tone"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("QUIET"), Number: proto.Int32(0)},
+				{Name: proto.String("LOUD"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("HelloRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("greeting", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				field("tone", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".hello.Tone"),
+				field("at", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+			}},
+			{Name: proto.String("HelloReply"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("reply", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("HelloService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("SayHello"),
+				InputType:  proto.String(".hello.HelloRequest"),
+				OutputType: proto.String(".hello.HelloReply"),
+			}},
+		}},
+	}
+}
+
+// writeHelloDescriptorSet writes helloProto as a descriptor set without its import, as protoc
+// does without --include_imports.
+func writeHelloDescriptorSet(t *testing.T, dir string) string {
+	t.Helper()
+	data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{helloProto()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "hello.pb")
+	os.WriteFile(path, data, 0644)
+	return path
+}
+
+func TestBuildGrpcRequests(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	descriptorSet := writeHelloDescriptorSet(t, dir)
+	records := filepath.Join(dir, "requests.ndjson")
+	os.WriteFile(records, []byte(`{"greeting":"hi","count":3,"tone":1,"at":"2024-01-01T00:00:00Z"}
+{"greeting":"bye","tone":"QUIET"}
+`), 0644)
+
+	for _, method := range []string{"hello.HelloService/SayHello", "/hello.HelloService/SayHello", "hello.HelloService.SayHello"} {
+		batch, err := loader.BuildGrpcRequests(records, method, GrpcRequestOptions{DescriptorSet: descriptorSet})
+		if err != nil {
+			t.Fatalf("BuildGrpcRequests(%q) error = %v", method, err)
+		}
+		if batch.Method != "hello.HelloService/SayHello" || batch.RequestType != "hello.HelloRequest" || batch.ResponseType != "hello.HelloReply" {
+			t.Errorf("BuildGrpcRequests(%q) = %s %s %s", method, batch.Method, batch.RequestType, batch.ResponseType)
+		}
+		// Requests are in the canonical JSON form
+		got, _ := json.Marshal(batch.Requests)
+		want := `[{"at":"2024-01-01T00:00:00Z","count":"3","greeting":"hi","tone":"LOUD"},{"greeting":"bye"}]`
+		if string(got) != want {
+			t.Errorf("BuildGrpcRequests(%q) requests = %s, want %s", method, got, want)
+		}
+	}
+
+	// Binary requests decode as the request message
+	batch, err := loader.BuildGrpcRequests(records, "hello.HelloService/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet, Binary: true})
+	if err != nil {
+		t.Fatalf("BuildGrpcRequests(binary) error = %v", err)
+	}
+	files, err := newGrpcFiles([]*descriptorpb.FileDescriptorProto{helloProto()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, _ := files.FindDescriptorByName("hello.HelloRequest")
+	message := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	if err := proto.Unmarshal(batch.Requests[0].([]byte), message); err != nil {
+		t.Fatalf("decoding binary request: %v", err)
+	}
+	if got := message.Get(message.Descriptor().Fields().ByName("count")).Int(); got != 3 {
+		t.Errorf("binary request count = %d, want 3", got)
+	}
+
+	// Mismatches are reported together, with the record numbers
+	mismatched := filepath.Join(dir, "mismatched.json")
+	os.WriteFile(mismatched, []byte(`[{"greeting":"hi"},{"greeting":"hi","user":"ada"},{"count":"many"}]`), 0644)
+	_, err = loader.BuildGrpcRequests(mismatched, "hello.HelloService/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet})
+	if err == nil || !strings.Contains(err.Error(), "2 of 3 records don't match hello.HelloRequest") ||
+		!strings.Contains(err.Error(), "record 1:") || !strings.Contains(err.Error(), "record 2:") {
+		t.Errorf("BuildGrpcRequests(mismatched) error = %v", err)
+	}
+	unknown := filepath.Join(dir, "unknown.json")
+	os.WriteFile(unknown, []byte(`[{"greeting":"hi","user":"ada"}]`), 0644)
+	batch, err = loader.BuildGrpcRequests(unknown, "hello.HelloService/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet, DiscardUnknown: true})
+	if err != nil || len(batch.Requests) != 1 {
+		t.Errorf("BuildGrpcRequests(discardUnknown) = %v, %v", batch, err)
+	}
+
+	// Errors
+	invalid := []struct {
+		method  string
+		options GrpcRequestOptions
+	}{
+		{"hello.HelloService/SayHello", GrpcRequestOptions{}},
+		{"hello.HelloService/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet, Reflect: "localhost:1"}},
+		{"SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet}},
+		{"hello.HelloService/SayBye", GrpcRequestOptions{DescriptorSet: descriptorSet}},
+		{"hello.Missing/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet}},
+		{"hello.HelloRequest/SayHello", GrpcRequestOptions{DescriptorSet: descriptorSet}},
+		{"hello.HelloService/SayHello", GrpcRequestOptions{DescriptorSet: records}},
+	}
+	for _, tt := range invalid {
+		if _, err := loader.BuildGrpcRequests(records, tt.method, tt.options); err == nil {
+			t.Errorf("BuildGrpcRequests(%q, %+v) expected an error", tt.method, tt.options)
+		}
+	}
+}
+
+// helloServices lists the service of helloProto for the reflection server.
+type helloServices struct{}
+
+func (helloServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	return map[string]grpc.ServiceInfo{"hello.HelloService": {}}
+}
+
+func TestBuildGrpcRequestsReflect(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	records := filepath.Join(dir, "requests.json")
+	os.WriteFile(records, []byte(`[{"greeting":"hi","at":"2024-01-01T00:00:00Z"}]`), 0644)
+	files, err := newGrpcFiles([]*descriptorpb.FileDescriptorProto{helloProto()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := reflection.ServerOptions{Services: helloServices{}, DescriptorResolver: files}
+
+	// Servers with only the v1alpha protocol are reflected too
+	for _, version := range []string{"v1", "v1alpha"} {
+		t.Run(version, func(t *testing.T) {
+			server := grpc.NewServer()
+			if version == "v1" {
+				reflectionv1.RegisterServerReflectionServer(server, reflection.NewServerV1(options))
+			} else {
+				reflectionv1alpha.RegisterServerReflectionServer(server, reflection.NewServer(options))
+			}
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go server.Serve(listener)
+			defer server.Stop()
+
+			batch, err := loader.BuildGrpcRequests(records, "hello.HelloService/SayHello", GrpcRequestOptions{Reflect: listener.Addr().String(), Plaintext: true})
+			if err != nil {
+				t.Fatalf("BuildGrpcRequests(reflect) error = %v", err)
+			}
+			if got, _ := json.Marshal(batch.Requests); string(got) != `[{"at":"2024-01-01T00:00:00Z","greeting":"hi"}]` {
+				t.Errorf("BuildGrpcRequests(reflect) requests = %s", got)
+			}
+			if _, err := loader.BuildGrpcRequests(records, "hello.Missing/SayHello", GrpcRequestOptions{Reflect: listener.Addr().String(), Plaintext: true}); err == nil {
+				t.Error("BuildGrpcRequests(reflect, unknown service) expected an error")
+			}
+		})
+	}
+}
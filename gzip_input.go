@@ -0,0 +1,69 @@
+// gzip_input.go
+package streamloader
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// isGzipPath reports whether an input path has a ".gz" extension, or is a data URI of
+// application/gzip.
+func isGzipPath(filePath string) bool {
+	return strings.EqualFold(inputExt(filePath), ".gz")
+}
+
+// trimGzipExt returns an input path without its ".gz" extension, so the format of "data.ndjson.gz"
+// or "data.tsv.gz" is still detected from the extension of the compressed file.
+func trimGzipExt(filePath string) string {
+	if isDataURI(filePath) || !strings.EqualFold(inputExt(filePath), ".gz") {
+		return filePath
+	}
+	if isRemoteURL(filePath) {
+		u, _ := url.Parse(filePath) // Parsed by inputExt
+		u.Path = u.Path[:len(u.Path)-len(".gz")]
+		u.RawPath = ""
+		return u.String()
+	}
+	return filePath[:len(filePath)-len(".gz")]
+}
+
+// gunzipInput transparently decompresses an input that has a ".gz" extension or starts with
+// the gzip magic bytes, so "data.json.gz" and "data.csv.gz" load without the script
+// decompressing them to a temporary file first. Other inputs are returned as they are.
+// Concatenated gzip members are read as one stream. With snapshot set, the input is cut off at
+// the snapshot limit, usually in the middle of the compressed stream of a file that is being
+// appended to, so a truncated stream ends the input instead of failing. The decompressed bytes
+// count against the call's maxFileBytes limit.
+func gunzipInput(reader *bufio.Reader, filePath string, snapshot bool, call callLimits) (*bufio.Reader, error) {
+	magic, _ := reader.Peek(2)
+	if !isGzipPath(filePath) && (len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b) {
+		return reader, nil
+	}
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip header of %s: %w", filePath, err)
+	}
+	if snapshot {
+		return bufio.NewReaderSize(call.decompressed(filePath, truncatedGzipReader{gz}), readBufferSize()), nil
+	}
+	return bufio.NewReaderSize(call.decompressed(filePath, gz), readBufferSize()), nil
+}
+
+// truncatedGzipReader reads a gzip stream up to where it was cut off. What was decompressed
+// before the cut is returned; a record it splits is dropped by the loaders like any half-written
+// last record of a snapshot.
+type truncatedGzipReader struct {
+	gz *gzip.Reader
+}
+
+func (r truncatedGzipReader) Read(p []byte) (int, error) {
+	n, err := r.gz.Read(p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,97 @@
+package streamloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadJSONGzip(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+		want    int
+		wantErr bool
+	}{
+		{name: "array with extension", file: "data.json.gz", content: gzipBytes(t, `[{"id":1},{"id":2}]`), want: 2},
+		{name: "ndjson with extension", file: "events.ndjson.gz", content: gzipBytes(t, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"), want: 3},
+		{name: "magic bytes without extension", file: "data.json", content: gzipBytes(t, `[{"id":1}]`), want: 1},
+		{name: "uncompressed", file: "data.json", content: []byte(`[{"id":1}]`), want: 1},
+		{name: "extension without gzip data", file: "data.json.gz", content: []byte(`[{"id":1}]`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, tt.content, 0644)
+			got, err := loader.LoadJSON(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadJSON() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadJSON() error = %v", err)
+			}
+			n := 0
+			switch records := got.(type) {
+			case []map[string]any:
+				n = len(records)
+			case []interface{}:
+				n = len(records)
+			}
+			if n != tt.want {
+				t.Errorf("LoadJSON() = %v, want %d records", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCSVGzip(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+		want    [][]string
+	}{
+		{name: "csv", file: "data.csv.gz", content: gzipBytes(t, "id,name\n1,a\n"), want: [][]string{{"id", "name"}, {"1", "a"}}},
+		{name: "tsv delimiter from inner extension", file: "data.tsv.gz", content: gzipBytes(t, "id\tname\n1\ta\n"), want: [][]string{{"id", "name"}, {"1", "a"}}},
+		{name: "magic bytes without extension", file: "data.csv", content: gzipBytes(t, "id\n1\n"), want: [][]string{{"id"}, {"1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, tt.content, 0644)
+			got, err := loader.LoadCSV(path)
+			if err != nil {
+				t.Fatalf("LoadCSV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadCSV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// gzip_members.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// gzipMembersReader decompresses every member of a multi-member gzip stream, as written by
+// producers that append one gzip member per batch. A newline is inserted after a member that
+// doesn't end with one, so the last line of a batch never merges with the first line of the
+// next. Zero padding after the last member is ignored.
+type gzipMembersReader struct {
+	src            *bufio.Reader
+	gz             *gzip.Reader
+	members        int  // Number of members fully read
+	last           byte // Last byte returned, 0 if nothing was returned yet
+	pendingNewline bool
+	done           bool
+}
+
+// newGzipMembersReader reads the first member header and returns a reader over all members.
+func newGzipMembersReader(r io.Reader) (*gzipMembersReader, error) {
+	src := bufio.NewReaderSize(r, readBufferSize())
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, err
+	}
+	// Stop at each member boundary so the next member can be handled explicitly
+	gz.Multistream(false)
+	return &gzipMembersReader{src: src, gz: gz}, nil
+}
+
+func (m *gzipMembersReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if m.pendingNewline {
+			m.pendingNewline = false
+			m.last = '\n'
+			p[0] = '\n'
+			return 1, nil
+		}
+		if m.done {
+			return 0, io.EOF
+		}
+
+		n, err := m.gz.Read(p)
+		if n > 0 {
+			m.last = p[n-1]
+			if err == io.EOF {
+				// Handle the member boundary on the next call
+				err = nil
+			}
+			return n, err
+		}
+		if err != io.EOF {
+			return 0, err
+		}
+
+		m.members++
+		if err := m.nextMember(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// nextMember advances to the next member, or marks the stream as done if only padding is left.
+func (m *gzipMembersReader) nextMember() error {
+	for {
+		b, err := m.src.ReadByte()
+		if err == io.EOF {
+			m.done = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b != 0 {
+			m.src.UnreadByte()
+			break
+		}
+	}
+	if err := m.gz.Reset(m.src); err != nil {
+		return fmt.Errorf("invalid gzip member %d: %w", m.members, err)
+	}
+	m.gz.Multistream(false)
+	if m.last != 0 && m.last != '\n' {
+		m.pendingNewline = true
+	}
+	return nil
+}
+
+func (m *gzipMembersReader) Close() error {
+	return m.gz.Close()
+}
+
+// MultiMemberGunzipToObjects decodes base64-encoded gzip data made of one or more concatenated
+// gzip members, such as a file that a producer appends one compressed batch at a time to, and
+// parses the JSON lines of every member. Each member is parsed on its own, so a batch that
+// doesn't end with a newline doesn't merge with the next one, and errors name the member.
+//
+// The other compressed JSON lines functions read all members too; use this function when the
+// member number in error messages helps to find a broken batch.
+//
+// Example usage:
+//
+//	objects, err := streamloader.MultiMemberGunzipToObjects(encoding.b64encode(open("batches.jsonl.gz", "b")))
+func (StreamLoader) MultiMemberGunzipToObjects(compressedData string) ([]interface{}, error) {
+	data, err := base64.StdEncoding.DecodeString(compressedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 data: %w", err)
+	}
+
+	reader, err := newGzipMembersReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer reader.Close()
+
+	objects := make([]interface{}, 0)
+	for member := 0; !reader.done; member++ {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, reader.gz); err != nil {
+			return nil, fmt.Errorf("failed to decompress member %d: %w", member, err)
+		}
+		parsed, err := (StreamLoader{}).JsonLinesToObjects(buf.String())
+		if err != nil {
+			return nil, fmt.Errorf("member %d: %w", member, err)
+		}
+		objects = append(objects, parsed...)
+
+		reader.members++
+		if err := reader.nextMember(); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
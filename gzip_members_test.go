@@ -0,0 +1,82 @@
+package streamloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gzipMembers compresses each batch as its own gzip member and concatenates them.
+func gzipMembers(batches ...string) []byte {
+	var buf bytes.Buffer
+	for _, batch := range batches {
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(batch))
+		gz.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestMultiMemberGunzipToObjects(t *testing.T) {
+	loader := StreamLoader{}
+	expected := []interface{}{
+		map[string]interface{}{"id": 1.0},
+		map[string]interface{}{"id": 2.0},
+		map[string]interface{}{"id": 3.0},
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"single member", gzipMembers("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")},
+		{"one member per batch", gzipMembers("{\"id\":1}\n", "{\"id\":2}\n", "{\"id\":3}\n")},
+		{"batches without trailing newlines", gzipMembers("{\"id\":1}", "{\"id\":2}\n{\"id\":3}")},
+		{"empty member", gzipMembers("{\"id\":1}\n", "", "{\"id\":2}\n{\"id\":3}\n")},
+		{"zero padding", append(gzipMembers("{\"id\":1}\n", "{\"id\":2}\n{\"id\":3}\n"), 0, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := base64.StdEncoding.EncodeToString(tt.data)
+			objects, err := loader.MultiMemberGunzipToObjects(encoded)
+			if err != nil {
+				t.Fatalf("MultiMemberGunzipToObjects() error = %v", err)
+			}
+			if !reflect.DeepEqual(objects, expected) {
+				t.Errorf("MultiMemberGunzipToObjects() = %v, want %v", objects, expected)
+			}
+
+			// The other compressed readers see every member as well
+			objects, err = loader.CompressedJsonLinesToObjects(encoded)
+			if err != nil || !reflect.DeepEqual(objects, expected) {
+				t.Errorf("CompressedJsonLinesToObjects() = %v, %v", objects, err)
+			}
+			path := filepath.Join(t.TempDir(), "out.json")
+			count, err := loader.WriteCompressedJsonLinesToArrayFile(encoded, path)
+			if err != nil || count != 3 {
+				t.Errorf("WriteCompressedJsonLinesToArrayFile() = %d, %v", count, err)
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != `[{"id":1},{"id":2},{"id":3}]` {
+				t.Errorf("file = %s", data)
+			}
+		})
+	}
+
+	broken := base64.StdEncoding.EncodeToString(gzipMembers("{\"id\":1}\n", "{\"id\":\n"))
+	if _, err := loader.MultiMemberGunzipToObjects(broken); err == nil || !strings.Contains(err.Error(), "member 1") {
+		t.Errorf("Expected error naming member 1, got %v", err)
+	}
+	garbage := base64.StdEncoding.EncodeToString(append(gzipMembers("{\"id\":1}\n"), "trailing"...))
+	if _, err := loader.MultiMemberGunzipToObjects(garbage); err == nil {
+		t.Error("Expected error for trailing garbage")
+	}
+	if _, err := loader.MultiMemberGunzipToObjects("not base64!"); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+}
@@ -0,0 +1,19 @@
+// handles.go
+package streamloader
+
+import "context"
+
+// handle is an object returned to scripts that holds resources until it is closed.
+type handle interface {
+	Close()
+}
+
+// closeAtIterationEnd closes h when the VU iteration that created it ends, so handles created in
+// the default function don't pile up over a long test when scripts forget to close them. Handles
+// created in the init context live as long as the VU and must be closed explicitly.
+func (s StreamLoader) closeAtIterationEnd(h handle) {
+	if s.vu == nil || s.vu.State() == nil {
+		return // Go caller or init context
+	}
+	context.AfterFunc(s.vu.Context(), h.Close)
+}
@@ -0,0 +1,117 @@
+package streamloader
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/lib"
+)
+
+// iterationVU is a VU in the middle of an iteration whose context is the given one.
+type iterationVU struct {
+	modules.VU
+	ctx     context.Context
+	state   *lib.State
+	runtime *sobek.Runtime
+}
+
+func (vu *iterationVU) Context() context.Context { return vu.ctx }
+func (vu *iterationVU) State() *lib.State        { return vu.state }
+func (vu *iterationVU) Runtime() *sobek.Runtime  { return vu.runtime }
+func (vu *iterationVU) Events() common.Events    { return common.Events{} }
+
+func TestHandlesClosedAtIterationEnd(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	loader := StreamLoader{vu: &iterationVU{ctx: ctx, state: &lib.State{}}}
+
+	seq, err := loader.GenerateRange(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := loader.WatchDirectory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seq.HasNext() {
+		t.Fatal("sequence should have values before the iteration ends")
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for seq.HasNext() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if seq.HasNext() {
+		t.Error("sequence was not closed at iteration end")
+	}
+	for {
+		_, err := watcher.Next()
+		if err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watcher was not closed at iteration end")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandlesInInitContextStayOpen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	loader := StreamLoader{vu: &iterationVU{ctx: ctx}} // No state: init context
+
+	seq, err := loader.GenerateUUIDs(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := seq.At(0); err != nil {
+		t.Errorf("sequence from the init context was closed: %v", err)
+	}
+}
+
+func TestCloseAndDispose(t *testing.T) {
+	loader := StreamLoader{}
+
+	seq, _ := loader.GenerateRange(0, 10)
+	part, _ := seq.Partition(0, 2)
+	seq.Dispose()
+	if seq.HasNext() || seq.Next() != nil {
+		t.Error("closed sequence still returns values")
+	}
+	if _, err := seq.At(0); err == nil {
+		t.Error("At() on a closed sequence should fail")
+	}
+	if v, err := part.At(1); err != nil || v != int64(1) {
+		t.Errorf("partition of a closed sequence: At(1) = %v, %v", v, err)
+	}
+
+	path := t.TempDir() + "/bodies.json"
+	if err := os.WriteFile(path, []byte(`{"sha256:a":{"x":1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := loader.LoadChunkStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("sha256:a"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store.Dispose()
+	if _, err := store.Get("sha256:a"); err == nil || store.Size() != 0 {
+		t.Error("closed chunk store still returns bodies")
+	}
+
+	watcher, _ := loader.WatchDirectory(t.TempDir())
+	watcher.Dispose()
+	if _, err := watcher.Next(); err == nil {
+		t.Error("Next() on a closed watcher should fail")
+	}
+}
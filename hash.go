@@ -0,0 +1,130 @@
+// hash.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/grafana/sobek"
+)
+
+// hashConstructor returns the constructor of a hash algorithm by name.
+func hashConstructor(algo string) (func() hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha384":
+		return sha512.New384, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q, expected md5, sha1, sha256, sha384 or sha512", algo)
+	}
+}
+
+// encodeDigest encodes a digest as "hex" (the default), "base64" or "base64url".
+func encodeDigest(sum []byte, encoding []string) (string, error) {
+	enc := "hex"
+	if len(encoding) > 0 && encoding[0] != "" {
+		enc = encoding[0]
+	}
+	switch enc {
+	case "hex":
+		return hex.EncodeToString(sum), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(sum), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	default:
+		return "", fmt.Errorf("unknown digest encoding %q, expected hex, base64 or base64url", enc)
+	}
+}
+
+// hashInput returns the bytes HashObject digests for a value.
+func hashInput(obj interface{}) ([]byte, error) {
+	switch v := obj.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case sobek.ArrayBuffer:
+		return v.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(obj); err != nil {
+		return nil, fmt.Errorf("failed to encode object: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// HashObject returns the digest of a value. Strings and ArrayBuffers are hashed as they are, so
+// the digest of a request body matches what is sent; other values are hashed as compact JSON
+// with object keys sorted, so equal records always produce the same digest regardless of key
+// order. The optional encoding is "hex" (the default), "base64" or "base64url".
+//
+// Supported algorithms: md5, sha1, sha256, sha384, sha512
+//
+// Example usage:
+//
+//	const body = JSON.stringify(payloads[i]);
+//	http.post(url, body, { headers: { "x-content-sha256": streamloader.hashObject(body, "sha256") } });
+func (StreamLoader) HashObject(obj interface{}, algo string, encoding ...string) (string, error) {
+	newHash, err := hashConstructor(algo)
+	if err != nil {
+		return "", err
+	}
+	data, err := hashInput(obj)
+	if err != nil {
+		return "", err
+	}
+	h := newHash()
+	h.Write(data)
+	return encodeDigest(h.Sum(nil), encoding)
+}
+
+// HmacFile returns the HMAC of a file's contents, streaming the file so large payloads are never
+// held in memory. The optional encoding is "hex" (the default), "base64" or "base64url".
+//
+// Supported algorithms: md5, sha1, sha256, sha384, sha512
+//
+// Example usage:
+//
+//	const signature = streamloader.hmacFile("webhook.json", "sha256", __ENV.WEBHOOK_SECRET);
+//	http.post(url, open("webhook.json"), { headers: { "x-signature": `sha256=${signature}` } });
+func (s StreamLoader) HmacFile(filePath string, algo string, key string, encoding ...string) (string, error) {
+	newHash, err := hashConstructor(algo)
+	if err != nil {
+		return "", err
+	}
+	if _, err := encodeDigest(nil, encoding); err != nil {
+		return "", err
+	}
+	file, err := openSequential(filePath, pageCacheKeep, s.call)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	mac := hmac.New(newHash, []byte(key))
+	if _, err := io.Copy(mac, bufio.NewReaderSize(file, readBufferSize())); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return encodeDigest(mac.Sum(nil), encoding)
+}
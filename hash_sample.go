@@ -0,0 +1,41 @@
+// hash_sample.go
+package streamloader
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// validateHashSampleRate checks the rate of a hashSample filter or condition.
+func validateHashSampleRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("hashSample rate must be between 0 and 1, got %v", rate)
+	}
+	return nil
+}
+
+// hashSampled reports whether a key falls in the sampled fraction. The key is hashed with the
+// salt into a number in [0, 1), and kept if that number is below rate. The decision depends only
+// on the key, salt and rate, so a key is either kept in every dataset sampled with the same salt
+// and rate or dropped from all of them, and raising the rate only ever adds keys.
+func hashSampled(key string, rate float64, salt string) bool {
+	sum := sha256.Sum256([]byte(salt + key))
+	fraction := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+	return fraction < rate
+}
+
+// keyString returns the string form of a key value. Numbers are formatted the way they appear in
+// CSV cells, so 42 in a JSON record and "42" in a CSV row are the same key.
+func keyString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if n, ok := numberValue(v); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
@@ -0,0 +1,139 @@
+package streamloader
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashSampled(t *testing.T) {
+	const n = 20000
+	for _, rate := range []float64{0, 0.1, 0.5, 1} {
+		kept := 0
+		for i := 0; i < n; i++ {
+			if hashSampled(fmt.Sprintf("user-%d", i), rate, "") {
+				kept++
+			}
+		}
+		if got := float64(kept) / n; math.Abs(got-rate) > 0.01 {
+			t.Errorf("rate %v kept %v of the keys", rate, got)
+		}
+	}
+
+	// A larger rate keeps a superset of the keys, and a salt draws a different sample
+	differs := false
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("order-%d", i)
+		if hashSampled(key, 0.1, "") && !hashSampled(key, 0.2, "") {
+			t.Fatalf("key %s kept at rate 0.1 but not at 0.2", key)
+		}
+		if hashSampled(key, 0.5, "") != hashSampled(key, 0.5, "v2") {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Error("salt didn't change the sample")
+	}
+}
+
+// TestHashSampleAcrossDatasets checks that a CSV file and a JSON file keyed by the same users
+// keep exactly the same users.
+func TestHashSampleAcrossDatasets(t *testing.T) {
+	dir := t.TempDir()
+	var csvRows, jsonRecords []string
+	for i := 0; i < 500; i++ {
+		csvRows = append(csvRows, fmt.Sprintf("%d,name-%d", i, i))
+		jsonRecords = append(jsonRecords, fmt.Sprintf(`{"userId":%d,"total":%d}`, i, i*10))
+	}
+	csvPath := filepath.Join(dir, "users.csv")
+	jsonPath := filepath.Join(dir, "orders.json")
+	if err := os.WriteFile(csvPath, []byte("id,name\n"+strings.Join(csvRows, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte("["+strings.Join(jsonRecords, ",")+"]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := StreamLoader{}
+	rate := 0.2
+	users, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		Filters:    []FilterConfig{{Type: "hashSample", Column: 0, Rate: &rate, Salt: "s1"}},
+		Fields:     []FieldConfig{{Type: "column", Column: 0}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+
+	orders, err := loader.LoadJSON(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it, err := loader.Iterate(orders.([]interface{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := it.Filter(map[string]interface{}{"field": "userId", "op": "hashSample", "value": rate, "salt": "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampled, err := filtered.ToArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) == 0 || len(users) == 500 {
+		t.Fatalf("sampled %d users, want a fraction", len(users))
+	}
+	if len(sampled) != len(users) {
+		t.Fatalf("sampled %d orders and %d users, want the same keys", len(sampled), len(users))
+	}
+	for i, row := range users {
+		if got := fmt.Sprint(sampled[i].(map[string]interface{})["userId"]); got != row[0] {
+			t.Errorf("order %d is for user %s, want %v", i, got, row[0])
+		}
+	}
+}
+
+func TestHashSampleErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte("a\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tooHigh := 1.5
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr string
+	}{
+		{"csv without rate", func() error {
+			_, err := loader.ProcessCsvFile(path, ProcessCsvOptions{Filters: []FilterConfig{{Type: "hashSample"}}})
+			return err
+		}, "needs a rate"},
+		{"csv rate out of range", func() error {
+			_, err := loader.ProcessCsvFile(path, ProcessCsvOptions{Filters: []FilterConfig{{Type: "hashSample", Rate: &tooHigh}}})
+			return err
+		}, "between 0 and 1"},
+		{"condition without rate", func() error {
+			_, err := compileCondition(IteratorCondition{Field: "id", Op: "hashSample"})
+			return err
+		}, "numeric rate"},
+		{"condition rate out of range", func() error {
+			_, err := compileCondition(IteratorCondition{Field: "id", Op: "hashSample", Value: -0.1})
+			return err
+		}, "between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package streamloader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestHashObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      interface{}
+		algo     string
+		encoding []string
+		want     string
+	}{
+		{"string sha256", "abc", "sha256", nil, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"string md5", "abc", "md5", nil, "900150983cd24fb0d6963f7d28e17f72"},
+		{"string sha1 base64", "abc", "sha1", []string{"base64"}, "qZk+NkcGgWq6PiVxeFDCbJzQ2J0="},
+		{"bytes", []byte("abc"), "sha256", []string{"hex"}, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		// sha256(`{"a":1,"b":"<x>"}`): keys are sorted and HTML is not escaped
+		{"object", map[string]interface{}{"b": "<x>", "a": 1}, "sha256", nil, sha256Hex(`{"a":1,"b":"<x>"}`)},
+		{"array base64url", []interface{}{1, "two"}, "sha256", []string{"base64url"}, "K2LibM2HiHR68Yt_0lP_KY2xtRc76XOnP6InIZBUaNU"},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loader.HashObject(tt.obj, tt.algo, tt.encoding...)
+			if err != nil {
+				t.Fatalf("HashObject() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HashObject() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHmacFile(t *testing.T) {
+	content := strings.Repeat(`{"event":"order.created"}`, 10000)
+	path := filepath.Join(t.TempDir(), "webhook.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(content))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	got, err := StreamLoader{}.HmacFile(path, "sha256", "secret")
+	if err != nil {
+		t.Fatalf("HmacFile() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("HmacFile() = %s, want %s", got, want)
+	}
+	if pooledFiles() != 0 {
+		t.Error("HmacFile() left the file open")
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name    string
+		call    func() (string, error)
+		wantErr string
+	}{
+		{"unknown algorithm", func() (string, error) { return loader.HashObject("x", "crc32") }, "unknown hash algorithm"},
+		{"unknown encoding", func() (string, error) { return loader.HashObject("x", "sha256", "base32") }, "unknown digest encoding"},
+		{"unencodable object", func() (string, error) { return loader.HashObject(map[string]interface{}{"f": func() {}}, "sha256") }, "failed to encode object"},
+		{"hmac unknown algorithm", func() (string, error) { return loader.HmacFile(path, "sha3", "k") }, "unknown hash algorithm"},
+		{"hmac unknown encoding", func() (string, error) { return loader.HmacFile(path, "sha256", "k", "binary") }, "unknown digest encoding"},
+		{"hmac missing file", func() (string, error) { return loader.HmacFile(path+".missing", "sha256", "k") }, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.call()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashObjectJavaScript(t *testing.T) {
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		const buffer = new Uint8Array([97, 98, 99]).buffer;
+		[
+			streamloader.hashObject(buffer, "sha256"),
+			streamloader.hashObject({ b: 2, a: 1 }, "sha256") === streamloader.hashObject({ a: 1, b: 2 }, "sha256"),
+		].join(",");
+	`)
+	if err != nil {
+		t.Fatalf("script error = %v", err)
+	}
+	if got, want := value.String(), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad,true"; got != want {
+		t.Errorf("script result = %s, want %s", got, want)
+	}
+}
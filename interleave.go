@@ -0,0 +1,150 @@
+// interleave.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+)
+
+// InterleaveSource represents one dataset for InterleaveFiles
+type InterleaveSource struct {
+	Path  string  `json:"path" js:"path"`
+	Ratio float64 `json:"ratio" js:"ratio"`
+}
+
+// InterleaveOptions represents options for InterleaveFiles
+type InterleaveOptions struct {
+	ExhaustAll bool   `json:"exhaustAll" js:"exhaustAll"`
+	Limit      int    `json:"limit" js:"limit"`
+	BufferSize int    `json:"bufferSize" js:"bufferSize"`
+	Provenance string `json:"provenance" js:"provenance"`
+}
+
+// InterleaveFiles merges records from several JSON array or NDJSON files into a single JSON
+// array file, picking records from each source according to its ratio. Sources are read one
+// record at a time, so the inputs are never loaded into memory.
+//
+// Records are picked with smooth weighted round-robin, which spreads each source evenly over
+// the output: ratios 80 and 20 produce four records of the first source for every record of
+// the second, interleaved rather than in blocks. The order is deterministic.
+//
+// Options:
+//   - exhaustAll: Keep going with the remaining sources once one runs out (default: false,
+//     stop at the first exhausted source so the ratios hold for the whole output)
+//   - limit: Maximum number of records to write (default: 0, no limit)
+//   - bufferSize: Output buffer size in bytes (default: 64KB)
+//   - provenance: Name of a field added to every record, holding {"file": path, "record": n}
+//     with the source file and the zero-based position of the record in it (default: none)
+//
+// Returns: The number of records written to the output file
+//
+// Example usage:
+//
+//	count, err := streamloader.InterleaveFiles([]InterleaveSource{
+//		{Path: "reads.json", Ratio: 80},
+//		{Path: "writes.json", Ratio: 20},
+//	}, "mixed.json")
+func (s StreamLoader) InterleaveFiles(sources []InterleaveSource, outputFilePath string, options ...InterleaveOptions) (int, error) {
+	var opts InterleaveOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	bufSize := opts.BufferSize
+	if bufSize <= 0 {
+		bufSize = writeBufferSize()
+	}
+
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("at least one source is required")
+	}
+
+	// Open every source with a positive ratio
+	readers := make([]*jsonRecordReader, len(sources))
+	defer func() {
+		for _, r := range readers {
+			if r != nil {
+				r.Close()
+			}
+		}
+	}()
+
+	total := 0.0
+	for i, source := range sources {
+		if source.Ratio < 0 {
+			return 0, fmt.Errorf("invalid ratio %v for %s: must not be negative", source.Ratio, source.Path)
+		}
+		if source.Ratio == 0 {
+			continue
+		}
+		r, err := openJsonRecords(source.Path, s.call)
+		if err != nil {
+			return 0, err
+		}
+		readers[i] = r
+		total += source.Ratio
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("at least one source must have a positive ratio")
+	}
+
+	out, err := createJsonArrayFile(outputFilePath, bufSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Smooth weighted round-robin selection
+	current := make([]float64, len(sources))
+	for opts.Limit <= 0 || out.count < opts.Limit {
+		pick := -1
+		for i, r := range readers {
+			if r == nil {
+				continue
+			}
+			current[i] += sources[i].Ratio
+			if pick < 0 || current[i] > current[pick] {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			break // All sources exhausted
+		}
+		current[pick] -= total
+
+		record, err := readers[pick].Next()
+		if err == io.EOF {
+			if !opts.ExhaustAll {
+				break
+			}
+			// Drop the exhausted source and rebalance the remaining ones
+			readers[pick].Close()
+			readers[pick] = nil
+			total -= sources[pick].Ratio
+			for i := range current {
+				current[i] = 0
+			}
+			continue
+		}
+		if err != nil {
+			out.Close()
+			return out.count, err
+		}
+
+		if opts.Provenance != "" {
+			// Next has already advanced past the record
+			if record, err = addProvenance(record, opts.Provenance, sources[pick].Path, readers[pick].index-1); err != nil {
+				out.Close()
+				return out.count, err
+			}
+		}
+
+		if err := out.Write(record); err != nil {
+			out.Close()
+			return out.count, err
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return out.count, err
+	}
+	return out.count, nil
+}
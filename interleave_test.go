@@ -0,0 +1,183 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeInterleaveSource writes count records tagged with the given source name
+func writeInterleaveSource(t *testing.T, path string, source string, count int, ndjson bool) {
+	t.Helper()
+	var content []byte
+	if !ndjson {
+		content = append(content, '[')
+	}
+	for i := 0; i < count; i++ {
+		if i > 0 && !ndjson {
+			content = append(content, ',')
+		}
+		record, _ := json.Marshal(map[string]interface{}{"source": source, "n": i})
+		content = append(content, record...)
+		if ndjson {
+			content = append(content, '\n')
+		}
+	}
+	if !ndjson {
+		content = append(content, ']')
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// readInterleaveSources returns the source tag of every record in the output file
+func readInterleaveSources(t *testing.T, path string) []string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(content, &records); err != nil {
+		t.Fatalf("Output is not a valid JSON array: %v", err)
+	}
+	sources := make([]string, len(records))
+	for i, r := range records {
+		sources[i] = r["source"].(string)
+	}
+	return sources
+}
+
+func TestInterleaveFiles_Ratios(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	readsPath := filepath.Join(tempDir, "reads.json")
+	writesPath := filepath.Join(tempDir, "writes.ndjson")
+	writeInterleaveSource(t, readsPath, "r", 100, false)
+	writeInterleaveSource(t, writesPath, "w", 100, true)
+
+	outputPath := filepath.Join(tempDir, "mixed.json")
+	count, err := loader.InterleaveFiles([]InterleaveSource{
+		{Path: readsPath, Ratio: 80},
+		{Path: writesPath, Ratio: 20},
+	}, outputPath)
+	if err != nil {
+		t.Fatalf("InterleaveFiles() error = %v", err)
+	}
+
+	sources := readInterleaveSources(t, outputPath)
+	if count != len(sources) {
+		t.Errorf("Returned count %d does not match output length %d", count, len(sources))
+	}
+
+	// Reads run out first after 100 records, with 25 writes interleaved
+	reads, writes := 0, 0
+	for _, s := range sources {
+		if s == "r" {
+			reads++
+		} else {
+			writes++
+		}
+	}
+	if reads != 100 || writes != 25 {
+		t.Errorf("Expected 100 reads and 25 writes, got %d and %d", reads, writes)
+	}
+
+	// Every window of five records contains exactly one write
+	for i := 0; i+5 <= 100; i += 5 {
+		w := 0
+		for _, s := range sources[i : i+5] {
+			if s == "w" {
+				w++
+			}
+		}
+		if w != 1 {
+			t.Fatalf("Window at %d has %d writes: %v", i, w, sources[i:i+5])
+		}
+	}
+}
+
+func TestInterleaveFiles_Options(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	aPath := filepath.Join(tempDir, "a.json")
+	bPath := filepath.Join(tempDir, "b.json")
+	writeInterleaveSource(t, aPath, "a", 2, false)
+	writeInterleaveSource(t, bPath, "b", 5, false)
+	sources := []InterleaveSource{{Path: aPath, Ratio: 1}, {Path: bPath, Ratio: 1}}
+	outputPath := filepath.Join(tempDir, "out.json")
+
+	t.Run("Stop at first exhausted source", func(t *testing.T) {
+		count, err := loader.InterleaveFiles(sources, outputPath)
+		if err != nil {
+			t.Fatalf("InterleaveFiles() error = %v", err)
+		}
+		if count != 4 {
+			t.Errorf("Expected 4 records, got %d: %v", count, readInterleaveSources(t, outputPath))
+		}
+	})
+
+	t.Run("Exhaust all sources", func(t *testing.T) {
+		count, err := loader.InterleaveFiles(sources, outputPath, InterleaveOptions{ExhaustAll: true})
+		if err != nil {
+			t.Fatalf("InterleaveFiles() error = %v", err)
+		}
+		if count != 7 {
+			t.Errorf("Expected 7 records, got %d", count)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		count, err := loader.InterleaveFiles(sources, outputPath, InterleaveOptions{ExhaustAll: true, Limit: 3})
+		if err != nil {
+			t.Fatalf("InterleaveFiles() error = %v", err)
+		}
+		if got := readInterleaveSources(t, outputPath); count != 3 || len(got) != 3 {
+			t.Errorf("Expected 3 records, got %d: %v", count, got)
+		}
+	})
+
+	t.Run("Zero ratio source is skipped", func(t *testing.T) {
+		count, err := loader.InterleaveFiles([]InterleaveSource{{Path: aPath, Ratio: 0}, {Path: bPath, Ratio: 1}}, outputPath)
+		if err != nil {
+			t.Fatalf("InterleaveFiles() error = %v", err)
+		}
+		if count != 5 {
+			t.Errorf("Expected 5 records, got %d", count)
+		}
+	})
+}
+
+func TestInterleaveFiles_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	outputPath := filepath.Join(tempDir, "out.json")
+	aPath := filepath.Join(tempDir, "a.json")
+	writeInterleaveSource(t, aPath, "a", 2, false)
+
+	badPath := filepath.Join(tempDir, "bad.json")
+	os.WriteFile(badPath, []byte(`[{"a":1},{"a":`), 0644)
+
+	tests := []struct {
+		name    string
+		sources []InterleaveSource
+	}{
+		{"No sources", nil},
+		{"Missing file", []InterleaveSource{{Path: "no_such_file.json", Ratio: 1}}},
+		{"Negative ratio", []InterleaveSource{{Path: aPath, Ratio: -1}}},
+		{"All zero ratios", []InterleaveSource{{Path: aPath, Ratio: 0}}},
+		{"Malformed source", []InterleaveSource{{Path: badPath, Ratio: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loader.InterleaveFiles(tt.sources, outputPath, InterleaveOptions{ExhaustAll: true}); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
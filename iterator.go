@@ -0,0 +1,614 @@
+// iterator.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/sobek"
+)
+
+// IteratorCondition is a config-based filter condition for Iterator.Filter, evaluated in Go
+type IteratorCondition struct {
+	Field string      `json:"field" js:"field"`
+	Op    string      `json:"op" js:"op"`
+	Value interface{} `json:"value" js:"value"`
+	Salt  string      `json:"salt" js:"salt"`
+}
+
+// IteratorMapping is a config-based mapping for Iterator.Map, evaluated in Go
+type IteratorMapping struct {
+	Field string                 `json:"field" js:"field"`
+	Pick  []string               `json:"pick" js:"pick"`
+	Omit  []string               `json:"omit" js:"omit"`
+	Set   map[string]interface{} `json:"set" js:"set"`
+}
+
+// Iterator is a chainable stream of values over a source such as a sequence or an array. Filter,
+// Map, Take and Skip return new iterators that pull from this one lazily, so nothing is computed
+// until values are read. Config-based conditions and mappings run entirely in Go; JavaScript
+// callbacks are only called for the values that reach them, so put cheap config filters first.
+// An iterator created during an iteration is closed when the iteration ends.
+type Iterator struct {
+	rt      *sobek.Runtime // For calling JavaScript callbacks, nil outside a VU
+	pull    func() (interface{}, bool, error)
+	release func() // Releases the source, nil if the iterator doesn't own it
+	shared  bool   // Values are shared dataset records, handed to scripts as read-only views
+
+	mu       sync.Mutex
+	peeked   bool
+	peekVal  interface{}
+	closed   bool
+	children []*Iterator
+}
+
+// Iterate returns an iterator over a sequence, the new files of a directory watcher, an array,
+// or the records of a JSON array or NDJSON file. Iterating a sequence advances its cursor;
+// closing the iterator doesn't close the source, except for a file, which is closed with it.
+//
+// A file is read as values are pulled, so it may be a named pipe (FIFO) an external generator
+// writes records into while the test runs: next and hasNext wait for the next record, and the
+// iterator ends when the writer closes the pipe.
+//
+// Example usage:
+//
+//	const ids = streamloader.iterate(streamloader.generateRange(0, 1000000))
+//		.filter({ op: "gte", value: 1000 })
+//		.map((id) => `user-${id}`)
+//		.take(10);
+//	while (ids.hasNext()) { http.get(`${base}/${ids.next()}`); }
+//
+//	// mkfifo requests.pipe && ./generate-requests > requests.pipe &
+//	const requests = streamloader.iterate("requests.pipe");
+func (s StreamLoader) Iterate(source interface{}) (*Iterator, error) {
+	var pull func() (interface{}, bool, error)
+	shared := false
+	switch src := source.(type) {
+	case string:
+		records, err := openJsonRecords(src, s.call.untimed())
+		if err != nil {
+			return nil, err
+		}
+		pull = func() (interface{}, bool, error) {
+			raw, err := records.Next()
+			if err == io.EOF {
+				return nil, false, nil
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return nil, false, fmt.Errorf("failed to decode record in %s: %w", src, err)
+			}
+			return value, true, nil
+		}
+		return s.newIterator(pull, func() { records.Close() }), nil
+	case *Sequence:
+		pull = func() (interface{}, bool, error) {
+			src.mu.Lock()
+			defer src.mu.Unlock()
+			if src.closed || src.pos >= src.length {
+				return nil, false, nil
+			}
+			value := src.at(src.offset + src.pos)
+			src.pos++
+			return value, true, nil
+		}
+	case *DirectoryWatcher:
+		pull = func() (interface{}, bool, error) {
+			path, err := src.Next()
+			return path, err == nil && path != "", err
+		}
+	case *Iterator:
+		return src, nil
+	case []interface{}:
+		shared = isDatasetData(src)
+		i := 0
+		pull = func() (interface{}, bool, error) {
+			if i >= len(src) {
+				return nil, false, nil
+			}
+			i++
+			return src[i-1], true, nil
+		}
+	default:
+		return nil, fmt.Errorf("cannot iterate %T: expected a file path, sequence, directory watcher, iterator or array", source)
+	}
+	it := s.newIterator(pull, nil)
+	it.shared = shared
+	return it, nil
+}
+
+// newIterator returns an iterator over pull, closed at the end of the current iteration.
+func (s StreamLoader) newIterator(pull func() (interface{}, bool, error), release func()) *Iterator {
+	it := &Iterator{pull: pull, release: release}
+	if s.vu != nil {
+		it.rt = s.vu.Runtime()
+	}
+	s.closeAtIterationEnd(it)
+	return it
+}
+
+// derive returns an iterator that pulls from it through pull.
+func (it *Iterator) derive(pull func() (interface{}, bool, error)) *Iterator {
+	child := &Iterator{rt: it.rt, pull: pull, shared: it.shared}
+	it.mu.Lock()
+	it.children = append(it.children, child)
+	it.mu.Unlock()
+	child.release = it.Close
+	return child
+}
+
+// next returns the next value and whether there was one.
+func (it *Iterator) next() (interface{}, bool, error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.closed {
+		return nil, false, nil
+	}
+	if it.peeked {
+		it.peeked = false
+		value := it.peekVal
+		it.peekVal = nil
+		return value, true, nil
+	}
+	return it.pull()
+}
+
+// HasNext reports whether another value is available.
+func (it *Iterator) HasNext() (bool, error) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.closed {
+		return false, nil
+	}
+	if !it.peeked {
+		value, ok, err := it.pull()
+		if err != nil || !ok {
+			return false, err
+		}
+		it.peeked, it.peekVal = true, value
+	}
+	return true, nil
+}
+
+// Next returns the next value, or nil once the iterator is exhausted.
+func (it *Iterator) Next() (interface{}, error) {
+	value, _, err := it.next()
+	return it.view(value), err
+}
+
+// ToArray reads the remaining values into an array.
+func (it *Iterator) ToArray() ([]interface{}, error) {
+	values := make([]interface{}, 0)
+	for {
+		value, ok, err := it.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return values, nil
+		}
+		values = append(values, it.view(value))
+	}
+}
+
+// Close releases the iterator and the iterators derived from it, and the source if the
+// iterator owns it.
+func (it *Iterator) Close() {
+	it.mu.Lock()
+	if it.closed {
+		it.mu.Unlock()
+		return
+	}
+	it.closed = true
+	it.peekVal = nil
+	children, release := it.children, it.release
+	it.children = nil
+	it.mu.Unlock()
+
+	for _, child := range children {
+		child.Close()
+	}
+	if release != nil {
+		release()
+	}
+}
+
+// Dispose is an alias of Close.
+func (it *Iterator) Dispose() {
+	it.Close()
+}
+
+// Take returns an iterator over at most the next n values.
+func (it *Iterator) Take(n int) (*Iterator, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("take count must not be negative, got %d", n)
+	}
+	taken := 0
+	return it.derive(func() (interface{}, bool, error) {
+		if taken >= n {
+			return nil, false, nil
+		}
+		value, ok, err := it.next()
+		if ok {
+			taken++
+		}
+		return value, ok, err
+	}), nil
+}
+
+// Skip returns an iterator that drops the next n values.
+func (it *Iterator) Skip(n int) (*Iterator, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("skip count must not be negative, got %d", n)
+	}
+	remaining := n
+	return it.derive(func() (interface{}, bool, error) {
+		for ; remaining > 0; remaining-- {
+			if _, ok, err := it.next(); err != nil || !ok {
+				return nil, false, err
+			}
+		}
+		return it.next()
+	}), nil
+}
+
+// Filter returns an iterator over the values matching predicate, which is a JavaScript
+// function returning a truthy value, or a condition object or array of condition objects that
+// must all hold:
+//   - field: Dotted path of the value to test, e.g. "user.age" or "items.0" (default: the value itself)
+//   - op: "eq", "ne", "lt", "lte", "gt", "gte", "in" (value is an array), "regex" (value is a
+//     pattern), "exists", "missing", "hashSample" (value is a rate between 0 and 1; keeps the
+//     same keys as the hashSample filter of ProcessCsvFile with the same rate and salt), or
+//     "expired" and "notExpired" (field is a timestamp; value is "now", the default, or
+//     "testStart"; values without the field never expire and unreadable ones match neither)
+//   - value: The value to compare with
+//   - salt: Mixed into the hash of hashSample, to draw a different sample (default: none)
+func (it *Iterator) Filter(predicate interface{}) (*Iterator, error) {
+	match, err := it.compilePredicate(predicate)
+	if err != nil {
+		return nil, err
+	}
+	return it.derive(func() (interface{}, bool, error) {
+		for {
+			value, ok, err := it.next()
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			if keep, err := match(value); err != nil {
+				return nil, false, err
+			} else if keep {
+				return value, true, nil
+			}
+		}
+	}), nil
+}
+
+// Map returns an iterator over the values transformed by mapping, which is a JavaScript
+// function, or a mapping object:
+//   - field: Replace each value with the value at this dotted path
+//   - pick: Keep only these keys of each object
+//   - omit: Drop these keys from each object
+//   - set: Add or overwrite these keys of each object
+func (it *Iterator) Map(mapping interface{}) (*Iterator, error) {
+	transform, err := it.compileMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	return it.derive(func() (interface{}, bool, error) {
+		value, ok, err := it.next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		value, err = transform(value)
+		return value, err == nil, err
+	}), nil
+}
+
+// view returns a value as scripts should see it: a read-only view of shared dataset records.
+func (it *Iterator) view(value interface{}) interface{} {
+	if !it.shared || it.rt == nil {
+		return value
+	}
+	return readOnlyValue(it.rt, value)
+}
+
+// callJS calls a JavaScript callback with a value.
+func (it *Iterator) callJS(fn func(sobek.FunctionCall) sobek.Value, value interface{}) (sobek.Value, error) {
+	if it.rt == nil {
+		return nil, fmt.Errorf("JavaScript callbacks need a VU runtime")
+	}
+	return fn(sobek.FunctionCall{This: sobek.Undefined(), Arguments: []sobek.Value{it.rt.ToValue(it.view(value))}}), nil
+}
+
+// compilePredicate turns a filter argument into a Go predicate.
+func (it *Iterator) compilePredicate(predicate interface{}) (func(interface{}) (bool, error), error) {
+	switch p := predicate.(type) {
+	case func(sobek.FunctionCall) sobek.Value:
+		return func(value interface{}) (bool, error) {
+			result, err := it.callJS(p, value)
+			if err != nil {
+				return false, err
+			}
+			return result.ToBoolean(), nil
+		}, nil
+	case func(interface{}) bool:
+		return func(value interface{}) (bool, error) { return p(value), nil }, nil
+	}
+
+	var conditions []IteratorCondition
+	switch p := predicate.(type) {
+	case IteratorCondition:
+		conditions = []IteratorCondition{p}
+	case []IteratorCondition:
+		conditions = p
+	case map[string]interface{}:
+		conditions = make([]IteratorCondition, 1)
+		if err := convertConfig(p, &conditions[0]); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		if err := convertConfig(p, &conditions); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid filter: expected a function or condition object, got %T", predicate)
+	}
+
+	checks := make([]func(interface{}) bool, len(conditions))
+	for i, c := range conditions {
+		check, err := compileCondition(c)
+		if err != nil {
+			return nil, err
+		}
+		checks[i] = check
+	}
+	return func(value interface{}) (bool, error) {
+		for _, check := range checks {
+			if !check(value) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, nil
+}
+
+// compileCondition turns a condition into a check.
+func compileCondition(c IteratorCondition) (func(interface{}) bool, error) {
+	var test func(v interface{}) bool
+	switch c.Op {
+	case "eq":
+		test = func(v interface{}) bool { return valuesEqual(v, c.Value) }
+	case "ne":
+		test = func(v interface{}) bool { return !valuesEqual(v, c.Value) }
+	case "lt", "lte", "gt", "gte":
+		op := c.Op
+		test = func(v interface{}) bool {
+			cmp, ok := compareValues(v, c.Value)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "lt":
+				return cmp < 0
+			case "lte":
+				return cmp <= 0
+			case "gt":
+				return cmp > 0
+			}
+			return cmp >= 0
+		}
+	case "in":
+		options, ok := c.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("condition op \"in\" needs an array value")
+		}
+		test = func(v interface{}) bool {
+			for _, option := range options {
+				if valuesEqual(v, option) {
+					return true
+				}
+			}
+			return false
+		}
+	case "regex":
+		pattern, _ := c.Value.(string)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition pattern %q: %w", pattern, err)
+		}
+		test = func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		}
+	case "hashSample":
+		rate, ok := numberValue(c.Value)
+		if !ok {
+			return nil, fmt.Errorf("condition op \"hashSample\" needs a numeric rate value")
+		}
+		if err := validateHashSampleRate(rate); err != nil {
+			return nil, err
+		}
+		test = func(v interface{}) bool { return hashSampled(keyString(v), rate, c.Salt) }
+	case "expired", "notExpired":
+		reference, _ := c.Value.(string)
+		now, err := expiryReference(reference)
+		if err != nil {
+			return nil, err
+		}
+		check := &expiryCheck{field: c.Field, now: now}
+		wantExpired := c.Op == "expired"
+		return func(value interface{}) bool {
+			expired, err := check.expired(value)
+			return err == nil && expired == wantExpired
+		}, nil
+	case "exists", "missing":
+		exists := c.Op == "exists"
+		return func(value interface{}) bool {
+			_, found := lookupField(value, c.Field)
+			return found == exists
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid condition op %q", c.Op)
+	}
+	return func(value interface{}) bool {
+		v, found := lookupField(value, c.Field)
+		return found && test(v)
+	}, nil
+}
+
+// compileMapping turns a map argument into a Go transform.
+func (it *Iterator) compileMapping(mapping interface{}) (func(interface{}) (interface{}, error), error) {
+	var m IteratorMapping
+	switch p := mapping.(type) {
+	case func(sobek.FunctionCall) sobek.Value:
+		return func(value interface{}) (interface{}, error) {
+			result, err := it.callJS(p, value)
+			if err != nil {
+				return nil, err
+			}
+			return unwrapShared(result.Export()), nil
+		}, nil
+	case func(interface{}) interface{}:
+		return func(value interface{}) (interface{}, error) { return p(value), nil }, nil
+	case IteratorMapping:
+		m = p
+	case map[string]interface{}:
+		if err := convertConfig(p, &m); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid map: expected a function or mapping object, got %T", mapping)
+	}
+
+	if m.Field != "" {
+		if len(m.Pick) > 0 || len(m.Omit) > 0 || len(m.Set) > 0 {
+			return nil, fmt.Errorf("invalid map: field can't be combined with pick, omit or set")
+		}
+		return func(value interface{}) (interface{}, error) {
+			v, _ := lookupField(value, m.Field)
+			return v, nil
+		}, nil
+	}
+	return func(value interface{}) (interface{}, error) {
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot pick, omit or set keys of %T", value)
+		}
+		result := make(map[string]interface{}, len(record))
+		if len(m.Pick) > 0 {
+			for _, key := range m.Pick {
+				if v, ok := record[key]; ok {
+					result[key] = v
+				}
+			}
+		} else {
+			for k, v := range record {
+				result[k] = v
+			}
+		}
+		for _, key := range m.Omit {
+			delete(result, key)
+		}
+		for k, v := range m.Set {
+			result[k] = v
+		}
+		return result, nil
+	}, nil
+}
+
+// convertConfig converts a config object passed from JavaScript into a config struct.
+func convertConfig(config interface{}, target interface{}) error {
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := json.Unmarshal(encoded, target); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// lookupField returns the value at a dotted path of object keys and array indexes, or the value
+// itself for an empty path.
+func lookupField(value interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return value, true
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// numberValue returns a numeric value as a float64.
+func numberValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// valuesEqual compares values, treating numbers of different types as equal if their values are.
+func valuesEqual(a interface{}, b interface{}) bool {
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders two numbers or two strings.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, ok := a.(string)
+	y, ok2 := b.(string)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
@@ -0,0 +1,201 @@
+package streamloader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func records() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"id": float64(1), "name": "ann", "tags": []interface{}{"a"}, "user": map[string]interface{}{"age": float64(31)}},
+		map[string]interface{}{"id": float64(2), "name": "bob", "user": map[string]interface{}{"age": float64(17)}},
+		map[string]interface{}{"id": float64(3), "name": "cy", "tags": []interface{}{"b"}, "user": map[string]interface{}{"age": float64(45)}},
+		map[string]interface{}{"id": float64(4), "name": "dee", "user": map[string]interface{}{"age": float64(28)}},
+	}
+}
+
+func ids(t *testing.T, it *Iterator) []interface{} {
+	t.Helper()
+	values, err := it.ToArray()
+	if err != nil {
+		t.Fatalf("ToArray() error = %v", err)
+	}
+	ids := make([]interface{}, len(values))
+	for i, v := range values {
+		ids[i] = v.(map[string]interface{})["id"]
+	}
+	return ids
+}
+
+func TestIteratorFilter(t *testing.T) {
+	loader := StreamLoader{}
+	tests := []struct {
+		name      string
+		predicate interface{}
+		want      []interface{}
+	}{
+		{"eq", map[string]interface{}{"field": "name", "op": "eq", "value": "bob"}, []interface{}{float64(2)}},
+		{"ne", map[string]interface{}{"field": "name", "op": "ne", "value": "bob"}, []interface{}{float64(1), float64(3), float64(4)}},
+		{"gte on nested field with int value", map[string]interface{}{"field": "user.age", "op": "gte", "value": int64(31)}, []interface{}{float64(1), float64(3)}},
+		{"lt on strings", map[string]interface{}{"field": "name", "op": "lt", "value": "c"}, []interface{}{float64(1), float64(2)}},
+		{"in", map[string]interface{}{"field": "id", "op": "in", "value": []interface{}{int64(2), int64(4)}}, []interface{}{float64(2), float64(4)}},
+		{"regex", map[string]interface{}{"field": "name", "op": "regex", "value": "^.e"}, []interface{}{float64(4)}},
+		{"exists", map[string]interface{}{"field": "tags.0", "op": "exists"}, []interface{}{float64(1), float64(3)}},
+		{"missing", map[string]interface{}{"field": "tags", "op": "missing"}, []interface{}{float64(2), float64(4)}},
+		{"all conditions", []interface{}{
+			map[string]interface{}{"field": "user.age", "op": "gt", "value": 20},
+			map[string]interface{}{"field": "tags", "op": "missing"},
+		}, []interface{}{float64(4)}},
+		{"go function", func(v interface{}) bool { return v.(map[string]interface{})["id"].(float64) > 3 }, []interface{}{float64(4)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := loader.Iterate(records())
+			if err != nil {
+				t.Fatal(err)
+			}
+			filtered, err := it.Filter(tt.predicate)
+			if err != nil {
+				t.Fatalf("Filter() error = %v", err)
+			}
+			if got := ids(t, filtered); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	it, _ := loader.Iterate(records())
+	for _, bad := range []interface{}{
+		map[string]interface{}{"op": "like"},
+		map[string]interface{}{"op": "in", "value": 1},
+		map[string]interface{}{"op": "regex", "value": "("},
+		42,
+	} {
+		if _, err := it.Filter(bad); err == nil {
+			t.Errorf("Filter(%v) expected an error", bad)
+		}
+	}
+}
+
+func TestIteratorMap(t *testing.T) {
+	loader := StreamLoader{}
+
+	it, _ := loader.Iterate(records())
+	ages, err := it.Map(map[string]interface{}{"field": "user.age"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ages.ToArray(); !reflect.DeepEqual(got, []interface{}{float64(31), float64(17), float64(45), float64(28)}) {
+		t.Errorf("Map(field) = %v", got)
+	}
+
+	it, _ = loader.Iterate(records())
+	picked, _ := it.Map(IteratorMapping{Pick: []string{"id", "name"}, Omit: []string{"name"}, Set: map[string]interface{}{"env": "test"}})
+	first, _ := picked.Next()
+	if want := map[string]interface{}{"id": float64(1), "env": "test"}; !reflect.DeepEqual(first, want) {
+		t.Errorf("Map(pick, omit, set) = %v, want %v", first, want)
+	}
+
+	it, _ = loader.Iterate([]interface{}{1, "x"})
+	if _, err := it.Map(map[string]interface{}{"field": "a", "pick": []interface{}{"b"}}); err == nil {
+		t.Error("expected an error combining field and pick")
+	}
+	bad, _ := it.Map(map[string]interface{}{"omit": []interface{}{"a"}})
+	if _, err := bad.Next(); err == nil {
+		t.Error("expected an error omitting keys of a number")
+	}
+}
+
+func TestIteratorTakeSkip(t *testing.T) {
+	loader := StreamLoader{}
+	seq, _ := loader.GenerateRange(0, 100)
+	it, _ := loader.Iterate(seq)
+
+	evens, _ := it.Filter(func(v interface{}) bool { return v.(int64)%2 == 0 })
+	skipped, _ := evens.Skip(3)
+	taken, _ := skipped.Take(4)
+	got, err := taken.ToArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{int64(6), int64(8), int64(10), int64(12)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filter/skip/take = %v, want %v", got, want)
+	}
+	if has, _ := taken.HasNext(); has {
+		t.Error("HasNext() after take is exhausted")
+	}
+	// The sequence cursor only advanced as far as needed
+	if next := seq.Next(); next != int64(13) {
+		t.Errorf("sequence cursor at %v, want 13", next)
+	}
+
+	if _, err := it.Take(-1); err == nil {
+		t.Error("expected an error for a negative take")
+	}
+	if _, err := it.Skip(-1); err == nil {
+		t.Error("expected an error for a negative skip")
+	}
+	if _, err := loader.Iterate("not iterable"); err == nil {
+		t.Error("expected an error for a string source")
+	}
+}
+
+func TestIteratorHasNextAndClose(t *testing.T) {
+	loader := StreamLoader{}
+	it, _ := loader.Iterate([]interface{}{"a", "b"})
+	mapped, _ := it.Map(func(v interface{}) interface{} { return v.(string) + "!" })
+
+	if has, _ := mapped.HasNext(); !has {
+		t.Fatal("HasNext() = false, want true")
+	}
+	if has, _ := mapped.HasNext(); !has {
+		t.Fatal("HasNext() twice should not consume a value")
+	}
+	if v, _ := mapped.Next(); v != "a!" {
+		t.Errorf("Next() = %v, want a!", v)
+	}
+
+	// Closing a derived iterator closes the chain
+	mapped.Dispose()
+	if has, _ := it.HasNext(); has {
+		t.Error("source iterator still has values after closing the chain")
+	}
+	if v, _ := mapped.Next(); v != nil {
+		t.Errorf("Next() after Close = %v, want nil", v)
+	}
+}
+
+func TestIteratorJavaScriptCallbacks(t *testing.T) {
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		let calls = 0;
+		const it = streamloader.iterate([{n: 1}, {n: 2}, {n: 3}, {n: 4}, {n: 5}])
+			.filter({ field: "n", op: "gt", value: 2 })
+			.map((r) => { calls++; return r.n * 10; })
+			.take(2);
+		const out = [];
+		while (it.hasNext()) out.push(it.next());
+		JSON.stringify({ out, calls });
+	`)
+	if err != nil {
+		t.Fatalf("script error = %v", err)
+	}
+	// Only the records that passed the config filter reached the callback
+	if got := value.String(); got != `{"out":[30,40],"calls":2}` {
+		t.Errorf("script result = %s", got)
+	}
+
+	if _, err := rt.RunString(`streamloader.iterate([1]).filter(() => { throw new Error("boom"); }).next()`); err == nil {
+		t.Error("expected the callback's exception to propagate")
+	}
+}
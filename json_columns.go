@@ -0,0 +1,82 @@
+// json_columns.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// LoadJSONColumns is LoadCSVColumns for a JSON array or NDJSON file of objects: each schema
+// entry names a field, or a dotted path into nested objects, and its values across records are
+// stored in one column under that name. The column option of the schema does not apply.
+//
+// Values are converted as CSV cells would be: numbers, booleans and strings holding them are
+// parsed for the numeric and boolean types, and missing or null fields count as empty cells, so
+// they become NaN in "number" and "float64" columns and are an error in the integer and boolean
+// columns. Use "float64" and "int32" for columns a script only does arithmetic over, such as a
+// pacing table, to get a Float64Array or Int32Array rather than one object per record.
+//
+// Example usage:
+//
+//	const pacing = streamloader.loadJSONColumns("pacing.json", [
+//		{ name: "offsetMs", type: "int32" },
+//		{ name: "rate.rps", type: "float64" },
+//	]);
+//	// pacing.offsetMs is an Int32Array and pacing["rate.rps"] a Float64Array
+func (s StreamLoader) LoadJSONColumns(filePath string, schema []CsvColumnSchema) (map[string]interface{}, error) {
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("schema must contain at least one column")
+	}
+	vectors := make([]interface{}, len(schema))
+	for i, col := range schema {
+		if col.Name == "" {
+			return nil, fmt.Errorf("schema entry %d has no name", i)
+		}
+		if col.Column != nil {
+			return nil, fmt.Errorf("column index is not supported for JSON field %q", col.Name)
+		}
+		var err error
+		if vectors[i], err = newColumnVector(col); err != nil {
+			return nil, err
+		}
+	}
+
+	index := 0
+	err := forEachJsonRecord(filePath, s.call, func(raw json.RawMessage) (bool, error) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var record interface{}
+		if err := dec.Decode(&record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", index, err)
+		}
+		for i, col := range schema {
+			cell := ""
+			if value, ok := lookupField(record, col.Name); ok {
+				text, err := jsonCellString(value)
+				if err != nil {
+					return false, fmt.Errorf("invalid value of field %q in record %d: %w", col.Name, index, err)
+				}
+				cell = text
+			}
+			var err error
+			if vectors[i], err = appendColumnCell(vectors[i], cell, col.Name, fmt.Sprintf("record %d", index)); err != nil {
+				return false, err
+			}
+		}
+		index++
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.columnResult(schema, vectors), nil
+}
+
+// jsonCellString returns a scalar JSON value as the text of a CSV cell.
+func jsonCellString(value interface{}) (string, error) {
+	if n, ok := value.(json.Number); ok {
+		return n.String(), nil
+	}
+	return csvCellString(value)
+}
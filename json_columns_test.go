@@ -0,0 +1,68 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadJSONColumns(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	array := filepath.Join(dir, "pacing.json")
+	os.WriteFile(array, []byte(`[
+		{"offset": 0, "rate": {"rps": 1.5}, "name": "warm", "burst": true},
+		{"offset": 250, "rate": {"rps": null}, "name": "peak", "burst": "false"},
+		{"offset": "500", "name": "cool", "burst": false}
+	]`), 0644)
+	ndjson := filepath.Join(dir, "pacing.jsonl")
+	os.WriteFile(ndjson, []byte("{\"offset\": 0}\n{\"offset\": 250}\n"), 0644)
+
+	columns, err := loader.LoadJSONColumns(array, []CsvColumnSchema{
+		{Name: "offset", Type: "int32"},
+		{Name: "rate.rps", Type: "float64"},
+		{Name: "name"},
+		{Name: "burst", Type: "bool"},
+	})
+	if err != nil {
+		t.Fatalf("LoadJSONColumns failed: %v", err)
+	}
+	if got := columns["offset"]; !reflect.DeepEqual(got, []int32{0, 250, 500}) {
+		t.Errorf("Unexpected offset column: %v", got)
+	}
+	rate := columns["rate.rps"].([]float64)
+	if rate[0] != 1.5 || !math.IsNaN(rate[1]) || !math.IsNaN(rate[2]) {
+		t.Errorf("Unexpected rate.rps column: %v", rate)
+	}
+	if got := columns["name"]; !reflect.DeepEqual(got, []string{"warm", "peak", "cool"}) {
+		t.Errorf("Unexpected name column: %v", got)
+	}
+	if got := columns["burst"]; !reflect.DeepEqual(got, []bool{true, false, false}) {
+		t.Errorf("Unexpected burst column: %v", got)
+	}
+
+	columns, err = loader.LoadJSONColumns(ndjson, []CsvColumnSchema{{Name: "offset", Type: "int"}})
+	if err != nil {
+		t.Fatalf("LoadJSONColumns failed for NDJSON: %v", err)
+	}
+	if got := columns["offset"]; !reflect.DeepEqual(got, []int64{0, 250}) {
+		t.Errorf("Unexpected NDJSON offset column: %v", got)
+	}
+
+	column := 1
+	invalid := [][]CsvColumnSchema{
+		nil,
+		{{Name: ""}},
+		{{Name: "offset", Column: &column}},
+		{{Name: "offset", Type: "date"}},
+		{{Name: "rate.rps", Type: "int32"}}, // null
+		{{Name: "rate", Type: "number"}},    // object
+	}
+	for _, schema := range invalid {
+		if _, err := loader.LoadJSONColumns(array, schema); err == nil {
+			t.Errorf("LoadJSONColumns(%v) expected an error", schema)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// json_format.go
+package streamloader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FormatJsonOptions represents options for FormatJsonFile
+type FormatJsonOptions struct {
+	// Indent is either a number of spaces or a literal indent string such as "\t" (default: 2 spaces)
+	Indent interface{} `json:"indent" js:"indent"`
+}
+
+// FormatJsonFile pretty-prints a JSON file (array, object or NDJSON) into the output file.
+// The input is processed as a stream of bytes, so memory usage stays constant regardless of
+// file size. String contents and number literals are copied exactly as written; only the
+// whitespace between tokens is rewritten. Multiple top-level values are separated by a newline.
+//
+// Options:
+// - indent: Number of spaces or an indent string such as "\t" (default: 2)
+//
+// Returns: The number of bytes written to the output file
+//
+// Example usage:
+//
+//	written, err := streamloader.FormatJsonFile("data.json", "data.pretty.json", FormatJsonOptions{Indent: 4})
+func (s StreamLoader) FormatJsonFile(inputFilePath string, outputFilePath string, options ...FormatJsonOptions) (int, error) {
+	indent := "  "
+	if len(options) > 0 && options[0].Indent != nil {
+		switch v := options[0].Indent.(type) {
+		case string:
+			indent = v
+		case int:
+			indent = strings.Repeat(" ", max(v, 0))
+		case int64:
+			indent = strings.Repeat(" ", max(int(v), 0))
+		case float64:
+			indent = strings.Repeat(" ", max(int(v), 0))
+		default:
+			return 0, fmt.Errorf("invalid indent: expected number or string, got %T", v)
+		}
+	}
+	return transformJsonFile(inputFilePath, outputFilePath, true, indent, s.call)
+}
+
+// MinifyJsonFile removes all insignificant whitespace from a JSON file (array, object or NDJSON)
+// and writes the result to the output file. Like FormatJsonFile it streams the input, so it is
+// suitable for shrinking very large artifacts. NDJSON input keeps one value per line.
+//
+// Returns: The number of bytes written to the output file
+//
+// Example usage:
+//
+//	written, err := streamloader.MinifyJsonFile("data.pretty.json", "data.min.json")
+func (s StreamLoader) MinifyJsonFile(inputFilePath string, outputFilePath string) (int, error) {
+	return transformJsonFile(inputFilePath, outputFilePath, false, "", s.call)
+}
+
+// transformJsonFile opens the input and output files and runs reformatJSON between them.
+func transformJsonFile(inputFilePath string, outputFilePath string, pretty bool, indent string, call callLimits) (int, error) {
+	inputFile, err := openInput(inputFilePath, call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer inputFile.Close()
+
+	lock, err := lockOutputPath(outputFilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.release()
+	outputFile, err := os.Create(outputFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer outputFile.Close()
+
+	reader := bufio.NewReaderSize(inputFile, readBufferSize())
+	writer := bufio.NewWriterSize(outputFile, writeBufferSize())
+
+	written, err := reformatJSON(reader, writer, pretty, indent)
+	if err != nil {
+		return written, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return written, fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	return written, nil
+}
+
+// reformatJSON copies JSON tokens from reader to writer, rewriting the whitespace between them.
+// It tracks string state and bracket nesting so that unbalanced or unterminated input is
+// reported as an error, but it does not otherwise validate the JSON grammar.
+func reformatJSON(reader *bufio.Reader, writer *bufio.Writer, pretty bool, indent string) (int, error) {
+	written := 0
+	write := func(s string) {
+		n, _ := writer.WriteString(s)
+		written += n
+	}
+	newline := func(depth int) {
+		write("\n")
+		for i := 0; i < depth; i++ {
+			write(indent)
+		}
+	}
+
+	var stack []byte // Open delimiters
+	inString := false
+	escaped := false
+	pendingOpen := false   // A container was opened and its first element has not been written yet
+	inScalar := false      // Currently inside a number or literal at the top level
+	needSeparator := false // A top-level value has completed
+
+	offset := 0
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read input: %w", err)
+		}
+		offset++
+
+		if inString {
+			writer.WriteByte(c)
+			written++
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+				if len(stack) == 0 {
+					needSeparator = true
+				}
+			}
+			continue
+		}
+
+		if isWhitespace(c) {
+			if inScalar && len(stack) == 0 {
+				inScalar = false
+				needSeparator = true
+			}
+			continue
+		}
+
+		// Closing delimiters end the current container
+		if c == '}' || c == ']' {
+			if len(stack) == 0 || (c == '}') != (stack[len(stack)-1] == '{') {
+				return written, fmt.Errorf("unexpected %q at byte %d", c, offset)
+			}
+			stack = stack[:len(stack)-1]
+			if pendingOpen {
+				pendingOpen = false
+			} else if pretty {
+				newline(len(stack))
+			}
+			writer.WriteByte(c)
+			written++
+			if len(stack) == 0 {
+				needSeparator = true
+			}
+			continue
+		}
+
+		if c == ',' || c == ':' {
+			if len(stack) == 0 {
+				return written, fmt.Errorf("unexpected %q at byte %d", c, offset)
+			}
+			writer.WriteByte(c)
+			written++
+			if pretty {
+				if c == ',' {
+					newline(len(stack))
+				} else {
+					write(" ")
+				}
+			}
+			continue
+		}
+
+		// Start of a new value or continuation of a scalar
+		if len(stack) == 0 && needSeparator {
+			write("\n")
+			needSeparator = false
+			inScalar = false
+		}
+		if pendingOpen {
+			if pretty {
+				newline(len(stack))
+			}
+			pendingOpen = false
+		}
+
+		writer.WriteByte(c)
+		written++
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+			pendingOpen = true
+		default:
+			if len(stack) == 0 {
+				inScalar = true
+			}
+		}
+	}
+
+	if inString {
+		return written, fmt.Errorf("unterminated string at end of input")
+	}
+	if len(stack) > 0 {
+		return written, fmt.Errorf("unexpected end of input: %d unclosed bracket(s)", len(stack))
+	}
+	if pretty && written > 0 {
+		write("\n")
+	}
+	return written, nil
+}
@@ -0,0 +1,163 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFormatJsonFile(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		input    string
+		options  []FormatJsonOptions
+		expected string
+	}{
+		{
+			name:     "Array with default indent",
+			input:    `[{"id":1,"tags":["a","b"]},{"empty":{},"list":[]}]`,
+			expected: "[\n  {\n    \"id\": 1,\n    \"tags\": [\n      \"a\",\n      \"b\"\n    ]\n  },\n  {\n    \"empty\": {},\n    \"list\": []\n  }\n]\n",
+		},
+		{
+			name:     "Numeric indent",
+			input:    `{"a":{"b":1.50}}`,
+			options:  []FormatJsonOptions{{Indent: int64(4)}},
+			expected: "{\n    \"a\": {\n        \"b\": 1.50\n    }\n}\n",
+		},
+		{
+			name:     "Tab indent keeps string contents",
+			input:    `{"s":"a, b: [c] {\"d\"}"}`,
+			options:  []FormatJsonOptions{{Indent: "\t"}},
+			expected: "{\n\t\"s\": \"a, b: [c] {\\\"d\\\"}\"\n}\n",
+		},
+		{
+			name:     "NDJSON values stay separated",
+			input:    "{\"a\":1}\n{\"b\":2}\n",
+			expected: "{\n  \"a\": 1\n}\n{\n  \"b\": 2\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputPath := filepath.Join(tempDir, "input.json")
+			outputPath := filepath.Join(tempDir, "output.json")
+			if err := os.WriteFile(inputPath, []byte(tt.input), 0644); err != nil {
+				t.Fatalf("Failed to write input: %v", err)
+			}
+
+			written, err := loader.FormatJsonFile(inputPath, outputPath, tt.options...)
+			if err != nil {
+				t.Fatalf("FormatJsonFile() error = %v", err)
+			}
+
+			content, _ := os.ReadFile(outputPath)
+			if string(content) != tt.expected {
+				t.Errorf("Unexpected output:\n got: %q\nwant: %q", content, tt.expected)
+			}
+			if written != len(content) {
+				t.Errorf("Expected %d bytes written, got %d", len(content), written)
+			}
+		})
+	}
+}
+
+func TestMinifyJsonFile(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Pretty array",
+			input:    "[\n  {\n    \"id\": 1,\n    \"name\": \"A B\"\n  },\n  {\n    \"id\": 2e10\n  }\n]\n",
+			expected: `[{"id":1,"name":"A B"},{"id":2e10}]`,
+		},
+		{
+			name:     "NDJSON keeps one value per line",
+			input:    "{ \"a\" : 1 }\n\n{ \"b\" : [ true , null ] }\n",
+			expected: "{\"a\":1}\n{\"b\":[true,null]}",
+		},
+		{
+			name:     "Top-level scalars",
+			input:    "1 2\n\"x y\"",
+			expected: "1\n2\n\"x y\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputPath := filepath.Join(tempDir, "input.json")
+			outputPath := filepath.Join(tempDir, "output.json")
+			os.WriteFile(inputPath, []byte(tt.input), 0644)
+
+			if _, err := loader.MinifyJsonFile(inputPath, outputPath); err != nil {
+				t.Fatalf("MinifyJsonFile() error = %v", err)
+			}
+
+			content, _ := os.ReadFile(outputPath)
+			if string(content) != tt.expected {
+				t.Errorf("Unexpected output:\n got: %q\nwant: %q", content, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatJsonFile_RoundTrip(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	prettyPath := filepath.Join(tempDir, "pretty.json")
+	minPath := filepath.Join(tempDir, "min.json")
+
+	if _, err := loader.FormatJsonFile("testdata/complex.json", prettyPath); err != nil {
+		t.Fatalf("FormatJsonFile() error = %v", err)
+	}
+	if _, err := loader.MinifyJsonFile(prettyPath, minPath); err != nil {
+		t.Fatalf("MinifyJsonFile() error = %v", err)
+	}
+
+	var original, roundTrip interface{}
+	originalBytes, _ := os.ReadFile("testdata/complex.json")
+	minBytes, _ := os.ReadFile(minPath)
+	if err := json.Unmarshal(originalBytes, &original); err != nil {
+		t.Fatalf("Failed to parse original: %v", err)
+	}
+	if err := json.Unmarshal(minBytes, &roundTrip); err != nil {
+		t.Fatalf("Failed to parse minified output: %v", err)
+	}
+	if !reflect.DeepEqual(original, roundTrip) {
+		t.Error("Round-tripped JSON differs from the original")
+	}
+}
+
+func TestFormatJsonFile_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	outputPath := filepath.Join(tempDir, "output.json")
+
+	if _, err := loader.FormatJsonFile("no_such_file.json", outputPath); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	for _, input := range []string{`[{"a":1}`, `{"a":"b}`, `[1,2}`, `]`} {
+		inputPath := filepath.Join(tempDir, "bad.json")
+		os.WriteFile(inputPath, []byte(input), 0644)
+		if _, err := loader.MinifyJsonFile(inputPath, outputPath); err == nil {
+			t.Errorf("Expected error for malformed input %q", input)
+		}
+	}
+
+	inputPath := filepath.Join(tempDir, "ok.json")
+	os.WriteFile(inputPath, []byte(`{}`), 0644)
+	if _, err := loader.FormatJsonFile(inputPath, outputPath, FormatJsonOptions{Indent: true}); err == nil {
+		t.Error("Expected error for invalid indent type")
+	}
+}
@@ -0,0 +1,232 @@
+// json_path.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSegment is a step of a path: an object key, an array index, both for the numeric
+// segments of dotted paths, or any member.
+type jsonPathSegment struct {
+	name     string // Object key, "" for none
+	index    int    // Array index, -1 for none
+	wildcard bool
+}
+
+// parseJSONPath parses a JSONPath of child steps, such as "$.data.items[*].id" or
+// "$['data'][0]", or a dotted path such as "data.items.0". Recursive descent, filters and
+// slices are not supported. definite reports whether the path has no wildcard, so it selects at
+// most one value.
+func parseJSONPath(path string) (segments []jsonPathSegment, definite bool, err error) {
+	invalid := func(reason string) error {
+		return fmt.Errorf("invalid path %q: %s", path, reason)
+	}
+	rest := strings.TrimSpace(path)
+	if rest == "" {
+		return nil, false, invalid("empty path")
+	}
+	definite = true
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest[0] != '[' {
+		rest = "." + rest // Dotted path
+	}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			if strings.HasPrefix(rest, "..") {
+				return nil, false, invalid("recursive descent (..) is not supported")
+			}
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			rest = rest[end+1:]
+			switch {
+			case name == "":
+				return nil, false, invalid("empty key")
+			case name == "*":
+				segments = append(segments, jsonPathSegment{index: -1, wildcard: true})
+				definite = false
+			default:
+				segment := jsonPathSegment{name: name, index: -1}
+				if i, err := strconv.Atoi(name); err == nil && i >= 0 {
+					segment.index = i
+				}
+				segments = append(segments, segment)
+			}
+		case '[':
+			if len(rest) > 1 && (rest[1] == '\'' || rest[1] == '"') {
+				quote := rest[1:2]
+				closing := strings.Index(rest[2:], quote+"]")
+				if closing < 0 {
+					return nil, false, invalid("unclosed quoted key")
+				}
+				segments = append(segments, jsonPathSegment{name: rest[2 : 2+closing], index: -1})
+				rest = rest[2+closing+2:]
+				continue
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false, invalid("unclosed bracket")
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if inner == "*" {
+				segments = append(segments, jsonPathSegment{index: -1, wildcard: true})
+				definite = false
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, false, invalid(fmt.Sprintf("unsupported selector [%s]: expected a key, an index or *", inner))
+			}
+			segments = append(segments, jsonPathSegment{index: i})
+		default:
+			return nil, false, invalid(fmt.Sprintf("unexpected %q", rest[0]))
+		}
+	}
+	return segments, definite, nil
+}
+
+// jsonPathWalker streams a document and decodes only the values a path selects; everything
+// else is skipped token by token, so a small array nested in a large document costs little more
+// than reading the file.
+type jsonPathWalker struct {
+	dec             *json.Decoder
+	detectDuplicate bool
+	matches         []interface{}
+}
+
+// walk reads the value at the decoder's position, decoding it if the remaining segments are
+// empty and descending into its members otherwise.
+func (w *jsonPathWalker) walk(segments []jsonPathSegment, at string) error {
+	if len(segments) == 0 {
+		var value interface{}
+		if w.detectDuplicate {
+			var raw json.RawMessage
+			if err := w.dec.Decode(&raw); err != nil {
+				return err
+			}
+			if err := checkDuplicateKeys(raw, at); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return err
+			}
+		} else if err := w.dec.Decode(&value); err != nil {
+			return err
+		}
+		w.matches = append(w.matches, value)
+		return nil
+	}
+
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil // A scalar has no members
+	}
+	segment := segments[0]
+	switch delim {
+	case '{':
+		for w.dec.More() {
+			tok, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			if segment.wildcard || (segment.name != "" && key == segment.name) {
+				err = w.walk(segments[1:], at+"."+key)
+			} else {
+				err = skipJSONValue(w.dec)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; w.dec.More(); i++ {
+			if segment.wildcard || segment.index == i {
+				err = w.walk(segments[1:], fmt.Sprintf("%s[%d]", at, i))
+			} else {
+				err = skipJSONValue(w.dec)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err = w.dec.Token() // Closing delimiter
+	return err
+}
+
+// skipJSONValue reads past the value at the decoder's position without decoding it.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// loadJSONPath returns the values of a JSON document, or of every record of an NDJSON file,
+// selected by the path option of LoadJSON. A definite path in a single document returns the
+// value itself, and fails if the document doesn't have it; other paths return an array of the
+// matches. When the result is an array, the per-record options apply to its elements.
+func loadJSONPath(reader *bufio.Reader, filePath string, opts JsonOptions, pipeline *recordPipeline) (any, error) {
+	if (opts.ObjectOrder != "" && opts.ObjectOrder != "map") || opts.KeyPrefix != "" || opts.KeyRegex != "" || opts.MaxKeys != 0 {
+		return nil, fmt.Errorf("path can't be combined with objectOrder, keyPrefix, keyRegex or maxKeys")
+	}
+	segments, definite, err := parseJSONPath(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	w := &jsonPathWalker{dec: json.NewDecoder(reader), detectDuplicate: opts.DetectDuplicateKeys}
+	documents := 0
+	for ; w.dec.More(); documents++ {
+		if err := w.walk(segments, "$"); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+	}
+
+	var result any = w.matches
+	if definite && documents <= 1 {
+		if len(w.matches) == 0 {
+			return nil, fmt.Errorf("path %q not found in %s", opts.Path, filePath)
+		}
+		result = w.matches[0]
+	}
+	records, ok := result.([]interface{})
+	if !ok {
+		return result, nil
+	}
+	kept := make([]interface{}, 0, len(records))
+	for i, record := range records {
+		if keep, err := pipeline.apply(record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		} else if keep {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
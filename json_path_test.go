@@ -0,0 +1,102 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadJSONPath(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	document := filepath.Join(dir, "export.json")
+	os.WriteFile(document, []byte(`{
+		"meta": {"skipped": [1, 2, {"deep": [3]}], "note": "x"},
+		"data": {
+			"items": [{"id": 1, "tags": ["a"]}, {"id": 2, "tags": []}, {"id": 3}],
+			"0": "key zero",
+			"my key": {"n": 5}
+		},
+		"pages": [{"items": [10, 11]}, {"items": [20]}, {"other": true}]
+	}`), 0644)
+
+	items := []interface{}{
+		map[string]interface{}{"id": float64(1), "tags": []interface{}{"a"}},
+		map[string]interface{}{"id": float64(2), "tags": []interface{}{}},
+		map[string]interface{}{"id": float64(3)},
+	}
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"$.data.items", items},
+		{"data.items", items},
+		{"$['data']['items']", items},
+		{"$.data.items[1].id", float64(2)},
+		{"data.items.1.id", float64(2)},
+		{"data.0", "key zero"},
+		{`$.data["my key"].n`, float64(5)},
+		{"$.meta.note", "x"},
+		{"$.data.items[*].id", []interface{}{float64(1), float64(2), float64(3)}},
+		{"$.pages[*].items[0]", []interface{}{float64(10), float64(20)}},
+		{"$.pages.*.items", []interface{}{[]interface{}{float64(10), float64(11)}, []interface{}{float64(20)}}},
+		{"$.data.items[*].missing", []interface{}{}},
+	}
+	for _, tt := range tests {
+		got, err := loader.LoadJSON(document, JsonOptions{Path: tt.path})
+		if err != nil {
+			t.Errorf("LoadJSON(path %q) error = %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadJSON(path %q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Every record of an NDJSON file, with the per-record options on the matches
+	records := filepath.Join(dir, "records.ndjson")
+	os.WriteFile(records, []byte("{\"user\":{\"name\":\"YQ==\"}}\n{\"user\":{}}\n{\"user\":{\"name\":\"Yg==\"}}\n"), 0644)
+	got, err := loader.LoadJSON(records, JsonOptions{Path: "user"})
+	if err != nil || len(got.([]interface{})) != 3 {
+		t.Errorf("LoadJSON(ndjson, path) = %v, %v", got, err)
+	}
+	got, err = loader.LoadJSON(records, JsonOptions{Path: "user", DecodeFields: map[string]string{"name": "base64"}})
+	if want := []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{}, map[string]interface{}{"name": "b"}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadJSON(ndjson, path, decodeFields) = %v, %v, want %v", got, err, want)
+	}
+
+	// Duplicate keys are only checked in the selected values
+	duplicates := filepath.Join(dir, "duplicates.json")
+	os.WriteFile(duplicates, []byte(`{"a": {"x": 1, "x": 2}, "b": {"y": 1}}`), 0644)
+	if _, err := loader.LoadJSON(duplicates, JsonOptions{Path: "b", DetectDuplicateKeys: true}); err != nil {
+		t.Errorf("LoadJSON(path b, detectDuplicateKeys) error = %v", err)
+	}
+	if _, err := loader.LoadJSON(duplicates, JsonOptions{Path: "a", DetectDuplicateKeys: true}); err == nil {
+		t.Error("LoadJSON(path a, detectDuplicateKeys) expected an error")
+	}
+
+	// Errors
+	truncated := filepath.Join(dir, "truncated.json")
+	os.WriteFile(truncated, []byte(`{"data": {"items": [1, 2`), 0644)
+	invalid := []struct {
+		file    string
+		options JsonOptions
+	}{
+		{document, JsonOptions{Path: "$.data.missing"}},
+		{document, JsonOptions{Path: "$..items"}},
+		{document, JsonOptions{Path: "$.data.items[1:2]"}},
+		{document, JsonOptions{Path: "$.data.items[-1]"}},
+		{document, JsonOptions{Path: "$['data"}},
+		{document, JsonOptions{Path: "$.data[0"}},
+		{document, JsonOptions{Path: "$.data..x"}},
+		{document, JsonOptions{Path: "$x"}},
+		{document, JsonOptions{Path: "data", KeyPrefix: "x"}},
+		{truncated, JsonOptions{Path: "$.data.items"}},
+	}
+	for _, tt := range invalid {
+		if _, err := loader.LoadJSON(tt.file, tt.options); err == nil {
+			t.Errorf("LoadJSON(%s, %+v) expected an error", filepath.Base(tt.file), tt.options)
+		}
+	}
+}
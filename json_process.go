@@ -0,0 +1,356 @@
+// json_process.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// JsonFilterConfig is a record filter of ProcessJsonFile, the counterpart of FilterConfig
+type JsonFilterConfig struct {
+	Type    string   `json:"type" js:"type"`
+	Field   string   `json:"field" js:"field"`
+	Pattern string   `json:"pattern,omitempty" js:"pattern"`
+	Min     *float64 `json:"min,omitempty" js:"min"`
+	Max     *float64 `json:"max,omitempty" js:"max"`
+	Rate    *float64 `json:"rate,omitempty" js:"rate"`
+	Salt    string   `json:"salt,omitempty" js:"salt"`
+}
+
+// JsonTransformConfig is a value transform of ProcessJsonFile, the counterpart of
+// TransformConfig
+type JsonTransformConfig struct {
+	Type        string      `json:"type" js:"type"`
+	Field       string      `json:"field" js:"field"`
+	Value       interface{} `json:"value,omitempty" js:"value"`
+	Start       int         `json:"start,omitempty" js:"start"`
+	Length      *int        `json:"length,omitempty" js:"length"`
+	Name        string      `json:"name,omitempty" js:"name"`
+	Part        string      `json:"part,omitempty" js:"part"`
+	SourceField string      `json:"sourceField,omitempty" js:"sourceField"`
+}
+
+// JsonGroupByConfig is the grouping of ProcessJsonFile, the counterpart of GroupByConfig
+type JsonGroupByConfig struct {
+	Field         string `json:"field" js:"field"`
+	OutputPattern string `json:"outputPattern,omitempty" js:"outputPattern"`
+	HashKey       string `json:"hashKey,omitempty" js:"hashKey"`
+	Salt          string `json:"salt,omitempty" js:"salt"`
+}
+
+// JsonFieldConfig is a projection field of ProcessJsonFile, the counterpart of FieldConfig
+type JsonFieldConfig struct {
+	Type  string      `json:"type" js:"type"`
+	Field string      `json:"field,omitempty" js:"field"`
+	Value interface{} `json:"value,omitempty" js:"value"`
+	Name  string      `json:"name,omitempty" js:"name"`
+	Part  string      `json:"part,omitempty" js:"part"`
+}
+
+// ProcessJsonOptions represents options for ProcessJsonFile
+type ProcessJsonOptions struct {
+	Filters    []JsonFilterConfig    `json:"filters" js:"filters"`
+	Transforms []JsonTransformConfig `json:"transforms" js:"transforms"`
+	GroupBy    *JsonGroupByConfig    `json:"groupBy,omitempty" js:"groupBy"`
+	Fields     []JsonFieldConfig     `json:"fields" js:"fields"`
+}
+
+// ProcessJsonFile streams a JSON array or NDJSON file of objects record by record, applying
+// filters, transforms, grouping, and projection in a single pass like ProcessCsvFile does for
+// CSV rows. Stages address values by field name, or dotted path such as "user.id", instead of
+// column index, and values keep their JSON types.
+//
+// Options:
+// - filters: Array of filter configs to drop unwanted records, and records without the field:
+//   - { type: "emptyString", field: F } drops records whose field is "" or null
+//   - { type: "regexMatch", field: F, pattern: "regex" }
+//   - { type: "valueRange", field: F, min: X, max: Y } keeps numbers and numeric strings in range
+//   - { type: "hashSample", field: F, rate: R, salt: S }, as for ProcessCsvFile
+//
+// - transforms: Array of transform configs to apply in place, skipping absent fields:
+//   - { type: "parseInt", field: F } turns an integer string into a number
+//   - { type: "fixedValue", field: F, value: V } also adds the field to its parent object
+//   - { type: "substring", field: F, start: S, length: L }
+//   - { type: "setQueryParam", field: F, name: P, value: V }, or sourceField: G instead of value
+//   - { type: "deleteQueryParam", field: F, name: P }
+//   - { type: "setUrlPart", field: F, part: "scheme" | "host" | "path" | "query" | "fragment",
+//     value: V }, or sourceField: G
+//
+// - groupBy: Optional grouping by field, skipping records without it: { field: F }
+//   - outputPattern, hashKey, salt: As for ProcessCsvFile
+//
+// - fields: Projection fields; without them each record is returned whole:
+//   - { type: "field", field: F } projects the value, or null when the record doesn't have it
+//   - { type: "fixed", value: V } | { type: "sourceFile" }
+//   - { type: "sourceIndex" } projects the index of the record in the file
+//   - { type: "urlPart", field: F, part: "path" } | { type: "queryParam", field: F, name: P } |
+//     { type: "queryParams", field: F }, as for ProcessCsvFile
+//
+// Returns: Array of projected rows, or of records without fields, grouped if groupBy is
+// specified, with groups in the order their first record appears. With groupBy.outputPattern,
+// one [key, filePath, recordCount] array per group, sorted by key.
+//
+// Example usage:
+//
+//	result, err := streamloader.ProcessJsonFile("orders.ndjson", ProcessJsonOptions{
+//		Filters: []JsonFilterConfig{
+//			{Type: "valueRange", Field: "total", Min: &minTotal},
+//		},
+//		Fields: []JsonFieldConfig{
+//			{Type: "field", Field: "id"},
+//			{Type: "field", Field: "customer.country"},
+//		},
+//	})
+func (s StreamLoader) ProcessJsonFile(filePath string, options ProcessJsonOptions) ([]interface{}, error) {
+	// 1) Validate the pipeline before reading
+	regexCache := make(map[string]*regexp.Regexp)
+	for _, filter := range options.Filters {
+		if filter.Field == "" {
+			return nil, fmt.Errorf("%s filter needs a field", filter.Type)
+		}
+		switch filter.Type {
+		case "emptyString", "valueRange":
+		case "hashSample":
+			if filter.Rate == nil {
+				return nil, fmt.Errorf("hashSample filter needs a rate")
+			}
+			if err := validateHashSampleRate(*filter.Rate); err != nil {
+				return nil, err
+			}
+		case "regexMatch":
+			compiled, err := regexp.Compile(filter.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern in filter: %w", err)
+			}
+			regexCache[filter.Pattern] = compiled
+		default:
+			return nil, fmt.Errorf("unknown filter type %q", filter.Type)
+		}
+	}
+	for _, transform := range options.Transforms {
+		if transform.Field == "" {
+			return nil, fmt.Errorf("%s transform needs a field", transform.Type)
+		}
+		if err := validateUrlTransform(TransformConfig{Type: transform.Type, Name: transform.Name, Part: transform.Part}); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range options.Fields {
+		if err := validateUrlField(FieldConfig{Type: field.Type, Name: field.Name, Part: field.Part}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2) Initialize grouping state
+	group := options.GroupBy
+	var groupFiles *groupFileWriter
+	var groupKeys []string
+	groupMap := make(map[string][]interface{})
+	if group != nil {
+		if group.Field == "" {
+			return nil, fmt.Errorf("groupBy needs a field")
+		}
+		if err := validateGroupKeyHash(group.HashKey); err != nil {
+			return nil, err
+		}
+		if group.OutputPattern != "" {
+			var err error
+			if groupFiles, err = newGroupFileWriter(group.OutputPattern); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 3) Process the records one by one
+	result := make([]interface{}, 0)
+	index := 0
+	err := forEachJsonRecord(filePath, s.call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", index, err)
+		}
+		recordIndex := index
+		index++
+
+		if !keepJsonRecord(record, options.Filters, regexCache) {
+			return true, nil
+		}
+		for _, transform := range options.Transforms {
+			applyJsonTransform(record, transform)
+		}
+
+		projected := record
+		if len(options.Fields) > 0 {
+			row := make([]interface{}, 0, len(options.Fields))
+			for _, field := range options.Fields {
+				row = append(row, projectJsonField(record, field, filePath, recordIndex))
+			}
+			projected = row
+		}
+
+		if group == nil {
+			result = append(result, projected)
+			return true, nil
+		}
+		value, found := lookupField(record, group.Field)
+		if !found {
+			return true, nil
+		}
+		key := hashGroupKey(keyString(value), group.HashKey, group.Salt)
+		if groupFiles != nil {
+			// Stream the record straight to its group file
+			return true, groupFiles.Write(key, projected)
+		}
+		if _, seen := groupMap[key]; !seen {
+			groupKeys = append(groupKeys, key)
+		}
+		groupMap[key] = append(groupMap[key], projected)
+		return true, nil
+	})
+	if err != nil {
+		if groupFiles != nil {
+			groupFiles.Abort()
+		}
+		return nil, err
+	}
+
+	// 4) Finalize output
+	if groupFiles != nil {
+		// Report the files written instead of the records
+		summary, err := groupFiles.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range summary {
+			result = append(result, row)
+		}
+		return result, nil
+	}
+	if group != nil {
+		for _, key := range groupKeys {
+			// Flatten each group into a single array, as ProcessCsvFile does
+			var flatGroup []interface{}
+			for _, projected := range groupMap[key] {
+				if row, ok := projected.([]interface{}); ok && len(options.Fields) > 0 {
+					flatGroup = append(flatGroup, row...)
+				} else {
+					flatGroup = append(flatGroup, projected)
+				}
+			}
+			result = append(result, flatGroup)
+		}
+	}
+	return result, nil
+}
+
+// keepJsonRecord reports whether a record passes every filter of ProcessJsonFile.
+func keepJsonRecord(record interface{}, filters []JsonFilterConfig, regexCache map[string]*regexp.Regexp) bool {
+	for _, filter := range filters {
+		value, found := lookupField(record, filter.Field)
+		if !found {
+			return false // Drop the record if the field doesn't exist
+		}
+		switch filter.Type {
+		case "emptyString":
+			if value == nil || value == "" {
+				return false
+			}
+		case "regexMatch":
+			if value == nil || !regexCache[filter.Pattern].MatchString(keyString(value)) {
+				return false
+			}
+		case "valueRange":
+			num, ok := numberValue(value)
+			if s, isString := value.(string); isString {
+				var err error
+				num, err = strconv.ParseFloat(s, 64)
+				ok = err == nil
+			}
+			// Treat non-numeric values as not satisfying the range
+			if !ok || (filter.Min != nil && num < *filter.Min) || (filter.Max != nil && num > *filter.Max) {
+				return false
+			}
+		case "hashSample":
+			if value == nil || !hashSampled(keyString(value), *filter.Rate, filter.Salt) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// applyJsonTransform applies a transform of ProcessJsonFile to a record in place.
+func applyJsonTransform(record interface{}, t JsonTransformConfig) {
+	obj, key, ok := fieldParent(record, t.Field)
+	if !ok {
+		return
+	}
+	if t.Type == "fixedValue" {
+		obj[key] = t.Value
+		return
+	}
+	value, found := obj[key]
+	if !found || value == nil {
+		return // Skip transform if the field doesn't exist
+	}
+
+	switch t.Type {
+	case "parseInt":
+		if s, ok := value.(string); ok {
+			if num, err := strconv.Atoi(s); err == nil {
+				obj[key] = num
+			}
+		}
+	case "substring":
+		str := keyString(value)
+		start := t.Start
+		if start < 0 || start >= len(str) {
+			obj[key] = ""
+		} else {
+			end := len(str)
+			if t.Length != nil && *t.Length > 0 && start+*t.Length < len(str) {
+				end = start + *t.Length
+			}
+			obj[key] = str[start:end]
+		}
+	case "setQueryParam", "deleteQueryParam", "setUrlPart":
+		// Run the CSV transform on a row of the URL and, with sourceField, the source value
+		row := []string{keyString(value)}
+		transform := TransformConfig{Type: t.Type, Value: t.Value, Name: t.Name, Part: t.Part}
+		if t.SourceField != "" {
+			source, found := lookupField(record, t.SourceField)
+			if !found || source == nil {
+				return
+			}
+			row = append(row, keyString(source))
+			sourceColumn := 1
+			transform.SourceColumn = &sourceColumn
+		}
+		applyUrlTransform(row, transform)
+		obj[key] = row[0]
+	}
+}
+
+// projectJsonField returns the value of a projection field of ProcessJsonFile for a record.
+func projectJsonField(record interface{}, f JsonFieldConfig, filePath string, index int) interface{} {
+	switch f.Type {
+	case "field":
+		value, _ := lookupField(record, f.Field)
+		return value
+	case "fixed":
+		return f.Value
+	case "sourceFile":
+		return filePath
+	case "sourceIndex":
+		return index
+	case "urlPart", "queryParam", "queryParams":
+		cell := ""
+		if value, found := lookupField(record, f.Field); found && value != nil {
+			cell = keyString(value)
+		}
+		return projectUrlField(cell, FieldConfig{Type: f.Type, Name: f.Name, Part: f.Part})
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessJsonFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "orders.ndjson")
+	os.WriteFile(path, []byte(strings.Join([]string{
+		`{"id":"o1","total":"120","customer":{"country":"DE"},"url":"https://shop.test/cart?token=x&page=1","user":"u1"}`,
+		`{"id":"o2","total":15,"customer":{"country":"FR"},"url":"https://shop.test/checkout?token=y","user":"u2"}`,
+		`{"id":"","total":300,"customer":{"country":"DE"},"url":"https://shop.test/cart","user":"u3"}`,
+		`{"id":"o4","total":"n/a","customer":{"country":"DE"},"url":"https://shop.test/","user":"u4"}`,
+		`{"id":"o5","total":250,"url":"https://shop.test/cart?page=2","user":"u5"}`,
+	}, "\n")), 0644)
+	minTotal := 100.0
+	length := 1
+
+	tests := []struct {
+		name    string
+		options ProcessJsonOptions
+		want    []interface{}
+		wantErr string
+	}{
+		{
+			name: "filters and fields",
+			options: ProcessJsonOptions{
+				Filters: []JsonFilterConfig{
+					{Type: "emptyString", Field: "id"},
+					{Type: "valueRange", Field: "total", Min: &minTotal},
+				},
+				Fields: []JsonFieldConfig{
+					{Type: "field", Field: "id"},
+					{Type: "field", Field: "customer.country"},
+					{Type: "sourceIndex"},
+				},
+			},
+			want: []interface{}{
+				[]interface{}{"o1", "DE", 0},
+				[]interface{}{"o5", nil, 4},
+			},
+		},
+		{
+			name: "transforms",
+			options: ProcessJsonOptions{
+				Filters: []JsonFilterConfig{{Type: "regexMatch", Field: "id", Pattern: "^o[12]$"}},
+				Transforms: []JsonTransformConfig{
+					{Type: "parseInt", Field: "total"},
+					{Type: "substring", Field: "customer.country", Start: 0, Length: &length},
+					{Type: "fixedValue", Field: "customer.tier", Value: "gold"},
+					{Type: "deleteQueryParam", Field: "url", Name: "token"},
+					{Type: "setQueryParam", Field: "url", Name: "uid", SourceField: "user"},
+				},
+				Fields: []JsonFieldConfig{
+					{Type: "field", Field: "total"},
+					{Type: "field", Field: "customer"},
+					{Type: "field", Field: "url"},
+					{Type: "urlPart", Field: "url", Part: "path"},
+					{Type: "queryParam", Field: "url", Name: "uid"},
+				},
+			},
+			want: []interface{}{
+				[]interface{}{120, map[string]interface{}{"country": "D", "tier": "gold"}, "https://shop.test/cart?page=1&uid=u1", "/cart", "u1"},
+				[]interface{}{float64(15), map[string]interface{}{"country": "F", "tier": "gold"}, "https://shop.test/checkout?uid=u2", "/checkout", "u2"},
+			},
+		},
+		{
+			name: "whole records",
+			options: ProcessJsonOptions{
+				Filters: []JsonFilterConfig{{Type: "regexMatch", Field: "customer.country", Pattern: "FR"}},
+			},
+			want: []interface{}{
+				map[string]interface{}{"id": "o2", "total": float64(15), "customer": map[string]interface{}{"country": "FR"}, "url": "https://shop.test/checkout?token=y", "user": "u2"},
+			},
+		},
+		{
+			name: "group by field",
+			options: ProcessJsonOptions{
+				GroupBy: &JsonGroupByConfig{Field: "customer.country"},
+				Fields:  []JsonFieldConfig{{Type: "field", Field: "user"}},
+			},
+			want: []interface{}{
+				[]interface{}{"u1", "u3", "u4"},
+				[]interface{}{"u2"},
+			},
+		},
+		{
+			name:    "filter without field",
+			options: ProcessJsonOptions{Filters: []JsonFilterConfig{{Type: "emptyString"}}},
+			wantErr: "needs a field",
+		},
+		{
+			name:    "unknown filter",
+			options: ProcessJsonOptions{Filters: []JsonFilterConfig{{Type: "notEmpty", Field: "id"}}},
+			wantErr: "unknown filter type",
+		},
+		{
+			name:    "invalid url part",
+			options: ProcessJsonOptions{Fields: []JsonFieldConfig{{Type: "urlPart", Field: "url", Part: "port"}}},
+			wantErr: "invalid part",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loader.ProcessJsonFile(path, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ProcessJsonFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessJsonFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProcessJsonFile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessJsonFileGroupOutput(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.json")
+	os.WriteFile(path, []byte(`[{"tenant":"acme","n":1},{"tenant":"globex","n":2},{"tenant":"acme","n":3},{"n":4}]`), 0644)
+
+	got, err := loader.ProcessJsonFile(path, ProcessJsonOptions{
+		GroupBy: &JsonGroupByConfig{Field: "tenant", OutputPattern: filepath.Join(dir, "tenant-{key}.json")},
+	})
+	if err != nil {
+		t.Fatalf("ProcessJsonFile() error = %v", err)
+	}
+	want := []interface{}{
+		[]interface{}{"acme", filepath.Join(dir, "tenant-acme.json"), 2},
+		[]interface{}{"globex", filepath.Join(dir, "tenant-globex.json"), 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ProcessJsonFile() = %v, want %v", got, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "tenant-acme.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := loader.LoadJSON(filepath.Join(dir, "tenant-acme.json"))
+	if err != nil {
+		t.Fatalf("LoadJSON() error = %v (%s)", err, data)
+	}
+	if n := len(records.([]interface{})); n != 2 {
+		t.Errorf("acme records = %d, want 2", n)
+	}
+}
@@ -0,0 +1,193 @@
+// json_records.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// jsonRecordReader streams the records of a JSON array or NDJSON file one at a time as raw
+// JSON, so callers can process arbitrarily large datasets without loading them into memory.
+type jsonRecordReader struct {
+	path     string
+	file     *inputFile
+	dec      *json.Decoder
+	isArray  bool
+	done     bool
+	index    int
+	slot     *fileSlot
+	snapshot bool // Reading up to the size at open; a truncated last record ends the file
+}
+
+// openJsonRecords opens a JSON array or NDJSON file for record-by-record reading, applying the
+// limits of the call reading it.
+func openJsonRecords(filePath string, call callLimits) (*jsonRecordReader, error) {
+	return openJsonRecordReader(filePath, false, call)
+}
+
+// openJsonRecordReader opens a JSON array or NDJSON file for record-by-record reading, only up
+// to its size at open time with snapshot set.
+func openJsonRecordReader(filePath string, snapshot bool, call callLimits) (*jsonRecordReader, error) {
+	slot, err := acquireFileSlot(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openInput(filePath, call)
+	if err != nil {
+		slot.release()
+		return nil, fmt.Errorf("failed to open input file %s: %w", filePath, err)
+	}
+
+	var src io.Reader = countingReader{file}
+	if snapshot {
+		if src, err = snapshotReader(src, filePath); err != nil {
+			file.Close()
+			slot.release()
+			return nil, err
+		}
+	}
+	reader := bufio.NewReaderSize(src, readBufferSize())
+	r := &jsonRecordReader{path: filePath, file: file, slot: slot, snapshot: snapshot}
+
+	// Peek first non-whitespace byte to detect format
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			r.done = true // Empty file has no records
+			break
+		}
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		if isWhitespace(b[0]) {
+			reader.ReadByte()
+			continue
+		}
+		r.isArray = b[0] == '['
+		break
+	}
+
+	r.dec = json.NewDecoder(reader)
+	if r.isArray {
+		if _, err := r.dec.Token(); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to read opening bracket from %s: %w", filePath, err)
+		}
+	}
+	return r, nil
+}
+
+// Next returns the next record as raw JSON, or io.EOF when there are no records left.
+func (r *jsonRecordReader) Next() (json.RawMessage, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	if !r.dec.More() {
+		r.done = true
+		if r.isArray {
+			// Consume closing ']'
+			if t, err := r.dec.Token(); err != nil {
+				return nil, fmt.Errorf("failed to read closing bracket from %s: %w", r.path, err)
+			} else if delim, ok := t.(json.Delim); !ok || delim != ']' {
+				return nil, fmt.Errorf("expected closing bracket in %s, got %v", r.path, t)
+			}
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := r.dec.Decode(&raw); err != nil {
+		r.done = true
+		if err == io.EOF || (r.snapshot && !r.isArray && err == io.ErrUnexpectedEOF) {
+			// A truncated last record of a snapshot was still being written
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to decode record %d in %s: %w", r.index, r.path, err)
+	}
+	if r.index == 0 && r.isArray && isRawDatasetHeader(raw) {
+		// Skip the header written by the metadata writer option
+		r.index++
+		return r.Next()
+	}
+	r.index++
+	return raw, nil
+}
+
+// Close releases the underlying file.
+func (r *jsonRecordReader) Close() error {
+	r.done = true
+	defer r.slot.release()
+	return r.file.Close()
+}
+
+// jsonArrayWriter streams raw JSON records into a JSON array file.
+type jsonArrayWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	count  int
+	slot   *fileSlot
+	lock   *outputLock
+}
+
+// createJsonArrayFile creates or truncates the output file and writes the opening bracket.
+// Other writers of the file wait until it is closed.
+func createJsonArrayFile(filePath string, bufSize int) (*jsonArrayWriter, error) {
+	lock, err := lockOutputPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := acquireFileSlot(filePath)
+	if err != nil {
+		lock.release()
+		return nil, err
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		slot.release()
+		lock.release()
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	w := &jsonArrayWriter{file: file, writer: bufio.NewWriterSize(countingWriter{file}, bufSize), slot: slot, lock: lock}
+	if _, err := w.writer.WriteString("["); err != nil {
+		file.Close()
+		slot.release()
+		lock.release()
+		return nil, fmt.Errorf("failed to write opening bracket: %w", err)
+	}
+	return w, nil
+}
+
+// Write appends one encoded JSON record to the array.
+func (w *jsonArrayWriter) Write(record []byte) error {
+	if w.count > 0 {
+		if err := w.writer.WriteByte(','); err != nil {
+			return fmt.Errorf("failed to write comma separator: %w", err)
+		}
+	}
+	if _, err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write JSON object: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Close writes the closing bracket, flushes buffered data and closes the file.
+func (w *jsonArrayWriter) Close() error {
+	defer w.lock.release()
+	defer w.slot.release()
+	if _, err := w.writer.WriteString("]"); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to write closing bracket: %w", err)
+	}
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,170 @@
+// json_seq.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonSeqRS is the record separator that starts every text in an application/json-seq stream
+const jsonSeqRS = 0x1E
+
+// WriteObjectsToJsonSeqFile writes objects as a JSON text sequence (RFC 7464,
+// application/json-seq): every value is preceded by an ASCII record separator (0x1E) and
+// followed by a newline.
+//
+// Options are the same as for WriteObjectsToJsonArrayFile: a buffer size in bytes (default:
+// 64KB), or a JsonWriterOptions object with bufferSize, sortKeys and stableFormatting fields.
+//
+// Returns: The number of values written
+//
+// Example usage:
+//
+//	count, err := streamloader.WriteObjectsToJsonSeqFile(objects, "events.json-seq")
+func (StreamLoader) WriteObjectsToJsonSeqFile(objects []interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	return writeJsonValuesFile(objects, outputFilePath, options, func(w *bufio.Writer, _ []byte, value []byte) error {
+		w.WriteByte(jsonSeqRS)
+		w.Write(value)
+		return w.WriteByte('\n')
+	})
+}
+
+// WriteObjectsToConcatenatedJsonFile writes objects as plain concatenated JSON with no
+// separators, e.g. {"a":1}{"a":2}. A single space is inserted only where two adjacent values
+// would otherwise run together, such as consecutive numbers. Read the file back with
+// LoadConcatenatedJSON.
+//
+// Options are the same as for WriteObjectsToJsonArrayFile.
+//
+// Returns: The number of values written
+//
+// Example usage:
+//
+//	count, err := streamloader.WriteObjectsToConcatenatedJsonFile(objects, "events.json")
+func (StreamLoader) WriteObjectsToConcatenatedJsonFile(objects []interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	return writeJsonValuesFile(objects, outputFilePath, options, func(w *bufio.Writer, previous []byte, value []byte) error {
+		if len(previous) > 0 && isBareJSONByte(previous[len(previous)-1]) && isBareJSONByte(value[0]) {
+			w.WriteByte(' ')
+		}
+		_, err := w.Write(value)
+		return err
+	})
+}
+
+// ObjectsToJsonSeq converts objects to an application/json-seq string (RFC 7464), for example
+// to use as a request body.
+//
+// Example usage:
+//
+//	body, err := streamloader.ObjectsToJsonSeq(objects)
+func (StreamLoader) ObjectsToJsonSeq(objects []interface{}) (string, error) {
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		encoded, err := json.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+		buf.WriteByte(jsonSeqRS)
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+	}
+	return buf.String(), nil
+}
+
+// JsonSeqToObjects parses an application/json-seq string into objects.
+func (StreamLoader) JsonSeqToObjects(jsonSeq string) ([]interface{}, error) {
+	return readJsonSeq(strings.NewReader(jsonSeq))
+}
+
+// LoadJsonSeq loads a JSON text sequence file (RFC 7464). Texts are split on the record
+// separator, so values may span several lines. Empty texts are skipped; a text that fails to
+// parse is reported as an error.
+//
+// Example usage:
+//
+//	events, err := streamloader.LoadJsonSeq("events.json-seq")
+func (s StreamLoader) LoadJsonSeq(filePath string) ([]interface{}, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return readJsonSeq(file)
+}
+
+// readJsonSeq decodes the texts of a JSON text sequence.
+func readJsonSeq(r io.Reader) ([]interface{}, error) {
+	reader := bufio.NewReaderSize(r, readBufferSize())
+	values := make([]interface{}, 0)
+	for index := 0; ; {
+		text, err := reader.ReadBytes(jsonSeqRS)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read JSON text sequence: %w", err)
+		}
+		text = bytes.TrimSpace(bytes.TrimSuffix(text, []byte{jsonSeqRS}))
+		if len(text) > 0 {
+			var value interface{}
+			if jsonErr := json.Unmarshal(text, &value); jsonErr != nil {
+				return nil, fmt.Errorf("failed to parse text %d in JSON text sequence: %w", index, jsonErr)
+			}
+			values = append(values, value)
+			index++
+		}
+		if err == io.EOF {
+			return values, nil
+		}
+	}
+}
+
+// writeJsonValuesFile encodes objects and writes them with a format-specific framing function,
+// which receives the previously written value (nil for the first).
+func writeJsonValuesFile(objects []interface{}, outputFilePath string, options []interface{}, frame func(w *bufio.Writer, previous []byte, value []byte) error) (int, error) {
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return estimateObjectsSize(objects) }); err != nil {
+		return 0, err
+	}
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	writer := bufio.NewWriterSize(file, opts.BufferSize)
+
+	var previous []byte
+	for i, obj := range objects {
+		encoded, err := json.Marshal(obj)
+		if err != nil {
+			return i, fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+		if encoded, err = opts.formatJSON(encoded); err != nil {
+			return i, fmt.Errorf("failed to format object at index %d: %w", i, err)
+		}
+		if err := frame(writer, previous, encoded); err != nil {
+			return i, fmt.Errorf("failed to write object: %w", err)
+		}
+		previous = encoded
+	}
+
+	if err := writer.Flush(); err != nil {
+		return len(objects), fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return len(objects), err
+	}
+	return len(objects), nil
+}
+
+// isBareJSONByte reports whether b can start or end a JSON number or literal, the only values
+// that need a separator when concatenated.
+func isBareJSONByte(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || b == '-' || b == '.' || b == 'E'
+}
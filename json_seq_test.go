@@ -0,0 +1,86 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJsonSeq(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	objects := []interface{}{
+		map[string]interface{}{"b": 1, "a": "<x>"},
+		[]interface{}{1, 2},
+		"text",
+		42,
+	}
+	decoded := []interface{}{
+		map[string]interface{}{"a": "<x>", "b": 1.0},
+		[]interface{}{1.0, 2.0},
+		"text",
+		42.0,
+	}
+
+	path := filepath.Join(dir, "events.json-seq")
+	count, err := loader.WriteObjectsToJsonSeqFile(objects, path, JsonWriterOptions{SortKeys: true})
+	if err != nil || count != 4 {
+		t.Fatalf("WriteObjectsToJsonSeqFile() = %d, %v", count, err)
+	}
+	data, _ := os.ReadFile(path)
+	expected := "\x1e{\"a\":\"<x>\",\"b\":1}\n\x1e[1,2]\n\x1e\"text\"\n\x1e42\n"
+	if string(data) != expected {
+		t.Errorf("file = %q, want %q", data, expected)
+	}
+
+	values, err := loader.LoadJsonSeq(path)
+	if err != nil {
+		t.Fatalf("LoadJsonSeq() error = %v", err)
+	}
+	if !reflect.DeepEqual(values, decoded) {
+		t.Errorf("LoadJsonSeq() = %v, want %v", values, decoded)
+	}
+
+	body, err := loader.ObjectsToJsonSeq(objects[1:])
+	if err != nil || body != "\x1e[1,2]\n\x1e\"text\"\n\x1e42\n" {
+		t.Errorf("ObjectsToJsonSeq() = %q, %v", body, err)
+	}
+
+	// Multi-line texts, empty texts and missing trailing newlines are accepted
+	parsed, err := loader.JsonSeqToObjects("\x1e\x1e{\n  \"a\": 1\n}\n\x1e2")
+	if err != nil || !reflect.DeepEqual(parsed, []interface{}{map[string]interface{}{"a": 1.0}, 2.0}) {
+		t.Errorf("JsonSeqToObjects() = %v, %v", parsed, err)
+	}
+	if _, err := loader.JsonSeqToObjects("\x1e{\"a\":\n\x1e1\n"); err == nil {
+		t.Error("Expected error for a truncated text")
+	}
+	if _, err := loader.LoadJsonSeq(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestWriteObjectsToConcatenatedJsonFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.json")
+	objects := []interface{}{map[string]interface{}{"a": 1}, 1, 2.5, true, "s", nil, -3, []interface{}{}}
+
+	count, err := loader.WriteObjectsToConcatenatedJsonFile(objects, path)
+	if err != nil || count != len(objects) {
+		t.Fatalf("WriteObjectsToConcatenatedJsonFile() = %d, %v", count, err)
+	}
+	data, _ := os.ReadFile(path)
+	if expected := `{"a":1}1 2.5 true"s"null -3[]`; string(data) != expected {
+		t.Errorf("file = %s, want %s", data, expected)
+	}
+
+	values, err := loader.LoadConcatenatedJSON(path)
+	if err != nil {
+		t.Fatalf("LoadConcatenatedJSON() error = %v", err)
+	}
+	expected := []any{map[string]any{"a": 1.0}, 1.0, 2.5, true, "s", nil, -3.0, []any{}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("round trip = %v, want %v", values, expected)
+	}
+}
@@ -0,0 +1,128 @@
+// json_stream.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// JSONStreamOptions represents options for OpenJSONStream
+type JSONStreamOptions struct {
+	DecodeFields    map[string]string `json:"decodeFields" js:"decodeFields"`
+	DropExpired     string            `json:"dropExpired" js:"dropExpired"`
+	ExpiryReference string            `json:"expiryReference" js:"expiryReference"`
+	Snapshot        bool              `json:"snapshot" js:"snapshot"`
+}
+
+// JSONStream is a cursor over the records of a JSON array or NDJSON file
+type JSONStream struct {
+	mu       sync.Mutex
+	path     string
+	records  *jsonRecordReader // nil once closed
+	pipeline *recordPipeline
+	count    int
+}
+
+// OpenJSONStream opens a JSON array or NDJSON file for reading one record at a time, so a
+// script can go through a multi-GB file in the VU loop with only the current record in memory,
+// where LoadJSON would decode the whole file up front. Only one record is decoded per Next call,
+// and the file is closed once the last record is read. A stream opened during an iteration is
+// closed when the iteration ends; one opened in the init context lasts until it is closed.
+//
+// Options:
+//   - decodeFields: Map from field (or dotted path) to its encoding, decoded in every record,
+//     as for LoadJSON (default: none)
+//   - dropExpired: Timestamp field of records to skip once it is in the past, as for LoadJSON
+//     (default: none)
+//   - expiryReference: "now" or "testStart", as for LoadJSON (default: "now")
+//   - snapshot: Stop at the size of the file when it is opened, and at a last record that is
+//     still being written, as for LoadJSON (default: false)
+//
+// Example usage:
+//
+//	const stream = streamloader.openJSONStream("requests.ndjson");
+//	export default function () {
+//		const request = stream.next();
+//		if (request === null) {
+//			exec.test.abort("no requests left");
+//		}
+//		http.get(request.url);
+//	}
+//	export function teardown() { stream.close(); }
+func (s StreamLoader) OpenJSONStream(filePath string, options ...JSONStreamOptions) (*JSONStream, error) {
+	var opts JSONStreamOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	pipeline, err := newRecordPipeline(JsonOptions{
+		DecodeFields:    opts.DecodeFields,
+		DropExpired:     opts.DropExpired,
+		ExpiryReference: opts.ExpiryReference,
+	})
+	if err != nil {
+		return nil, err
+	}
+	records, err := openJsonRecordReader(filePath, opts.Snapshot, s.call.untimed())
+	if err != nil {
+		return nil, err
+	}
+	stream := &JSONStream{path: filePath, records: records, pipeline: pipeline}
+	s.closeAtIterationEnd(stream)
+	return stream, nil
+}
+
+// Next returns the next record, or nil once the file is exhausted or the stream is closed.
+func (j *JSONStream) Next() (interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for j.records != nil {
+		raw, err := j.records.Next()
+		if err == io.EOF {
+			j.close()
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, fmt.Errorf("failed to decode record %d in %s: %w", j.count, j.path, err)
+		}
+		j.count++
+		if keep, err := j.pipeline.apply(record); err != nil {
+			return nil, fmt.Errorf("record %d in %s: %w", j.count-1, j.path, err)
+		} else if keep {
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+// Count returns the number of records read so far, including those dropExpired skipped.
+func (j *JSONStream) Count() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.count
+}
+
+// Close closes the file. Closing a stream again does nothing.
+func (j *JSONStream) Close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.close()
+}
+
+// Dispose is an alias of Close.
+func (j *JSONStream) Dispose() {
+	j.Close()
+}
+
+// close closes the file; the caller holds j.mu.
+func (j *JSONStream) close() {
+	if j.records != nil {
+		j.records.Close()
+		j.records = nil
+	}
+}
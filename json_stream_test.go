@@ -0,0 +1,95 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenJSONStream(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		options []JSONStreamOptions
+		want    []interface{}
+	}{
+		{"array", `[{"id":1},{"id":2},{"id":3}]`, nil, []interface{}{
+			map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)}, map[string]interface{}{"id": float64(3)},
+		}},
+		{"ndjson", "{\"id\":1}\n\n{\"id\":2}\n", nil, []interface{}{
+			map[string]interface{}{"id": float64(1)}, map[string]interface{}{"id": float64(2)},
+		}},
+		{"empty", `[]`, nil, nil},
+		{"options", `[{"id":1,"body":"aGk="},{"id":2,"body":"eW8=","exp":1},{"id":3,"body":"b2s="}]`,
+			[]JSONStreamOptions{{DecodeFields: map[string]string{"body": "base64"}, DropExpired: "exp"}}, []interface{}{
+				map[string]interface{}{"id": float64(1), "body": "hi"}, map[string]interface{}{"id": float64(3), "body": "ok"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			os.WriteFile(path, []byte(tt.content), 0644)
+			stream, err := loader.OpenJSONStream(path, tt.options...)
+			if err != nil {
+				t.Fatalf("OpenJSONStream() error = %v", err)
+			}
+			defer stream.Close()
+			var got []interface{}
+			for {
+				record, err := stream.Next()
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if record == nil {
+					break
+				}
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+			// The stream stays exhausted
+			if record, err := stream.Next(); record != nil || err != nil {
+				t.Errorf("Next() after the end = %v, %v", record, err)
+			}
+		})
+	}
+
+	path := filepath.Join(dir, "close.json")
+	os.WriteFile(path, []byte(`[{"id":1},{"id":2}]`), 0644)
+	stream, err := loader.OpenJSONStream(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record, _ := stream.Next(); record == nil || stream.Count() != 1 {
+		t.Fatalf("Next() = %v, Count() = %d", record, stream.Count())
+	}
+	stream.Close()
+	stream.Dispose()
+	if record, err := stream.Next(); record != nil || err != nil {
+		t.Errorf("Next() after Close = %v, %v", record, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.ndjson")
+	os.WriteFile(invalid, []byte("{\"id\":1}\n{bad}\n"), 0644)
+	stream, err = loader.OpenJSONStream(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if _, err := stream.Next(); err == nil {
+		t.Error("Next() expected an error for an invalid record")
+	}
+	if _, err := loader.OpenJSONStream(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("OpenJSONStream(missing) expected an error")
+	}
+	if _, err := loader.OpenJSONStream(path, JSONStreamOptions{DecodeFields: map[string]string{"body": "rot13"}}); err == nil {
+		t.Error("OpenJSONStream(invalid encoding) expected an error")
+	}
+}
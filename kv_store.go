@@ -0,0 +1,180 @@
+// kv_store.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// kvSweepInterval is the number of writes between sweeps of expired entries
+const kvSweepInterval = 1024
+
+// kvEntry is a stored value, kept JSON-encoded so VUs never share mutable objects
+type kvEntry struct {
+	value   []byte
+	expires time.Time // Zero means no expiry
+}
+
+// kvStore is a mutex-guarded map with per-entry expiry
+type kvStore struct {
+	mu      sync.Mutex
+	entries map[string]kvEntry
+	writes  int
+}
+
+// scratchStore is shared by every VU in the k6 process
+var scratchStore = &kvStore{entries: make(map[string]kvEntry)}
+
+// get returns the live entry for key, dropping it if it has expired. The caller must hold the lock.
+func (s *kvStore) get(key string, now time.Time) (kvEntry, bool) {
+	entry, ok := s.entries[key]
+	if ok && !entry.expires.IsZero() && !now.Before(entry.expires) {
+		delete(s.entries, key)
+		return kvEntry{}, false
+	}
+	return entry, ok
+}
+
+// put stores an entry and periodically sweeps expired ones. The caller must hold the lock.
+func (s *kvStore) put(key string, entry kvEntry, now time.Time) {
+	s.entries[key] = entry
+	s.writes++
+	if s.writes%kvSweepInterval == 0 {
+		for k, e := range s.entries {
+			if !e.expires.IsZero() && !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+}
+
+// kvExpiry converts an optional TTL in milliseconds into an expiry time.
+func kvExpiry(now time.Time, ttlMs []int64) time.Time {
+	if len(ttlMs) == 0 || ttlMs[0] <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(ttlMs[0]) * time.Millisecond)
+}
+
+// decodeKvValue decodes a stored value.
+func decodeKvValue(data []byte) interface{} {
+	var value interface{}
+	json.Unmarshal(data, &value)
+	return value
+}
+
+// KvSet stores a value in the process-wide scratch store shared by all VUs, replacing any
+// existing value. This coordinates counters and dataset positions across VUs in single-instance
+// runs without an external store such as Redis. Values are copied, so later changes to the
+// object in one VU are not visible to others.
+//
+// An optional TTL in milliseconds makes the entry expire; 0 or omitted keeps it for the whole run.
+//
+// Example usage:
+//
+//	streamloader.KvSet("token", "abc123", 60000)
+func (StreamLoader) KvSet(key string, value interface{}, ttlMs ...int64) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to store value for key %q: %w", key, err)
+	}
+
+	now := time.Now()
+	scratchStore.mu.Lock()
+	defer scratchStore.mu.Unlock()
+	scratchStore.put(key, kvEntry{value: encoded, expires: kvExpiry(now, ttlMs)}, now)
+	return nil
+}
+
+// KvGet returns the value stored under key, or nil if it is missing or has expired.
+func (StreamLoader) KvGet(key string) interface{} {
+	scratchStore.mu.Lock()
+	entry, ok := scratchStore.get(key, time.Now())
+	scratchStore.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return decodeKvValue(entry.value)
+}
+
+// KvIncr atomically adds delta (default 1) to the integer stored under key and returns the new
+// value. A missing or expired key starts at 0 and never expires; an existing key keeps its TTL.
+//
+// Example usage:
+//
+//	// Each VU claims the next row of a shared dataset
+//	const row = streamloader.kvIncr("row") - 1;
+func (StreamLoader) KvIncr(key string, delta ...int64) (int64, error) {
+	step := int64(1)
+	if len(delta) > 0 {
+		step = delta[0]
+	}
+
+	now := time.Now()
+	scratchStore.mu.Lock()
+	defer scratchStore.mu.Unlock()
+
+	var current int64
+	entry, ok := scratchStore.get(key, now)
+	if ok {
+		var err error
+		if current, err = strconv.ParseInt(string(entry.value), 10, 64); err != nil {
+			return 0, fmt.Errorf("cannot increment key %q: value %s is not an integer", key, entry.value)
+		}
+	}
+	current += step
+	entry.value = []byte(strconv.FormatInt(current, 10))
+	scratchStore.put(key, entry, now)
+	return current, nil
+}
+
+// KvCas atomically replaces the value under key with value if the current value equals expected,
+// and reports whether it did. Values are compared by their JSON encoding. An expected value of
+// null matches a missing or expired key, so KvCas can also claim a key only once.
+//
+// Example usage:
+//
+//	if (streamloader.kvCas("leader", null, __VU)) { /* this VU won */ }
+func (StreamLoader) KvCas(key string, expected interface{}, value interface{}, ttlMs ...int64) (bool, error) {
+	expectedEncoded, err := json.Marshal(expected)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode expected value for key %q: %w", key, err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to store value for key %q: %w", key, err)
+	}
+
+	now := time.Now()
+	scratchStore.mu.Lock()
+	defer scratchStore.mu.Unlock()
+
+	current := []byte("null")
+	if entry, ok := scratchStore.get(key, now); ok {
+		current = entry.value
+	}
+	if string(current) != string(expectedEncoded) {
+		return false, nil
+	}
+	scratchStore.put(key, kvEntry{value: encoded, expires: kvExpiry(now, ttlMs)}, now)
+	return true, nil
+}
+
+// KvDelete removes key and reports whether it was present.
+func (StreamLoader) KvDelete(key string) bool {
+	scratchStore.mu.Lock()
+	defer scratchStore.mu.Unlock()
+	_, ok := scratchStore.get(key, time.Now())
+	delete(scratchStore.entries, key)
+	return ok
+}
+
+// KvClear removes every entry from the scratch store.
+func (StreamLoader) KvClear() {
+	scratchStore.mu.Lock()
+	defer scratchStore.mu.Unlock()
+	scratchStore.entries = make(map[string]kvEntry)
+}
@@ -0,0 +1,122 @@
+package streamloader
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKvStore(t *testing.T) {
+	loader := StreamLoader{}
+	loader.KvClear()
+	defer loader.KvClear()
+
+	// Values are copied in and out
+	obj := map[string]interface{}{"name": "alice"}
+	if err := loader.KvSet("user", obj); err != nil {
+		t.Fatalf("KvSet() error = %v", err)
+	}
+	obj["name"] = "changed"
+	if got := loader.KvGet("user"); !reflect.DeepEqual(got, map[string]interface{}{"name": "alice"}) {
+		t.Errorf("KvGet() = %v, want the stored copy", got)
+	}
+	if got := loader.KvGet("missing"); got != nil {
+		t.Errorf("KvGet() of missing key = %v, want nil", got)
+	}
+
+	// Incr starts at 0 and rejects non-integers
+	if n, err := loader.KvIncr("counter"); err != nil || n != 1 {
+		t.Errorf("KvIncr() = %d (err %v), want 1", n, err)
+	}
+	if n, _ := loader.KvIncr("counter", 10); n != 11 {
+		t.Errorf("KvIncr(10) = %d, want 11", n)
+	}
+	if _, err := loader.KvIncr("user"); err == nil {
+		t.Error("Expected error incrementing a non-integer value")
+	}
+	loader.KvSet("float", float64(5))
+	if n, err := loader.KvIncr("float"); err != nil || n != 6 {
+		t.Errorf("KvIncr() on a JS number = %d (err %v), want 6", n, err)
+	}
+
+	// CAS
+	if ok, _ := loader.KvCas("leader", nil, int64(1)); !ok {
+		t.Error("KvCas(null) on a missing key should succeed")
+	}
+	if ok, _ := loader.KvCas("leader", nil, int64(2)); ok {
+		t.Error("KvCas(null) on an existing key should fail")
+	}
+	if ok, _ := loader.KvCas("leader", float64(1), int64(3)); !ok {
+		t.Error("KvCas() with the current value should succeed")
+	}
+	if got := loader.KvGet("leader"); got != float64(3) {
+		t.Errorf("KvGet() after CAS = %v, want 3", got)
+	}
+
+	// Delete
+	if !loader.KvDelete("leader") || loader.KvDelete("leader") {
+		t.Error("KvDelete() should report whether the key existed")
+	}
+}
+
+func TestKvStore_TTL(t *testing.T) {
+	loader := StreamLoader{}
+	loader.KvClear()
+	defer loader.KvClear()
+
+	loader.KvSet("short", "value", 20)
+	loader.KvSet("forever", "value", 0)
+	loader.KvIncr("short-counter")
+	loader.KvCas("lock", nil, "held", 20)
+	if loader.KvGet("short") != "value" || loader.KvGet("lock") != "held" {
+		t.Fatal("Expected entries before expiry")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if got := loader.KvGet("short"); got != nil {
+		t.Errorf("Expected expired entry, got %v", got)
+	}
+	if got := loader.KvGet("forever"); got != "value" {
+		t.Errorf("Expected entry without TTL to remain, got %v", got)
+	}
+	if ok, _ := loader.KvCas("lock", nil, "taken"); !ok {
+		t.Error("Expected an expired lock to be claimable")
+	}
+}
+
+func TestKvStore_Concurrent(t *testing.T) {
+	loader := StreamLoader{}
+	loader.KvClear()
+	defer loader.KvClear()
+
+	const workers, perWorker = 16, 500
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				loader.KvIncr("hits")
+				for {
+					current := loader.KvGet("cas")
+					next := int64(1)
+					if current != nil {
+						next = int64(current.(float64)) + 1
+					}
+					if ok, _ := loader.KvCas("cas", current, next); ok {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := loader.KvGet("hits"); got != float64(workers*perWorker) {
+		t.Errorf("hits = %v, want %d", got, workers*perWorker)
+	}
+	if got := loader.KvGet("cas"); got != float64(workers*perWorker) {
+		t.Errorf("cas = %v, want %d", got, workers*perWorker)
+	}
+}
@@ -0,0 +1,120 @@
+// latest.go
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ResolveLatestOptions represents options for ResolveLatest and LoadJSONLatest
+type ResolveLatestOptions struct {
+	By string `json:"by" js:"by"`
+}
+
+// filenameTimestampPattern matches dates such as 20261016, 2026-10-16, 2026-10-16T12-30-00
+// or 20261016_123000 embedded in a file name
+var filenameTimestampPattern = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})(?:[T_ -]?(\d{2})[:\-]?(\d{2})(?:[:\-]?(\d{2}))?)?`)
+
+// filenameEpochPattern matches Unix timestamps in seconds or milliseconds
+var filenameEpochPattern = regexp.MustCompile(`(?:^|\D)(\d{10}|\d{13})(?:\D|$)`)
+
+// ResolveLatest returns the newest regular file matching a glob pattern. This replaces
+// shelling out to `ls -t` when exports are written with timestamps.
+//
+// Options:
+//   - by: "mtime" to compare modification times (default), or "timestamp" to compare the
+//     date embedded in the file name (e.g. export-2026-10-16T12-30-00.json, data_20261016.csv
+//     or a Unix epoch such as 1792152000). Files without a timestamp are ignored in that mode.
+//
+// Ties are broken by file name so the result is deterministic.
+//
+// Example usage:
+//
+//	path, err := streamloader.ResolveLatest("exports/orders-*.json")
+//	path, err := streamloader.ResolveLatest("exports/*.json", ResolveLatestOptions{By: "timestamp"})
+func (StreamLoader) ResolveLatest(globPattern string, options ...ResolveLatestOptions) (string, error) {
+	by := "mtime"
+	if len(options) > 0 && options[0].By != "" {
+		by = options[0].By
+	}
+	if by != "mtime" && by != "timestamp" {
+		return "", fmt.Errorf("invalid option by=%q: expected \"mtime\" or \"timestamp\"", by)
+	}
+
+	matches, err := filepath.Glob(globPattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid glob pattern: %w", err)
+	}
+
+	var latest string
+	var latestTime time.Time
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		fileTime := info.ModTime()
+		if by == "timestamp" {
+			var ok bool
+			if fileTime, ok = parseFilenameTimestamp(filepath.Base(path)); !ok {
+				continue
+			}
+		}
+
+		if latest == "" || fileTime.After(latestTime) || (fileTime.Equal(latestTime) && path > latest) {
+			latest = path
+			latestTime = fileTime
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no files match pattern %q", globPattern)
+	}
+	return latest, nil
+}
+
+// LoadJSONLatest resolves the newest file matching the glob pattern with ResolveLatest and
+// loads it with LoadJSON.
+//
+// Example usage:
+//
+//	data, err := streamloader.LoadJSONLatest("exports/nightly-*.json")
+func (s StreamLoader) LoadJSONLatest(globPattern string, options ...ResolveLatestOptions) (any, error) {
+	path, err := s.ResolveLatest(globPattern, options...)
+	if err != nil {
+		return nil, err
+	}
+	return s.LoadJSON(path)
+}
+
+// parseFilenameTimestamp extracts a Unix epoch, date or date-time from a file name. An isolated
+// run of 10 or 13 digits is read as an epoch before trying the date formats.
+func parseFilenameTimestamp(name string) (time.Time, bool) {
+	if m := filenameEpochPattern.FindStringSubmatch(name); m != nil {
+		epoch, _ := strconv.ParseInt(m[1], 10, 64)
+		if len(m[1]) == 13 {
+			return time.UnixMilli(epoch).UTC(), true
+		}
+		return time.Unix(epoch, 0).UTC(), true
+	}
+	if m := filenameTimestampPattern.FindStringSubmatch(name); m != nil {
+		parts := make([]int, 6)
+		for i, s := range m[1:] {
+			if s != "" {
+				parts[i], _ = strconv.Atoi(s)
+			}
+		}
+		if parts[1] >= 1 && parts[1] <= 12 && parts[2] >= 1 && parts[2] <= 31 && parts[3] < 24 && parts[4] < 60 && parts[5] < 60 {
+			return time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,119 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveLatest_ByMtime(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	now := time.Now()
+	files := map[string]time.Duration{
+		"export-a.json": -3 * time.Hour,
+		"export-b.json": -1 * time.Hour,
+		"export-c.json": -2 * time.Hour,
+		"other.json":    0,
+	}
+	for name, age := range files {
+		path := filepath.Join(tempDir, name)
+		os.WriteFile(path, []byte(`[{"name":"`+name+`"}]`), 0644)
+		os.Chtimes(path, now.Add(age), now.Add(age))
+	}
+
+	got, err := loader.ResolveLatest(filepath.Join(tempDir, "export-*.json"))
+	if err != nil {
+		t.Fatalf("ResolveLatest() error = %v", err)
+	}
+	if filepath.Base(got) != "export-b.json" {
+		t.Errorf("Expected export-b.json, got %s", got)
+	}
+
+	data, err := loader.LoadJSONLatest(filepath.Join(tempDir, "export-*.json"))
+	if err != nil {
+		t.Fatalf("LoadJSONLatest() error = %v", err)
+	}
+	arr := data.([]interface{})
+	if arr[0].(map[string]interface{})["name"] != "export-b.json" {
+		t.Errorf("LoadJSONLatest loaded the wrong file: %v", arr)
+	}
+}
+
+func TestResolveLatest_ByTimestamp(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{
+			name:     "Compact dates",
+			files:    []string{"data_20261014.csv", "data_20261016.csv", "data_20261015.csv"},
+			expected: "data_20261016.csv",
+		},
+		{
+			name:     "Date-times with separators",
+			files:    []string{"export-2026-10-16T09-00-00.json", "export-2026-10-16T12-30-00.json", "export-2026-10-15T23-59-59.json"},
+			expected: "export-2026-10-16T12-30-00.json",
+		},
+		{
+			name:     "Unix epochs",
+			files:    []string{"dump-1792152000.json", "dump-1792238400.json", "dump-1700000000.json"},
+			expected: "dump-1792238400.json",
+		},
+		{
+			name:     "Files without timestamps are ignored",
+			files:    []string{"latest.json", "run-2026-01-01.json"},
+			expected: "run-2026-01-01.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			now := time.Now()
+			for i, name := range tt.files {
+				path := filepath.Join(tempDir, name)
+				os.WriteFile(path, []byte("[]"), 0644)
+				// Make modification times disagree with the embedded timestamps
+				mtime := now.Add(-time.Duration(i) * time.Minute)
+				os.Chtimes(path, mtime, mtime)
+			}
+
+			got, err := loader.ResolveLatest(filepath.Join(tempDir, "*"), ResolveLatestOptions{By: "timestamp"})
+			if err != nil {
+				t.Fatalf("ResolveLatest() error = %v", err)
+			}
+			if filepath.Base(got) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, filepath.Base(got))
+			}
+		})
+	}
+}
+
+func TestResolveLatest_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "plain.json"), []byte("[]"), 0644)
+	os.Mkdir(filepath.Join(tempDir, "dir.json"), 0755)
+
+	if _, err := loader.ResolveLatest(filepath.Join(tempDir, "missing-*.json")); err == nil {
+		t.Error("Expected error when nothing matches")
+	}
+	if _, err := loader.ResolveLatest(filepath.Join(tempDir, "dir*")); err == nil {
+		t.Error("Expected error when only directories match")
+	}
+	if _, err := loader.ResolveLatest(filepath.Join(tempDir, "*.json"), ResolveLatestOptions{By: "timestamp"}); err == nil {
+		t.Error("Expected error when no file has a timestamp")
+	}
+	if _, err := loader.ResolveLatest(filepath.Join(tempDir, "*.json"), ResolveLatestOptions{By: "size"}); err == nil {
+		t.Error("Expected error for invalid option")
+	}
+	if _, err := loader.ResolveLatest("[", ResolveLatestOptions{}); err == nil {
+		t.Error("Expected error for invalid glob pattern")
+	}
+}
@@ -0,0 +1,274 @@
+// limits.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables setting the limits the process starts with
+const (
+	maxFileBytesEnv  = "STREAMLOADER_MAX_FILE_BYTES"
+	maxDurationMsEnv = "STREAMLOADER_MAX_DURATION_MS"
+)
+
+// FunctionLimits are the guardrails of one function. 0 means no limit.
+type FunctionLimits struct {
+	MaxFileBytes  int64 `json:"maxFileBytes" js:"maxFileBytes"`
+	MaxDurationMs int64 `json:"maxDurationMs" js:"maxDurationMs"`
+}
+
+// LimitOptions holds the guardrails of every function, and overrides for single functions by
+// JavaScript name
+type LimitOptions struct {
+	MaxFileBytes  int64                     `json:"maxFileBytes" js:"maxFileBytes"`
+	MaxDurationMs int64                     `json:"maxDurationMs" js:"maxDurationMs"`
+	Functions     map[string]FunctionLimits `json:"functions" js:"functions"`
+}
+
+// LimitError is the error of a call that exceeded a limit set with SetLimits or the environment
+type LimitError struct {
+	Function string `json:"function" js:"function"`
+	Limit    string `json:"limit" js:"limit"` // maxFileBytes or maxDurationMs
+	Path     string `json:"path" js:"path"`   // The file over maxFileBytes
+	Value    int64  `json:"value" js:"value"`
+	Max      int64  `json:"max" js:"max"`
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "maxFileBytes" {
+		return fmt.Sprintf("%s: file %s is %s, over the maxFileBytes limit of %s", e.Function, e.Path, formatBytes(e.Value), formatBytes(e.Max))
+	}
+	return fmt.Sprintf("%s took %s, over the maxDurationMs limit of %s", e.Function, time.Duration(e.Value)*time.Millisecond, time.Duration(e.Max)*time.Millisecond)
+}
+
+// limits is shared by every VU in the k6 process
+var limits atomic.Pointer[LimitOptions]
+
+func init() {
+	l := envLimits()
+	limits.Store(&l)
+}
+
+// envLimits returns the limits set in the environment. Invalid values are ignored.
+func envLimits() LimitOptions {
+	var l LimitOptions
+	if n, err := strconv.ParseInt(strings.TrimSpace(os.Getenv(maxFileBytesEnv)), 10, 64); err == nil && n > 0 {
+		l.MaxFileBytes = n
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(os.Getenv(maxDurationMsEnv)), 10, 64); err == nil && n > 0 {
+		l.MaxDurationMs = n
+	}
+	return l
+}
+
+// SetLimits sets guardrails so a bad path or a runaway call fails instead of tying up a load
+// generator, for example by parsing a 200GB file:
+//   - maxFileBytes: Calls fail before reading any input file larger than this, including each
+//     file of an array or glob, and once a gzip input decompresses to more (default:
+//     STREAMLOADER_MAX_FILE_BYTES, or no limit)
+//   - maxDurationMs: Calls taking longer fail: their reads stop mid-stream once the limit is
+//     exceeded, and handles they returned are closed (default: STREAMLOADER_MAX_DURATION_MS,
+//     or no limit)
+//   - functions: Limits of single functions by JavaScript name, overriding the limits above
+//
+// Fields left at 0 keep their current value and negative values remove the limit. Calls over a
+// limit fail with a LimitError, whose function, limit, path, value and max fields scripts can
+// inspect. Only functions that can fail are limited. The setting affects all VUs.
+//
+// Returns: The limits now in effect
+//
+// Example usage:
+//
+//	streamloader.setLimits({ maxFileBytes: 2 * 1024 ** 3, functions: { loadJSON: { maxDurationMs: 30000 } } });
+func (StreamLoader) SetLimits(options LimitOptions) (LimitOptions, error) {
+	for {
+		current := limits.Load()
+		next := *current
+		next.MaxFileBytes = mergeLimit(current.MaxFileBytes, options.MaxFileBytes)
+		next.MaxDurationMs = mergeLimit(current.MaxDurationMs, options.MaxDurationMs)
+		if len(options.Functions) > 0 {
+			next.Functions = make(map[string]FunctionLimits, len(current.Functions)+len(options.Functions))
+			for name, l := range current.Functions {
+				next.Functions[name] = l
+			}
+			for name, l := range options.Functions {
+				previous := next.Functions[name]
+				next.Functions[name] = FunctionLimits{
+					MaxFileBytes:  mergeLimit(previous.MaxFileBytes, l.MaxFileBytes),
+					MaxDurationMs: mergeLimit(previous.MaxDurationMs, l.MaxDurationMs),
+				}
+			}
+		}
+		if limits.CompareAndSwap(current, &next) {
+			return next, nil
+		}
+	}
+}
+
+// mergeLimit returns the limit after setting it to value: 0 keeps current, negative removes it.
+func mergeLimit(current int64, value int64) int64 {
+	switch {
+	case value > 0:
+		return value
+	case value < 0:
+		return 0
+	default:
+		return current
+	}
+}
+
+// ResetLimits restores the limits set in the environment, or no limits.
+func (StreamLoader) ResetLimits() {
+	l := envLimits()
+	limits.Store(&l)
+}
+
+// functionLimits returns the limits that apply to a function.
+func functionLimits(name string) FunctionLimits {
+	settings := limits.Load()
+	l := FunctionLimits{MaxFileBytes: settings.MaxFileBytes, MaxDurationMs: settings.MaxDurationMs}
+	if override, ok := settings.Functions[name]; ok {
+		if override.MaxFileBytes != 0 {
+			l.MaxFileBytes = override.MaxFileBytes
+		}
+		if override.MaxDurationMs != 0 {
+			l.MaxDurationMs = override.MaxDurationMs
+		}
+	}
+	return l
+}
+
+// callLimits are the limits of one call from a script, applied to every input the call reads:
+// each file must be within maxFileBytes when it is opened, and reads fail once the call is past
+// its deadline, so a runaway load stops mid-stream. The zero value limits nothing, as for calls
+// from Go and inputs of handles read after the call returned.
+type callLimits struct {
+	function     string
+	maxFileBytes int64
+	maxDuration  time.Duration
+	start        time.Time
+	deadline     time.Time // Zero without a maxDurationMs limit
+}
+
+// newCallLimits returns the limits of a call of function started at start.
+func newCallLimits(function string, l FunctionLimits, start time.Time) callLimits {
+	c := callLimits{function: function, maxFileBytes: l.MaxFileBytes, start: start}
+	if l.MaxDurationMs > 0 {
+		c.maxDuration = time.Duration(l.MaxDurationMs) * time.Millisecond
+		c.deadline = start.Add(c.maxDuration)
+	}
+	return c
+}
+
+// untimed returns the limits without the deadline, for inputs of handles the call returns.
+func (c callLimits) untimed() callLimits {
+	c.maxDuration, c.deadline = 0, time.Time{}
+	return c
+}
+
+// checkSize returns a LimitError if an input of size bytes is over maxFileBytes.
+func (c callLimits) checkSize(path string, size int64) error {
+	if c.maxFileBytes <= 0 || size <= c.maxFileBytes {
+		return nil
+	}
+	return &LimitError{Function: c.function, Limit: "maxFileBytes", Path: path, Value: size, Max: c.maxFileBytes}
+}
+
+// checkDeadline returns a LimitError once the call is past its deadline.
+func (c callLimits) checkDeadline() error {
+	if c.deadline.IsZero() || time.Now().Before(c.deadline) {
+		return nil
+	}
+	return checkDurationLimit(c.function, FunctionLimits{MaxDurationMs: c.maxDuration.Milliseconds()}, time.Since(c.start))
+}
+
+// decompressed limits the decompressed bytes of an input to maxFileBytes, since the size of a
+// compressed file says little about how much a loader will parse.
+func (c callLimits) decompressed(path string, r io.Reader) io.Reader {
+	if c.maxFileBytes <= 0 {
+		return r
+	}
+	return &limitedBody{ReadCloser: io.NopCloser(r), limit: LimitError{Function: c.function, Limit: "maxFileBytes", Path: path, Max: c.maxFileBytes}}
+}
+
+// deadlineReader fails reads once its call is past the deadline.
+type deadlineReader struct {
+	io.ReadCloser
+	call callLimits
+}
+
+func (r deadlineReader) Read(p []byte) (int, error) {
+	if err := r.call.checkDeadline(); err != nil {
+		return 0, err
+	}
+	return r.ReadCloser.Read(p)
+}
+
+// timed returns r failing reads once the call is past its deadline.
+func (c callLimits) timed(r io.ReadCloser) io.ReadCloser {
+	if c.deadline.IsZero() {
+		return r
+	}
+	return deadlineReader{r, c}
+}
+
+// checkFileLimit returns a LimitError if the first argument of a call names a file larger than
+// the function's limit.
+func checkFileLimit(name string, l FunctionLimits, args []reflect.Value) error {
+	if l.MaxFileBytes <= 0 || len(args) == 0 || args[0].Kind() != reflect.String {
+		return nil
+	}
+	path := args[0].String()
+	info, err := statInput(path)
+	if err != nil || !info.Mode().IsRegular() {
+		// Missing files fail in the function itself
+		return nil
+	}
+	if info.Size() > l.MaxFileBytes {
+		return &LimitError{Function: name, Limit: "maxFileBytes", Path: path, Value: info.Size(), Max: l.MaxFileBytes}
+	}
+	return nil
+}
+
+// checkDurationLimit returns a LimitError if a call took longer than the function's limit.
+func checkDurationLimit(name string, l FunctionLimits, duration time.Duration) error {
+	if l.MaxDurationMs <= 0 || duration <= time.Duration(l.MaxDurationMs)*time.Millisecond {
+		return nil
+	}
+	return &LimitError{Function: name, Limit: "maxDurationMs", Value: duration.Milliseconds(), Max: l.MaxDurationMs}
+}
+
+// errorResults returns the results of a failed call of a function of type typ: zero values
+// followed by err.
+func errorResults(typ reflect.Type, err error) []reflect.Value {
+	results := make([]reflect.Value, typ.NumOut())
+	for i := range results {
+		results[i] = reflect.Zero(typ.Out(i))
+	}
+	results[len(results)-1] = reflect.ValueOf(&err).Elem()
+	return results
+}
+
+// closeResults closes the handles among the results of a call that are discarded.
+func closeResults(results []reflect.Value) {
+	for _, r := range results {
+		if !r.IsValid() || !r.CanInterface() {
+			continue
+		}
+		if h, ok := r.Interface().(interface{ Close() }); ok && !(r.Kind() == reflect.Ptr && r.IsNil()) {
+			h.Close()
+		}
+	}
+}
+
+// returnsError reports whether the last result of a function of type typ is an error.
+func returnsError(typ reflect.Type) bool {
+	return typ.NumOut() > 0 && typ.Out(typ.NumOut()-1) == reflect.TypeOf((*error)(nil)).Elem()
+}
@@ -0,0 +1,208 @@
+package streamloader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestSetLimits(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLimits)
+	loader := StreamLoader{}
+	loader.ResetLimits()
+
+	got, _ := loader.SetLimits(LimitOptions{MaxFileBytes: 1000, Functions: map[string]FunctionLimits{"loadJSON": {MaxDurationMs: 50}}})
+	got, _ = loader.SetLimits(LimitOptions{MaxDurationMs: 200, Functions: map[string]FunctionLimits{"loadJSON": {MaxFileBytes: 10}}})
+	if got.MaxFileBytes != 1000 || got.MaxDurationMs != 200 || got.Functions["loadJSON"] != (FunctionLimits{MaxFileBytes: 10, MaxDurationMs: 50}) {
+		t.Errorf("SetLimits() = %+v", got)
+	}
+	if l := functionLimits("loadJSON"); l != (FunctionLimits{MaxFileBytes: 10, MaxDurationMs: 50}) {
+		t.Errorf("functionLimits(loadJSON) = %+v", l)
+	}
+	if l := functionLimits("loadCSV"); l != (FunctionLimits{MaxFileBytes: 1000, MaxDurationMs: 200}) {
+		t.Errorf("functionLimits(loadCSV) = %+v", l)
+	}
+
+	got, _ = loader.SetLimits(LimitOptions{MaxFileBytes: -1})
+	if got.MaxFileBytes != 0 || got.MaxDurationMs != 200 {
+		t.Errorf("removing maxFileBytes = %+v", got)
+	}
+
+	t.Setenv(maxFileBytesEnv, "2048")
+	t.Setenv(maxDurationMsEnv, "not a number")
+	loader.ResetLimits()
+	if got := *limits.Load(); got.MaxFileBytes != 2048 || got.MaxDurationMs != 0 || got.Functions != nil {
+		t.Errorf("limits from the environment = %+v", got)
+	}
+}
+
+func TestFileLimit(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLimits)
+	dir := t.TempDir()
+	path, size := writeDataset(t, dir, "users", 100)
+	small, _ := writeDataset(t, dir, "small", 1)
+	exports := exportsWithCallCounts(&StreamLoader{})
+	loadJSON := exports["loadJSON"].(func(string, ...JsonOptions) (any, error))
+	countLines := exports["countLines"].(func(string) (int, error))
+
+	if _, err := (StreamLoader{}).SetLimits(LimitOptions{MaxFileBytes: size - 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadJSON(path)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("loadJSON() error = %v, want a LimitError", err)
+	}
+	if *limitErr != (LimitError{Function: "loadJSON", Limit: "maxFileBytes", Path: path, Value: size, Max: size - 1}) {
+		t.Errorf("LimitError = %+v", limitErr)
+	}
+	if _, err := loadJSON(small); err != nil {
+		t.Errorf("loadJSON(small) error = %v", err)
+	}
+	// Missing files fail with the function's own error
+	if _, err := loadJSON(filepath.Join(dir, "missing.json")); err == nil || errors.As(err, &limitErr) {
+		t.Errorf("loadJSON(missing) error = %v", err)
+	}
+
+	// A function's own limit overrides the global one
+	if _, err := (StreamLoader{}).SetLimits(LimitOptions{Functions: map[string]FunctionLimits{"countLines": {MaxFileBytes: size}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := countLines(path); err != nil {
+		t.Errorf("countLines() error = %v", err)
+	}
+}
+
+func TestFileLimitOfEveryInput(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLimits)
+	dir := t.TempDir()
+	large, size := writeDataset(t, dir, "large", 100)
+	small, _ := writeDataset(t, dir, "small", 1)
+	exports := exportsWithCallCounts(&StreamLoader{})
+	loadJSON := exports["loadJSON"].(func(string, ...JsonOptions) (any, error))
+	loadJSONMany := exports["loadJSONMany"].(func([]string, ...LoadManyOptions) (map[string]any, error))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	content, _ := os.ReadFile(large)
+	gz.Write(content)
+	gz.Close()
+	compressed := filepath.Join(dir, "large.json.gz")
+	if err := os.WriteFile(compressed, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if int64(buf.Len()) >= size-1 {
+		t.Fatalf("compressed size %d is not below the limit", buf.Len())
+	}
+	if _, err := (StreamLoader{}).SetLimits(LimitOptions{MaxFileBytes: size - 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every file of an array is checked, not only the first argument
+	var limitErr *LimitError
+	if _, err := loadJSONMany([]string{small, large}); !errors.As(err, &limitErr) || limitErr.Path != large {
+		t.Errorf("loadJSONMany() error = %v, want a LimitError for %s", err, large)
+	}
+	// A gzip input is limited by its decompressed bytes
+	if _, err := loadJSON(compressed); !errors.As(err, &limitErr) || limitErr.Path != compressed || limitErr.Value <= size-1 {
+		t.Errorf("loadJSON(gzip) error = %v, want a LimitError for %s", err, compressed)
+	}
+	if _, err := loadJSONMany([]string{small}); err != nil {
+		t.Errorf("loadJSONMany(small) error = %v", err)
+	}
+}
+
+func TestDeadlineMidStream(t *testing.T) {
+	path, _ := writeDataset(t, t.TempDir(), "users", 100)
+	call := newCallLimits("loadJSON", FunctionLimits{MaxDurationMs: 50}, time.Now())
+	file, err := openInput(path, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	buf := make([]byte, 10)
+	if _, err := file.Read(buf); err != nil {
+		t.Fatalf("Read() before the deadline = %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	_, err = file.Read(buf)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "maxDurationMs" || limitErr.Max != 50 {
+		t.Errorf("Read() after the deadline = %v, want a maxDurationMs LimitError", err)
+	}
+
+	// Inputs of returned handles are read after the call returned
+	untimed, err := openInput(path, call.untimed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer untimed.Close()
+	if _, err := untimed.Read(buf); err != nil {
+		t.Errorf("Read() of an untimed input = %v", err)
+	}
+}
+
+func TestDurationLimit(t *testing.T) {
+	l := FunctionLimits{MaxDurationMs: 100}
+	if err := checkDurationLimit("loadJSON", l, 50*time.Millisecond); err != nil {
+		t.Errorf("checkDurationLimit(50ms) = %v", err)
+	}
+	err := checkDurationLimit("loadJSON", l, 1500*time.Millisecond)
+	if err == nil || err.Error() != "loadJSON took 1.5s, over the maxDurationMs limit of 100ms" {
+		t.Errorf("checkDurationLimit(1.5s) = %v", err)
+	}
+	if err := checkDurationLimit("loadJSON", FunctionLimits{}, time.Hour); err != nil {
+		t.Errorf("checkDurationLimit without a limit = %v", err)
+	}
+
+	// Handles returned by a call over the limit are closed
+	it, _ := StreamLoader{}.Iterate([]interface{}{1, 2})
+	var nilIterator *Iterator
+	results := errorResults(reflect.TypeOf(StreamLoader{}.Iterate), err)
+	if len(results) != 2 || !results[0].IsNil() || results[1].Interface() != err {
+		t.Errorf("errorResults() = %v", results)
+	}
+	closeResults(append(results, reflect.ValueOf(it), reflect.ValueOf(nilIterator)))
+	if has, _ := it.HasNext(); has {
+		t.Error("iterator still open after closeResults")
+	}
+}
+
+func TestLimitErrorInJavaScript(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLimits)
+	path, _ := writeDataset(t, t.TempDir(), "users", 100)
+
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Set("path", path); err != nil {
+		t.Fatal(err)
+	}
+	value, err := rt.RunString(`
+		streamloader.setLimits({ functions: { loadJSON: { maxFileBytes: 100 } } });
+		let limit;
+		try {
+			streamloader.loadJSON(path);
+		} catch (e) {
+			limit = e.value.limit + " " + e.value.max;
+		}
+		limit;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != "maxFileBytes 100" {
+		t.Errorf("script result = %s", got)
+	}
+}
@@ -0,0 +1,72 @@
+// line_endings.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// utf8BOM is the byte order mark some Windows tools prepend to UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// lineNormalizer wraps a reader to strip a leading UTF-8 BOM and rewrite "\r\n" and lone "\r"
+// line endings to "\n" as the data streams through, so downstream line and CSV readers never
+// see carriage returns at the end of values.
+type lineNormalizer struct {
+	reader *bufio.Reader
+	skipLF bool // The previous byte was '\r', drop a directly following '\n'
+}
+
+// newLineNormalizer returns a reader that strips a UTF-8 BOM and/or normalizes line endings.
+// If neither is requested the buffered reader is returned unchanged.
+func newLineNormalizer(reader *bufio.Reader, stripBOM bool, normalizeNewlines bool) io.Reader {
+	if stripBOM {
+		if b, err := reader.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+			reader.Discard(len(utf8BOM))
+		}
+	}
+	if !normalizeNewlines {
+		return reader
+	}
+	return &lineNormalizer{reader: reader}
+}
+
+// Read implements io.Reader, rewriting line endings in place.
+func (n *lineNormalizer) Read(p []byte) (int, error) {
+	for {
+		read, err := n.reader.Read(p)
+		out := 0
+		for i := 0; i < read; i++ {
+			c := p[i]
+			if n.skipLF {
+				n.skipLF = false
+				if c == '\n' {
+					continue
+				}
+			}
+			if c == '\r' {
+				c = '\n'
+				n.skipLF = true
+			}
+			p[out] = c
+			out++
+		}
+		// Avoid returning 0, nil when a chunk consisted of a single dropped '\n'
+		if out > 0 || err != nil || read == 0 {
+			return out, err
+		}
+	}
+}
+
+// normalizeText applies BOM stripping and line ending normalization to an in-memory buffer.
+func normalizeText(data []byte, stripBOM bool, normalizeNewlines bool) []byte {
+	if stripBOM {
+		data = bytes.TrimPrefix(data, utf8BOM)
+	}
+	if normalizeNewlines && bytes.IndexByte(data, '\r') >= 0 {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	}
+	return data
+}
@@ -0,0 +1,136 @@
+package streamloader
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineNormalizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Unix newlines unchanged", "a\nb\n", "a\nb\n"},
+		{"CRLF", "a\r\nb\r\n", "a\nb\n"},
+		{"Lone CR", "a\rb\r", "a\nb\n"},
+		{"Mixed", "a\r\nb\rc\nd", "a\nb\nc\nd"},
+		{"BOM stripped", "\xEF\xBB\xBFid,name\r\n", "id,name\n"},
+		{"BOM only at start", "a\xEF\xBB\xBF", "a\xEF\xBB\xBF"},
+		{"Blank CRLF lines kept", "a\r\n\r\nb", "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time exercises "\r\n" split across reads
+			reader := bufio.NewReader(iotest.OneByteReader(strings.NewReader(tt.input)))
+			got, err := io.ReadAll(newLineNormalizer(reader, true, true))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWindowsFixtures(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	csvPath := filepath.Join(tempDir, "windows.csv")
+	os.WriteFile(csvPath, []byte("\xEF\xBB\xBFid,name\r\n1,Alice\r2,Bob\r\n"), 0644)
+
+	ndjsonPath := filepath.Join(tempDir, "windows.ndjson")
+	os.WriteFile(ndjsonPath, []byte("\xEF\xBB\xBF{\"id\":1}\r{\"id\":2}\r\n"), 0644)
+
+	jsonPath := filepath.Join(tempDir, "windows.json")
+	os.WriteFile(jsonPath, []byte("\xEF\xBB\xBF[\r\n{\"id\":1}\r\n]\r\n"), 0644)
+
+	textPath := filepath.Join(tempDir, "windows.txt")
+	os.WriteFile(textPath, []byte("\xEF\xBB\xBFline1\r\nline2\rline3"), 0644)
+
+	t.Run("LoadCSV", func(t *testing.T) {
+		records, err := loader.LoadCSV(csvPath)
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		expected := [][]string{{"id", "name"}, {"1", "Alice"}, {"2", "Bob"}}
+		if !reflect.DeepEqual(records, expected) {
+			t.Errorf("got %q, want %q", records, expected)
+		}
+
+		raw, err := loader.LoadCSV(csvPath, CsvOptions{LazyQuotes: true, PreserveLineEndings: true})
+		if err != nil {
+			t.Fatalf("LoadCSV failed: %v", err)
+		}
+		if raw[0][0] != "\xEF\xBB\xBFid" {
+			t.Errorf("Expected BOM to be preserved, got %q", raw[0][0])
+		}
+	})
+
+	t.Run("ProcessCsvFile", func(t *testing.T) {
+		result, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{SkipHeader: true})
+		if err != nil {
+			t.Fatalf("ProcessCsvFile failed: %v", err)
+		}
+		expected := [][]interface{}{{"1", "Alice"}, {"2", "Bob"}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("got %v, want %v", result, expected)
+		}
+	})
+
+	t.Run("LoadJSON", func(t *testing.T) {
+		for _, path := range []string{ndjsonPath, jsonPath} {
+			data, err := loader.LoadJSON(path)
+			if err != nil {
+				t.Fatalf("LoadJSON(%s) failed: %v", filepath.Base(path), err)
+			}
+			if reflect.ValueOf(data).Len() == 0 {
+				t.Errorf("LoadJSON(%s) returned no records", filepath.Base(path))
+			}
+		}
+		if _, err := loader.LoadJSON(jsonPath, JsonOptions{PreserveLineEndings: true}); err == nil {
+			t.Error("Expected error when the BOM is preserved")
+		}
+	})
+
+	t.Run("Head and Tail", func(t *testing.T) {
+		head, err := loader.Head(textPath, 2)
+		if err != nil || head != "line1\nline2" {
+			t.Errorf("Head() = %q, %v", head, err)
+		}
+		tail, err := loader.Tail(textPath, 2)
+		if err != nil || tail != "line2\nline3" {
+			t.Errorf("Tail() = %q, %v", tail, err)
+		}
+
+		preserved := LineOptions{PreserveLineEndings: true}
+		head, err = loader.Head(textPath, 2, preserved)
+		if err != nil || head != "\xEF\xBB\xBFline1\nline2\rline3" {
+			t.Errorf("Head(preserveLineEndings) = %q, %v", head, err)
+		}
+		tail, err = loader.Tail(textPath, 1, preserved)
+		if err != nil || tail != "line2\rline3" {
+			t.Errorf("Tail(preserveLineEndings) = %q, %v", tail, err)
+		}
+	})
+
+	t.Run("LoadText", func(t *testing.T) {
+		raw, _ := loader.LoadText(textPath)
+		if raw != "\xEF\xBB\xBFline1\r\nline2\rline3" {
+			t.Errorf("Expected content unchanged by default, got %q", raw)
+		}
+		normalized, err := loader.LoadText(textPath, TextOptions{StripBOM: true, NormalizeNewlines: true})
+		if err != nil || normalized != "line1\nline2\nline3" {
+			t.Errorf("LoadText() = %q, %v", normalized, err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// load_many.go
+package streamloader
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// LoadManyOptions represents options for LoadJSONMany. The LoadJSON options apply to every file.
+type LoadManyOptions struct {
+	JsonOptions
+	Concurrency int `json:"concurrency" js:"concurrency"`
+}
+
+// LoadJSONMany loads several JSON files with LoadJSON in parallel and returns their data keyed
+// by path. Loading dozens of medium-size fixture files in setup one after another leaves most
+// cores idle; this keeps up to concurrency files loading at once. Each path is loaded once,
+// even if it is listed more than once.
+//
+// Options are those of LoadJSON plus:
+//   - concurrency: The maximum number of files loaded at once (default: the number of CPUs)
+//
+// If any file fails to load, the files not started yet are skipped and the error of the first
+// file in the list that failed is returned.
+//
+// Example usage:
+//
+//	fixtures, err := streamloader.LoadJSONMany([]string{"users.json", "orders.json"}, LoadManyOptions{Concurrency: 8})
+//	users := fixtures["users.json"]
+func (s StreamLoader) LoadJSONMany(filePaths []string, options ...LoadManyOptions) (map[string]any, error) {
+	var opts LoadManyOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must not be negative, got %d", opts.Concurrency)
+	}
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	// Load each distinct path once, remembering its first position for error reporting
+	paths := make([]string, 0, len(filePaths))
+	seen := make(map[string]bool, len(filePaths))
+	for _, path := range filePaths {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	results := make([]any, len(paths))
+	errs := make([]error, len(paths))
+	var failed sync.Once
+	stop := make(chan struct{})
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				data, err := s.LoadJSON(paths[i], opts.JsonOptions)
+				if err != nil {
+					errs[i] = err
+					failed.Do(func() { close(stop) })
+					continue
+				}
+				results[i] = data
+			}
+		}()
+	}
+
+feed:
+	for i := range paths {
+		select {
+		case next <- i:
+		case <-stop:
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", paths[i], err)
+		}
+	}
+	data := make(map[string]any, len(paths))
+	for i, path := range paths {
+		data[path] = results[i]
+	}
+	return data, nil
+}
@@ -0,0 +1,68 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSONMany(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	var paths []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("fixture-%d.json", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(`[{"file":%d},{"file":%d}]`, i, i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	object := filepath.Join(dir, "object.json")
+	if err := os.WriteFile(object, []byte(`{"a":{"b":1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{0, 1, 4, 100} {
+		data, err := loader.LoadJSONMany(append(paths, object, paths[0]), LoadManyOptions{Concurrency: concurrency})
+		if err != nil {
+			t.Fatalf("concurrency %d: LoadJSONMany() error = %v", concurrency, err)
+		}
+		if len(data) != len(paths)+1 {
+			t.Fatalf("concurrency %d: got %d results, want %d", concurrency, len(data), len(paths)+1)
+		}
+		for i, path := range paths {
+			records := data[path].([]interface{})
+			if len(records) != 2 || records[0].(map[string]interface{})["file"] != float64(i) {
+				t.Errorf("concurrency %d: %s = %v", concurrency, path, records)
+			}
+		}
+		if _, ok := data[object].(map[string]any); !ok {
+			t.Errorf("concurrency %d: %s = %v, want an object", concurrency, object, data[object])
+		}
+	}
+
+	if data, err := loader.LoadJSONMany(nil); err != nil || len(data) != 0 {
+		t.Errorf("LoadJSONMany(nil) = %v, %v", data, err)
+	}
+
+	missing := filepath.Join(dir, "missing.json")
+	_, err := loader.LoadJSONMany(append([]string{missing}, paths...), LoadManyOptions{Concurrency: 2})
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("LoadJSONMany() with a missing file error = %v", err)
+	}
+	if _, err := loader.LoadJSONMany(paths, LoadManyOptions{Concurrency: -1}); err == nil {
+		t.Error("expected an error for negative concurrency")
+	}
+
+	// Options apply to every file
+	bad := filepath.Join(dir, "truncated.json")
+	os.WriteFile(bad, []byte(`[{"id":1},{"id"`), 0644)
+	data, err := loader.LoadJSONMany([]string{bad}, LoadManyOptions{JsonOptions: JsonOptions{AllowPartial: true}})
+	if err != nil || len(data[bad].([]interface{})) != 1 {
+		t.Errorf("LoadJSONMany() with allowPartial = %v, %v", data, err)
+	}
+	loader.ClearPartialErrors()
+}
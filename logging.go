@@ -0,0 +1,239 @@
+// logging.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Log levels of SetLogging, from most to least verbose
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "off": 3}
+
+// maxLoggedArgLength bounds the length of each argument logged at debug level, so writing a
+// large array of objects doesn't flood the log
+const maxLoggedArgLength = 200
+
+// LoggingOptions changes what the data layer logs through k6's logger. Fields left unset keep
+// their current value; SlowOperationMs is a pointer so an explicit 0 can be told from unset.
+type LoggingOptions struct {
+	Level           string            `json:"level" js:"level"`
+	SlowOperationMs *int              `json:"slowOperationMs" js:"slowOperationMs"`
+	Functions       map[string]string `json:"functions" js:"functions"`
+}
+
+// LoggingSettings are the logging settings in effect
+type LoggingSettings struct {
+	Level           string            `json:"level" js:"level"`
+	SlowOperationMs int               `json:"slowOperationMs" js:"slowOperationMs"`
+	Functions       map[string]string `json:"functions" js:"functions"`
+}
+
+// defaultLogging logs only operations slower than 10 seconds.
+var defaultLogging = LoggingSettings{Level: "warn", SlowOperationMs: 10000}
+
+// logging is shared by every VU in the k6 process
+var logging atomic.Pointer[LoggingSettings]
+
+func init() {
+	l := defaultLogging
+	logging.Store(&l)
+}
+
+// SetLogging configures the logging of script calls to the data layer, written to the k6
+// output through k6's logger so data loading is visible without console.log calls:
+//   - level: "debug" logs every call with its duration and all its arguments, each cut off
+//     after 200 bytes, "info" adds the calls that read or wrote data with the bytes moved,
+//     "warn" only logs slow operations, and "off" logs nothing (default: "warn")
+//   - slowOperationMs: Calls taking longer are logged as warnings, such as "loadJSON took 45s
+//     for 3.2GB read"; 0 disables the warnings (default: 10000)
+//   - functions: Levels of single functions by JavaScript name, overriding level, e.g.
+//     { loadJSON: "debug" } (default: none)
+//
+// Fields left unset keep their current value. Bytes are counted for the whole process, so they
+// include the I/O of other VUs running at the same time. The setting affects all VUs.
+//
+// Returns: The settings now in effect
+//
+// Example usage:
+//
+//	streamloader.setLogging({ level: "info", slowOperationMs: 2000, functions: { processCsvFile: "debug" } });
+func (StreamLoader) SetLogging(options LoggingOptions) (LoggingSettings, error) {
+	if _, ok := logLevels[options.Level]; options.Level != "" && !ok {
+		return *logging.Load(), fmt.Errorf("invalid log level %q: expected debug, info, warn or off", options.Level)
+	}
+	for name, level := range options.Functions {
+		if _, ok := logLevels[level]; !ok {
+			return *logging.Load(), fmt.Errorf("invalid log level %q for %s: expected debug, info, warn or off", level, name)
+		}
+	}
+	if options.SlowOperationMs != nil && *options.SlowOperationMs < 0 {
+		return *logging.Load(), fmt.Errorf("slowOperationMs must not be negative, got %d", *options.SlowOperationMs)
+	}
+
+	for {
+		current := logging.Load()
+		next := *current
+		if options.Level != "" {
+			next.Level = options.Level
+		}
+		if options.SlowOperationMs != nil {
+			next.SlowOperationMs = *options.SlowOperationMs
+		}
+		if len(options.Functions) > 0 {
+			next.Functions = make(map[string]string, len(current.Functions)+len(options.Functions))
+			for name, level := range current.Functions {
+				next.Functions[name] = level
+			}
+			for name, level := range options.Functions {
+				next.Functions[name] = level
+			}
+		}
+		if logging.CompareAndSwap(current, &next) {
+			return next, nil
+		}
+	}
+}
+
+// ResetLogging restores the default logging: warnings for operations slower than 10 seconds.
+func (StreamLoader) ResetLogging() {
+	l := defaultLogging
+	logging.Store(&l)
+}
+
+// logger returns k6's logger for the VU: the iteration's logger in the default function, or
+// the init context's logger. It is nil outside a VU.
+func (s StreamLoader) logger() logrus.FieldLogger {
+	if s.vu == nil {
+		return nil
+	}
+	if state := s.vu.State(); state != nil && state.Logger != nil {
+		return state.Logger
+	}
+	if env := s.vu.InitEnv(); env != nil && env.TestPreInitState != nil {
+		return env.Logger
+	}
+	return nil
+}
+
+// callLog measures one script call for logging.
+type callLog struct {
+	start        time.Time
+	bytesRead    int64
+	bytesWritten int64
+}
+
+// startCallLog records the state before a call.
+func startCallLog() callLog {
+	return callLog{start: time.Now(), bytesRead: ioStats.bytesRead.Load(), bytesWritten: ioStats.bytesWritten.Load()}
+}
+
+// finish logs a call according to the logging settings.
+func (c callLog) finish(s StreamLoader, name string, args []reflect.Value, results []reflect.Value) {
+	settings := logging.Load()
+	level := settings.Level
+	if l, ok := settings.Functions[name]; ok {
+		level = l
+	}
+	verbosity := logLevels[level]
+	if verbosity >= logLevels["off"] {
+		return
+	}
+
+	duration := time.Since(c.start)
+	read := ioStats.bytesRead.Load() - c.bytesRead
+	written := ioStats.bytesWritten.Load() - c.bytesWritten
+	moved := read > 0 || written > 0
+
+	var logLevel logrus.Level
+	switch {
+	case settings.SlowOperationMs > 0 && duration >= time.Duration(settings.SlowOperationMs)*time.Millisecond:
+		logLevel = logrus.WarnLevel
+	case moved && verbosity <= logLevels["info"]:
+		logLevel = logrus.InfoLevel
+	case verbosity <= logLevels["debug"]:
+		logLevel = logrus.DebugLevel
+	default:
+		return
+	}
+	logger := s.logger()
+	if logger == nil {
+		return
+	}
+
+	entry := logger.WithFields(logrus.Fields{
+		"source":       "streamloader",
+		"function":     name,
+		"durationMs":   durationMs(duration),
+		"bytesRead":    read,
+		"bytesWritten": written,
+	})
+	if len(args) > 0 && args[0].Kind() == reflect.String {
+		entry = entry.WithField("path", args[0].String())
+	}
+	if verbosity <= logLevels["debug"] {
+		entry = entry.WithField("args", describeArgs(args))
+	}
+	if n := len(results); n > 0 && results[n-1].Type() == reflect.TypeOf((*error)(nil)).Elem() && !results[n-1].IsNil() {
+		entry = entry.WithField("error", results[n-1].Interface())
+	}
+
+	message := fmt.Sprintf("%s took %s", name, duration.Round(time.Millisecond))
+	if moved {
+		message += " for " + describeBytes(read, written)
+	}
+	entry.Log(logLevel, message)
+}
+
+// describeArgs describes the arguments of a call for the log, as JSON where they can be encoded,
+// each cut off after maxLoggedArgLength bytes.
+func describeArgs(args []reflect.Value) []string {
+	described := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !arg.IsValid() || !arg.CanInterface() {
+			described = append(described, "undefined")
+			continue
+		}
+		var text string
+		if encoded, err := json.Marshal(arg.Interface()); err == nil {
+			text = string(encoded)
+		} else {
+			text = fmt.Sprintf("%v", arg.Interface())
+		}
+		if len(text) > maxLoggedArgLength {
+			text = text[:maxLoggedArgLength] + "..."
+		}
+		described = append(described, text)
+	}
+	return described
+}
+
+// describeBytes describes the bytes a call read and wrote, e.g. "3.2GB read".
+func describeBytes(read int64, written int64) string {
+	switch {
+	case read > 0 && written > 0:
+		return formatBytes(read) + " read, " + formatBytes(written) + " written"
+	case written > 0:
+		return formatBytes(written) + " written"
+	default:
+		return formatBytes(read) + " read"
+	}
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 3435973837 as "3.2GB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
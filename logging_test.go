@@ -0,0 +1,271 @@
+package streamloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib"
+)
+
+func intOption(n int) *int {
+	return &n
+}
+
+func loggingVU(t *testing.T) (*StreamLoader, *test.Hook) {
+	t.Helper()
+	t.Cleanup(StreamLoader{}.ResetLogging)
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	return &StreamLoader{vu: &iterationVU{ctx: context.Background(), state: &lib.State{Logger: logger}}}, hook
+}
+
+func TestLoggingLevels(t *testing.T) {
+	path, _ := writeDataset(t, t.TempDir(), "users", 100)
+
+	tests := []struct {
+		name      string
+		options   LoggingOptions
+		wantLevel logrus.Level
+		wantCount int
+	}{
+		{"default is quiet", LoggingOptions{}, 0, 0},
+		{"info logs data movement", LoggingOptions{Level: "info"}, logrus.InfoLevel, 1},
+		{"debug logs every call", LoggingOptions{Level: "debug"}, logrus.InfoLevel, 2},
+		{"per function level", LoggingOptions{Level: "off", Functions: map[string]string{"countLines": "debug"}}, logrus.DebugLevel, 1},
+		{"off", LoggingOptions{Level: "off", SlowOperationMs: intOption(1)}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader, hook := loggingVU(t)
+			if _, err := loader.SetLogging(tt.options); err != nil {
+				t.Fatal(err)
+			}
+			exports := exportsWithCallCounts(loader)
+			loadJSON := exports["loadJSON"].(func(string, ...JsonOptions) (any, error))
+			countLines := exports["countLines"].(func(string) (int, error))
+
+			if _, err := loadJSON(path); err != nil {
+				t.Fatal(err)
+			}
+			// countLines doesn't go through the counting readers, so it moves no data
+			if _, err := countLines(path); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := hook.AllEntries()
+			if len(entries) != tt.wantCount {
+				for _, e := range entries {
+					t.Logf("%s: %s", e.Level, e.Message)
+				}
+				t.Fatalf("logged %d entries, want %d", len(entries), tt.wantCount)
+			}
+			if tt.wantCount > 0 && entries[0].Level != tt.wantLevel {
+				t.Errorf("first entry level = %s, want %s", entries[0].Level, tt.wantLevel)
+			}
+			for _, e := range entries {
+				if e.Data["source"] != "streamloader" || e.Data["path"] != path {
+					t.Errorf("entry fields = %v", e.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingMessage(t *testing.T) {
+	loader, hook := loggingVU(t)
+	if _, err := loader.SetLogging(LoggingOptions{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	path, size := writeDataset(t, t.TempDir(), "users", 100)
+	loadJSON := exportsWithCallCounts(loader)["loadJSON"].(func(string, ...JsonOptions) (any, error))
+	if _, err := loadJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadJSON(filepath.Join(filepath.Dir(path), "missing.json")); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("nothing logged")
+	}
+	// The failed load read nothing, so only the successful one is logged at info level
+	if len(hook.AllEntries()) != 1 || !strings.HasPrefix(entry.Message, "loadJSON took ") || !strings.HasSuffix(entry.Message, " for "+formatBytes(size)+" read") {
+		t.Errorf("logged %d entries, last %q", len(hook.AllEntries()), entry.Message)
+	}
+	if entry.Data["bytesRead"] != size || entry.Data["function"] != "loadJSON" {
+		t.Errorf("entry fields = %v", entry.Data)
+	}
+}
+
+func TestLoggingSlowOperation(t *testing.T) {
+	loader, hook := loggingVU(t)
+	if _, err := loader.SetLogging(LoggingOptions{Level: "off", Functions: map[string]string{"loadJSON": "warn"}, SlowOperationMs: intOption(500)}); err != nil {
+		t.Fatal(err)
+	}
+	startCallLog().finish(*loader, "loadJSON", nil, nil)
+	if entries := hook.AllEntries(); len(entries) != 0 {
+		t.Fatalf("fast call logged %d entries", len(entries))
+	}
+
+	call := startCallLog()
+	call.start = call.start.Add(-time.Second)
+	call.finish(*loader, "loadJSON", nil, nil)
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel || !strings.HasPrefix(entry.Message, "loadJSON took 1s") {
+		t.Errorf("last entry = %+v", entry)
+	}
+
+	// Functions set to off aren't warned about either
+	call.finish(*loader, "loadCSV", nil, nil)
+	if entries := hook.AllEntries(); len(entries) != 1 {
+		t.Errorf("logged %d entries, want 1", len(entries))
+	}
+
+	// An explicit 0 disables the warnings, while leaving the field out keeps it
+	if got, err := loader.SetLogging(LoggingOptions{SlowOperationMs: intOption(0)}); err != nil || got.SlowOperationMs != 0 {
+		t.Fatalf("SetLogging(slowOperationMs: 0) = %+v, %v", got, err)
+	}
+	if got, _ := loader.SetLogging(LoggingOptions{Level: "warn"}); got.SlowOperationMs != 0 {
+		t.Errorf("SetLogging() without slowOperationMs = %+v", got)
+	}
+	call.finish(*loader, "loadJSON", nil, nil)
+	if entries := hook.AllEntries(); len(entries) != 1 {
+		t.Errorf("logged %d entries with the warnings disabled, want 1", len(entries))
+	}
+}
+
+func TestLoggingArguments(t *testing.T) {
+	loader, hook := loggingVU(t)
+	if _, err := loader.SetLogging(LoggingOptions{Level: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "out.json")
+	long := strings.Repeat("x", 300)
+	write := exportsWithCallCounts(loader)["writeObjectsToJsonArrayFile"].(func([]interface{}, string, ...interface{}) (int, error))
+	if _, err := write([]interface{}{map[string]interface{}{"id": 1}, long}, path, map[string]interface{}{"sortKeys": true}); err != nil {
+		t.Fatal(err)
+	}
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("nothing logged")
+	}
+	args, _ := entry.Data["args"].([]string)
+	want := []string{`[{"id":1},"` + long[:maxLoggedArgLength-len(`[{"id":1},"`)] + "...", `"` + path + `"`, `[{"sortKeys":true}]`}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+
+	// Only debug logs the arguments
+	if _, err := loader.SetLogging(LoggingOptions{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := write([]interface{}{1}, path); err != nil {
+		t.Fatal(err)
+	}
+	if entry := hook.LastEntry(); entry.Data["args"] != nil {
+		t.Errorf("info entry has args %v", entry.Data["args"])
+	}
+}
+
+func TestLoggingErrorField(t *testing.T) {
+	loader, hook := loggingVU(t)
+	if _, err := loader.SetLogging(LoggingOptions{Level: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	loadJSON := exportsWithCallCounts(loader)["loadJSON"].(func(string, ...JsonOptions) (any, error))
+	if _, err := loadJSON(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error")
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.DebugLevel || entry.Data["error"] == nil {
+		t.Errorf("last entry = %+v", entry)
+	}
+}
+
+func TestSetLogging(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLogging)
+	loader := StreamLoader{}
+
+	got, err := loader.SetLogging(LoggingOptions{Level: "info", Functions: map[string]string{"loadJSON": "debug"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = loader.SetLogging(LoggingOptions{SlowOperationMs: intOption(500), Functions: map[string]string{"loadCSV": "off"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != "info" || got.SlowOperationMs != 500 || got.Functions["loadJSON"] != "debug" || got.Functions["loadCSV"] != "off" {
+		t.Errorf("SetLogging() = %+v", got)
+	}
+
+	for _, invalid := range []LoggingOptions{
+		{Level: "trace"},
+		{Functions: map[string]string{"loadJSON": "loud"}},
+		{SlowOperationMs: intOption(-1)},
+	} {
+		if _, err := loader.SetLogging(invalid); err == nil {
+			t.Errorf("SetLogging(%+v) succeeded", invalid)
+		}
+	}
+
+	loader.ResetLogging()
+	if got := *logging.Load(); got.Level != "warn" || got.SlowOperationMs != 10000 || got.Functions != nil {
+		t.Errorf("after ResetLogging = %+v", got)
+	}
+}
+
+func TestSetLoggingInJavaScript(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLogging)
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	value, err := rt.RunString(`
+		const kept = streamloader.setLogging({ level: "info" }).slowOperationMs;
+		const disabled = streamloader.setLogging({ slowOperationMs: 0 }).slowOperationMs;
+		kept + " " + disabled + " " + typeof disabled;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != "10000 0 number" {
+		t.Errorf("script result = %s", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{0: "0B", 1023: "1023B", 1536: "1.5KB", 3435973837: "3.2GB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestLoggingOutsideVU(t *testing.T) {
+	t.Cleanup(StreamLoader{}.ResetLogging)
+	loader := &StreamLoader{}
+	if _, err := loader.SetLogging(LoggingOptions{Level: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Without a VU there is no logger; the call must still work
+	countLines := exportsWithCallCounts(loader)["countLines"].(func(string) (int, error))
+	if n, err := countLines(path); err != nil || n != 1 {
+		t.Errorf("countLines() = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,146 @@
+// lookup.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// lookupTable is the data of a lookup table: the raw JSON record for every key.
+type lookupTable struct {
+	entries    map[string]json.RawMessage
+	duplicates int
+}
+
+// Lookup is a read-only handle on a lookup table shared by every VU. Close detaches the handle;
+// the table itself stays in memory for the other VUs.
+type Lookup struct {
+	mu    sync.RWMutex
+	key   string       // Key of the shared table, for Release
+	table *lookupTable // nil once closed
+}
+
+// BuildLookup indexes the records of a JSON array or NDJSON file by the value of keyField, a
+// field or dotted path, for per-iteration enrichment such as userId → token. The table is built
+// once per process, by the first VU to ask for it, and shared by all VUs; records are kept as raw
+// JSON and only decoded when returned by Get, so a table of millions of entries costs a fraction
+// of the memory of a JavaScript object per VU. Keys compare by value, with numbers matching their
+// string form. Records without the key are skipped; if a key repeats, its first record is kept.
+//
+// Example usage:
+//
+//	const tokens = streamloader.buildLookup("tokens.json", "userId");
+//	// In the default function:
+//	const token = tokens.get(user.id)?.token;
+func (s StreamLoader) BuildLookup(filePath string, keyField string) (*Lookup, error) {
+	if keyField == "" {
+		return nil, fmt.Errorf("keyField is required")
+	}
+	key := sharedBuildKey("lookup", filePath, keyField)
+	table, err := buildShared(key, func() (*lookupTable, error) {
+		return buildLookupTable(filePath, keyField, s.call)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := &Lookup{key: key, table: table}
+	s.closeAtIterationEnd(lookup)
+	return lookup, nil
+}
+
+// buildLookupTable reads the records of a file into a map keyed by the string form of keyField.
+func buildLookupTable(filePath string, keyField string, call callLimits) (*lookupTable, error) {
+	table := &lookupTable{entries: make(map[string]json.RawMessage)}
+	index := 0
+	err := forEachJsonRecord(filePath, call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d in %s: %w", index, filePath, err)
+		}
+		index++
+		value, found := lookupField(record, keyField)
+		if !found || value == nil {
+			return true, nil
+		}
+		key := keyString(value)
+		if _, ok := table.entries[key]; ok {
+			table.duplicates++
+			return true, nil
+		}
+		table.entries[key] = raw
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build lookup: %w", err)
+	}
+	return table, nil
+}
+
+// shared returns the table, or an error if the handle is closed.
+func (l *Lookup) shared() (*lookupTable, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.table == nil {
+		return nil, fmt.Errorf("lookup is closed")
+	}
+	return l.table, nil
+}
+
+// Get returns a fresh copy of the record for a key, or null if there is none.
+func (l *Lookup) Get(key interface{}) (any, error) {
+	table, err := l.shared()
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := table.entries[keyString(key)]
+	if !ok {
+		return nil, nil
+	}
+	var record any
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode record for key %v: %w", key, err)
+	}
+	return record, nil
+}
+
+// Has reports whether there is a record for a key.
+func (l *Lookup) Has(key interface{}) (bool, error) {
+	table, err := l.shared()
+	if err != nil {
+		return false, err
+	}
+	_, ok := table.entries[keyString(key)]
+	return ok, nil
+}
+
+// Size returns the number of keys in the table.
+func (l *Lookup) Size() (int, error) {
+	table, err := l.shared()
+	if err != nil {
+		return 0, err
+	}
+	return len(table.entries), nil
+}
+
+// Duplicates returns the number of records skipped because their key repeated an earlier one.
+func (l *Lookup) Duplicates() (int, error) {
+	table, err := l.shared()
+	if err != nil {
+		return 0, err
+	}
+	return table.duplicates, nil
+}
+
+// Close detaches the handle from the shared table. Afterwards its methods return an error.
+func (l *Lookup) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.table = nil
+}
+
+// Dispose is an alias of Close.
+func (l *Lookup) Dispose() {
+	l.Close()
+}
@@ -0,0 +1,141 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func resetSharedBuilds(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		sharedBuilds.mu.Lock()
+		sharedBuilds.builds = make(map[string]*sharedBuild)
+		sharedBuilds.mu.Unlock()
+	})
+}
+
+func TestBuildLookup(t *testing.T) {
+	resetSharedBuilds(t)
+	path := filepath.Join(t.TempDir(), "tokens.ndjson")
+	content := `{"user":{"id":1},"token":"a"}
+{"user":{"id":"2"},"token":"b"}
+{"user":{"id":1},"token":"dup"}
+{"token":"no key"}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lookup, err := StreamLoader{}.BuildLookup(path, "user.id")
+	if err != nil {
+		t.Fatalf("BuildLookup() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  interface{}
+		want interface{}
+	}{
+		{"number key", int64(1), map[string]interface{}{"user": map[string]interface{}{"id": float64(1)}, "token": "a"}},
+		{"string key", "1", map[string]interface{}{"user": map[string]interface{}{"id": float64(1)}, "token": "a"}},
+		{"string value matched by number", float64(2), map[string]interface{}{"user": map[string]interface{}{"id": "2"}, "token": "b"}},
+		{"missing", "9", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookup.Get(tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%v) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	if size, _ := lookup.Size(); size != 2 {
+		t.Errorf("Size() = %d, want 2", size)
+	}
+	if dups, _ := lookup.Duplicates(); dups != 1 {
+		t.Errorf("Duplicates() = %d, want 1", dups)
+	}
+	if ok, _ := lookup.Has(2); !ok {
+		t.Error("Has(2) = false")
+	}
+
+	// Returned records are copies, so changing one doesn't affect other VUs
+	first, _ := lookup.Get(1)
+	first.(map[string]interface{})["token"] = "changed"
+	if again, _ := lookup.Get(1); again.(map[string]interface{})["token"] != "a" {
+		t.Error("changing a returned record changed the table")
+	}
+
+	lookup.Close()
+	if _, err := lookup.Get(1); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Get() after Close error = %v", err)
+	}
+}
+
+func TestBuildLookupShared(t *testing.T) {
+	resetSharedBuilds(t)
+	path, _ := writeDataset(t, t.TempDir(), "users", 1000)
+
+	var wg sync.WaitGroup
+	handles := make([]*Lookup, 8)
+	errs := make([]error, len(handles))
+	for i := range handles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			handles[i], errs[i] = StreamLoader{}.BuildLookup(path, "id")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, h := range handles {
+		if errs[i] != nil {
+			t.Fatalf("BuildLookup() error = %v", errs[i])
+		}
+		if h.table != handles[0].table {
+			t.Fatal("VUs got separate tables for the same file and key")
+		}
+	}
+	if size, _ := handles[0].Size(); size != 1000 {
+		t.Errorf("Size() = %d, want 1000", size)
+	}
+
+	// Closing one handle leaves the table to the others
+	handles[0].Close()
+	if ok, err := handles[1].Has(999); err != nil || !ok {
+		t.Errorf("Has(999) = %v, %v after another handle was closed", ok, err)
+	}
+}
+
+func TestBuildLookupErrors(t *testing.T) {
+	resetSharedBuilds(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "later.json")
+
+	if _, err := (StreamLoader{}).BuildLookup(path, ""); err == nil || !strings.Contains(err.Error(), "keyField is required") {
+		t.Errorf("BuildLookup() without key error = %v", err)
+	}
+	if _, err := (StreamLoader{}).BuildLookup(path, "id"); err == nil || !strings.Contains(err.Error(), "failed to build lookup") {
+		t.Errorf("BuildLookup() of a missing file error = %v", err)
+	}
+
+	// A failed build is retried once the file exists
+	if err := os.WriteFile(path, []byte(`[{"id":1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lookup, err := StreamLoader{}.BuildLookup(path, "id")
+	if err != nil {
+		t.Fatalf("BuildLookup() retry error = %v", err)
+	}
+	if ok, _ := lookup.Has(1); !ok {
+		t.Error("Has(1) = false after retry")
+	}
+}
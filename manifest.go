@@ -0,0 +1,282 @@
+// manifest.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestDataset is a dataset of a scenario manifest
+type ManifestDataset struct {
+	Path    string      `json:"path" js:"path"`
+	Options JsonOptions `json:"options" js:"options"`
+	Filter  interface{} `json:"filter" js:"filter"`
+}
+
+// ManifestWeights is a weighted distribution of a scenario manifest
+type ManifestWeights struct {
+	Path    string `json:"path" js:"path"`
+	Dataset string `json:"dataset" js:"dataset"`
+	Field   string `json:"field" js:"field"`
+	Seed    *int64 `json:"seed" js:"seed"`
+}
+
+// ManifestScenario is a scenario of a scenario manifest
+type ManifestScenario struct {
+	Dataset string      `json:"dataset" js:"dataset"`
+	Filter  interface{} `json:"filter" js:"filter"`
+	Slice   []float64   `json:"slice" js:"slice"`
+	Weights string      `json:"weights" js:"weights"`
+}
+
+// scenarioManifestFile is the content of a scenario manifest
+type scenarioManifestFile struct {
+	Datasets  map[string]ManifestDataset  `json:"datasets"`
+	Weights   map[string]ManifestWeights  `json:"weights"`
+	Scenarios map[string]ManifestScenario `json:"scenarios"`
+}
+
+// ScenarioData is what a scenario of a manifest uses
+type ScenarioData struct {
+	Data    []interface{}    `json:"data" js:"data"`
+	Weights *WeightedSampler `json:"-" js:"weights"`
+}
+
+// ScenarioManifest is the result of LoadScenarioManifest
+type ScenarioManifest struct {
+	Datasets  map[string][]interface{}    `json:"datasets" js:"datasets"`
+	Weights   map[string]*WeightedSampler `json:"-" js:"weights"`
+	Scenarios map[string]*ScenarioData    `json:"scenarios" js:"scenarios"`
+}
+
+// sortedKeys returns the keys of a manifest section in order, so datasets load and errors are
+// reported in the same order every run.
+func sortedKeys[T any](section map[string]T) []string {
+	keys := make([]string, 0, len(section))
+	for key := range section {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordArray returns the records of a loaded dataset, which must be an array.
+func recordArray(data any) ([]interface{}, bool) {
+	switch v := data.(type) {
+	case []interface{}:
+		return v, true
+	case []map[string]any:
+		records := make([]interface{}, len(v))
+		for i, item := range v {
+			records[i] = item
+		}
+		return records, true
+	}
+	return nil, false
+}
+
+// filterRecords returns the records matching a filter of condition objects, as for
+// Iterator.Filter.
+func filterRecords(records []interface{}, filter interface{}) ([]interface{}, error) {
+	if filter == nil {
+		return records, nil
+	}
+	match, err := (&Iterator{}).compilePredicate(filter)
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		if ok, err := match(record); err != nil {
+			return nil, err
+		} else if ok {
+			kept = append(kept, record)
+		}
+	}
+	return kept, nil
+}
+
+// LoadScenarioManifest reads a manifest describing the data of a test and materializes all of
+// it in one call, so a standard test harness is bootstrapped from configuration instead of
+// loading, filtering and slicing code at the top of every script. The manifest is a YAML or
+// JSON object with three sections, each mapping names to entries; relative paths are resolved
+// from the manifest's directory, and unknown keys are errors to catch typos.
+//
+// datasets:
+//   - path: JSON array, NDJSON or CSV file (with a header row) of records (required)
+//   - options: Options of LoadJSON for JSON files, e.g. decodeFields or dropExpired
+//   - filter: Condition object or array of condition objects that records must match, as for
+//     Iterator.Filter, e.g. { field: "active", op: "eq", value: true }
+//
+// weights:
+//   - path: Stats file, as for CompileWeights, or dataset: name of a dataset whose records are
+//     the categories
+//   - field: Field (or dotted path) holding each category's weight (required)
+//   - seed: Random seed to make the draws repeatable
+//
+// scenarios:
+//   - dataset: Name of the dataset the scenario uses
+//   - filter: Further conditions the scenario's records must match
+//   - slice: [fromPct, toPct] part of the (filtered) records, as for SlicePercent, so scenarios
+//     can use disjoint parts of a dataset (default: [0, 100])
+//   - weights: Name of the weights the scenario draws from
+//
+// Returns: The datasets by name as arrays, the weights by name as samplers like CompileWeights
+// returns, and the scenarios by name, each with its data and weights
+//
+// Example usage:
+//
+//	// manifest.yaml:
+//	// datasets:
+//	//   users: { path: users.csv, filter: { field: active, op: eq, value: "true" } }
+//	// weights:
+//	//   mix: { path: recording-stats.json, field: weight }
+//	// scenarios:
+//	//   browse: { dataset: users, slice: [0, 80], weights: mix }
+//	//   checkout: { dataset: users, slice: [80, 100] }
+//	const manifest = streamloader.loadScenarioManifest("manifest.yaml");
+//	// In the browse scenario:
+//	const { data, weights } = manifest.scenarios.browse;
+//	const user = data[exec.scenario.iterationInTest % data.length];
+//	const endpoint = weights.next();
+func (s StreamLoader) LoadScenarioManifest(path string) (*ScenarioManifest, error) {
+	data, err := readInputFile(path, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario manifest: %w", err)
+	}
+	var doc interface{}
+	if trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM)); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scenario manifest %s: %w", path, err)
+	}
+	encoded, err := json.Marshal(normalizeYAML(doc))
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario manifest %s: %w", path, err)
+	}
+	var manifest scenarioManifestFile
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid scenario manifest %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	result := &ScenarioManifest{
+		Datasets:  make(map[string][]interface{}),
+		Weights:   make(map[string]*WeightedSampler),
+		Scenarios: make(map[string]*ScenarioData),
+	}
+
+	for _, name := range sortedKeys(manifest.Datasets) {
+		dataset := manifest.Datasets[name]
+		if dataset.Path == "" {
+			return nil, fmt.Errorf("dataset %q: path is required", name)
+		}
+		filePath := resolve(dataset.Path)
+		var records []interface{}
+		if isCsvPath(filePath) {
+			open := func(filePath string) (*datasetRecords, error) {
+				return openDatasetRecords(filePath, false, nil, s.call)
+			}
+			records = make([]interface{}, 0)
+			err = forEachDatasetRecord(open, filePath, func(_ int, record interface{}) error {
+				records = append(records, record)
+				return nil
+			})
+		} else {
+			var loaded any
+			if loaded, err = s.LoadJSON(filePath, dataset.Options); err == nil {
+				var ok bool
+				if records, ok = recordArray(loaded); !ok {
+					err = fmt.Errorf("%s is not an array or NDJSON file of records", filePath)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dataset %q: %w", name, err)
+		}
+		if records, err = filterRecords(records, dataset.Filter); err != nil {
+			return nil, fmt.Errorf("dataset %q: %w", name, err)
+		}
+		result.Datasets[name] = records
+	}
+
+	for _, name := range sortedKeys(manifest.Weights) {
+		weights := manifest.Weights[name]
+		var source interface{}
+		switch {
+		case weights.Path != "" && weights.Dataset != "":
+			return nil, fmt.Errorf("weights %q: path and dataset are exclusive", name)
+		case weights.Path != "":
+			source = resolve(weights.Path)
+		case weights.Dataset != "":
+			records, ok := result.Datasets[weights.Dataset]
+			if !ok {
+				return nil, fmt.Errorf("weights %q: unknown dataset %q", name, weights.Dataset)
+			}
+			source = records
+		default:
+			return nil, fmt.Errorf("weights %q: path or dataset is required", name)
+		}
+		var seed []int64
+		if weights.Seed != nil {
+			seed = append(seed, *weights.Seed)
+		}
+		sampler, err := s.CompileWeights(source, weights.Field, seed...)
+		if err != nil {
+			return nil, fmt.Errorf("weights %q: %w", name, err)
+		}
+		result.Weights[name] = sampler
+	}
+
+	for _, name := range sortedKeys(manifest.Scenarios) {
+		scenario := manifest.Scenarios[name]
+		sd := &ScenarioData{}
+		if scenario.Dataset != "" {
+			records, ok := result.Datasets[scenario.Dataset]
+			if !ok {
+				return nil, fmt.Errorf("scenario %q: unknown dataset %q", name, scenario.Dataset)
+			}
+			if records, err = filterRecords(records, scenario.Filter); err != nil {
+				return nil, fmt.Errorf("scenario %q: %w", name, err)
+			}
+			if scenario.Slice != nil {
+				if len(scenario.Slice) != 2 {
+					return nil, fmt.Errorf("scenario %q: slice must be [fromPct, toPct], got %v", name, scenario.Slice)
+				}
+				start, end, err := percentRange(len(records), scenario.Slice[0], scenario.Slice[1])
+				if err != nil {
+					return nil, fmt.Errorf("scenario %q: %w", name, err)
+				}
+				records = records[start:end]
+			}
+			sd.Data = records
+		} else if scenario.Filter != nil || scenario.Slice != nil {
+			return nil, fmt.Errorf("scenario %q: filter and slice need a dataset", name)
+		}
+		if scenario.Weights != "" {
+			sampler, ok := result.Weights[scenario.Weights]
+			if !ok {
+				return nil, fmt.Errorf("scenario %q: unknown weights %q", name, scenario.Weights)
+			}
+			sd.Weights = sampler
+		}
+		result.Scenarios[name] = sd
+	}
+	return result, nil
+}
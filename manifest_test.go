@@ -0,0 +1,111 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadScenarioManifest(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "users.csv"), []byte("id,active\n1,true\n2,false\n3,true\n4,true\n5,true\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "data"), 0755)
+	os.WriteFile(filepath.Join(dir, "data", "products.ndjson"), []byte("{\"sku\":\"a\",\"stock\":0}\n{\"sku\":\"b\",\"stock\":3}\n{\"sku\":\"c\",\"stock\":9}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "stats.json"), []byte(`{"filterStats":[{"uri":"/a","weight":1},{"uri":"/b","weight":0}]}`), 0644)
+	manifest := filepath.Join(dir, "manifest.yaml")
+	os.WriteFile(manifest, []byte(`
+datasets:
+  users:
+    path: users.csv
+    filter: {field: active, op: eq, value: "true"}
+  products:
+    path: data/products.ndjson
+    options: {detectDuplicateKeys: true}
+weights:
+  mix: {path: stats.json, field: weight, seed: 1}
+  skus: {dataset: products, field: stock}
+scenarios:
+  browse:
+    dataset: users
+    slice: [0, 75]
+    weights: mix
+  checkout:
+    dataset: users
+    slice: [75, 100]
+  restock:
+    dataset: products
+    filter: [{field: stock, op: lt, value: 5}]
+    weights: skus
+  idle: {}
+`), 0644)
+
+	result, err := loader.LoadScenarioManifest(manifest)
+	if err != nil {
+		t.Fatalf("LoadScenarioManifest() error = %v", err)
+	}
+	ids := func(records []interface{}) []string {
+		list := []string{}
+		for _, r := range records {
+			list = append(list, r.(map[string]interface{})["id"].(string))
+		}
+		return list
+	}
+	if got := ids(result.Datasets["users"]); !reflect.DeepEqual(got, []string{"1", "3", "4", "5"}) {
+		t.Errorf("users = %v", got)
+	}
+	if len(result.Datasets["products"]) != 3 {
+		t.Errorf("products = %v", result.Datasets["products"])
+	}
+	browse, checkout := result.Scenarios["browse"], result.Scenarios["checkout"]
+	if got := ids(browse.Data); !reflect.DeepEqual(got, []string{"1", "3", "4"}) {
+		t.Errorf("browse data = %v", got)
+	}
+	if got := ids(checkout.Data); !reflect.DeepEqual(got, []string{"5"}) || checkout.Weights != nil {
+		t.Errorf("checkout = %v, %v", got, checkout.Weights)
+	}
+	if browse.Weights != result.Weights["mix"] || browse.Weights.Next().(map[string]interface{})["uri"] != "/a" {
+		t.Errorf("browse weights = %v", browse.Weights)
+	}
+	restock := result.Scenarios["restock"]
+	if len(restock.Data) != 2 || restock.Weights.Next().(map[string]interface{})["sku"] == "a" {
+		t.Errorf("restock = %+v", restock)
+	}
+	if idle := result.Scenarios["idle"]; idle.Data != nil || idle.Weights != nil {
+		t.Errorf("idle = %+v", idle)
+	}
+
+	// A JSON manifest
+	jsonManifest := filepath.Join(dir, "manifest.json")
+	os.WriteFile(jsonManifest, []byte(`{"datasets":{"products":{"path":"data/products.ndjson"}},"scenarios":{"all":{"dataset":"products"}}}`), 0644)
+	if result, err := loader.LoadScenarioManifest(jsonManifest); err != nil || len(result.Scenarios["all"].Data) != 3 {
+		t.Errorf("LoadScenarioManifest(json) = %+v, %v", result, err)
+	}
+
+	// Errors
+	for _, content := range []string{
+		`datasets: {users: {path: users.csv, filtr: {}}}`,
+		`datasets: {users: {}}`,
+		`datasets: {users: {path: missing.json}}`,
+		`datasets: {users: {path: users.csv, filter: {field: id, op: bogus}}}`,
+		`weights: {mix: {field: weight}}`,
+		`weights: {mix: {dataset: missing, field: weight}}`,
+		`weights: {mix: {path: stats.json}}`,
+		`scenarios: {browse: {dataset: missing}}`,
+		`scenarios: {browse: {weights: missing}}`,
+		`scenarios: {browse: {slice: [0, 10]}}`,
+		`{datasets: {users: {path: users.csv}}, scenarios: {browse: {dataset: users, slice: [50]}}}`,
+		`{datasets: {users: {path: users.csv}}, scenarios: {browse: {dataset: users, slice: [50, 10]}}}`,
+		`[1, 2]`,
+	} {
+		invalid := filepath.Join(dir, "invalid.yaml")
+		os.WriteFile(invalid, []byte(content), 0644)
+		if _, err := loader.LoadScenarioManifest(invalid); err == nil {
+			t.Errorf("LoadScenarioManifest(%s) expected an error", content)
+		}
+	}
+	if _, err := loader.LoadScenarioManifest(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadScenarioManifest(missing) expected an error")
+	}
+}
@@ -0,0 +1,176 @@
+// metadata.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// datasetMetadataKey is the only key of the header record holding a dataset's metadata
+const datasetMetadataKey = "$dataset"
+
+// DatasetMetadataOptions is the metadata writer option: what to record about the dataset
+type DatasetMetadataOptions struct {
+	Name    string      `json:"name" js:"name"`
+	Version string      `json:"version" js:"version"`
+	Options interface{} `json:"options" js:"options"` // The generator's options, recorded as a hash
+}
+
+// DatasetMetadata is the metadata embedded in a dataset, as returned by ReadDatasetMetadata
+type DatasetMetadata struct {
+	Name        string `json:"name" js:"name"`
+	Version     string `json:"version" js:"version"`
+	OptionsHash string `json:"optionsHash,omitempty" js:"optionsHash"`
+	CreatedAt   string `json:"createdAt" js:"createdAt"`
+}
+
+// parseDatasetMetadataOptions reads the metadata writer option from a script object.
+func parseDatasetMetadataOptions(v interface{}) (*DatasetMetadataOptions, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid metadata option: expected an object, got %T", v)
+	}
+	opts := &DatasetMetadataOptions{Options: m["options"]}
+	opts.Name, _ = m["name"].(string)
+	opts.Version, _ = m["version"].(string)
+	return opts, nil
+}
+
+// datasetHeader returns the header record embedding the metadata, created now.
+func datasetHeader(opts *DatasetMetadataOptions) ([]byte, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("metadata name must not be empty")
+	}
+	meta := DatasetMetadata{Name: opts.Name, Version: opts.Version, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	if opts.Options != nil {
+		// Maps are encoded with sorted keys, so equal options hash the same
+		encoded, err := json.Marshal(opts.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash metadata options: %w", err)
+		}
+		sum := sha256.Sum256(encoded)
+		meta.OptionsHash = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return json.Marshal(map[string]DatasetMetadata{datasetMetadataKey: meta})
+}
+
+// isDatasetHeader reports whether a decoded record is a metadata header.
+func isDatasetHeader(record interface{}) bool {
+	obj, ok := record.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return false
+	}
+	_, ok = obj[datasetMetadataKey].(map[string]interface{})
+	return ok
+}
+
+// isRawDatasetHeader reports whether an encoded record is a metadata header, without decoding
+// other records.
+func isRawDatasetHeader(raw json.RawMessage) bool {
+	if !bytes.Contains(raw, []byte(`"`+datasetMetadataKey+`"`)) {
+		return false
+	}
+	var record interface{}
+	return json.Unmarshal(raw, &record) == nil && isDatasetHeader(record)
+}
+
+// headerWriter inserts a header record as the first element of the JSON array written through
+// it, so every JSON array writer can embed metadata without knowing about it.
+type headerWriter struct {
+	w      io.Writer
+	header []byte
+	state  int // 0 before the opening bracket, 1 after it, 2 once the header is written
+}
+
+func (h *headerWriter) Write(p []byte) (int, error) {
+	for i := 0; i < len(p) && h.state < 2; i++ {
+		if isWhitespace(p[i]) {
+			continue
+		}
+		if h.state == 0 {
+			if p[i] != '[' {
+				return 0, fmt.Errorf("the metadata option needs JSON array output")
+			}
+			h.state = 1
+			continue
+		}
+		// The first byte after the opening bracket: the header goes before it
+		h.state = 2
+		insert := h.header
+		if p[i] != ']' {
+			insert = append(append([]byte(nil), h.header...), ',')
+		}
+		if _, err := h.w.Write(p[:i]); err != nil {
+			return 0, err
+		}
+		if _, err := h.w.Write(insert); err != nil {
+			return 0, err
+		}
+		n, err := h.w.Write(p[i:])
+		return i + n, err
+	}
+	return h.w.Write(p)
+}
+
+// ReadDatasetMetadata returns the metadata embedded in a JSON array file by a writer's metadata
+// option: the dataset's name and version, the hash of the generator options and when it was
+// written, so a script can check it was given the dataset it expects. Gzip-compressed files are
+// decompressed. Returns null if the file has no metadata.
+//
+// Loaders skip the metadata header, so datasets with metadata load as before.
+//
+// Example usage:
+//
+//	streamloader.writeObjectsToJsonArrayFile(users, "users.json", { metadata: { name: "users", version: "2024-10-16", options: genOptions } });
+//	const meta = streamloader.readDatasetMetadata("users.json");
+//	if (!meta || meta.version !== "2024-10-16") throw new Error("stale users dataset");
+func (s StreamLoader) ReadDatasetMetadata(filePath string) (*DatasetMetadata, error) {
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReaderSize(countingReader{file}, readBufferSize())
+	var source io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	dec := json.NewDecoder(source)
+	t, err := dec.Token()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	if t != json.Delim('[') || !dec.More() {
+		return nil, nil
+	}
+	var first json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	if !isRawDatasetHeader(first) {
+		return nil, nil
+	}
+	var header map[string]DatasetMetadata
+	if err := json.Unmarshal(first, &header); err != nil {
+		return nil, fmt.Errorf("invalid metadata in %s: %w", filePath, err)
+	}
+	meta := header[datasetMetadataKey]
+	return &meta, nil
+}
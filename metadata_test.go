@@ -0,0 +1,94 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDatasetMetadata(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	metadata := map[string]interface{}{
+		"name":    "users",
+		"version": "2024-10-16",
+		"options": map[string]interface{}{"seed": int64(42), "count": int64(2)},
+	}
+	objects := []interface{}{map[string]interface{}{"id": int64(1)}, map[string]interface{}{"id": int64(2)}}
+
+	tests := []struct {
+		name    string
+		file    string
+		options map[string]interface{}
+		objects []interface{}
+	}{
+		{"plain", "users.json", map[string]interface{}{"metadata": metadata}, objects},
+		{"gzip", "users.json.gz", map[string]interface{}{"metadata": metadata, "compressOutput": "gzip"}, objects},
+		{"empty", "empty.json", map[string]interface{}{"metadata": metadata}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			count, err := loader.WriteObjectsToJsonArrayFile(tt.objects, path, tt.options)
+			if err != nil || count != len(tt.objects) {
+				t.Fatalf("WriteObjectsToJsonArrayFile() = %d, %v, want %d", count, err, len(tt.objects))
+			}
+			meta, err := loader.ReadDatasetMetadata(path)
+			if err != nil || meta == nil {
+				t.Fatalf("ReadDatasetMetadata() = %v, %v", meta, err)
+			}
+			if meta.Name != "users" || meta.Version != "2024-10-16" || !strings.HasPrefix(meta.OptionsHash, "sha256:") || meta.CreatedAt == "" {
+				t.Errorf("ReadDatasetMetadata() = %+v", meta)
+			}
+		})
+	}
+
+	// The options hash only depends on the options
+	other := filepath.Join(dir, "other.json")
+	loader.WriteObjectsToJsonArrayFile(objects, other, map[string]interface{}{"metadata": map[string]interface{}{
+		"name": "users", "options": map[string]interface{}{"count": int64(2), "seed": int64(42)},
+	}})
+	first, _ := loader.ReadDatasetMetadata(filepath.Join(dir, "users.json"))
+	second, _ := loader.ReadDatasetMetadata(other)
+	if first.OptionsHash != second.OptionsHash {
+		t.Errorf("OptionsHash = %s and %s, want equal hashes for equal options", first.OptionsHash, second.OptionsHash)
+	}
+
+	// Loaders skip the header
+	data, _ := os.ReadFile(filepath.Join(dir, "users.json"))
+	if !strings.HasPrefix(string(data), `[{"$dataset":{`) {
+		t.Errorf("File starts with %.30s, want the header record", data)
+	}
+	loaded, err := loader.LoadJSON(filepath.Join(dir, "users.json"))
+	if err != nil || len(loaded.([]interface{})) != 2 {
+		t.Errorf("LoadJSON() = %v, %v, want the 2 records", loaded, err)
+	}
+	var ids []string
+	forEachJsonRecord(filepath.Join(dir, "users.json"), callLimits{}, func(raw json.RawMessage) (bool, error) {
+		ids = append(ids, string(raw))
+		return true, nil
+	})
+	if strings.Join(ids, ",") != `{"id":1},{"id":2}` {
+		t.Errorf("Records = %v, want the 2 records", ids)
+	}
+	if loaded, _ := loader.LoadJSON(filepath.Join(dir, "empty.json")); len(loaded.([]interface{})) != 0 {
+		t.Errorf("LoadJSON() = %v, want no records", loaded)
+	}
+
+	// Files without metadata
+	plain := filepath.Join(dir, "plain.json")
+	loader.WriteObjectsToJsonArrayFile(objects, plain)
+	if meta, err := loader.ReadDatasetMetadata(plain); meta != nil || err != nil {
+		t.Errorf("ReadDatasetMetadata() = %v, %v, want nil", meta, err)
+	}
+
+	// Errors
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, plain, map[string]interface{}{"metadata": map[string]interface{}{"version": "1"}}); err == nil {
+		t.Error("Expected error for metadata without a name")
+	}
+	if _, err := loader.WriteObjectsToJsonSeqFile(objects, filepath.Join(dir, "users.seq"), map[string]interface{}{"metadata": metadata}); err == nil {
+		t.Error("Expected error for metadata on JSON sequence output")
+	}
+}
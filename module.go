@@ -0,0 +1,40 @@
+// module.go
+package streamloader
+
+import (
+	"go.k6.io/k6/js/modules"
+)
+
+// RootModule is the global module object; it creates a StreamLoader for every VU.
+type RootModule struct{}
+
+// ModuleInstance is the per-VU instance of the module
+type ModuleInstance struct {
+	loader *StreamLoader
+}
+
+var (
+	_ modules.Module   = &RootModule{}
+	_ modules.Instance = &ModuleInstance{}
+)
+
+// NewModuleInstance implements modules.Module. Functions that create JavaScript values, such as
+// ArrayBuffers, need the VU's runtime.
+func (*RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
+	return &ModuleInstance{loader: &StreamLoader{vu: vu}}
+}
+
+// Exports implements modules.Instance. Every function is wrapped to count its calls for
+// GetRuntimeStats.
+func (mi *ModuleInstance) Exports() modules.Exports {
+	return modules.Exports{Default: exportsWithCallCounts(mi.loader)}
+}
+
+// newArrayBuffer wraps data in a JavaScript ArrayBuffer without copying it. Outside a VU, as in
+// Go tests, the bytes are returned as they are.
+func (s StreamLoader) newArrayBuffer(data []byte) interface{} {
+	if s.vu == nil {
+		return data
+	}
+	return s.vu.Runtime().NewArrayBuffer(data)
+}
@@ -0,0 +1,151 @@
+// normalize_text.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// NormalizeTextOptions represents options for NormalizeTextFile
+type NormalizeTextOptions struct {
+	Encoding              string `json:"encoding" js:"encoding"`
+	Newline               string `json:"newline" js:"newline"`
+	StripBOM              bool   `json:"stripBOM" js:"stripBOM"`
+	EnsureTrailingNewline bool   `json:"ensureTrailingNewline" js:"ensureTrailingNewline"`
+}
+
+// NormalizeTextResult summarizes a NormalizeTextFile run
+type NormalizeTextResult struct {
+	Lines        int   `json:"lines" js:"lines"`
+	BytesWritten int64 `json:"bytesWritten" js:"bytesWritten"`
+}
+
+// textDecoder returns a decoder from the named encoding to UTF-8. A UTF-16 byte order mark
+// takes precedence over the name, since it identifies the encoding for certain.
+func textDecoder(name string) (transform.Transformer, error) {
+	enc := encoding.Encoding(unicode.UTF8)
+	if name != "" {
+		var err error
+		if enc, err = htmlindex.Get(name); err != nil {
+			return nil, fmt.Errorf("unknown encoding %q", name)
+		}
+	}
+	return unicode.BOMOverride(enc.NewDecoder()), nil
+}
+
+// NormalizeTextFile converts a text file to UTF-8 with uniform line endings, streaming it so
+// files of any size can be canonicalized before other tools or loaders read them.
+//
+// Options:
+//   - encoding: Encoding of the input, by WHATWG name, e.g. "utf-8", "utf-16le",
+//     "windows-1252", "iso-8859-1" or "shift_jis" (default: "utf-8"). A UTF-16 byte order
+//     mark overrides it. Invalid input is replaced with U+FFFD.
+//   - newline: Line endings of the output: "lf", "crlf" or "keep" (default: "lf"). "\r\n"
+//     and lone "\r" both count as line endings.
+//   - stripBOM: Drop the byte order mark; otherwise an input with a byte order mark gets a
+//     UTF-8 one (default: false)
+//   - ensureTrailingNewline: End a non-empty output with a line ending (default: false)
+//
+// Returns: The number of lines and bytes written
+//
+// Example usage:
+//
+//	result, err := streamloader.NormalizeTextFile("export.csv", "export-utf8.csv", NormalizeTextOptions{Encoding: "windows-1252", StripBOM: true})
+func (s StreamLoader) NormalizeTextFile(inputFilePath string, outputFilePath string, options ...NormalizeTextOptions) (*NormalizeTextResult, error) {
+	var opts NormalizeTextOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	newline := strings.ToLower(opts.Newline)
+	switch newline {
+	case "", "lf", "crlf", "keep":
+	default:
+		return nil, fmt.Errorf("invalid newline option %q: expected lf, crlf or keep", opts.Newline)
+	}
+	decoder, err := textDecoder(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := openSequential(inputFilePath, "", s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer input.Close()
+	raw := bufio.NewReaderSize(input, readBufferSize())
+	hasBOM := false
+	if prefix, _ := raw.Peek(3); bytes.HasPrefix(prefix, utf8BOM) || bytes.HasPrefix(prefix, []byte{0xFE, 0xFF}) || bytes.HasPrefix(prefix, []byte{0xFF, 0xFE}) {
+		hasBOM = true
+	}
+	// The decoder consumes any byte order mark
+	reader := bufio.NewReaderSize(transform.NewReader(raw, decoder), readBufferSize())
+	source := newLineNormalizer(reader, false, newline != "keep")
+
+	output, err := createOutputFile(outputFilePath, JsonWriterOptions{}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+	writer := bufio.NewWriterSize(output, writeBufferSize())
+
+	result := &NormalizeTextResult{}
+	if hasBOM && !opts.StripBOM {
+		writer.Write(utf8BOM)
+		result.BytesWritten += int64(len(utf8BOM))
+	}
+	buf := make([]byte, readBufferSize())
+	var last byte
+	empty := true
+	for {
+		n, err := source.Read(buf)
+		for _, c := range buf[:n] {
+			if c == '\n' {
+				result.Lines++
+				if newline == "crlf" {
+					writer.WriteByte('\r')
+					result.BytesWritten++
+				}
+			}
+			writer.WriteByte(c)
+		}
+		result.BytesWritten += int64(n)
+		if n > 0 {
+			last = buf[n-1]
+			empty = false
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	if !empty && last != '\n' {
+		if opts.EnsureTrailingNewline {
+			if newline == "crlf" {
+				writer.WriteByte('\r')
+				result.BytesWritten++
+			}
+			writer.WriteByte('\n')
+			result.BytesWritten++
+		}
+		// An unterminated last line is a line too
+		result.Lines++
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := output.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeTextFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    NormalizeTextOptions
+		want    string
+		lines   int
+		wantErr bool
+	}{
+		{
+			name:  "mixed line endings to lf",
+			input: "a\r\nb\rc\nd",
+			want:  "a\nb\nc\nd",
+			lines: 4,
+		},
+		{
+			name:  "crlf with trailing newline",
+			input: "a\nb\r\nc",
+			opts:  NormalizeTextOptions{Newline: "crlf", EnsureTrailingNewline: true},
+			want:  "a\r\nb\r\nc\r\n",
+			lines: 3,
+		},
+		{
+			name:  "keep line endings",
+			input: "a\r\nb\n",
+			opts:  NormalizeTextOptions{Newline: "keep", EnsureTrailingNewline: true},
+			want:  "a\r\nb\n",
+			lines: 2,
+		},
+		{
+			name:  "keep utf-8 bom",
+			input: "\xef\xbb\xbfid\n",
+			want:  "\xef\xbb\xbfid\n",
+			lines: 1,
+		},
+		{
+			name:  "strip utf-8 bom",
+			input: "\xef\xbb\xbfid\n",
+			opts:  NormalizeTextOptions{StripBOM: true},
+			want:  "id\n",
+			lines: 1,
+		},
+		{
+			name:  "utf-16le with bom",
+			input: "\xff\xfeh\x00i\x00\r\x00\n\x00",
+			opts:  NormalizeTextOptions{StripBOM: true},
+			want:  "hi\n",
+			lines: 1,
+		},
+		{
+			name:  "windows-1252",
+			input: "caf\xe9 \x80\n",
+			opts:  NormalizeTextOptions{Encoding: "windows-1252"},
+			want:  "café €\n",
+			lines: 1,
+		},
+		{
+			name:  "invalid utf-8 is replaced",
+			input: "a\xffb",
+			want:  "a�b",
+			lines: 1,
+		},
+		{
+			name:  "empty file",
+			input: "",
+			opts:  NormalizeTextOptions{EnsureTrailingNewline: true},
+			want:  "",
+		},
+		{
+			name:    "unknown encoding",
+			input:   "a",
+			opts:    NormalizeTextOptions{Encoding: "klingon"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid newline",
+			input:   "a",
+			opts:    NormalizeTextOptions{Newline: "cr"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(dir, "input.txt")
+			output := filepath.Join(dir, "output.txt")
+			if err := os.WriteFile(input, []byte(tt.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := loader.NormalizeTextFile(input, output, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTextFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if result.Lines != tt.lines || result.BytesWritten != int64(len(got)) {
+				t.Errorf("result = %+v, want %d lines and %d bytes", result, tt.lines, len(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// object_order.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OrderedObject is a JSON object loaded with the objectOrder option "keys": its data, and its
+// keys in file order
+type OrderedObject struct {
+	Keys []string       `json:"keys" js:"keys"`
+	Data map[string]any `json:"data" js:"data"`
+}
+
+// validateObjectOrder checks the objectOrder option of LoadJSON.
+func validateObjectOrder(order string) error {
+	switch order {
+	case "", "map", "keys", "entries":
+		return nil
+	}
+	return fmt.Errorf("invalid objectOrder option %q: expected map, keys or entries", order)
+}
+
+// objectKeyFilter selects the keys of a top-level JSON object to load
+type objectKeyFilter struct {
+	prefix  string
+	pattern *regexp.Regexp
+	max     int // 0 for no limit
+}
+
+// newObjectKeyFilter compiles the keyPrefix, keyRegex and maxKeys options of LoadJSON. It
+// returns nil if no keys are filtered.
+func newObjectKeyFilter(opts JsonOptions) (*objectKeyFilter, error) {
+	if opts.MaxKeys < 0 {
+		return nil, fmt.Errorf("maxKeys must not be negative, got %d", opts.MaxKeys)
+	}
+	if opts.KeyPrefix == "" && opts.KeyRegex == "" && opts.MaxKeys == 0 {
+		return nil, nil
+	}
+	filter := &objectKeyFilter{prefix: opts.KeyPrefix, max: opts.MaxKeys}
+	if opts.KeyRegex != "" {
+		pattern, err := regexp.Compile(opts.KeyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyRegex: %w", err)
+		}
+		filter.pattern = pattern
+	}
+	return filter, nil
+}
+
+// matches reports whether a key is selected by the prefix and the pattern.
+func (f *objectKeyFilter) matches(key string) bool {
+	if f == nil {
+		return true
+	}
+	return strings.HasPrefix(key, f.prefix) && (f.pattern == nil || f.pattern.MatchString(key))
+}
+
+// loadObject streams a top-level JSON object, decoding only the values of the keys the filter
+// selects and stopping once it has maxKeys of them, so a tenant's keys can be picked from a huge
+// keyed map without decoding the rest. The object is returned as a map, as an OrderedObject for
+// the objectOrder option "keys", or as [key, value] entries in file order for "entries". A key
+// that repeats keeps its first position and its last value, as when decoding into a map.
+func loadObject(dec *json.Decoder, opts JsonOptions, filter *objectKeyFilter) (any, error) {
+	if opts.DetectDuplicateKeys {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if err := checkDuplicateKeys(raw, "$"); err != nil {
+			return nil, err
+		}
+		dec = json.NewDecoder(bytes.NewReader(raw))
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	object := OrderedObject{Keys: make([]string, 0), Data: make(map[string]any)}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		if !filter.matches(key) {
+			// Skip the value without building it
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return nil, fmt.Errorf("failed to read value of %q: %w", key, err)
+			}
+			continue
+		}
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to decode value of %q: %w", key, err)
+		}
+		if _, seen := object.Data[key]; !seen {
+			object.Keys = append(object.Keys, key)
+		}
+		object.Data[key] = value
+		if filter != nil && filter.max > 0 && len(object.Keys) == filter.max {
+			// The rest of the file isn't read
+			return orderedResult(object, opts.ObjectOrder), nil
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return orderedResult(object, opts.ObjectOrder), nil
+}
+
+// orderedResult returns a loaded object in the shape requested by the objectOrder option.
+func orderedResult(object OrderedObject, order string) any {
+	switch order {
+	case "", "map":
+		return object.Data
+	case "keys":
+		return object
+	}
+	entries := make([]interface{}, len(object.Keys))
+	for i, key := range object.Keys {
+		entries[i] = []interface{}{key, object.Data[key]}
+	}
+	return entries
+}
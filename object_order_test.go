@@ -0,0 +1,167 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+const orderedCorpus = `{"zeta": {"n": 1}, "alpha": [2], "mid": "3", "beta": null}`
+
+func writeOrderedCorpus(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadJSONObjectOrder(t *testing.T) {
+	path := writeOrderedCorpus(t, orderedCorpus)
+	loader := StreamLoader{}
+
+	got, err := loader.LoadJSON(path, JsonOptions{ObjectOrder: "keys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := got.(OrderedObject)
+	if want := []string{"zeta", "alpha", "mid", "beta"}; !reflect.DeepEqual(object.Keys, want) {
+		t.Errorf("keys = %v, want %v", object.Keys, want)
+	}
+	if !reflect.DeepEqual(object.Data["zeta"], map[string]any{"n": float64(1)}) || object.Data["mid"] != "3" {
+		t.Errorf("data = %v", object.Data)
+	}
+
+	got, err = loader.LoadJSON(path, JsonOptions{ObjectOrder: "entries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		[]interface{}{"zeta", map[string]any{"n": float64(1)}},
+		[]interface{}{"alpha", []interface{}{float64(2)}},
+		[]interface{}{"mid", "3"},
+		[]interface{}{"beta", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	// The default and "map" keep returning a map; arrays ignore the option
+	for _, order := range []string{"", "map"} {
+		if got, err := loader.LoadJSON(path, JsonOptions{ObjectOrder: order}); err != nil || reflect.TypeOf(got) != reflect.TypeOf(map[string]any{}) {
+			t.Errorf("LoadJSON(objectOrder %q) = %T, %v", order, got, err)
+		}
+	}
+	array := writeOrderedCorpus(t, `[{"b": 1, "a": 2}]`)
+	if got, err := loader.LoadJSON(array, JsonOptions{ObjectOrder: "entries"}); err != nil || len(got.([]interface{})) != 1 {
+		t.Errorf("LoadJSON(array) = %v, %v", got, err)
+	}
+}
+
+func TestLoadJSONObjectOrderDuplicates(t *testing.T) {
+	path := writeOrderedCorpus(t, `{"b": 1, "a": 2, "b": 3}`)
+	loader := StreamLoader{}
+
+	got, err := loader.LoadJSON(path, JsonOptions{ObjectOrder: "entries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{[]interface{}{"b", float64(3)}, []interface{}{"a", float64(2)}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	if _, err := loader.LoadJSON(path, JsonOptions{ObjectOrder: "keys", DetectDuplicateKeys: true}); err == nil {
+		t.Error("expected a duplicate key error")
+	}
+	if _, err := loader.LoadJSON(path, JsonOptions{ObjectOrder: "sorted"}); err == nil {
+		t.Error("expected an error for an invalid objectOrder")
+	}
+	truncated := writeOrderedCorpus(t, `{"a": 1, "b": `)
+	if _, err := loader.LoadJSON(truncated, JsonOptions{ObjectOrder: "keys"}); err == nil {
+		t.Error("expected an error for a truncated object")
+	}
+}
+
+func TestLoadJSONObjectOrderJavaScript(t *testing.T) {
+	path := writeOrderedCorpus(t, orderedCorpus)
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Set("path", path); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		const { keys, data } = streamloader.loadJSON(path, { objectOrder: "keys" });
+		const entries = streamloader.loadJSON(path, { objectOrder: "entries" });
+		keys.map((k) => k + "=" + JSON.stringify(data[k])).join(";") + "|" + entries.map(([k]) => k).join(",");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != `zeta={"n":1};alpha=[2];mid="3";beta=null|zeta,alpha,mid,beta` {
+		t.Errorf("script result = %s", got)
+	}
+}
+
+func TestLoadJSONKeyFilter(t *testing.T) {
+	path := writeOrderedCorpus(t, `{"acme:1": {"n": 1}, "globex:1": {"n": 2}, "acme:22": {"n": 3}, "acme:3": [1, {"x": 2}], "initech:1": {"n": 5}}`)
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name string
+		opts JsonOptions
+		want []string
+	}{
+		{"prefix", JsonOptions{KeyPrefix: "acme:"}, []string{"acme:1", "acme:22", "acme:3"}},
+		{"regex", JsonOptions{KeyRegex: `:1$`}, []string{"acme:1", "globex:1", "initech:1"}},
+		{"prefix and regex", JsonOptions{KeyPrefix: "acme:", KeyRegex: `^\w+:\d$`}, []string{"acme:1", "acme:3"}},
+		{"max keys", JsonOptions{MaxKeys: 2}, []string{"acme:1", "globex:1"}},
+		{"prefix and max keys", JsonOptions{KeyPrefix: "acme:", MaxKeys: 2}, []string{"acme:1", "acme:22"}},
+		{"no match", JsonOptions{KeyPrefix: "umbrella:"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.ObjectOrder = "keys"
+			got, err := loader.LoadJSON(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			object := got.(OrderedObject)
+			if !reflect.DeepEqual(object.Keys, tt.want) || len(object.Data) != len(tt.want) {
+				t.Errorf("keys = %v, data = %v, want keys %v", object.Keys, object.Data, tt.want)
+			}
+		})
+	}
+
+	// Without objectOrder the selected keys come back as a map
+	got, err := loader.LoadJSON(path, JsonOptions{KeyPrefix: "globex:"})
+	if want := map[string]any{"globex:1": map[string]any{"n": float64(2)}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadJSON(keyPrefix) = %v, %v, want %v", got, err, want)
+	}
+
+	for _, bad := range []JsonOptions{{KeyRegex: "("}, {MaxKeys: -1}} {
+		if _, err := loader.LoadJSON(path, bad); err == nil {
+			t.Errorf("LoadJSON(%+v) expected an error", bad)
+		}
+	}
+}
+
+func TestLoadJSONMaxKeysStopsReading(t *testing.T) {
+	// Reading stops after maxKeys keys, so the truncated tail is never parsed
+	path := writeOrderedCorpus(t, `{"a": 1, "b": 2, "c": [tru`)
+	got, err := StreamLoader{}.LoadJSON(path, JsonOptions{MaxKeys: 2, ObjectOrder: "entries"})
+	if want := []interface{}{[]interface{}{"a", float64(1)}, []interface{}{"b", float64(2)}}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadJSON(maxKeys) = %v, %v, want %v", got, err, want)
+	}
+	if _, err := (StreamLoader{}).LoadJSON(path, JsonOptions{MaxKeys: 3}); err == nil {
+		t.Error("expected an error reading past the truncation")
+	}
+}
@@ -0,0 +1,831 @@
+// openapi.go
+package streamloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxOpenAPIErrors caps the errors listed per record in an OpenAPIProblem
+const maxOpenAPIErrors = 10
+
+// maxSchemaDepth bounds the nesting of schemas, so a schema referring to itself without
+// descending into the value fails instead of recursing forever
+const maxSchemaDepth = 64
+
+// openAPIMethods are the operations of a path item
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// uuidPattern matches the uuid string format
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// OpenAPIValidationOptions names the record fields ValidateAgainstOpenAPI checks
+type OpenAPIValidationOptions struct {
+	PathField   string `json:"pathField" js:"pathField"`
+	MethodField string `json:"methodField" js:"methodField"`
+	BodyField   string `json:"bodyField" js:"bodyField"`
+	MaxReported int    `json:"maxReported" js:"maxReported"`
+}
+
+// OpenAPIReport is the result of ValidateAgainstOpenAPI
+type OpenAPIReport struct {
+	Valid      bool             `json:"valid" js:"valid"`
+	Records    int              `json:"records" js:"records"`
+	Invalid    int              `json:"invalid" js:"invalid"`
+	Operations map[string]int   `json:"operations" js:"operations"` // Valid records per operation
+	Problems   []OpenAPIProblem `json:"problems" js:"problems"`
+}
+
+// OpenAPIProblem describes a record the API would reject
+type OpenAPIProblem struct {
+	Index     int      `json:"index" js:"index"`
+	Method    string   `json:"method" js:"method"`
+	URL       string   `json:"url" js:"url"`
+	Operation string   `json:"operation,omitempty" js:"operation"` // operationId, or "METHOD /template"
+	Errors    []string `json:"errors" js:"errors"`
+}
+
+// openAPIRoute is a path template of a spec with the pattern matching its paths.
+type openAPIRoute struct {
+	template string
+	pattern  *regexp.Regexp
+	params   []string // Names of the template's parameters, in pattern group order
+	item     map[string]interface{}
+}
+
+// openAPISpec is a parsed OpenAPI document.
+type openAPISpec struct {
+	root     map[string]interface{}
+	bases    []string // Path prefixes of the servers, longest first
+	routes   []openAPIRoute
+	patterns map[string]*regexp.Regexp
+}
+
+// openAPIErrors collects the errors of one record, up to maxOpenAPIErrors.
+type openAPIErrors struct {
+	list []string
+}
+
+func (e *openAPIErrors) add(format string, args ...interface{}) {
+	if len(e.list) < maxOpenAPIErrors {
+		e.list = append(e.list, fmt.Sprintf(format, args...))
+	}
+}
+
+// ValidateAgainstOpenAPI streams the records of a JSON array or NDJSON request corpus and
+// checks each against an OpenAPI 3 spec, so a test doesn't spend its load on requests the API
+// rejects outright. A record's URL must match a path of the spec, below the path of one of its
+// servers, and the method must be an operation of that path. Path and query parameters and the
+// JSON request body are then checked against their schemas.
+//
+// openapiSpec is the path of the spec as JSON or YAML, or the spec as an object. Only local
+// $refs ("#/components/...") are followed. Schemas are checked for type, nullable, enum,
+// const, properties, required (except readOnly properties), additionalProperties, items, the
+// length, size and range keywords, pattern, multipleOf, allOf, anyOf, oneOf and not, and the
+// date, date-time and uuid formats; other keywords and formats are ignored. Header and cookie
+// parameters are not checked.
+//
+// Options:
+//   - pathField: Field (or dotted path) holding the URL, a full URL or a path with an optional
+//     query string (default: "url")
+//   - methodField: Field holding the HTTP method; records without it are GET (default: "method")
+//   - bodyField: Field holding the body, an object or array, or a string parsed as JSON when
+//     the operation takes JSON; missing or null means no body (default: "body")
+//   - maxReported: Number of invalid records listed (default: 100)
+//
+// Returns: Whether every record is valid, the number of records and of invalid records, the
+// number of valid records per operation, and the first invalid records with their errors
+//
+// Example usage:
+//
+//	const report = streamloader.validateAgainstOpenAPI("requests.json", "openapi.yaml", { pathField: "path" });
+//	if (!report.valid) throw new Error(`${report.invalid} requests don't match the API: ${JSON.stringify(report.problems)}`);
+func (s StreamLoader) ValidateAgainstOpenAPI(datasetFile string, openapiSpec interface{}, options ...OpenAPIValidationOptions) (*OpenAPIReport, error) {
+	var opts OpenAPIValidationOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.PathField == "" {
+		opts.PathField = "url"
+	}
+	if opts.MethodField == "" {
+		opts.MethodField = "method"
+	}
+	if opts.BodyField == "" {
+		opts.BodyField = "body"
+	}
+	if opts.MaxReported <= 0 {
+		opts.MaxReported = maxOrphanExamples
+	}
+
+	spec, err := loadOpenAPISpec(openapiSpec, s.call)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OpenAPIReport{Operations: make(map[string]int), Problems: make([]OpenAPIProblem, 0)}
+	err = forEachJsonRecord(datasetFile, s.call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d: %w", report.Records, err)
+		}
+		index := report.Records
+		report.Records++
+
+		problem := OpenAPIProblem{Index: index, Method: "GET"}
+		if method, ok := lookupField(record, opts.MethodField); ok && method != nil {
+			problem.Method = strings.ToUpper(fmt.Sprint(method))
+		}
+		var errs openAPIErrors
+		if target, ok := lookupField(record, opts.PathField); !ok || target == nil {
+			errs.add("missing %s field", opts.PathField)
+		} else {
+			problem.URL = fmt.Sprint(target)
+			body, hasBody := lookupField(record, opts.BodyField)
+			problem.Operation = spec.checkRequest(problem.Method, problem.URL, body, hasBody && body != nil, &errs)
+		}
+
+		if len(errs.list) == 0 {
+			report.Operations[problem.Operation]++
+			return true, nil
+		}
+		report.Invalid++
+		if len(report.Problems) < opts.MaxReported {
+			problem.Errors = errs.list
+			report.Problems = append(report.Problems, problem)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Valid = report.Invalid == 0
+	return report, nil
+}
+
+// loadOpenAPISpec reads a spec from a JSON or YAML file, or takes it as an object.
+func loadOpenAPISpec(source interface{}, call callLimits) (*openAPISpec, error) {
+	var doc interface{}
+	switch v := source.(type) {
+	case string:
+		data, err := readInputFile(v, call)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+		}
+		if trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM)); len(trimmed) > 0 && trimmed[0] == '{' {
+			err = json.Unmarshal(trimmed, &doc)
+		} else {
+			err = yaml.Unmarshal(data, &doc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI spec %s: %w", v, err)
+		}
+	case map[string]interface{}:
+		doc = v
+	default:
+		return nil, fmt.Errorf("invalid OpenAPI spec: expected a file path or an object, got %T", source)
+	}
+
+	root, ok := normalizeYAML(doc).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenAPI spec: expected an object")
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI spec: expected an openapi 3.x version, got %v", root["openapi"])
+	}
+	spec := &openAPISpec{root: root, patterns: make(map[string]*regexp.Regexp)}
+
+	// Servers, with their variables at their defaults
+	servers, _ := root["servers"].([]interface{})
+	seen := make(map[string]bool)
+	for _, s := range servers {
+		server, _ := s.(map[string]interface{})
+		address, _ := server["url"].(string)
+		variables, _ := server["variables"].(map[string]interface{})
+		for name, v := range variables {
+			variable, _ := v.(map[string]interface{})
+			address = strings.ReplaceAll(address, "{"+name+"}", fmt.Sprint(variable["default"]))
+		}
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL %q in OpenAPI spec: %w", address, err)
+		}
+		base := strings.TrimSuffix(u.Path, "/")
+		if !seen[base] {
+			seen[base] = true
+			spec.bases = append(spec.bases, base)
+		}
+	}
+	if len(spec.bases) == 0 {
+		spec.bases = append(spec.bases, "")
+	}
+	sort.SliceStable(spec.bases, func(i, j int) bool { return len(spec.bases[i]) > len(spec.bases[j]) })
+
+	// Path templates, concrete ones first
+	paths, _ := root["paths"].(map[string]interface{})
+	for template, v := range paths {
+		item, err := spec.resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s in OpenAPI spec: %w", template, err)
+		}
+		route := openAPIRoute{template: template, item: item}
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		rest := template
+		for {
+			open := strings.IndexByte(rest, '{')
+			end := strings.IndexByte(rest[max(open, 0):], '}') + max(open, 0)
+			if open < 0 || end < open {
+				pattern.WriteString(regexp.QuoteMeta(rest))
+				break
+			}
+			pattern.WriteString(regexp.QuoteMeta(rest[:open]))
+			pattern.WriteString("([^/]+)")
+			route.params = append(route.params, rest[open+1:end])
+			rest = rest[end+1:]
+		}
+		pattern.WriteString("$")
+		route.pattern = regexp.MustCompile(pattern.String())
+		spec.routes = append(spec.routes, route)
+	}
+	sort.Slice(spec.routes, func(i, j int) bool {
+		a, b := spec.routes[i], spec.routes[j]
+		if len(a.params) != len(b.params) {
+			return len(a.params) < len(b.params)
+		}
+		if len(a.template) != len(b.template) {
+			return len(a.template) > len(b.template)
+		}
+		return a.template < b.template
+	})
+	return spec, nil
+}
+
+// normalizeYAML turns a decoded YAML document into the values encoding/json decodes: maps keyed
+// by strings and numbers as float64.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	default:
+		if n, ok := numberValue(v); ok {
+			return n
+		}
+		return v
+	}
+}
+
+// resolve follows the $ref of an object, if it has one, to the object it points to in the spec.
+func (spec *openAPISpec) resolve(node interface{}) (map[string]interface{}, error) {
+	for i := 0; i < maxSchemaDepth; i++ {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %T", node)
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj, nil
+		}
+		if !strings.HasPrefix(ref, "#") {
+			return nil, fmt.Errorf("external $ref %q is not supported", ref)
+		}
+		node = spec.root
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			if unescaped, err := url.PathUnescape(token); err == nil {
+				token = unescaped
+			}
+			ok := false
+			switch parent := node.(type) {
+			case map[string]interface{}:
+				node, ok = parent[token]
+			case []interface{}:
+				if n, err := strconv.Atoi(token); err == nil && n >= 0 && n < len(parent) {
+					node, ok = parent[n], true
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+		}
+	}
+	return nil, fmt.Errorf("$ref chain is too long")
+}
+
+// checkRequest checks a request against the spec and returns the name of its operation, or ""
+// when none matches.
+func (spec *openAPISpec) checkRequest(method string, target string, body interface{}, hasBody bool, errs *openAPIErrors) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		errs.add("invalid URL: %v", err)
+		return ""
+	}
+	path := u.EscapedPath()
+
+	var route *openAPIRoute
+	var matches []string
+	for _, base := range spec.bases {
+		if base != "" && path != base && !strings.HasPrefix(path, base+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(path, base)
+		for i := range spec.routes {
+			if m := spec.routes[i].pattern.FindStringSubmatch(rest); m != nil {
+				route, matches = &spec.routes[i], m[1:]
+				break
+			}
+		}
+		if route != nil {
+			break
+		}
+	}
+	if route == nil {
+		errs.add("no path of the spec matches %s", path)
+		return ""
+	}
+
+	name := method + " " + route.template
+	op, ok := route.item[strings.ToLower(method)]
+	if !ok || !isOpenAPIMethod(method) {
+		errs.add("method %s is not allowed for %s", method, route.template)
+		return ""
+	}
+	operation, err := spec.resolve(op)
+	if err != nil {
+		errs.add("invalid operation %s: %v", name, err)
+		return ""
+	}
+	if id, ok := operation["operationId"].(string); ok && id != "" {
+		name = id
+	}
+
+	// Parameters of the path item, overridden by those of the operation
+	params := make(map[string]map[string]interface{})
+	var order []string
+	for _, list := range []interface{}{route.item["parameters"], operation["parameters"]} {
+		items, _ := list.([]interface{})
+		for _, p := range items {
+			param, err := spec.resolve(p)
+			if err != nil {
+				errs.add("invalid parameter of %s: %v", name, err)
+				continue
+			}
+			key := fmt.Sprint(param["in"]) + ":" + fmt.Sprint(param["name"])
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	query := u.Query()
+	for _, key := range order {
+		param := params[key]
+		paramName := fmt.Sprint(param["name"])
+		schema := param["schema"]
+		switch param["in"] {
+		case "path":
+			for i, p := range route.params {
+				if p != paramName {
+					continue
+				}
+				value, err := url.PathUnescape(matches[i])
+				if err != nil {
+					errs.add("path parameter %s: %v", paramName, err)
+					continue
+				}
+				spec.validate(schema, spec.coerceParameter(schema, []string{value}), "path parameter "+paramName, errs, 0)
+			}
+		case "query":
+			values, ok := query[paramName]
+			if !ok {
+				if required, _ := param["required"].(bool); required {
+					errs.add("missing required query parameter %s", paramName)
+				}
+				continue
+			}
+			spec.validate(schema, spec.coerceParameter(schema, values), "query parameter "+paramName, errs, 0)
+		}
+	}
+
+	// Body
+	requestBody, hasRequestBody := operation["requestBody"]
+	if !hasRequestBody {
+		if hasBody {
+			errs.add("%s takes no request body", name)
+		}
+		return name
+	}
+	requestBodyObj, err := spec.resolve(requestBody)
+	if err != nil {
+		errs.add("invalid request body of %s: %v", name, err)
+		return name
+	}
+	if !hasBody {
+		if required, _ := requestBodyObj["required"].(bool); required {
+			errs.add("missing required request body")
+		}
+		return name
+	}
+	content, _ := requestBodyObj["content"].(map[string]interface{})
+	mediaType, media := openAPIMediaType(content)
+	if media == nil {
+		return name
+	}
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || strings.Contains(mediaType, "*")
+	if text, ok := body.(string); ok {
+		if !isJSON {
+			// Form and other encodings of strings are not checked
+			return name
+		}
+		if err := json.Unmarshal([]byte(text), &body); err != nil {
+			errs.add("body is not valid JSON: %v", err)
+			return name
+		}
+	}
+	if schema, ok := media["schema"]; ok {
+		spec.validate(schema, body, "body", errs, 0)
+	}
+	return name
+}
+
+// isOpenAPIMethod reports whether a method names an operation of a path item, rather than one
+// of its other fields, such as parameters.
+func isOpenAPIMethod(method string) bool {
+	for _, m := range openAPIMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// openAPIMediaType picks the media type a body is checked against: JSON first, then wildcards,
+// then forms.
+func openAPIMediaType(content map[string]interface{}) (string, map[string]interface{}) {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	rank := func(mediaType string) int {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]))
+		switch {
+		case mediaType == "application/json":
+			return 0
+		case strings.HasSuffix(mediaType, "+json"):
+			return 1
+		case strings.Contains(mediaType, "*"):
+			return 2
+		case mediaType == "application/x-www-form-urlencoded", mediaType == "multipart/form-data":
+			return 3
+		}
+		return -1
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return rank(types[i]) < rank(types[j]) || rank(types[i]) == rank(types[j]) && types[i] < types[j]
+	})
+	for _, mediaType := range types {
+		if rank(mediaType) < 0 {
+			continue
+		}
+		media, _ := content[mediaType].(map[string]interface{})
+		return strings.ToLower(strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])), media
+	}
+	return "", nil
+}
+
+// coerceParameter converts the text of a parameter to the type of its schema, so it can be
+// validated: numbers and booleans are parsed, and arrays split on commas unless the parameter
+// was repeated. Text that doesn't parse is left as it is, to fail the type check.
+func (spec *openAPISpec) coerceParameter(schema interface{}, values []string) interface{} {
+	obj, err := spec.resolve(schema)
+	if err != nil {
+		return values[0]
+	}
+	types := schemaTypes(obj)
+	if types["array"] {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = spec.coerceParameter(obj["items"], []string{value})
+		}
+		return items
+	}
+	value := values[0]
+	switch {
+	case types["integer"], types["number"]:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case types["boolean"]:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// schemaTypes returns the types a schema allows, from a type string or, in OpenAPI 3.1, a type
+// array; empty means any type.
+func schemaTypes(schema map[string]interface{}) map[string]bool {
+	types := make(map[string]bool)
+	switch t := schema["type"].(type) {
+	case string:
+		types[t] = true
+	case []interface{}:
+		for _, name := range t {
+			types[fmt.Sprint(name)] = true
+		}
+	}
+	return types
+}
+
+// jsonSchemaType returns the JSON Schema type of a decoded JSON value.
+func jsonSchemaType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validate checks a value against a schema, adding an error for each mismatch, prefixed by the
+// dotted path of the value.
+func (spec *openAPISpec) validate(schema interface{}, value interface{}, at string, errs *openAPIErrors, depth int) {
+	if depth > maxSchemaDepth {
+		errs.add("%s: schema nesting is too deep", at)
+		return
+	}
+	if allowed, ok := schema.(bool); ok {
+		// OpenAPI 3.1 boolean schemas
+		if !allowed {
+			errs.add("%s: no value is allowed", at)
+		}
+		return
+	}
+	if schema == nil {
+		return
+	}
+	s, err := spec.resolve(schema)
+	if err != nil {
+		errs.add("%s: invalid schema: %v", at, err)
+		return
+	}
+
+	types := schemaTypes(s)
+	actual := jsonSchemaType(value)
+	if value == nil {
+		if nullable, _ := s["nullable"].(bool); nullable || types["null"] || len(types) == 0 {
+			return
+		}
+		if enum, ok := s["enum"].([]interface{}); ok {
+			for _, allowed := range enum {
+				if allowed == nil {
+					return
+				}
+			}
+		}
+	}
+	if len(types) > 0 && !types[actual] && !(actual == "integer" && types["number"]) {
+		names := make([]string, 0, len(types))
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs.add("%s: expected %s, got %s", at, strings.Join(names, " or "), actual)
+		return
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if deepValuesEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs.add("%s: %s is not one of the allowed values", at, compactJSON(value))
+		}
+	}
+	if constant, ok := s["const"]; ok && !deepValuesEqual(constant, value) {
+		errs.add("%s: expected %s", at, compactJSON(constant))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := numberValue(s["minLength"]); ok && float64(length) < n {
+			errs.add("%s: shorter than %v characters", at, n)
+		}
+		if n, ok := numberValue(s["maxLength"]); ok && float64(length) > n {
+			errs.add("%s: longer than %v characters", at, n)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			re, err := spec.pattern(pattern)
+			if err != nil {
+				errs.add("%s: invalid pattern %q: %v", at, pattern, err)
+			} else if !re.MatchString(v) {
+				errs.add("%s: %q doesn't match %s", at, v, pattern)
+			}
+		}
+		if format, ok := s["format"].(string); ok && !validFormat(format, v) {
+			errs.add("%s: %q is not a valid %s", at, v, format)
+		}
+	case float64:
+		spec.validateNumber(s, v, at, errs)
+	case []interface{}:
+		if n, ok := numberValue(s["minItems"]); ok && float64(len(v)) < n {
+			errs.add("%s: fewer than %v items", at, n)
+		}
+		if n, ok := numberValue(s["maxItems"]); ok && float64(len(v)) > n {
+			errs.add("%s: more than %v items", at, n)
+		}
+		if unique, _ := s["uniqueItems"].(bool); unique {
+			seen := make(map[string]bool, len(v))
+			for _, item := range v {
+				key := compactJSON(item)
+				if seen[key] {
+					errs.add("%s: duplicate item %s", at, key)
+					break
+				}
+				seen[key] = true
+			}
+		}
+		if items, ok := s["items"]; ok {
+			for i, item := range v {
+				spec.validate(items, item, at+"."+strconv.Itoa(i), errs, depth+1)
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, r := range required {
+				name := fmt.Sprint(r)
+				if _, ok := v[name]; ok {
+					continue
+				}
+				// Read-only properties are only sent in responses
+				if property, err := spec.resolve(properties[name]); err == nil {
+					if readOnly, _ := property["readOnly"].(bool); readOnly {
+						continue
+					}
+				}
+				errs.add("%s: missing required field %s", at, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name]; ok {
+				spec.validate(property, v[name], at+"."+name, errs, depth+1)
+				continue
+			}
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					errs.add("%s: unexpected field %s", at, name)
+				}
+			case map[string]interface{}:
+				spec.validate(additional, v[name], at+"."+name, errs, depth+1)
+			}
+		}
+	}
+
+	// Combinations
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			spec.validate(sub, value, at, errs, depth+1)
+		}
+	}
+	matching := func(list []interface{}) int {
+		n := 0
+		for _, sub := range list {
+			var subErrs openAPIErrors
+			spec.validate(sub, value, at, &subErrs, depth+1)
+			if len(subErrs.list) == 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && matching(anyOf) == 0 {
+		errs.add("%s: matches none of the anyOf schemas", at)
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok {
+		if n := matching(oneOf); n != 1 {
+			errs.add("%s: matches %d of the oneOf schemas, expected exactly 1", at, n)
+		}
+	}
+	if not, ok := s["not"]; ok && matching([]interface{}{not}) == 1 {
+		errs.add("%s: matches the not schema", at)
+	}
+}
+
+// validateNumber checks a number against the range keywords of a schema, which take the
+// OpenAPI 3.0 boolean form of exclusiveMinimum and exclusiveMaximum or the 3.1 number form.
+func (spec *openAPISpec) validateNumber(s map[string]interface{}, v float64, at string, errs *openAPIErrors) {
+	exclusiveMin, _ := s["exclusiveMinimum"].(bool)
+	exclusiveMax, _ := s["exclusiveMaximum"].(bool)
+	if n, ok := numberValue(s["minimum"]); ok && (v < n || exclusiveMin && v == n) {
+		errs.add("%s: %v is below the minimum %v", at, v, n)
+	}
+	if n, ok := numberValue(s["maximum"]); ok && (v > n || exclusiveMax && v == n) {
+		errs.add("%s: %v is above the maximum %v", at, v, n)
+	}
+	if n, ok := numberValue(s["exclusiveMinimum"]); ok && v <= n {
+		errs.add("%s: %v is not above %v", at, v, n)
+	}
+	if n, ok := numberValue(s["exclusiveMaximum"]); ok && v >= n {
+		errs.add("%s: %v is not below %v", at, v, n)
+	}
+	if n, ok := numberValue(s["multipleOf"]); ok && n > 0 {
+		if q := v / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			errs.add("%s: %v is not a multiple of %v", at, v, n)
+		}
+	}
+}
+
+// pattern compiles a schema pattern once per spec.
+func (spec *openAPISpec) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := spec.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	spec.patterns[pattern] = re
+	return re, nil
+}
+
+// validFormat checks the string formats that are unambiguous; others are accepted.
+func validFormat(format string, value string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	}
+	return true
+}
+
+// deepValuesEqual compares decoded JSON values, including arrays and objects, by value.
+func deepValuesEqual(a interface{}, b interface{}) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+// compactJSON encodes a value for messages and comparisons; maps encode with sorted keys.
+func compactJSON(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
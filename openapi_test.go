@@ -0,0 +1,209 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const testOpenAPISpec = `openapi: 3.0.3
+servers:
+  - url: https://api.example.com/{version}
+    variables:
+      version:
+        default: v1
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, maximum: 100}
+        - name: tags
+          in: query
+          schema: {type: array, items: {type: string, enum: [a, b, c]}}
+    post:
+      operationId: createUser
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema: {type: integer}
+    get:
+      operationId: getUser
+  /users/me:
+    get:
+      responses:
+        200:
+          description: The current user
+components:
+  schemas:
+    User:
+      type: object
+      additionalProperties: false
+      required: [id, name, email]
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, minLength: 1}
+        email: {type: string}
+        age: {type: integer, minimum: 0}
+        role: {type: string, enum: [admin, member]}
+        manager:
+          nullable: true
+          allOf:
+            - $ref: '#/components/schemas/User'
+`
+
+func TestValidateAgainstOpenAPI(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "openapi.yaml")
+	os.WriteFile(spec, []byte(testOpenAPISpec), 0644)
+	records := filepath.Join(dir, "requests.ndjson")
+	os.WriteFile(records, []byte(strings.Join([]string{
+		`{"method":"GET","url":"https://api.example.com/v1/users/42"}`,
+		`{"method":"get","url":"/v1/users/me"}`,
+		`{"method":"GET","url":"/v1/users/abc"}`,
+		`{"method":"POST","url":"/v1/users","body":{"name":"Ada","email":"ada@example.com","age":36,"role":"admin","manager":null}}`,
+		`{"method":"POST","url":"/v1/users","body":"{\"name\":\"\",\"age\":-1,\"role\":\"root\",\"extra\":1}"}`,
+		`{"method":"DELETE","url":"/v1/users/42"}`,
+		`{"url":"/v2/users"}`,
+		`{"url":"/v1/users?limit=500"}`,
+		`{"method":"POST","url":"/v1/users"}`,
+		`{"url":"/v1/users?limit=10&tags=a,b"}`,
+		`{"url":"/v1/users?tags=a&tags=d","body":{}}`,
+	}, "\n")), 0644)
+
+	report, err := loader.ValidateAgainstOpenAPI(records, spec)
+	if err != nil {
+		t.Fatalf("ValidateAgainstOpenAPI() error = %v", err)
+	}
+	if report.Valid || report.Records != 11 || report.Invalid != 7 {
+		t.Errorf("ValidateAgainstOpenAPI() = valid %v, %d records, %d invalid, want false, 11, 7", report.Valid, report.Records, report.Invalid)
+	}
+	wantOperations := map[string]int{"getUser": 1, "GET /users/me": 1, "createUser": 1, "listUsers": 1}
+	if !reflect.DeepEqual(report.Operations, wantOperations) {
+		t.Errorf("operations = %v, want %v", report.Operations, wantOperations)
+	}
+	wantErrors := map[int][]string{
+		2: {"path parameter id: expected integer, got string"},
+		4: {
+			"body: missing required field email",
+			"body.age: -1 is below the minimum 0",
+			"body: unexpected field extra",
+			"body.name: shorter than 1 characters",
+			`body.role: "root" is not one of the allowed values`,
+		},
+		5:  {"method DELETE is not allowed for /users/{id}"},
+		6:  {"no path of the spec matches /v2/users"},
+		7:  {"query parameter limit: 500 is above the maximum 100"},
+		8:  {"missing required request body"},
+		10: {`query parameter tags.1: "d" is not one of the allowed values`, "listUsers takes no request body"},
+	}
+	if len(report.Problems) != len(wantErrors) {
+		t.Fatalf("problems = %+v, want %d", report.Problems, len(wantErrors))
+	}
+	for _, problem := range report.Problems {
+		if want := wantErrors[problem.Index]; !reflect.DeepEqual(problem.Errors, want) {
+			t.Errorf("record %d errors = %q, want %q", problem.Index, problem.Errors, want)
+		}
+	}
+	if p := report.Problems[1]; p.Method != "POST" || p.URL != "/v1/users" || p.Operation != "createUser" {
+		t.Errorf("problem = %+v", p)
+	}
+
+	// Fields, the number of listed records, and a spec given as an object
+	custom := filepath.Join(dir, "custom.json")
+	os.WriteFile(custom, []byte(`[{"req":{"verb":"PUT","path":"/items/1","payload":{"n":"x"}}},{"req":{"verb":"PUT","path":"/items/2","payload":{"n":"y"}}}]`), 0644)
+	object := map[string]interface{}{
+		"openapi": "3.1.0",
+		"paths": map[string]interface{}{
+			"/items/{id}": map[string]interface{}{
+				"put": map[string]interface{}{
+					"requestBody": map[string]interface{}{"content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": map[string]interface{}{
+							"type":       "object",
+							"properties": map[string]interface{}{"n": map[string]interface{}{"type": []interface{}{"integer", "null"}}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	report, err = loader.ValidateAgainstOpenAPI(custom, object, OpenAPIValidationOptions{PathField: "req.path", MethodField: "req.verb", BodyField: "req.payload", MaxReported: 1})
+	if err != nil {
+		t.Fatalf("ValidateAgainstOpenAPI(object) error = %v", err)
+	}
+	if report.Invalid != 2 || len(report.Problems) != 1 || report.Problems[0].Errors[0] != "body.n: expected integer or null, got string" {
+		t.Errorf("ValidateAgainstOpenAPI(object) = %+v", report)
+	}
+
+	// Errors
+	swagger := filepath.Join(dir, "swagger.json")
+	os.WriteFile(swagger, []byte(`{"swagger":"2.0","paths":{}}`), 0644)
+	for _, invalid := range []interface{}{swagger, filepath.Join(dir, "missing.yaml"), 42} {
+		if _, err := loader.ValidateAgainstOpenAPI(records, invalid); err == nil {
+			t.Errorf("ValidateAgainstOpenAPI(%v) expected an error", invalid)
+		}
+	}
+}
+
+func TestOpenAPISchemaValidation(t *testing.T) {
+	spec := &openAPISpec{root: map[string]interface{}{}, patterns: make(map[string]*regexp.Regexp)}
+	schema := func(text string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		schema string
+		value  string
+		errors int
+	}{
+		{`{"type":"string","pattern":"^[a-z]+$"}`, `"abc"`, 0},
+		{`{"type":"string","pattern":"^[a-z]+$"}`, `"ABC"`, 1},
+		{`{"type":"string","format":"date-time"}`, `"2024-01-01T00:00:00Z"`, 0},
+		{`{"type":"string","format":"date"}`, `"2024-13-01"`, 1},
+		{`{"type":"string","format":"uuid"}`, `"not-a-uuid"`, 1},
+		{`{"type":"string","maxLength":2}`, `"héé"`, 1},
+		{`{"type":"number","exclusiveMinimum":true,"minimum":0}`, `0`, 1},
+		{`{"type":"number","exclusiveMaximum":10}`, `9.5`, 0},
+		{`{"type":"number","multipleOf":0.1}`, `0.3`, 0},
+		{`{"type":"integer"}`, `1.5`, 1},
+		{`{"type":"number"}`, `2`, 0},
+		{`{"type":"string"}`, `null`, 1},
+		{`{"type":"string","nullable":true}`, `null`, 0},
+		{`{"type":"array","minItems":1,"uniqueItems":true}`, `[1,1]`, 1},
+		{`{"type":"array","maxItems":1}`, `[]`, 0},
+		{`{"type":"object","additionalProperties":{"type":"integer"}}`, `{"a":1,"b":"x"}`, 1},
+		{`{"enum":[{"a":[1]}]}`, `{"a":[1]}`, 0},
+		{`{"const":"x"}`, `"y"`, 1},
+		{`{"anyOf":[{"type":"string"},{"type":"integer"}]}`, `true`, 1},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, 1},
+		{`{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1.5`, 0},
+		{`{"not":{"type":"string"}}`, `"x"`, 1},
+		{`{"properties":{"a":false}}`, `{"a":1}`, 1},
+		{`{"$ref":"#/missing"}`, `1`, 1},
+	}
+	for _, tt := range tests {
+		var errs openAPIErrors
+		spec.validate(schema(tt.schema), schema(tt.value), "value", &errs, 0)
+		if len(errs.list) != tt.errors {
+			t.Errorf("validate(%s, %s) = %q, want %d errors", tt.schema, tt.value, errs.list, tt.errors)
+		}
+	}
+}
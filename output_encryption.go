@@ -0,0 +1,233 @@
+// output_encryption.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted files start with a header of the magic string, the segment size and a random nonce
+// prefix. The plaintext follows in segments, each sealed separately with AES-GCM so files of
+// any size are encrypted and decrypted in a streaming fashion. A segment's nonce is the prefix
+// followed by its 32-bit index, with the top bit set on the last segment, so a truncated or
+// reordered file fails to decrypt. The header is authenticated with every segment.
+const (
+	encryptionMagic       = "SLENC1"
+	encryptionSegmentSize = 64 * 1024
+	encryptionPrefixSize  = 8
+	encryptionHeaderSize  = len(encryptionMagic) + 4 + encryptionPrefixSize
+	encryptionLastSegment = 1 << 31
+)
+
+// encryptionKey reads an AES key from the environment variable keyEnv. The key is hex or
+// base64 encoded and must be 16, 24 or 32 bytes long.
+func encryptionKey(keyEnv string) (cipher.AEAD, error) {
+	block, err := encryptionBlock(keyEnv)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptionBlock reads an AES key from the environment variable keyEnv, as encryptionKey does,
+// and returns the raw block cipher.
+func encryptionBlock(keyEnv string) (cipher.Block, error) {
+	value := strings.TrimSpace(os.Getenv(keyEnv))
+	if value == "" {
+		return nil, fmt.Errorf("encryption key environment variable %s is not set", keyEnv)
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("encryption key in %s must be hex or base64 encoded", keyEnv)
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key in %s: must be 16, 24 or 32 bytes, got %d", keyEnv, len(key))
+	}
+	return block, nil
+}
+
+// segmentNonce returns the nonce of segment index.
+func segmentNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, encryptionPrefixSize+4)
+	copy(nonce, prefix)
+	if last {
+		index |= encryptionLastSegment
+	}
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixSize:], index)
+	return nonce
+}
+
+// encryptWriter encrypts everything written to it into w. Close seals the last segment.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	sealed  []byte
+	index   uint32
+	written bool // Whether the header has been written
+}
+
+// newEncryptWriter prepares an encrypting writer with a key from the environment variable keyEnv.
+func newEncryptWriter(w io.Writer, keyEnv string) (*encryptWriter, error) {
+	aead, err := encryptionKey(keyEnv)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, encryptionHeaderSize)
+	copy(header, encryptionMagic)
+	binary.BigEndian.PutUint32(header[len(encryptionMagic):], encryptionSegmentSize)
+	if _, err := rand.Read(header[len(encryptionMagic)+4:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &encryptWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, encryptionSegmentSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data arrives, since the last one is marked
+		if len(e.buf) == encryptionSegmentSize {
+			if err := e.seal(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(e.buf[len(e.buf):encryptionSegmentSize], p)
+		e.buf = e.buf[:len(e.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// seal encrypts and writes the buffered segment.
+func (e *encryptWriter) seal(last bool) error {
+	if !e.written {
+		if _, err := e.w.Write(e.header); err != nil {
+			return err
+		}
+		e.written = true
+	}
+	prefix := e.header[len(encryptionMagic)+4:]
+	e.sealed = e.aead.Seal(e.sealed[:0], segmentNonce(prefix, e.index, last), e.buf, e.header)
+	e.index++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.sealed)
+	return err
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// decryptReader decrypts a file written by encryptWriter.
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	segLen int
+	sealed []byte
+	plain  []byte
+	index  uint32
+	done   bool
+}
+
+// newDecryptReader reads and checks the header of an encrypted file.
+func newDecryptReader(r io.Reader, keyEnv string) (*decryptReader, error) {
+	aead, err := encryptionKey(keyEnv)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(r, readBufferSize())
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil || !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return nil, fmt.Errorf("not an encrypted streamloader file")
+	}
+	segLen := int(binary.BigEndian.Uint32(header[len(encryptionMagic):]))
+	if segLen <= 0 || segLen > 16*1024*1024 {
+		return nil, fmt.Errorf("invalid segment size %d", segLen)
+	}
+	return &decryptReader{r: br, aead: aead, header: header, segLen: segLen, sealed: make([]byte, segLen+aead.Overhead())}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(d.r, d.sealed)
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			d.done = true
+		} else if err != nil {
+			return 0, err
+		} else if _, err := d.r.Peek(1); err == io.EOF {
+			// A full segment at the end of the file is the last one
+			d.done = true
+		}
+		prefix := d.header[len(encryptionMagic)+4:]
+		plain, err := d.aead.Open(d.sealed[:0], segmentNonce(prefix, d.index, d.done), d.sealed[:n], d.header)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt segment %d: wrong key, or the file is corrupt or truncated", d.index)
+		}
+		d.plain = plain
+		d.index++
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// DecryptFile decrypts a file written with the encryptOutput writer option, using the key in
+// the environment variable keyEnv, and writes the plaintext to outputFilePath. Files that were
+// also written with compressOutput are left compressed.
+//
+// Returns: The number of plaintext bytes written
+//
+// Example usage:
+//
+//	streamloader.DecryptFile("users.json.enc", "/tmp/users.json", "DATASET_KEY")
+func (s StreamLoader) DecryptFile(inputFilePath string, outputFilePath string, keyEnv string) (int64, error) {
+	input, err := openInput(inputFilePath, s.call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer input.Close()
+	reader, err := newDecryptReader(input, keyEnv)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", inputFilePath, err)
+	}
+
+	lock, err := lockOutputPath(outputFilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.release()
+	output, err := os.Create(outputFilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer output.Close()
+	written, err := io.Copy(output, reader)
+	if err != nil {
+		output.Close()
+		os.Remove(outputFilePath)
+		return 0, fmt.Errorf("failed to read %s: %w", inputFilePath, err)
+	}
+	if err := output.Close(); err != nil {
+		return written, fmt.Errorf("failed to close output file: %w", err)
+	}
+	return written, nil
+}
@@ -0,0 +1,105 @@
+package streamloader
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptOutput(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{0x42}, 32)
+	t.Setenv("TEST_DATASET_KEY", hex.EncodeToString(key))
+	t.Setenv("TEST_OTHER_KEY", hex.EncodeToString(bytes.Repeat([]byte{0x24}, 16)))
+
+	// Sizes around the segment boundary
+	for _, size := range []int{0, 10, encryptionSegmentSize - 4, encryptionSegmentSize - 3, 3*encryptionSegmentSize + 100} {
+		jsonLines := ""
+		if size > 0 {
+			jsonLines = `"` + strings.Repeat("x", size) + `"`
+		}
+		encrypted := filepath.Join(dir, "out.json.enc")
+		if _, err := loader.WriteJsonLinesToArrayFile(jsonLines, encrypted, map[string]interface{}{"encryptOutput": "TEST_DATASET_KEY"}); err != nil {
+			t.Fatalf("size %d: WriteJsonLinesToArrayFile() error = %v", size, err)
+		}
+		data, _ := os.ReadFile(encrypted)
+		if bytes.Contains(data, []byte("xxxx")) {
+			t.Errorf("size %d: output contains plaintext", size)
+		}
+
+		decrypted := filepath.Join(dir, "out.json")
+		written, err := loader.DecryptFile(encrypted, decrypted, "TEST_DATASET_KEY")
+		if err != nil {
+			t.Fatalf("size %d: DecryptFile() error = %v", size, err)
+		}
+		plain, _ := os.ReadFile(decrypted)
+		if string(plain) != "["+jsonLines+"]" || written != int64(len(plain)) {
+			t.Errorf("size %d: decrypted %d bytes, want %d", size, written, len(jsonLines)+2)
+		}
+
+		if size > encryptionSegmentSize {
+			// Dropping the last segment must be detected
+			truncated := filepath.Join(dir, "truncated.enc")
+			os.WriteFile(truncated, data[:encryptionHeaderSize+2*(encryptionSegmentSize+16)], 0644)
+			if _, err := loader.DecryptFile(truncated, decrypted, "TEST_DATASET_KEY"); err == nil {
+				t.Error("Expected error for a truncated file")
+			}
+		}
+	}
+
+	// Compression and encryption combine, and the file is left compressed
+	objects := []interface{}{map[string]interface{}{"id": 1}}
+	encrypted := filepath.Join(dir, "objects.json.gz.enc")
+	options := JsonWriterOptions{CompressOutput: "gzip", EncryptOutput: "TEST_DATASET_KEY"}
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, encrypted, options); err != nil {
+		t.Fatalf("WriteObjectsToJsonArrayFile() error = %v", err)
+	}
+	compressed := filepath.Join(dir, "objects.json.gz")
+	if _, err := loader.DecryptFile(encrypted, compressed, "TEST_DATASET_KEY"); err != nil {
+		t.Fatalf("DecryptFile() error = %v", err)
+	}
+	if got := readGzipFile(t, compressed); got != `[{"id":1}]` {
+		t.Errorf("decrypted file = %s", got)
+	}
+
+	if _, err := loader.DecryptFile(encrypted, compressed, "TEST_OTHER_KEY"); err == nil {
+		t.Error("Expected error for the wrong key")
+	}
+	if _, err := loader.DecryptFile(compressed, filepath.Join(dir, "x"), "TEST_DATASET_KEY"); err == nil {
+		t.Error("Expected error for an unencrypted file")
+	}
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, filepath.Join(dir, "missing.enc"), JsonWriterOptions{EncryptOutput: "TEST_UNSET_KEY"}); err == nil || !strings.Contains(err.Error(), "TEST_UNSET_KEY") {
+		t.Errorf("Expected error for an unset key variable, got %v", err)
+	}
+	t.Setenv("TEST_SHORT_KEY", "abcd")
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, filepath.Join(dir, "short.enc"), JsonWriterOptions{EncryptOutput: "TEST_SHORT_KEY"}); err == nil {
+		t.Error("Expected error for a short key")
+	}
+
+	// The CBOR and compressed-objects writers encrypt too
+	cborEncrypted := filepath.Join(dir, "objects.cbor.enc")
+	if _, err := loader.WriteObjectsToCborSequenceFile(objects, cborEncrypted, JsonWriterOptions{EncryptOutput: "TEST_DATASET_KEY"}); err != nil {
+		t.Fatalf("WriteObjectsToCborSequenceFile() error = %v", err)
+	}
+	cborPlain := filepath.Join(dir, "objects.cbor")
+	if _, err := loader.DecryptFile(cborEncrypted, cborPlain, "TEST_DATASET_KEY"); err != nil {
+		t.Fatalf("DecryptFile(cbor) error = %v", err)
+	}
+	if loaded, err := loader.LoadCborSequence(cborPlain); err != nil || len(loaded) != 1 {
+		t.Errorf("LoadCborSequence() = %v, %v", loaded, err)
+	}
+	objectsEncrypted := filepath.Join(dir, "compressed-objects.json.gz.enc")
+	if _, err := loader.WriteCompressedObjectsToJsonArrayFile(objects, objectsEncrypted, JsonWriterOptions{CompressOutput: "gzip", EncryptOutput: "TEST_DATASET_KEY"}); err != nil {
+		t.Fatalf("WriteCompressedObjectsToJsonArrayFile() error = %v", err)
+	}
+	if _, err := loader.DecryptFile(objectsEncrypted, compressed, "TEST_DATASET_KEY"); err != nil {
+		t.Fatalf("DecryptFile(compressed objects) error = %v", err)
+	}
+	if got := readGzipFile(t, compressed); got != `[{"id":1}]` {
+		t.Errorf("decrypted compressed objects = %s", got)
+	}
+}
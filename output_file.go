@@ -0,0 +1,138 @@
+// output_file.go
+package streamloader
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// outputFile is an output file that is optionally written through a compressor and an
+// encryptor. Close flushes both before closing the file, and is safe to call more than once, so
+// writers can both defer it and check its error on success.
+type outputFile struct {
+	file       *os.File
+	compressor io.WriteCloser // nil for uncompressed output
+	encryptor  *encryptWriter // nil for unencrypted output
+	w          io.Writer      // The outermost layer
+	slot       *fileSlot
+	lock       *outputLock
+	closed     bool
+}
+
+// createOutputFile creates or truncates path for writing with the compressOutput ("" or "none"
+// for uncompressed output, "gzip", at the given gzip level, or "zstd", at its default level) and
+// encryptOutput writer options.
+// Data is compressed before it is encrypted. Other writers of path wait until the file is closed.
+func createOutputFile(path string, opts JsonWriterOptions, level int) (*outputFile, error) {
+	return createOutputFileAfter(path, opts, level, nil)
+}
+
+// createOutputFileAfter is createOutputFile running prepare, unless nil, once the options are
+// validated and path is locked, right before the file is created, so a writer can move the
+// previous file aside without racing other writers of path.
+func createOutputFileAfter(path string, opts JsonWriterOptions, level int, prepare func() error) (*outputFile, error) {
+	compression := opts.CompressOutput
+	switch compression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("unknown output compression %q, expected gzip, zstd or none", compression)
+	}
+	if opts.EncryptOutput != "" {
+		if _, err := encryptionKey(opts.EncryptOutput); err != nil {
+			return nil, err
+		}
+	}
+	var header []byte
+	if opts.Metadata != nil {
+		var err error
+		if header, err = datasetHeader(opts.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	lock, err := lockOutputPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			lock.release()
+			return nil, err
+		}
+	}
+	slot, err := acquireFileSlot(path)
+	if err != nil {
+		lock.release()
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		slot.release()
+		lock.release()
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	out := &outputFile{file: file, w: countingWriter{file}, slot: slot, lock: lock}
+	if opts.EncryptOutput != "" {
+		if out.encryptor, err = newEncryptWriter(out.w, opts.EncryptOutput); err != nil {
+			file.Close()
+			slot.release()
+			os.Remove(path)
+			lock.release()
+			return nil, err
+		}
+		out.w = out.encryptor
+	}
+	switch compression {
+	case "gzip":
+		out.compressor, err = gzip.NewWriterLevel(out.w, level)
+	case "zstd":
+		out.compressor, err = zstd.NewWriter(out.w, zstd.WithEncoderConcurrency(1))
+	}
+	if err != nil {
+		file.Close()
+		slot.release()
+		os.Remove(path)
+		lock.release()
+		return nil, fmt.Errorf("failed to create %s writer: %w", compression, err)
+	}
+	if out.compressor != nil {
+		out.w = out.compressor
+	}
+	if header != nil {
+		out.w = &headerWriter{w: out.w, header: header}
+	}
+	return out, nil
+}
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+func (o *outputFile) Close() error {
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	defer o.lock.release()
+	defer o.slot.release()
+	if o.compressor != nil {
+		if err := o.compressor.Close(); err != nil {
+			o.file.Close()
+			return fmt.Errorf("failed to close compressor: %w", err)
+		}
+	}
+	if o.encryptor != nil {
+		if err := o.encryptor.Close(); err != nil {
+			o.file.Close()
+			return fmt.Errorf("failed to encrypt output: %w", err)
+		}
+	}
+	if err := o.file.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+	return nil
+}
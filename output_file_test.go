@@ -0,0 +1,98 @@
+package streamloader
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// readGzipFile returns the decompressed contents of a gzip file.
+func readGzipFile(t *testing.T, path string) string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	gzReader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("%s is not gzip-compressed: %v", path, err)
+	}
+	data, err := io.ReadAll(gzReader)
+	if err != nil {
+		t.Fatalf("failed to decompress %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestCompressOutput(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	gzipOptions := map[string]interface{}{"compressOutput": "gzip"}
+	expected := `[{"id":1},{"id":2}]`
+
+	objectsPath := filepath.Join(dir, "objects.json.gz")
+	objects := []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}
+	if count, err := loader.WriteObjectsToJsonArrayFile(objects, objectsPath, gzipOptions); err != nil || count != 2 {
+		t.Fatalf("WriteObjectsToJsonArrayFile() = %d, %v", count, err)
+	}
+	if got := readGzipFile(t, objectsPath); got != expected {
+		t.Errorf("WriteObjectsToJsonArrayFile() wrote %s, want %s", got, expected)
+	}
+
+	linesPath := filepath.Join(dir, "lines.json.gz")
+	if count, err := loader.WriteJsonLinesToArrayFile("{\"id\":1}\n{\"id\":2}\n", linesPath, JsonWriterOptions{CompressOutput: "gzip"}); err != nil || count != 2 {
+		t.Fatalf("WriteJsonLinesToArrayFile() = %d, %v", count, err)
+	}
+	if got := readGzipFile(t, linesPath); got != expected {
+		t.Errorf("WriteJsonLinesToArrayFile() wrote %s, want %s", got, expected)
+	}
+
+	part1, part2 := filepath.Join(dir, "part1.json"), filepath.Join(dir, "part2.json")
+	os.WriteFile(part1, []byte(`[{"id":1}]`), 0644)
+	os.WriteFile(part2, []byte(`[{"id":2}]`), 0644)
+	combinedPath := filepath.Join(dir, "combined.json.gz")
+	if count, err := loader.CombineJsonArrayFiles([]string{part1, part2}, combinedPath, gzipOptions); err != nil || count != 2 {
+		t.Fatalf("CombineJsonArrayFiles() = %d, %v", count, err)
+	}
+	if got := readGzipFile(t, combinedPath); got != expected {
+		t.Errorf("CombineJsonArrayFiles() wrote %s, want %s", got, expected)
+	}
+
+	// Without the option the output stays plain JSON
+	plainPath := filepath.Join(dir, "plain.json")
+	loader.WriteObjectsToJsonArrayFile(objects, plainPath, map[string]interface{}{"compressOutput": "none"})
+	if data, _ := os.ReadFile(plainPath); string(data) != expected {
+		t.Errorf("plain output = %s, want %s", data, expected)
+	}
+
+	zstdPath := filepath.Join(dir, "objects.json.zst")
+	if count, err := loader.WriteObjectsToJsonArrayFile(objects, zstdPath, map[string]interface{}{"compressOutput": "zstd"}); err != nil || count != 2 {
+		t.Fatalf("WriteObjectsToJsonArrayFile(zstd) = %d, %v", count, err)
+	}
+	compressed, _ := os.ReadFile(zstdPath)
+	decoder, _ := zstd.NewReader(nil)
+	defer decoder.Close()
+	if data, err := decoder.DecodeAll(compressed, nil); err != nil || string(data) != expected {
+		t.Errorf("zstd output = %s, %v, want %s", data, err, expected)
+	}
+
+	_, err := loader.WriteObjectsToJsonArrayFile(objects, filepath.Join(dir, "bad.json"), map[string]interface{}{"compressOutput": "brotli"})
+	if err == nil || !strings.Contains(err.Error(), "brotli") {
+		t.Errorf("compressOutput brotli: expected error, got %v", err)
+	}
+
+	// A compressor that can't be created leaves no output file behind
+	badLevelPath := filepath.Join(dir, "bad-level.json.gz")
+	if _, err := createOutputFile(badLevelPath, JsonWriterOptions{CompressOutput: "gzip"}, 42); err == nil {
+		t.Error("createOutputFile() with an invalid gzip level succeeded")
+	}
+	if _, err := os.Stat(badLevelPath); !os.IsNotExist(err) {
+		t.Errorf("output file left behind: %v", err)
+	}
+}
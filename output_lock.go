@@ -0,0 +1,144 @@
+// output_lock.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outputLockWait is how long writing a file waits for another writer of the same file to finish
+// before failing
+var outputLockWait = time.Minute
+
+// outputLocks serializes the writers of each output path across the VUs of the k6 process, so
+// VUs writing the same file take turns instead of interleaving their bytes.
+var outputLocks = struct {
+	mu    sync.Mutex
+	paths map[string]*pathLock
+}{paths: make(map[string]*pathLock)}
+
+// pathLock is the lock of one output path, dropped from outputLocks when nobody uses it.
+type pathLock struct {
+	held  chan struct{} // Holds a value while a writer has the path
+	users int           // Writers holding or waiting for the lock
+}
+
+// outputLock is a held lock of an output path. Releasing it more than once is safe, so it can be
+// released from a Close that may be called repeatedly.
+type outputLock struct {
+	key  string
+	lock *pathLock
+	once sync.Once
+}
+
+// lockOutputPath waits until no other writer has path, then holds it until the lock is released.
+// Every writer takes the lock before creating its file, so it also fails in read-only mode.
+func lockOutputPath(path string) (*outputLock, error) {
+	if err := checkWritable(path); err != nil {
+		return nil, err
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = filepath.Clean(path)
+	}
+
+	outputLocks.mu.Lock()
+	lock, ok := outputLocks.paths[key]
+	if !ok {
+		lock = &pathLock{held: make(chan struct{}, 1)}
+		outputLocks.paths[key] = lock
+	}
+	lock.users++
+	outputLocks.mu.Unlock()
+
+	l := &outputLock{key: key, lock: lock}
+	select {
+	case lock.held <- struct{}{}:
+		return l, nil
+	default:
+	}
+	timer := time.NewTimer(outputLockWait)
+	defer timer.Stop()
+	select {
+	case lock.held <- struct{}{}:
+		return l, nil
+	case <-timer.C:
+		l.drop()
+		return nil, fmt.Errorf("failed to write %s: another writer has held it for over %s", path, outputLockWait)
+	}
+}
+
+// release lets the next writer have the path.
+func (l *outputLock) release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		<-l.lock.held
+		l.drop()
+	})
+}
+
+// drop stops using the path's lock, removing it once nobody else does.
+func (l *outputLock) drop() {
+	outputLocks.mu.Lock()
+	defer outputLocks.mu.Unlock()
+	l.lock.users--
+	if l.lock.users == 0 {
+		delete(outputLocks.paths, l.key)
+	}
+}
+
+// AppendLine appends a line to a file, creating the file if needed, so VUs can collect results
+// in a shared file. Strings are written as they are and other values as JSON, making the file
+// JSON lines. Writers of the same file take turns, so lines from concurrent VUs are never
+// interleaved, including with the other writers of this module. Each call opens and closes the
+// file, which is fine for result records but too slow for bulk output; use the array writers
+// for that.
+//
+// Returns: The number of bytes written, including the newline
+//
+// Example usage:
+//
+//	streamloader.appendLine("results.jsonl", { vu: __VU, iter: __ITER, status: res.status });
+func (StreamLoader) AppendLine(filePath string, line interface{}) (int, error) {
+	text, ok := line.(string)
+	if !ok {
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal line: %w", err)
+		}
+		text = string(encoded)
+	}
+	data := make([]byte, 0, len(text)+1)
+	data = append(append(data, text...), '\n')
+
+	lock, err := lockOutputPath(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.release()
+	slot, err := acquireFileSlot(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer slot.release()
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open output file: %w", err)
+	}
+	n, err := countingWriter{file}.Write(data)
+	if err != nil {
+		file.Close()
+		return n, fmt.Errorf("failed to append line: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return n, fmt.Errorf("failed to close output file: %w", err)
+	}
+	return n, nil
+}
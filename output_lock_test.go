@@ -0,0 +1,146 @@
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendLineConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	loader := StreamLoader{}
+	payload := strings.Repeat("x", 8192)
+
+	var wg sync.WaitGroup
+	for vu := 0; vu < 20; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				if _, err := loader.AppendLine(path, map[string]interface{}{"vu": vu, "iter": i, "payload": payload}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(vu)
+	}
+	wg.Wait()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024)
+	lines := 0
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d is corrupt: %v", lines+1, err)
+		}
+		lines++
+	}
+	if lines != 500 {
+		t.Errorf("read %d lines, want 500", lines)
+	}
+}
+
+func TestAppendLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	loader := StreamLoader{}
+	if n, err := loader.AppendLine(path, "plain text"); err != nil || n != 11 {
+		t.Fatalf("AppendLine(string) = %d, %v", n, err)
+	}
+	if _, err := loader.AppendLine(path, []interface{}{1, "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "plain text\n[1,\"a\"]\n" {
+		t.Errorf("file = %q", data)
+	}
+	if _, err := loader.AppendLine(path, func() {}); err == nil {
+		t.Error("expected an error for a value that can't be marshaled")
+	}
+	if _, err := loader.AppendLine(filepath.Join(path, "not a dir", "x"), "a"); err == nil {
+		t.Error("expected an error for an invalid path")
+	}
+}
+
+func TestConcurrentArrayWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	loader := StreamLoader{}
+
+	var wg sync.WaitGroup
+	for vu := 0; vu < 8; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			objects := make([]interface{}, 2000)
+			for i := range objects {
+				objects[i] = map[string]interface{}{"vu": vu, "i": i}
+			}
+			if _, err := loader.WriteObjectsToJsonArrayFile(objects, path); err != nil {
+				t.Error(err)
+			}
+		}(vu)
+	}
+	wg.Wait()
+
+	// The writers took turns, so the file holds exactly one writer's complete array
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("output is corrupt: %v", err)
+	}
+	if len(records) != 2000 {
+		t.Errorf("output has %d records, want 2000", len(records))
+	}
+}
+
+func TestOutputLockWait(t *testing.T) {
+	previous := outputLockWait
+	outputLockWait = 20 * time.Millisecond
+	t.Cleanup(func() { outputLockWait = previous })
+	path := filepath.Join(t.TempDir(), "out.json")
+
+	lock, err := lockOutputPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockOutputPath(path); err == nil || !strings.Contains(err.Error(), "another writer") {
+		t.Errorf("second lock error = %v", err)
+	}
+	// Locks are keyed by absolute path
+	wd, _ := os.Getwd()
+	if rel, err := filepath.Rel(wd, path); err == nil {
+		if _, err := lockOutputPath(rel); err == nil {
+			t.Errorf("locked %s while %s is locked", rel, path)
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		waiting, err := lockOutputPath(path)
+		waiting.release()
+		done <- err
+	}()
+	lock.release()
+	lock.release()
+	if err := <-done; err != nil {
+		t.Errorf("lock after release error = %v", err)
+	}
+
+	outputLocks.mu.Lock()
+	defer outputLocks.mu.Unlock()
+	if n := len(outputLocks.paths); n != 0 {
+		t.Errorf("%d path locks left after release", n)
+	}
+}
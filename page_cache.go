@@ -0,0 +1,43 @@
+// page_cache.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+)
+
+// Page cache modes for the pageCache option of the loaders
+const (
+	pageCacheKeep   = "keep"   // Default: read normally
+	pageCacheDrop   = "drop"   // Hint sequential access and drop pages behind the read position
+	pageCacheDirect = "direct" // Bypass the page cache with direct I/O
+)
+
+// pageCacheDropInterval is the number of bytes read between hints to drop cached pages
+const pageCacheDropInterval = 8 * 1024 * 1024
+
+// openSequential opens a file that is read once from start to end, applying the pageCache
+// option. Loading a corpus much larger than memory otherwise fills the page cache and evicts
+// pages other processes on the host depend on, such as a colocated system under test. The hints
+// are applied on Linux; other platforms read normally. The limits of the call reading the file
+// apply.
+func openSequential(filePath string, mode string, call callLimits) (io.ReadCloser, error) {
+	switch mode {
+	case "", pageCacheKeep, pageCacheDrop, pageCacheDirect:
+	default:
+		return nil, fmt.Errorf("invalid pageCache option %q: expected keep, drop or direct", mode)
+	}
+	if isDataURI(filePath) || isStreamInput(filePath) {
+		mode = pageCacheKeep // The data isn't in the page cache of a file
+	}
+	slot, err := acquireFileSlot(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openWithPageCacheMode(filePath, mode, call)
+	if err != nil {
+		slot.release()
+		return nil, err
+	}
+	return pooledReader{countingReader{file}, slot}, nil
+}
@@ -0,0 +1,173 @@
+//go:build linux
+
+// page_cache_linux.go
+package streamloader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// directIOAlignment is the buffer, offset and length alignment direct I/O requires
+const directIOAlignment = 4096
+
+// directIOBufferSize is the size of each direct read
+const directIOBufferSize = 1024 * 1024
+
+// openWithPageCacheMode opens the file and applies the page cache mode, and the limits of the
+// call reading it.
+func openWithPageCacheMode(filePath string, mode string, call callLimits) (io.ReadCloser, error) {
+	if mode == pageCacheDirect {
+		fd, err := unix.Open(filePath, unix.O_RDONLY|unix.O_DIRECT|unix.O_CLOEXEC, 0)
+		if err == nil {
+			file := os.NewFile(uintptr(fd), filePath)
+			if info, err := file.Stat(); err == nil {
+				if err := call.checkSize(filePath, info.Size()); err != nil {
+					file.Close()
+					return nil, err
+				}
+			}
+			return call.timed(&directReader{file: file, buf: alignedBuffer(directIOBufferSize)}), nil
+		}
+		if !errors.Is(err, syscall.EINVAL) {
+			return nil, &os.PathError{Op: "open", Path: filePath, Err: err}
+		}
+		// The file system doesn't support direct I/O, e.g. tmpfs
+		mode = pageCacheDrop
+	}
+
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return nil, err
+	}
+	if mode != pageCacheDrop {
+		return file, nil
+	}
+	unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_SEQUENTIAL)
+	return &dropBehindReader{file: file}, nil
+}
+
+// adviseWillNeed asks the kernel to read the whole file into the page cache ahead of reads.
+func adviseWillNeed(file *inputFile) {
+	if file.File == nil {
+		return // A data URI is already in memory
+	}
+	unix.Fadvise(int(file.Fd()), 0, 0, unix.FADV_WILLNEED)
+}
+
+// dropBehindReader tells the kernel to drop the cached pages of the part of the file already read.
+type dropBehindReader struct {
+	file    *inputFile
+	offset  int64 // Bytes read so far
+	dropped int64 // Bytes already dropped from the cache
+}
+
+func (r *dropBehindReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.offset += int64(n)
+	if r.offset-r.dropped >= pageCacheDropInterval {
+		unix.Fadvise(int(r.file.Fd()), r.dropped, r.offset-r.dropped, unix.FADV_DONTNEED)
+		r.dropped = r.offset
+	}
+	return n, err
+}
+
+func (r *dropBehindReader) Close() error {
+	unix.Fadvise(int(r.file.Fd()), r.dropped, 0, unix.FADV_DONTNEED)
+	return r.file.Close()
+}
+
+func (r *dropBehindReader) Stat() (os.FileInfo, error) {
+	return r.file.Stat()
+}
+
+// directReader reads a file opened with O_DIRECT in aligned blocks. If the file system rejects
+// direct reads, it reopens the file and continues with dropBehindReader.
+type directReader struct {
+	file     *os.File
+	buf      []byte
+	data     []byte // Unread part of buf
+	offset   int64  // File offset of the end of buf
+	fallback io.ReadCloser
+	eof      bool
+}
+
+func (r *directReader) Stat() (os.FileInfo, error) {
+	return r.file.Stat()
+}
+
+// alignedBuffer returns a buffer of size bytes whose address is aligned for direct I/O.
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directIOAlignment)
+	shift := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) & (directIOAlignment - 1)); rem != 0 {
+		shift = directIOAlignment - rem
+	}
+	return buf[shift : shift+size]
+}
+
+func (r *directReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.fallback != nil {
+			return r.fallback.Read(p)
+		}
+		if r.eof {
+			return 0, io.EOF
+		}
+		n, err := r.file.Read(r.buf)
+		if errors.Is(err, syscall.EINVAL) {
+			if err := r.switchToFallback(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		r.offset += int64(n)
+		r.data = r.buf[:n]
+		switch {
+		case err == io.EOF || (err == nil && n == 0):
+			r.eof = true
+		case err != nil:
+			return 0, err
+		case n%directIOAlignment != 0:
+			// Reads may come back short, e.g. on NFS or after a signal, but direct reads must
+			// start at an aligned offset, so whatever follows an unaligned one is read without
+			// direct I/O
+			if err := r.switchToFallback(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// switchToFallback reopens the file without direct I/O at the current offset.
+func (r *directReader) switchToFallback() error {
+	name := r.file.Name()
+	r.file.Close()
+	reader, err := openWithPageCacheMode(name, pageCacheDrop, callLimits{})
+	if err != nil {
+		return err
+	}
+	drop := reader.(*dropBehindReader)
+	if _, err := drop.file.Seek(r.offset, io.SeekStart); err != nil {
+		drop.Close()
+		return err
+	}
+	drop.offset, drop.dropped = r.offset, r.offset
+	r.fallback = drop
+	return nil
+}
+
+func (r *directReader) Close() error {
+	if r.fallback != nil {
+		return r.fallback.Close()
+	}
+	return r.file.Close()
+}
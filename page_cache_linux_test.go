@@ -0,0 +1,38 @@
+//go:build linux
+
+package streamloader
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDirectReaderShortReads(t *testing.T) {
+	// A pipe returns whatever has been written so far, so every read of the 1MB buffer is short
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5*directIOAlignment)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	go func() {
+		for off := 0; off < len(data); off += directIOAlignment {
+			pw.Write(data[off : off+directIOAlignment])
+		}
+		pw.Close()
+	}()
+
+	reader := &directReader{file: pr, buf: alignedBuffer(directIOBufferSize)}
+	defer reader.Close()
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Read %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+// page_cache_other.go
+package streamloader
+
+import (
+	"io"
+)
+
+// openWithPageCacheMode opens the file normally, applying the limits of the call reading it;
+// page cache hints are only applied on Linux.
+func openWithPageCacheMode(filePath string, mode string, call callLimits) (io.ReadCloser, error) {
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// adviseWillNeed does nothing; page cache hints are only applied on Linux.
+func adviseWillNeed(file *inputFile) {}
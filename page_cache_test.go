@@ -0,0 +1,70 @@
+package streamloader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenSequential(t *testing.T) {
+	dir := t.TempDir()
+	// Sizes around the direct I/O block and buffer sizes
+	for _, size := range []int{0, 1, 4095, 4096, 4097, 1024*1024 + 17, pageCacheDropInterval + 5} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i % 251)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("data-%d.bin", size))
+		os.WriteFile(path, data, 0644)
+
+		for _, mode := range []string{"", "keep", "drop", "direct"} {
+			reader, err := openSequential(path, mode, callLimits{})
+			if err != nil {
+				t.Fatalf("size %d, mode %q: openSequential() error = %v", size, mode, err)
+			}
+			got, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("size %d, mode %q: read %d bytes, %v", size, mode, len(got), err)
+			}
+		}
+	}
+
+	if _, err := openSequential(filepath.Join(dir, "missing"), "direct", callLimits{}); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := openSequential(filepath.Join(dir, "data-1.bin"), "bypass", callLimits{}); err == nil || !strings.Contains(err.Error(), "pageCache") {
+		t.Errorf("Expected error for invalid mode, got %v", err)
+	}
+}
+
+func TestPageCacheOption(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	jsonPath := filepath.Join(dir, "data.json")
+	os.WriteFile(jsonPath, []byte(`[{"id":1},{"id":2}]`), 0644)
+	data, err := loader.LoadJSON(jsonPath, JsonOptions{PageCache: "direct"})
+	if err != nil || !reflect.DeepEqual(data, []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}) {
+		t.Errorf("LoadJSON() = %v, %v", data, err)
+	}
+
+	csvPath := filepath.Join(dir, "data.csv")
+	os.WriteFile(csvPath, []byte("id,name\n1,a\n2,b\n"), 0644)
+	records, err := loader.LoadCSV(csvPath, CsvOptions{PageCache: "drop", LazyQuotes: true, ReuseRecord: false})
+	if err != nil || len(records) != 3 {
+		t.Errorf("LoadCSV() = %v, %v", records, err)
+	}
+	rows, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{SkipHeader: true, PageCache: "direct"})
+	if err != nil || len(rows) != 2 {
+		t.Errorf("ProcessCsvFile() = %v, %v", rows, err)
+	}
+	if _, err := loader.LoadCSV(csvPath, CsvOptions{PageCache: "invalid"}); err == nil {
+		t.Error("Expected error for invalid pageCache option")
+	}
+}
@@ -0,0 +1,64 @@
+// partial.go
+package streamloader
+
+import (
+	"sync"
+	"time"
+)
+
+// PartialError describes a load that failed partway through and returned the records parsed
+// before the error because the allowPartial option was set
+type PartialError struct {
+	Function string `json:"function" js:"function"`
+	Path     string `json:"path" js:"path"`
+	Records  int    `json:"records" js:"records"`
+	Error    string `json:"error" js:"error"`
+	Time     string `json:"time" js:"time"` // RFC 3339
+}
+
+// partialErrors lists the partial loads of every VU in the k6 process
+var partialErrors struct {
+	mu     sync.Mutex
+	errors []PartialError
+}
+
+// partialOrError handles an error hit while loading records. With allowPartial set and records
+// parsed before the error, the error is recorded for GetPartialErrors and the records are
+// returned instead; otherwise err is returned. Errors before the first record are never
+// partial, since there is nothing to degrade to.
+func partialOrError[T any](allowPartial bool, function string, path string, records []T, err error) ([]T, error) {
+	if !allowPartial || len(records) == 0 {
+		return nil, err
+	}
+	partialErrors.mu.Lock()
+	defer partialErrors.mu.Unlock()
+	partialErrors.errors = append(partialErrors.errors, PartialError{
+		Function: function,
+		Path:     path,
+		Records:  len(records),
+		Error:    err.Error(),
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	})
+	return records, nil
+}
+
+// GetPartialErrors returns the loads of any VU that hit an error after reading some records and
+// returned those records because the allowPartial option was set, oldest first. Check it after
+// loading in the init context or setup to report degraded data instead of aborting the test.
+//
+// Example usage:
+//
+//	const users = streamloader.loadJSON("users.json", { allowPartial: true });
+//	streamloader.getPartialErrors().forEach((e) => console.warn(`${e.path}: ${e.records} records, then ${e.error}`));
+func (StreamLoader) GetPartialErrors() []PartialError {
+	partialErrors.mu.Lock()
+	defer partialErrors.mu.Unlock()
+	return append([]PartialError{}, partialErrors.errors...)
+}
+
+// ClearPartialErrors empties the list returned by GetPartialErrors.
+func (StreamLoader) ClearPartialErrors() {
+	partialErrors.mu.Lock()
+	defer partialErrors.mu.Unlock()
+	partialErrors.errors = nil
+}
@@ -0,0 +1,129 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowPartial(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		load    func(path string, allowPartial bool) (int, error)
+		records int // Records returned with allowPartial, -1 if it still fails
+	}{
+		{
+			name:    "truncated JSON array",
+			file:    "truncated.json",
+			content: `[{"id":1},{"id":2},{"id":`,
+			load: func(path string, allowPartial bool) (int, error) {
+				data, err := loader.LoadJSON(path, JsonOptions{AllowPartial: allowPartial})
+				if err != nil {
+					return 0, err
+				}
+				return len(data.([]interface{})), nil
+			},
+			records: 2,
+		},
+		{
+			name:    "JSON array missing closing bracket",
+			file:    "unclosed.json",
+			content: `[{"id":1}`,
+			load: func(path string, allowPartial bool) (int, error) {
+				data, err := loader.LoadJSON(path, JsonOptions{AllowPartial: allowPartial, DetectDuplicateKeys: true})
+				if err != nil {
+					return 0, err
+				}
+				return len(data.([]interface{})), nil
+			},
+			records: 1,
+		},
+		{
+			name:    "NDJSON with a broken line",
+			file:    "broken.ndjson",
+			content: "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\n",
+			load: func(path string, allowPartial bool) (int, error) {
+				data, err := loader.LoadJSON(path, JsonOptions{AllowPartial: allowPartial})
+				if err != nil {
+					return 0, err
+				}
+				return len(data.([]map[string]any)), nil
+			},
+			records: 3,
+		},
+		{
+			name:    "error before the first record",
+			file:    "empty.json",
+			content: `[{"id":`,
+			load: func(path string, allowPartial bool) (int, error) {
+				_, err := loader.LoadJSON(path, JsonOptions{AllowPartial: allowPartial})
+				return 0, err
+			},
+			records: -1,
+		},
+		{
+			name:    "CSV with a bad quote",
+			file:    "bad.csv",
+			content: "id,name\n1,a\n2,\"b\"x\n3,c\n",
+			load: func(path string, allowPartial bool) (int, error) {
+				records, err := loader.LoadCSV(path, CsvOptions{AllowPartial: allowPartial})
+				return len(records), err
+			},
+			records: 2,
+		},
+		{
+			name:    "concatenated JSON",
+			file:    "concat.json",
+			content: `{"a":1}{"a":2}{"a"`,
+			load: func(path string, allowPartial bool) (int, error) {
+				values, err := loader.LoadConcatenatedJSON(path, JsonOptions{AllowPartial: allowPartial})
+				return len(values), err
+			},
+			records: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader.ClearPartialErrors()
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := tt.load(path, false); err == nil {
+				t.Fatal("expected an error without allowPartial")
+			}
+			n, err := tt.load(path, true)
+			if tt.records < 0 {
+				if err == nil {
+					t.Error("expected an error with allowPartial")
+				}
+				if len(loader.GetPartialErrors()) != 0 {
+					t.Error("failed load was reported as partial")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("allowPartial error = %v", err)
+			}
+			if n != tt.records {
+				t.Errorf("got %d records, want %d", n, tt.records)
+			}
+
+			partial := loader.GetPartialErrors()
+			if len(partial) != 1 {
+				t.Fatalf("GetPartialErrors() = %v, want one error", partial)
+			}
+			if partial[0].Path != path || partial[0].Records != tt.records || partial[0].Error == "" || partial[0].Time == "" {
+				t.Errorf("GetPartialErrors()[0] = %+v", partial[0])
+			}
+		})
+	}
+	loader.ClearPartialErrors()
+}
@@ -0,0 +1,82 @@
+// pick_random.go
+package streamloader
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// splitMixGamma is the increment of the SplitMix64 generator
+const splitMixGamma = 0x9e3779b97f4a7c15
+
+// splitMix64 scrambles the state of a SplitMix64 generator into its output.
+func splitMix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// pickIndex returns the index in [0, n) at position iteration of the SplitMix64 stream of seed.
+// Any position is computed directly, without generating the ones before it, and the reduction
+// to [0, n) is unbiased (Lemire's method).
+func pickIndex(seed int64, iteration int64, n int) int {
+	state := uint64(seed) + uint64(iteration+1)*splitMixGamma
+	hi, lo := bits.Mul64(splitMix64(state), uint64(n))
+	if lo < uint64(n) {
+		threshold := -uint64(n) % uint64(n)
+		for lo < threshold {
+			// Rare retry: draw from a generator seeded with this position
+			state += splitMixGamma
+			hi, lo = bits.Mul64(splitMix64(state^0xd1b54a32d192ed03), uint64(n))
+		}
+	}
+	return int(hi)
+}
+
+// PickRandom returns a record chosen at random from a dataset by a pseudo-random generator that
+// depends only on seed and iteration: the same seed and iteration always pick the same record,
+// on any machine and in any version, so two runs with the same seed replay the same data in the
+// same order, as regression comparisons between releases need. Different iterations are
+// independent draws, so records may repeat.
+//
+// dataset is the array of records, a sequence, or the name of a dataset registered with
+// RegisterDataset. Pass a counter that runs the same way in both runs as iteration, such as
+// exec.scenario.iterationInTest, or exec.vu.iterationInScenario with a seed per VU.
+//
+// Example usage:
+//
+//	streamloader.registerDataset("users", "users.json");
+//	// In the default function:
+//	const user = streamloader.pickRandom("users", 42, exec.scenario.iterationInTest);
+func (s StreamLoader) PickRandom(dataset interface{}, seed int64, iteration int64) (interface{}, error) {
+	if iteration < 0 {
+		return nil, fmt.Errorf("iteration must not be negative, got %d", iteration)
+	}
+	shared := false
+	if name, ok := dataset.(string); ok {
+		data, err := getDataset(name)
+		if err != nil {
+			return nil, err
+		}
+		dataset, shared = data, true
+	}
+	switch v := dataset.(type) {
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("cannot pick from an empty dataset")
+		}
+		record := v[pickIndex(seed, iteration, len(v))]
+		if shared || isDatasetData(v) {
+			// Records of registered datasets are read-only, as with GetDataset
+			return s.sharedView(record), nil
+		}
+		return record, nil
+	case *Sequence:
+		if v.Length() == 0 {
+			return nil, fmt.Errorf("cannot pick from an empty sequence")
+		}
+		return v.At(pickIndex(seed, iteration, v.Length()))
+	default:
+		return nil, fmt.Errorf("cannot pick from %T: expected an array, sequence or registered dataset name", dataset)
+	}
+}
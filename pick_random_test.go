@@ -0,0 +1,84 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPickRandom(t *testing.T) {
+	// The first output of SplitMix64 seeded with 0 is the reference value
+	if got := splitMix64(splitMixGamma); got != 0xe220a8397b1dcdaf {
+		t.Fatalf("splitMix64() = %#x, want 0xe220a8397b1dcdaf", got)
+	}
+
+	loader := StreamLoader{}
+	records := make([]interface{}, 1000)
+	for i := range records {
+		records[i] = float64(i)
+	}
+
+	// Picks never change for a seed, so recorded sequences stay valid across versions
+	var picks []interface{}
+	for i := int64(0); i < 8; i++ {
+		record, err := loader.PickRandom(records, 42, i)
+		if err != nil {
+			t.Fatalf("PickRandom() error = %v", err)
+		}
+		picks = append(picks, record)
+	}
+	want := []interface{}{741.0, 159.0, 278.0, 344.0, 38.0, 868.0, 218.0, 800.0}
+	if !reflect.DeepEqual(picks, want) {
+		t.Errorf("PickRandom() picks = %v, want %v", picks, want)
+	}
+	if other, _ := loader.PickRandom(records, 43, 0); other == picks[0] {
+		t.Errorf("PickRandom() with another seed picked the same record %v", other)
+	}
+
+	// Picks are spread evenly
+	counts := make([]int, 4)
+	for i := int64(0); i < 40000; i++ {
+		record, _ := loader.PickRandom([]interface{}{0, 1, 2, 3}, 7, i)
+		counts[record.(int)]++
+	}
+	for i, count := range counts {
+		if count < 9500 || count > 10500 {
+			t.Errorf("record %d picked %d times out of 40000", i, count)
+		}
+	}
+
+	// Sequences and registered datasets
+	seq, _ := loader.GenerateRange(0, 1000)
+	if got, _ := loader.PickRandom(seq, 42, 0); got != int64(741) {
+		t.Errorf("PickRandom(sequence) = %v (%T), want 741", got, got)
+	}
+	resetDatasets(t)
+	path := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(path, []byte(`[{"id":"a"},{"id":"b"},{"id":"c"}]`), 0644)
+	if err := loader.RegisterDataset("users", path); err != nil {
+		t.Fatal(err)
+	}
+	first, err := loader.PickRandom("users", 1, 5)
+	if err != nil {
+		t.Fatalf("PickRandom(dataset) error = %v", err)
+	}
+	if again, _ := loader.PickRandom("users", 1, 5); !reflect.DeepEqual(first, again) {
+		t.Errorf("PickRandom(dataset) = %v, then %v", first, again)
+	}
+
+	invalid := []struct {
+		dataset   interface{}
+		iteration int64
+	}{
+		{records, -1},
+		{[]interface{}{}, 0},
+		{"unregistered", 0},
+		{42, 0},
+	}
+	for _, tt := range invalid {
+		if _, err := loader.PickRandom(tt.dataset, 1, tt.iteration); err == nil {
+			t.Errorf("PickRandom(%v, 1, %d) expected an error", tt.dataset, tt.iteration)
+		}
+	}
+}
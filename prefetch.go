@@ -0,0 +1,194 @@
+// prefetch.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// prefetchBufferSize is the size of the reads that pull a file into the page cache
+const prefetchBufferSize = 1024 * 1024
+
+// PrefetchOptions configures Prefetch
+type PrefetchOptions struct {
+	Concurrency int `json:"concurrency" js:"concurrency"`
+}
+
+// PrefetchResult describes the files a Prefetch has read
+type PrefetchResult struct {
+	Files      int   `json:"files" js:"files"`           // Files read to the end
+	Bytes      int64 `json:"bytes" js:"bytes"`           // Bytes read
+	DurationMs int64 `json:"durationMs" js:"durationMs"` // Time from the start of the prefetch to the end of the last read
+	Canceled   bool  `json:"canceled" js:"canceled"`     // Close stopped the prefetch before every file was read
+}
+
+// Prefetch reads files in the background, returned by StreamLoader.Prefetch.
+type Prefetch struct {
+	done   chan struct{}
+	stop   chan struct{}
+	closed sync.Once
+	paths  []string
+	errs   []error
+	result PrefetchResult
+}
+
+// Prefetch reads files in background goroutines so they are in the operating system's page
+// cache by the time a loader opens them. Call it in the init context or setup, where reading a
+// large dataset from a cold disk would otherwise happen in the first iterations and show up as
+// latency spikes in their metrics. It returns at once; call wait on the result to block until
+// every file is read, for example at the end of setup. On Linux the kernel is also told to read
+// ahead the whole file. Each path is read once, even if it is listed more than once.
+//
+// Options:
+//   - concurrency: The maximum number of files read at once (default: the number of CPUs)
+//
+// The files are read whole, so prefetching more than fits in free memory only evicts the first
+// files again. Files opened with the pageCache option set to drop or direct are not served from
+// the cache by design.
+//
+// Example usage:
+//
+//	const warmup = streamloader.prefetch(["users.json", "orders.csv"]);
+//	export function setup() {
+//		const { files, bytes } = warmup.wait();
+//	}
+func (s StreamLoader) Prefetch(filePaths []string, options ...PrefetchOptions) (*Prefetch, error) {
+	var opts PrefetchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Concurrency < 0 {
+		return nil, fmt.Errorf("concurrency must not be negative, got %d", opts.Concurrency)
+	}
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	paths := make([]string, 0, len(filePaths))
+	seen := make(map[string]bool, len(filePaths))
+	for _, path := range filePaths {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	p := &Prefetch{done: make(chan struct{}), stop: make(chan struct{}), paths: paths, errs: make([]error, len(paths))}
+	start := time.Now()
+	sizes := make([]int64, len(paths))
+	complete := make([]bool, len(paths))
+	next := make(chan int)
+	call := s.call.untimed() // The files are read after the call returns
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(paths)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, prefetchBufferSize)
+			for i := range next {
+				sizes[i], complete[i], p.errs[i] = p.read(paths[i], buf, call)
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for i := range paths {
+			select {
+			case next <- i:
+			case <-p.stop:
+				break feed
+			}
+		}
+		close(next)
+		wg.Wait()
+
+		for i := range paths {
+			if complete[i] {
+				p.result.Files++
+			}
+			p.result.Bytes += sizes[i]
+		}
+		p.result.Canceled = p.result.Files < len(paths) && p.isClosed()
+		p.result.DurationMs = time.Since(start).Milliseconds()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// read reads a file until its end or until the prefetch is closed, and returns the bytes read
+// and whether the end was reached.
+func (p *Prefetch) read(filePath string, buf []byte, call callLimits) (int64, bool, error) {
+	slot, err := acquireFileSlot(filePath)
+	if err != nil {
+		return 0, false, err
+	}
+	defer slot.release()
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+	adviseWillNeed(file)
+
+	var total int64
+	for !p.isClosed() {
+		n, err := file.Read(buf)
+		total += int64(n)
+		if err == io.EOF {
+			return total, true, nil
+		}
+		if err != nil {
+			return total, false, err
+		}
+	}
+	return total, false, nil
+}
+
+// isClosed reports whether Close was called.
+func (p *Prefetch) isClosed() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait blocks until every file is read or the prefetch is closed, and returns an error naming
+// the first file in the list that could not be read.
+func (p *Prefetch) Wait() (*PrefetchResult, error) {
+	<-p.done
+	for i, err := range p.errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to prefetch %s: %w", p.paths[i], err)
+		}
+	}
+	result := p.result
+	return &result, nil
+}
+
+// Done reports whether the prefetch has finished, without blocking.
+func (p *Prefetch) Done() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops reading and waits for the files being read to be closed.
+func (p *Prefetch) Close() {
+	p.closed.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// Dispose is an alias of Close.
+func (p *Prefetch) Dispose() {
+	p.Close()
+}
@@ -0,0 +1,88 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestPrefetch(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.json")
+	os.WriteFile(users, []byte(`[{"id":1},{"id":2}]`), 0644)
+	orders := filepath.Join(dir, "orders.csv")
+	os.WriteFile(orders, []byte(strings.Repeat("1,2,3\n", 1000)), 0644)
+
+	tests := []struct {
+		name      string
+		paths     []string
+		options   PrefetchOptions
+		wantFiles int
+		wantBytes int64
+	}{
+		{"files", []string{users, orders}, PrefetchOptions{}, 2, 19 + 6000},
+		{"duplicates are read once", []string{users, users}, PrefetchOptions{Concurrency: 1}, 1, 19},
+		{"no files", nil, PrefetchOptions{}, 0, 0},
+	}
+	for _, tt := range tests {
+		prefetch, err := loader.Prefetch(tt.paths, tt.options)
+		if err != nil {
+			t.Fatalf("%s: Prefetch() error = %v", tt.name, err)
+		}
+		result, err := prefetch.Wait()
+		if err != nil {
+			t.Fatalf("%s: Wait() error = %v", tt.name, err)
+		}
+		if result.Files != tt.wantFiles || result.Bytes != tt.wantBytes || result.Canceled {
+			t.Errorf("%s: Wait() = %+v, want %d files and %d bytes", tt.name, result, tt.wantFiles, tt.wantBytes)
+		}
+		if !prefetch.Done() {
+			t.Errorf("%s: Done() = false after Wait()", tt.name)
+		}
+	}
+
+	prefetch, _ := loader.Prefetch([]string{users, filepath.Join(dir, "missing.json")})
+	if _, err := prefetch.Wait(); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("Wait() error = %v, want one naming the missing file", err)
+	}
+
+	// Closing stops the prefetch; closing twice is fine
+	prefetch, _ = loader.Prefetch([]string{users, orders})
+	prefetch.Close()
+	prefetch.Close()
+	if !prefetch.Done() {
+		t.Error("Done() = false after Close()")
+	}
+
+	if _, err := loader.Prefetch([]string{users}, PrefetchOptions{Concurrency: -1}); err == nil {
+		t.Error("Prefetch() with a negative concurrency expected an error")
+	}
+}
+
+func TestPrefetchFromScript(t *testing.T) {
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "data.json")
+	os.WriteFile(path, []byte(`[1,2,3]`), 0644)
+	rt.Set("path", path)
+	value, err := rt.RunString(`
+		const warmup = streamloader.prefetch([path], { concurrency: 2 });
+		const result = warmup.wait();
+		[result.files, result.bytes, warmup.done()].join(" ");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != "1 7 true" {
+		t.Errorf("script result = %s", got)
+	}
+}
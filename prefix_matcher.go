@@ -0,0 +1,254 @@
+// prefix_matcher.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// prefixPattern is one pattern of a prefix matcher.
+type prefixPattern struct {
+	group  string
+	method string          // Upper case, "" for any method
+	re     *regexp.Regexp  // nil when the pattern is a literal prefix
+	entry  json.RawMessage // The pattern as given in the file, decoded afresh for every VU
+}
+
+// prefixNode is a node of the byte trie over the literal prefixes of the patterns.
+type prefixNode struct {
+	children map[byte]*prefixNode
+	patterns []*prefixPattern // Patterns whose literal prefix ends at this node, in file order
+}
+
+// prefixTrie is the data of a prefix matcher, read-only once built.
+type prefixTrie struct {
+	root   *prefixNode
+	groups []string
+}
+
+// PrefixMatcher is a read-only handle on a prefix matcher shared by every VU. Close detaches the
+// handle; the matcher itself stays in memory for the other VUs.
+type PrefixMatcher struct {
+	mu   sync.RWMutex
+	key  string      // Key of the shared trie, for Release
+	trie *prefixTrie // nil once closed
+}
+
+// BuildPrefixMatcher builds a matcher that classifies URIs into endpoint groups, replacing
+// chains of regular expressions evaluated in JavaScript. The patterns file is a recording stats
+// file, whose filterStats entries each define a group by uriRegex and method, or a JSON array of
+// prefix strings or of objects with these fields:
+//   - prefix or uriRegex: The start of the URIs in the group. Regular expression syntax is
+//     honoured, but the literal characters before the first metacharacter are looked up in a
+//     trie, so only patterns sharing a URI's prefix are ever evaluated.
+//   - group: Name returned by Match (default: the pattern itself)
+//   - method: Only match requests with this HTTP method (default: any)
+//
+// A URI belongs to the pattern with the longest literal prefix that matches the start of the
+// URI; patterns with equally long prefixes are tried in file order. Like BuildLookup, the matcher
+// is built once per process and shared by every VU.
+//
+// Example usage:
+//
+//	const endpoints = streamloader.buildPrefixMatcher("recording-stats.json");
+//	// In the default function:
+//	const group = endpoints.match(request.uri, request.method);
+func (s StreamLoader) BuildPrefixMatcher(patternsFile string) (*PrefixMatcher, error) {
+	key := sharedBuildKey("prefix", patternsFile)
+	trie, err := buildShared(key, func() (*prefixTrie, error) {
+		data, err := s.LoadJSON(patternsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load patterns: %w", err)
+		}
+		return buildPrefixTrie(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	matcher := &PrefixMatcher{key: key, trie: trie}
+	s.closeAtIterationEnd(matcher)
+	return matcher, nil
+}
+
+// buildPrefixTrie builds the trie for the contents of a patterns file.
+func buildPrefixTrie(data any) (*prefixTrie, error) {
+	items, err := statsEntries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	trie := &prefixTrie{root: &prefixNode{}}
+	seen := make(map[string]bool)
+	for i, item := range items {
+		entry, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+		p := &prefixPattern{entry: entry}
+		var pattern string
+		switch v := item.(type) {
+		case string:
+			pattern = v
+		case map[string]interface{}:
+			pattern, _ = v["prefix"].(string)
+			if pattern == "" {
+				pattern, _ = v["uriRegex"].(string)
+			}
+			p.group, _ = v["group"].(string)
+			method, _ := v["method"].(string)
+			p.method = strings.ToUpper(method)
+		default:
+			return nil, fmt.Errorf("pattern %d: expected a string or object, got %T", i, item)
+		}
+		if pattern == "" {
+			return nil, fmt.Errorf("pattern %d: prefix or uriRegex is required", i)
+		}
+		if p.group == "" {
+			p.group = pattern
+		}
+
+		pattern = strings.TrimPrefix(pattern, "^")
+		literal := literalPrefix(pattern)
+		if literal != pattern {
+			re, err := regexp.Compile("^(?:" + pattern + ")")
+			if err != nil {
+				return nil, fmt.Errorf("pattern %d: %w", i, err)
+			}
+			p.re = re
+		}
+
+		node := trie.root
+		for j := 0; j < len(literal); j++ {
+			if node.children == nil {
+				node.children = make(map[byte]*prefixNode)
+			}
+			child, ok := node.children[literal[j]]
+			if !ok {
+				child = &prefixNode{}
+				node.children[literal[j]] = child
+			}
+			node = child
+		}
+		node.patterns = append(node.patterns, p)
+		if !seen[p.group] {
+			seen[p.group] = true
+			trie.groups = append(trie.groups, p.group)
+		}
+	}
+	return trie, nil
+}
+
+// literalPrefix returns the characters of a regular expression before its first metacharacter.
+// A character followed by a quantifier is excluded, since it may not appear, and a pattern with
+// alternatives has no common prefix.
+func literalPrefix(pattern string) string {
+	if strings.Contains(pattern, "|") {
+		return ""
+	}
+	end := strings.IndexAny(pattern, `\.+*?()|[]{}^$`)
+	if end < 0 {
+		return pattern
+	}
+	if end > 0 && strings.ContainsRune("*?{", rune(pattern[end])) {
+		end--
+	}
+	return pattern[:end]
+}
+
+// match returns the pattern a URI belongs to, or nil.
+func (t *prefixTrie) match(uri string, method string) *prefixPattern {
+	// Collect the nodes along the URI, then try the deepest ones first
+	nodes := []*prefixNode{t.root}
+	node := t.root
+	for i := 0; i < len(uri) && node.children != nil; i++ {
+		if node = node.children[uri[i]]; node == nil {
+			break
+		}
+		nodes = append(nodes, node)
+	}
+	method = strings.ToUpper(method)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		for _, p := range nodes[i].patterns {
+			if p.method != "" && method != "" && p.method != method {
+				continue
+			}
+			if p.re == nil || p.re.MatchString(uri) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// shared returns the matcher, or an error if the handle is closed.
+func (m *PrefixMatcher) shared() (*prefixTrie, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.trie == nil {
+		return nil, fmt.Errorf("prefix matcher is closed")
+	}
+	return m.trie, nil
+}
+
+// Match returns the group of a URI, or null if no pattern matches. Patterns restricted to a
+// method only match if the method is given and equal; without a method, every pattern is tried.
+func (m *PrefixMatcher) Match(uri string, method ...string) (any, error) {
+	trie, err := m.shared()
+	if err != nil {
+		return nil, err
+	}
+	if p := trie.match(uri, firstOr(method, "")); p != nil {
+		return p.group, nil
+	}
+	return nil, nil
+}
+
+// MatchEntry returns the pattern entry a URI belongs to, as given in the patterns file (such as
+// its filterStats entry with the weight and counts), or null if no pattern matches.
+func (m *PrefixMatcher) MatchEntry(uri string, method ...string) (any, error) {
+	trie, err := m.shared()
+	if err != nil {
+		return nil, err
+	}
+	p := trie.match(uri, firstOr(method, ""))
+	if p == nil {
+		return nil, nil
+	}
+	var entry any
+	if err := json.Unmarshal(p.entry, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode pattern entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Groups returns the group names in file order.
+func (m *PrefixMatcher) Groups() ([]string, error) {
+	trie, err := m.shared()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), trie.groups...), nil
+}
+
+// Close detaches the handle from the shared matcher. Afterwards its methods return an error.
+func (m *PrefixMatcher) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trie = nil
+}
+
+// Dispose is an alias of Close.
+func (m *PrefixMatcher) Dispose() {
+	m.Close()
+}
+
+// firstOr returns the first of the optional arguments, or fallback if there is none.
+func firstOr(values []string, fallback string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return fallback
+}
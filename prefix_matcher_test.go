@@ -0,0 +1,164 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writePatterns(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "patterns.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildPrefixMatcher(t *testing.T) {
+	resetSharedBuilds(t)
+	path := writePatterns(t, `[
+		"/api",
+		{"prefix": "/api/users", "group": "users"},
+		{"prefix": "/api/users", "group": "user-updates", "method": "put"},
+		{"uriRegex": "^/api/v[0-9]+/orders", "group": "orders"},
+		{"prefix": "/static/", "group": "static"},
+		{"uriRegex": "/health|/ready", "group": "probes"}
+	]`)
+
+	matcher, err := StreamLoader{}.BuildPrefixMatcher(path)
+	if err != nil {
+		t.Fatalf("BuildPrefixMatcher() error = %v", err)
+	}
+
+	tests := []struct {
+		uri    string
+		method []string
+		want   interface{}
+	}{
+		{"/api/users/42", nil, "users"},
+		{"/api/users/42", []string{"GET"}, "users"},
+		{"/api/users/42", []string{"PUT"}, "users"}, // Equal prefixes are tried in file order
+		{"/api/v2/orders/7", nil, "orders"},
+		{"/api/vX/orders", nil, "/api"}, // The regex fails, so a shorter prefix wins
+		{"/api", nil, "/api"},
+		{"/static/app.js", nil, "static"},
+		{"/ready", nil, "probes"},
+		{"/other", nil, nil},
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		got, err := matcher.Match(tt.uri, tt.method...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.uri, tt.method, got, tt.want)
+		}
+	}
+
+	groups, _ := matcher.Groups()
+	if want := []string{"/api", "users", "user-updates", "orders", "static", "probes"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("Groups() = %v, want %v", groups, want)
+	}
+}
+
+func TestBuildPrefixMatcherMethods(t *testing.T) {
+	resetSharedBuilds(t)
+	path := writePatterns(t, `[
+		{"prefix": "/items", "group": "update", "method": "PUT"},
+		{"prefix": "/items", "group": "read", "method": "GET"}
+	]`)
+	matcher, err := StreamLoader{}.BuildPrefixMatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, want := range map[string]interface{}{"get": "read", "PUT": "update", "DELETE": nil} {
+		if got, _ := matcher.Match("/items/1", method); got != want {
+			t.Errorf("Match(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestBuildPrefixMatcherRecordingStats(t *testing.T) {
+	resetSharedBuilds(t)
+	path := writePatterns(t, `{
+		"recordingId": "18aebc27",
+		"filterStats": [
+			{"domain": "EATS", "method": "GET", "uriRegex": "/endpoint/store.get_gateway", "weight": 361},
+			{"domain": "EATS", "method": "POST", "uriRegex": "/endpoint/cart", "weight": 12}
+		]
+	}`)
+	matcher, err := StreamLoader{}.BuildPrefixMatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := matcher.Match("/endpoint/store.get_gateway?id=1", "GET"); got != "/endpoint/store.get_gateway" {
+		t.Errorf("Match() = %v", got)
+	}
+	entry, err := matcher.MatchEntry("/endpoint/cart/add", "POST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.(map[string]interface{})["weight"] != float64(12) {
+		t.Errorf("MatchEntry() = %v", entry)
+	}
+
+	// Entries are copies, so changing one doesn't affect other VUs
+	entry.(map[string]interface{})["weight"] = 0
+	if again, _ := matcher.MatchEntry("/endpoint/cart", "POST"); again.(map[string]interface{})["weight"] != float64(12) {
+		t.Error("changing a returned entry changed the matcher")
+	}
+
+	matcher.Close()
+	if _, err := matcher.Match("/endpoint/cart"); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Match() after Close error = %v", err)
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := map[string]string{
+		"/api/users":       "/api/users",
+		"/api/v[0-9]+/x":   "/api/v",
+		"/store.get":       "/store",
+		"/items?":          "/item",
+		"/a{2}":            "/",
+		`/path\.json`:      "/path",
+		"(/health|/ready)": "",
+		"/files/.*\\.png$": "/files/",
+		"/health|/ready":   "",
+	}
+	for pattern, want := range tests {
+		if got := literalPrefix(pattern); got != want {
+			t.Errorf("literalPrefix(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestBuildPrefixMatcherErrors(t *testing.T) {
+	resetSharedBuilds(t)
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"not an array", `{"a": 1}`, "filterStats"},
+		{"bad entry", `[1]`, "pattern 0: expected a string or object"},
+		{"missing prefix", `[{"group": "x"}]`, "prefix or uriRegex is required"},
+		{"invalid regex", `["/a(b"]`, "pattern 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamLoader{}.BuildPrefixMatcher(writePatterns(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildPrefixMatcher() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := (StreamLoader{}).BuildPrefixMatcher("missing.json"); err == nil || !strings.Contains(err.Error(), "failed to load patterns") {
+		t.Errorf("BuildPrefixMatcher() of a missing file error = %v", err)
+	}
+}
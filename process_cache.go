@@ -0,0 +1,106 @@
+// process_cache.go
+package streamloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// processCacheVersion is part of every cache key, so results cached by an older version of the
+// pipeline are never reused
+const processCacheVersion = "1"
+
+// processCsvCached returns the cached result for the files and options if there is one, and
+// otherwise runs the pipeline and stores its result in the cache directory.
+func processCsvCached(paths []string, options ProcessCsvOptions, call callLimits) ([][]interface{}, error) {
+	key, err := processCsvCacheKey(paths, options)
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(options.Cache, "processcsv-"+key+".json")
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var cached [][]interface{}
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return cached, nil
+		}
+		// A corrupt entry is treated as a miss and overwritten below
+	}
+
+	result, err := processCsvPaths(paths, options, nil, call)
+	if err != nil {
+		return nil, err
+	}
+	if readOnly.Load() {
+		return result, nil
+	}
+	if err := writeProcessCsvCache(options.Cache, cachePath, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// processCsvCacheKey hashes the input files' paths, sizes and modification times together with
+// the options, so any change to the data or the pipeline produces a new key.
+func processCsvCacheKey(paths []string, options ProcessCsvOptions) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(processCacheVersion + "\n"))
+	for _, path := range paths {
+		info, err := statInput(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to open CSV file: %w", err)
+		}
+		if isStreamMode(info.Mode()) {
+			return "", fmt.Errorf("the cache option can't be used with %s, which is a named pipe or other stream", path)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		fmt.Fprintf(h, "%s\n%d\n%d\n", abs, info.Size(), info.ModTime().UnixNano())
+	}
+
+	// The cache directory and page cache mode don't affect the result
+	options.Cache = ""
+	options.PageCache = ""
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash options: %w", err)
+	}
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeProcessCsvCache stores a result atomically, so concurrent runs never read a partial entry.
+func writeProcessCsvCache(dir string, cachePath string, result [][]interface{}) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached result: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "processcsv-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), cachePath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProcessCsvFile_Cache(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	csvPath := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(csvPath, []byte("id,name\n1,alice\n2,bob\n"), 0644); err != nil {
+		t.Fatalf("failed to write CSV: %v", err)
+	}
+
+	options := ProcessCsvOptions{SkipHeader: true, Cache: cacheDir}
+	cacheEntries := func() []string {
+		matches, _ := filepath.Glob(filepath.Join(cacheDir, "processcsv-*.json"))
+		return matches
+	}
+
+	first, err := loader.ProcessCsvFile(csvPath, options)
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	entries := cacheEntries()
+	if len(entries) != 1 {
+		t.Fatalf("expected one cache entry, got %v", entries)
+	}
+
+	// Tamper with the cache entry to prove the second run reads it
+	os.WriteFile(entries[0], []byte(`[["cached"]]`), 0644)
+	cached, err := loader.ProcessCsvFile(csvPath, options)
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(cached, [][]interface{}{{"cached"}}) {
+		t.Errorf("expected the cached result, got %v", cached)
+	}
+
+	// Different options miss the cache
+	reversed, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		Cache:      cacheDir,
+		Fields:     []FieldConfig{{Type: "column", Column: 1}},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(reversed, [][]interface{}{{"alice"}, {"bob"}}) {
+		t.Errorf("unexpected result for new options: %v", reversed)
+	}
+
+	// Modifying the file invalidates the entry
+	os.WriteFile(csvPath, []byte("id,name\n1,alice\n2,bob\n3,carol\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(csvPath, later, later)
+	updated, err := loader.ProcessCsvFile(csvPath, options)
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	if len(updated) != 3 || len(first) != 2 {
+		t.Errorf("expected fresh results after a change, got %v", updated)
+	}
+
+	// A corrupt entry is ignored and rewritten
+	for _, entry := range cacheEntries() {
+		os.WriteFile(entry, []byte("{broken"), 0644)
+	}
+	if again, err := loader.ProcessCsvFile(csvPath, options); err != nil || !reflect.DeepEqual(again, updated) {
+		t.Errorf("expected a recomputed result, got %v (err %v)", again, err)
+	}
+}
@@ -0,0 +1,35 @@
+// provenance.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// recordProvenance identifies the source of a record written by a combining operation
+type recordProvenance struct {
+	File   string `json:"file"`
+	Record int    `json:"record"`
+}
+
+// addProvenance sets field on a JSON object to {"file": filePath, "record": index}, so records
+// in combined outputs can be traced back to the exact source record. An existing field with the
+// same name is replaced in place; otherwise the field is appended.
+func addProvenance(raw json.RawMessage, field string, filePath string, index int) (json.RawMessage, error) {
+	names, values, err := objectFields(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record %d in %s: %w", index, filePath, err)
+	}
+	if names == nil {
+		return nil, fmt.Errorf("cannot add provenance to record %d in %s: not a JSON object", index, filePath)
+	}
+
+	encoded, _ := json.Marshal(recordProvenance{File: filePath, Record: index})
+	for i, name := range names {
+		if name == field {
+			values[i] = encoded
+			return encodeObjectFields(names, values), nil
+		}
+	}
+	return encodeObjectFields(append(names, field), append(values, encoded)), nil
+}
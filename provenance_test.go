@@ -0,0 +1,74 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvenance_CombineAndInterleave(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	os.WriteFile(first, []byte(`[{"id":1},{"id":2,"_source":"stale"}]`), 0644)
+	os.WriteFile(second, []byte(`[{"id":3}]`), 0644)
+
+	t.Run("CombineJsonArrayFiles", func(t *testing.T) {
+		output := filepath.Join(dir, "combined.json")
+		count, err := loader.CombineJsonArrayFiles([]string{first, second}, output, map[string]interface{}{"provenance": "_source"})
+		if err != nil || count != 3 {
+			t.Fatalf("CombineJsonArrayFiles() = %d, %v", count, err)
+		}
+		data, _ := os.ReadFile(output)
+		expected := fmt.Sprintf(`[{"id":1,"_source":{"file":%q,"record":0}},{"id":2,"_source":{"file":%q,"record":1}},{"id":3,"_source":{"file":%q,"record":0}}]`, first, first, second)
+		if string(data) != expected {
+			t.Errorf("got %s\nwant %s", data, expected)
+		}
+	})
+
+	t.Run("InterleaveFiles", func(t *testing.T) {
+		output := filepath.Join(dir, "mixed.json")
+		_, err := loader.InterleaveFiles([]InterleaveSource{{Path: first, Ratio: 1}, {Path: second, Ratio: 1}}, output, InterleaveOptions{Provenance: "src", ExhaustAll: true})
+		if err != nil {
+			t.Fatalf("InterleaveFiles() error = %v", err)
+		}
+		data, _ := os.ReadFile(output)
+		expected := fmt.Sprintf(`[{"id":1,"src":{"file":%q,"record":0}},{"id":3,"src":{"file":%q,"record":0}},{"id":2,"_source":"stale","src":{"file":%q,"record":1}}]`, first, second, first)
+		if string(data) != expected {
+			t.Errorf("got %s\nwant %s", data, expected)
+		}
+	})
+
+	t.Run("non-object records", func(t *testing.T) {
+		primitives := filepath.Join(dir, "primitives.json")
+		os.WriteFile(primitives, []byte(`[1,2]`), 0644)
+		if _, err := loader.CombineJsonArrayFiles([]string{primitives}, filepath.Join(dir, "out.json"), JsonWriterOptions{Provenance: "_source"}); err == nil {
+			t.Error("Expected error when adding provenance to a non-object")
+		}
+	})
+}
+
+func TestProvenance_ProcessCsvSourceLine(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "data.csv")
+	os.WriteFile(csvPath, []byte("id,note\r\n1,a\r\n\r\n2,\"multi\nline\"\r\n3,c\r\n"), 0644)
+
+	result, err := loader.ProcessCsvFile(csvPath, ProcessCsvOptions{
+		SkipHeader: true,
+		Fields: []FieldConfig{
+			{Type: "column", Column: 0},
+			{Type: "sourceFile"},
+			{Type: "sourceLine"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	expected := fmt.Sprint([][]interface{}{{"1", csvPath, 2}, {"2", csvPath, 4}, {"3", csvPath, 6}})
+	if got := fmt.Sprint(result); got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
@@ -0,0 +1,56 @@
+// read_only.go
+package streamloader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// readOnlyEnv is the environment variable that switches the process to read-only mode
+const readOnlyEnv = "STREAMLOADER_READ_ONLY"
+
+// errReadOnly is returned by every function that would write to storage in read-only mode
+var errReadOnly = errors.New("streamloader is in read-only mode (" + readOnlyEnv + ")")
+
+// readOnly is set once from the environment when the process starts, so scripts can't turn it off
+var readOnly atomic.Bool
+
+func init() {
+	readOnly.Store(readOnlyFromEnv())
+}
+
+// readOnlyFromEnv reports whether the environment asks for read-only mode: 1, true or yes.
+func readOnlyFromEnv() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(readOnlyEnv))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// checkWritable returns an error if path may not be written because of read-only mode.
+func checkWritable(path string) error {
+	if readOnly.Load() {
+		return fmt.Errorf("cannot write %s: %w", path, errReadOnly)
+	}
+	return nil
+}
+
+// IsReadOnly reports whether the process runs in read-only mode, set by starting k6 with the
+// environment variable STREAMLOADER_READ_ONLY=1. In read-only mode every function that would
+// create, modify, rotate or delete a file fails before touching it, so environments next to
+// production can guarantee the extension never modifies shared storage; loading is unaffected,
+// and processCsvFile still reads its cache but doesn't store new entries. The mode is read
+// from the process environment, not from k6's -e flag, and can't be changed by scripts.
+//
+// Example usage:
+//
+//	if (!streamloader.isReadOnly()) {
+//		streamloader.writeObjectsToJsonArrayFile(results, "results.json");
+//	}
+func (StreamLoader) IsReadOnly() bool {
+	return readOnly.Load()
+}
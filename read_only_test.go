@@ -0,0 +1,127 @@
+package streamloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setReadOnly switches the process to read-only mode for the test.
+func setReadOnly(t *testing.T) {
+	t.Helper()
+	previous := readOnly.Load()
+	readOnly.Store(true)
+	t.Cleanup(func() { readOnly.Store(previous) })
+}
+
+func TestReadOnlyWriters(t *testing.T) {
+	dir := t.TempDir()
+	input, _ := writeDataset(t, dir, "users", 10)
+	loader := StreamLoader{}
+	objects := []interface{}{map[string]interface{}{"id": 1}}
+	collector, _ := loader.NewCollector("read-only")
+	collector.Add(1)
+	t.Cleanup(func() { collector.Clear() })
+
+	setReadOnly(t)
+	tests := []struct {
+		name  string
+		write func(out string) error
+	}{
+		{"writeObjectsToJsonArrayFile", func(out string) error {
+			_, err := loader.WriteObjectsToJsonArrayFile(objects, out)
+			return err
+		}},
+		{"writeJsonLinesToArrayFile", func(out string) error {
+			_, err := loader.WriteJsonLinesToArrayFile(`{"id":1}`, out)
+			return err
+		}},
+		{"combineJsonArrayFiles", func(out string) error {
+			_, err := loader.CombineJsonArrayFiles([]string{input}, out)
+			return err
+		}},
+		{"writeObjectsToCborSequenceFile", func(out string) error {
+			_, err := loader.WriteObjectsToCborSequenceFile(objects, out)
+			return err
+		}},
+		{"formatJsonFile", func(out string) error {
+			_, err := loader.FormatJsonFile(input, out)
+			return err
+		}},
+		{"deduplicateField", func(out string) error {
+			_, err := loader.DeduplicateField(input, "name", out, out+".store")
+			return err
+		}},
+		{"appendLine", func(out string) error {
+			_, err := loader.AppendLine(out, "line")
+			return err
+		}},
+		{"writeSummaryJson", func(out string) error {
+			_, err := loader.WriteSummaryJson(map[string]interface{}{}, out)
+			return err
+		}},
+		{"collector flush", func(out string) error {
+			_, err := collector.FlushToJsonArrayFile(out)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(dir, tt.name+".json")
+			if err := tt.write(out); !errors.Is(err, errReadOnly) {
+				t.Errorf("error = %v, want the read-only error", err)
+			}
+			if _, err := os.Stat(out); !os.IsNotExist(err) {
+				t.Errorf("output file was created: %v", err)
+			}
+		})
+	}
+
+	// Existing files aren't rotated or truncated
+	if _, err := loader.WriteSummaryJson(nil, input, map[string]interface{}{"keepPrevious": int64(1)}); !errors.Is(err, errReadOnly) {
+		t.Errorf("WriteSummaryJson(keepPrevious) error = %v", err)
+	}
+	if _, err := os.Stat(input + ".1"); !os.IsNotExist(err) {
+		t.Error("file was rotated in read-only mode")
+	}
+	// The buffered records stay for a later flush
+	if size, _ := collector.Size(); size != 1 {
+		t.Errorf("collector Size() = %d, want 1", size)
+	}
+
+	// Loading still works
+	if data, err := loader.LoadJSON(input); err != nil || len(data.([]interface{})) != 10 {
+		t.Errorf("LoadJSON() in read-only mode = %v", err)
+	}
+	if !loader.IsReadOnly() {
+		t.Error("IsReadOnly() = false")
+	}
+}
+
+func TestReadOnlyFromEnv(t *testing.T) {
+	for value, want := range map[string]bool{"1": true, "TRUE": true, " yes ": true, "": false, "0": false, "no": false} {
+		t.Setenv(readOnlyEnv, value)
+		if got := readOnlyFromEnv(); got != want {
+			t.Errorf("readOnlyFromEnv(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestReadOnlyProcessCsvCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "cache")
+
+	setReadOnly(t)
+	result, err := StreamLoader{}.ProcessCsvFile(path, ProcessCsvOptions{Cache: cache})
+	if err != nil || len(result) != 2 {
+		t.Fatalf("ProcessCsvFile() = %v, %v", result, err)
+	}
+	if _, err := os.Stat(cache); !os.IsNotExist(err) {
+		t.Error("cache directory was created in read-only mode")
+	}
+}
@@ -0,0 +1,99 @@
+// references.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxOrphanExamples caps the orphaned keys and record indexes listed in a ReferenceReport
+const maxOrphanExamples = 100
+
+// ReferenceReport is the result of CheckReferences
+type ReferenceReport struct {
+	Valid         bool     `json:"valid" js:"valid"`
+	ParentKeys    int      `json:"parentKeys" js:"parentKeys"`
+	ChildRecords  int      `json:"childRecords" js:"childRecords"`
+	References    int      `json:"references" js:"references"`
+	Orphans       int      `json:"orphans" js:"orphans"`
+	OrphanKeys    []string `json:"orphanKeys" js:"orphanKeys"`
+	OrphanRecords []int    `json:"orphanRecords" js:"orphanRecords"`
+}
+
+// CheckReferences checks that every child record references a parent record, such as orders
+// referencing users, so a broken corpus is caught before a test rather than as errors during
+// it. Both JSON array or NDJSON files are streamed; only the distinct parent keys are kept in
+// memory. Keys may be dotted paths into nested objects and are compared by value, with numbers
+// matching their string form, so 42 and "42" are the same key. Child records whose foreign key
+// is missing or null don't reference anything and are not orphans.
+//
+// Returns: Whether there are no orphans, the number of distinct parent keys, child records and
+// child records with a foreign key, the number of orphaned child records, and up to 100 distinct
+// orphaned keys and zero-based indexes of orphaned child records
+//
+// Example usage:
+//
+//	const report = streamloader.checkReferences("users.json", "id", "orders.json", "userId");
+//	if (!report.valid) throw new Error(`${report.orphans} orders reference unknown users: ${report.orphanKeys}`);
+func (s StreamLoader) CheckReferences(parentFile string, parentKey string, childFile string, childForeignKey string) (*ReferenceReport, error) {
+	if parentKey == "" || childForeignKey == "" {
+		return nil, fmt.Errorf("parentKey and childForeignKey are required")
+	}
+
+	parents := make(map[string]struct{})
+	err := forEachKeyedRecord(parentFile, parentKey, s.call, func(_ int, key string, found bool) {
+		if found {
+			parents[key] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReferenceReport{ParentKeys: len(parents), OrphanKeys: make([]string, 0), OrphanRecords: make([]int, 0)}
+	listed := make(map[string]struct{})
+	err = forEachKeyedRecord(childFile, childForeignKey, s.call, func(index int, key string, found bool) {
+		report.ChildRecords++
+		if !found {
+			return
+		}
+		report.References++
+		if _, ok := parents[key]; ok {
+			return
+		}
+		report.Orphans++
+		if len(report.OrphanRecords) < maxOrphanExamples {
+			report.OrphanRecords = append(report.OrphanRecords, index)
+		}
+		if _, ok := listed[key]; !ok && len(report.OrphanKeys) < maxOrphanExamples {
+			listed[key] = struct{}{}
+			report.OrphanKeys = append(report.OrphanKeys, key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Valid = report.Orphans == 0
+	return report, nil
+}
+
+// forEachKeyedRecord streams the records of a JSON array or NDJSON file and calls fn with each
+// record's index and the string form of the value at a dotted path, or found false when the
+// value is missing or null.
+func forEachKeyedRecord(filePath string, keyPath string, call callLimits, fn func(index int, key string, found bool)) error {
+	index := 0
+	return forEachJsonRecord(filePath, call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d in %s: %w", index, filePath, err)
+		}
+		value, found := lookupField(record, keyPath)
+		if found && value != nil {
+			fn(index, keyString(value), true)
+		} else {
+			fn(index, "", false)
+		}
+		index++
+		return true, nil
+	})
+}
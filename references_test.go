@@ -0,0 +1,108 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckReferences(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	users := write("users.json", `[{"id":1},{"id":2},{"id":"3"},{"name":"no id"}]`)
+	nested := write("accounts.ndjson", `{"owner":{"id":1}}`+"\n"+`{"owner":{"id":2}}`+"\n")
+
+	tests := []struct {
+		name      string
+		parent    string
+		parentKey string
+		child     string
+		childKey  string
+		want      ReferenceReport
+	}{
+		{
+			name: "valid", parent: users, parentKey: "id",
+			child: write("valid.json", `[{"userId":1},{"userId":"2"},{"userId":3},{"userId":null},{}]`), childKey: "userId",
+			want: ReferenceReport{Valid: true, ParentKeys: 3, ChildRecords: 5, References: 3, OrphanKeys: []string{}, OrphanRecords: []int{}},
+		},
+		{
+			name: "orphans", parent: users, parentKey: "id",
+			child: write("orphans.ndjson", `{"userId":9}`+"\n"+`{"userId":1}`+"\n"+`{"userId":9}`+"\n"+`{"userId":"x"}`+"\n"), childKey: "userId",
+			want: ReferenceReport{ParentKeys: 3, ChildRecords: 4, References: 4, Orphans: 3, OrphanKeys: []string{"9", "x"}, OrphanRecords: []int{0, 2, 3}},
+		},
+		{
+			name: "nested keys", parent: nested, parentKey: "owner.id",
+			child: write("nested.json", `[{"order":{"user":{"id":2}}},{"order":{"user":{"id":5}}}]`), childKey: "order.user.id",
+			want: ReferenceReport{ParentKeys: 2, ChildRecords: 2, References: 2, Orphans: 1, OrphanKeys: []string{"5"}, OrphanRecords: []int{1}},
+		},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loader.CheckReferences(tt.parent, tt.parentKey, tt.child, tt.childKey)
+			if err != nil {
+				t.Fatalf("CheckReferences() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("CheckReferences() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckReferencesCapsExamples(t *testing.T) {
+	dir := t.TempDir()
+	parent := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(parent, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	child, _ := writeDataset(t, dir, "orders", 250)
+
+	report, err := StreamLoader{}.CheckReferences(parent, "id", child, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Orphans != 250 || len(report.OrphanKeys) != maxOrphanExamples || len(report.OrphanRecords) != maxOrphanExamples {
+		t.Errorf("report has %d orphans, %d keys and %d records listed", report.Orphans, len(report.OrphanKeys), len(report.OrphanRecords))
+	}
+}
+
+func TestCheckReferencesErrors(t *testing.T) {
+	dir := t.TempDir()
+	users, _ := writeDataset(t, dir, "users", 3)
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`[{"id":1},{"id":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+
+	tests := []struct {
+		name      string
+		parent    string
+		parentKey string
+		child     string
+		wantErr   string
+	}{
+		{"empty key", users, "", users, "required"},
+		{"missing parent", missing, "id", users, "missing.json"},
+		{"missing child", users, "id", missing, "missing.json"},
+		{"broken child", users, "id", broken, "broken.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamLoader{}.CheckReferences(tt.parent, tt.parentKey, tt.child, "id")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckReferences() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
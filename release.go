@@ -0,0 +1,79 @@
+// release.go
+package streamloader
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// sharedHandle is a handle on a structure built once per process by buildShared.
+type sharedHandle interface {
+	handle
+	sharedKey() string
+}
+
+func (l *Lookup) sharedKey() string        { return l.key }
+func (f *BloomFilter) sharedKey() string   { return f.key }
+func (m *PrefixMatcher) sharedKey() string { return m.key }
+
+// Release drops data held for the rest of the test, so long runs with several scenarios can
+// reclaim the memory of the datasets and indexes of a phase once it is over, such as the
+// ramp-up's, instead of keeping everything until the process exits. target may be:
+//   - The name of a shared dataset: its data is unloaded, even if it is pinned. It stays
+//     registered and is reloaded from its file if GetDataset is called again.
+//   - A file path: the lookups, Bloom filters and prefix matchers built from the file are
+//     dropped, so they are built again if asked for.
+//   - A handle, such as a lookup, iterator, sequence or stream: it is closed, and for a lookup,
+//     Bloom filter or prefix matcher the shared structure is dropped as well.
+//
+// Structures shared by every VU are only freed once no VU holds a handle on them, so release
+// them from every VU, for example at the end of the scenario's last iteration, or close the
+// handles and release the file once.
+//
+// Returns: Whether anything was released
+//
+// Example usage:
+//
+//	const tokens = streamloader.buildLookup("ramp-tokens.json", "userId");
+//	// When the ramp-up scenario is done:
+//	streamloader.release(tokens);
+//	streamloader.release("ramp-users");
+func (StreamLoader) Release(target interface{}) (bool, error) {
+	switch t := target.(type) {
+	case string:
+		if t == "" {
+			return false, fmt.Errorf("release target must not be empty")
+		}
+		released := releaseDataset(t)
+		path := t
+		if abs, err := filepath.Abs(t); err == nil {
+			path = abs
+		}
+		if releaseShared(func(key string) bool { return sharedBuildFile(key) == path }) > 0 {
+			released = true
+		}
+		return released, nil
+	case sharedHandle:
+		key := t.sharedKey()
+		t.Close()
+		releaseShared(func(k string) bool { return k == key })
+		return true, nil
+	case handle:
+		t.Close()
+		return true, nil
+	default:
+		return false, fmt.Errorf("cannot release %T: expected a dataset name, file path or handle", target)
+	}
+}
+
+// releaseDataset unloads the data of a shared dataset and reports whether it was loaded.
+func releaseDataset(name string) bool {
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	d, ok := datasets.byName[name]
+	if !ok || d.elem == nil {
+		return false
+	}
+	unloadDataset(d)
+	return true
+}
@@ -0,0 +1,94 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	resetDatasets(t)
+	resetSharedBuilds(t)
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.json")
+	os.WriteFile(users, []byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`), 0644)
+
+	// Datasets are unloaded and reloaded on next use
+	if err := loader.RegisterDataset("users", users, DatasetOptions{Pinned: true}); err != nil {
+		t.Fatal(err)
+	}
+	if released, err := loader.Release("users"); err != nil || released {
+		t.Errorf("Release(unloaded dataset) = %v, %v, want false", released, err)
+	}
+	if _, err := loader.GetDataset("users"); err != nil {
+		t.Fatal(err)
+	}
+	if released, err := loader.Release("users"); err != nil || !released {
+		t.Errorf("Release(dataset) = %v, %v, want true", released, err)
+	}
+	if info := loader.GetDatasetInfo()[0]; info.Loaded || info.SizeBytes != 0 || !info.Pinned {
+		t.Errorf("dataset after Release = %+v", info)
+	}
+	if data, err := loader.GetDataset("users"); err != nil || len(data.([]interface{})) != 2 {
+		t.Errorf("GetDataset() after Release = %v, %v", data, err)
+	}
+
+	// Structures built from a file are dropped and rebuilt
+	lookup, err := loader.BuildLookup(users, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.BuildBloomFilter(users, "name", 0.01); err != nil {
+		t.Fatal(err)
+	}
+	if released, err := loader.Release(users); err != nil || !released {
+		t.Errorf("Release(file) = %v, %v, want true", released, err)
+	}
+	if len(sharedBuilds.builds) != 0 {
+		t.Errorf("shared builds after Release(file) = %d", len(sharedBuilds.builds))
+	}
+	// The handle keeps the table it had
+	if record, err := lookup.Get(2); err != nil || record == nil {
+		t.Errorf("Get() after Release(file) = %v, %v", record, err)
+	}
+
+	// Handles are closed, and the structures of shared handles dropped
+	os.WriteFile(users, []byte(`[{"id":3}]`), 0644)
+	rebuilt, err := loader.BuildLookup(users, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := rebuilt.Size(); size != 1 {
+		t.Errorf("rebuilt lookup size = %d, want 1", size)
+	}
+	if released, err := loader.Release(rebuilt); err != nil || !released {
+		t.Errorf("Release(lookup) = %v, %v, want true", released, err)
+	}
+	if _, err := rebuilt.Size(); err == nil {
+		t.Error("Size() after Release expected an error")
+	}
+	if len(sharedBuilds.builds) != 0 {
+		t.Errorf("shared builds after Release(lookup) = %d", len(sharedBuilds.builds))
+	}
+	sequence, err := loader.GenerateRange(0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released, err := loader.Release(sequence); err != nil || !released {
+		t.Errorf("Release(sequence) = %v, %v, want true", released, err)
+	}
+	if sequence.Next() != nil {
+		t.Error("sequence is not closed after Release")
+	}
+
+	// Nothing to release, and invalid targets
+	if released, err := loader.Release(filepath.Join(dir, "other.json")); err != nil || released {
+		t.Errorf("Release(unknown) = %v, %v, want false", released, err)
+	}
+	for _, target := range []interface{}{"", 42, nil} {
+		if _, err := loader.Release(target); err == nil {
+			t.Errorf("Release(%v) expected an error", target)
+		}
+	}
+}
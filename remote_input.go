@@ -0,0 +1,156 @@
+// remote_input.go
+package streamloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.k6.io/k6/lib"
+)
+
+// defaultRemoteTimeout bounds a request for a remote input, including reading its body, unless
+// the timeoutMs option overrides it. It matches the default timeout of k6/http.
+const defaultRemoteTimeout = 60 * time.Second
+
+// isRemoteURL reports whether a path argument is an http or https URL rather than a file path.
+func isRemoteURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// remoteExt returns the extension of the path of a URL, ignoring its query and fragment, so
+// "https://host/data.ndjson?token=x" is detected as NDJSON.
+func remoteExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
+}
+
+// remoteBody is the body of a response, which cancels the request's timeout when it is closed.
+type remoteBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b remoteBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// limitedBody fails with a LimitError once more bytes than the maxFileBytes limit have been read,
+// for responses that don't declare their size.
+type limitedBody struct {
+	io.ReadCloser
+	read  int64
+	limit LimitError
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit.Max {
+		return 0, b.err()
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.read+int64(n) > b.limit.Max {
+		// Drop the bytes over the limit, so parsers can't finish and miss the error
+		within := int(b.limit.Max - b.read)
+		b.read += int64(n)
+		return within, b.err()
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+// err returns the LimitError for the bytes read so far.
+func (b *limitedBody) err() error {
+	limitErr := b.limit
+	limitErr.Value = b.read
+	return &limitErr
+}
+
+// remoteClient returns the client fetching remote inputs. In a VU it goes through k6's transport,
+// so the blacklistIPs, blockHostnames, hosts and TLS options and the proxy apply as they do to
+// k6/http. Those options aren't known yet in the init context, so there, as outside k6 in Go
+// tests, a plain client bounded by the request's timeout is used.
+func (s StreamLoader) remoteClient(timeout time.Duration) *http.Client {
+	var state *lib.State
+	if s.vu != nil {
+		state = s.vu.State()
+	}
+	if state == nil {
+		return &http.Client{Timeout: timeout}
+	}
+	if state.Transport != nil {
+		return &http.Client{Transport: state.Transport}
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: state.TLSConfig}
+	if state.Dialer != nil {
+		transport.DialContext = state.Dialer.DialContext
+	}
+	return &http.Client{Transport: transport}
+}
+
+// openRemote requests a remote input and returns its body for the loaders to stream through
+// their parsers, so test data can be pulled from an artifact server instead of being baked into
+// the image. headers are sent with the request, and timeoutMs bounds the request including the
+// body (default: 60s). Responses other than 2xx are errors. The maxFileBytes limit of function
+// applies to the body, before reading it if the response declares its length.
+func (s StreamLoader) openRemote(function string, rawURL string, headers map[string]string, timeoutMs int64) (io.ReadCloser, error) {
+	if timeoutMs < 0 {
+		return nil, fmt.Errorf("timeoutMs must not be negative, got %d", timeoutMs)
+	}
+	timeout := defaultRemoteTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	client := s.remoteClient(timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+
+	var body io.ReadCloser = countingReader{resp.Body}
+	if max := functionLimits(function).MaxFileBytes; max > 0 {
+		limit := LimitError{Function: function, Limit: "maxFileBytes", Path: rawURL, Max: max}
+		if resp.ContentLength > max {
+			resp.Body.Close()
+			cancel()
+			limit.Value = resp.ContentLength
+			return nil, &limit
+		}
+		body = &limitedBody{ReadCloser: body, limit: limit}
+	}
+	return remoteBody{s.call.timed(body), cancel}, nil
+}
+
+// openLoaderInput opens the input of a loader: a remote URL with the headers and timeout of its
+// options, or a local file or data URI read with the pageCache option. function is the name of
+// the loader whose limits apply.
+func (s StreamLoader) openLoaderInput(function string, filePath string, pageCache string, headers map[string]string, timeoutMs int64) (io.ReadCloser, error) {
+	if isRemoteURL(filePath) {
+		return s.openRemote(function, filePath, headers, timeoutMs)
+	}
+	return openSequential(filePath, pageCache, s.call)
+}
@@ -0,0 +1,222 @@
+package streamloader
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/lib/netext"
+)
+
+func TestRemoteInput(t *testing.T) {
+	loader := StreamLoader{}
+	compressed := gzipBytes(t, "{\"id\":1}\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/public.csv" && r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/users.json":
+			w.Write([]byte(`[{"id":1},{"id":2}]`))
+		case "/events.ndjson":
+			w.Write([]byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"))
+		case "/events.ndjson.gz":
+			w.Write(compressed)
+		case "/public.csv":
+			w.Write([]byte("id,name\n1,a\n"))
+		case "/slow.json":
+			time.Sleep(500 * time.Millisecond)
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	auth := map[string]string{"Authorization": "Bearer secret"}
+
+	t.Run("json", func(t *testing.T) {
+		got, err := loader.LoadJSON(server.URL+"/users.json", JsonOptions{Headers: auth})
+		if err != nil {
+			t.Fatalf("LoadJSON() error = %v", err)
+		}
+		if records, ok := got.([]interface{}); !ok || len(records) != 2 {
+			t.Errorf("LoadJSON() = %v, want 2 records", got)
+		}
+	})
+
+	t.Run("ndjson detected from the URL path", func(t *testing.T) {
+		got, err := loader.LoadJSON(server.URL+"/events.ndjson?version=2", JsonOptions{Headers: auth})
+		if err != nil {
+			t.Fatalf("LoadJSON() error = %v", err)
+		}
+		if records, ok := got.([]map[string]any); !ok || len(records) != 3 {
+			t.Errorf("LoadJSON() = %v, want 3 records", got)
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		got, err := loader.LoadJSON(server.URL+"/events.ndjson.gz", JsonOptions{Headers: auth})
+		if err != nil {
+			t.Fatalf("LoadJSON() error = %v", err)
+		}
+		if records, ok := got.([]map[string]any); !ok || len(records) != 1 {
+			t.Errorf("LoadJSON() = %v, want 1 record", got)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		got, err := loader.LoadCSV(server.URL + "/public.csv")
+		if err != nil {
+			t.Fatalf("LoadCSV() error = %v", err)
+		}
+		if want := [][]string{{"id", "name"}, {"1", "a"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("LoadCSV() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		got, err := loader.LoadText(server.URL+"/users.json", TextOptions{Headers: auth})
+		if err != nil {
+			t.Fatalf("LoadText() error = %v", err)
+		}
+		if got != `[{"id":1},{"id":2}]` {
+			t.Errorf("LoadText() = %q", got)
+		}
+	})
+
+	t.Run("init context", func(t *testing.T) {
+		// Without a VU state k6's network options aren't known yet, so a plain client is used
+		loader := StreamLoader{vu: &iterationVU{}}
+		if client := loader.remoteClient(5 * time.Second); client.Transport != nil || client.Timeout != 5*time.Second {
+			t.Errorf("remoteClient() = %+v, want the default transport with a 5s timeout", client)
+		}
+		if _, err := loader.LoadCSV(server.URL + "/public.csv"); err != nil {
+			t.Errorf("LoadCSV() in the init context error = %v", err)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		load    func() error
+		wantErr string
+	}{
+		{
+			name:    "missing headers",
+			load:    func() error { _, err := loader.LoadJSON(server.URL + "/users.json"); return err },
+			wantErr: "401",
+		},
+		{
+			name: "not found",
+			load: func() error {
+				_, err := loader.LoadText(server.URL+"/missing.txt", TextOptions{Headers: auth})
+				return err
+			},
+			wantErr: "404",
+		},
+		{
+			name: "timeout",
+			load: func() error {
+				_, err := loader.LoadJSON(server.URL+"/slow.json", JsonOptions{Headers: auth, TimeoutMs: 50})
+				return err
+			},
+			wantErr: "deadline exceeded",
+		},
+		{
+			name: "snapshot",
+			load: func() error {
+				_, err := loader.LoadJSON(server.URL+"/users.json", JsonOptions{Headers: auth, Snapshot: true})
+				return err
+			},
+			wantErr: "snapshot needs a local file",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemoteInputNetworkOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked.json" {
+			// Flushing before the end sends the body chunked, without a Content-Length
+			w.Write([]byte(`[{"id":1},`))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(`{"id":2}]`))
+			return
+		}
+		w.Write([]byte(`[{"id":1},{"id":2}]`))
+	}))
+	defer server.Close()
+
+	blocked, err := lib.ParseCIDR("127.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vuWithDialer := func(dialer *netext.Dialer) StreamLoader {
+		return StreamLoader{vu: &iterationVU{ctx: context.Background(), state: &lib.State{Dialer: dialer}}}
+	}
+
+	t.Run("allowed by the VU's dialer", func(t *testing.T) {
+		loader := vuWithDialer(netext.NewDialer(net.Dialer{}, nil))
+		if _, err := loader.LoadJSON(server.URL + "/users.json"); err != nil {
+			t.Errorf("LoadJSON() error = %v", err)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		load    func() error
+		wantErr string
+	}{
+		{
+			name: "blacklisted IP",
+			load: func() error {
+				dialer := netext.NewDialer(net.Dialer{}, nil)
+				dialer.Blacklist = []*lib.IPNet{blocked}
+				_, err := vuWithDialer(dialer).LoadCSV(server.URL + "/users.csv")
+				return err
+			},
+			wantErr: "blacklisted",
+		},
+		{
+			name: "over maxFileBytes",
+			load: func() error {
+				_, err := StreamLoader{}.LoadText(server.URL + "/users.json")
+				return err
+			},
+			wantErr: "maxFileBytes",
+		},
+		{
+			name: "chunked over maxFileBytes",
+			load: func() error {
+				_, err := StreamLoader{}.LoadJSON(server.URL + "/chunked.json")
+				return err
+			},
+			wantErr: "maxFileBytes",
+		},
+	}
+	loader := StreamLoader{}
+	defer loader.ResetLimits()
+	loader.SetLimits(LimitOptions{Functions: map[string]FunctionLimits{
+		"loadText": {MaxFileBytes: 8},
+		"loadJSON": {MaxFileBytes: 12},
+	}})
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.load(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
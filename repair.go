@@ -0,0 +1,102 @@
+// repair.go
+package streamloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+)
+
+// RepairResult summarizes a RepairJsonArrayFile run
+type RepairResult struct {
+	Recovered    int    `json:"recovered" js:"recovered"`
+	Complete     bool   `json:"complete" js:"complete"`
+	DroppedBytes int64  `json:"droppedBytes" js:"droppedBytes"`
+	Error        string `json:"error" js:"error"`
+}
+
+// RepairJsonArrayFile salvages the complete elements of a truncated or corrupt JSON array file,
+// such as the output of a writer that was killed before writing the closing bracket, and writes
+// them to a valid JSON array file. Elements are copied in order up to the first one that is cut
+// off or malformed; everything after it is dropped. A file that is already valid is copied as
+// it is, element by element.
+//
+// Returns:
+//   - recovered: The number of elements written
+//   - complete: Whether the input was a valid array, so nothing was dropped
+//   - droppedBytes: The bytes of the input after the last recovered element, or 0 for a named
+//     pipe, whose size is unknown
+//   - error: Why reading stopped early, or "" if complete
+//
+// Example usage:
+//
+//	result, err := streamloader.RepairJsonArrayFile("results.json", "results-repaired.json")
+//	if (!result.complete) console.warn(`recovered ${result.recovered} records, dropped ${result.droppedBytes} bytes`);
+func (s StreamLoader) RepairJsonArrayFile(inputFilePath string, outputFilePath string) (*RepairResult, error) {
+	info, err := statInput(inputFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	file, err := openSequential(inputFilePath, "", s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	// Skip a BOM and leading whitespace, keeping count so offsets refer to the file
+	reader := bufio.NewReaderSize(file, readBufferSize())
+	var skipped int64
+	if bom, err := reader.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		reader.Discard(3)
+		skipped += 3
+	}
+	for {
+		b, err := reader.Peek(1)
+		if err != nil || b[0] != '[' && !isWhitespace(b[0]) {
+			return nil, fmt.Errorf("%s is not a JSON array file", inputFilePath)
+		}
+		if b[0] == '[' {
+			break
+		}
+		reader.ReadByte()
+		skipped++
+	}
+
+	out, err := createJsonArrayFile(outputFilePath, writeBufferSize())
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	result := &RepairResult{}
+	dec := json.NewDecoder(reader)
+	dec.Token() // The opening bracket, peeked above
+	end := dec.InputOffset()
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			result.Error = fmt.Sprintf("element %d: %v", out.count, err)
+			break
+		}
+		if err := out.Write(raw); err != nil {
+			return nil, err
+		}
+		end = dec.InputOffset()
+	}
+	if result.Error == "" {
+		if _, err := dec.Token(); err != nil {
+			result.Error = fmt.Sprintf("after element %d: %v", out.count, err)
+		} else {
+			result.Complete = true
+		}
+	}
+
+	result.Recovered = out.count
+	if !result.Complete && info.Mode().IsRegular() {
+		result.DroppedBytes = info.Size() - skipped - end
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairJsonArrayFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		content   string
+		recovered int
+		complete  bool
+		dropped   int64
+	}{
+		{"valid", `[{"id":1}, {"id":2}]`, 2, true, 0},
+		{"empty array", "\xef\xbb\xbf\n[ ]", 0, true, 0},
+		{"cut inside an element", `[{"id":1},{"id":2},{"id":`, 2, false, 7},
+		{"missing closing bracket", "[{\"id\":1},\n{\"id\":2}\n", 2, false, 1},
+		{"trailing comma", `[{"id":1},`, 1, false, 1},
+		{"corrupt element", `[{"id":1},{"id" 2},{"id":3}]`, 1, false, 19},
+		{"zero padding", "[{\"id\":1}\x00\x00\x00", 1, false, 3},
+		{"cut after the bracket", `[`, 0, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(dir, "input.json")
+			output := filepath.Join(dir, "output.json")
+			if err := os.WriteFile(input, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := loader.RepairJsonArrayFile(input, output)
+			if err != nil {
+				t.Fatalf("RepairJsonArrayFile() error = %v", err)
+			}
+			if result.Recovered != tt.recovered || result.Complete != tt.complete || result.DroppedBytes != tt.dropped {
+				t.Errorf("RepairJsonArrayFile() = %+v, want recovered %d, complete %v, dropped %d", result, tt.recovered, tt.complete, tt.dropped)
+			}
+			if (result.Error == "") != tt.complete {
+				t.Errorf("Error = %q, complete = %v", result.Error, result.Complete)
+			}
+
+			data, err := loader.LoadJSON(output)
+			if err != nil {
+				t.Fatalf("repaired file doesn't load: %v", err)
+			}
+			if arr, _ := data.([]interface{}); len(arr) != tt.recovered {
+				t.Errorf("repaired file has %d elements, want %d", len(arr), tt.recovered)
+			}
+		})
+	}
+
+	input := filepath.Join(dir, "object.json")
+	os.WriteFile(input, []byte(`{"id":1}`), 0644)
+	if _, err := loader.RepairJsonArrayFile(input, filepath.Join(dir, "out.json")); err == nil {
+		t.Error("expected an error for a JSON object file")
+	}
+	if _, err := loader.RepairJsonArrayFile(filepath.Join(dir, "missing.json"), filepath.Join(dir, "out.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
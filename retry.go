@@ -0,0 +1,186 @@
+// retry.go
+package streamloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// maxRetryBackoff caps the wait between two attempts
+const maxRetryBackoff = 30 * time.Second
+
+// openInputFile opens a file for reading; tests replace it to simulate failures
+var openInputFile = os.Open
+
+// isTransientIOError reports whether an error may go away when the operation is tried again,
+// such as the stale handles and I/O errors network file systems like NFS and EFS report during
+// a failover
+var isTransientIOError = func(err error) bool {
+	return errors.Is(err, syscall.ESTALE) || errors.Is(err, syscall.EIO)
+}
+
+// retryAttempts returns how many times opening or reading an input file is tried.
+func retryAttempts() int {
+	if n := tuning.Load().RetryAttempts; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// retryBackoff returns the wait before attempt number attempt + 1, doubling after each attempt.
+func retryBackoff(attempt int) time.Duration {
+	backoff := time.Duration(tuning.Load().RetryBackoffMs) * time.Millisecond
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// inputFile is an input file whose reads are retried on transient errors by reopening the file
+// and seeking back to the read position, so a momentary hiccup of a network file system doesn't
+// abort a long load.
+type inputFile struct {
+	*os.File               // nil for a data URI
+	data     *bytes.Reader // The payload of a data URI, served from memory
+	path     string
+	offset   int64 // Read position
+	stream   bool  // A named pipe or device that can't be reopened at the read position
+	call     callLimits
+}
+
+// openInput opens an input file, retrying transient errors. A data URI opens its payload. The
+// limits of the call reading the file apply: a file over maxFileBytes fails to open, and reads
+// fail once the call is past its deadline.
+func openInput(filePath string, call callLimits) (*inputFile, error) {
+	if isDataURI(filePath) {
+		f, err := openDataURI(filePath)
+		if err != nil {
+			return nil, err
+		}
+		f.call = call
+		if info, err := f.Stat(); err == nil {
+			if err := call.checkSize(filePath, info.Size()); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+	file, err := openInputFile(filePath)
+	for attempt := 1; err != nil && isTransientIOError(err) && attempt < retryAttempts(); attempt++ {
+		time.Sleep(retryBackoff(attempt))
+		file, err = openInputFile(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &inputFile{File: file, path: filePath, call: call}
+	if info, err := file.Stat(); err == nil {
+		f.stream = isStreamMode(info.Mode())
+		if info.Mode().IsRegular() {
+			if err := call.checkSize(filePath, info.Size()); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// readInputFile reads a whole input file, retrying transient errors.
+func readInputFile(filePath string, call callLimits) ([]byte, error) {
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func (f *inputFile) Read(p []byte) (int, error) {
+	if err := f.call.checkDeadline(); err != nil {
+		return 0, err
+	}
+	if f.data != nil {
+		return f.data.Read(p)
+	}
+	n, err := f.File.Read(p)
+	f.offset += int64(n)
+	if err == nil || f.stream || !isTransientIOError(err) {
+		return n, err
+	}
+	if n > 0 {
+		// Hand over what was read; the next read tries again
+		return n, nil
+	}
+	for attempt := 1; attempt < retryAttempts(); attempt++ {
+		time.Sleep(retryBackoff(attempt))
+		if reopenErr := f.reopen(); reopenErr != nil {
+			err = reopenErr
+			if !isTransientIOError(err) {
+				break
+			}
+			continue
+		}
+		n, err = f.File.Read(p)
+		f.offset += int64(n)
+		if n > 0 && err != nil && isTransientIOError(err) {
+			err = nil
+		}
+		if err == nil || !isTransientIOError(err) {
+			return n, err
+		}
+	}
+	return 0, fmt.Errorf("failed to read %s after %d attempts: %w", f.path, retryAttempts(), err)
+}
+
+// reopen replaces the file with a new handle positioned at the read position.
+func (f *inputFile) reopen() error {
+	file, err := openInputFile(f.path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	f.File.Close()
+	f.File = file
+	return nil
+}
+
+func (f *inputFile) Close() error {
+	if f.data != nil {
+		return nil
+	}
+	return f.File.Close()
+}
+
+// Stat describes the file, or the payload of a data URI as a read-only regular file.
+func (f *inputFile) Stat() (os.FileInfo, error) {
+	if f.data != nil {
+		return dataURIInfo{size: f.data.Size()}, nil
+	}
+	return f.File.Stat()
+}
+
+// WriteTo copies the rest of the file through Read, so copies are retried too.
+func (f *inputFile) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{f})
+}
+
+func (f *inputFile) Seek(offset int64, whence int) (int64, error) {
+	if f.data != nil {
+		return f.data.Seek(offset, whence)
+	}
+	pos, err := f.File.Seek(offset, whence)
+	if err == nil {
+		f.offset = pos
+	}
+	return pos, err
+}
@@ -0,0 +1,135 @@
+package streamloader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyOpens makes the next failures opens of any file fail with err.
+func flakyOpens(t *testing.T, failures int, err error) *int {
+	t.Helper()
+	loader := StreamLoader{}
+	t.Cleanup(func() {
+		openInputFile = os.Open
+		loader.ResetDefaults()
+	})
+	if _, setErr := loader.SetDefaults(TuningDefaults{RetryBackoffMs: 1}); setErr != nil {
+		t.Fatal(setErr)
+	}
+	calls := 0
+	openInputFile = func(name string) (*os.File, error) {
+		calls++
+		if calls <= failures {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		return os.Open(name)
+	}
+	return &calls
+}
+
+func TestOpenInputRetries(t *testing.T) {
+	path, _ := writeDataset(t, t.TempDir(), "users", 10)
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"stale handle recovers", 2, syscall.ESTALE, 3, 3, false},
+		{"I/O error recovers", 1, syscall.EIO, 3, 2, false},
+		{"attempts exhausted", 3, syscall.ESTALE, 3, 3, true},
+		{"retries disabled", 1, syscall.EIO, 1, 1, true},
+		{"permanent error", 1, syscall.EACCES, 3, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := flakyOpens(t, tt.failures, tt.err)
+			if _, err := (StreamLoader{}).SetDefaults(TuningDefaults{RetryAttempts: tt.attempts}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := StreamLoader{}.LoadJSON(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(data.([]interface{})) != 10 {
+				t.Errorf("LoadJSON() loaded %d records", len(data.([]interface{})))
+			}
+			if tt.wantErr && !errors.Is(err, tt.err) {
+				t.Errorf("LoadJSON() error = %v, want %v", err, tt.err)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("opened %d times, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestInputFileReadRetries(t *testing.T) {
+	flakyOpens(t, 0, nil)
+	previous := isTransientIOError
+	t.Cleanup(func() { isTransientIOError = previous })
+	// Reading a closed file stands in for a stale NFS handle
+	isTransientIOError = func(err error) bool { return errors.Is(err, os.ErrClosed) }
+
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("0123456789abcdef"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := openInput(path, callLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(file, head); err != nil {
+		t.Fatal(err)
+	}
+	file.File.Close()
+	rest, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("ReadAll() after a stale handle error = %v", err)
+	}
+	if got := string(head) + string(rest); got != "0123456789abcdef" {
+		t.Errorf("read %q", got)
+	}
+
+	// Seeking moves the position reads resume from
+	if _, err := file.Seek(10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	file.File.Close()
+	if rest, err := io.ReadAll(file); err != nil || string(rest) != "abcdef" {
+		t.Errorf("ReadAll() after Seek = %q, %v", rest, err)
+	}
+
+	// A file that can't be reopened fails after the attempts
+	os.Remove(path)
+	file.File.Close()
+	if _, err := file.Read(head); err == nil {
+		t.Error("expected an error once the file is gone")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	loader := StreamLoader{}
+	t.Cleanup(loader.ResetDefaults)
+	if got := []time.Duration{retryBackoff(1), retryBackoff(2), retryBackoff(3)}; got[0] != 100*time.Millisecond || got[1] != 200*time.Millisecond || got[2] != 400*time.Millisecond {
+		t.Errorf("default backoff = %v", got)
+	}
+	loader.SetDefaults(TuningDefaults{RetryBackoffMs: 20000})
+	if got := retryBackoff(5); got != maxRetryBackoff {
+		t.Errorf("retryBackoff(5) = %v, want %v", got, maxRetryBackoff)
+	}
+	if _, err := loader.SetDefaults(TuningDefaults{RetryAttempts: -1}); err == nil {
+		t.Error("expected an error for negative retryAttempts")
+	}
+}
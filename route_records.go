@@ -0,0 +1,154 @@
+// route_records.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RouteRule sends the records matching a condition to a sink
+type RouteRule struct {
+	Match interface{} `json:"match" js:"match"`
+	Sink  string      `json:"sink" js:"sink"`
+	File  string      `json:"file" js:"file"`
+}
+
+// RouteResult is the result of RouteRecords
+type RouteResult struct {
+	Feeds     map[string][]interface{} `json:"feeds" js:"feeds"`
+	Counts    map[string]int           `json:"counts" js:"counts"`
+	Unmatched int                      `json:"unmatched" js:"unmatched"`
+}
+
+// routeSink is where the records of one sink go: memory, or a JSON array file.
+type routeSink struct {
+	name string
+	file string
+	out  *jsonArrayWriter // nil for in-memory sinks
+}
+
+// RouteRecords streams a mixed JSON array or NDJSON corpus once and splits it into several
+// datasets by content, such as one per scenario by HTTP method or URL pattern. Each record goes
+// to the sink of the first rule it matches; records matching no rule are dropped and counted.
+//
+// Rules:
+//   - match: A function, condition object or array of conditions, as for Iterator.Filter,
+//     e.g. { field: "method", op: "eq", value: "GET" }. A rule without match takes every
+//     record, so it serves as a default at the end.
+//   - sink: The name of the dataset. Several rules may send records to the same sink.
+//   - file: Stream the sink's records to this JSON array file instead of returning them in
+//     memory. Every rule of a sink must name the same file.
+//
+// Returns: The in-memory feeds by sink name, the record count of every sink, and the number of
+// unmatched records
+//
+// Example usage:
+//
+//	routed, err := streamloader.RouteRecords("traffic.json", []RouteRule{
+//		{Match: map[string]interface{}{"field": "method", "op": "eq", "value": "GET"}, Sink: "browse"},
+//		{Match: map[string]interface{}{"field": "url", "op": "regex", "value": "^/api/checkout"}, Sink: "checkout", File: "checkout.json"},
+//		{Sink: "other"},
+//	})
+//	browse := routed.Feeds["browse"]
+func (s StreamLoader) RouteRecords(filePath string, rules []RouteRule) (*RouteResult, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("at least one rule is required")
+	}
+
+	// Match functions run on the VU's runtime, like Iterator.Filter callbacks
+	compiler := &Iterator{}
+	if s.vu != nil {
+		compiler.rt = s.vu.Runtime()
+	}
+
+	result := &RouteResult{Feeds: make(map[string][]interface{}), Counts: make(map[string]int)}
+	sinks := make(map[string]*routeSink)
+	matchers := make([]func(interface{}) (bool, error), len(rules))
+	ruleSinks := make([]*routeSink, len(rules))
+	defer func() {
+		for _, sink := range sinks {
+			if sink.out != nil {
+				sink.out.Close()
+			}
+		}
+	}()
+
+	for i, rule := range rules {
+		if rule.Sink == "" {
+			return nil, fmt.Errorf("rule %d: sink is required", i)
+		}
+		sink, ok := sinks[rule.Sink]
+		if !ok {
+			sink = &routeSink{name: rule.Sink, file: rule.File}
+			if rule.File != "" {
+				for _, other := range sinks {
+					if other.file == rule.File {
+						return nil, fmt.Errorf("rule %d: sinks %q and %q both write to %q", i, other.name, rule.Sink, rule.File)
+					}
+				}
+				out, err := createJsonArrayFile(rule.File, writeBufferSize())
+				if err != nil {
+					return nil, fmt.Errorf("rule %d: %w", i, err)
+				}
+				sink.out = out
+			} else {
+				result.Feeds[rule.Sink] = make([]interface{}, 0)
+			}
+			sinks[rule.Sink] = sink
+			result.Counts[rule.Sink] = 0
+		} else if sink.file != rule.File {
+			return nil, fmt.Errorf("rule %d: sink %q is already routed to %q", i, rule.Sink, sink.file)
+		}
+		ruleSinks[i] = sink
+
+		if rule.Match != nil {
+			match, err := compiler.compilePredicate(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			matchers[i] = match
+		}
+	}
+
+	index := 0
+	err := forEachJsonRecord(filePath, s.call, func(raw json.RawMessage) (bool, error) {
+		var record interface{}
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d in %s: %w", index, filePath, err)
+		}
+		index++
+
+		for i, match := range matchers {
+			if match != nil {
+				if ok, err := match(record); err != nil {
+					return false, err
+				} else if !ok {
+					continue
+				}
+			}
+			sink := ruleSinks[i]
+			result.Counts[sink.name]++
+			if sink.out != nil {
+				return true, sink.out.Write(raw)
+			}
+			result.Feeds[sink.name] = append(result.Feeds[sink.name], record)
+			return true, nil
+		}
+		result.Unmatched++
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sink := range sinks {
+		if sink.out != nil {
+			out := sink.out
+			sink.out = nil
+			if err := out.Close(); err != nil {
+				return nil, fmt.Errorf("failed to finish %s: %w", sink.file, err)
+			}
+		}
+	}
+	return result, nil
+}
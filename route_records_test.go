@@ -0,0 +1,166 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+const trafficCorpus = `[
+	{"method": "GET", "url": "/home"},
+	{"method": "POST", "url": "/api/checkout/cart"},
+	{"method": "GET", "url": "/api/checkout/status"},
+	{"method": "DELETE", "url": "/api/items/1"},
+	{"method": "POST", "url": "/login"}
+]`
+
+func TestRouteRecords(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "traffic.json")
+	if err := os.WriteFile(input, []byte(trafficCorpus), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checkoutFile := filepath.Join(dir, "checkout.json")
+
+	loader := StreamLoader{}
+	result, err := loader.RouteRecords(input, []RouteRule{
+		{Match: map[string]interface{}{"field": "url", "op": "regex", "value": "^/api/checkout"}, Sink: "checkout", File: checkoutFile},
+		{Match: map[string]interface{}{"field": "method", "op": "eq", "value": "GET"}, Sink: "browse"},
+		{Match: map[string]interface{}{"field": "url", "op": "eq", "value": "/login"}, Sink: "browse"},
+		{Match: map[string]interface{}{"field": "method", "op": "eq", "value": "PUT"}, Sink: "update"},
+	})
+	if err != nil {
+		t.Fatalf("RouteRecords() error = %v", err)
+	}
+
+	wantCounts := map[string]int{"checkout": 2, "browse": 2, "update": 0}
+	if !reflect.DeepEqual(result.Counts, wantCounts) {
+		t.Errorf("Counts = %v, want %v", result.Counts, wantCounts)
+	}
+	if result.Unmatched != 1 {
+		t.Errorf("Unmatched = %d, want 1", result.Unmatched)
+	}
+	if _, ok := result.Feeds["checkout"]; ok {
+		t.Error("file sink should not be returned as a feed")
+	}
+	if got := len(result.Feeds["update"]); got != 0 {
+		t.Errorf("update feed has %d records, want an empty feed", got)
+	}
+	var urls []string
+	for _, r := range result.Feeds["browse"] {
+		urls = append(urls, r.(map[string]interface{})["url"].(string))
+	}
+	if want := []string{"/home", "/login"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("browse feed = %v, want %v", urls, want)
+	}
+
+	data, err := os.ReadFile(checkoutFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkout []map[string]interface{}
+	if err := json.Unmarshal(data, &checkout); err != nil {
+		t.Fatalf("checkout file is not a JSON array: %v\n%s", err, data)
+	}
+	if len(checkout) != 2 || checkout[0]["url"] != "/api/checkout/cart" || checkout[1]["url"] != "/api/checkout/status" {
+		t.Errorf("checkout file = %s", data)
+	}
+}
+
+func TestRouteRecordsDefaultSink(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "traffic.ndjson")
+	lines := `{"method":"GET"}` + "\n" + `{"method":"POST"}` + "\n" + `{"method":"GET"}` + "\n"
+	if err := os.WriteFile(input, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := StreamLoader{}
+	result, err := loader.RouteRecords(input, []RouteRule{
+		{Match: []interface{}{map[string]interface{}{"field": "method", "op": "eq", "value": "GET"}}, Sink: "reads"},
+		{Sink: "rest"},
+	})
+	if err != nil {
+		t.Fatalf("RouteRecords() error = %v", err)
+	}
+	if len(result.Feeds["reads"]) != 2 || len(result.Feeds["rest"]) != 1 || result.Unmatched != 0 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestRouteRecordsErrors(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "traffic.json")
+	if err := os.WriteFile(input, []byte(trafficCorpus), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		rules   []RouteRule
+		wantErr string
+	}{
+		{"no rules", input, nil, "at least one rule"},
+		{"missing sink", input, []RouteRule{{}}, "sink is required"},
+		{"sink routed to two files", input, []RouteRule{
+			{Sink: "a", File: filepath.Join(dir, "a.json")},
+			{Sink: "a", File: filepath.Join(dir, "b.json")},
+		}, "already routed"},
+		{"two sinks writing one file", input, []RouteRule{
+			{Sink: "a", File: filepath.Join(dir, "a.json")},
+			{Sink: "b", File: filepath.Join(dir, "a.json")},
+		}, "both write to"},
+		{"invalid condition", input, []RouteRule{{Match: map[string]interface{}{"field": "method", "op": "like"}, Sink: "a"}}, "rule 0"},
+		{"missing file", filepath.Join(dir, "missing.json"), []RouteRule{{Sink: "a"}}, "missing.json"},
+	}
+
+	loader := StreamLoader{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loader.RouteRecords(tt.path, tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RouteRecords() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteRecordsJavaScript(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "traffic.json")
+	if err := os.WriteFile(input, []byte(trafficCorpus), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Set("input", input); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunString(`
+		const routed = streamloader.routeRecords(input, [
+			{ match: (r) => r.url.startsWith("/api/"), sink: "api" },
+			{ match: { field: "method", op: "eq", value: "POST" }, sink: "writes" },
+			{ sink: "other" },
+		]);
+		JSON.stringify([routed.counts.api, routed.counts.other, routed.feeds.writes.map((r) => r.url), routed.unmatched]);
+	`)
+	if err != nil {
+		t.Fatalf("script error = %v", err)
+	}
+	if got, want := value.String(), `[3,1,["/login"],0]`; got != want {
+		t.Errorf("script result = %s, want %s", got, want)
+	}
+}
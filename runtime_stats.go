@@ -0,0 +1,193 @@
+// runtime_stats.go
+package streamloader
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.k6.io/k6/js/common"
+)
+
+// RuntimeStats is the result of GetRuntimeStats
+type RuntimeStats struct {
+	UptimeMs        float64          `json:"uptimeMs" js:"uptimeMs"`
+	BytesRead       int64            `json:"bytesRead" js:"bytesRead"`
+	BytesWritten    int64            `json:"bytesWritten" js:"bytesWritten"`
+	OpenFileHandles int              `json:"openFileHandles" js:"openFileHandles"`
+	PooledFiles     int              `json:"pooledFiles" js:"pooledFiles"`
+	KvEntries       int              `json:"kvEntries" js:"kvEntries"`
+	KvBytes         int64            `json:"kvBytes" js:"kvBytes"`
+	DatasetsLoaded  int              `json:"datasetsLoaded" js:"datasetsLoaded"`
+	DatasetBytes    int64            `json:"datasetBytes" js:"datasetBytes"`
+	HeapAllocBytes  uint64           `json:"heapAllocBytes" js:"heapAllocBytes"`
+	Goroutines      int              `json:"goroutines" js:"goroutines"`
+	Calls           map[string]int64 `json:"calls" js:"calls"`
+}
+
+// ioStats holds the process-wide counters behind GetRuntimeStats
+var ioStats struct {
+	started      atomic.Int64 // Unix nanoseconds
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	calls        sync.Map // JavaScript function name -> *atomic.Int64
+}
+
+func init() {
+	ioStats.started.Store(time.Now().UnixNano())
+}
+
+// countingReader counts the bytes read through it in the runtime stats.
+type countingReader struct {
+	io.ReadCloser
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	ioStats.bytesRead.Add(int64(n))
+	return n, err
+}
+
+// countingWriter counts the bytes written through it in the runtime stats.
+type countingWriter struct {
+	w io.Writer
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	ioStats.bytesWritten.Add(int64(n))
+	return n, err
+}
+
+// callCounter returns the call counter of a JavaScript function.
+func callCounter(name string) *atomic.Int64 {
+	counter, _ := ioStats.calls.LoadOrStore(name, new(atomic.Int64))
+	return counter.(*atomic.Int64)
+}
+
+// exportsWithCallCounts returns the loader's methods under their JavaScript names, each wrapped
+// to count its calls, enforce the limits of SetLimits and log the calls according to SetLogging.
+func exportsWithCallCounts(loader *StreamLoader) map[string]interface{} {
+	value := reflect.ValueOf(loader)
+	typ := value.Type()
+	exports := make(map[string]interface{}, typ.NumMethod())
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := common.MethodName(typ, typ.Method(i))
+		method := value.Method(i)
+		counter := callCounter(name)
+		limited := returnsError(method.Type())
+		exports[name] = reflect.MakeFunc(method.Type(), func(args []reflect.Value) []reflect.Value {
+			counter.Add(1)
+			unwrapSharedArgs(args)
+			var l FunctionLimits
+			if limited {
+				l = functionLimits(name)
+			}
+			call := startCallLog()
+			bound := method
+			if l != (FunctionLimits{}) {
+				// Call a copy of the loader carrying the limits to the inputs it reads
+				limitedLoader := *loader
+				limitedLoader.call = newCallLimits(name, l, call.start)
+				bound = reflect.ValueOf(&limitedLoader).Method(i)
+			}
+			var results []reflect.Value
+			if err := checkFileLimit(name, l, args); err != nil {
+				results = errorResults(method.Type(), err)
+			} else {
+				if method.Type().IsVariadic() {
+					results = bound.CallSlice(args)
+				} else {
+					results = bound.Call(args)
+				}
+				if err := checkDurationLimit(name, l, time.Since(call.start)); err != nil {
+					closeResults(results)
+					results = errorResults(method.Type(), err)
+				}
+			}
+			call.finish(*loader, name, args, results)
+			return results
+		}).Interface()
+	}
+	return exports
+}
+
+// openFileHandles returns the number of file descriptors the process has open, or -1 where
+// it can't be determined.
+func openFileHandles() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			// Reading the directory itself opens one descriptor
+			return len(entries) - 1
+		}
+	}
+	return -1
+}
+
+// GetRuntimeStats returns cumulative statistics of the data layer since the process started,
+// shared by all VUs, so long soak tests can assert that handles and memory don't leak:
+//
+//   - bytesRead: bytes read by the streaming loaders (loadJSON, loadCSV, processCsvFile and
+//     the functions that stream JSON records)
+//   - bytesWritten: bytes written to disk by the JSON array and JSONL writers
+//   - openFileHandles: file descriptors open in the k6 process (-1 if unknown)
+//   - pooledFiles: files held open by the loaders and writers, limited by maxOpenFiles
+//   - kvEntries, kvBytes: entries and encoded value bytes in the scratch store
+//   - datasetsLoaded, datasetBytes: shared datasets in memory and the size of their files
+//   - heapAllocBytes, goroutines: Go heap in use and running goroutines
+//   - calls: the number of calls of each function by scripts, by JavaScript name
+//
+// Example usage:
+//
+//	const stats = streamloader.getRuntimeStats();
+//	check(stats, { "no handle leak": (s) => s.openFileHandles < 100 });
+func (StreamLoader) GetRuntimeStats() RuntimeStats {
+	stats := RuntimeStats{
+		UptimeMs:        durationMs(time.Since(time.Unix(0, ioStats.started.Load()))),
+		BytesRead:       ioStats.bytesRead.Load(),
+		BytesWritten:    ioStats.bytesWritten.Load(),
+		OpenFileHandles: openFileHandles(),
+		PooledFiles:     pooledFiles(),
+		Goroutines:      runtime.NumGoroutine(),
+		Calls:           make(map[string]int64),
+	}
+
+	stats.DatasetsLoaded, stats.DatasetBytes = loadedDatasets()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats.HeapAllocBytes = mem.HeapAlloc
+
+	now := time.Now()
+	scratchStore.mu.Lock()
+	for key := range scratchStore.entries {
+		if entry, ok := scratchStore.get(key, now); ok {
+			stats.KvEntries++
+			stats.KvBytes += int64(len(entry.value))
+		}
+	}
+	scratchStore.mu.Unlock()
+
+	ioStats.calls.Range(func(key, value interface{}) bool {
+		if n := value.(*atomic.Int64).Load(); n > 0 {
+			stats.Calls[key.(string)] = n
+		}
+		return true
+	})
+	return stats
+}
+
+// ResetRuntimeStats zeroes the byte and call counters and restarts the uptime.
+func (StreamLoader) ResetRuntimeStats() {
+	ioStats.started.Store(time.Now().UnixNano())
+	ioStats.bytesRead.Store(0)
+	ioStats.bytesWritten.Store(0)
+	ioStats.calls.Range(func(key, value interface{}) bool {
+		value.(*atomic.Int64).Store(0)
+		return true
+	})
+}
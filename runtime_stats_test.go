@@ -0,0 +1,111 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+func TestGetRuntimeStatsCountsBytes(t *testing.T) {
+	loader := StreamLoader{}
+	loader.ResetRuntimeStats()
+	dir := t.TempDir()
+
+	input := filepath.Join(dir, "input.json")
+	content := `[{"id":1},{"id":2}]`
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.LoadJSON(input); err != nil {
+		t.Fatalf("LoadJSON() error = %v", err)
+	}
+	if _, err := loader.LoadText(input); err != nil {
+		t.Fatalf("LoadText() error = %v", err)
+	}
+	output := filepath.Join(dir, "output.json")
+	if _, err := loader.WriteObjectsToJsonArrayFile([]interface{}{map[string]interface{}{"id": 1}}, output); err != nil {
+		t.Fatalf("WriteObjectsToJsonArrayFile() error = %v", err)
+	}
+
+	stats := loader.GetRuntimeStats()
+	if want := int64(2 * len(content)); stats.BytesRead < want {
+		t.Errorf("BytesRead = %d, want at least %d", stats.BytesRead, want)
+	}
+	info, err := os.Stat(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.BytesWritten != info.Size() {
+		t.Errorf("BytesWritten = %d, want %d", stats.BytesWritten, info.Size())
+	}
+	if stats.UptimeMs < 0 || stats.Goroutines <= 0 || stats.HeapAllocBytes == 0 {
+		t.Errorf("unexpected process stats: %+v", stats)
+	}
+}
+
+func TestGetRuntimeStatsOpenFileHandles(t *testing.T) {
+	loader := StreamLoader{}
+	before := loader.GetRuntimeStats().OpenFileHandles
+	if before < 0 {
+		t.Skip("open file handles are not available on this platform")
+	}
+	file, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	during := loader.GetRuntimeStats().OpenFileHandles
+	file.Close()
+	after := loader.GetRuntimeStats().OpenFileHandles
+	if during != before+1 || after != before {
+		t.Errorf("open file handles before, during and after = %d, %d, %d", before, during, after)
+	}
+}
+
+func TestGetRuntimeStatsKvStore(t *testing.T) {
+	loader := StreamLoader{}
+	loader.KvClear()
+	defer loader.KvClear()
+	if err := loader.KvSet("a", "xyz"); err != nil {
+		t.Fatal(err)
+	}
+	stats := loader.GetRuntimeStats()
+	if stats.KvEntries != 1 || stats.KvBytes == 0 {
+		t.Errorf("KvEntries = %d, KvBytes = %d, want 1 entry", stats.KvEntries, stats.KvBytes)
+	}
+}
+
+func TestExportsWithCallCounts(t *testing.T) {
+	loader := &StreamLoader{}
+	loader.ResetRuntimeStats()
+	exports := exportsWithCallCounts(loader)
+	for _, name := range []string{"loadJSON", "objectsToJsonLines", "getRuntimeStats"} {
+		if _, ok := exports[name]; !ok {
+			t.Fatalf("export %q is missing", name)
+		}
+	}
+
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	if err := rt.Set("streamloader", exports); err != nil {
+		t.Fatal(err)
+	}
+	_, err := rt.RunString(`
+		streamloader.objectsToJsonLines([{a: 1}]);
+		streamloader.objectsToJsonLines([{a: 2}]);
+		streamloader.objectsToCompressedJsonLines([{a: 1}], 9);
+	`)
+	if err != nil {
+		t.Fatalf("script error = %v", err)
+	}
+
+	calls := loader.GetRuntimeStats().Calls
+	if calls["objectsToJsonLines"] != 2 || calls["objectsToCompressedJsonLines"] != 1 {
+		t.Errorf("Calls = %v", calls)
+	}
+	if _, ok := calls["loadJSON"]; ok {
+		t.Errorf("uncalled function loadJSON is reported")
+	}
+}
@@ -0,0 +1,116 @@
+// sampling.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+)
+
+// StratifiedSample downsamples a JSON array or NDJSON file while keeping every group of records
+// represented. Records are grouped by the value of groupField, and each group is sampled on its
+// own, so rare groups (such as seldom-hit endpoints in a recording) don't vanish the way they
+// can with plain random sampling.
+//
+// perGroup selects the sample size:
+//   - A value between 0 and 1 is a rate: each group keeps round(rate * groupSize) records,
+//     but at least one, so the sample stays proportional to the input
+//   - A whole number >= 1 is a fixed count: each group keeps up to that many records
+//
+// Records without groupField form their own group. The file is read twice, once to count the
+// groups and once to select records, and only the sampled records are held in memory. The
+// sample keeps the original file order and is deterministic for a given seed.
+//
+// Example usage:
+//
+//	// Keep 10% of every endpoint's requests
+//	sample, err := streamloader.StratifiedSample("requests.json", "endpoint", 0.1, 42)
+//	// Keep up to 50 requests per endpoint
+//	sample, err := streamloader.StratifiedSample("requests.json", "endpoint", 50, 42)
+func (s StreamLoader) StratifiedSample(filePath string, groupField string, perGroup float64, seed int64) ([]any, error) {
+	if perGroup <= 0 || (perGroup >= 1 && perGroup != math.Trunc(perGroup)) {
+		return nil, fmt.Errorf("invalid perGroup %v: expected a rate between 0 and 1 or a whole count >= 1", perGroup)
+	}
+
+	if err := requireSeekable(filePath, "stratifiedSample"); err != nil {
+		return nil, err
+	}
+
+	// First pass: count the records in each group
+	counts := make(map[string]int)
+	err := forEachGroupedRecord(filePath, groupField, s.call, func(key string, _ json.RawMessage) error {
+		counts[key]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out how many records each group keeps
+	remaining := make(map[string]int, len(counts))
+	for key, count := range counts {
+		target := int(perGroup)
+		if perGroup < 1 {
+			target = int(math.Max(1, math.Round(perGroup*float64(count))))
+		}
+		remaining[key] = min(target, count)
+	}
+
+	// Second pass: selection sampling (Knuth's Algorithm S) picks exactly the target number
+	// of records from each group in a single ordered pass
+	rng := rand.New(rand.NewSource(seed))
+	sample := make([]any, 0)
+	err = forEachGroupedRecord(filePath, groupField, s.call, func(key string, raw json.RawMessage) error {
+		left := counts[key]
+		if left <= 0 {
+			// The file was changed between the passes
+			return fmt.Errorf("%s changed while it was sampled: group %s=%s has more records than the first pass counted", filePath, groupField, key)
+		}
+		counts[key]--
+		if remaining[key] == 0 || rng.Intn(left) >= remaining[key] {
+			return nil
+		}
+		remaining[key]--
+
+		var record any
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return fmt.Errorf("failed to decode record: %w", err)
+		}
+		sample = append(sample, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sample, nil
+}
+
+// forEachGroupedRecord streams the records of a JSON array or NDJSON file and calls fn with the
+// raw JSON of groupField's value (or "" when the field is missing) and the raw record.
+func forEachGroupedRecord(filePath string, groupField string, call callLimits, fn func(key string, raw json.RawMessage) error) error {
+	records, err := openJsonRecords(filePath, call)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+
+	for index := 0; ; index++ {
+		raw, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return fmt.Errorf("record %d in %s is not a JSON object: %w", index, filePath, err)
+		}
+		if err := fn(string(fields[groupField]), raw); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStratifiedSample(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	// 100 "list" requests, 10 "search" requests and a single "export" request
+	var lines []string
+	for i := 0; i < 111; i++ {
+		endpoint := "list"
+		switch {
+		case i%11 == 5:
+			endpoint = "search"
+		case i == 110:
+			endpoint = "export"
+		}
+		lines = append(lines, fmt.Sprintf(`{"id":%d,"endpoint":%q}`, i, endpoint))
+	}
+	filePath := filepath.Join(dir, "requests.ndjson")
+	if err := os.WriteFile(filePath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	countByEndpoint := func(sample []any) map[string]int {
+		counts := make(map[string]int)
+		lastID := -1.0
+		for _, record := range sample {
+			obj := record.(map[string]interface{})
+			counts[obj["endpoint"].(string)]++
+			if id := obj["id"].(float64); id <= lastID {
+				t.Errorf("sample is not in file order: %v after %v", id, lastID)
+			} else {
+				lastID = id
+			}
+		}
+		return counts
+	}
+
+	tests := []struct {
+		name     string
+		perGroup float64
+		want     map[string]int
+	}{
+		{"rate", 0.1, map[string]int{"list": 10, "search": 1, "export": 1}},
+		{"fixed count", 5, map[string]int{"list": 5, "search": 5, "export": 1}},
+		{"count larger than groups", 1000, map[string]int{"list": 100, "search": 10, "export": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := loader.StratifiedSample(filePath, "endpoint", tt.perGroup, 7)
+			if err != nil {
+				t.Fatalf("StratifiedSample() error = %v", err)
+			}
+			if got := countByEndpoint(sample); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("deterministic for a seed", func(t *testing.T) {
+		first, _ := loader.StratifiedSample(filePath, "endpoint", 0.2, 99)
+		second, _ := loader.StratifiedSample(filePath, "endpoint", 0.2, 99)
+		other, _ := loader.StratifiedSample(filePath, "endpoint", 0.2, 100)
+		if !reflect.DeepEqual(first, second) {
+			t.Error("Expected the same sample for the same seed")
+		}
+		if reflect.DeepEqual(first, other) {
+			t.Error("Expected a different sample for a different seed")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, perGroup := range []float64{0, -1, 2.5} {
+			if _, err := loader.StratifiedSample(filePath, "endpoint", perGroup, 1); err == nil {
+				t.Errorf("Expected error for perGroup %v", perGroup)
+			}
+		}
+		primitives := filepath.Join(dir, "primitives.json")
+		os.WriteFile(primitives, []byte(`[1,2,3]`), 0644)
+		if _, err := loader.StratifiedSample(primitives, "endpoint", 1, 1); err == nil {
+			t.Error("Expected error for non-object records")
+		}
+		if _, err := loader.StratifiedSample(filepath.Join(dir, "missing.json"), "endpoint", 1, 1); err == nil {
+			t.Error("Expected error for missing file")
+		}
+	})
+}
@@ -0,0 +1,309 @@
+// schema.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// schemaCardinalityCap bounds the distinct values tracked per field, so scanning a whole file
+// never holds more than this many values for any column
+const schemaCardinalityCap = 10000
+
+// schemaExampleCount is the number of distinct example values reported per field
+const schemaExampleCount = 3
+
+// schemaTypeOrder lists the reported value types in a fixed order
+var schemaTypeOrder = []string{"string", "integer", "number", "boolean", "object", "array"}
+
+// SchemaReport describes the records of a dataset as returned by InferSchema
+type SchemaReport struct {
+	Format         string        `json:"format" js:"format"`
+	RecordsSampled int           `json:"recordsSampled" js:"recordsSampled"`
+	Fields         []SchemaField `json:"fields" js:"fields"`
+}
+
+// SchemaField describes one field or column of a dataset
+type SchemaField struct {
+	Name              string        `json:"name" js:"name"`
+	Types             []string      `json:"types" js:"types"`
+	Nullable          bool          `json:"nullable" js:"nullable"`
+	Count             int           `json:"count" js:"count"`
+	Examples          []interface{} `json:"examples" js:"examples"`
+	Cardinality       int           `json:"cardinality" js:"cardinality"`
+	CardinalityCapped bool          `json:"cardinalityCapped" js:"cardinalityCapped"`
+}
+
+// schemaBuilder accumulates field statistics in first-seen field order.
+type schemaBuilder struct {
+	records int
+	order   []string
+	fields  map[string]*fieldStats
+}
+
+type fieldStats struct {
+	types    map[string]bool
+	count    int
+	examples []interface{}
+	distinct map[string]bool
+	capped   bool
+}
+
+// InferSchema samples the first sampleN records of a JSON array, NDJSON or CSV file and reports
+// each field's name, value types, nullability, example values and number of distinct values.
+// It helps with writing options and schemas for unfamiliar datasets without opening them in
+// other tools.
+//
+// Files ending in .csv are read as CSV with a header row; everything else is read as a JSON
+// array or NDJSON. A sampleN of 0 or less scans the whole file.
+//
+// Types are "string", "integer", "number", "boolean", "object" and "array". CSV cells are
+// classified by their content. A field is nullable if it is null, empty (CSV) or missing in any
+// sampled record. Cardinality counts distinct values in the sample; past 10000 distinct values
+// counting stops and cardinalityCapped is set.
+//
+// Example usage:
+//
+//	report, err := streamloader.InferSchema("requests.json", 1000)
+//	// report.Fields[0] = {Name: "id", Types: ["integer"], Nullable: false, Cardinality: 1000, ...}
+func (s StreamLoader) InferSchema(filePath string, sampleN int) (*SchemaReport, error) {
+	b := &schemaBuilder{fields: make(map[string]*fieldStats)}
+	format := "json"
+	var err error
+	if strings.EqualFold(inputExt(filePath), ".csv") {
+		format = "csv"
+		err = b.scanCSV(filePath, sampleN, s.call)
+	} else {
+		err = b.scanJSON(filePath, sampleN, s.call)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.report(format), nil
+}
+
+// scanJSON collects statistics from the records of a JSON array or NDJSON file.
+func (b *schemaBuilder) scanJSON(filePath string, sampleN int, call callLimits) error {
+	records, err := openJsonRecords(filePath, call)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+
+	for sampleN <= 0 || b.records < sampleN {
+		raw, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		names, values, err := objectFields(raw)
+		if err != nil {
+			return fmt.Errorf("failed to read record %d: %w", b.records, err)
+		}
+		if names == nil {
+			// Records that aren't objects are reported as a single "$" field
+			names, values = []string{"$"}, []json.RawMessage{raw}
+		}
+
+		b.records++
+		for i, name := range names {
+			value := values[i]
+			typ := jsonValueType(value)
+			var decoded interface{}
+			if typ != "null" && b.wantsExample(name, string(value)) {
+				json.Unmarshal(value, &decoded)
+			}
+			b.add(name, typ, string(value), decoded)
+		}
+	}
+	return nil
+}
+
+// scanCSV collects statistics from the rows of a CSV file, naming columns after the header row.
+func (b *schemaBuilder) scanCSV(filePath string, sampleN int, call callLimits) error {
+	file, err := openInput(filePath, call)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true))
+	csvReader.LazyQuotes = true
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse CSV header: %w", err)
+	}
+	header = append([]string(nil), header...)
+
+	for sampleN <= 0 || b.records < sampleN {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV at row %d: %w", b.records+2, err)
+		}
+
+		b.records++
+		for i, cell := range record {
+			name := fmt.Sprintf("column_%d", i)
+			if i < len(header) {
+				name = header[i]
+			}
+			typ := csvValueType(cell)
+			if typ == "null" {
+				b.add(name, typ, "", nil)
+			} else {
+				b.add(name, typ, cell, cell)
+			}
+		}
+	}
+	return nil
+}
+
+// wantsExample reports whether a value would be kept as an example, to avoid decoding values
+// that are never reported.
+func (b *schemaBuilder) wantsExample(name string, key string) bool {
+	stats := b.fields[name]
+	return stats == nil || (len(stats.examples) < schemaExampleCount && !stats.distinct[key])
+}
+
+// add records one value of a field. key identifies the value for cardinality counting.
+func (b *schemaBuilder) add(name string, typ string, key string, example interface{}) {
+	stats, ok := b.fields[name]
+	if !ok {
+		stats = &fieldStats{types: make(map[string]bool), distinct: make(map[string]bool)}
+		b.fields[name] = stats
+		b.order = append(b.order, name)
+	}
+	if typ == "null" {
+		return
+	}
+
+	stats.count++
+	stats.types[typ] = true
+	if stats.distinct[key] {
+		return
+	}
+	if len(stats.examples) < schemaExampleCount {
+		stats.examples = append(stats.examples, example)
+	}
+	if len(stats.distinct) < schemaCardinalityCap {
+		stats.distinct[key] = true
+	} else {
+		stats.capped = true
+	}
+}
+
+// report builds the final report. Fields missing from some records are marked nullable.
+func (b *schemaBuilder) report(format string) *SchemaReport {
+	report := &SchemaReport{Format: format, RecordsSampled: b.records, Fields: make([]SchemaField, 0, len(b.order))}
+	for _, name := range b.order {
+		stats := b.fields[name]
+		types := make([]string, 0, len(stats.types))
+		for _, typ := range schemaTypeOrder {
+			if stats.types[typ] {
+				types = append(types, typ)
+			}
+		}
+		examples := stats.examples
+		if examples == nil {
+			examples = []interface{}{}
+		}
+		report.Fields = append(report.Fields, SchemaField{
+			Name:              name,
+			Types:             types,
+			Nullable:          stats.count < b.records,
+			Count:             stats.count,
+			Examples:          examples,
+			Cardinality:       len(stats.distinct),
+			CardinalityCapped: stats.capped,
+		})
+	}
+	return report
+}
+
+// objectFields returns the top-level keys and raw values of a JSON object in document order.
+// It returns nil slices if the value is not an object.
+func objectFields(raw json.RawMessage) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	t, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '{' {
+		return nil, nil, nil
+	}
+
+	names := []string{}
+	var values []json.RawMessage
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, t.(string))
+		values = append(values, value)
+	}
+	return names, values, nil
+}
+
+// jsonValueType classifies a raw JSON value by its first byte.
+func jsonValueType(value json.RawMessage) string {
+	if len(value) == 0 {
+		return "null"
+	}
+	switch value[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	if bytes.ContainsAny(value, ".eE") {
+		return "number"
+	}
+	return "integer"
+}
+
+// csvValueType classifies a CSV cell by its content. Empty cells are treated as null.
+func csvValueType(cell string) string {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return "null"
+	}
+	if strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false") {
+		return "boolean"
+	}
+	if _, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return "number"
+	}
+	return "string"
+}
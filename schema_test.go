@@ -0,0 +1,96 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema_JSON(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "requests.json")
+	content := `[
+		{"id": 1, "method": "GET", "latency": 1.5, "tags": ["a"], "user": null},
+		{"id": 2, "method": "POST", "latency": 2, "tags": [], "user": {"name": "x"}},
+		{"id": 3, "method": "GET", "latency": 3.25, "cached": true},
+		{"id": 4, "method": "GET", "latency": 4.5}
+	]`
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	report, err := loader.InferSchema(filePath, 0)
+	if err != nil {
+		t.Fatalf("InferSchema() error = %v", err)
+	}
+	if report.Format != "json" || report.RecordsSampled != 4 {
+		t.Errorf("unexpected report header: %+v", report)
+	}
+
+	expected := []SchemaField{
+		{Name: "id", Types: []string{"integer"}, Count: 4, Examples: []interface{}{1.0, 2.0, 3.0}, Cardinality: 4},
+		{Name: "method", Types: []string{"string"}, Count: 4, Examples: []interface{}{"GET", "POST"}, Cardinality: 2},
+		{Name: "latency", Types: []string{"integer", "number"}, Count: 4, Examples: []interface{}{1.5, 2.0, 3.25}, Cardinality: 4},
+		{Name: "tags", Types: []string{"array"}, Nullable: true, Count: 2, Examples: []interface{}{[]interface{}{"a"}, []interface{}{}}, Cardinality: 2},
+		{Name: "user", Types: []string{"object"}, Nullable: true, Count: 1, Examples: []interface{}{map[string]interface{}{"name": "x"}}, Cardinality: 1},
+		{Name: "cached", Types: []string{"boolean"}, Nullable: true, Count: 1, Examples: []interface{}{true}, Cardinality: 1},
+	}
+	if !reflect.DeepEqual(report.Fields, expected) {
+		t.Errorf("got %+v\nwant %+v", report.Fields, expected)
+	}
+
+	// Sampling stops after sampleN records
+	report, err = loader.InferSchema(filePath, 2)
+	if err != nil {
+		t.Fatalf("InferSchema() error = %v", err)
+	}
+	if report.RecordsSampled != 2 || len(report.Fields) != 5 {
+		t.Errorf("expected 2 records and 5 fields, got %d and %d", report.RecordsSampled, len(report.Fields))
+	}
+}
+
+func TestInferSchema_CSV(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "users.csv")
+	if err := os.WriteFile(filePath, []byte("id,name,score,active\n1,Alice,9.5,true\n2,Bob,,false\n3,Alice,7,TRUE,extra\n"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	report, err := loader.InferSchema(filePath, 100)
+	if err != nil {
+		t.Fatalf("InferSchema() error = %v", err)
+	}
+	if report.Format != "csv" || report.RecordsSampled != 3 {
+		t.Errorf("unexpected report header: %+v", report)
+	}
+
+	expected := []SchemaField{
+		{Name: "id", Types: []string{"integer"}, Count: 3, Examples: []interface{}{"1", "2", "3"}, Cardinality: 3},
+		{Name: "name", Types: []string{"string"}, Count: 3, Examples: []interface{}{"Alice", "Bob"}, Cardinality: 2},
+		{Name: "score", Types: []string{"integer", "number"}, Nullable: true, Count: 2, Examples: []interface{}{"9.5", "7"}, Cardinality: 2},
+		{Name: "active", Types: []string{"boolean"}, Count: 3, Examples: []interface{}{"true", "false", "TRUE"}, Cardinality: 3},
+		{Name: "column_4", Types: []string{"string"}, Nullable: true, Count: 1, Examples: []interface{}{"extra"}, Cardinality: 1},
+	}
+	if !reflect.DeepEqual(report.Fields, expected) {
+		t.Errorf("got %+v\nwant %+v", report.Fields, expected)
+	}
+}
+
+func TestInferSchema_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	if _, err := loader.InferSchema(filepath.Join(dir, "missing.json"), 10); err == nil {
+		t.Error("Expected error for missing JSON file")
+	}
+	if _, err := loader.InferSchema(filepath.Join(dir, "missing.csv"), 10); err == nil {
+		t.Error("Expected error for missing CSV file")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte(`[{"a":1},{"a":`), 0644)
+	if _, err := loader.InferSchema(bad, 0); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+}
@@ -0,0 +1,192 @@
+// sequences.go
+package streamloader
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// Sequence is a lazily evaluated, random-access list of generated values. Values are computed
+// on demand from their index, so a sequence of millions of keys costs no memory until it is read.
+// A sequence also carries its own cursor (Next/HasNext/Reset) and can be split into disjoint
+// partitions, e.g. one per VU. A sequence created during an iteration is closed when the
+// iteration ends.
+type Sequence struct {
+	offset int
+	length int
+	at     func(i int) interface{}
+
+	mu     sync.Mutex
+	pos    int
+	closed bool
+}
+
+// Length returns the number of values in the sequence.
+func (s *Sequence) Length() int {
+	return s.length
+}
+
+// At returns the value at the given zero-based index.
+func (s *Sequence) At(index int) (interface{}, error) {
+	if s.isClosed() {
+		return nil, fmt.Errorf("sequence is closed")
+	}
+	if index < 0 || index >= s.length {
+		return nil, fmt.Errorf("index %d out of range [0, %d)", index, s.length)
+	}
+	return s.at(s.offset + index), nil
+}
+
+// HasNext reports whether the cursor has values left.
+func (s *Sequence) HasNext() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed && s.pos < s.length
+}
+
+// Next returns the value under the cursor and advances it. It returns nil once the sequence
+// is exhausted; call Reset to start over.
+func (s *Sequence) Next() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.pos >= s.length {
+		return nil
+	}
+	value := s.at(s.offset + s.pos)
+	s.pos++
+	return value
+}
+
+// Reset moves the cursor back to the first value.
+func (s *Sequence) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pos = 0
+}
+
+// Close releases the sequence. Afterwards Next returns nil and At returns an error; sequences
+// sliced from it are not affected.
+func (s *Sequence) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+// Dispose is an alias of Close.
+func (s *Sequence) Dispose() {
+	s.Close()
+}
+
+// isClosed reports whether the sequence was closed.
+func (s *Sequence) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Slice returns a new sequence with the values in [start, end).
+func (s *Sequence) Slice(start int, end int) (*Sequence, error) {
+	if start < 0 || end > s.length || start > end {
+		return nil, fmt.Errorf("invalid slice [%d, %d) of sequence with length %d", start, end, s.length)
+	}
+	return &Sequence{offset: s.offset + start, length: end - start, at: s.at}, nil
+}
+
+// Partition splits the sequence into count contiguous parts of near-equal size and returns the
+// part with the given zero-based index. Earlier parts receive the remainder, one extra value each.
+//
+// Example usage:
+//
+//	ids := streamloader.GenerateRange(0, 1000000)
+//	mine := ids.Partition(__VU - 1, totalVUs)
+func (s *Sequence) Partition(index int, count int) (*Sequence, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("partition count must be positive, got %d", count)
+	}
+	if index < 0 || index >= count {
+		return nil, fmt.Errorf("partition index %d out of range [0, %d)", index, count)
+	}
+	size := s.length / count
+	remainder := s.length % count
+	start := index*size + min(index, remainder)
+	end := start + size
+	if index < remainder {
+		end++
+	}
+	return s.Slice(start, end)
+}
+
+// ToArray materializes every value of the sequence.
+func (s *Sequence) ToArray() []interface{} {
+	values := make([]interface{}, s.length)
+	for i := range values {
+		values[i] = s.at(s.offset + i)
+	}
+	return values
+}
+
+// GenerateRange returns a lazy sequence of integers from start (inclusive) to end (exclusive)
+// advancing by step (default: 1). A negative step produces a descending sequence.
+//
+// Example usage:
+//
+//	ids := streamloader.GenerateRange(1000, 2000, 10) // 1000, 1010, ..., 1990
+//	id := ids.At(5)                                   // 1050
+func (s StreamLoader) GenerateRange(start int64, end int64, step ...int64) (*Sequence, error) {
+	inc := int64(1)
+	if len(step) > 0 {
+		inc = step[0]
+	}
+	if inc == 0 {
+		return nil, fmt.Errorf("step must not be zero")
+	}
+
+	length := int64(0)
+	if inc > 0 && end > start {
+		length = (end - start + inc - 1) / inc
+	} else if inc < 0 && end < start {
+		length = (start - end - inc - 1) / -inc
+	}
+
+	seq := &Sequence{
+		length: int(length),
+		at: func(i int) interface{} {
+			return start + int64(i)*inc
+		},
+	}
+	s.closeAtIterationEnd(seq)
+	return seq, nil
+}
+
+// GenerateUUIDs returns a lazy sequence of n version 4 UUIDs derived deterministically from
+// the seed, so every VU and every test run sees the same keyspace. Each UUID is computed from
+// a hash of the seed and its index, which keeps random access cheap.
+//
+// Example usage:
+//
+//	users := streamloader.GenerateUUIDs(100000, 42)
+//	userId := users.At(__ITER % users.length())
+func (s StreamLoader) GenerateUUIDs(n int, seed int64) (*Sequence, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("count must not be negative, got %d", n)
+	}
+
+	seq := &Sequence{
+		length: n,
+		at: func(i int) interface{} {
+			var input [16]byte
+			binary.BigEndian.PutUint64(input[:8], uint64(seed))
+			binary.BigEndian.PutUint64(input[8:], uint64(i))
+			sum := sha256.Sum256(input[:])
+
+			uuid := sum[:16]
+			uuid[6] = (uuid[6] & 0x0f) | 0x40 // Version 4
+			uuid[8] = (uuid[8] & 0x3f) | 0x80 // RFC 4122 variant
+			return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+		},
+	}
+	s.closeAtIterationEnd(seq)
+	return seq, nil
+}
@@ -0,0 +1,142 @@
+package streamloader
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateRange(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name     string
+		start    int64
+		end      int64
+		step     []int64
+		expected []interface{}
+	}{
+		{"Default step", 0, 5, nil, []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4)}},
+		{"Custom step", 10, 20, []int64{4}, []interface{}{int64(10), int64(14), int64(18)}},
+		{"Descending", 5, 0, []int64{-2}, []interface{}{int64(5), int64(3), int64(1)}},
+		{"Empty range", 5, 5, nil, []interface{}{}},
+		{"Wrong direction", 0, 10, []int64{-1}, []interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, err := loader.GenerateRange(tt.start, tt.end, tt.step...)
+			if err != nil {
+				t.Fatalf("GenerateRange() error = %v", err)
+			}
+			if seq.Length() != len(tt.expected) {
+				t.Errorf("Length() = %d, want %d", seq.Length(), len(tt.expected))
+			}
+			if got := seq.ToArray(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ToArray() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := loader.GenerateRange(0, 10, 0); err == nil {
+		t.Error("Expected error for zero step")
+	}
+}
+
+func TestSequence_Cursor(t *testing.T) {
+	loader := StreamLoader{}
+	seq, _ := loader.GenerateRange(1, 4)
+
+	var values []interface{}
+	for seq.HasNext() {
+		values = append(values, seq.Next())
+	}
+	if !reflect.DeepEqual(values, []interface{}{int64(1), int64(2), int64(3)}) {
+		t.Errorf("Unexpected cursor values: %v", values)
+	}
+	if seq.Next() != nil {
+		t.Error("Expected nil after the sequence is exhausted")
+	}
+
+	seq.Reset()
+	if got := seq.Next(); got != int64(1) {
+		t.Errorf("Expected 1 after Reset, got %v", got)
+	}
+
+	if _, err := seq.At(3); err == nil {
+		t.Error("Expected error for out of range index")
+	}
+}
+
+func TestSequence_Partition(t *testing.T) {
+	loader := StreamLoader{}
+	seq, _ := loader.GenerateRange(0, 10)
+
+	var all []interface{}
+	sizes := []int{}
+	for i := 0; i < 3; i++ {
+		part, err := seq.Partition(i, 3)
+		if err != nil {
+			t.Fatalf("Partition(%d, 3) error = %v", i, err)
+		}
+		sizes = append(sizes, part.Length())
+		all = append(all, part.ToArray()...)
+	}
+
+	if !reflect.DeepEqual(sizes, []int{4, 3, 3}) {
+		t.Errorf("Unexpected partition sizes: %v", sizes)
+	}
+	if !reflect.DeepEqual(all, seq.ToArray()) {
+		t.Errorf("Partitions do not cover the sequence: %v", all)
+	}
+
+	part, _ := seq.Partition(1, 3)
+	if v, _ := part.At(0); v != int64(4) {
+		t.Errorf("Expected first value of partition 1 to be 4, got %v", v)
+	}
+
+	if _, err := seq.Partition(3, 3); err == nil {
+		t.Error("Expected error for partition index out of range")
+	}
+	if _, err := seq.Partition(0, 0); err == nil {
+		t.Error("Expected error for zero partitions")
+	}
+}
+
+func TestGenerateUUIDs(t *testing.T) {
+	loader := StreamLoader{}
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seq, err := loader.GenerateUUIDs(1000, 42)
+	if err != nil {
+		t.Fatalf("GenerateUUIDs() error = %v", err)
+	}
+
+	seen := make(map[interface{}]bool)
+	for _, v := range seq.ToArray() {
+		if !uuidPattern.MatchString(v.(string)) {
+			t.Fatalf("Invalid UUID: %v", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 1000 {
+		t.Errorf("Expected 1000 unique UUIDs, got %d", len(seen))
+	}
+
+	// Same seed yields the same keyspace, a different seed does not
+	again, _ := loader.GenerateUUIDs(1000, 42)
+	other, _ := loader.GenerateUUIDs(1000, 7)
+	a, _ := seq.At(123)
+	b, _ := again.At(123)
+	c, _ := other.At(123)
+	if a != b {
+		t.Errorf("Expected identical UUIDs for the same seed, got %v and %v", a, b)
+	}
+	if a == c {
+		t.Error("Expected different UUIDs for different seeds")
+	}
+
+	if _, err := loader.GenerateUUIDs(-1, 0); err == nil {
+		t.Error("Expected error for negative count")
+	}
+}
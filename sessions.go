@@ -0,0 +1,207 @@
+// sessions.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionGroupOptions configures GroupIntoSessions
+type SessionGroupOptions struct {
+	SessionField   string  `json:"sessionField" js:"sessionField"`
+	TimestampField string  `json:"timestampField" js:"timestampField"`
+	MaxGapSeconds  float64 `json:"maxGapSeconds" js:"maxGapSeconds"`
+	OutputPattern  string  `json:"outputPattern" js:"outputPattern"`
+}
+
+// RecordedSession is one user journey found by GroupIntoSessions
+type RecordedSession struct {
+	ID      string        `json:"id" js:"id"`   // The session value, with "#2", "#3"... for the later sessions a gap split off
+	Key     string        `json:"key" js:"key"` // The session value
+	Start   string        `json:"start" js:"start"`
+	End     string        `json:"end" js:"end"`
+	Count   int           `json:"count" js:"count"`
+	Records []interface{} `json:"records,omitempty" js:"records"` // Unless written to File
+	File    string        `json:"file,omitempty" js:"file"`
+}
+
+// SessionGroups is the result of GroupIntoSessions
+type SessionGroups struct {
+	Records  int               `json:"records" js:"records"`
+	Skipped  int               `json:"skipped" js:"skipped"` // Records without a session
+	Sessions []RecordedSession `json:"sessions" js:"sessions"`
+}
+
+// sessionRecord is a record of a session with its parsed timestamp.
+type sessionRecord struct {
+	at     time.Time
+	record interface{}
+}
+
+// GroupIntoSessions splits a corpus of requests into sessions, the ordered requests of one user,
+// so user-journey scenarios can replay whole sessions instead of individual requests. Records
+// are grouped by their session field and ordered by their timestamp, keeping the file order of
+// records with the same timestamp. With maxGapSeconds, a pause longer than the gap between two
+// requests of a session starts a new session, as analytics tools do with idle timeouts.
+//
+// Records are read from a JSON array, NDJSON or CSV file (with a header row) and held in memory
+// until the end of the file, since a session's requests may be anywhere in it; with
+// outputPattern the sessions are then written to their own files and not returned.
+//
+// Options:
+//   - sessionField: Field (or dotted path) naming the session of a record; records without it
+//     are skipped (default: "sessionId")
+//   - timestampField: Field holding the time of a record, as an RFC 3339 string or a Unix time in
+//     seconds or milliseconds; every record of a session needs it (default: "timestamp")
+//   - maxGapSeconds: Longest pause within a session, 0 for no limit (default: 0)
+//   - outputPattern: Write each session to its own JSON array file, named by replacing {key}
+//     in the pattern with the session ID made safe for file names
+//
+// Returns: The number of records read and skipped, and the sessions in order of their first
+// request, each with its id, session key, start and end time (RFC 3339), number of records, and
+// records or file
+//
+// Example usage:
+//
+//	const { sessions } = streamloader.groupIntoSessions("traffic.json", { sessionField: "user", maxGapSeconds: 1800 });
+//	export default function () {
+//		const session = sessions[exec.scenario.iterationInTest % sessions.length];
+//		for (const request of session.records) {
+//			http.request(request.method, request.url);
+//		}
+//	}
+func (s StreamLoader) GroupIntoSessions(filePath string, options ...SessionGroupOptions) (*SessionGroups, error) {
+	var opts SessionGroupOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.SessionField == "" {
+		opts.SessionField = "sessionId"
+	}
+	if opts.TimestampField == "" {
+		opts.TimestampField = "timestamp"
+	}
+	if opts.MaxGapSeconds < 0 {
+		return nil, fmt.Errorf("maxGapSeconds must not be negative, got %v", opts.MaxGapSeconds)
+	}
+	if opts.OutputPattern != "" && !strings.Contains(opts.OutputPattern, "{key}") {
+		return nil, fmt.Errorf("invalid outputPattern %q: must contain {key}", opts.OutputPattern)
+	}
+
+	result := &SessionGroups{Sessions: []RecordedSession{}}
+	var keys []string
+	groups := make(map[string][]sessionRecord)
+	open := func(filePath string) (*datasetRecords, error) {
+		return openDatasetRecords(filePath, false, nil, s.call)
+	}
+	err := forEachDatasetRecord(open, filePath, func(index int, record interface{}) error {
+		result.Records++
+		session, found := lookupField(record, opts.SessionField)
+		if !found || session == nil {
+			result.Skipped++
+			return nil
+		}
+		value, found := lookupField(record, opts.TimestampField)
+		if !found || value == nil {
+			return fmt.Errorf("record %d has no %q field", index, opts.TimestampField)
+		}
+		at, err := parseTimestamp(value)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", index, err)
+		}
+		key := keyString(session)
+		if _, seen := groups[key]; !seen {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], sessionRecord{at: at, record: record})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Order each session and split it at gaps
+	maxGap := time.Duration(opts.MaxGapSeconds * float64(time.Second))
+	var sessions [][]sessionRecord
+	var ids, sessionKeys []string
+	for _, key := range keys {
+		records := groups[key]
+		sort.SliceStable(records, func(i, j int) bool { return records[i].at.Before(records[j].at) })
+		start, n := 0, 0
+		for i := 1; i <= len(records); i++ {
+			if i < len(records) && (maxGap == 0 || records[i].at.Sub(records[i-1].at) <= maxGap) {
+				continue
+			}
+			id := key
+			if n++; n > 1 {
+				id = key + "#" + strconv.Itoa(n)
+			}
+			sessions = append(sessions, records[start:i])
+			ids = append(ids, id)
+			sessionKeys = append(sessionKeys, key)
+			start = i
+		}
+		delete(groups, key)
+	}
+	order := make([]int, len(sessions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sessions[order[a]][0].at.Before(sessions[order[b]][0].at)
+	})
+
+	paths := make(map[string]string) // Output path -> session ID, to detect IDs mapping to the same file
+	for _, i := range order {
+		records := sessions[i]
+		session := RecordedSession{
+			ID:    ids[i],
+			Key:   sessionKeys[i],
+			Start: records[0].at.UTC().Format(time.RFC3339Nano),
+			End:   records[len(records)-1].at.UTC().Format(time.RFC3339Nano),
+			Count: len(records),
+		}
+		if opts.OutputPattern == "" {
+			session.Records = make([]interface{}, len(records))
+			for j, r := range records {
+				session.Records[j] = r.record
+			}
+			result.Sessions = append(result.Sessions, session)
+			continue
+		}
+
+		session.File = strings.ReplaceAll(opts.OutputPattern, "{key}", groupFileName(session.ID))
+		if other, exists := paths[session.File]; exists {
+			return nil, fmt.Errorf("sessions %q and %q both map to output file %s", other, session.ID, session.File)
+		}
+		paths[session.File] = session.ID
+		if err := writeSessionFile(session.File, records); err != nil {
+			return nil, err
+		}
+		result.Sessions = append(result.Sessions, session)
+	}
+	return result, nil
+}
+
+// writeSessionFile writes the records of a session as a JSON array file.
+func writeSessionFile(filePath string, records []sessionRecord) error {
+	out, err := createJsonArrayFile(filePath, writeBufferSize())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, r := range records {
+		encoded, err := json.Marshal(r.record)
+		if err != nil {
+			return fmt.Errorf("failed to encode session record: %w", err)
+		}
+		if err := out.Write(encoded); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
@@ -0,0 +1,102 @@
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupIntoSessions(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "traffic.ndjson")
+	os.WriteFile(corpus, []byte(strings.Join([]string{
+		`{"sessionId":"a","timestamp":"2025-01-01T10:00:05Z","url":"/cart"}`,
+		`{"sessionId":"b","timestamp":1735725601,"url":"/"}`,
+		`{"sessionId":"a","timestamp":"2025-01-01T10:00:00Z","url":"/"}`,
+		`{"url":"/health"}`,
+		`{"sessionId":"a","timestamp":1735729200000,"url":"/"}`,
+		`{"sessionId":"b","timestamp":1735725601,"url":"/search"}`,
+	}, "\n")), 0644)
+
+	urls := func(records []interface{}) []string {
+		var list []string
+		for _, r := range records {
+			list = append(list, r.(map[string]interface{})["url"].(string))
+		}
+		return list
+	}
+
+	result, err := loader.GroupIntoSessions(corpus)
+	if err != nil {
+		t.Fatalf("GroupIntoSessions() error = %v", err)
+	}
+	if result.Records != 6 || result.Skipped != 1 || len(result.Sessions) != 2 {
+		t.Fatalf("GroupIntoSessions() = %+v", result)
+	}
+	a, b := result.Sessions[0], result.Sessions[1]
+	if a.ID != "a" || a.Count != 3 || a.Start != "2025-01-01T10:00:00Z" || a.End != "2025-01-01T11:00:00Z" {
+		t.Errorf("session a = %+v", a)
+	}
+	if got := urls(a.Records); !reflect.DeepEqual(got, []string{"/", "/cart", "/"}) {
+		t.Errorf("session a urls = %v", got)
+	}
+	// Records with the same timestamp keep their file order
+	if got := urls(b.Records); b.ID != "b" || !reflect.DeepEqual(got, []string{"/", "/search"}) {
+		t.Errorf("session b = %+v", b)
+	}
+
+	// A gap splits a session
+	result, err = loader.GroupIntoSessions(corpus, SessionGroupOptions{MaxGapSeconds: 1800})
+	if err != nil {
+		t.Fatalf("GroupIntoSessions(maxGapSeconds) error = %v", err)
+	}
+	var ids []string
+	for _, s := range result.Sessions {
+		ids = append(ids, s.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b", "a#2"}) || result.Sessions[2].Key != "a" || result.Sessions[2].Count != 1 {
+		t.Errorf("GroupIntoSessions(maxGapSeconds) = %+v", result.Sessions)
+	}
+
+	// Files, from a CSV corpus with other fields
+	csv := filepath.Join(dir, "traffic.csv")
+	os.WriteFile(csv, []byte("user,time,url\nu/1,20,/b\nu/1,10,/a\nu2,15,/c\n"), 0644)
+	result, err = loader.GroupIntoSessions(csv, SessionGroupOptions{SessionField: "user", TimestampField: "time", OutputPattern: filepath.Join(dir, "session-{key}.json")})
+	if err != nil {
+		t.Fatalf("GroupIntoSessions(outputPattern) error = %v", err)
+	}
+	first := result.Sessions[0]
+	if len(result.Sessions) != 2 || first.ID != "u/1" || first.Records != nil || first.File != filepath.Join(dir, "session-u_1.json") {
+		t.Fatalf("GroupIntoSessions(outputPattern) = %+v", result.Sessions)
+	}
+	var written []map[string]string
+	data, _ := os.ReadFile(first.File)
+	if err := json.Unmarshal(data, &written); err != nil || len(written) != 2 || written[0]["url"] != "/a" || written[1]["url"] != "/b" {
+		t.Errorf("session file = %s (%v)", data, err)
+	}
+
+	// Errors
+	noTime := filepath.Join(dir, "no-time.json")
+	os.WriteFile(noTime, []byte(`[{"sessionId":"a"}]`), 0644)
+	badTime := filepath.Join(dir, "bad-time.json")
+	os.WriteFile(badTime, []byte(`[{"sessionId":"a","timestamp":"yesterday"}]`), 0644)
+	invalid := []struct {
+		file    string
+		options SessionGroupOptions
+	}{
+		{noTime, SessionGroupOptions{}},
+		{badTime, SessionGroupOptions{}},
+		{corpus, SessionGroupOptions{MaxGapSeconds: -1}},
+		{corpus, SessionGroupOptions{OutputPattern: filepath.Join(dir, "sessions.json")}},
+		{filepath.Join(dir, "missing.json"), SessionGroupOptions{}},
+	}
+	for i, tt := range invalid {
+		if _, err := loader.GroupIntoSessions(tt.file, tt.options); err == nil {
+			t.Errorf("case %d: GroupIntoSessions() expected an error", i)
+		}
+	}
+}
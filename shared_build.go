@@ -0,0 +1,94 @@
+// shared_build.go
+package streamloader
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sharedBuild is a structure built once per process from a file and shared by every VU.
+type sharedBuild struct {
+	value   any
+	err     error
+	loading chan struct{} // Closed when the build finishes
+}
+
+// sharedBuilds holds the structures built so far by buildShared, keyed by kind, file and
+// parameters.
+var sharedBuilds = struct {
+	mu     sync.Mutex
+	builds map[string]*sharedBuild
+}{builds: make(map[string]*sharedBuild)}
+
+// buildShared returns the structure for key, calling build if no VU has built it yet. Concurrent
+// callers wait for the first one's build. Failed builds aren't kept, so the next call retries,
+// for example once the file exists.
+func buildShared[T any](key string, build func() (T, error)) (T, error) {
+	sharedBuilds.mu.Lock()
+	b, ok := sharedBuilds.builds[key]
+	if !ok {
+		b = &sharedBuild{loading: make(chan struct{})}
+		sharedBuilds.builds[key] = b
+	}
+	sharedBuilds.mu.Unlock()
+
+	if ok {
+		<-b.loading
+	} else {
+		b.value, b.err = build()
+		if b.err != nil {
+			sharedBuilds.mu.Lock()
+			delete(sharedBuilds.builds, key)
+			sharedBuilds.mu.Unlock()
+		}
+		close(b.loading)
+	}
+	if b.err != nil {
+		var zero T
+		return zero, b.err
+	}
+	return b.value.(T), nil
+}
+
+// sharedBuildKey returns the key of a structure of the given kind built from a file.
+func sharedBuildKey(kind string, filePath string, params ...string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	key := kind + "\x00" + filePath
+	for _, p := range params {
+		key += "\x00" + p
+	}
+	return key
+}
+
+// releaseShared drops the finished structures whose keys match, so the next VU to ask for one
+// builds it again, and returns how many were dropped. Handles already holding a structure keep
+// it until they are closed. Builds in progress are left alone.
+func releaseShared(match func(key string) bool) int {
+	sharedBuilds.mu.Lock()
+	defer sharedBuilds.mu.Unlock()
+	released := 0
+	for key, b := range sharedBuilds.builds {
+		select {
+		case <-b.loading:
+		default:
+			continue
+		}
+		if match(key) {
+			delete(sharedBuilds.builds, key)
+			released++
+		}
+	}
+	return released
+}
+
+// sharedBuildFile returns the file a structure was built from.
+func sharedBuildFile(key string) string {
+	parts := strings.SplitN(key, "\x00", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
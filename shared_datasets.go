@@ -0,0 +1,383 @@
+// shared_datasets.go
+package streamloader
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DatasetOptions represents options for RegisterDataset
+type DatasetOptions struct {
+	Pinned    bool   `json:"pinned" js:"pinned"`
+	PageCache string `json:"pageCache" js:"pageCache"`
+}
+
+// ReloadDatasetOptions represents options for ReloadDataset
+type ReloadDatasetOptions struct {
+	IfChanged bool `json:"ifChanged" js:"ifChanged"`
+}
+
+// DatasetInfo describes a registered dataset, as returned by GetDatasetInfo
+type DatasetInfo struct {
+	Name       string  `json:"name" js:"name"`
+	Path       string  `json:"path" js:"path"`
+	Loaded     bool    `json:"loaded" js:"loaded"`
+	Pinned     bool    `json:"pinned" js:"pinned"`
+	SizeBytes  int64   `json:"sizeBytes" js:"sizeBytes"`
+	Loads      int     `json:"loads" js:"loads"`
+	LastUsedMs float64 `json:"lastUsedMs" js:"lastUsedMs"` // Time since last use, -1 if never used
+}
+
+// sharedDataset is a registered dataset. Its data is loaded on first use and may be evicted
+// and reloaded later unless it is pinned.
+type sharedDataset struct {
+	name      string
+	path      string
+	opts      JsonOptions
+	pinned    bool
+	data      any
+	size      int64         // Size of the file when it was loaded, 0 while not loaded
+	modTime   time.Time     // Modification time of the file when it was loaded
+	loads     int           // Number of times the file was loaded
+	lastUsed  time.Time     // Zero until first use
+	elem      *list.Element // Position in the LRU list while loaded
+	loading   chan struct{} // Closed when a load in progress finishes
+	reloading chan struct{} // Closed when a reload in progress finishes
+}
+
+// datasets is the registry of shared datasets, shared by every VU in the k6 process. Loaded
+// datasets are kept in least recently used order; when their total size exceeds the limit, the
+// least recently used datasets that aren't pinned are dropped.
+var datasets = struct {
+	mu          sync.Mutex
+	byName      map[string]*sharedDataset
+	lru         *list.List // Loaded datasets, most recently used first
+	loadedBytes int64
+	limit       int64 // 0 for no limit
+}{byName: make(map[string]*sharedDataset), lru: list.New()}
+
+// RegisterDataset registers a JSON file as a dataset shared by every VU in the process under the
+// given name. Nothing is read until the dataset is first used with GetDataset, so registering
+// many corpora is cheap. Since the init context runs once per VU, registering the same name and
+// path again is a no-op; registering the name with another path fails.
+//
+// Options:
+//   - pinned: Never evict the dataset once loaded (default: false)
+//   - pageCache: The pageCache option of LoadJSON used when loading (default: "keep")
+//
+// Example usage:
+//
+//	streamloader.RegisterDataset("users", "users.json", DatasetOptions{Pinned: true})
+//	streamloader.RegisterDataset("archive", "archive.json")
+func (StreamLoader) RegisterDataset(name string, filePath string, options ...DatasetOptions) error {
+	if name == "" {
+		return fmt.Errorf("dataset name must not be empty")
+	}
+	var opts DatasetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	switch opts.PageCache {
+	case "", pageCacheKeep, pageCacheDrop, pageCacheDirect:
+	default:
+		return fmt.Errorf("invalid pageCache option %q: expected keep, drop or direct", opts.PageCache)
+	}
+
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	if d, ok := datasets.byName[name]; ok {
+		if d.path != filePath {
+			return fmt.Errorf("dataset %q is already registered for %s", name, d.path)
+		}
+		d.pinned = d.pinned || opts.Pinned
+		return nil
+	}
+	datasets.byName[name] = &sharedDataset{
+		name:   name,
+		path:   filePath,
+		opts:   JsonOptions{PageCache: opts.PageCache},
+		pinned: opts.Pinned,
+	}
+	return nil
+}
+
+// GetDataset returns the data of a registered dataset, loading it with LoadJSON if it isn't in
+// memory, for example because it was evicted. The data is shared by every VU without being
+// copied, so scripts get a read-only view of it: assigning to or deleting its properties and
+// elements throws a TypeError. Loading a dataset may evict the least recently used datasets that
+// aren't pinned to stay within the limit set with SetDatasetMemoryLimit.
+//
+// Example usage:
+//
+//	const users = streamloader.GetDataset("users");
+//	const user = { ...users[0], visited: true }; // Copy a record to change it
+func (s StreamLoader) GetDataset(name string) (any, error) {
+	data, err := getDataset(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.sharedView(data), nil
+}
+
+// getDataset returns the shared data of a registered dataset, loading it if needed.
+func getDataset(name string) (any, error) {
+	datasets.mu.Lock()
+	d, ok := datasets.byName[name]
+	if !ok {
+		datasets.mu.Unlock()
+		return nil, fmt.Errorf("dataset %q is not registered", name)
+	}
+	for d.loading != nil || (d.elem == nil && d.reloading != nil) {
+		// Another VU is loading the dataset, or reloading it after it was evicted, wait for it.
+		// A dataset that is still loaded keeps serving its old data during a reload.
+		wait := d.loading
+		if wait == nil {
+			wait = d.reloading
+		}
+		datasets.mu.Unlock()
+		<-wait
+		datasets.mu.Lock()
+	}
+	d.lastUsed = time.Now()
+	if d.elem != nil {
+		datasets.lru.MoveToFront(d.elem)
+		data := d.data
+		datasets.mu.Unlock()
+		return data, nil
+	}
+	d.loading = make(chan struct{})
+	datasets.mu.Unlock()
+
+	data, info, err := loadDataset(d.path, d.opts)
+
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	close(d.loading)
+	d.loading = nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dataset %q: %w", name, err)
+	}
+	d.data, d.size, d.modTime = data, info.Size(), info.ModTime()
+	d.loads++
+	d.elem = datasets.lru.PushFront(d)
+	datasets.loadedBytes += d.size
+	evictDatasets(d)
+	return data, nil
+}
+
+// isDatasetData reports whether records is the data of a loaded dataset, such as a dataset passed
+// back to iterate.
+func isDatasetData(records []interface{}) bool {
+	if len(records) == 0 {
+		return false
+	}
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	for e := datasets.lru.Front(); e != nil; e = e.Next() {
+		if data, ok := e.Value.(*sharedDataset).data.([]interface{}); ok && len(data) > 0 && &data[0] == &records[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDataset loads the file of a dataset and returns its data and the file's size and
+// modification time.
+func loadDataset(path string, opts JsonOptions) (any, os.FileInfo, error) {
+	info, err := statInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := StreamLoader{}.LoadJSON(path, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
+}
+
+// ReloadDataset reads the file of a loaded dataset again and swaps the new data in at once, so
+// data and configuration can be refreshed during long-running tests. Until the swap, GetDataset
+// keeps returning the old data, which stays valid for the VUs holding it; afterwards it returns
+// the new data. If the file can't be loaded, the old data is kept and an error is returned.
+// A dataset that isn't loaded is left alone, since its next use loads the current file anyway.
+//
+// Options:
+//   - ifChanged: Only reload if the file's size or modification time changed since it was
+//     loaded, so a scenario can check for updates cheaply and often (default: false)
+//
+// Returns: Whether the data was reloaded
+//
+// Example usage:
+//
+//	// In a scenario running every minute:
+//	streamloader.reloadDataset("feature-flags", { ifChanged: true });
+func (StreamLoader) ReloadDataset(name string, options ...ReloadDatasetOptions) (bool, error) {
+	var opts ReloadDatasetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	datasets.mu.Lock()
+	d, ok := datasets.byName[name]
+	if !ok {
+		datasets.mu.Unlock()
+		return false, fmt.Errorf("dataset %q is not registered", name)
+	}
+	for d.loading != nil || d.reloading != nil {
+		// Wait for the load or reload in progress
+		wait := d.loading
+		if wait == nil {
+			wait = d.reloading
+		}
+		datasets.mu.Unlock()
+		<-wait
+		datasets.mu.Lock()
+	}
+	if d.elem == nil {
+		datasets.mu.Unlock()
+		return false, nil
+	}
+	if opts.IfChanged {
+		info, err := statInput(d.path)
+		if err != nil {
+			datasets.mu.Unlock()
+			return false, fmt.Errorf("failed to reload dataset %q: %w", name, err)
+		}
+		if info.Size() == d.size && info.ModTime().Equal(d.modTime) {
+			datasets.mu.Unlock()
+			return false, nil
+		}
+	}
+	d.reloading = make(chan struct{})
+	datasets.mu.Unlock()
+
+	data, info, err := loadDataset(d.path, d.opts)
+
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	close(d.reloading)
+	d.reloading = nil
+	if err != nil {
+		return false, fmt.Errorf("failed to reload dataset %q: %w", name, err)
+	}
+	if d.elem != nil {
+		datasets.loadedBytes -= d.size
+		datasets.lru.MoveToFront(d.elem)
+	} else {
+		// Evicted or released while reloading
+		d.elem = datasets.lru.PushFront(d)
+	}
+	d.data, d.size, d.modTime = data, info.Size(), info.ModTime()
+	d.loads++
+	datasets.loadedBytes += d.size
+	evictDatasets(d)
+	return true, nil
+}
+
+// evictDatasets drops the least recently used datasets that aren't pinned until the loaded
+// datasets fit within the limit. keep, the dataset just used, is never dropped. The caller must
+// hold the lock.
+func evictDatasets(keep *sharedDataset) {
+	if datasets.limit <= 0 {
+		return
+	}
+	for e := datasets.lru.Back(); e != nil && datasets.loadedBytes > datasets.limit; {
+		d := e.Value.(*sharedDataset)
+		e = e.Prev()
+		if d == keep || d.pinned {
+			continue
+		}
+		unloadDataset(d)
+	}
+}
+
+// unloadDataset drops the data of a loaded dataset. The caller must hold the lock.
+func unloadDataset(d *sharedDataset) {
+	datasets.lru.Remove(d.elem)
+	datasets.loadedBytes -= d.size
+	d.elem, d.data, d.size = nil, nil, 0
+}
+
+// SetDatasetMemoryLimit limits the total size of the loaded shared datasets, measured by the
+// size of their files, evicting least recently used datasets that aren't pinned right away if
+// needed. Evicted datasets are reloaded from their files on next use. A limit of 0 removes
+// the limit. Pinned datasets and the dataset in use can exceed the limit on their own.
+//
+// Example usage:
+//
+//	streamloader.SetDatasetMemoryLimit(2 * 1024 * 1024 * 1024);
+func (StreamLoader) SetDatasetMemoryLimit(limitBytes int64) error {
+	if limitBytes < 0 {
+		return fmt.Errorf("dataset memory limit must not be negative, got %d", limitBytes)
+	}
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	datasets.limit = limitBytes
+	evictDatasets(nil)
+	return nil
+}
+
+// PinDataset keeps a registered dataset in memory once it is loaded, regardless of the limit.
+func (StreamLoader) PinDataset(name string) error {
+	return setDatasetPinned(name, true)
+}
+
+// UnpinDataset lets a registered dataset be evicted again. If the loaded datasets exceed the
+// limit, it may be evicted right away.
+func (StreamLoader) UnpinDataset(name string) error {
+	return setDatasetPinned(name, false)
+}
+
+func setDatasetPinned(name string, pinned bool) error {
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	d, ok := datasets.byName[name]
+	if !ok {
+		return fmt.Errorf("dataset %q is not registered", name)
+	}
+	d.pinned = pinned
+	if !pinned {
+		evictDatasets(nil)
+	}
+	return nil
+}
+
+// GetDatasetInfo describes every registered dataset, sorted by name.
+//
+// Example usage:
+//
+//	streamloader.GetDatasetInfo().filter((d) => d.loaded).forEach((d) => console.log(d.name, d.loads));
+func (StreamLoader) GetDatasetInfo() []DatasetInfo {
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	infos := make([]DatasetInfo, 0, len(datasets.byName))
+	for _, d := range datasets.byName {
+		info := DatasetInfo{
+			Name:       d.name,
+			Path:       d.path,
+			Loaded:     d.elem != nil,
+			Pinned:     d.pinned,
+			SizeBytes:  d.size,
+			Loads:      d.loads,
+			LastUsedMs: -1,
+		}
+		if !d.lastUsed.IsZero() {
+			info.LastUsedMs = durationMs(time.Since(d.lastUsed))
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// loadedDatasets returns the number and total size of the loaded shared datasets.
+func loadedDatasets() (int, int64) {
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	return datasets.lru.Len(), datasets.loadedBytes
+}
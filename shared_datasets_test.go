@@ -0,0 +1,306 @@
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/js/common"
+)
+
+// resetDatasets clears the shared dataset registry.
+func resetDatasets(t *testing.T) {
+	t.Helper()
+	clear := func() {
+		datasets.mu.Lock()
+		defer datasets.mu.Unlock()
+		datasets.byName = make(map[string]*sharedDataset)
+		datasets.lru.Init()
+		datasets.loadedBytes = 0
+		datasets.limit = 0
+	}
+	clear()
+	t.Cleanup(clear)
+}
+
+// writeDataset writes a JSON array of n records and returns its path and size.
+func writeDataset(t *testing.T, dir string, name string, n int) (string, int64) {
+	t.Helper()
+	records := make([]string, n)
+	for i := range records {
+		records[i] = fmt.Sprintf(`{"id":%d}`, i)
+	}
+	path := filepath.Join(dir, name+".json")
+	content := "[" + strings.Join(records, ",") + "]"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, int64(len(content))
+}
+
+func datasetInfo(t *testing.T, name string) DatasetInfo {
+	t.Helper()
+	for _, info := range (StreamLoader{}).GetDatasetInfo() {
+		if info.Name == name {
+			return info
+		}
+	}
+	t.Fatalf("dataset %q not found", name)
+	return DatasetInfo{}
+}
+
+func TestRegisterDataset(t *testing.T) {
+	resetDatasets(t)
+	loader := StreamLoader{}
+	path, _ := writeDataset(t, t.TempDir(), "a", 3)
+
+	if err := loader.RegisterDataset("a", path); err != nil {
+		t.Fatal(err)
+	}
+	// Every VU registers again in its init context
+	if err := loader.RegisterDataset("a", path); err != nil {
+		t.Errorf("registering again error = %v", err)
+	}
+	if err := loader.RegisterDataset("a", path+".other"); err == nil {
+		t.Error("registering the name with another path should fail")
+	}
+	if err := loader.RegisterDataset("", path); err == nil {
+		t.Error("empty name should fail")
+	}
+	if err := loader.RegisterDataset("b", path, DatasetOptions{PageCache: "bypass"}); err == nil {
+		t.Error("invalid pageCache should fail")
+	}
+	if _, err := loader.GetDataset("missing"); err == nil {
+		t.Error("unregistered dataset should fail")
+	}
+	if info := datasetInfo(t, "a"); info.Loaded || info.Loads != 0 || info.LastUsedMs != -1 {
+		t.Errorf("dataset loaded before use: %+v", info)
+	}
+
+	data, err := loader.GetDataset("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records, ok := data.([]any); !ok || len(records) != 3 {
+		t.Errorf("GetDataset() = %v", data)
+	}
+	if _, err := loader.GetDataset("a"); err != nil {
+		t.Fatal(err)
+	}
+	if info := datasetInfo(t, "a"); !info.Loaded || info.Loads != 1 {
+		t.Errorf("dataset info after two uses = %+v, want loaded once", info)
+	}
+}
+
+func TestDatasetEviction(t *testing.T) {
+	resetDatasets(t)
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	var size int64
+	for _, name := range []string{"a", "b", "c", "pinned"} {
+		path, n := writeDataset(t, dir, name, 10)
+		size = n
+		if err := loader.RegisterDataset(name, path, DatasetOptions{Pinned: name == "pinned"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Room for two datasets besides the pinned one
+	if err := loader.SetDatasetMemoryLimit(3 * size); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pinned", "a", "b", "a", "c"} {
+		if _, err := loader.GetDataset(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, loaded := range map[string]bool{"pinned": true, "a": true, "b": false, "c": true} {
+		if info := datasetInfo(t, name); info.Loaded != loaded {
+			t.Errorf("dataset %q loaded = %v, want %v", name, info.Loaded, loaded)
+		}
+	}
+	if n, bytes := loadedDatasets(); n != 3 || bytes != 3*size {
+		t.Errorf("loadedDatasets() = %d, %d, want 3, %d", n, bytes, 3*size)
+	}
+
+	// An evicted dataset is reloaded on next use
+	data, err := loader.GetDataset("b")
+	if err != nil || len(data.([]any)) != 10 {
+		t.Fatalf("reloading evicted dataset = %v, %v", data, err)
+	}
+	if info := datasetInfo(t, "b"); info.Loads != 2 {
+		t.Errorf("dataset b loads = %d, want 2", info.Loads)
+	}
+	if datasetInfo(t, "a").Loaded {
+		t.Error("least recently used dataset a should be evicted")
+	}
+
+	// Unpinning and lowering the limit evict right away
+	if err := loader.UnpinDataset("pinned"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.SetDatasetMemoryLimit(size); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := loadedDatasets(); n != 1 {
+		t.Errorf("loaded datasets after lowering the limit = %d, want 1", n)
+	}
+	if err := loader.PinDataset("missing"); err == nil {
+		t.Error("pinning an unregistered dataset should fail")
+	}
+	if err := loader.SetDatasetMemoryLimit(-1); err == nil {
+		t.Error("negative limit should fail")
+	}
+}
+
+func TestDatasetConcurrentLoad(t *testing.T) {
+	resetDatasets(t)
+	loader := StreamLoader{}
+	path, _ := writeDataset(t, t.TempDir(), "a", 1000)
+	if err := loader.RegisterDataset("a", path); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := loader.GetDataset("a"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if info := datasetInfo(t, "a"); info.Loads != 1 {
+		t.Errorf("concurrent first use loaded the dataset %d times, want 1", info.Loads)
+	}
+}
+
+func TestReloadDataset(t *testing.T) {
+	resetDatasets(t)
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	path, _ := writeDataset(t, dir, "flags", 2)
+	if err := loader.RegisterDataset("flags", path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dataset that isn't loaded is left alone
+	if reloaded, err := loader.ReloadDataset("flags"); err != nil || reloaded {
+		t.Errorf("ReloadDataset(unloaded) = %v, %v, want false", reloaded, err)
+	}
+	old, err := loader.GetDataset("flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := loader.ReloadDataset("flags", ReloadDatasetOptions{IfChanged: true}); err != nil || reloaded {
+		t.Errorf("ReloadDataset(unchanged, ifChanged) = %v, %v, want false", reloaded, err)
+	}
+
+	_, newSize := writeDataset(t, dir, "flags", 5)
+	if reloaded, err := loader.ReloadDataset("flags", ReloadDatasetOptions{IfChanged: true}); err != nil || !reloaded {
+		t.Fatalf("ReloadDataset(changed) = %v, %v, want true", reloaded, err)
+	}
+	data, _ := loader.GetDataset("flags")
+	if len(data.([]interface{})) != 5 || len(old.([]interface{})) != 2 {
+		t.Errorf("data after reload = %d records, old data = %d, want 5 and 2", len(data.([]interface{})), len(old.([]interface{})))
+	}
+	if info := datasetInfo(t, "flags"); info.Loads != 2 || info.SizeBytes != newSize {
+		t.Errorf("info after reload = %+v", info)
+	}
+	if count, bytes := loadedDatasets(); count != 1 || bytes != newSize {
+		t.Errorf("loadedDatasets() = %d, %d, want 1, %d", count, bytes, newSize)
+	}
+	if reloaded, err := loader.ReloadDataset("flags"); err != nil || !reloaded {
+		t.Errorf("ReloadDataset(unchanged) = %v, %v, want true", reloaded, err)
+	}
+
+	// Readers keep getting data while reloads run
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if data, err := loader.GetDataset("flags"); err != nil || data == nil {
+					t.Errorf("GetDataset() during reload = %v, %v", data, err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := loader.ReloadDataset("flags"); err != nil {
+				t.Errorf("ReloadDataset() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A failed reload keeps the old data
+	os.WriteFile(path, []byte(`[{"id":`), 0644)
+	if _, err := loader.ReloadDataset("flags"); err == nil {
+		t.Error("ReloadDataset(invalid file) expected an error")
+	}
+	if data, err := loader.GetDataset("flags"); err != nil || len(data.([]interface{})) != 5 {
+		t.Errorf("GetDataset() after a failed reload = %v, %v", data, err)
+	}
+	if _, err := loader.ReloadDataset("missing"); err == nil {
+		t.Error("ReloadDataset(unregistered) expected an error")
+	}
+}
+
+func TestGetDatasetReadOnlyInScripts(t *testing.T) {
+	resetDatasets(t)
+	path := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(path, []byte(`[{"id":1,"name":"a","tags":["x"]},{"id":2,"name":"b","tags":[]}]`), 0644)
+	if err := (StreamLoader{}).RegisterDataset("users", path); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := sobek.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loader := StreamLoader{vu: &iterationVU{runtime: rt}}
+	if err := rt.Set("streamloader", exportsWithCallCounts(&loader)); err != nil {
+		t.Fatal(err)
+	}
+	value, err := rt.RunString(`
+		const users = streamloader.getDataset("users");
+		[Array.isArray(users), users.length, users[1].name, users[0].tags[0], JSON.stringify(users[0]), JSON.stringify({ ...users[1], visited: true })].join(" ");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := value.String(); got != `true 2 b x {"id":1,"name":"a","tags":["x"]} {"id":2,"name":"b","tags":[],"visited":true}` {
+		t.Errorf("script result = %s", got)
+	}
+
+	writes := []string{
+		`users[0].name = "changed"`,
+		`delete users[0].id`,
+		`users[0].tags.push("y")`,
+		`users[2] = {}`,
+		`users.length = 0`,
+		`streamloader.pickRandom("users", 1, 0).id = 3`,
+		`streamloader.pickRandom(users, 1, 0).id = 3`,
+		`streamloader.iterate(users).next().name = "changed"`,
+		`streamloader.iterate(users).filter({ field: "id", op: "eq", value: 2 }).toArray()[0].name = "changed"`,
+		`streamloader.iterate(users).map((u) => u.tags).next().push("y")`,
+	}
+	for _, write := range writes {
+		if _, err := rt.RunString(`"use strict"; ` + write); err == nil || !strings.Contains(err.Error(), "TypeError") {
+			t.Errorf("%s: error = %v, want a TypeError", write, err)
+		}
+	}
+
+	data, _ := StreamLoader{}.GetDataset("users")
+	records := data.([]interface{})
+	if len(records) != 2 || records[0].(map[string]interface{})["name"] != "a" || len(records[0].(map[string]interface{})["tags"].([]interface{})) != 1 {
+		t.Errorf("shared data was changed: %v", data)
+	}
+}
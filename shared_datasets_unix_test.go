@@ -0,0 +1,92 @@
+//go:build linux || darwin || freebsd
+
+package streamloader
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGetDatasetEvictedDuringReload(t *testing.T) {
+	resetDatasets(t)
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	pathA, _ := writeDataset(t, dir, "a", 3)
+	pathB, sizeB := writeDataset(t, dir, "b", 50)
+	loader.RegisterDataset("a", pathA)
+	loader.RegisterDataset("b", pathB)
+	if _, err := loader.GetDataset("a"); err != nil {
+		t.Fatal(err)
+	}
+	loader.SetDatasetMemoryLimit(sizeB)
+
+	// Replace the file with a named pipe, so the reload blocks until it is written
+	os.Remove(pathA)
+	if err := syscall.Mkfifo(pathA, 0600); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+	reloaded := make(chan error, 1)
+	go func() {
+		_, err := loader.ReloadDataset("a")
+		reloaded <- err
+	}()
+	for {
+		datasets.mu.Lock()
+		inFlight := datasets.byName["a"].reloading != nil
+		datasets.mu.Unlock()
+		if inFlight {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Loading b evicts a while it is reloading; getting a must wait for the reload
+	if _, err := loader.GetDataset("b"); err != nil {
+		t.Fatal(err)
+	}
+	if datasetInfo(t, "a").Loaded {
+		t.Fatal("Expected a to be evicted")
+	}
+	got := make(chan any, 1)
+	go func() {
+		data, _ := loader.GetDataset("a")
+		got <- data
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(pathA, []byte(`[{"id":7}]`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("ReloadDataset failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReloadDataset did not finish")
+	}
+	select {
+	case data := <-got:
+		if records, ok := data.([]any); !ok || len(records) != 1 {
+			t.Errorf("GetDataset(a) = %v, want the reloaded record", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetDataset did not finish")
+	}
+
+	// Every loaded dataset is in the LRU list once and counted once
+	datasets.mu.Lock()
+	defer datasets.mu.Unlock()
+	loaded, bytes := 0, int64(0)
+	for _, d := range datasets.byName {
+		if d.elem != nil {
+			loaded++
+			bytes += d.size
+		}
+	}
+	if datasets.lru.Len() != loaded || datasets.loadedBytes != bytes {
+		t.Errorf("LRU has %d datasets and %d bytes, want %d and %d", datasets.lru.Len(), datasets.loadedBytes, loaded, bytes)
+	}
+}
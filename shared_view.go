@@ -0,0 +1,132 @@
+// shared_view.go
+package streamloader
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/grafana/sobek"
+)
+
+// sharedView returns data shared by every VU as this VU's scripts should see it. In a VU, objects
+// and arrays are wrapped in read-only JavaScript views instead of being handed over as the Go
+// maps and slices themselves: scripts read the shared data without copying it, but writing to
+// it, which would race with the other VUs reading the same maps, throws a TypeError in strict
+// mode code such as k6 scripts and is ignored otherwise. Outside a VU the data is returned as it
+// is.
+func (s StreamLoader) sharedView(data any) any {
+	if s.vu == nil {
+		return data
+	}
+	rt := s.vu.Runtime()
+	if rt == nil {
+		return data
+	}
+	return readOnlyValue(rt, data)
+}
+
+// readOnlyValue wraps a decoded JSON value in a read-only view. Nested objects and arrays are
+// wrapped when they are read, so a view costs nothing until it is used.
+func readOnlyValue(rt *sobek.Runtime, value any) sobek.Value {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return rt.NewDynamicObject(readOnlyObject{rt: rt, m: v})
+	case []interface{}:
+		return rt.NewDynamicArray(readOnlyArray{rt: rt, data: v, len: len(v), get: func(i int) any { return v[i] }})
+	case []map[string]interface{}:
+		return rt.NewDynamicArray(readOnlyArray{rt: rt, data: v, len: len(v), get: func(i int) any { return v[i] }})
+	default:
+		return rt.ToValue(value)
+	}
+}
+
+// readOnlyObject is the read-only view of a JSON object.
+type readOnlyObject struct {
+	rt *sobek.Runtime
+	m  map[string]interface{}
+}
+
+func (o readOnlyObject) Get(key string) sobek.Value {
+	value, ok := o.m[key]
+	if !ok {
+		return nil
+	}
+	return readOnlyValue(o.rt, value)
+}
+
+func (o readOnlyObject) Has(key string) bool {
+	_, ok := o.m[key]
+	return ok
+}
+
+func (o readOnlyObject) Set(string, sobek.Value) bool { return false }
+func (o readOnlyObject) Delete(string) bool           { return false }
+
+// Keys returns the keys sorted, since the order of the decoded object is lost.
+func (o readOnlyObject) Keys() []string {
+	keys := make([]string, 0, len(o.m))
+	for key := range o.m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readOnlyArray is the read-only view of a JSON array.
+type readOnlyArray struct {
+	rt   *sobek.Runtime
+	data any // The slice
+	len  int
+	get  func(int) any
+}
+
+func (a readOnlyArray) Len() int { return a.len }
+
+func (a readOnlyArray) Get(i int) sobek.Value {
+	if i < 0 || i >= a.len {
+		return nil
+	}
+	return readOnlyValue(a.rt, a.get(i))
+}
+
+func (a readOnlyArray) Set(int, sobek.Value) bool { return false }
+func (a readOnlyArray) SetLen(int) bool           { return false }
+
+// unwrapShared replaces the read-only views in a value exported from a script, such as a dataset
+// passed back to iterate, with the shared data they wrap, so functions receive the data and not
+// the views. Arrays and objects created by the script are fresh copies and are changed in place;
+// the shared data itself is never changed.
+func unwrapShared(value any) any {
+	switch v := value.(type) {
+	case readOnlyObject:
+		return v.m
+	case readOnlyArray:
+		return v.data
+	case []interface{}:
+		for i, element := range v {
+			v[i] = unwrapShared(element)
+		}
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = unwrapShared(element)
+		}
+	}
+	return value
+}
+
+// unwrapSharedArgs applies unwrapShared to the arguments of a call from a script.
+func unwrapSharedArgs(args []reflect.Value) {
+	for i, arg := range args {
+		switch arg.Kind() {
+		case reflect.Interface:
+			if !arg.IsNil() {
+				value := unwrapShared(arg.Interface())
+				args[i] = reflect.ValueOf(&value).Elem()
+			}
+		case reflect.Slice, reflect.Map:
+			if arg.Type().Elem().Kind() == reflect.Interface {
+				unwrapShared(arg.Interface())
+			}
+		}
+	}
+}
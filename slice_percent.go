@@ -0,0 +1,122 @@
+// slice_percent.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// percentRange returns the half-open index range [start, end) of the records between fromPct
+// and toPct percent of n records. Adjacent ranges, such as 0-10 and 10-80, never overlap and
+// together cover every record.
+func percentRange(n int, fromPct float64, toPct float64) (int, int, error) {
+	if fromPct < 0 || toPct > 100 || fromPct > toPct {
+		return 0, 0, fmt.Errorf("invalid percentage range %v-%v: expected 0 <= from <= to <= 100", fromPct, toPct)
+	}
+	return int(float64(n) * fromPct / 100), int(float64(n) * toPct / 100), nil
+}
+
+// SlicePercent returns the records between fromPct and toPct percent of a dataset, so scenarios
+// can use disjoint parts of the same corpus, e.g. 0-10 for warm-up and 10-80 for the ramp.
+// Ranges are computed by record count and are half-open, so adjacent ranges never share a
+// record.
+//
+// source may be:
+//   - The path of a JSON array or NDJSON file, which is streamed twice: once to count the
+//     records and once to decode only those in range
+//   - A sequence, which returns a sequence over the range without computing any values
+//   - An array, such as a loaded or shared dataset
+//
+// Example usage:
+//
+//	warmup := streamloader.SlicePercent("users.json", 0, 10)
+//	ramp := streamloader.SlicePercent("users.json", 10, 80)
+func (s StreamLoader) SlicePercent(source interface{}, fromPct float64, toPct float64) (interface{}, error) {
+	switch src := source.(type) {
+	case string:
+		return slicePercentFile(src, fromPct, toPct, s.call)
+	case *Sequence:
+		start, end, err := percentRange(src.Length(), fromPct, toPct)
+		if err != nil {
+			return nil, err
+		}
+		slice, err := src.Slice(start, end)
+		if err != nil {
+			return nil, err
+		}
+		s.closeAtIterationEnd(slice)
+		return slice, nil
+	case []interface{}:
+		start, end, err := percentRange(len(src), fromPct, toPct)
+		if err != nil {
+			return nil, err
+		}
+		return src[start:end], nil
+	default:
+		return nil, fmt.Errorf("cannot slice %T: expected a file path, sequence or array", source)
+	}
+}
+
+// slicePercentFile streams the records of a file in the percentage range.
+func slicePercentFile(filePath string, fromPct float64, toPct float64, call callLimits) ([]interface{}, error) {
+	if _, _, err := percentRange(0, fromPct, toPct); err != nil {
+		return nil, err
+	}
+	if err := requireSeekable(filePath, "slicePercent"); err != nil {
+		return nil, err
+	}
+
+	count := 0
+	err := forEachJsonRecord(filePath, call, func(json.RawMessage) (bool, error) {
+		count++
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	start, end, _ := percentRange(count, fromPct, toPct)
+
+	records := make([]interface{}, 0, end-start)
+	index := 0
+	err = forEachJsonRecord(filePath, call, func(raw json.RawMessage) (bool, error) {
+		if index >= end {
+			return false, nil
+		}
+		if index >= start {
+			var record interface{}
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return false, fmt.Errorf("failed to decode record %d in %s: %w", index, filePath, err)
+			}
+			records = append(records, record)
+		}
+		index++
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// forEachJsonRecord calls fn with each record of a JSON array or NDJSON file until fn returns
+// false, applying the limits of the call reading it.
+func forEachJsonRecord(filePath string, call callLimits, fn func(raw json.RawMessage) (bool, error)) error {
+	records, err := openJsonRecords(filePath, call)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+	for {
+		raw, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if more, err := fn(raw); err != nil || !more {
+			return err
+		}
+	}
+}
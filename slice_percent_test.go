@@ -0,0 +1,65 @@
+package streamloader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSlicePercent(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	path, _ := writeDataset(t, dir, "corpus", 10)
+
+	tests := []struct {
+		name     string
+		from, to float64
+		want     []interface{} // ids
+	}{
+		{"warm-up", 0, 10, []interface{}{float64(0)}},
+		{"ramp", 10, 80, []interface{}{float64(1), float64(2), float64(3), float64(4), float64(5), float64(6), float64(7)}},
+		{"rest", 80, 100, []interface{}{float64(8), float64(9)}},
+		{"empty", 50, 50, []interface{}{}},
+		{"fractional", 12.5, 37.5, []interface{}{float64(1), float64(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := loader.SlicePercent(path, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("SlicePercent() error = %v", err)
+			}
+			got := make([]interface{}, 0)
+			for _, r := range result.([]interface{}) {
+				got = append(got, r.(map[string]interface{})["id"])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SlicePercent(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+
+	// Sequences and arrays are sliced the same way
+	seq, _ := loader.GenerateRange(0, 10)
+	slice, err := loader.SlicePercent(seq, 10, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slice.(*Sequence).ToArray(); len(got) != 7 || got[0] != int64(1) {
+		t.Errorf("SlicePercent(sequence) = %v", got)
+	}
+	arr, _ := loader.SlicePercent(seq.ToArray(), 80, 100)
+	if got := arr.([]interface{}); !reflect.DeepEqual(got, []interface{}{int64(8), int64(9)}) {
+		t.Errorf("SlicePercent(array) = %v", got)
+	}
+
+	for _, bad := range [][2]float64{{-1, 10}, {10, 101}, {50, 40}} {
+		if _, err := loader.SlicePercent(path, bad[0], bad[1]); err == nil {
+			t.Errorf("SlicePercent(%v, %v) expected an error", bad[0], bad[1])
+		}
+	}
+	if _, err := loader.SlicePercent(42, 0, 10); err == nil {
+		t.Error("expected an error for a number source")
+	}
+	if _, err := loader.SlicePercent(dir+"/missing.json", 0, 10); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,48 @@
+// snapshot.go
+package streamloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// snapshotReader limits a reader of an input file to the file's size when it was opened, so
+// records another process appends while the file is read are left for the next load. The size
+// is taken from the opened handle, not the path, so a file replaced at the path after it was
+// opened doesn't change the limit. The last record inside the limit may still be half written;
+// the loaders drop it when it doesn't parse.
+func snapshotReader(r io.Reader, filePath string) (io.Reader, error) {
+	if isRemoteURL(filePath) {
+		return nil, fmt.Errorf("snapshot needs a local file, not the URL %s", filePath)
+	}
+	if isStreamInput(filePath) {
+		return nil, fmt.Errorf("snapshot needs a regular file: %s is a named pipe or other stream", filePath)
+	}
+	info, err := inputStat(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s for snapshot: %w", filePath, err)
+	}
+	if isStreamMode(info.Mode()) {
+		return nil, fmt.Errorf("snapshot needs a regular file: %s is a named pipe or other stream", filePath)
+	}
+	return io.LimitReader(r, info.Size()), nil
+}
+
+// inputStat returns the file info of an opened input, looking through the readers wrapping it.
+func inputStat(r io.Reader) (os.FileInfo, error) {
+	for {
+		switch h := r.(type) {
+		case interface{ Stat() (os.FileInfo, error) }:
+			return h.Stat()
+		case pooledReader:
+			r = h.ReadCloser
+		case countingReader:
+			r = h.ReadCloser
+		case deadlineReader:
+			r = h.ReadCloser
+		default:
+			return nil, fmt.Errorf("input is not a file")
+		}
+	}
+}
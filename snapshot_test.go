@@ -0,0 +1,149 @@
+package streamloader
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadJSONSnapshot(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		snapshot bool
+		want     int
+		wantErr  bool
+	}{
+		{name: "half-written last line", file: "events.ndjson", content: "{\"id\":1}\n{\"id\":2}\n{\"id\":", snapshot: true, want: 2},
+		{name: "half-written last line without snapshot", file: "events.ndjson", content: "{\"id\":1}\n{\"id\":2}\n{\"id\":", wantErr: true},
+		{name: "complete last line without newline", file: "events.ndjson", content: "{\"id\":1}\n{\"id\":2}", snapshot: true, want: 2},
+		{name: "malformed line before the end", file: "events.ndjson", content: "{\"id\":1}\n{\"id\":\n{\"id\":3}\n", snapshot: true, wantErr: true},
+		{name: "array", file: "events.json", content: "[{\"id\":1},{\"id\":2}]", snapshot: true, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, []byte(tt.content), 0644)
+			got, err := loader.LoadJSON(path, JsonOptions{Snapshot: tt.snapshot})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadJSON() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadJSON() error = %v", err)
+			}
+			n := 0
+			switch records := got.(type) {
+			case []map[string]any:
+				n = len(records)
+			case []interface{}:
+				n = len(records)
+			}
+			if n != tt.want {
+				t.Errorf("LoadJSON() = %v, want %d records", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadJSONSnapshotGzip(t *testing.T) {
+	loader := StreamLoader{}
+	path := filepath.Join(t.TempDir(), "events.ndjson.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A writer that flushes as it appends, without the gzip trailer yet
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("{\"id\":1}\n{\"id\":2}\n"))
+	gz.Flush()
+	gz.Write([]byte("{\"id\":3,\"na"))
+	gz.Flush()
+	file.Close()
+
+	got, err := loader.LoadJSON(path, JsonOptions{Snapshot: true})
+	if err != nil {
+		t.Fatalf("LoadJSON() error = %v", err)
+	}
+	if records, ok := got.([]map[string]any); !ok || len(records) != 2 {
+		t.Errorf("LoadJSON() = %v, want 2 records", got)
+	}
+	if _, err := loader.LoadJSON(path); err == nil {
+		t.Error("LoadJSON() without snapshot should fail on a truncated gzip file")
+	}
+}
+
+func TestOpenJSONStreamSnapshot(t *testing.T) {
+	loader := StreamLoader{}
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	os.WriteFile(path, []byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3,\"na"), 0644)
+
+	for _, snapshot := range []bool{true, false} {
+		stream, err := loader.OpenJSONStream(path, JSONStreamOptions{Snapshot: snapshot})
+		if err != nil {
+			t.Fatalf("OpenJSONStream() error = %v", err)
+		}
+		if snapshot {
+			// The writer finishes the record and appends another after the stream is opened
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString("me\":\"c\"}\n{\"id\":4}\n")
+			f.Close()
+		}
+
+		var ids []float64
+		for {
+			record, err := stream.Next()
+			if err != nil {
+				t.Fatalf("snapshot %v: Next() error = %v", snapshot, err)
+			}
+			if record == nil {
+				break
+			}
+			ids = append(ids, record.(map[string]interface{})["id"].(float64))
+		}
+		want := 2
+		if !snapshot {
+			want = 4 // Opened after the appends, and reading to the end
+		}
+		if len(ids) != want {
+			t.Errorf("snapshot %v: ids = %v, want %d records", snapshot, ids, want)
+		}
+	}
+}
+
+func TestSnapshotSizeOfOpenedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson")
+	os.WriteFile(path, []byte("{\"id\":1}\n"), 0644)
+	file, err := openSequential(path, "", callLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// A larger file replacing the path after it was opened doesn't change the snapshot
+	replacement := filepath.Join(dir, "replacement.ndjson")
+	os.WriteFile(replacement, []byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"), 0644)
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+	src, err := snapshotReader(file, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(src)
+	if string(data) != "{\"id\":1}\n" {
+		t.Errorf("snapshot read %q, want the opened file", data)
+	}
+}
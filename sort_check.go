@@ -0,0 +1,145 @@
+// sort_check.go
+package streamloader
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortCheckOptions configures CheckSorted
+type SortCheckOptions struct {
+	Strict    bool `json:"strict" js:"strict"`
+	Timestamp bool `json:"timestamp" js:"timestamp"`
+}
+
+// SortViolation is the first record of a dataset that breaks its order
+type SortViolation struct {
+	Index         int         `json:"index" js:"index"`
+	PreviousIndex int         `json:"previousIndex" js:"previousIndex"`
+	Previous      interface{} `json:"previous" js:"previous"`
+	Value         interface{} `json:"value" js:"value"`
+	Reason        string      `json:"reason" js:"reason"`
+}
+
+// SortCheck is the result of CheckSorted
+type SortCheck struct {
+	Sorted    bool           `json:"sorted" js:"sorted"`
+	Records   int            `json:"records" js:"records"`
+	Violation *SortViolation `json:"violation" js:"violation"`
+}
+
+// errSortViolation stops reading at the first violation.
+var errSortViolation = errors.New("sort violation")
+
+// compareSortKeys orders two values of the checked field: times chronologically, numbers
+// numerically, strings that both hold numbers numerically as well, so the IDs of a CSV file are
+// not ordered "10" < "9", and other strings byte by byte.
+func compareSortKeys(a interface{}, b interface{}) (int, bool) {
+	if x, ok := a.(time.Time); ok {
+		y, ok := b.(time.Time)
+		return x.Compare(y), ok
+	}
+	x, okX := a.(string)
+	y, okY := b.(string)
+	if okX && okY {
+		if fx, err := strconv.ParseFloat(x, 64); err == nil {
+			if fy, err := strconv.ParseFloat(y, 64); err == nil {
+				return compareValues(fx, fy)
+			}
+		}
+	}
+	return compareValues(a, b)
+}
+
+// CheckSorted verifies in one streaming pass that a JSON array, NDJSON or CSV file (with a
+// header row) is sorted by a field, such as a timestamp or an ID, and reports the first record
+// out of order, so a test whose replay depends on sorted input fails fast instead of silently
+// replaying out of order. order is "asc" or "desc" (default: "asc"). Reading stops at the first
+// violation.
+//
+// Options:
+//   - strict: Equal consecutive values are violations too, to check that IDs increase
+//     monotonically (default: false)
+//   - timestamp: Compare the values as points in time, RFC 3339 strings in any time zone or Unix
+//     times in seconds or milliseconds, instead of as numbers and strings (default: false)
+//
+// A record without the field, or with a null value, is a violation with reason "missing"; a
+// value that can't be compared with the previous one, such as a string after a number or an
+// invalid timestamp, one with reason "incomparable".
+//
+// Returns: { sorted, records, violation }, where records is the number of records checked and
+// violation, when not sorted, is { index, previousIndex, previous, value, reason } with the
+// 0-based indexes of the offending record and the one before it in the file (-1 for the first
+// record), their values, and reason "order", "duplicate" (with strict), "missing" or
+// "incomparable"
+//
+// Example usage:
+//
+//	const check = streamloader.checkSorted("recording.ndjson", "timestamp", "asc", { timestamp: true });
+//	if (!check.sorted) {
+//		throw new Error(`recording.ndjson: record ${check.violation.index} is out of order (${check.violation.reason})`);
+//	}
+func (s StreamLoader) CheckSorted(filePath string, field string, order string, options ...SortCheckOptions) (*SortCheck, error) {
+	if field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	direction := 1
+	switch strings.ToLower(order) {
+	case "", "asc":
+	case "desc":
+		direction = -1
+	default:
+		return nil, fmt.Errorf("invalid order %q: expected \"asc\" or \"desc\"", order)
+	}
+	var opts SortCheckOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	result := &SortCheck{Sorted: true}
+	var previous, previousKey interface{}
+	open := func(filePath string) (*datasetRecords, error) {
+		return openDatasetRecords(filePath, false, nil, s.call)
+	}
+	err := forEachDatasetRecord(open, filePath, func(index int, record interface{}) error {
+		result.Records++
+		violation := func(value interface{}, reason string) error {
+			result.Sorted = false
+			result.Violation = &SortViolation{Index: index, PreviousIndex: index - 1, Previous: previous, Value: value, Reason: reason}
+			return errSortViolation
+		}
+
+		value, found := lookupField(record, field)
+		if !found || value == nil {
+			return violation(nil, "missing")
+		}
+		key := value
+		if opts.Timestamp {
+			at, err := parseTimestamp(value)
+			if err != nil {
+				return violation(value, "incomparable")
+			}
+			key = at
+		}
+		if index > 0 {
+			cmp, ok := compareSortKeys(previousKey, key)
+			switch {
+			case !ok:
+				return violation(value, "incomparable")
+			case cmp*direction > 0:
+				return violation(value, "order")
+			case cmp == 0 && opts.Strict:
+				return violation(value, "duplicate")
+			}
+		}
+		previous, previousKey = value, key
+		return nil
+	})
+	if err != nil && err != errSortViolation {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckSorted(t *testing.T) {
+	loader := StreamLoader{}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		field   string
+		order   string
+		options SortCheckOptions
+		want    SortCheck
+		wantErr string
+	}{
+		{
+			name:    "sorted ndjson",
+			file:    "events.ndjson",
+			content: "{\"id\":1}\n{\"id\":2}\n{\"id\":2}\n{\"id\":5}\n",
+			field:   "id",
+			want:    SortCheck{Sorted: true, Records: 4},
+		},
+		{
+			name:    "duplicate with strict",
+			file:    "events.ndjson",
+			content: "{\"id\":1}\n{\"id\":2}\n{\"id\":2}\n{\"id\":5}\n",
+			field:   "id",
+			options: SortCheckOptions{Strict: true},
+			want: SortCheck{Records: 3, Violation: &SortViolation{
+				Index: 2, PreviousIndex: 1, Previous: float64(2), Value: float64(2), Reason: "duplicate",
+			}},
+		},
+		{
+			name:    "out of order stops reading",
+			file:    "events.json",
+			content: `[{"at":{"id":3}},{"at":{"id":7}},{"at":{"id":4}},{"at":{"id":1}}]`,
+			field:   "at.id",
+			want: SortCheck{Records: 3, Violation: &SortViolation{
+				Index: 2, PreviousIndex: 1, Previous: float64(7), Value: float64(4), Reason: "order",
+			}},
+		},
+		{
+			name:    "descending",
+			file:    "events.json",
+			content: `[{"id":"c"},{"id":"b"},{"id":"a"}]`,
+			field:   "id",
+			order:   "desc",
+			want:    SortCheck{Sorted: true, Records: 3},
+		},
+		{
+			name:    "numeric csv cells",
+			file:    "users.csv",
+			content: "id,name\n9,a\n10,b\n100,c\n",
+			field:   "id",
+			want:    SortCheck{Sorted: true, Records: 3},
+		},
+		{
+			name:    "timestamps in different zones",
+			file:    "events.ndjson",
+			content: "{\"t\":\"2026-01-01T10:00:00+02:00\"}\n{\"t\":\"2026-01-01T09:00:00Z\"}\n{\"t\":1767261600}\n",
+			field:   "t",
+			options: SortCheckOptions{Timestamp: true},
+			want:    SortCheck{Sorted: true, Records: 3},
+		},
+		{
+			name:    "missing field",
+			file:    "events.ndjson",
+			content: "{\"id\":1}\n{\"other\":2}\n",
+			field:   "id",
+			want: SortCheck{Records: 2, Violation: &SortViolation{
+				Index: 1, PreviousIndex: 0, Previous: float64(1), Reason: "missing",
+			}},
+		},
+		{
+			name:    "incomparable",
+			file:    "events.ndjson",
+			content: "{\"id\":1}\n{\"id\":\"x\"}\n",
+			field:   "id",
+			want: SortCheck{Records: 2, Violation: &SortViolation{
+				Index: 1, PreviousIndex: 0, Previous: float64(1), Value: "x", Reason: "incomparable",
+			}},
+		},
+		{
+			name:    "invalid order",
+			file:    "events.ndjson",
+			content: "{\"id\":1}\n",
+			field:   "id",
+			order:   "up",
+			wantErr: "invalid order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			os.WriteFile(path, []byte(tt.content), 0644)
+			got, err := loader.CheckSorted(path, tt.field, tt.order, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CheckSorted() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckSorted() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("CheckSorted() = %+v (violation %+v), want %+v (violation %+v)", *got, got.Violation, tt.want, tt.want.Violation)
+			}
+		})
+	}
+}
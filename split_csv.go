@@ -0,0 +1,271 @@
+// split_csv.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitCsvOptions configures SplitCsvFile. Exactly one of MaxRows, MaxBytes and ByColumn is set.
+type SplitCsvOptions struct {
+	MaxRows      int    `json:"maxRows" js:"maxRows"`
+	MaxBytes     int64  `json:"maxBytes" js:"maxBytes"`
+	ByColumn     string `json:"byColumn" js:"byColumn"`
+	Header       bool   `json:"header" js:"header"`
+	RepeatHeader bool   `json:"repeatHeader" js:"repeatHeader"`
+	Delimiter    string `json:"delimiter" js:"delimiter"`
+	LazyQuotes   bool   `json:"lazyQuotes" js:"lazyQuotes"`
+}
+
+// CsvShard describes a file written by SplitCsvFile
+type CsvShard struct {
+	Path  string `json:"path" js:"path"`
+	Group string `json:"group,omitempty" js:"group"`
+	Rows  int    `json:"rows" js:"rows"`
+	Bytes int64  `json:"bytes" js:"bytes"`
+}
+
+// csvShardWriter writes the rows of one shard.
+type csvShardWriter struct {
+	shard  CsvShard
+	file   *outputFile
+	writer *bufio.Writer
+}
+
+func (w *csvShardWriter) write(row []byte) error {
+	if _, err := w.writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.shard.Path, err)
+	}
+	w.shard.Bytes += int64(len(row))
+	return nil
+}
+
+func (w *csvShardWriter) close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to flush %s: %w", w.shard.Path, err)
+	}
+	return w.file.Close()
+}
+
+// csvSplitter creates the shards of SplitCsvFile and encodes rows for them.
+type csvSplitter struct {
+	dir     string
+	base    string // Input file name without its extension
+	ext     string
+	header  []byte // Encoded header written at the top of every shard, or nil
+	encoded bytes.Buffer
+	encoder *csv.Writer // Writes to encoded
+	shards  []*csvShardWriter
+	paths   map[string]string // Output path -> group, to detect groups mapping to the same file
+}
+
+// encode returns a row as CSV text, valid until the next call.
+func (s *csvSplitter) encode(row []string) ([]byte, error) {
+	s.encoded.Reset()
+	if err := s.encoder.Write(row); err != nil {
+		return nil, err
+	}
+	s.encoder.Flush()
+	return s.encoded.Bytes(), s.encoder.Error()
+}
+
+// create starts a new shard, named after its group or its number.
+func (s *csvSplitter) create(group string, grouped bool) (*csvShardWriter, error) {
+	name := fmt.Sprintf("%s-%05d%s", s.base, len(s.shards)+1, s.ext)
+	if grouped {
+		name = s.base + "-" + groupFileName(group) + s.ext
+	}
+	path := filepath.Join(s.dir, name)
+	if other, exists := s.paths[path]; exists {
+		return nil, fmt.Errorf("groups %q and %q both map to output file %s", other, group, path)
+	}
+	s.paths[path] = group
+
+	file, err := createOutputFile(path, JsonWriterOptions{}, gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	w := &csvShardWriter{shard: CsvShard{Path: path, Group: group}, file: file, writer: bufio.NewWriterSize(file, groupOutputBufferSize)}
+	s.shards = append(s.shards, w)
+	if s.header != nil {
+		if err := w.write(s.header); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// close finishes every shard and returns their descriptions in the order they were created.
+func (s *csvSplitter) close() ([]CsvShard, error) {
+	var firstErr error
+	shards := make([]CsvShard, 0, len(s.shards))
+	for _, w := range s.shards {
+		if err := w.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		shards = append(shards, w.shard)
+	}
+	return shards, firstErr
+}
+
+// SplitCsvFile streams a CSV file into shard files in outputDir, so a giant CSV can be
+// distributed across load generators or processed per category. Rows are split by one of:
+//   - maxRows: Start a new shard after this many data rows
+//   - maxBytes: Start a new shard before a row that would make the current one larger than this;
+//     a single row larger than maxBytes gets a shard of its own
+//   - byColumn: Write each row to the shard of its value in this column, named in the header
+//
+// Other options:
+//   - header: The first row is a header (default: false; required by byColumn)
+//   - repeatHeader: Write the header at the top of every shard (default: false)
+//   - delimiter: Field delimiter, detected from the extension as for the CSV loaders
+//   - lazyQuotes: Accept quotes in unquoted fields
+//
+// Shards are named after the input, e.g. orders-00001.csv, or orders-<value>.csv with byColumn,
+// where characters other than letters, digits, '.', '-' and '_' become '_'. With byColumn every
+// shard stays open until the input is read, so the number of distinct values is bounded by the
+// maxOpenFiles setting.
+//
+// Returns: The shards in the order they were created, with their path, group, rows and bytes
+//
+// Example usage:
+//
+//	const shards = streamloader.splitCsvFile("orders.csv", "shards", { maxRows: 100000, header: true, repeatHeader: true });
+//	const byRegion = streamloader.splitCsvFile("orders.csv", "regions", { byColumn: "region", header: true, repeatHeader: true });
+func (loader StreamLoader) SplitCsvFile(inputFilePath string, outputDir string, options SplitCsvOptions) ([]CsvShard, error) {
+	modes := 0
+	for _, set := range []bool{options.MaxRows != 0, options.MaxBytes != 0, options.ByColumn != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return nil, fmt.Errorf("exactly one of maxRows, maxBytes and byColumn must be set")
+	}
+	if options.MaxRows < 0 || options.MaxBytes < 0 {
+		return nil, fmt.Errorf("maxRows and maxBytes must be positive")
+	}
+	if (options.ByColumn != "" || options.RepeatHeader) && !options.Header {
+		return nil, fmt.Errorf("byColumn and repeatHeader need the header option")
+	}
+	if err := checkWritable(outputDir); err != nil {
+		return nil, err
+	}
+
+	file, err := openInput(inputFilePath, loader.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+	csvReader := csv.NewReader(newLineNormalizer(bufio.NewReaderSize(file, readBufferSize()), true, true))
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = options.LazyQuotes
+	if err := setCsvDelimiter(csvReader, options.Delimiter, inputFilePath); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	name := filepath.Base(inputFilePath)
+	if isDataURI(inputFilePath) {
+		name = "data" + inputExt(inputFilePath)
+	}
+	ext := filepath.Ext(name)
+	s := &csvSplitter{dir: outputDir, base: strings.TrimSuffix(name, ext), ext: ext, paths: make(map[string]string)}
+	s.encoder = csv.NewWriter(&s.encoded)
+	s.encoder.Comma = csvReader.Comma
+	fail := func(err error) ([]CsvShard, error) {
+		s.close()
+		return nil, err
+	}
+
+	column := -1
+	if options.Header {
+		header, err := csvReader.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV file is empty")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV header: %w", err)
+		}
+		if options.ByColumn != "" {
+			for i, name := range header {
+				if strings.TrimSpace(name) == options.ByColumn {
+					column = i
+					break
+				}
+			}
+			if column < 0 {
+				return nil, fmt.Errorf("column %q not found in CSV header", options.ByColumn)
+			}
+		}
+		if options.RepeatHeader {
+			encoded, err := s.encode(header)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode CSV header: %w", err)
+			}
+			s.header = append([]byte(nil), encoded...)
+		}
+	}
+
+	groups := make(map[string]*csvShardWriter)
+	var current *csvShardWriter
+	line := 0
+	if options.Header {
+		line = 1
+	}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return fail(fmt.Errorf("failed to parse CSV at line %d: %w", line, err))
+		}
+		row, err := s.encode(record)
+		if err != nil {
+			return fail(fmt.Errorf("failed to encode CSV line %d: %w", line, err))
+		}
+
+		// Pick the shard of the row, starting a new one when needed
+		switch {
+		case column >= 0:
+			group := ""
+			if column < len(record) {
+				group = record[column]
+			}
+			if current = groups[group]; current == nil {
+				if current, err = s.create(group, true); err != nil {
+					return fail(err)
+				}
+				groups[group] = current
+			}
+		case current == nil,
+			options.MaxRows > 0 && current.shard.Rows >= options.MaxRows,
+			options.MaxBytes > 0 && current.shard.Rows > 0 && current.shard.Bytes+int64(len(row)) > options.MaxBytes:
+			if current != nil {
+				if err := current.close(); err != nil {
+					return fail(err)
+				}
+			}
+			if current, err = s.create("", false); err != nil {
+				return fail(err)
+			}
+		}
+		if err := current.write(row); err != nil {
+			return fail(err)
+		}
+		current.shard.Rows++
+	}
+	return s.close()
+}
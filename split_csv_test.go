@@ -0,0 +1,114 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitCsvFile(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "orders.csv")
+	os.WriteFile(input, []byte("id,region,note\n1,eu,a\n2,us,\"b, c\"\n3,eu,d\n4,apac,e\n5,us,f\n"), 0644)
+
+	tests := []struct {
+		name    string
+		options SplitCsvOptions
+		want    map[string]string // File name -> content
+	}{
+		{
+			name:    "maxRows with the header repeated",
+			options: SplitCsvOptions{MaxRows: 2, Header: true, RepeatHeader: true},
+			want: map[string]string{
+				"orders-00001.csv": "id,region,note\n1,eu,a\n2,us,\"b, c\"\n",
+				"orders-00002.csv": "id,region,note\n3,eu,d\n4,apac,e\n",
+				"orders-00003.csv": "id,region,note\n5,us,f\n",
+			},
+		},
+		{
+			name:    "maxRows without a header",
+			options: SplitCsvOptions{MaxRows: 4},
+			want: map[string]string{
+				"orders-00001.csv": "id,region,note\n1,eu,a\n2,us,\"b, c\"\n3,eu,d\n",
+				"orders-00002.csv": "4,apac,e\n5,us,f\n",
+			},
+		},
+		{
+			name:    "maxBytes",
+			options: SplitCsvOptions{MaxBytes: 30, Header: true, RepeatHeader: true},
+			want: map[string]string{
+				"orders-00001.csv": "id,region,note\n1,eu,a\n",
+				"orders-00002.csv": "id,region,note\n2,us,\"b, c\"\n",
+				"orders-00003.csv": "id,region,note\n3,eu,d\n",
+				"orders-00004.csv": "id,region,note\n4,apac,e\n",
+				"orders-00005.csv": "id,region,note\n5,us,f\n",
+			},
+		},
+		{
+			name:    "byColumn",
+			options: SplitCsvOptions{ByColumn: "region", Header: true},
+			want: map[string]string{
+				"orders-eu.csv":   "1,eu,a\n3,eu,d\n",
+				"orders-us.csv":   "2,us,\"b, c\"\n5,us,f\n",
+				"orders-apac.csv": "4,apac,e\n",
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(dir, "out", string(rune('a'+i)))
+			shards, err := loader.SplitCsvFile(input, out, tt.options)
+			if err != nil {
+				t.Fatalf("SplitCsvFile() error = %v", err)
+			}
+			if len(shards) != len(tt.want) {
+				t.Fatalf("SplitCsvFile() = %d shards, want %d", len(shards), len(tt.want))
+			}
+			rows := 0
+			for _, shard := range shards {
+				data, _ := os.ReadFile(shard.Path)
+				name := filepath.Base(shard.Path)
+				if string(data) != tt.want[name] {
+					t.Errorf("%s = %q, want %q", name, data, tt.want[name])
+				}
+				if shard.Bytes != int64(len(data)) {
+					t.Errorf("%s: bytes = %d, want %d", name, shard.Bytes, len(data))
+				}
+				rows += shard.Rows
+			}
+			wantRows := 5
+			if !tt.options.Header {
+				wantRows = 6
+			}
+			if rows != wantRows {
+				t.Errorf("SplitCsvFile() wrote %d rows, want %d", rows, wantRows)
+			}
+		})
+	}
+
+	// The groups of shards are reported
+	shards, _ := loader.SplitCsvFile(input, filepath.Join(dir, "groups"), SplitCsvOptions{ByColumn: "region", Header: true})
+	var groups []string
+	for _, shard := range shards {
+		groups = append(groups, shard.Group)
+	}
+	if strings.Join(groups, ",") != "eu,us,apac" {
+		t.Errorf("groups = %v, want eu,us,apac in order of appearance", groups)
+	}
+
+	// Errors
+	invalid := []SplitCsvOptions{
+		{},
+		{MaxRows: 2, MaxBytes: 10},
+		{ByColumn: "region"},
+		{MaxRows: 2, RepeatHeader: true},
+		{ByColumn: "missing", Header: true},
+	}
+	for _, options := range invalid {
+		if _, err := loader.SplitCsvFile(input, filepath.Join(dir, "invalid"), options); err == nil {
+			t.Errorf("SplitCsvFile(%+v) expected an error", options)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// stream_input.go
+package streamloader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// isStreamMode reports whether a file mode is that of an input that can only be read once from
+// start to end, such as a named pipe (FIFO), a character device like /dev/stdin, or a socket.
+func isStreamMode(mode fs.FileMode) bool {
+	return mode&(fs.ModeNamedPipe|fs.ModeCharDevice|fs.ModeSocket) != 0
+}
+
+// isStreamInput reports whether an input path names a stream rather than a regular file.
+// Stat doesn't open the path, so it doesn't block on a named pipe without a writer.
+func isStreamInput(path string) bool {
+	if isDataURI(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && isStreamMode(info.Mode())
+}
+
+// requireSeekable returns an error if an input that a function reads more than once is a
+// stream.
+func requireSeekable(path string, function string) error {
+	if isStreamInput(path) {
+		return fmt.Errorf("%s reads %s more than once, which a named pipe or other stream doesn't allow", function, path)
+	}
+	return nil
+}
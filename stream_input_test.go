@@ -0,0 +1,88 @@
+//go:build linux || darwin || freebsd
+
+package streamloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeFifo creates a named pipe and writes chunks into it from a goroutine, pausing between
+// them like a generator process.
+func writeFifo(t *testing.T, chunks ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "records.pipe")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+	go func() {
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		for _, chunk := range chunks {
+			file.WriteString(chunk)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	return path
+}
+
+func TestIterateFifo(t *testing.T) {
+	loader := StreamLoader{}
+	path := writeFifo(t, "{\"id\":1}\n", "{\"id\":2}\n{\"id\"", ":3}\n")
+	if !isStreamInput(path) {
+		t.Fatal("isStreamInput(fifo) = false")
+	}
+
+	it, err := loader.Iterate(path)
+	if err != nil {
+		t.Fatalf("Iterate(fifo) error = %v", err)
+	}
+	defer it.Close()
+	values, err := it.ToArray()
+	if err != nil {
+		t.Fatalf("ToArray() error = %v", err)
+	}
+	if encoded, _ := json.Marshal(values); string(encoded) != `[{"id":1},{"id":2},{"id":3}]` {
+		t.Errorf("Iterate(fifo) = %s", encoded)
+	}
+}
+
+func TestLoadJSONFifo(t *testing.T) {
+	loader := StreamLoader{}
+	for _, pageCache := range []string{"", "drop", "direct"} {
+		path := writeFifo(t, `[{"id":1},`, `{"id":2}]`)
+		data, err := loader.LoadJSON(path, JsonOptions{PageCache: pageCache})
+		if err != nil {
+			t.Fatalf("LoadJSON(fifo, pageCache %q) error = %v", pageCache, err)
+		}
+		if encoded, _ := json.Marshal(data); string(encoded) != `[{"id":1},{"id":2}]` {
+			t.Errorf("LoadJSON(fifo, pageCache %q) = %s", pageCache, encoded)
+		}
+	}
+}
+
+func TestStreamInputReadTwice(t *testing.T) {
+	loader := StreamLoader{}
+	path := filepath.Join(t.TempDir(), "records.pipe")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+	// Functions that read their input twice fail before opening the pipe, so nothing blocks
+	if _, err := loader.SlicePercent(path, 0, 10); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("SlicePercent(fifo) error = %v", err)
+	}
+	if _, err := loader.StratifiedSample(path, "endpoint", 0.5, 1); err == nil {
+		t.Error("StratifiedSample(fifo) expected an error")
+	}
+	if _, err := loader.AssertDatasetsEqual(path, path, CompareDatasetsOptions{IgnoreOrder: true}); err == nil {
+		t.Error("AssertDatasetsEqual(fifo, ignoreOrder) expected an error")
+	}
+}
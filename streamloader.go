@@ -11,11 +11,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"go.k6.io/k6/js/modules"
 )
@@ -25,7 +26,10 @@ import (
 // using a small buffer and supporting standard JSON arrays, NDJSON, or JSON objects.
 // It also provides LoadCSV for streaming CSV files with minimal memory footprint.
 // Additionally, it includes utilities for converting between JSON formats and working with compressed JSON data.
-type StreamLoader struct{}
+type StreamLoader struct {
+	vu   modules.VU // The VU the instance belongs to; nil when used directly from Go
+	call callLimits // Limits of the current call from a script, set by exportsWithCallCounts
+}
 
 // FilterConfig represents a row filter configuration
 type FilterConfig struct {
@@ -34,20 +38,28 @@ type FilterConfig struct {
 	Pattern string   `json:"pattern,omitempty" js:"pattern"`
 	Min     *float64 `json:"min,omitempty" js:"min"`
 	Max     *float64 `json:"max,omitempty" js:"max"`
+	Rate    *float64 `json:"rate,omitempty" js:"rate"`
+	Salt    string   `json:"salt,omitempty" js:"salt"`
 }
 
 // TransformConfig represents a value transform configuration
 type TransformConfig struct {
-	Type   string      `json:"type" js:"type"`
-	Column int         `json:"column" js:"column"`
-	Value  interface{} `json:"value,omitempty" js:"value"`
-	Start  int         `json:"start,omitempty" js:"start"`
-	Length *int        `json:"length,omitempty" js:"length"`
+	Type         string      `json:"type" js:"type"`
+	Column       int         `json:"column" js:"column"`
+	Value        interface{} `json:"value,omitempty" js:"value"`
+	Start        int         `json:"start,omitempty" js:"start"`
+	Length       *int        `json:"length,omitempty" js:"length"`
+	Name         string      `json:"name,omitempty" js:"name"`
+	Part         string      `json:"part,omitempty" js:"part"`
+	SourceColumn *int        `json:"sourceColumn,omitempty" js:"sourceColumn"`
 }
 
 // GroupByConfig represents grouping configuration
 type GroupByConfig struct {
-	Column int `json:"column" js:"column"`
+	Column        int    `json:"column" js:"column"`
+	OutputPattern string `json:"outputPattern,omitempty" js:"outputPattern"`
+	HashKey       string `json:"hashKey,omitempty" js:"hashKey"`
+	Salt          string `json:"salt,omitempty" js:"salt"`
 }
 
 // FieldConfig represents a projection field configuration
@@ -55,27 +67,95 @@ type FieldConfig struct {
 	Type   string      `json:"type" js:"type"`
 	Column int         `json:"column,omitempty" js:"column"`
 	Value  interface{} `json:"value,omitempty" js:"value"`
+	Name   string      `json:"name,omitempty" js:"name"`
+	Part   string      `json:"part,omitempty" js:"part"`
 }
 
 // CsvOptions represents options for CSV parsing in LoadCSV
 type CsvOptions struct {
-	LazyQuotes       bool `json:"lazyQuotes" js:"lazyQuotes"`
-	TrimLeadingSpace bool `json:"trimLeadingSpace" js:"trimLeadingSpace"`
-	TrimSpace        bool `json:"trimSpace" js:"trimSpace"`
-	ReuseRecord      bool `json:"reuseRecord" js:"reuseRecord"`
+	LazyQuotes            bool              `json:"lazyQuotes" js:"lazyQuotes"`
+	TrimLeadingSpace      bool              `json:"trimLeadingSpace" js:"trimLeadingSpace"`
+	TrimSpace             bool              `json:"trimSpace" js:"trimSpace"`
+	ReuseRecord           bool              `json:"reuseRecord" js:"reuseRecord"`
+	PreserveLineEndings   bool              `json:"preserveLineEndings" js:"preserveLineEndings"`
+	Comment               string            `json:"comment" js:"comment"`
+	SkipBlankRows         bool              `json:"skipBlankRows" js:"skipBlankRows"`
+	ExpectHeaders         []string          `json:"expectHeaders" js:"expectHeaders"`
+	HeaderMatch           string            `json:"headerMatch" js:"headerMatch"`
+	HeaderCaseInsensitive bool              `json:"headerCaseInsensitive" js:"headerCaseInsensitive"`
+	Delimiter             string            `json:"delimiter" js:"delimiter"`
+	PageCache             string            `json:"pageCache" js:"pageCache"`
+	AllowPartial          bool              `json:"allowPartial" js:"allowPartial"`
+	Headers               map[string]string `json:"headers" js:"headers"`
+	TimeoutMs             int64             `json:"timeoutMs" js:"timeoutMs"`
+}
+
+// JsonOptions represents options for LoadJSON
+type JsonOptions struct {
+	DetectDuplicateKeys bool              `json:"detectDuplicateKeys" js:"detectDuplicateKeys"`
+	PreserveLineEndings bool              `json:"preserveLineEndings" js:"preserveLineEndings"`
+	PageCache           string            `json:"pageCache" js:"pageCache"`
+	AllowPartial        bool              `json:"allowPartial" js:"allowPartial"`
+	DecodeFields        map[string]string `json:"decodeFields" js:"decodeFields"`
+	DropExpired         string            `json:"dropExpired" js:"dropExpired"`
+	ExpiryReference     string            `json:"expiryReference" js:"expiryReference"`
+	ObjectOrder         string            `json:"objectOrder" js:"objectOrder"`
+	KeyPrefix           string            `json:"keyPrefix" js:"keyPrefix"`
+	KeyRegex            string            `json:"keyRegex" js:"keyRegex"`
+	MaxKeys             int               `json:"maxKeys" js:"maxKeys"`
+	Path                string            `json:"path" js:"path"`
+	Snapshot            bool              `json:"snapshot" js:"snapshot"`
+	Headers             map[string]string `json:"headers" js:"headers"`
+	TimeoutMs           int64             `json:"timeoutMs" js:"timeoutMs"`
+}
+
+// TextOptions represents options for LoadText
+type TextOptions struct {
+	StripBOM          bool              `json:"stripBOM" js:"stripBOM"`
+	NormalizeNewlines bool              `json:"normalizeNewlines" js:"normalizeNewlines"`
+	Headers           map[string]string `json:"headers" js:"headers"`
+	TimeoutMs         int64             `json:"timeoutMs" js:"timeoutMs"`
+}
+
+// LineOptions represents options for Head and Tail
+type LineOptions struct {
+	PreserveLineEndings bool `json:"preserveLineEndings" js:"preserveLineEndings"`
+}
+
+// JsonWriterOptions represents options for the JSON array and JSONL writers
+type JsonWriterOptions struct {
+	BufferSize       int    `json:"bufferSize" js:"bufferSize"`
+	SortKeys         bool   `json:"sortKeys" js:"sortKeys"`
+	StableFormatting bool   `json:"stableFormatting" js:"stableFormatting"`
+	Provenance       string `json:"provenance" js:"provenance"`
+	CompressOutput   string `json:"compressOutput" js:"compressOutput"`
+	EncryptOutput    string `json:"encryptOutput" js:"encryptOutput"`
+	CheckDiskSpace   bool   `json:"checkDiskSpace" js:"checkDiskSpace"`
+
+	Metadata *DatasetMetadataOptions `json:"metadata,omitempty" js:"metadata"`
+	Manifest string                  `json:"manifest,omitempty" js:"manifest"`
 }
 
 // ProcessCsvOptions represents options for ProcessCsvFile
 type ProcessCsvOptions struct {
-	SkipHeader       bool              `json:"skipHeader" js:"skipHeader"`
-	LazyQuotes       bool              `json:"lazyQuotes" js:"lazyQuotes"`
-	TrimLeadingSpace bool              `json:"trimLeadingSpace" js:"trimLeadingSpace"`
-	TrimSpace        bool              `json:"trimSpace" js:"trimSpace"`
-	ReuseRecord      bool              `json:"reuseRecord" js:"reuseRecord"`
-	Filters          []FilterConfig    `json:"filters" js:"filters"`
-	Transforms       []TransformConfig `json:"transforms" js:"transforms"`
-	GroupBy          *GroupByConfig    `json:"groupBy,omitempty" js:"groupBy"`
-	Fields           []FieldConfig     `json:"fields" js:"fields"`
+	SkipHeader            bool              `json:"skipHeader" js:"skipHeader"`
+	LazyQuotes            bool              `json:"lazyQuotes" js:"lazyQuotes"`
+	TrimLeadingSpace      bool              `json:"trimLeadingSpace" js:"trimLeadingSpace"`
+	TrimSpace             bool              `json:"trimSpace" js:"trimSpace"`
+	ReuseRecord           bool              `json:"reuseRecord" js:"reuseRecord"`
+	PreserveLineEndings   bool              `json:"preserveLineEndings" js:"preserveLineEndings"`
+	Comment               string            `json:"comment" js:"comment"`
+	SkipBlankRows         bool              `json:"skipBlankRows" js:"skipBlankRows"`
+	ExpectHeaders         []string          `json:"expectHeaders" js:"expectHeaders"`
+	HeaderMatch           string            `json:"headerMatch" js:"headerMatch"`
+	HeaderCaseInsensitive bool              `json:"headerCaseInsensitive" js:"headerCaseInsensitive"`
+	Cache                 string            `json:"cache,omitempty" js:"cache"`
+	Delimiter             string            `json:"delimiter,omitempty" js:"delimiter"`
+	PageCache             string            `json:"pageCache,omitempty" js:"pageCache"`
+	Filters               []FilterConfig    `json:"filters" js:"filters"`
+	Transforms            []TransformConfig `json:"transforms" js:"transforms"`
+	GroupBy               *GroupByConfig    `json:"groupBy,omitempty" js:"groupBy"`
+	Fields                []FieldConfig     `json:"fields" js:"fields"`
 }
 
 // ProcessCsvFile opens a CSV file and processes it row by row using streaming to minimize memory usage.
@@ -83,27 +163,64 @@ type ProcessCsvOptions struct {
 // This approach is memory-efficient for large CSV files since it processes one row at a time
 // instead of loading the entire file into memory first.
 //
+// filePath may be a single path, a glob pattern such as "exports/2026-*.csv", or an array of
+// paths. Multiple files are processed in order as one logical stream; header handling
+// (skipHeader, expectHeaders) applies to the first row of every file.
+//
 // Options:
 // - skipHeader: Whether to skip the first row as header (default: true)
 // - lazyQuotes: Allow unescaped quotes in quoted fields (default: true)
 // - trimLeadingSpace: Trim leading whitespace from fields (default: true)
 // - trimSpace: Trim all whitespace from fields (leading and trailing) (default: false)
 // - reuseRecord: Reuse record memory for better performance (default: true)
+// - preserveLineEndings: Keep a UTF-8 BOM and lone "\r" line endings as-is (default: false)
+// - comment: Skip lines starting with this character, e.g. "#" (default: none)
+// - skipBlankRows: Drop rows whose fields are all empty or whitespace (default: false)
+// - expectHeaders: Fail unless the first row matches these column names (default: none)
+// - headerMatch: "exact" (same columns in the same order) or "subset" (default: "exact")
+// - headerCaseInsensitive: Compare expected headers case-insensitively (default: false)
+// - delimiter: Field delimiter; detected from the extension (.tsv tab, .psv "|") when unset (default: ",")
+// - cache: Cache directory; runs with unchanged files (path, size, mtime) and options reuse the stored result (default: none)
+// - pageCache: "drop" or "direct" to keep large scans out of the OS page cache on Linux, as in LoadCSV (default: "keep")
 // - filters: Array of filter configs to drop unwanted rows:
 //   - { type: "emptyString", column: N }
 //   - { type: "regexMatch", column: N, pattern: "regex" }
 //   - { type: "valueRange", column: N, min: X, max: Y }
+//   - { type: "hashSample", column: N, rate: R, salt: S } keeps a row when the salted hash of the
+//     cell falls in the first fraction R of the hash space. For the same rate and salt, the same
+//     keys are kept in every file, and by iterators filtered with the hashSample op
 //
 // - transforms: Array of transform configs to apply in-place:
 //   - { type: "parseInt", column: N }
 //   - { type: "fixedValue", column: N, value: V }
 //   - { type: "substring", column: N, start: S, length: L }
+//   - { type: "setQueryParam", column: N, name: P, value: V } sets query parameter P of the URL in
+//     column N, in place of its first occurrence or appended; with sourceColumn: M instead of
+//     value, the parameter takes the cell of column M. Other parameters keep their order.
+//   - { type: "deleteQueryParam", column: N, name: P } removes every occurrence of parameter P
+//   - { type: "setUrlPart", column: N, part: "scheme" | "host" | "path" | "query" | "fragment",
+//     value: V } replaces a component of the URL, or takes it from sourceColumn: M
 //
 // - groupBy: Optional grouping by column: { column: N }
+//   - outputPattern: Stream each group to its own JSON array file instead of returning the rows,
+//     e.g. "out-{key}.json". Characters unsafe in file names are replaced with "_" in the key.
+//   - hashKey: Replace the key with its hex "sha1" or "sha256" digest so identifiers are not
+//     exposed in file names or results (default: none)
+//   - salt: Prefix mixed into the hashed key to prevent dictionary lookups (default: none)
+//
 // - fields: Projection fields:
 //   - { type: "column", column: N } | { type: "fixed", value: V }
-//
-// Returns: Array of arrays containing processed data, grouped if groupBy is specified
+//   - { type: "sourceFile" } projects the path of the file the row came from
+//   - { type: "sourceLine" } projects the line number where the row starts in that file
+//   - { type: "urlPart", column: N, part: "path" } projects a component of the URL in column N:
+//     "scheme", "host", "path" (still escaped), "query" (raw) or "fragment"
+//   - { type: "queryParam", column: N, name: P } projects the first value of query parameter P,
+//     or "" when the URL doesn't have it
+//   - { type: "queryParams", column: N } projects the query as an object; repeated parameters
+//     become arrays
+//
+// Returns: Array of arrays containing processed data, grouped if groupBy is specified. With
+// groupBy.outputPattern, one [key, filePath, rowCount] array per group, sorted by key.
 //
 // Example usage:
 //
@@ -121,19 +238,189 @@ type ProcessCsvOptions struct {
 //		},
 //	}
 //	result, err := streamloader.ProcessCsvFile("data.csv", options)
-func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) ([][]interface{}, error) {
+func (s StreamLoader) ProcessCsvFile(filePath interface{}, options ProcessCsvOptions) ([][]interface{}, error) {
+	// 1) Resolve the input files
+	paths, err := resolveCsvPaths(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse a cached result when the files and options are unchanged. Writing group files is a
+	// side effect the cache can't replay, so those pipelines always run.
+	if options.Cache != "" && (options.GroupBy == nil || options.GroupBy.OutputPattern == "") {
+		return processCsvCached(paths, options, s.call)
+	}
+	return processCsvPaths(paths, options, nil, s.call)
+}
+
+// processCsvPaths runs the ProcessCsvFile pipeline over the resolved input files.
+func processCsvPaths(paths []string, options ProcessCsvOptions, trace *pipelineTrace, call callLimits) ([][]interface{}, error) {
+	var err error
+
+	// 2) Initialize processing state
+	hasGrouping := options.GroupBy != nil
+	var groupMap map[string][][]interface{}
+	var result [][]interface{}
+
+	var groupFiles *groupFileWriter
+
+	if hasGrouping {
+		if err := validateGroupKeyHash(options.GroupBy.HashKey); err != nil {
+			return nil, err
+		}
+		if options.GroupBy.OutputPattern != "" {
+			if groupFiles, err = newGroupFileWriter(options.GroupBy.OutputPattern); err != nil {
+				return nil, err
+			}
+		} else {
+			groupMap = make(map[string][][]interface{})
+		}
+	}
+
+	// Pre-compile regex patterns for performance
+	regexCache := make(map[string]*regexp.Regexp)
+	for _, filter := range options.Filters {
+		if filter.Type == "hashSample" {
+			if filter.Rate == nil {
+				return nil, fmt.Errorf("hashSample filter needs a rate")
+			}
+			if err := validateHashSampleRate(*filter.Rate); err != nil {
+				return nil, err
+			}
+		}
+		if filter.Type == "regexMatch" {
+			compiled, err := regexp.Compile(filter.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern in filter: %w", err)
+			}
+			regexCache[filter.Pattern] = compiled
+		}
+	}
+	for _, transform := range options.Transforms {
+		if err := validateUrlTransform(transform); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range options.Fields {
+		if err := validateUrlField(field); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3) Process the files one after another as a single stream of rows
+	for _, path := range paths {
+		trace.fileStarted()
+		err := processCsvSource(path, options, regexCache, func(row []string, projected []interface{}) error {
+			trace.rowOut()
+			// Handle grouping or direct collection
+			if hasGrouping {
+				if options.GroupBy.Column < len(row) {
+					key := hashGroupKey(row[options.GroupBy.Column], options.GroupBy.HashKey, options.GroupBy.Salt)
+					if groupFiles != nil {
+						// Stream the row straight to its group file
+						return groupFiles.Write(key, projected)
+					}
+					if groupMap[key] == nil {
+						groupMap[key] = make([][]interface{}, 0)
+					}
+					groupMap[key] = append(groupMap[key], projected)
+				}
+			} else {
+				result = append(result, projected)
+			}
+			return nil
+		}, trace, call)
+		if err != nil {
+			if groupFiles != nil {
+				groupFiles.Abort()
+			}
+			if len(paths) > 1 {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return nil, err
+		}
+	}
+
+	// 4) Finalize output
+	if groupFiles != nil {
+		// Report the files written instead of the rows
+		trace.groupsFormed(len(groupFiles.groups))
+		return groupFiles.Close()
+	}
+	if hasGrouping {
+		trace.groupsFormed(len(groupMap))
+		// Convert grouped data to flat arrays
+		groupedResult := make([][]interface{}, 0, len(groupMap))
+		for _, group := range groupMap {
+			// Flatten each group into a single array
+			var flatGroup []interface{}
+			for _, row := range group {
+				flatGroup = append(flatGroup, row...)
+			}
+			groupedResult = append(groupedResult, flatGroup)
+		}
+		return groupedResult, nil
+	}
+
+	return result, nil
+}
+
+// resolveCsvPaths expands the filePath argument of ProcessCsvFile into a list of files. It accepts
+// a single path, a glob pattern, or an array of paths.
+func resolveCsvPaths(filePath interface{}) ([]string, error) {
+	switch v := filePath.(type) {
+	case string:
+		// An existing file wins over glob interpretation, so names containing "[" still work
+		if _, err := statInput(v); err == nil || !strings.ContainsAny(v, "*?[") {
+			return []string{v}, nil
+		}
+		matches, err := filepath.Glob(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern: %w", err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match pattern %q", v)
+		}
+		return matches, nil
+	case []string:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("at least one CSV file is required")
+		}
+		return v, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("at least one CSV file is required")
+		}
+		paths := make([]string, len(v))
+		for i, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid file path at index %d: expected string, got %T", i, item)
+			}
+			paths[i] = path
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("invalid file path argument: expected string or array of strings, got %T", filePath)
+	}
+}
+
+// processCsvSource streams the rows of one CSV file through the filters, transforms and projection
+// of ProcessCsvFile, handing each surviving row to emit. Header handling applies to every file.
+func processCsvSource(filePath string, options ProcessCsvOptions, regexCache map[string]*regexp.Regexp, emit func(row []string, projected []interface{}) error, trace *pipelineTrace, call callLimits) error {
 	// 1) Open file
-	file, err := os.Open(filePath)
+	file, err := openSequential(filePath, options.PageCache, call)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		return fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
 	// 2) Create buffered reader (64 KB) for efficient reading
-	reader := bufio.NewReaderSize(file, 64*1024)
+	reader := bufio.NewReaderSize(file, readBufferSize())
 
-	// 3) Create CSV reader with standard settings
-	csvReader := csv.NewReader(reader)
+	// 3) Create CSV reader with standard settings, stripping any BOM and normalizing line endings
+	normalize := !options.PreserveLineEndings
+	csvReader := csv.NewReader(newLineNormalizer(reader, normalize, normalize))
 
 	// Configure CSV reader for robust parsing
 	csvReader.TrimLeadingSpace = true // Default to true
@@ -148,29 +435,18 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 	if !options.ReuseRecord {    // Only override if explicitly set to false
 		csvReader.ReuseRecord = false
 	}
+	// Skip lines starting with the comment character
+	if err := setCsvComment(csvReader, options.Comment); err != nil {
+		return err
+	}
+	// Use the configured delimiter, or detect it from the extension (.tsv, .psv)
+	if err := setCsvDelimiter(csvReader, options.Delimiter, filePath); err != nil {
+		return err
+	}
 
 	// 4) Initialize processing state
 	var rowIndex int
 	skipHeader := options.SkipHeader
-	hasGrouping := options.GroupBy != nil
-	var groupMap map[string][][]interface{}
-	var result [][]interface{}
-
-	if hasGrouping {
-		groupMap = make(map[string][][]interface{})
-	}
-
-	// Pre-compile regex patterns for performance
-	regexCache := make(map[string]*regexp.Regexp)
-	for _, filter := range options.Filters {
-		if filter.Type == "regexMatch" {
-			compiled, err := regexp.Compile(filter.Pattern)
-			if err != nil {
-				return nil, fmt.Errorf("invalid regex pattern in filter: %w", err)
-			}
-			regexCache[filter.Pattern] = compiled
-		}
-	}
 
 	// 5) Process rows one by one
 	for {
@@ -179,11 +455,26 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse CSV at line %d: %w", rowIndex+1, err)
+			return fmt.Errorf("failed to parse CSV at line %d: %w", rowIndex+1, err)
+		}
+		trace.rowRead()
+
+		// Drop fully blank rows before they are counted
+		if options.SkipBlankRows && isBlankRow(record) {
+			trace.blankRowSkipped()
+			continue
+		}
+
+		// Validate the header against the expected columns before processing any data
+		if rowIndex == 0 && len(options.ExpectHeaders) > 0 {
+			if err := validateCsvHeader(record, options.ExpectHeaders, options.HeaderMatch, options.HeaderCaseInsensitive); err != nil {
+				return err
+			}
 		}
 
 		// Skip header if requested
 		if rowIndex == 0 && skipHeader {
+			trace.headerSkipped()
 			rowIndex++
 			continue
 		}
@@ -204,9 +495,11 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 
 		// Apply filters
 		shouldDrop := false
-		for _, filter := range options.Filters {
+		for i, filter := range options.Filters {
+			stageStart := trace.now()
 			if filter.Column >= len(row) {
 				shouldDrop = true
+				trace.filter(i, stageStart, true)
 				break // Drop the row if column doesn't exist
 			}
 
@@ -232,7 +525,12 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 					// Treat non-numeric values as not satisfying the range
 					shouldDrop = true
 				}
+			case "hashSample":
+				if !hashSampled(cell, *filter.Rate, filter.Salt) {
+					shouldDrop = true
+				}
 			}
+			trace.filter(i, stageStart, shouldDrop)
 			if shouldDrop {
 				break
 			}
@@ -244,10 +542,11 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 		}
 
 		// Apply transforms
-		for _, transform := range options.Transforms {
+		for i, transform := range options.Transforms {
 			if transform.Column >= len(row) {
 				continue // Skip transform if column doesn't exist
 			}
+			stageStart := trace.now()
 
 			switch transform.Type {
 			case "parseInt":
@@ -270,10 +569,14 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 					}
 					row[transform.Column] = str[start:end]
 				}
+			case "setQueryParam", "deleteQueryParam", "setUrlPart":
+				applyUrlTransform(row, transform)
 			}
+			trace.transform(i, stageStart)
 		}
 
 		// Build projected row
+		stageStart := trace.now()
 		var projected []interface{}
 		if len(options.Fields) > 0 {
 			for _, field := range options.Fields {
@@ -286,6 +589,17 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 					}
 				case "fixed":
 					projected = append(projected, field.Value)
+				case "sourceFile":
+					projected = append(projected, filePath)
+				case "sourceLine":
+					line, _ := csvReader.FieldPos(0)
+					projected = append(projected, line)
+				case "urlPart", "queryParam", "queryParams":
+					cell := ""
+					if field.Column < len(row) {
+						cell = row[field.Column]
+					}
+					projected = append(projected, projectUrlField(cell, field))
 				}
 			}
 		} else {
@@ -295,44 +609,25 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 			}
 		}
 
-		// Handle grouping or direct collection
-		if hasGrouping {
-			if options.GroupBy.Column < len(row) {
-				key := row[options.GroupBy.Column]
-				if groupMap[key] == nil {
-					groupMap[key] = make([][]interface{}, 0)
-				}
-				groupMap[key] = append(groupMap[key], projected)
-			}
-		} else {
-			result = append(result, projected)
+		trace.projection(stageStart)
+
+		if err := emit(row, projected); err != nil {
+			return err
 		}
 
 		rowIndex++
 	}
 
-	// 7) Finalize output
-	if hasGrouping {
-		// Convert grouped data to flat arrays
-		groupedResult := make([][]interface{}, 0, len(groupMap))
-		for _, group := range groupMap {
-			// Flatten each group into a single array
-			var flatGroup []interface{}
-			for _, row := range group {
-				flatGroup = append(flatGroup, row...)
-			}
-			groupedResult = append(groupedResult, flatGroup)
-		}
-		return groupedResult, nil
-	}
-
-	return result, nil
+	return nil
 }
 
 // LoadCSV opens the given CSV file and streams its content into a slice of string slices.
 // Each row is represented as []string, and the entire result is [][]string.
 // The function reads the file incrementally to minimize memory usage and avoid spikes.
 // It automatically detects common CSV delimiters and handles quoted fields properly.
+// Files with a ".gz" extension or starting with the gzip magic bytes, such as "data.csv.gz", are
+// decompressed as they are read. filePath may also be an http or https URL, whose response body
+// is streamed through the parser.
 //
 // Options for memory optimization:
 // - Uses buffered reading with configurable buffer size
@@ -356,6 +651,36 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 //   - Reduces memory allocations by reusing the same slice for each record
 //   - Only set to false if you need to retain references to individual records
 //
+// - preserveLineEndings: Disables input normalization (default: false)
+//   - By default a leading UTF-8 BOM is stripped and "\r\n" and lone "\r" are treated as newlines
+//
+// - comment: Skips lines that start with this character, e.g. "#" (default: none)
+//
+// - skipBlankRows: Drops rows whose fields are all empty or whitespace, such as ",," (default: false)
+//   - Completely empty lines are always skipped by the CSV reader
+//
+// - delimiter: Field delimiter, e.g. "\t" or "|" (default: detected from the extension)
+//   - Files ending in .tsv or .tab use a tab, files ending in .psv use "|", others use ","
+//
+// - expectHeaders: Fails fast unless the first row matches these column names (default: none)
+//   - headerMatch: "exact" requires the same columns in the same order, "subset" only requires
+//     the expected columns to be present (default: "exact")
+//   - headerCaseInsensitive: Compares column names case-insensitively (default: false)
+//
+// - pageCache: How the read uses the OS page cache (default: "keep")
+//   - "drop" hints sequential access and drops the pages already read, so loading a huge file
+//     doesn't evict the page cache of other processes on the host (Linux only)
+//   - "direct" bypasses the page cache with direct I/O, falling back to "drop" on file systems
+//     without direct I/O support (Linux only)
+//
+// - allowPartial: Returns the rows read so far if reading or parsing fails (default: false)
+//   - The error is reported through GetPartialErrors, so setup can continue with degraded data
+//   - Errors before the first row still fail the load
+//
+// - headers: Request headers when filePath is a URL, e.g. an Authorization token (default: none)
+//
+// - timeoutMs: Timeout of the request when filePath is a URL, including reading the body (default: 60000)
+//
 // Example usage:
 //
 // With detailed options:
@@ -379,37 +704,100 @@ func (StreamLoader) ProcessCsvFile(filePath string, options ProcessCsvOptions) (
 //	// records[0] contains the first row as []string
 //	// records[1] contains the second row as []string, etc.
 func (s StreamLoader) LoadCSV(filePath string, options ...interface{}) ([][]string, error) {
+	return s.loadDelimited("loadCSV", filePath, "", options...)
+}
+
+// LoadTSV loads a tab-separated file. It is LoadCSV with the delimiter set to a tab, and accepts
+// the same options.
+//
+// Example usage:
+//
+//	records, err := streamloader.LoadTSV("data.tsv")
+func (s StreamLoader) LoadTSV(filePath string, options ...interface{}) ([][]string, error) {
+	return s.loadDelimited("loadTSV", filePath, "\t", options...)
+}
+
+// LoadPSV loads a pipe-separated file. It is LoadCSV with the delimiter set to "|", and accepts
+// the same options.
+//
+// Example usage:
+//
+//	records, err := streamloader.LoadPSV("data.psv")
+func (s StreamLoader) LoadPSV(filePath string, options ...interface{}) ([][]string, error) {
+	return s.loadDelimited("loadPSV", filePath, "|", options...)
+}
+
+// loadDelimited implements LoadCSV, LoadTSV and LoadPSV for the function named function.
+// defaultDelimiter applies unless the options set a delimiter; when both are empty the delimiter
+// is detected from the file extension.
+func (s StreamLoader) loadDelimited(function string, filePath string, defaultDelimiter string, options ...interface{}) ([][]string, error) {
 	// Set defaults
 	isLazyQuotes := true
 	isTrimLeadingSpace := true
 	isTrimSpace := false
 	isReuseRecord := true
+	isPreserveLineEndings := false
+	comment := ""
+	isSkipBlankRows := false
+	var expectHeaders []string
+	headerMatch := ""
+	isHeaderCaseInsensitive := false
+	delimiter := defaultDelimiter
+	pageCache := ""
+	isAllowPartial := false
+	var headers map[string]string
+	var timeoutMs int64
 
 	// Process options if provided
 	if len(options) > 0 {
-		// First try to process as CsvOptions struct
-		if csvOptions, ok := options[0].(CsvOptions); ok {
+		// First try to process as CsvOptions struct, or a script object decoded over the defaults
+		csvOptions, isStruct := options[0].(CsvOptions)
+		if m, ok := options[0].(map[string]interface{}); ok {
+			csvOptions = CsvOptions{LazyQuotes: true, TrimLeadingSpace: true, ReuseRecord: true}
+			if err := convertConfig(m, &csvOptions); err != nil {
+				return nil, fmt.Errorf("invalid CSV options: %w", err)
+			}
+			isStruct = true
+		}
+		if isStruct {
 			isLazyQuotes = csvOptions.LazyQuotes
 			isTrimLeadingSpace = csvOptions.TrimLeadingSpace
 			isTrimSpace = csvOptions.TrimSpace
 			isReuseRecord = csvOptions.ReuseRecord
+			isPreserveLineEndings = csvOptions.PreserveLineEndings
+			comment = csvOptions.Comment
+			isSkipBlankRows = csvOptions.SkipBlankRows
+			expectHeaders = csvOptions.ExpectHeaders
+			headerMatch = csvOptions.HeaderMatch
+			isHeaderCaseInsensitive = csvOptions.HeaderCaseInsensitive
+			if csvOptions.Delimiter != "" {
+				delimiter = csvOptions.Delimiter
+			}
+			pageCache = csvOptions.PageCache
+			isAllowPartial = csvOptions.AllowPartial
+			headers = csvOptions.Headers
+			timeoutMs = csvOptions.TimeoutMs
 		} else if lazyQuotes, ok := options[0].(bool); ok {
 			// Backward compatibility: interpret bool as LazyQuotes
 			isLazyQuotes = lazyQuotes
 		}
 	}
 	// 1) Open file
-	file, err := os.Open(filePath)
+	file, err := s.openLoaderInput(function, filePath, pageCache, headers, timeoutMs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
-	// 2) Create buffered reader (64 KB) for efficient reading
-	reader := bufio.NewReaderSize(file, 64*1024)
+	// 2) Create buffered reader (64 KB) for efficient reading, decompressing gzip
+	reader, err := gunzipInput(bufio.NewReaderSize(file, readBufferSize()), filePath, false, s.call)
+	if err != nil {
+		return nil, err
+	}
 
-	// 3) Create CSV reader with standard settings
-	csvReader := csv.NewReader(reader)
+	// 3) Create CSV reader with standard settings, stripping any BOM and normalizing line endings
+	normalize := !isPreserveLineEndings
+	csvReader := csv.NewReader(newLineNormalizer(reader, normalize, normalize))
 
 	// Configure CSV reader for robust parsing
 	csvReader.TrimLeadingSpace = isTrimLeadingSpace
@@ -418,6 +806,14 @@ func (s StreamLoader) LoadCSV(filePath string, options ...interface{}) ([][]stri
 	csvReader.FieldsPerRecord = -1
 	// Apply ReuseRecord option for memory efficiency
 	csvReader.ReuseRecord = isReuseRecord
+	// Skip lines starting with the comment character
+	if err := setCsvComment(csvReader, comment); err != nil {
+		return nil, err
+	}
+	// Use the configured delimiter, or detect it from the extension (.tsv, .psv)
+	if err := setCsvDelimiter(csvReader, delimiter, filePath); err != nil {
+		return nil, err
+	}
 
 	// 4) Read all records incrementally
 	var records [][]string
@@ -428,7 +824,19 @@ func (s StreamLoader) LoadCSV(filePath string, options ...interface{}) ([][]stri
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse CSV at line %d: %w", len(records)+1, err)
+			return partialOrError(isAllowPartial, "loadCSV", filePath, records, fmt.Errorf("failed to parse CSV at line %d: %w", len(records)+1, err))
+		}
+
+		// Drop fully blank rows if requested
+		if isSkipBlankRows && isBlankRow(record) {
+			continue
+		}
+
+		// Validate the header against the expected columns before reading any data
+		if len(records) == 0 && len(expectHeaders) > 0 {
+			if err := validateCsvHeader(record, expectHeaders, headerMatch, isHeaderCaseInsensitive); err != nil {
+				return nil, err
+			}
 		}
 
 		// Make a copy of the record to avoid memory sharing issues
@@ -455,36 +863,80 @@ func (s StreamLoader) LoadCSV(filePath string, options ...interface{}) ([][]stri
 // 1. JSON array: [{...}, {...}]
 // 2. NDJSON: {...}\n{...}\n
 // 3. JSON object: {"key1": {...}, "key2": {...}} (returned as a map)
-func (StreamLoader) LoadJSON(filePath string) (any, error) {
-	// 1) Open file
-	file, err := os.Open(filePath)
+// Files with a ".gz" extension or starting with the gzip magic bytes, such as "data.json.gz" or
+// "events.ndjson.gz", are decompressed as they are read. filePath may also be an http or https
+// URL, whose response body is streamed through the parser; the format is detected from the
+// extension of the URL's path, as for files.
+//
+// Available options:
+// - detectDuplicateKeys: Fail with the paths of keys repeated within an object (default: false)
+// - preserveLineEndings: Keep a UTF-8 BOM and lone "\r" line endings as-is (default: false)
+// - pageCache: "drop" or "direct" to keep large scans out of the OS page cache on Linux, as in LoadCSV (default: "keep")
+// - allowPartial: If reading or parsing fails after some records of an array or NDJSON file, return those records and report the error through GetPartialErrors (default: false)
+// - decodeFields: Map from field (or dotted path) to its encoding, such as "base64+gzip", decoded in the records of an array or NDJSON file as they are loaded (default: none)
+// - dropExpired: Timestamp field (or dotted path), such as "expireAt", of records to drop from an array or NDJSON file once it is in the past (default: none)
+// - expiryReference: "now" to compare dropExpired with the wall clock, or "testStart" with the start of the test run (default: "now")
+// - objectOrder: For a JSON object file, "keys" to return {keys, data} with the keys in file order alongside the map, or "entries" to return [key, value] pairs in file order; maps enumerate their keys in a different order every run (default: "map")
+// - keyPrefix, keyRegex: For a JSON object file, only load the keys starting with keyPrefix and matching keyRegex; the values of other keys are skipped without being decoded (default: all keys)
+// - maxKeys: For a JSON object file, stop reading once this many keys are loaded (default: 0, no limit)
+// - path: JSONPath of child steps, such as "$.data.items" or "$.pages[*].items[0]", or dotted path, such as "data.items", of the values to return; the rest of the document is skipped without being decoded. A path without wildcards returns its value, and fails if the document doesn't have it; a path with wildcards, or any path in an NDJSON file, returns an array of the matches. Other per-record options apply to the elements of a returned array (default: the whole document)
+// - snapshot: Only read up to the size of the file when it is opened, and drop a last NDJSON line that doesn't parse, so records another process is appending don't cause parse errors; a gzip file being appended to is read up to where its compressed stream is cut off (default: false)
+// - headers: Request headers when filePath is a URL, e.g. an Authorization token (default: none)
+// - timeoutMs: Timeout of the request when filePath is a URL, including reading the body (default: 60000)
+//
+// Example usage:
+//
+//	data, err := streamloader.LoadJSON("data.json", JsonOptions{DetectDuplicateKeys: true})
+//	data, err := streamloader.LoadJSON("recording.json", JsonOptions{DecodeFields: map[string]string{"response.body": "base64+gzip"}})
+//	items, err := streamloader.LoadJSON("export.json", JsonOptions{Path: "$.data.items"})
+//	events, err := streamloader.LoadJSON("events.ndjson", JsonOptions{Snapshot: true})
+//	users, err := streamloader.LoadJSON("https://artifacts.example.com/users.json", JsonOptions{Headers: map[string]string{"Authorization": "Bearer " + token}})
+func (s StreamLoader) LoadJSON(filePath string, options ...JsonOptions) (any, error) {
+	var opts JsonOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	pipeline, err := newRecordPipeline(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateObjectOrder(opts.ObjectOrder); err != nil {
+		return nil, err
+	}
+	keyFilter, err := newObjectKeyFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1) Open file, or request the URL
+	file, err := s.openLoaderInput("loadJSON", filePath, opts.PageCache, opts.Headers, opts.TimeoutMs)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	var src io.Reader = file
+	if opts.Snapshot {
+		if src, err = snapshotReader(file, filePath); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2) Buffered reader (64 KB), decompressing gzip and stripping any BOM and normalizing line endings
+	reader, err := gunzipInput(bufio.NewReaderSize(src, readBufferSize()), filePath, opts.Snapshot, s.call)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.PreserveLineEndings {
+		reader = bufio.NewReaderSize(newLineNormalizer(reader, true, true), readBufferSize())
+	}
 
-	// 2) Buffered reader (64 KB)
-	reader := bufio.NewReaderSize(file, 64*1024)
+	if opts.Path != "" {
+		return loadJSONPath(reader, filePath, opts, pipeline)
+	}
 
 	// 3) NDJSON detection by extension
-	if strings.HasSuffix(strings.ToLower(filepath.Ext(filePath)), ".ndjson") {
-		scanner := bufio.NewScanner(reader)
-		var objects []map[string]any
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
-			var item map[string]any
-			if err := json.Unmarshal([]byte(line), &item); err != nil {
-				return nil, err
-			}
-			objects = append(objects, item)
-		}
-		if err := scanner.Err(); err != nil {
-			return nil, err
-		}
-		return objects, nil
+	if strings.HasSuffix(strings.ToLower(inputExt(trimGzipExt(filePath))), ".ndjson") {
+		return loadNDJSON(reader, filePath, opts, pipeline)
 	}
 
 	// 4) Peek first non-whitespace byte to detect format
@@ -517,84 +969,173 @@ func (StreamLoader) LoadJSON(filePath string) (any, error) {
 		}
 
 		var arr []interface{}
+		read := 0
 		for dec.More() {
 			var item interface{}
-			if err := dec.Decode(&item); err != nil {
-				return nil, err
+			if opts.DetectDuplicateKeys {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return partialOrError(opts.AllowPartial, "loadJSON", filePath, arr, err)
+				}
+				if err := checkDuplicateKeys(raw, fmt.Sprintf("$[%d]", len(arr))); err != nil {
+					return nil, err
+				}
+				if err := json.Unmarshal(raw, &item); err != nil {
+					return nil, err
+				}
+			} else if err := dec.Decode(&item); err != nil {
+				return partialOrError(opts.AllowPartial, "loadJSON", filePath, arr, err)
+			}
+			if read++; read == 1 && isDatasetHeader(item) {
+				continue // Written by the metadata writer option
+			}
+			if keep, err := pipeline.apply(item); err != nil {
+				return nil, fmt.Errorf("record %d: %w", len(arr), err)
+			} else if !keep {
+				continue
 			}
 			arr = append(arr, item)
 		}
 
 		// Consume closing ']'
 		if _, err := dec.Token(); err != nil {
-			return nil, err
+			return partialOrError(opts.AllowPartial, "loadJSON", filePath, arr, err)
 		}
 		return arr, nil
 	case '{':
-		// JSON object format - return as map directly
+		// JSON object format - return as map directly, unless keys are filtered or their order is requested
 		dec := json.NewDecoder(reader)
+		if keyFilter != nil || opts.ObjectOrder == "keys" || opts.ObjectOrder == "entries" {
+			return loadObject(dec, opts, keyFilter)
+		}
 
 		var objMap map[string]any
+		if opts.DetectDuplicateKeys {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if err := checkDuplicateKeys(raw, "$"); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(raw, &objMap); err != nil {
+				return nil, err
+			}
+			return objMap, nil
+		}
 		if err := dec.Decode(&objMap); err != nil {
 			return nil, err
 		}
 		return objMap, nil
 	default:
 		// Newline-delimited JSON (NDJSON) format
-		scanner := bufio.NewScanner(reader)
-		var objects []map[string]any
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
-			}
-			var item map[string]any
-			if err := json.Unmarshal([]byte(line), &item); err != nil {
+		return loadNDJSON(reader, filePath, opts, pipeline)
+	}
+}
+
+// loadNDJSON parses newline-delimited JSON objects, skipping blank lines.
+func loadNDJSON(reader io.Reader, filePath string, opts JsonOptions, pipeline *recordPipeline) ([]map[string]any, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, scannerMaxSize(bufio.MaxScanTokenSize))
+	var objects []map[string]any
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if opts.DetectDuplicateKeys {
+			if err := checkDuplicateKeys([]byte(line), fmt.Sprintf("$[%d]", len(objects))); err != nil {
 				return nil, err
 			}
-			objects = append(objects, item)
 		}
-		if err := scanner.Err(); err != nil {
-			return nil, err
+		var item map[string]any
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			if opts.Snapshot && !scanner.Scan() && scanner.Err() == nil {
+				break // The last line was still being written when the file was opened
+			}
+			return partialOrError(opts.AllowPartial, "loadJSON", filePath, objects, err)
 		}
-		return objects, nil
+		if keep, err := pipeline.apply(item); err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(objects), err)
+		} else if !keep {
+			continue
+		}
+		objects = append(objects, item)
 	}
+	if err := scanner.Err(); err != nil {
+		return partialOrError(opts.AllowPartial, "loadJSON", filePath, objects, err)
+	}
+	return objects, nil
 }
 
 // LoadText opens the given file and reads its entire content into a string.
 // This function is optimized for performance and is suitable for loading moderate-sized text files.
 // It uses os.ReadFile for an efficient single-read operation.
 //
+// filePath may also be an http or https URL, whose response body is returned.
+//
+// Available options (content is returned unchanged by default):
+// - stripBOM: Remove a leading UTF-8 byte order mark (default: false)
+// - normalizeNewlines: Convert "\r\n" and lone "\r" line endings to "\n" (default: false)
+// - headers: Request headers when filePath is a URL (default: none)
+// - timeoutMs: Timeout of the request when filePath is a URL (default: 60000)
+//
 // Example usage:
 //
 //	content, err := streamloader.LoadText("data.txt")
-func (StreamLoader) LoadText(filePath string) (string, error) {
-	bytes, err := os.ReadFile(filePath)
+//	content, err := streamloader.LoadText("windows.txt", TextOptions{StripBOM: true, NormalizeNewlines: true})
+func (s StreamLoader) LoadText(filePath string, options ...TextOptions) (string, error) {
+	if isRemoteURL(filePath) {
+		var opts TextOptions
+		if len(options) > 0 {
+			opts = options[0]
+		}
+		body, err := s.openRemote("loadText", filePath, opts.Headers, opts.TimeoutMs)
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+		return string(normalizeText(data, opts.StripBOM, opts.NormalizeNewlines)), nil
+	}
+	bytes, err := readInputFile(filePath, s.call)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	ioStats.bytesRead.Add(int64(len(bytes)))
+	if len(options) > 0 {
+		bytes = normalizeText(bytes, options[0].StripBOM, options[0].NormalizeNewlines)
+	}
 	return string(bytes), nil
 }
 
 // Head reads the first N lines of a file without loading the entire file into memory.
 // It returns the lines as a single string, with each line separated by a newline character.
 // This is useful for previewing large files without consuming excessive memory.
+// A UTF-8 BOM is stripped and "\r\n" and lone "\r" count as line endings, unless the
+// preserveLineEndings option is set.
 //
 // Example usage:
 //
 //	first10Lines, err := streamloader.Head("large_file.txt", 10)
-func (StreamLoader) Head(filePath string, n int) (string, error) {
+func (s StreamLoader) Head(filePath string, n int, options ...LineOptions) (string, error) {
 	if n <= 0 {
 		return "", nil
 	}
 
-	file, err := os.Open(filePath)
+	file, err := openInput(filePath, s.call)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	// Strip any BOM and treat "\r\n" and lone "\r" as line endings
+	normalize := len(options) == 0 || !options[0].PreserveLineEndings
+	scanner := bufio.NewScanner(newLineNormalizer(bufio.NewReader(file), normalize, normalize))
+	scanner.Buffer(nil, scannerMaxSize(bufio.MaxScanTokenSize))
 	var lines []string
 	for i := 0; i < n && scanner.Scan(); i++ {
 		lines = append(lines, scanner.Text())
@@ -613,42 +1154,162 @@ func (StreamLoader) Head(filePath string, n int) (string, error) {
 
 // Tail reads the last N lines of a file without loading the entire file into memory.
 // It returns the lines as a single string, with each line separated by a newline character.
-// This is useful for previewing the end of large files.
+// This is useful for previewing the end of large files. Line endings are handled as in Head.
 //
 // Example usage:
 //
 //	last10Lines, err := streamloader.Tail("large_file.txt", 10)
-func (StreamLoader) Tail(filePath string, n int) (string, error) {
+func (s StreamLoader) Tail(filePath string, n int, options ...LineOptions) (string, error) {
 	if n <= 0 {
 		return "", nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+	file, err := openInput(filePath, s.call)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Strip any BOM and treat "\r\n" and lone "\r" as line endings
+	normalize := len(options) == 0 || !options[0].PreserveLineEndings
+	scanner := bufio.NewScanner(newLineNormalizer(bufio.NewReader(file), normalize, normalize))
+	scanner.Buffer(nil, scannerMaxSize(bufio.MaxScanTokenSize))
+
+	ringBuffer := ring.New(n)
+	for scanner.Scan() {
+		ringBuffer.Value = scanner.Text()
+		ringBuffer = ringBuffer.Next()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var resultLines []string
+	ringBuffer.Do(func(p interface{}) {
+		if p != nil {
+			resultLines = append(resultLines, p.(string))
+		}
+	})
+
+	return strings.Join(resultLines, "\n"), nil
+}
+
+// setCsvComment configures the CSV reader to skip lines starting with the given character.
+func setCsvComment(csvReader *csv.Reader, comment string) error {
+	if comment == "" {
+		return nil
+	}
+	r, size := utf8.DecodeRuneInString(comment)
+	if size != len(comment) || r == utf8.RuneError {
+		return fmt.Errorf("invalid comment option %q: must be a single character", comment)
+	}
+	csvReader.Comment = r
+	return nil
+}
+
+// setCsvDelimiter configures the field delimiter. Without an explicit delimiter, files ending in
+// .tsv or .tab use a tab and files ending in .psv use "|"; everything else stays comma-separated.
+// Leading-space trimming is disabled for whitespace delimiters, because the CSV reader would
+// otherwise swallow the delimiter of an empty field.
+func setCsvDelimiter(csvReader *csv.Reader, delimiter string, filePath string) error {
+	if delimiter == "" {
+		switch strings.ToLower(inputExt(trimGzipExt(filePath))) {
+		case ".tsv", ".tab":
+			delimiter = "\t"
+		case ".psv":
+			delimiter = "|"
+		default:
+			return nil
+		}
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return fmt.Errorf("invalid delimiter option %q: must be a single character other than a quote or newline", delimiter)
+	}
+	csvReader.Comma = r
+	if unicode.IsSpace(r) {
+		csvReader.TrimLeadingSpace = false
+	}
+	return nil
+}
+
+// validateCsvHeader compares a CSV header row with the expected column names and returns an
+// error describing the differences. Mode "exact" requires the same columns in the same order,
+// "subset" only requires every expected column to be present.
+func validateCsvHeader(header []string, expected []string, mode string, caseInsensitive bool) error {
+	if mode == "" {
+		mode = "exact"
+	}
+	if mode != "exact" && mode != "subset" {
+		return fmt.Errorf("invalid headerMatch option %q: expected \"exact\" or \"subset\"", mode)
+	}
+
+	normalize := func(name string) string {
+		name = strings.TrimSpace(name)
+		if caseInsensitive {
+			return strings.ToLower(name)
+		}
+		return name
+	}
+
+	actual := make(map[string]bool, len(header))
+	for _, name := range header {
+		actual[normalize(name)] = true
+	}
+	wanted := make(map[string]bool, len(expected))
+	for _, name := range expected {
+		wanted[normalize(name)] = true
+	}
+
+	var missing, unexpected []string
+	for _, name := range expected {
+		if !actual[normalize(name)] {
+			missing = append(missing, name)
+		}
+	}
+	if mode == "exact" {
+		for _, name := range header {
+			if !wanted[normalize(name)] {
+				unexpected = append(unexpected, strings.TrimSpace(name))
+			}
+		}
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
 
-	ringBuffer := ring.New(n)
-	for scanner.Scan() {
-		ringBuffer.Value = scanner.Text()
-		ringBuffer = ringBuffer.Next()
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing columns %q", missing))
+	}
+	if len(unexpected) > 0 {
+		problems = append(problems, fmt.Sprintf("unexpected columns %q", unexpected))
+	}
+	if len(problems) == 0 && mode == "exact" {
+		// Same set of columns, check the order
+		for i, name := range expected {
+			if i >= len(header) || normalize(header[i]) != normalize(name) {
+				problems = append(problems, "columns are in a different order")
+				break
+			}
+		}
+		if len(problems) == 0 && len(header) != len(expected) {
+			problems = append(problems, "duplicate columns")
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("failed to read file: %w", err)
+	if len(problems) > 0 {
+		return fmt.Errorf("CSV header mismatch: %s (expected %q, got %q)", strings.Join(problems, "; "), expected, header)
 	}
+	return nil
+}
 
-	var resultLines []string
-	ringBuffer.Do(func(p interface{}) {
-		if p != nil {
-			resultLines = append(resultLines, p.(string))
+// isBlankRow reports whether every field of a CSV record is empty or whitespace.
+func isBlankRow(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
 		}
-	})
-
-	return strings.Join(resultLines, "\n"), nil
+	}
+	return true
 }
 
 // isWhitespace checks for JSON whitespace characters
@@ -656,6 +1317,115 @@ func isWhitespace(b byte) bool {
 	return b == ' ' || b == '\n' || b == '\r' || b == '\t'
 }
 
+// parseJsonWriterOptions resolves the optional writer argument. For backward compatibility a
+// plain number is interpreted as the buffer size; otherwise a JsonWriterOptions struct or a
+// JavaScript object with the same fields is accepted.
+func parseJsonWriterOptions(options []interface{}) (JsonWriterOptions, error) {
+	opts := JsonWriterOptions{BufferSize: writeBufferSize()} // 64KB unless tuned
+	if len(options) == 0 || options[0] == nil {
+		return opts, nil
+	}
+
+	switch v := options[0].(type) {
+	case int:
+		opts.BufferSize = v
+	case int64:
+		opts.BufferSize = int(v)
+	case float64:
+		opts.BufferSize = int(v)
+	case JsonWriterOptions:
+		opts = v
+	case map[string]interface{}:
+		if size, ok := v["bufferSize"]; ok {
+			switch n := size.(type) {
+			case int64:
+				opts.BufferSize = int(n)
+			case float64:
+				opts.BufferSize = int(n)
+			case int:
+				opts.BufferSize = n
+			}
+		}
+		if sortKeys, ok := v["sortKeys"].(bool); ok {
+			opts.SortKeys = sortKeys
+		}
+		if stable, ok := v["stableFormatting"].(bool); ok {
+			opts.StableFormatting = stable
+		}
+		if provenance, ok := v["provenance"].(string); ok {
+			opts.Provenance = provenance
+		}
+		if compression, ok := v["compressOutput"].(string); ok {
+			opts.CompressOutput = compression
+		}
+		if keyEnv, ok := v["encryptOutput"].(string); ok {
+			opts.EncryptOutput = keyEnv
+		}
+		if check, ok := v["checkDiskSpace"].(bool); ok {
+			opts.CheckDiskSpace = check
+		}
+		if metadata, ok := v["metadata"]; ok && metadata != nil {
+			parsed, err := parseDatasetMetadataOptions(metadata)
+			if err != nil {
+				return opts, err
+			}
+			opts.Metadata = parsed
+		}
+		if manifest, ok := v["manifest"].(string); ok {
+			opts.Manifest = manifest
+		}
+	default:
+		return opts, fmt.Errorf("invalid writer options: expected buffer size or options object, got %T", options[0])
+	}
+
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = writeBufferSize()
+	}
+	return opts, nil
+}
+
+// formatJSON rewrites a single encoded JSON value according to the writer options so that the
+// output is byte-stable across runs. sortKeys re-encodes the value with object keys in sorted
+// order, stableFormatting only strips insignificant whitespace and keeps the key order.
+// Numbers are kept exactly as written in both cases.
+func (o JsonWriterOptions) formatJSON(raw []byte) ([]byte, error) {
+	if o.SortKeys {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(buf.Bytes(), "\n"), nil
+	}
+	if o.StableFormatting {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return raw, nil
+}
+
+// formatLine applies formatJSON to a JSON line, skipping the work when no formatting is requested.
+func (o JsonWriterOptions) formatLine(line string) (string, error) {
+	if !o.SortKeys && !o.StableFormatting {
+		return line, nil
+	}
+	formatted, err := o.formatJSON([]byte(line))
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
 // DebugOptions returns the options structure exactly as received for debugging parameter passing
 func (StreamLoader) DebugOptions(options interface{}) interface{} {
 	return options
@@ -672,6 +1442,8 @@ func (StreamLoader) DebugCsvOptions(options ProcessCsvOptions) ProcessCsvOptions
 //
 // Parameters:
 //   - objects: An array of JavaScript objects to convert to JSONL format.
+//   - options: Optional JsonWriterOptions object; sortKeys and stableFormatting produce
+//     byte-stable lines across runs.
 //
 // Returns:
 //   - A string containing the JSONL representation of the objects.
@@ -681,12 +1453,35 @@ func (StreamLoader) DebugCsvOptions(options ProcessCsvOptions) ProcessCsvOptions
 //	objects = [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}]
 //	jsonLines = streamloader.ObjectsToJsonLines(objects)
 //	// jsonLines will be '{"id":1,"name":"Alice"}\n{"id":2,"name":"Bob"}'
-func (StreamLoader) ObjectsToJsonLines(objects []interface{}) (string, error) {
+func (StreamLoader) ObjectsToJsonLines(objects []interface{}, options ...interface{}) (string, error) {
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return "", err
+	}
+
 	var builder strings.Builder
 	encoder := json.NewEncoder(&builder)
 	encoder.SetEscapeHTML(false) // Avoid escaping HTML entities like &, <, >
 
+	// Lines are encoded separately when they need to be canonicalized before being appended
+	var lineBuffer bytes.Buffer
+	lineEncoder := json.NewEncoder(&lineBuffer)
+	lineEncoder.SetEscapeHTML(false)
+
 	for i, obj := range objects {
+		if opts.SortKeys || opts.StableFormatting {
+			lineBuffer.Reset()
+			if err := lineEncoder.Encode(obj); err != nil {
+				return "", fmt.Errorf("failed to encode object at index %d: %w", i, err)
+			}
+			objBytes, err := opts.formatJSON(lineBuffer.Bytes())
+			if err != nil {
+				return "", fmt.Errorf("failed to format object at index %d: %w", i, err)
+			}
+			builder.Write(objBytes)
+			builder.WriteByte('\n')
+			continue
+		}
 		if err := encoder.Encode(obj); err != nil {
 			return "", fmt.Errorf("failed to encode object at index %d: %w", i, err)
 		}
@@ -721,39 +1516,74 @@ func (StreamLoader) ObjectsToJsonLines(objects []interface{}) (string, error) {
 //	compressedJsonLines = streamloader.ObjectsToCompressedJsonLines(objects)
 //	// Returns base64-encoded gzipped JSON lines
 func (s StreamLoader) ObjectsToCompressedJsonLines(objects []interface{}, compressionLevel ...int) (string, error) {
-	// First convert objects to JSON lines
-	jsonLines, err := s.ObjectsToJsonLines(objects)
-	if err != nil {
-		return "", fmt.Errorf("failed to convert objects to JSON lines: %w", err)
+	// Objects are encoded straight into the gzip writer, which writes through the base64 encoder,
+	// so neither the JSONL text nor the gzip data is ever held in memory in full
+	var builder strings.Builder
+	b64Writer := base64.NewEncoder(base64.StdEncoding, &builder)
+	if err := writeCompressedJsonLines(b64Writer, objects, compressionLevel...); err != nil {
+		return "", err
+	}
+	if err := b64Writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode base64 data: %w", err)
+	}
+	return builder.String(), nil
+}
+
+// ObjectsToCompressedJsonLinesBuffer works like ObjectsToCompressedJsonLines but returns the gzip
+// data as an ArrayBuffer instead of a base64 string. It skips the base64 step, which makes the
+// result a quarter smaller, and can be passed to http.post as the request body directly.
+//
+// Example:
+//
+//	body = streamloader.objectsToCompressedJsonLinesBuffer(objects)
+//	http.post(url, body, { headers: { "Content-Encoding": "gzip" } })
+func (s StreamLoader) ObjectsToCompressedJsonLinesBuffer(objects []interface{}, compressionLevel ...int) (interface{}, error) {
+	var compressedBuffer bytes.Buffer
+	if err := writeCompressedJsonLines(&compressedBuffer, objects, compressionLevel...); err != nil {
+		return nil, err
 	}
+	return s.newArrayBuffer(compressedBuffer.Bytes()), nil
+}
 
+// writeCompressedJsonLines gzips the JSONL representation of objects into w, with no newline
+// after the last line, as in ObjectsToJsonLines.
+func writeCompressedJsonLines(w io.Writer, objects []interface{}, compressionLevel ...int) error {
 	// Set default compression level if not provided
 	level := gzip.DefaultCompression
 	if len(compressionLevel) > 0 && compressionLevel[0] >= gzip.NoCompression && compressionLevel[0] <= gzip.BestCompression {
 		level = compressionLevel[0]
 	}
 
-	// Compress the JSON lines with gzip
-	var compressedBuffer bytes.Buffer
-	gzWriter, err := gzip.NewWriterLevel(&compressedBuffer, level)
+	gzWriter, err := gzip.NewWriterLevel(w, level)
 	if err != nil {
-		return "", fmt.Errorf("failed to create gzip writer: %w", err)
+		return fmt.Errorf("failed to create gzip writer: %w", err)
 	}
 
-	// Write the JSON lines to the gzip writer
-	if _, err := gzWriter.Write([]byte(jsonLines)); err != nil {
-		gzWriter.Close()
-		return "", fmt.Errorf("failed to compress data: %w", err)
+	var lineBuffer bytes.Buffer
+	encoder := json.NewEncoder(&lineBuffer)
+	encoder.SetEscapeHTML(false) // Avoid escaping HTML entities like &, <, >
+
+	for i, obj := range objects {
+		lineBuffer.Reset()
+		if i > 0 {
+			lineBuffer.WriteByte('\n')
+		}
+		if err := encoder.Encode(obj); err != nil {
+			gzWriter.Close()
+			return fmt.Errorf("failed to convert objects to JSON lines: failed to encode object at index %d: %w", i, err)
+		}
+		// Drop the encoder's newline; it is written before the next object instead
+		if _, err := gzWriter.Write(lineBuffer.Bytes()[:lineBuffer.Len()-1]); err != nil {
+			gzWriter.Close()
+			return fmt.Errorf("failed to compress data: %w", err)
+		}
 	}
 
 	// Close the gzip writer to flush all data
 	if err := gzWriter.Close(); err != nil {
-		return "", fmt.Errorf("failed to close gzip writer: %w", err)
+		return fmt.Errorf("failed to close gzip writer: %w", err)
 	}
-
-	// Base64 encode the compressed data
-	compressedBase64 := base64.StdEncoding.EncodeToString(compressedBuffer.Bytes())
-	return compressedBase64, nil
+	return nil
 }
 
 // WriteJsonLinesToArrayFile reads JSONL-formatted data (one JSON object per line) and writes it
@@ -763,8 +1593,9 @@ func (s StreamLoader) ObjectsToCompressedJsonLines(objects []interface{}, compre
 // Parameters:
 //   - jsonLines: A string containing JSONL-formatted data, with one JSON object per line.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB). Determines how much data is
-//     buffered before writing to disk.
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
+//     Set compressOutput to "gzip" to write the file gzip-compressed.
 //
 // Returns:
 //   - The count of objects written to the file.
@@ -775,17 +1606,23 @@ func (s StreamLoader) ObjectsToCompressedJsonLines(objects []interface{}, compre
 //	jsonLines := '{"id":1,"name":"Alice"}\n{"id":2,"name":"Bob"}'
 //	count, err := streamloader.WriteJsonLinesToArrayFile(jsonLines, "output.json")
 //	// Will write '[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}]' to output.json
-func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return int64(len(jsonLines)) + 2 }); err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -801,7 +1638,7 @@ func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath s
 	// Process the JSON lines
 	scanner := bufio.NewScanner(strings.NewReader(jsonLines))
 	// For very large lines, increase the scanner buffer size
-	scanner.Buffer(make([]byte, bufSize), 10*bufSize)
+	scanner.Buffer(make([]byte, bufSize), scannerMaxSize(10*bufSize))
 
 	count := 0
 	for scanner.Scan() {
@@ -823,6 +1660,12 @@ func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath s
 			return count, fmt.Errorf("invalid JSON at line %d: %w", count+1, err)
 		}
 
+		// Apply canonical formatting if requested
+		line, err = opts.formatLine(line)
+		if err != nil {
+			return count, fmt.Errorf("failed to format JSON at line %d: %w", count+1, err)
+		}
+
 		// Write the JSON object to the file
 		if _, err := writer.WriteString(line); err != nil {
 			return count, fmt.Errorf("failed to write JSON object: %w", err)
@@ -844,6 +1687,9 @@ func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath s
 	if err := writer.Flush(); err != nil {
 		return count, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return count, err
+	}
 
 	return count, nil
 }
@@ -855,8 +1701,8 @@ func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath s
 // Parameters:
 //   - compressedJsonLines: A base64-encoded string containing gzip-compressed JSONL data.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB). Determines how much data is
-//     buffered before writing to disk.
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
 //
 // Returns:
 //   - The count of objects written to the file.
@@ -867,12 +1713,13 @@ func (StreamLoader) WriteJsonLinesToArrayFile(jsonLines string, outputFilePath s
 //	compressedData := "H4sIAAAAAAAA/6tWSk5OLCpKVbJSMjA2M9RRKsgsVrIyBHITKzNSixQUQPLJ..."
 //	count, err := streamloader.WriteCompressedJsonLinesToArrayFile(compressedData, "output.json")
 //	// Will decompress and write the JSON array to output.json
-func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines string, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines string, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
 	// Decode base64 data
 	compressedData, err := base64.StdEncoding.DecodeString(compressedJsonLines)
@@ -881,16 +1728,21 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 	}
 
 	// Set up the gzip reader to decompress the data
-	gzReader, err := gzip.NewReader(bytes.NewReader(compressedData))
+	gzReader, err := newGzipMembersReader(bytes.NewReader(compressedData))
 	if err != nil {
 		return 0, fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer gzReader.Close()
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return gzipUncompressedSize(compressedData) }); err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -906,7 +1758,7 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 	// Process the decompressed JSON lines
 	scanner := bufio.NewScanner(gzReader)
 	// For very large lines, increase the scanner buffer size
-	scanner.Buffer(make([]byte, bufSize), 10*bufSize)
+	scanner.Buffer(make([]byte, bufSize), scannerMaxSize(10*bufSize))
 
 	count := 0
 	for scanner.Scan() {
@@ -928,6 +1780,12 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 			return count, fmt.Errorf("invalid JSON at line %d: %w", count+1, err)
 		}
 
+		// Apply canonical formatting if requested
+		line, err = opts.formatLine(line)
+		if err != nil {
+			return count, fmt.Errorf("failed to format JSON at line %d: %w", count+1, err)
+		}
+
 		// Write the JSON object to the file
 		if _, err := writer.WriteString(line); err != nil {
 			return count, fmt.Errorf("failed to write JSON object: %w", err)
@@ -949,6 +1807,9 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 	if err := writer.Flush(); err != nil {
 		return count, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return count, err
+	}
 
 	return count, nil
 }
@@ -960,7 +1821,12 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 // Parameters:
 //   - inputFilePaths: An array of paths to JSON array files to combine.
 //   - outputFilePath: The path where the resulting combined JSON array will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output. Its
+//     provenance field names a key added to every object, holding {"file": path, "record": n}
+//     with the source file and the zero-based position of the object in it.
+//     Set compressOutput to "gzip" to write the file gzip-compressed. Its manifest field names
+//     a file recording the shards already combined, so a re-run only appends new shards.
 //
 // Returns:
 //   - The count of objects written to the file.
@@ -970,17 +1836,35 @@ func (StreamLoader) WriteCompressedJsonLinesToArrayFile(compressedJsonLines stri
 //
 //	count, err := streamloader.CombineJsonArrayFiles(["file1.json", "file2.json"], "combined.json")
 //	// Will merge the arrays from file1.json and file2.json into combined.json
-func (StreamLoader) CombineJsonArrayFiles(inputFilePaths []string, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (s StreamLoader) CombineJsonArrayFiles(inputFilePaths []string, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	if opts.Manifest != "" {
+		return s.combineIncremental(inputFilePaths, outputFilePath, opts)
+	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	err = opts.ensureDiskSpace(outputFilePath, func() int64 {
+		var total int64
+		for _, inputPath := range inputFilePaths {
+			if info, err := statInput(inputPath); err == nil {
+				total += info.Size()
+			}
+		}
+		return total
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -995,88 +1879,105 @@ func (StreamLoader) CombineJsonArrayFiles(inputFilePaths []string, outputFilePat
 
 	totalCount := 0
 	for _, inputPath := range inputFilePaths {
-		// Open the input file
-		inputFile, err := os.Open(inputPath)
+		n, err := copyJsonArrayFile(writer, inputPath, opts, totalCount, s.call)
+		totalCount += n
 		if err != nil {
-			return totalCount, fmt.Errorf("failed to open input file %s: %w", inputPath, err)
+			return totalCount, err
 		}
+	}
 
-		// Create a JSON decoder for the input file
-		decoder := json.NewDecoder(bufio.NewReaderSize(inputFile, bufSize))
+	// Write the closing bracket of the JSON array
+	if _, err := writer.WriteString("]"); err != nil {
+		return totalCount, fmt.Errorf("failed to write closing bracket: %w", err)
+	}
 
-		// Read the opening bracket
-		t, err := decoder.Token()
-		if err != nil {
-			inputFile.Close()
-			return totalCount, fmt.Errorf("failed to read opening bracket from %s: %w", inputPath, err)
-		}
-		if delim, ok := t.(json.Delim); !ok || delim != '[' {
-			inputFile.Close()
-			return totalCount, fmt.Errorf("expected opening bracket in %s, got %v", inputPath, t)
-		}
+	// Flush any buffered data to the file
+	if err := writer.Flush(); err != nil {
+		return totalCount, fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return totalCount, err
+	}
 
-		// Process each object in the array
-		fileCount := 0
-		for decoder.More() {
-			// Read the next object
-			var obj json.RawMessage
-			if err := decoder.Decode(&obj); err != nil {
-				inputFile.Close()
-				return totalCount, fmt.Errorf("failed to decode object in %s: %w", inputPath, err)
-			}
+	return totalCount, nil
+}
 
-			// Write comma before object (except for the first object overall)
-			if totalCount > 0 {
-				if _, err := writer.WriteString(","); err != nil {
-					inputFile.Close()
-					return totalCount, fmt.Errorf("failed to write comma separator: %w", err)
-				}
-			}
+// copyJsonArrayFile copies the objects of a JSON array file to the array being written, after
+// written objects, and returns the number copied.
+func copyJsonArrayFile(writer *bufio.Writer, inputPath string, opts JsonWriterOptions, written int, call callLimits) (int, error) {
+	// Open the input file
+	inputFile, err := openInput(inputPath, call)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open input file %s: %w", inputPath, err)
+	}
+	defer inputFile.Close()
 
-			// Write the object
-			if _, err := writer.Write(obj); err != nil {
-				inputFile.Close()
-				return totalCount, fmt.Errorf("failed to write object: %w", err)
-			}
+	// Create a JSON decoder for the input file
+	decoder := json.NewDecoder(bufio.NewReaderSize(inputFile, opts.BufferSize))
 
-			fileCount++
-			totalCount++
+	// Read the opening bracket
+	t, err := decoder.Token()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read opening bracket from %s: %w", inputPath, err)
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != '[' {
+		return 0, fmt.Errorf("expected opening bracket in %s, got %v", inputPath, t)
+	}
 
-			// Periodically flush for very large files
-			if totalCount%1000 == 0 {
-				if err := writer.Flush(); err != nil {
-					inputFile.Close()
-					return totalCount, fmt.Errorf("failed to flush data: %w", err)
-				}
+	// Process each object in the array
+	fileCount := 0
+	for decoder.More() {
+		// Read the next object
+		var obj json.RawMessage
+		if err := decoder.Decode(&obj); err != nil {
+			return fileCount, fmt.Errorf("failed to decode object in %s: %w", inputPath, err)
+		}
+
+		// Record where the object came from if requested
+		if opts.Provenance != "" {
+			if obj, err = addProvenance(obj, opts.Provenance, inputPath, fileCount); err != nil {
+				return fileCount, err
 			}
 		}
 
-		// Read the closing bracket
-		t, err = decoder.Token()
-		if err != nil {
-			inputFile.Close()
-			return totalCount, fmt.Errorf("failed to read closing bracket from %s: %w", inputPath, err)
+		// Apply canonical formatting if requested
+		if opts.SortKeys || opts.StableFormatting {
+			if obj, err = opts.formatJSON(obj); err != nil {
+				return fileCount, fmt.Errorf("failed to format object in %s: %w", inputPath, err)
+			}
 		}
-		if delim, ok := t.(json.Delim); !ok || delim != ']' {
-			inputFile.Close()
-			return totalCount, fmt.Errorf("expected closing bracket in %s, got %v", inputPath, t)
+
+		// Write comma before object (except for the first object overall)
+		if written+fileCount > 0 {
+			if _, err := writer.WriteString(","); err != nil {
+				return fileCount, fmt.Errorf("failed to write comma separator: %w", err)
+			}
 		}
 
-		// Close the input file
-		inputFile.Close()
-	}
+		// Write the object
+		if _, err := writer.Write(obj); err != nil {
+			return fileCount, fmt.Errorf("failed to write object: %w", err)
+		}
 
-	// Write the closing bracket of the JSON array
-	if _, err := writer.WriteString("]"); err != nil {
-		return totalCount, fmt.Errorf("failed to write closing bracket: %w", err)
-	}
+		fileCount++
 
-	// Flush any buffered data to the file
-	if err := writer.Flush(); err != nil {
-		return totalCount, fmt.Errorf("failed to flush data to file: %w", err)
+		// Periodically flush for very large files
+		if (written+fileCount)%flushEveryN() == 0 {
+			if err := writer.Flush(); err != nil {
+				return fileCount, fmt.Errorf("failed to flush data: %w", err)
+			}
+		}
 	}
 
-	return totalCount, nil
+	// Read the closing bracket
+	t, err = decoder.Token()
+	if err != nil {
+		return fileCount, fmt.Errorf("failed to read closing bracket from %s: %w", inputPath, err)
+	}
+	if delim, ok := t.(json.Delim); !ok || delim != ']' {
+		return fileCount, fmt.Errorf("expected closing bracket in %s, got %v", inputPath, t)
+	}
+	return fileCount, nil
 }
 
 // WriteObjectsToJsonArrayFile writes a slice of JavaScript objects directly to a JSON array file.
@@ -1086,7 +1987,9 @@ func (StreamLoader) CombineJsonArrayFiles(inputFilePaths []string, outputFilePat
 // Parameters:
 //   - objects: An array of JavaScript objects to write to the file.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
+//     Set compressOutput to "gzip" to write the file gzip-compressed.
 //
 // Returns:
 //   - The count of objects written to the file.
@@ -1097,17 +2000,23 @@ func (StreamLoader) CombineJsonArrayFiles(inputFilePaths []string, outputFilePat
 //	objects := [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}]
 //	count, err := streamloader.WriteObjectsToJsonArrayFile(objects, "output.json")
 //	// Will write '[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}]' to output.json
-func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return estimateObjectsSize(objects) }); err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -1136,6 +2045,11 @@ func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputF
 			return count, fmt.Errorf("failed to encode object at index %d: %w", i, err)
 		}
 
+		// Apply canonical formatting if requested
+		if objBytes, err = opts.formatJSON(objBytes); err != nil {
+			return count, fmt.Errorf("failed to format object at index %d: %w", i, err)
+		}
+
 		// Write the object
 		if _, err := writer.Write(objBytes); err != nil {
 			return count, fmt.Errorf("failed to write object: %w", err)
@@ -1144,7 +2058,7 @@ func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputF
 		count++
 
 		// Periodically flush for very large datasets
-		if count%1000 == 0 {
+		if count%flushEveryN() == 0 {
 			if err := writer.Flush(); err != nil {
 				return count, fmt.Errorf("failed to flush data: %w", err)
 			}
@@ -1160,19 +2074,23 @@ func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputF
 	if err := writer.Flush(); err != nil {
 		return count, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return count, err
+	}
 
 	return count, nil
 }
 
-// WriteCompressedObjectsToJsonArrayFile writes a slice of JavaScript objects to a JSON array file
-// using compression for memory efficiency. The objects are first converted to JSONL format,
-// then compressed with gzip, and finally streamed to the output file.
+// WriteCompressedObjectsToJsonArrayFile writes a slice of JavaScript objects to a JSON array file.
+// Objects are serialized straight to the output file one at a time, without building the whole
+// array in memory. If outputFilePath ends with ".gz", the file is written gzip-compressed.
 //
 // Parameters:
 //   - objects: An array of JavaScript objects to write to the file.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - compressionLevel: Optional compression level (0-9, default is gzip.DefaultCompression).
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional compression level for ".gz" files (0-9, default is gzip.DefaultCompression),
+//     or a JsonWriterOptions object as for WriteObjectsToJsonArrayFile. A compressOutput set in
+//     the options takes precedence over the ".gz" extension.
 //
 // Returns:
 //   - The count of objects written to the file.
@@ -1181,23 +2099,82 @@ func (s StreamLoader) WriteObjectsToJsonArrayFile(objects []interface{}, outputF
 // Example:
 //
 //	objects := [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}]
-//	count, err := streamloader.WriteCompressedObjectsToJsonArrayFile(objects, "output.json")
-//	// Will write a JSON array with the objects to output.json, using compression for efficiency
-func (s StreamLoader) WriteCompressedObjectsToJsonArrayFile(objects []interface{}, outputFilePath string, compressionLevel ...int) (int, error) {
-	// Get compression level, if provided
+//	count, err := streamloader.WriteCompressedObjectsToJsonArrayFile(objects, "output.json.gz")
+//	// Will write a gzip-compressed JSON array with the objects to output.json.gz
+func (s StreamLoader) WriteCompressedObjectsToJsonArrayFile(objects []interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the compression level)
 	level := gzip.DefaultCompression
-	if len(compressionLevel) > 0 && compressionLevel[0] >= gzip.NoCompression && compressionLevel[0] <= gzip.BestCompression {
-		level = compressionLevel[0]
+	opts := JsonWriterOptions{BufferSize: writeBufferSize()}
+	var err error
+	if len(options) > 0 {
+		n := level
+		switch v := options[0].(type) {
+		case int:
+			n = v
+		case int64:
+			n = int(v)
+		case float64:
+			n = int(v)
+		default:
+			if opts, err = parseJsonWriterOptions(options); err != nil {
+				return 0, err
+			}
+		}
+		if n >= gzip.NoCompression && n <= gzip.BestCompression {
+			level = n
+		}
+	}
+	if opts.CompressOutput == "" && strings.HasSuffix(outputFilePath, ".gz") {
+		opts.CompressOutput = "gzip"
 	}
 
-	// First compress the objects to JSONL format
-	compressedData, err := s.ObjectsToCompressedJsonLines(objects, level)
+	// Fail early if the output won't fit
+	if err := opts.ensureDiskSpace(outputFilePath, func() int64 { return estimateObjectsSize(objects) }); err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file
+	file, err := createOutputFile(outputFilePath, opts, level)
 	if err != nil {
-		return 0, fmt.Errorf("failed to compress objects: %w", err)
+		return 0, err
+	}
+	defer file.Close()
+	writer := bufio.NewWriterSize(file, opts.BufferSize)
+
+	var lineBuffer bytes.Buffer
+	encoder := json.NewEncoder(&lineBuffer)
+	encoder.SetEscapeHTML(false) // Avoid escaping HTML entities like &, <, >
+
+	writer.WriteByte('[')
+	count := 0
+	for i, obj := range objects {
+		lineBuffer.Reset()
+		if err := encoder.Encode(obj); err != nil {
+			return count, fmt.Errorf("failed to encode object at index %d: %w", i, err)
+		}
+		if i > 0 {
+			writer.WriteByte(',')
+		}
+		// Drop the newline the encoder appends
+		objBytes, err := opts.formatJSON(lineBuffer.Bytes()[:lineBuffer.Len()-1])
+		if err != nil {
+			return count, fmt.Errorf("failed to format object at index %d: %w", i, err)
+		}
+		if _, err := writer.Write(objBytes); err != nil {
+			return count, fmt.Errorf("failed to write object: %w", err)
+		}
+		count++
 	}
+	writer.WriteByte(']')
 
-	// Then write the compressed data to the output file as a JSON array
-	return s.WriteCompressedJsonLinesToArrayFile(compressedData, outputFilePath)
+	// Flush any buffered data to the file
+	if err := writer.Flush(); err != nil {
+		return count, fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return count, err
+	}
+	return count, nil
 }
 
 // WriteMultipleCompressedJsonLinesToArrayFile takes multiple compressed JSON lines strings,
@@ -1207,7 +2184,8 @@ func (s StreamLoader) WriteCompressedObjectsToJsonArrayFile(objects []interface{
 // Parameters:
 //   - compressedJsonLinesArray: An array of base64-encoded, gzip-compressed JSONL strings.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
 //
 // Returns:
 //   - The total count of objects written to the file.
@@ -1220,17 +2198,32 @@ func (s StreamLoader) WriteCompressedObjectsToJsonArrayFile(objects []interface{
 //	count, err := streamloader.WriteMultipleCompressedJsonLinesToArrayFile(
 //	    []string{compressedBatch1, compressedBatch2}, "combined.json")
 //	// Will write a single combined JSON array to combined.json
-func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLinesArray []string, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLinesArray []string, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	err = opts.ensureDiskSpace(outputFilePath, func() int64 {
+		var total int64
+		for _, compressedJsonLines := range compressedJsonLinesArray {
+			if data, err := base64.StdEncoding.DecodeString(compressedJsonLines); err == nil {
+				total += gzipUncompressedSize(data)
+			}
+		}
+		return total
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
+	if err != nil {
+		return 0, err
 	}
 	defer file.Close()
 
@@ -1259,7 +2252,7 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 		}
 
 		// Set up the gzip reader to decompress the data
-		gzReader, err := gzip.NewReader(bytes.NewReader(compressedData))
+		gzReader, err := newGzipMembersReader(bytes.NewReader(compressedData))
 		if err != nil {
 			return totalCount, fmt.Errorf("failed to create gzip reader at index %d: %w", compressedIndex, err)
 		}
@@ -1267,7 +2260,7 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 		// Process the decompressed JSON lines
 		scanner := bufio.NewScanner(gzReader)
 		// For very large lines, increase the scanner buffer size
-		scanner.Buffer(make([]byte, bufSize), 10*bufSize)
+		scanner.Buffer(make([]byte, bufSize), scannerMaxSize(10*bufSize))
 
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
@@ -1285,6 +2278,13 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 				isFirstObject = false
 			}
 
+			// Apply canonical formatting if requested
+			line, err = opts.formatLine(line)
+			if err != nil {
+				gzReader.Close()
+				return totalCount, fmt.Errorf("invalid JSON at index %d: %w", compressedIndex, err)
+			}
+
 			// Write the JSON object to the file
 			if _, err := writer.WriteString(line); err != nil {
 				gzReader.Close()
@@ -1312,6 +2312,9 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 	if err := writer.Flush(); err != nil {
 		return totalCount, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return totalCount, err
+	}
 
 	return totalCount, nil
 }
@@ -1328,7 +2331,8 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 //       - If actual count > weight: slice to keep only `weight` objects
 //       - If actual count < weight: duplicate objects cyclically until count == weight
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
 //
 // Returns:
 //   - The total count of objects written to the file.
@@ -1344,17 +2348,45 @@ func (StreamLoader) WriteMultipleCompressedJsonLinesToArrayFile(compressedJsonLi
 //	}
 //	count, err := streamloader.WriteWeightedMultipleCompressedJsonLinesToArrayFile(
 //	    weightedBatches, "weighted_output.json")
-func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weightedMultipleCompressedJsonLinesArray [][]interface{}, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weightedMultipleCompressedJsonLinesArray [][]interface{}, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	err = opts.ensureDiskSpace(outputFilePath, func() int64 {
+		var total int64
+		for _, weightedEntry := range weightedMultipleCompressedJsonLinesArray {
+			if len(weightedEntry) == 0 {
+				continue
+			}
+			group, _ := weightedEntry[0].([]interface{})
+			if items, ok := weightedEntry[0].([]string); ok {
+				for _, str := range items {
+					group = append(group, str)
+				}
+			}
+			for _, item := range group {
+				if str, ok := item.(string); ok {
+					if data, err := base64.StdEncoding.DecodeString(str); err == nil {
+						total += gzipUncompressedSize(data)
+					}
+				}
+			}
+		}
+		return total
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -1433,20 +2465,26 @@ func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weighted
 			}
 
 			// Set up the gzip reader to decompress the data
-			gzReader, err := gzip.NewReader(bytes.NewReader(compressedData))
+			gzReader, err := newGzipMembersReader(bytes.NewReader(compressedData))
 			if err != nil {
 				return totalCount, fmt.Errorf("failed to create gzip reader at group %d, compressed %d: %w", groupIndex, compressedIndex, err)
 			}
 
 			// Process the decompressed JSON lines
 			scanner := bufio.NewScanner(gzReader)
-			scanner.Buffer(make([]byte, bufSize), 10*bufSize)
+			scanner.Buffer(make([]byte, bufSize), scannerMaxSize(10*bufSize))
 
 			for scanner.Scan() {
 				line := strings.TrimSpace(scanner.Text())
 				if line == "" {
 					continue // Skip empty lines
 				}
+				// Apply canonical formatting if requested
+				line, err = opts.formatLine(line)
+				if err != nil {
+					gzReader.Close()
+					return totalCount, fmt.Errorf("invalid JSON at group %d, compressed %d: %w", groupIndex, compressedIndex, err)
+				}
 				allJsonLines = append(allJsonLines, line)
 			}
 
@@ -1506,6 +2544,9 @@ func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weighted
 	if err := writer.Flush(); err != nil {
 		return totalCount, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return totalCount, err
+	}
 
 	return totalCount, nil
 }
@@ -1516,7 +2557,8 @@ func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weighted
 // Parameters:
 //   - jsonLinesArray: An array of strings containing JSONL-formatted data.
 //   - outputFilePath: The path where the resulting JSON array file will be written.
-//   - bufferSize: Optional buffer size in bytes (default: 64KB).
+//   - options: Optional buffer size in bytes (default: 64KB), or a JsonWriterOptions object
+//     with bufferSize, sortKeys and stableFormatting fields for byte-stable output.
 //
 // Returns:
 //   - The total count of objects written to the file.
@@ -1530,17 +2572,30 @@ func (StreamLoader) WriteWeightedMultipleCompressedJsonLinesToArrayFile(weighted
 //	    []string{batch1, batch2}, "combined.json")
 //	// Will write '[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"},{"id":3,"name":"Charlie"},{"id":4,"name":"Dave"}]'
 //	// to combined.json
-func (StreamLoader) WriteMultipleJsonLinesToArrayFile(jsonLinesArray []string, outputFilePath string, bufferSize ...int) (int, error) {
-	// Set default buffer size if not provided
-	bufSize := 64 * 1024 // 64KB default
-	if len(bufferSize) > 0 && bufferSize[0] > 0 {
-		bufSize = bufferSize[0]
+func (StreamLoader) WriteMultipleJsonLinesToArrayFile(jsonLinesArray []string, outputFilePath string, options ...interface{}) (int, error) {
+	// Resolve writer options (a plain number is treated as the buffer size)
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
 	}
+	bufSize := opts.BufferSize
 
-	// Create or truncate the output file
-	file, err := os.Create(outputFilePath)
+	// Fail early if the output won't fit
+	err = opts.ensureDiskSpace(outputFilePath, func() int64 {
+		var total int64
+		for _, jsonLines := range jsonLinesArray {
+			total += int64(len(jsonLines))
+		}
+		return total
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Create or truncate the output file, compressing it if requested
+	file, err := createOutputFile(outputFilePath, opts, gzip.DefaultCompression)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create output file: %w", err)
+		return 0, err
 	}
 	defer file.Close()
 
@@ -1565,7 +2620,7 @@ func (StreamLoader) WriteMultipleJsonLinesToArrayFile(jsonLinesArray []string, o
 		// Process the JSON lines
 		scanner := bufio.NewScanner(strings.NewReader(jsonLines))
 		// For very large lines, increase the scanner buffer size
-		scanner.Buffer(make([]byte, bufSize), 10*bufSize)
+		scanner.Buffer(make([]byte, bufSize), scannerMaxSize(10*bufSize))
 
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
@@ -1587,6 +2642,12 @@ func (StreamLoader) WriteMultipleJsonLinesToArrayFile(jsonLinesArray []string, o
 			if err := json.Unmarshal([]byte(line), &obj); err != nil {
 				return totalCount, fmt.Errorf("invalid JSON at batch %d: %w", batchIndex, err)
 			}
+
+			// Apply canonical formatting if requested
+			line, err = opts.formatLine(line)
+			if err != nil {
+				return totalCount, fmt.Errorf("failed to format JSON at batch %d: %w", batchIndex, err)
+			}
 			
 			// Write the JSON object to the file
 			if _, err := writer.WriteString(line); err != nil {
@@ -1610,6 +2671,9 @@ func (StreamLoader) WriteMultipleJsonLinesToArrayFile(jsonLinesArray []string, o
 	if err := writer.Flush(); err != nil {
 		return totalCount, fmt.Errorf("failed to flush data to file: %w", err)
 	}
+	if err := file.Close(); err != nil {
+		return totalCount, err
+	}
 
 	return totalCount, nil
 }
@@ -1637,6 +2701,7 @@ func (StreamLoader) JsonLinesToObjects(jsonLines string) ([]interface{}, error)
 
 	var objects []interface{}
 	scanner := bufio.NewScanner(strings.NewReader(jsonLines))
+	scanner.Buffer(nil, scannerMaxSize(bufio.MaxScanTokenSize))
 	
 	lineNum := 0
 	for scanner.Scan() {
@@ -1684,7 +2749,7 @@ func (s StreamLoader) CompressedJsonLinesToObjects(compressedJsonLines string) (
 	}
 
 	// Set up the gzip reader to decompress the data
-	gzReader, err := gzip.NewReader(bytes.NewReader(compressedData))
+	gzReader, err := newGzipMembersReader(bytes.NewReader(compressedData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
 	}
@@ -1736,7 +2801,7 @@ func (s StreamLoader) MultipleCompressedJsonLinesToObjects(compressedJsonLinesAr
 		}
 
 		// Set up the gzip reader to decompress the data
-		gzReader, err := gzip.NewReader(bytes.NewReader(compressedData))
+		gzReader, err := newGzipMembersReader(bytes.NewReader(compressedData))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create gzip reader at index %d: %w", compressedIndex, err)
 		}
@@ -1750,6 +2815,7 @@ func (s StreamLoader) MultipleCompressedJsonLinesToObjects(compressedJsonLinesAr
 
 		// Parse the decompressed JSONL data line by line
 		scanner := bufio.NewScanner(strings.NewReader(string(decompressed)))
+		scanner.Buffer(nil, scannerMaxSize(bufio.MaxScanTokenSize))
 		lineNum := 0
 		for scanner.Scan() {
 			lineNum++
@@ -1775,5 +2841,5 @@ func (s StreamLoader) MultipleCompressedJsonLinesToObjects(compressedJsonLinesAr
 }
 
 func init() {
-	modules.Register("k6/x/streamloader", new(StreamLoader))
+	modules.Register("k6/x/streamloader", new(RootModule))
 }
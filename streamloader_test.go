@@ -3,6 +3,7 @@ package streamloader
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -2376,3 +2377,62 @@ line",400
 		}
 	})
 }
+
+func TestProcessCsvFile_MultipleFiles(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders-2026-01.csv": "id,amount\n1,10\n2,20\n",
+		"orders-2026-02.csv": "id,amount\n3,30\n",
+		"notes.csv":          "id,amount\n9,90\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	jan := filepath.Join(dir, "orders-2026-01.csv")
+	feb := filepath.Join(dir, "orders-2026-02.csv")
+
+	options := ProcessCsvOptions{
+		SkipHeader: true,
+		Fields: []FieldConfig{
+			{Type: "column", Column: 0},
+			{Type: "sourceFile"},
+		},
+	}
+	expected := fmt.Sprint([][]interface{}{{"1", jan}, {"2", jan}, {"3", feb}})
+
+	tests := []struct {
+		name     string
+		filePath interface{}
+	}{
+		{"glob", filepath.Join(dir, "orders-*.csv")},
+		{"string array", []string{jan, feb}},
+		{"JS array", []interface{}{jan, feb}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := loader.ProcessCsvFile(tt.filePath, options)
+			if err != nil {
+				t.Fatalf("ProcessCsvFile() error = %v", err)
+			}
+			if got := fmt.Sprint(result); got != expected {
+				t.Errorf("got %s, want %s", got, expected)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := loader.ProcessCsvFile(filepath.Join(dir, "missing-*.csv"), options); err == nil {
+			t.Error("Expected error when the glob matches nothing")
+		}
+		if _, err := loader.ProcessCsvFile([]interface{}{jan, 42}, options); err == nil {
+			t.Error("Expected error for a non-string path")
+		}
+		_, err := loader.ProcessCsvFile([]string{jan, filepath.Join(dir, "gone.csv")}, options)
+		if err == nil || !strings.Contains(err.Error(), "gone.csv") {
+			t.Errorf("Expected error naming the missing file, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+// summary.go
+package streamloader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// WriteSummaryJson writes the data of k6's handleSummary, or any other large object, to a JSON
+// file by streaming it value by value, without building the whole JSON text in memory as
+// JSON.stringify does; for summaries of big tests with many tagged sub-metrics this avoids
+// holding the summary both as an object and as a giant string at the end of the test. Object
+// keys are written in sorted order, so summaries of different runs can be diffed, and NaN and
+// infinite numbers are written as null, as JSON.stringify does.
+//
+// Options are the JSON writer options, such as compressOutput and encryptOutput, plus:
+//   - keepPrevious: Rotate an existing file to outputFilePath.1, .2 and so on, keeping this
+//     many previous summaries (default: 0, overwrite it)
+//
+// Returns: The number of bytes written, before compression
+//
+// Example usage:
+//
+//	export function handleSummary(data) {
+//		streamloader.writeSummaryJson(data, "summary.json.gz", { compressOutput: "gzip", keepPrevious: 5 });
+//		return { stdout: textSummary(data) };
+//	}
+func (StreamLoader) WriteSummaryJson(data interface{}, outputFilePath string, options ...interface{}) (int64, error) {
+	opts, err := parseJsonWriterOptions(options)
+	if err != nil {
+		return 0, err
+	}
+	keepPrevious := 0
+	if len(options) > 0 {
+		if m, ok := options[0].(map[string]interface{}); ok {
+			if n, ok := numberValue(m["keepPrevious"]); ok {
+				keepPrevious = int(n)
+			}
+		}
+	}
+	if keepPrevious < 0 {
+		return 0, fmt.Errorf("keepPrevious must not be negative, got %d", keepPrevious)
+	}
+
+	// The previous summary is only rotated once the options are valid and the path is ours
+	file, err := createOutputFileAfter(outputFilePath, opts, gzip.DefaultCompression, func() error {
+		return rotateFile(outputFilePath, keepPrevious)
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	counter := &byteCounter{w: file}
+	writer := bufio.NewWriterSize(counter, opts.BufferSize)
+	encoder := &summaryEncoder{w: writer}
+	encoder.scalar = json.NewEncoder(&encoder.buf)
+	encoder.scalar.SetEscapeHTML(false)
+	if err := encoder.encode(data); err != nil {
+		return counter.n, fmt.Errorf("failed to write summary: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return counter.n, fmt.Errorf("failed to flush data to file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+// rotateFile renames an existing file to path.1, shifting older copies up to path.keep and
+// dropping the oldest. With keep 0 nothing is rotated.
+func rotateFile(path string, keep int) error {
+	if keep == 0 {
+		return nil
+	}
+	if err := checkWritable(path); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Remove(path + "." + strconv.Itoa(keep)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate %s: %w", path, err)
+	}
+	for i := keep - 1; i >= 1; i-- {
+		older := path + "." + strconv.Itoa(i)
+		if err := os.Rename(older, path+"."+strconv.Itoa(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate %s: %w", older, err)
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", path, err)
+	}
+	return nil
+}
+
+// byteCounter counts the bytes written through it.
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// summaryEncoder streams a value decoded from JavaScript as JSON, writing objects and arrays
+// element by element and only encoding scalars in memory.
+type summaryEncoder struct {
+	w      *bufio.Writer
+	buf    bytes.Buffer
+	scalar *json.Encoder // Writes to buf
+}
+
+func (e *summaryEncoder) encode(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		_, err := e.w.WriteString("null")
+		return err
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.w.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			if err := e.encodeScalar(key); err != nil {
+				return err
+			}
+			e.w.WriteByte(':')
+			if err := e.encode(v[key]); err != nil {
+				return err
+			}
+		}
+		return e.w.WriteByte('}')
+	case []interface{}:
+		e.w.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+		return e.w.WriteByte(']')
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			_, err := e.w.WriteString("null")
+			return err
+		}
+	}
+	return e.encodeScalar(v)
+}
+
+// encodeScalar encodes any other value with encoding/json.
+func (e *summaryEncoder) encodeScalar(v interface{}) error {
+	e.buf.Reset()
+	if err := e.scalar.Encode(v); err != nil {
+		return err
+	}
+	_, err := e.w.Write(bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")))
+	return err
+}
+
+// LoadSummaryJson reads a summary written by WriteSummaryJson, or by k6's --summary-export or
+// handleSummary, for example to compare a run with a baseline. Gzip-compressed files are
+// decompressed, whatever their name.
+//
+// Example usage:
+//
+//	const baseline = streamloader.loadSummaryJson("baseline-summary.json.gz");
+//	const p95 = baseline.metrics.http_req_duration.values["p(95)"];
+func (s StreamLoader) LoadSummaryJson(filePath string) (any, error) {
+	reader, err := openSequential(filePath, pageCacheKeep, s.call)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer reader.Close()
+
+	buffered := bufio.NewReaderSize(reader, readBufferSize())
+	var source io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	var summary any
+	decoder := json.NewDecoder(source)
+	if err := decoder.Decode(&summary); err != nil {
+		return nil, fmt.Errorf("failed to decode summary %s: %w", filePath, err)
+	}
+	return summary, nil
+}
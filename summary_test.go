@@ -0,0 +1,94 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testSummary() map[string]interface{} {
+	return map[string]interface{}{
+		"state": map[string]interface{}{"testRunDurationMs": 61234.5, "isStdOutTTY": false},
+		"metrics": map[string]interface{}{
+			"http_req_duration{endpoint:<checkout>}": map[string]interface{}{
+				"type":   "trend",
+				"values": map[string]interface{}{"p(95)": 182.25, "max": math.Inf(1), "avg": math.NaN()},
+			},
+			"checks": map[string]interface{}{"values": map[string]interface{}{"passes": int64(120), "fails": int64(0)}},
+		},
+		"root_group": map[string]interface{}{"groups": []interface{}{}, "checks": []interface{}{map[string]interface{}{"name": "ok & fast", "passes": int64(3)}}},
+		"setup_data": nil,
+	}
+}
+
+func TestWriteSummaryJson(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	loader := StreamLoader{}
+
+	n, err := loader.WriteSummaryJson(testSummary(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `{"metrics":{"checks":{"values":{"fails":0,"passes":120}},"http_req_duration{endpoint:<checkout>}":{"type":"trend","values":{"avg":null,"max":null,"p(95)":182.25}}},` +
+		`"root_group":{"checks":[{"name":"ok & fast","passes":3}],"groups":[]},"setup_data":null,"state":{"isStdOutTTY":false,"testRunDurationMs":61234.5}}`
+	if string(data) != want {
+		t.Errorf("file = %s\nwant   %s", data, want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("WriteSummaryJson() = %d, want %d", n, len(want))
+	}
+
+	loaded, err := loader.LoadSummaryJson(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.(map[string]interface{})["state"]; !reflect.DeepEqual(got, map[string]interface{}{"isStdOutTTY": false, "testRunDurationMs": 61234.5}) {
+		t.Errorf("LoadSummaryJson() state = %v", got)
+	}
+}
+
+func TestWriteSummaryJsonCompressedAndRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "summary.json.gz")
+	loader := StreamLoader{}
+	options := map[string]interface{}{"compressOutput": "gzip", "keepPrevious": int64(2)}
+
+	for run := int64(1); run <= 4; run++ {
+		if _, err := loader.WriteSummaryJson(map[string]interface{}{"run": run}, path, options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The latest run and the two before it are kept
+	for suffix, want := range map[string]float64{"": 4, ".1": 3, ".2": 2} {
+		summary, err := loader.LoadSummaryJson(path + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := summary.(map[string]interface{})["run"]; got != want {
+			t.Errorf("summary%s run = %v, want %v", suffix, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("summary.3 exists: %v", err)
+	}
+
+	if _, err := loader.WriteSummaryJson(nil, path, map[string]interface{}{"keepPrevious": -1}); err == nil {
+		t.Error("expected an error for a negative keepPrevious")
+	}
+	// Invalid options fail before the previous summaries are rotated
+	if _, err := loader.WriteSummaryJson(nil, path, map[string]interface{}{"compressOutput": "brotli", "keepPrevious": int64(2)}); err == nil {
+		t.Error("expected an error for an unknown compression")
+	}
+	if summary, err := loader.LoadSummaryJson(path); err != nil || summary.(map[string]interface{})["run"] != float64(4) {
+		t.Errorf("latest summary after a failed write = %v, %v", summary, err)
+	}
+	if _, err := loader.WriteSummaryJson(map[string]interface{}{"f": func() {}}, filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("expected an error for a value that can't be encoded")
+	}
+	if _, err := loader.LoadSummaryJson(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,216 @@
+// think_times.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ThinkTimeOptions configures ComputeThinkTimes
+type ThinkTimeOptions struct {
+	StateField    string  `json:"stateField" js:"stateField"`
+	MaxGapSeconds float64 `json:"maxGapSeconds" js:"maxGapSeconds"`
+	Seed          int64   `json:"seed" js:"seed"`
+}
+
+// ThinkTimeSummary describes the think times of a transition, in seconds
+type ThinkTimeSummary struct {
+	Count int     `json:"count" js:"count"`
+	Min   float64 `json:"min" js:"min"`
+	Max   float64 `json:"max" js:"max"`
+	Mean  float64 `json:"mean" js:"mean"`
+	P50   float64 `json:"p50" js:"p50"`
+	P90   float64 `json:"p90" js:"p90"`
+	P95   float64 `json:"p95" js:"p95"`
+	P99   float64 `json:"p99" js:"p99"`
+}
+
+// allTransitions is the key of the think times of all transitions.
+const allTransitions = "*"
+
+// ThinkTimes holds the recorded pauses between the requests of sessions and draws from them
+type ThinkTimes struct {
+	mu      sync.Mutex
+	gaps    map[string][]float64 // Transition -> sorted think times in seconds
+	ignored int
+	rng     *rand.Rand
+}
+
+// transitionKey names the transition between two states.
+func transitionKey(from string, to string) string {
+	return from + " -> " + to
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating between the closest
+// ranks.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// ComputeThinkTimes measures how long users paused between consecutive requests of recorded
+// sessions, so sleep() calls in journey scripts follow real user pacing instead of a fixed
+// delay. The think time of a request is the time between its timestamp and that of the
+// previous request of its session. With stateField, think times are also kept per transition
+// between the states of the two requests, such as from one endpoint to the next, since users
+// read a product page longer than they wait for search suggestions.
+//
+// sessionsFile holds sessions like the ones BuildTransitionModel reads: a JSON array of
+// sessions, each an array of records in order or an object with records (or file) like the
+// sessions of GroupIntoSessions, or an NDJSON file of such objects. timestampField names the
+// time of a record, as an RFC 3339 string or a Unix time in seconds or milliseconds; records
+// without it are skipped.
+//
+// Options:
+//   - stateField: Field (or dotted path) naming the state of a record; records without it are
+//     skipped
+//   - maxGapSeconds: Ignore longer pauses, which are users leaving rather than thinking
+//     (default: 0, no limit)
+//   - seed: Seed of the draws, 0 for a random one (default: 0)
+//
+// Negative think times, of records out of order, are ignored.
+//
+// Returns: A handle whose sample(from, to) draws a recorded think time in seconds of the
+// transition, or of all transitions when called without states or for a transition that was
+// never recorded, and whose summary() has the count, min, max, mean and 50th, 90th, 95th and
+// 99th percentiles of every transition, keyed "from -> to", and of all of them, keyed "*"
+//
+// Example usage:
+//
+//	const thinkTimes = streamloader.computeThinkTimes("sessions.json", "timestamp", { stateField: "endpoint", maxGapSeconds: 300 });
+//	// In the default function:
+//	for (let state = model.start(), next; state !== ""; state = next) {
+//		http.get(`${BASE_URL}${state}`);
+//		next = model.nextState(state);
+//		sleep(thinkTimes.sample(state, next));
+//	}
+func (s StreamLoader) ComputeThinkTimes(sessionsFile string, timestampField string, options ...ThinkTimeOptions) (*ThinkTimes, error) {
+	if timestampField == "" {
+		return nil, fmt.Errorf("timestampField is required")
+	}
+	var opts ThinkTimeOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxGapSeconds < 0 {
+		return nil, fmt.Errorf("maxGapSeconds must not be negative, got %v", opts.MaxGapSeconds)
+	}
+
+	t := &ThinkTimes{gaps: make(map[string][]float64)}
+	index := 0
+	err := forEachJsonRecord(sessionsFile, s.call, func(raw json.RawMessage) (bool, error) {
+		var session interface{}
+		if err := json.Unmarshal(raw, &session); err != nil {
+			return false, fmt.Errorf("failed to decode session %d: %w", index, err)
+		}
+		records, err := sessionRecords(session, s.call)
+		if err != nil {
+			return false, fmt.Errorf("session %d: %w", index, err)
+		}
+		index++
+
+		var previous time.Time
+		previousState := ""
+		for i, record := range records {
+			value, found := lookupField(record, timestampField)
+			if !found || value == nil {
+				continue
+			}
+			at, err := parseTimestamp(value)
+			if err != nil {
+				return false, fmt.Errorf("session %d, record %d: %w", index-1, i, err)
+			}
+			state := ""
+			if opts.StateField != "" {
+				value, found := lookupField(record, opts.StateField)
+				if !found || value == nil {
+					continue
+				}
+				state = keyString(value)
+			}
+
+			if !previous.IsZero() {
+				gap := at.Sub(previous).Seconds()
+				if gap < 0 || (opts.MaxGapSeconds > 0 && gap > opts.MaxGapSeconds) {
+					t.ignored++
+				} else {
+					t.gaps[allTransitions] = append(t.gaps[allTransitions], gap)
+					if opts.StateField != "" {
+						key := transitionKey(previousState, state)
+						t.gaps[key] = append(t.gaps[key], gap)
+					}
+				}
+			}
+			previous, previousState = at, state
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(t.gaps[allTransitions]) == 0 {
+		return nil, fmt.Errorf("no session of %s has two consecutive records with a %q field", sessionsFile, timestampField)
+	}
+
+	for _, gaps := range t.gaps {
+		sort.Float64s(gaps)
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.rng = rand.New(rand.NewSource(seed))
+	return t, nil
+}
+
+// Sample returns a recorded think time in seconds of the transition between two states, or of
+// all transitions when no states are given or the transition was never recorded.
+func (t *ThinkTimes) Sample(states ...string) float64 {
+	gaps := t.gaps[allTransitions]
+	if len(states) >= 2 {
+		if recorded, ok := t.gaps[transitionKey(states[0], states[1])]; ok {
+			gaps = recorded
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return gaps[t.rng.Intn(len(gaps))]
+}
+
+// Summary returns the distribution of the think times of every transition, keyed "from -> to",
+// and of all of them, keyed "*".
+func (t *ThinkTimes) Summary() map[string]ThinkTimeSummary {
+	summary := make(map[string]ThinkTimeSummary, len(t.gaps))
+	for key, gaps := range t.gaps {
+		total := 0.0
+		for _, gap := range gaps {
+			total += gap
+		}
+		summary[key] = ThinkTimeSummary{
+			Count: len(gaps),
+			Min:   gaps[0],
+			Max:   gaps[len(gaps)-1],
+			Mean:  total / float64(len(gaps)),
+			P50:   percentile(gaps, 50),
+			P90:   percentile(gaps, 90),
+			P95:   percentile(gaps, 95),
+			P99:   percentile(gaps, 99),
+		}
+	}
+	return summary
+}
+
+// Ignored returns the number of think times left out because they were negative or longer than
+// maxGapSeconds.
+func (t *ThinkTimes) Ignored() int {
+	return t.ignored
+}
@@ -0,0 +1,97 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComputeThinkTimes(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	sessions := filepath.Join(dir, "sessions.json")
+	os.WriteFile(sessions, []byte(`[
+		[{"endpoint":"/","ts":0},{"endpoint":"/item","ts":10},{"endpoint":"/cart","ts":12},{"endpoint":"/item","ts":11}],
+		{"id":"b","records":[{"endpoint":"/","ts":"2025-01-01T00:00:00Z"},{"endpoint":"/item","ts":"2025-01-01T00:00:20Z"},{"ts":"2025-01-01T00:00:21Z"},{"endpoint":"/cart","ts":"2025-01-01T01:00:00Z"}]},
+		[{"endpoint":"/","ts":100},{"endpoint":"/item","ts":130}]
+	]`), 0644)
+
+	thinkTimes, err := loader.ComputeThinkTimes(sessions, "ts", ThinkTimeOptions{StateField: "endpoint", MaxGapSeconds: 600, Seed: 3})
+	if err != nil {
+		t.Fatalf("ComputeThinkTimes() error = %v", err)
+	}
+	summary := thinkTimes.Summary()
+	want := map[string]ThinkTimeSummary{
+		"*":              {Count: 4, Min: 2, Max: 30, Mean: 15.5, P50: 15, P90: 27, P95: 28.5, P99: 29.7},
+		"/ -> /item":     {Count: 3, Min: 10, Max: 30, Mean: 20, P50: 20, P90: 28, P95: 29, P99: 29.8},
+		"/item -> /cart": {Count: 1, Min: 2, Max: 2, Mean: 2, P50: 2, P90: 2, P95: 2, P99: 2},
+	}
+	if len(summary) != len(want) {
+		t.Fatalf("Summary() = %+v", summary)
+	}
+	for key, w := range want {
+		got := summary[key]
+		values := []float64{got.Min - w.Min, got.Max - w.Max, got.Mean - w.Mean, got.P50 - w.P50, got.P90 - w.P90, got.P95 - w.P95, got.P99 - w.P99}
+		for _, d := range values {
+			if got.Count != w.Count || math.Abs(d) > 1e-9 {
+				t.Errorf("Summary()[%q] = %+v, want %+v", key, got, w)
+				break
+			}
+		}
+	}
+	// /cart -> /item goes back in time, and /item -> /cart of session b is too long
+	if thinkTimes.Ignored() != 2 {
+		t.Errorf("Ignored() = %d, want 2", thinkTimes.Ignored())
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := thinkTimes.Sample("/item", "/cart"); got != 2 {
+			t.Fatalf("Sample(/item, /cart) = %v, want 2", got)
+		}
+		if got := thinkTimes.Sample("/", "/item"); got != 10 && got != 20 && got != 30 {
+			t.Fatalf("Sample(/, /item) = %v", got)
+		}
+		if got := thinkTimes.Sample("/cart", "/"); got != 2 && got != 10 && got != 20 && got != 30 {
+			t.Fatalf("Sample(unrecorded) = %v", got)
+		}
+	}
+
+	// Without states, all think times are kept
+	thinkTimes, err = loader.ComputeThinkTimes(sessions, "ts")
+	if err != nil {
+		t.Fatalf("ComputeThinkTimes(no options) error = %v", err)
+	}
+	summary = thinkTimes.Summary()
+	if len(summary) != 1 || summary["*"].Count != 6 || summary["*"].Max != 3579 {
+		t.Errorf("Summary() = %+v", summary)
+	}
+	seen := make(map[float64]bool)
+	for i := 0; i < 500; i++ {
+		seen[thinkTimes.Sample()] = true
+	}
+	if !reflect.DeepEqual(seen, map[float64]bool{10: true, 2: true, 20: true, 1: true, 3579: true, 30: true}) {
+		t.Errorf("Sample() values = %v", seen)
+	}
+
+	// Errors
+	single := filepath.Join(dir, "single.json")
+	os.WriteFile(single, []byte(`[[{"ts":1}]]`), 0644)
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[[{"ts":"noon"}]]`), 0644)
+	for _, tt := range []struct {
+		file, field string
+		options     ThinkTimeOptions
+	}{
+		{sessions, "", ThinkTimeOptions{}},
+		{sessions, "ts", ThinkTimeOptions{MaxGapSeconds: -1}},
+		{single, "ts", ThinkTimeOptions{}},
+		{invalid, "ts", ThinkTimeOptions{}},
+		{filepath.Join(dir, "missing.json"), "ts", ThinkTimeOptions{}},
+	} {
+		if _, err := loader.ComputeThinkTimes(tt.file, tt.field, tt.options); err == nil {
+			t.Errorf("ComputeThinkTimes(%s, %q, %+v) expected an error", tt.file, tt.field, tt.options)
+		}
+	}
+}
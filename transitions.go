@@ -0,0 +1,227 @@
+// transitions.go
+package streamloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TransitionModel is a Markov chain of the states of recorded sessions, such as the endpoints
+// users called, that generates synthetic journeys
+type TransitionModel struct {
+	mu          sync.Mutex
+	states      []string                    // In order of first appearance
+	start       *WeightedSampler            // First state of a session; entries are state names
+	next        map[string]*WeightedSampler // State -> next state, "" for the end of the session
+	sessions    int
+	transitions int
+	rng         *rand.Rand
+}
+
+// transitionCounts counts the states following a state, in order of first appearance.
+type transitionCounts struct {
+	order  []string
+	counts map[string]float64
+}
+
+func (c *transitionCounts) add(state string) {
+	if c.counts == nil {
+		c.counts = make(map[string]float64)
+	}
+	if _, seen := c.counts[state]; !seen {
+		c.order = append(c.order, state)
+	}
+	c.counts[state]++
+}
+
+// sampler compiles the counts into a sampler drawing the states in proportion to them.
+func (c *transitionCounts) sampler() *WeightedSampler {
+	s := &WeightedSampler{entries: make([]interface{}, len(c.order))}
+	weights := make([]float64, len(c.order))
+	total := 0.0
+	for i, state := range c.order {
+		s.entries[i] = state
+		weights[i] = c.counts[state]
+		total += weights[i]
+	}
+	s.buildAliasTable(weights, total)
+	return s
+}
+
+// sessionRecords returns the records of a session of a sessions file: an array of records, or
+// an object with a records array or, for sessions written with outputPattern, a file of records.
+func sessionRecords(session interface{}, call callLimits) ([]interface{}, error) {
+	switch v := session.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		if records, ok := v["records"].([]interface{}); ok {
+			return records, nil
+		}
+		if file, ok := v["file"].(string); ok {
+			var records []interface{}
+			err := forEachJsonRecord(file, call, func(raw json.RawMessage) (bool, error) {
+				var record interface{}
+				if err := json.Unmarshal(raw, &record); err != nil {
+					return false, fmt.Errorf("failed to decode record of %s: %w", file, err)
+				}
+				records = append(records, record)
+				return true, nil
+			})
+			return records, err
+		}
+	}
+	return nil, fmt.Errorf("expected an array of records or an object with records or file, got %T", session)
+}
+
+// BuildTransitionModel learns how users move between states, such as the endpoints they call,
+// from recorded sessions, so scenarios can generate statistically realistic synthetic journeys
+// when the recordings themselves can't be replayed. The probability of every next state, and of
+// the session ending, is the share of that transition among the transitions out of the state in
+// the recordings, making the model a first-order Markov chain.
+//
+// sessionsFile is a JSON array of sessions, each an array of records in order or an object with
+// a records array like the sessions of GroupIntoSessions, or an NDJSON file of such objects;
+// sessions that were written to their own files with outputPattern are read from their file.
+// stateField names the state of a record (a dotted path); records without it, or with an empty
+// state, are skipped. The optional seed makes the generated journeys repeatable.
+//
+// Returns: A model whose start() draws the first state of a journey and nextState(current) the
+// state after current, or "" when the journey ends
+//
+// Example usage:
+//
+//	const model = streamloader.buildTransitionModel("sessions.json", "endpoint");
+//	// In the default function:
+//	for (let state = model.start(); state !== ""; state = model.nextState(state)) {
+//		http.get(`${BASE_URL}${state}`);
+//	}
+func (s StreamLoader) BuildTransitionModel(sessionsFile string, stateField string, seed ...int64) (*TransitionModel, error) {
+	if stateField == "" {
+		return nil, fmt.Errorf("stateField is required")
+	}
+
+	model := &TransitionModel{next: make(map[string]*WeightedSampler)}
+	var starts transitionCounts
+	counts := make(map[string]*transitionCounts)
+	index := 0
+	err := forEachJsonRecord(sessionsFile, s.call, func(raw json.RawMessage) (bool, error) {
+		var session interface{}
+		if err := json.Unmarshal(raw, &session); err != nil {
+			return false, fmt.Errorf("failed to decode session %d: %w", index, err)
+		}
+		records, err := sessionRecords(session, s.call)
+		if err != nil {
+			return false, fmt.Errorf("session %d: %w", index, err)
+		}
+		index++
+
+		previous := ""
+		for _, record := range records {
+			value, found := lookupField(record, stateField)
+			if !found || value == nil {
+				continue
+			}
+			state := keyString(value)
+			if state == "" {
+				continue // "" marks the end of a journey
+			}
+			if previous == "" {
+				starts.add(state)
+			} else {
+				counts[previous].add(state)
+				model.transitions++
+			}
+			if counts[state] == nil {
+				counts[state] = &transitionCounts{}
+				model.states = append(model.states, state)
+			}
+			previous = state
+		}
+		if previous != "" {
+			counts[previous].add("")
+			model.sessions++
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if model.sessions == 0 {
+		return nil, fmt.Errorf("no session of %s has a %q field", sessionsFile, stateField)
+	}
+
+	model.start = starts.sampler()
+	for state, c := range counts {
+		model.next[state] = c.sampler()
+	}
+	if len(seed) > 0 {
+		model.rng = rand.New(rand.NewSource(seed[0]))
+	} else {
+		model.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return model, nil
+}
+
+// Start returns the first state of a new journey.
+func (m *TransitionModel) Start() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.start.entries[m.start.draw(m.rng)].(string)
+}
+
+// NextState returns the state after current, or "" when the journey ends there.
+func (m *TransitionModel) NextState(current string) (string, error) {
+	s, ok := m.next[current]
+	if !ok {
+		return "", fmt.Errorf("unknown state %q", current)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return s.entries[s.draw(m.rng)].(string), nil
+}
+
+// Journey returns the states of a new journey, stopping after maxSteps states when maxSteps is
+// positive, since a chain with loops can run long.
+func (m *TransitionModel) Journey(maxSteps int) []string {
+	journey := []string{}
+	for state := m.Start(); state != ""; {
+		journey = append(journey, state)
+		if maxSteps > 0 && len(journey) >= maxSteps {
+			break
+		}
+		state, _ = m.NextState(state)
+	}
+	return journey
+}
+
+// States returns the states seen in the sessions, in order of first appearance.
+func (m *TransitionModel) States() []string {
+	return append([]string(nil), m.states...)
+}
+
+// Probabilities returns the probability of every state following current, with "" for the end
+// of the journey, or of every first state when current is "".
+func (m *TransitionModel) Probabilities(current string) (map[string]float64, error) {
+	s := m.start
+	if current != "" {
+		var ok bool
+		if s, ok = m.next[current]; !ok {
+			return nil, fmt.Errorf("unknown state %q", current)
+		}
+	}
+	probs := make(map[string]float64, len(s.entries))
+	for i, entry := range s.entries {
+		probs[entry.(string)] = s.probs[i]
+	}
+	return probs, nil
+}
+
+// Stats returns the number of sessions, states and transitions between states the model was
+// built from.
+func (m *TransitionModel) Stats() map[string]int {
+	return map[string]int{"sessions": m.sessions, "states": len(m.states), "transitions": m.transitions}
+}
@@ -0,0 +1,122 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildTransitionModel(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	sessions := filepath.Join(dir, "sessions.json")
+	os.WriteFile(sessions, []byte("["+strings.Join([]string{
+		`[{"endpoint":"/"},{"endpoint":"/search"},{"endpoint":"/item"}]`,
+		`{"id":"b","records":[{"endpoint":"/"},{"endpoint":"/item"},{"asset":"app.js"},{"endpoint":"/cart"}]}`,
+		`[{"endpoint":"/search"},{"endpoint":"/item"},{"endpoint":""}]`,
+		`[{"asset":"app.js"}]`,
+	}, ",\n")+"]"), 0644)
+
+	model, err := loader.BuildTransitionModel(sessions, "endpoint", 1)
+	if err != nil {
+		t.Fatalf("BuildTransitionModel() error = %v", err)
+	}
+	if got := model.States(); !reflect.DeepEqual(got, []string{"/", "/search", "/item", "/cart"}) {
+		t.Errorf("States() = %v", got)
+	}
+	if got := model.Stats(); !reflect.DeepEqual(got, map[string]int{"sessions": 3, "states": 4, "transitions": 5}) {
+		t.Errorf("Stats() = %v", got)
+	}
+	near := func(got, want map[string]float64) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for k, v := range want {
+			if math.Abs(got[k]-v) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	tests := []struct {
+		current string
+		want    map[string]float64
+	}{
+		{"", map[string]float64{"/": 2.0 / 3, "/search": 1.0 / 3}},
+		{"/", map[string]float64{"/search": 0.5, "/item": 0.5}},
+		{"/item", map[string]float64{"": 2.0 / 3, "/cart": 1.0 / 3}},
+		{"/cart", map[string]float64{"": 1}},
+	}
+	for _, tt := range tests {
+		if got, err := model.Probabilities(tt.current); err != nil || !near(got, tt.want) {
+			t.Errorf("Probabilities(%q) = %v, %v, want %v", tt.current, got, err, tt.want)
+		}
+	}
+
+	// Sampled transitions follow the probabilities
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		next, err := model.NextState("/item")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[next]++
+	}
+	if share := float64(counts["/cart"]) / 3000; share < 0.28 || share > 0.39 {
+		t.Errorf("share of /item -> /cart = %v, want about 1/3", share)
+	}
+	for i := 0; i < 100; i++ {
+		journey := model.Journey(0)
+		if len(journey) == 0 || (journey[0] != "/" && journey[0] != "/search") || len(journey) > 4 {
+			t.Fatalf("Journey() = %v", journey)
+		}
+	}
+	if journey := model.Journey(1); len(journey) != 1 {
+		t.Errorf("Journey(1) = %v", journey)
+	}
+
+	// The same seed generates the same journeys
+	again, _ := loader.BuildTransitionModel(sessions, "endpoint", 1)
+	other, _ := loader.BuildTransitionModel(sessions, "endpoint", 1)
+	for i := 0; i < 20; i++ {
+		if a, b := again.Journey(0), other.Journey(0); !reflect.DeepEqual(a, b) {
+			t.Fatalf("Journey() with the same seed = %v and %v", a, b)
+		}
+	}
+
+	// Sessions written to their own files
+	group := filepath.Join(dir, "session-a.json")
+	os.WriteFile(group, []byte(`[{"page":{"name":"home"}},{"page":{"name":"about"}}]`), 0644)
+	index := filepath.Join(dir, "index.json")
+	os.WriteFile(index, []byte(`[{"id":"a","file":"`+filepath.ToSlash(group)+`"}]`), 0644)
+	model, err = loader.BuildTransitionModel(index, "page.name")
+	if err != nil {
+		t.Fatalf("BuildTransitionModel(files) error = %v", err)
+	}
+	if got := model.Journey(0); !reflect.DeepEqual(got, []string{"home", "about"}) {
+		t.Errorf("Journey() = %v", got)
+	}
+
+	// Errors
+	if _, err := model.NextState("/missing"); err == nil {
+		t.Error("NextState(unknown) expected an error")
+	}
+	if _, err := model.Probabilities("/missing"); err == nil {
+		t.Error("Probabilities(unknown) expected an error")
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[42]`), 0644)
+	for _, tt := range []struct{ file, field string }{
+		{sessions, ""},
+		{sessions, "missing"},
+		{invalid, "endpoint"},
+		{filepath.Join(dir, "missing.json"), "endpoint"},
+	} {
+		if _, err := loader.BuildTransitionModel(tt.file, tt.field); err == nil {
+			t.Errorf("BuildTransitionModel(%s, %q) expected an error", tt.file, tt.field)
+		}
+	}
+}
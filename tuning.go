@@ -0,0 +1,125 @@
+// tuning.go
+package streamloader
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// TuningDefaults holds the I/O settings used by every function unless its options override them
+type TuningDefaults struct {
+	ReadBufferBytes  int `json:"readBufferBytes" js:"readBufferBytes"`
+	WriteBufferBytes int `json:"writeBufferBytes" js:"writeBufferBytes"`
+	ScannerMaxBytes  int `json:"scannerMaxBytes" js:"scannerMaxBytes"`
+	FlushEveryN      int `json:"flushEveryN" js:"flushEveryN"`
+	MaxOpenFiles     int `json:"maxOpenFiles" js:"maxOpenFiles"`
+	RetryAttempts    int `json:"retryAttempts" js:"retryAttempts"`
+	RetryBackoffMs   int `json:"retryBackoffMs" js:"retryBackoffMs"`
+}
+
+// defaultTuning is the built-in tuning. A ScannerMaxBytes of 0 keeps each line scanner's own
+// limit: ten times its buffer size for the JSONL writers, 64KB elsewhere. A MaxOpenFiles of 0
+// allows half of the process's file descriptor limit. Opening and reading input files is tried
+// up to three times on transient errors, waiting 100ms and then 200ms.
+var defaultTuning = TuningDefaults{
+	ReadBufferBytes:  64 * 1024,
+	WriteBufferBytes: 64 * 1024,
+	FlushEveryN:      1000,
+	RetryAttempts:    3,
+	RetryBackoffMs:   100,
+}
+
+// tuning is shared by every VU in the k6 process
+var tuning atomic.Pointer[TuningDefaults]
+
+func init() {
+	t := defaultTuning
+	tuning.Store(&t)
+}
+
+// readBufferSize returns the buffer size for reading input files.
+func readBufferSize() int {
+	return tuning.Load().ReadBufferBytes
+}
+
+// writeBufferSize returns the buffer size for writing output files.
+func writeBufferSize() int {
+	return tuning.Load().WriteBufferBytes
+}
+
+// scannerMaxSize returns the longest line a line scanner accepts, or fallback if it isn't tuned.
+func scannerMaxSize(fallback int) int {
+	if max := tuning.Load().ScannerMaxBytes; max > 0 {
+		return max
+	}
+	return fallback
+}
+
+// flushEveryN returns the number of records between explicit flushes of the array writers.
+func flushEveryN() int {
+	return tuning.Load().FlushEveryN
+}
+
+// SetDefaults changes the I/O tuning of every function in the process: the read and write
+// buffer sizes (64KB by default), the longest line accepted when scanning JSON lines, how many
+// records the array writers write between flushes (1000 by default), and how many files the
+// loaders and writers may hold open at once (half the file descriptor limit by default), and
+// how often opening and reading input files is tried on transient errors of network file
+// systems (3 attempts, backing off from 100ms, by default; 1 attempt disables retries). Fields
+// left at 0 keep their current value. Larger buffers help on fast NVMe storage; options passed
+// to a single call, such as bufferSize, still take precedence. Call it in the init context,
+// since it affects all VUs.
+//
+// Returns: The settings now in effect
+//
+// Example usage:
+//
+//	streamloader.setDefaults({ readBufferBytes: 1048576, writeBufferBytes: 1048576, flushEveryN: 10000 });
+func (StreamLoader) SetDefaults(defaults TuningDefaults) (TuningDefaults, error) {
+	if defaults.ReadBufferBytes < 0 || defaults.WriteBufferBytes < 0 || defaults.ScannerMaxBytes < 0 || defaults.FlushEveryN < 0 || defaults.MaxOpenFiles < 0 || defaults.RetryAttempts < 0 || defaults.RetryBackoffMs < 0 {
+		return *tuning.Load(), fmt.Errorf("tuning values must not be negative: %+v", defaults)
+	}
+	if defaults.ReadBufferBytes > 0 && defaults.ReadBufferBytes < 16 {
+		return *tuning.Load(), fmt.Errorf("readBufferBytes must be at least 16, got %d", defaults.ReadBufferBytes)
+	}
+
+	for {
+		current := tuning.Load()
+		next := *current
+		if defaults.ReadBufferBytes > 0 {
+			next.ReadBufferBytes = defaults.ReadBufferBytes
+		}
+		if defaults.WriteBufferBytes > 0 {
+			next.WriteBufferBytes = defaults.WriteBufferBytes
+		}
+		if defaults.ScannerMaxBytes > 0 {
+			next.ScannerMaxBytes = defaults.ScannerMaxBytes
+		}
+		if defaults.FlushEveryN > 0 {
+			next.FlushEveryN = defaults.FlushEveryN
+		}
+		if defaults.MaxOpenFiles > 0 {
+			next.MaxOpenFiles = defaults.MaxOpenFiles
+		}
+		if defaults.RetryAttempts > 0 {
+			next.RetryAttempts = defaults.RetryAttempts
+		}
+		if defaults.RetryBackoffMs > 0 {
+			next.RetryBackoffMs = defaults.RetryBackoffMs
+		}
+		if tuning.CompareAndSwap(current, &next) {
+			return next, nil
+		}
+	}
+}
+
+// GetDefaults returns the I/O tuning now in effect.
+func (StreamLoader) GetDefaults() TuningDefaults {
+	return *tuning.Load()
+}
+
+// ResetDefaults restores the built-in I/O tuning.
+func (StreamLoader) ResetDefaults() {
+	t := defaultTuning
+	tuning.Store(&t)
+}
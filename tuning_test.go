@@ -0,0 +1,67 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetDefaults(t *testing.T) {
+	loader := StreamLoader{}
+	defer loader.ResetDefaults()
+
+	if got := loader.GetDefaults(); got != defaultTuning {
+		t.Fatalf("GetDefaults() = %+v, want %+v", got, defaultTuning)
+	}
+
+	// Fields left at 0 keep their value
+	got, err := loader.SetDefaults(TuningDefaults{ReadBufferBytes: 1 << 20, FlushEveryN: 10})
+	if err != nil {
+		t.Fatalf("SetDefaults() error = %v", err)
+	}
+	want := TuningDefaults{ReadBufferBytes: 1 << 20, WriteBufferBytes: 64 * 1024, FlushEveryN: 10, RetryAttempts: 3, RetryBackoffMs: 100}
+	if got != want || loader.GetDefaults() != want {
+		t.Errorf("SetDefaults() = %+v, want %+v", got, want)
+	}
+	if readBufferSize() != 1<<20 || writeBufferSize() != 64*1024 || flushEveryN() != 10 {
+		t.Error("tuning not applied")
+	}
+
+	if _, err := loader.SetDefaults(TuningDefaults{WriteBufferBytes: -1}); err == nil {
+		t.Error("Expected error for a negative value")
+	}
+	if _, err := loader.SetDefaults(TuningDefaults{ReadBufferBytes: 8}); err == nil {
+		t.Error("Expected error for a tiny read buffer")
+	}
+
+	// The scanner limit applies to line scanners
+	dir := t.TempDir()
+	longLine := `"` + strings.Repeat("x", 200) + `"`
+	if _, err := loader.SetDefaults(TuningDefaults{ScannerMaxBytes: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.JsonLinesToObjects(longLine); err == nil {
+		t.Error("Expected error for a line longer than scannerMaxBytes")
+	}
+	if _, err := loader.WriteJsonLinesToArrayFile(longLine, filepath.Join(dir, "out.json"), 16); err == nil {
+		t.Error("Expected error for a line longer than scannerMaxBytes")
+	}
+
+	// Functions keep working with tuned buffers
+	loader.ResetDefaults()
+	loader.SetDefaults(TuningDefaults{ReadBufferBytes: 16, WriteBufferBytes: 1, FlushEveryN: 1})
+	path := filepath.Join(dir, "objects.json")
+	objects := []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}}
+	if count, err := loader.WriteObjectsToJsonArrayFile(objects, path); err != nil || count != 2 {
+		t.Fatalf("WriteObjectsToJsonArrayFile() = %d, %v", count, err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != `[{"id":1},{"id":2}]` {
+		t.Errorf("file = %s", data)
+	}
+	loaded, err := loader.LoadJSON(path)
+	if err != nil || len(loaded.([]any)) != 2 {
+		t.Errorf("LoadJSON() = %v, %v", loaded, err)
+	}
+}
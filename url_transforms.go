@@ -0,0 +1,210 @@
+// url_transforms.go
+package streamloader
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// urlParts are the components of a URL the urlPart field and the setUrlPart transform address.
+var urlParts = map[string]bool{"scheme": true, "host": true, "path": true, "query": true, "fragment": true}
+
+// validateUrlTransform checks the options of the URL transforms of ProcessCsvFile up front, so
+// a misconfigured pipeline fails before reading.
+func validateUrlTransform(t TransformConfig) error {
+	switch t.Type {
+	case "setQueryParam", "deleteQueryParam":
+		if t.Name == "" {
+			return fmt.Errorf("%s transform needs a name", t.Type)
+		}
+	case "setUrlPart":
+		if !urlParts[t.Part] {
+			return fmt.Errorf("invalid part %q in setUrlPart transform: expected scheme, host, path, query or fragment", t.Part)
+		}
+	}
+	return nil
+}
+
+// validateUrlField checks the options of the URL projection fields of ProcessCsvFile.
+func validateUrlField(f FieldConfig) error {
+	switch f.Type {
+	case "urlPart":
+		if !urlParts[f.Part] {
+			return fmt.Errorf("invalid part %q in urlPart field: expected scheme, host, path, query or fragment", f.Part)
+		}
+	case "queryParam":
+		if f.Name == "" {
+			return fmt.Errorf("queryParam field needs a name")
+		}
+	}
+	return nil
+}
+
+// transformValue returns the value a set transform writes: the cell of its source column, or
+// its fixed value. ok is false when the source column is missing from the row.
+func transformValue(row []string, t TransformConfig) (string, bool) {
+	if t.SourceColumn != nil {
+		if *t.SourceColumn < 0 || *t.SourceColumn >= len(row) {
+			return "", false
+		}
+		return row[*t.SourceColumn], true
+	}
+	if t.Value == nil {
+		return "", true
+	}
+	return fmt.Sprintf("%v", t.Value), true
+}
+
+// applyUrlTransform rewrites the URL in a cell. The query transforms edit the text of the query;
+// setUrlPart leaves cells that don't parse as URLs as they are.
+func applyUrlTransform(row []string, t TransformConfig) {
+	cell := row[t.Column]
+	switch t.Type {
+	case "setQueryParam":
+		if value, ok := transformValue(row, t); ok {
+			row[t.Column] = setQueryParam(cell, t.Name, value)
+		}
+	case "deleteQueryParam":
+		row[t.Column] = deleteQueryParam(cell, t.Name)
+	case "setUrlPart":
+		value, ok := transformValue(row, t)
+		if !ok {
+			return
+		}
+		u, err := url.Parse(cell)
+		if err != nil {
+			return
+		}
+		switch t.Part {
+		case "scheme":
+			u.Scheme = value
+		case "host":
+			u.Host = value
+		case "path":
+			if path, err := url.PathUnescape(value); err == nil {
+				u.Path, u.RawPath = path, value
+			} else {
+				u.Path, u.RawPath = value, ""
+			}
+		case "query":
+			u.RawQuery = strings.TrimPrefix(value, "?")
+		case "fragment":
+			u.Fragment, u.RawFragment = value, ""
+		}
+		row[t.Column] = u.String()
+	}
+}
+
+// projectUrlField returns the value of a URL projection field for a cell: "" for cells that
+// don't parse as URLs, or an empty object for queryParams.
+func projectUrlField(cell string, f FieldConfig) interface{} {
+	u, err := url.Parse(cell)
+	switch f.Type {
+	case "urlPart":
+		if err != nil {
+			return ""
+		}
+		switch f.Part {
+		case "scheme":
+			return u.Scheme
+		case "host":
+			return u.Host
+		case "path":
+			return u.EscapedPath()
+		case "query":
+			return u.RawQuery
+		default:
+			return u.Fragment
+		}
+	case "queryParam":
+		if err != nil {
+			return ""
+		}
+		// Pairs that don't decode are skipped
+		values, _ := url.ParseQuery(u.RawQuery)
+		return values.Get(f.Name)
+	default:
+		params := make(map[string]interface{})
+		if err != nil {
+			return params
+		}
+		// Pairs that don't decode are skipped; repeated parameters become arrays
+		values, _ := url.ParseQuery(u.RawQuery)
+		for name, list := range values {
+			if len(list) == 1 {
+				params[name] = list[0]
+				continue
+			}
+			items := make([]interface{}, len(list))
+			for i, v := range list {
+				items[i] = v
+			}
+			params[name] = items
+		}
+		return params
+	}
+}
+
+// splitQuery splits a URL into the part before its query, the raw query, and the fragment with
+// its '#', so the query can be edited without re-encoding the rest of the URL.
+func splitQuery(rawURL string) (string, string, string, bool) {
+	fragment := ""
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		rawURL, fragment = rawURL[:i], rawURL[i:]
+	}
+	base, query, found := strings.Cut(rawURL, "?")
+	return base, query, fragment, found
+}
+
+// queryPairName returns the decoded name of a name=value pair of a raw query.
+func queryPairName(pair string) string {
+	name, _, _ := strings.Cut(pair, "=")
+	if decoded, err := url.QueryUnescape(name); err == nil {
+		return decoded
+	}
+	return name
+}
+
+// setQueryParam sets a query parameter of a URL, replacing the first occurrence in place and
+// dropping repeats, or appending it. The order and encoding of the other parameters are kept,
+// unlike url.Values.Encode, which sorts them.
+func setQueryParam(rawURL string, name string, value string) string {
+	base, query, fragment, _ := splitQuery(rawURL)
+	encoded := url.QueryEscape(name) + "=" + url.QueryEscape(value)
+	var pairs []string
+	set := false
+	if query != "" {
+		for _, pair := range strings.Split(query, "&") {
+			if queryPairName(pair) != name {
+				pairs = append(pairs, pair)
+			} else if !set {
+				pairs = append(pairs, encoded)
+				set = true
+			}
+		}
+	}
+	if !set {
+		pairs = append(pairs, encoded)
+	}
+	return base + "?" + strings.Join(pairs, "&") + fragment
+}
+
+// deleteQueryParam removes every occurrence of a query parameter from a URL, and the '?' when
+// no parameter is left.
+func deleteQueryParam(rawURL string, name string) string {
+	base, query, fragment, found := splitQuery(rawURL)
+	if !found {
+		return rawURL
+	}
+	var pairs []string
+	for _, pair := range strings.Split(query, "&") {
+		if pair != "" && queryPairName(pair) != name {
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) == 0 {
+		return base + fragment
+	}
+	return base + "?" + strings.Join(pairs, "&") + fragment
+}
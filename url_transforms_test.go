@@ -0,0 +1,76 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestQueryParamEditing(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"replace in place", setQueryParam("/a?x=1&user=7&y=2", "user", "42"), "/a?x=1&user=42&y=2"},
+		{"append", setQueryParam("/a?x=1#top", "user", "a b&c"), "/a?x=1&user=a+b%26c#top"},
+		{"no query", setQueryParam("https://h/a", "page", "2"), "https://h/a?page=2"},
+		{"repeats dropped", setQueryParam("/a?id=1&id=2&z", "id", "3"), "/a?id=3&z"},
+		{"encoded name", setQueryParam("/a?user%5Fid=1", "user_id", "2"), "/a?user_id=2"},
+		{"delete", deleteQueryParam("/a?token=s&x=1&token=t", "token"), "/a?x=1"},
+		{"delete last", deleteQueryParam("/a?token=s#f", "token"), "/a#f"},
+		{"delete missing", deleteQueryParam("/a", "token"), "/a"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestProcessCsvFileUrlTransforms(t *testing.T) {
+	loader := StreamLoader{}
+	path := filepath.Join(t.TempDir(), "requests.csv")
+	os.WriteFile(path, []byte("url,user\n"+
+		"https://shop.example.com/api/items?page=2&user=1&token=x,u-9\n"+
+		"/api/cart?tag=a&tag=b,u-7\n"), 0644)
+
+	source := 1
+	result, err := loader.ProcessCsvFile(path, ProcessCsvOptions{
+		SkipHeader: true,
+		Transforms: []TransformConfig{
+			{Type: "setQueryParam", Column: 0, Name: "user", SourceColumn: &source},
+			{Type: "deleteQueryParam", Column: 0, Name: "token"},
+			{Type: "setUrlPart", Column: 0, Part: "host", Value: "staging.example.com"},
+		},
+		Fields: []FieldConfig{
+			{Type: "column", Column: 0},
+			{Type: "urlPart", Column: 0, Part: "path"},
+			{Type: "queryParam", Column: 0, Name: "page"},
+			{Type: "queryParams", Column: 0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessCsvFile() error = %v", err)
+	}
+	want := [][]interface{}{
+		{"https://staging.example.com/api/items?page=2&user=u-9", "/api/items", "2", map[string]interface{}{"page": "2", "user": "u-9"}},
+		{"//staging.example.com/api/cart?tag=a&tag=b&user=u-7", "/api/cart", "", map[string]interface{}{"tag": []interface{}{"a", "b"}, "user": "u-7"}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("ProcessCsvFile() = %v, want %v", result, want)
+	}
+
+	invalid := []ProcessCsvOptions{
+		{Transforms: []TransformConfig{{Type: "setQueryParam", Column: 0}}},
+		{Transforms: []TransformConfig{{Type: "setUrlPart", Column: 0, Part: "port"}}},
+		{Fields: []FieldConfig{{Type: "urlPart", Column: 0}}},
+		{Fields: []FieldConfig{{Type: "queryParam", Column: 0}}},
+	}
+	for _, options := range invalid {
+		if _, err := loader.ProcessCsvFile(path, options); err == nil {
+			t.Errorf("ProcessCsvFile(%+v) expected an error", options)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// watch.go
+package streamloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchOptions represents options for WatchDirectory
+type WatchOptions struct {
+	Pattern         string `json:"pattern" js:"pattern"`
+	IncludeExisting bool   `json:"includeExisting" js:"includeExisting"`
+	MinAgeMs        int    `json:"minAgeMs" js:"minAgeMs"`
+}
+
+// DirectoryWatcher reports data shard files as they appear in a directory. The directory is
+// rescanned on demand whenever the iterator is advanced, so no background goroutine runs
+// between calls and nothing needs to be torn down other than calling Close. A watcher created
+// during an iteration is closed when the iteration ends.
+type DirectoryWatcher struct {
+	dir     string
+	pattern string
+	minAge  time.Duration
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	pending []string
+	closed  bool
+}
+
+// WatchDirectory starts watching a directory for new files, enabling rolling-dataset tests
+// where fresh recordings arrive while the test runs.
+//
+// Options:
+//   - pattern: Glob matched against file names, e.g. "shard-*.json" (default: "*")
+//   - includeExisting: Also report files already present when watching starts (default: false)
+//   - minAgeMs: Only report files whose modification time is at least this old, so shards
+//     that are still being written are picked up on a later call (default: 0)
+//
+// New files are reported oldest first (by modification time, then name), and each file is
+// reported exactly once.
+//
+// Example usage:
+//
+//	watcher, err := streamloader.WatchDirectory("recordings", WatchOptions{Pattern: "*.json", MinAgeMs: 2000})
+//	// In the default function:
+//	data, err := watcher.NextJSON() // nil until a new shard arrives
+func (s StreamLoader) WatchDirectory(dir string, options ...WatchOptions) (*DirectoryWatcher, error) {
+	var opts WatchOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Pattern == "" {
+		opts.Pattern = "*"
+	}
+	if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to watch directory: %s is not a directory", dir)
+	}
+
+	w := &DirectoryWatcher{
+		dir:     dir,
+		pattern: opts.Pattern,
+		minAge:  time.Duration(opts.MinAgeMs) * time.Millisecond,
+		seen:    make(map[string]bool),
+	}
+
+	if !opts.IncludeExisting {
+		// Mark current files as seen so only files created later are reported
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+		for _, entry := range entries {
+			if matched, _ := filepath.Match(w.pattern, entry.Name()); matched {
+				w.seen[entry.Name()] = true
+			}
+		}
+	}
+	s.closeAtIterationEnd(w)
+	return w, nil
+}
+
+// scan adds unseen, settled files to the pending queue. The caller must hold the lock.
+func (w *DirectoryWatcher) scan() error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	type candidate struct {
+		name    string
+		modTime time.Time
+	}
+	var found []candidate
+	now := time.Now()
+	for _, entry := range entries {
+		name := entry.Name()
+		if w.seen[name] || !entry.Type().IsRegular() {
+			continue
+		}
+		if matched, _ := filepath.Match(w.pattern, name); !matched {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed between listing and stat
+		}
+		if now.Sub(info.ModTime()) < w.minAge {
+			continue // Still being written, retry on a later scan
+		}
+		found = append(found, candidate{name: name, modTime: info.ModTime()})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].modTime.Equal(found[j].modTime) {
+			return found[i].modTime.Before(found[j].modTime)
+		}
+		return found[i].name < found[j].name
+	})
+	for _, c := range found {
+		w.seen[c.name] = true
+		w.pending = append(w.pending, filepath.Join(w.dir, c.name))
+	}
+	return nil
+}
+
+// HasNext rescans the directory and reports whether a new file is available.
+func (w *DirectoryWatcher) HasNext() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return false, nil
+	}
+	if len(w.pending) == 0 {
+		if err := w.scan(); err != nil {
+			return false, err
+		}
+	}
+	return len(w.pending) > 0, nil
+}
+
+// Next returns the path of the next new file, or an empty string if none has arrived yet.
+func (w *DirectoryWatcher) Next() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return "", fmt.Errorf("watcher is closed")
+	}
+	if len(w.pending) == 0 {
+		if err := w.scan(); err != nil {
+			return "", err
+		}
+	}
+	if len(w.pending) == 0 {
+		return "", nil
+	}
+	path := w.pending[0]
+	w.pending = w.pending[1:]
+	return path, nil
+}
+
+// NextJSON loads the next new file with LoadJSON, or returns nil if none has arrived yet.
+func (w *DirectoryWatcher) NextJSON() (any, error) {
+	path, err := w.Next()
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return StreamLoader{}.LoadJSON(path)
+}
+
+// Close stops the watcher. Further calls to Next return an error.
+func (w *DirectoryWatcher) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.pending = nil
+	w.seen = nil
+}
+
+// Dispose is an alias of Close.
+func (w *DirectoryWatcher) Dispose() {
+	w.Close()
+}
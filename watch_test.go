@@ -0,0 +1,105 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchDirectory_NewFiles(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "shard-0.json"), []byte(`[{"n":0}]`), 0644)
+
+	watcher, err := loader.WatchDirectory(dir, WatchOptions{Pattern: "shard-*.json"})
+	if err != nil {
+		t.Fatalf("WatchDirectory() error = %v", err)
+	}
+	defer watcher.Close()
+
+	// Existing files are not reported by default
+	if path, err := watcher.Next(); err != nil || path != "" {
+		t.Fatalf("Expected no new file, got %q (err %v)", path, err)
+	}
+
+	now := time.Now()
+	for i, name := range []string{"shard-2.json", "shard-1.json", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(`[{"name":"`+name+`"}]`), 0644)
+		mtime := now.Add(time.Duration(i-3) * time.Second)
+		os.Chtimes(path, mtime, mtime)
+	}
+
+	hasNext, err := watcher.HasNext()
+	if err != nil || !hasNext {
+		t.Fatalf("Expected a new file, got %v (err %v)", hasNext, err)
+	}
+
+	// Oldest first, non-matching files ignored
+	first, _ := watcher.Next()
+	second, _ := watcher.Next()
+	third, _ := watcher.Next()
+	if filepath.Base(first) != "shard-2.json" || filepath.Base(second) != "shard-1.json" || third != "" {
+		t.Errorf("Unexpected order: %q, %q, %q", first, second, third)
+	}
+
+	// Each file is reported only once, later arrivals are still picked up
+	os.WriteFile(filepath.Join(dir, "shard-3.json"), []byte(`[{"name":"shard-3"}]`), 0644)
+	data, err := watcher.NextJSON()
+	if err != nil {
+		t.Fatalf("NextJSON() error = %v", err)
+	}
+	records, ok := data.([]interface{})
+	if !ok || len(records) != 1 || records[0].(map[string]interface{})["name"] != "shard-3" {
+		t.Errorf("Unexpected NextJSON() result: %v", data)
+	}
+	if data, _ := watcher.NextJSON(); data != nil {
+		t.Errorf("Expected nil when no new shard arrived, got %v", data)
+	}
+
+	watcher.Close()
+	if _, err := watcher.Next(); err == nil {
+		t.Error("Expected error after Close")
+	}
+}
+
+func TestWatchDirectory_Options(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "existing.json"), []byte(`[]`), 0644)
+
+	watcher, err := loader.WatchDirectory(dir, WatchOptions{IncludeExisting: true, MinAgeMs: 60000})
+	if err != nil {
+		t.Fatalf("WatchDirectory() error = %v", err)
+	}
+
+	// The file is too recent to be considered complete
+	if path, _ := watcher.Next(); path != "" {
+		t.Errorf("Expected fresh file to be held back, got %q", path)
+	}
+
+	old := time.Now().Add(-2 * time.Minute)
+	os.Chtimes(filepath.Join(dir, "existing.json"), old, old)
+	if path, _ := watcher.Next(); filepath.Base(path) != "existing.json" {
+		t.Errorf("Expected existing.json once settled, got %q", path)
+	}
+}
+
+func TestWatchDirectory_Errors(t *testing.T) {
+	loader := StreamLoader{}
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.txt")
+	os.WriteFile(filePath, []byte("x"), 0644)
+
+	if _, err := loader.WatchDirectory(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+	if _, err := loader.WatchDirectory(filePath); err == nil {
+		t.Error("Expected error for a file instead of a directory")
+	}
+	if _, err := loader.WatchDirectory(dir, WatchOptions{Pattern: "["}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
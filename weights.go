@@ -0,0 +1,167 @@
+// weights.go
+package streamloader
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// WeightedSampler draws categories at random in proportion to their weights
+type WeightedSampler struct {
+	mu      sync.Mutex
+	entries []interface{}
+	probs   []float64 // Probability of every entry, for Probabilities
+	alias   []int     // Alias table (Vose's method): column i yields i with prob[i], else alias[i]
+	prob    []float64
+	rng     *rand.Rand
+}
+
+// CompileWeights compiles a weighted distribution of categories into a sampler whose Next
+// chooses a category in constant time, so scenario mixes follow a recorded distribution without
+// weighted random selection code in JavaScript. source is a stats file path or an array of
+// objects; a stats file is a recording stats file, whose filterStats entries are the categories,
+// or a JSON array or NDJSON file of objects. field names the numeric weight of every object.
+// Objects with a weight of 0 are never chosen. The optional seed makes the draws repeatable.
+//
+// Example usage:
+//
+//	const mix = streamloader.compileWeights("recording-stats.json", "weight");
+//	// In the default function:
+//	const endpoint = mix.next(); // e.g. { method: "GET", uriRegex: "/endpoint/store.get_gateway", weight: 361, ... }
+func (s StreamLoader) CompileWeights(source interface{}, field string, seed ...int64) (*WeightedSampler, error) {
+	if field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	var entries []interface{}
+	switch v := source.(type) {
+	case string:
+		data, err := s.LoadJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load weights: %w", err)
+		}
+		if entries, err = statsEntries(data); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		entries = v
+	default:
+		return nil, fmt.Errorf("invalid weights source: expected a file path or an array, got %T", source)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no categories to choose from")
+	}
+
+	weights := make([]float64, len(entries))
+	total := 0.0
+	for i, entry := range entries {
+		value, found := lookupField(entry, field)
+		weight, ok := numberValue(value)
+		if !found || !ok {
+			return nil, fmt.Errorf("category %d: %q is not a number", i, field)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("category %d: weight must not be negative, got %v", i, weight)
+		}
+		weights[i] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("all weights are 0")
+	}
+
+	sampler := &WeightedSampler{entries: entries}
+	sampler.buildAliasTable(weights, total)
+	if len(seed) > 0 {
+		sampler.rng = rand.New(rand.NewSource(seed[0]))
+	} else {
+		sampler.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return sampler, nil
+}
+
+// statsEntries returns the categories in the contents of a stats file: the filterStats entries
+// of a recording stats file, or the records of an array.
+func statsEntries(data any) ([]interface{}, error) {
+	switch v := data.(type) {
+	case []interface{}:
+		return v, nil
+	case []map[string]any:
+		entries := make([]interface{}, len(v))
+		for i, item := range v {
+			entries[i] = item
+		}
+		return entries, nil
+	case map[string]any:
+		if stats, ok := v["filterStats"].([]interface{}); ok {
+			return stats, nil
+		}
+	}
+	return nil, fmt.Errorf("stats file must be an array or have a filterStats array")
+}
+
+// buildAliasTable builds the alias table for the weights with Vose's method.
+func (s *WeightedSampler) buildAliasTable(weights []float64, total float64) {
+	n := len(weights)
+	s.probs = make([]float64, n)
+	s.prob = make([]float64, n)
+	s.alias = make([]int, n)
+
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		s.probs[i] = w / total
+		scaled[i] = s.probs[i] * float64(n)
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		l, g := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		s.prob[l], s.alias[l] = scaled[l], g
+		scaled[g] -= 1 - scaled[l]
+		if scaled[g] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, g)
+		}
+	}
+	// What is left is 1 up to rounding errors
+	for _, i := range append(small, large...) {
+		s.prob[i], s.alias[i] = 1, i
+	}
+}
+
+// NextIndex returns the index of a randomly chosen category.
+func (s *WeightedSampler) NextIndex() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.draw(s.rng)
+}
+
+// draw returns the index of a category chosen with rng, which the caller serializes.
+func (s *WeightedSampler) draw(rng *rand.Rand) int {
+	i := rng.Intn(len(s.prob))
+	if rng.Float64() < s.prob[i] {
+		return i
+	}
+	return s.alias[i]
+}
+
+// Next returns a randomly chosen category.
+func (s *WeightedSampler) Next() interface{} {
+	return s.entries[s.NextIndex()]
+}
+
+// Size returns the number of categories.
+func (s *WeightedSampler) Size() int {
+	return len(s.entries)
+}
+
+// Probabilities returns the probability of every category, in order.
+func (s *WeightedSampler) Probabilities() []float64 {
+	return append([]float64(nil), s.probs...)
+}
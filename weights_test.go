@@ -0,0 +1,92 @@
+package streamloader
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileWeights(t *testing.T) {
+	entries := []interface{}{
+		map[string]interface{}{"name": "browse", "stats": map[string]interface{}{"weight": float64(70)}},
+		map[string]interface{}{"name": "search", "stats": map[string]interface{}{"weight": int64(20)}},
+		map[string]interface{}{"name": "never", "stats": map[string]interface{}{"weight": 0}},
+		map[string]interface{}{"name": "checkout", "stats": map[string]interface{}{"weight": 10.0}},
+	}
+	sampler, err := StreamLoader{}.CompileWeights(entries, "stats.weight", 42)
+	if err != nil {
+		t.Fatalf("CompileWeights() error = %v", err)
+	}
+	if want := []float64{0.7, 0.2, 0, 0.1}; !reflect.DeepEqual(sampler.Probabilities(), want) {
+		t.Errorf("Probabilities() = %v, want %v", sampler.Probabilities(), want)
+	}
+
+	const draws = 100000
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		counts[sampler.Next().(map[string]interface{})["name"].(string)]++
+	}
+	for name, want := range map[string]float64{"browse": 0.7, "search": 0.2, "never": 0, "checkout": 0.1} {
+		if got := float64(counts[name]) / draws; math.Abs(got-want) > 0.01 {
+			t.Errorf("%s chosen %v of the time, want %v", name, got, want)
+		}
+	}
+
+	// The same seed repeats the draws
+	a, _ := StreamLoader{}.CompileWeights(entries, "stats.weight", 7)
+	b, _ := StreamLoader{}.CompileWeights(entries, "stats.weight", 7)
+	for i := 0; i < 100; i++ {
+		if a.NextIndex() != b.NextIndex() {
+			t.Fatal("samplers with the same seed drew differently")
+		}
+	}
+}
+
+func TestCompileWeightsStatsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	content := `{"recordingId": "x", "filterStats": [
+		{"method": "GET", "uriRegex": "/a", "weight": 3},
+		{"method": "POST", "uriRegex": "/b", "weight": 1}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sampler, err := StreamLoader{}.CompileWeights(path, "weight")
+	if err != nil {
+		t.Fatalf("CompileWeights() error = %v", err)
+	}
+	if sampler.Size() != 2 || !reflect.DeepEqual(sampler.Probabilities(), []float64{0.75, 0.25}) {
+		t.Errorf("sampler has %d categories with probabilities %v", sampler.Size(), sampler.Probabilities())
+	}
+	if uri := sampler.Next().(map[string]interface{})["uriRegex"]; uri != "/a" && uri != "/b" {
+		t.Errorf("Next() chose %v", uri)
+	}
+}
+
+func TestCompileWeightsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  interface{}
+		field   string
+		wantErr string
+	}{
+		{"no field", []interface{}{}, "", "field is required"},
+		{"empty", []interface{}{}, "weight", "no categories"},
+		{"missing weight", []interface{}{map[string]interface{}{"w": 1}}, "weight", `category 0: "weight" is not a number`},
+		{"negative weight", []interface{}{map[string]interface{}{"weight": 1}, map[string]interface{}{"weight": -1}}, "weight", "category 1: weight must not be negative"},
+		{"all zero", []interface{}{map[string]interface{}{"weight": 0}}, "weight", "all weights are 0"},
+		{"invalid source", 5, "weight", "invalid weights source"},
+		{"missing file", "missing.json", "weight", "failed to load weights"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := StreamLoader{}.CompileWeights(tt.source, tt.field)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CompileWeights() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
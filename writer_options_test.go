@@ -0,0 +1,131 @@
+package streamloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJsonWriterOptions_WriteJsonLinesToArrayFile(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+
+	jsonLines := "{\"b\": 2, \"a\": {\"z\": 1.50, \"y\": \"<x>\"}}\n{ \"id\" : 1e3 }"
+
+	tests := []struct {
+		name     string
+		options  interface{}
+		expected string
+	}{
+		{
+			name:     "Default writes lines verbatim",
+			options:  nil,
+			expected: "[{\"b\": 2, \"a\": {\"z\": 1.50, \"y\": \"<x>\"}},{ \"id\" : 1e3 }]",
+		},
+		{
+			name:     "Stable formatting keeps key order",
+			options:  JsonWriterOptions{StableFormatting: true},
+			expected: `[{"b":2,"a":{"z":1.50,"y":"<x>"}},{"id":1e3}]`,
+		},
+		{
+			name:     "Sort keys",
+			options:  JsonWriterOptions{SortKeys: true},
+			expected: `[{"a":{"y":"<x>","z":1.50},"b":2},{"id":1e3}]`,
+		},
+		{
+			name:     "JavaScript object options",
+			options:  map[string]interface{}{"sortKeys": true, "bufferSize": int64(16)},
+			expected: `[{"a":{"y":"<x>","z":1.50},"b":2},{"id":1e3}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath := filepath.Join(tempDir, "output.json")
+			var count int
+			var err error
+			if tt.options == nil {
+				count, err = loader.WriteJsonLinesToArrayFile(jsonLines, outputPath)
+			} else {
+				count, err = loader.WriteJsonLinesToArrayFile(jsonLines, outputPath, tt.options)
+			}
+			if err != nil {
+				t.Fatalf("WriteJsonLinesToArrayFile() error = %v", err)
+			}
+			if count != 2 {
+				t.Errorf("Expected count 2, got %d", count)
+			}
+
+			content, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Unexpected output:\n got: %s\nwant: %s", content, tt.expected)
+			}
+		})
+	}
+}
+
+func TestJsonWriterOptions_ByteStableAcrossWriters(t *testing.T) {
+	loader := StreamLoader{}
+	tempDir := t.TempDir()
+	options := JsonWriterOptions{SortKeys: true}
+
+	objects := []interface{}{
+		map[string]interface{}{"name": "Alice & <Bob>", "id": 1, "tags": []interface{}{"x", "y"}},
+		map[string]interface{}{"id": 2, "nested": map[string]interface{}{"b": true, "a": nil}},
+	}
+
+	// Objects written directly and via JSON lines must produce identical bytes
+	directPath := filepath.Join(tempDir, "direct.json")
+	if _, err := loader.WriteObjectsToJsonArrayFile(objects, directPath, options); err != nil {
+		t.Fatalf("WriteObjectsToJsonArrayFile() error = %v", err)
+	}
+
+	jsonLines, err := loader.ObjectsToJsonLines(objects, options)
+	if err != nil {
+		t.Fatalf("ObjectsToJsonLines() error = %v", err)
+	}
+	linesPath := filepath.Join(tempDir, "lines.json")
+	if _, err := loader.WriteJsonLinesToArrayFile(jsonLines, linesPath, options); err != nil {
+		t.Fatalf("WriteJsonLinesToArrayFile() error = %v", err)
+	}
+
+	compressed, err := loader.ObjectsToCompressedJsonLines(objects)
+	if err != nil {
+		t.Fatalf("ObjectsToCompressedJsonLines() error = %v", err)
+	}
+	compressedPath := filepath.Join(tempDir, "compressed.json")
+	if _, err := loader.WriteMultipleCompressedJsonLinesToArrayFile([]string{compressed}, compressedPath, options); err != nil {
+		t.Fatalf("WriteMultipleCompressedJsonLinesToArrayFile() error = %v", err)
+	}
+
+	combinedPath := filepath.Join(tempDir, "combined.json")
+	if _, err := loader.CombineJsonArrayFiles([]string{directPath}, combinedPath, options); err != nil {
+		t.Fatalf("CombineJsonArrayFiles() error = %v", err)
+	}
+
+	expected := `[{"id":1,"name":"Alice & <Bob>","tags":["x","y"]},{"id":2,"nested":{"a":null,"b":true}}]`
+	for _, path := range []string{directPath, linesPath, compressedPath, combinedPath} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if string(content) != expected {
+			t.Errorf("%s: unexpected output:\n got: %s\nwant: %s", filepath.Base(path), content, expected)
+		}
+	}
+}
+
+func TestJsonWriterOptions_InvalidOptions(t *testing.T) {
+	loader := StreamLoader{}
+	outputPath := filepath.Join(t.TempDir(), "output.json")
+
+	if _, err := loader.WriteJsonLinesToArrayFile(`{"id":1}`, outputPath, "fast"); err == nil {
+		t.Error("Expected error for invalid options type")
+	}
+	if _, err := loader.ObjectsToJsonLines([]interface{}{}, []int{1}); err == nil {
+		t.Error("Expected error for invalid options type")
+	}
+}